@@ -47,8 +47,8 @@ func main() {
 		log.Println("My ID:", myID)
 	}
 
-	runbeacon(beacon.NewMulticast(mc), fake)
-	runbeacon(beacon.NewBroadcast(bc), fake)
+	runbeacon(beacon.NewMulticast(mc, nil), fake)
+	runbeacon(beacon.NewBroadcast(bc, nil, nil), fake)
 
 	select {}
 }
@@ -482,7 +482,7 @@ func checkUpgrade() upgrade.Release {
 
 func performUpgrade(release upgrade.Release) {
 	// Use leveldb database locks to protect against concurrent upgrades
-	_, err := syncthing.OpenDBBackend(locations.Get(locations.Database), config.TuningAuto)
+	_, err := syncthing.OpenDBBackend(locations.Get(locations.Database), config.TuningAuto, config.DatabaseDurabilityDefault, 0)
 	if err == nil {
 		err = upgrade.To(release)
 		if err != nil {
@@ -574,7 +574,8 @@ func syncthingMain(runtimeOptions RuntimeOptions) {
 	}
 
 	dbFile := locations.Get(locations.Database)
-	ldb, err := syncthing.OpenDBBackend(dbFile, cfg.Options().DatabaseTuning)
+	opts := cfg.Options()
+	ldb, err := syncthing.OpenDBBackend(dbFile, opts.DatabaseTuning, opts.DatabaseDurability, time.Duration(opts.DatabaseSyncIntervalS)*time.Second)
 	if err != nil {
 		l.Warnln("Error opening database:", err)
 		os.Exit(1)
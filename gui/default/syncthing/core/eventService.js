@@ -78,6 +78,11 @@ angular.module('syncthing.core')
             FOLDER_SCAN_PROGRESS: 'FolderScanProgress',   // Emitted every ScanProgressIntervalS seconds, indicating how far into the scan it is at.
             FOLDER_PAUSED: 'FolderPaused',   // Emitted when a folder is paused
             FOLDER_RESUMED: 'FolderResumed',   // Emitted when a folder is resumed
+            FOLDER_OPERATION_PROGRESS: 'FolderOperationProgress',   // Emitted every second while a folder is reverted or overridden
+            FOLDER_OPERATION_FINISHED: 'FolderOperationFinished',   // Emitted with the report of a revert or override when it is done
+            EVENT_OVERFLOW: 'EventOverflow',   // Emitted when a subscriber doesn't keep up and events are dropped for it
+            COMPLETION_SUMMARY: 'CompletionSummary',   // Emitted with the completion of a folder on all connected devices at once
+            CONNECTION_REJECTED: 'ConnectionRejected',   // Emitted when an incoming connection is rejected, with the remote address and claimed device ID
 
             start: function () {
                 $http.get(urlbase + '/events?limit=1')
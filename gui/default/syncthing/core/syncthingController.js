@@ -48,6 +48,7 @@ angular.module('syncthing.core')
         $scope.failed = {};
         $scope.localChanged = {};
         $scope.scanProgress = {};
+        $scope.folderOperations = {};
         $scope.themes = [];
         $scope.globalChangeEvents = {};
         $scope.metricRates = false;
@@ -77,7 +78,10 @@ angular.module('syncthing.core')
             staggeredMaxAge: 365,
             staggeredCleanInterval: 3600,
             staggeredVersionsPath: "",
+            versionsMaxSize: "",
+            versionsCleanupSchedule: "",
             externalCommand: "",
+            externalJSONProtocol: false,
             autoNormalize: true,
             path: "",
         };
@@ -108,6 +112,16 @@ angular.module('syncthing.core')
             'sync': 'far fa-fw fa-arrow-alt-circle-down',
             'touch': 'fas fa-fw fa-asterisk'
         };
+        $scope.needReasons = {
+            'failed': 'Failed',
+            'notAvailable': 'Not available from any connected device',
+            'devicePaused': 'Only available from paused devices',
+            'ignoredRemotely': 'Ignored by the devices that have it',
+            'ignoreDelete': 'Deletes are ignored',
+            'folderPaused': 'Folder paused',
+            'outsideSyncWindow': 'Outside of sync window',
+            'notConnected': 'Device not connected'
+        };
 
         $scope.$on(Events.ONLINE, function () {
             if (online && !restarting) {
@@ -329,6 +343,17 @@ angular.module('syncthing.core')
             recalcCompletion(data.device);
         });
 
+        $scope.$on(Events.COMPLETION_SUMMARY, function (event, arg) {
+            var data = arg.data;
+            for (var device in data.completions) {
+                if (!$scope.completion[device]) {
+                    $scope.completion[device] = {};
+                }
+                $scope.completion[device][data.folder] = data.completions[device];
+                recalcCompletion(device);
+            }
+        });
+
         $scope.$on(Events.FOLDER_ERRORS, function (event, arg) {
             $scope.model[arg.data.folder].errors = arg.data.errors.length;
         });
@@ -343,6 +368,15 @@ angular.module('syncthing.core')
             console.log("FolderScanProgress", data);
         });
 
+        $scope.$on(Events.FOLDER_OPERATION_PROGRESS, function (event, arg) {
+            $scope.folderOperations[arg.data.folder] = arg.data;
+        });
+
+        $scope.$on(Events.FOLDER_OPERATION_FINISHED, function (event, arg) {
+            delete $scope.folderOperations[arg.data.folder];
+            console.log("FolderOperationFinished", arg.data);
+        });
+
         $scope.emitHTTPError = function (data, status, headers, config) {
             $scope.$emit('HTTPError', { data: data, status: status, headers: headers, config: config });
         };
@@ -837,7 +871,7 @@ angular.module('syncthing.core')
             if (status === 'stopped' || status === 'outofsync' || status === 'error' || status === 'faileditems') {
                 return 'danger';
             }
-            if (status === 'unshared' || status === 'scan-waiting' || status === 'sync-waiting') {
+            if (status === 'unshared' || status === 'scan-waiting' || status === 'sync-waiting' || status === 'maintenance') {
                 return 'warning';
             }
 
@@ -1696,7 +1730,7 @@ angular.module('syncthing.core')
             $scope.folderEditor.$setPristine();
             $('#editFolder').modal().one('shown.bs.tab', function (e) {
                 if (e.target.attributes.href.value === "#folder-ignores") {
-                    $('#folder-ignores textarea').focus();
+                    $('#folder-ignores-patterns').focus();
                 }
             }).one('hidden.bs.modal', function () {
                 $('.nav-tabs a[href="#folder-general"]').tab('show');
@@ -1728,20 +1762,32 @@ angular.module('syncthing.core')
                 $scope.currentFolder.trashcanFileVersioning = true;
                 $scope.currentFolder.fileVersioningSelector = "trashcan";
                 $scope.currentFolder.trashcanClean = +$scope.currentFolder.versioning.params.cleanoutDays;
+                $scope.currentFolder.versionsCleanupSchedule = $scope.currentFolder.versioning.params.cleanupSchedule;
             } else if ($scope.currentFolder.versioning && $scope.currentFolder.versioning.type === "simple") {
                 $scope.currentFolder.simpleFileVersioning = true;
                 $scope.currentFolder.fileVersioningSelector = "simple";
                 $scope.currentFolder.simpleKeep = +$scope.currentFolder.versioning.params.keep;
+                $scope.currentFolder.versionsMaxSize = $scope.currentFolder.versioning.params.maxSize;
             } else if ($scope.currentFolder.versioning && $scope.currentFolder.versioning.type === "staggered") {
                 $scope.currentFolder.staggeredFileVersioning = true;
                 $scope.currentFolder.fileVersioningSelector = "staggered";
                 $scope.currentFolder.staggeredMaxAge = Math.floor(+$scope.currentFolder.versioning.params.maxAge / 86400);
                 $scope.currentFolder.staggeredCleanInterval = +$scope.currentFolder.versioning.params.cleanInterval;
                 $scope.currentFolder.staggeredVersionsPath = $scope.currentFolder.versioning.params.versionsPath;
+                $scope.currentFolder.versionsMaxSize = $scope.currentFolder.versioning.params.maxSize;
+                $scope.currentFolder.versionsCleanupSchedule = $scope.currentFolder.versioning.params.cleanupSchedule;
+            } else if ($scope.currentFolder.versioning && $scope.currentFolder.versioning.type === "dedup") {
+                $scope.currentFolder.fileVersioningSelector = "dedup";
+                $scope.currentFolder.simpleKeep = +$scope.currentFolder.versioning.params.keep;
+                $scope.currentFolder.staggeredVersionsPath = $scope.currentFolder.versioning.params.versionsPath;
+                $scope.currentFolder.versionsCleanupSchedule = $scope.currentFolder.versioning.params.cleanupSchedule;
+            } else if ($scope.currentFolder.versioning && $scope.currentFolder.versioning.type === "systemtrash") {
+                $scope.currentFolder.fileVersioningSelector = "systemtrash";
             } else if ($scope.currentFolder.versioning && $scope.currentFolder.versioning.type === "external") {
                 $scope.currentFolder.externalFileVersioning = true;
                 $scope.currentFolder.fileVersioningSelector = "external";
                 $scope.currentFolder.externalCommand = $scope.currentFolder.versioning.params.command;
+                $scope.currentFolder.externalJSONProtocol = $scope.currentFolder.versioning.params.protocol === "json";
             } else {
                 $scope.currentFolder.fileVersioningSelector = "none";
             }
@@ -1749,6 +1795,8 @@ angular.module('syncthing.core')
             $scope.currentFolder.simpleKeep = $scope.currentFolder.simpleKeep || 5;
             $scope.currentFolder.staggeredCleanInterval = $scope.currentFolder.staggeredCleanInterval || 3600;
             $scope.currentFolder.staggeredVersionsPath = $scope.currentFolder.staggeredVersionsPath || "";
+            $scope.currentFolder.versionsMaxSize = $scope.currentFolder.versionsMaxSize || "";
+            $scope.currentFolder.versionsCleanupSchedule = $scope.currentFolder.versionsCleanupSchedule || "";
 
             // staggeredMaxAge can validly be zero, which we should not replace
             // with the default value of 365. So only set the default if it's
@@ -1758,16 +1806,17 @@ angular.module('syncthing.core')
             }
             $scope.currentFolder.externalCommand = $scope.currentFolder.externalCommand || "";
 
-            $('#folder-ignores textarea').val($translate.instant("Loading..."));
-            $('#folder-ignores textarea').attr('disabled', 'disabled');
+            $scope.ignoreTest = {};
+            $('#folder-ignores-patterns').val($translate.instant("Loading..."));
+            $('#folder-ignores-patterns').attr('disabled', 'disabled');
             $http.get(urlbase + '/db/ignores?folder=' + encodeURIComponent($scope.currentFolder.id))
                 .success(function (data) {
                     $scope.currentFolder.ignores = data.ignore || [];
-                    $('#folder-ignores textarea').val($scope.currentFolder.ignores.join('\n'));
-                    $('#folder-ignores textarea').removeAttr('disabled');
+                    $('#folder-ignores-patterns').val($scope.currentFolder.ignores.join('\n'));
+                    $('#folder-ignores-patterns').removeAttr('disabled');
                 })
                 .error(function (err) {
-                    $('#folder-ignores textarea').val($translate.instant("Failed to load ignore patterns."));
+                    $('#folder-ignores-patterns').val($translate.instant("Failed to load ignore patterns."));
                     $scope.emitHTTPError(err);
                 });
 
@@ -1794,8 +1843,8 @@ angular.module('syncthing.core')
                 $scope.currentFolder = angular.copy($scope.folderDefaults);
                 $scope.currentFolder.id = (data.random.substr(0, 5) + '-' + data.random.substr(5, 5)).toLowerCase();
                 $scope.currentFolder.unrelatedDevices = $scope.otherDevices();
-                $('#folder-ignores textarea').val("");
-                $('#folder-ignores textarea').removeAttr('disabled');
+                $('#folder-ignores-patterns').val("");
+                $('#folder-ignores-patterns').removeAttr('disabled');
                 $scope.editFolderModal();
             });
         };
@@ -1810,8 +1859,8 @@ angular.module('syncthing.core')
             };
             $scope.currentFolder.selectedDevices[device] = true;
             $scope.currentFolder.unrelatedDevices = $scope.otherDevices();
-            $('#folder-ignores textarea').val("");
-            $('#folder-ignores textarea').removeAttr('disabled');
+            $('#folder-ignores-patterns').val("");
+            $('#folder-ignores-patterns').removeAttr('disabled');
             $scope.editFolderModal();
         };
 
@@ -1839,53 +1888,86 @@ angular.module('syncthing.core')
             delete folderCfg.selectedDevices;
             delete folderCfg.unrelatedDevices;
 
+            // Path overrides aren't edited here, but must be kept
+            var versioningOverrides = folderCfg.versioning && folderCfg.versioning.overrides;
             if (folderCfg.fileVersioningSelector === "trashcan") {
                 folderCfg.versioning = {
                     'Type': 'trashcan',
                     'Params': {
-                        'cleanoutDays': '' + folderCfg.trashcanClean
+                        'cleanoutDays': '' + folderCfg.trashcanClean,
+                        'cleanupSchedule': '' + folderCfg.versionsCleanupSchedule
                     }
                 };
                 delete folderCfg.trashcanFileVersioning;
                 delete folderCfg.trashcanClean;
+                delete folderCfg.versionsCleanupSchedule;
             } else if (folderCfg.fileVersioningSelector === "simple") {
                 folderCfg.versioning = {
                     'Type': 'simple',
                     'Params': {
-                        'keep': '' + folderCfg.simpleKeep
+                        'keep': '' + folderCfg.simpleKeep,
+                        'maxSize': '' + folderCfg.versionsMaxSize
                     }
                 };
                 delete folderCfg.simpleFileVersioning;
                 delete folderCfg.simpleKeep;
+                delete folderCfg.versionsMaxSize;
             } else if (folderCfg.fileVersioningSelector === "staggered") {
                 folderCfg.versioning = {
                     'type': 'staggered',
                     'params': {
                         'maxAge': '' + (folderCfg.staggeredMaxAge * 86400),
                         'cleanInterval': '' + folderCfg.staggeredCleanInterval,
-                        'versionsPath': '' + folderCfg.staggeredVersionsPath
+                        'versionsPath': '' + folderCfg.staggeredVersionsPath,
+                        'maxSize': '' + folderCfg.versionsMaxSize,
+                        'cleanupSchedule': '' + folderCfg.versionsCleanupSchedule
                     }
                 };
                 delete folderCfg.staggeredFileVersioning;
                 delete folderCfg.staggeredMaxAge;
                 delete folderCfg.staggeredCleanInterval;
                 delete folderCfg.staggeredVersionsPath;
+                delete folderCfg.versionsMaxSize;
+                delete folderCfg.versionsCleanupSchedule;
 
+            } else if (folderCfg.fileVersioningSelector === "dedup") {
+                folderCfg.versioning = {
+                    'type': 'dedup',
+                    'params': {
+                        'keep': '' + folderCfg.simpleKeep,
+                        'versionsPath': '' + folderCfg.staggeredVersionsPath,
+                        'cleanupSchedule': '' + folderCfg.versionsCleanupSchedule
+                    }
+                };
+                delete folderCfg.simpleKeep;
+                delete folderCfg.staggeredVersionsPath;
+                delete folderCfg.versionsCleanupSchedule;
+            } else if (folderCfg.fileVersioningSelector === "systemtrash") {
+                folderCfg.versioning = {
+                    'type': 'systemtrash',
+                    'params': {}
+                };
             } else if (folderCfg.fileVersioningSelector === "external") {
                 folderCfg.versioning = {
                     'Type': 'external',
                     'Params': {
-                        'command': '' + folderCfg.externalCommand
+                        'command': '' + folderCfg.externalCommand,
+                        'protocol': folderCfg.externalJSONProtocol ? 'json' : ''
                     }
                 };
                 delete folderCfg.externalFileVersioning;
                 delete folderCfg.externalCommand;
+                delete folderCfg.externalJSONProtocol;
             } else {
                 delete folderCfg.versioning;
             }
+            if (versioningOverrides && versioningOverrides.length) {
+                folderCfg.versioning = folderCfg.versioning || { 'type': '', 'params': {} };
+                folderCfg.versioning.overrides = versioningOverrides;
+            }
 
-            var ignoresLoaded = !$('#folder-ignores textarea').is(':disabled');
-            var ignores = $('#folder-ignores textarea').val().split('\n');
+            var ignoresLoaded = !$('#folder-ignores-patterns').is(':disabled');
+            var ignores = $('#folder-ignores-patterns').val().split('\n');
             // Split always returns a minimum 1-length array even for no patterns
             if (ignores.length === 1 && ignores[0] === "") {
                 ignores = [];
@@ -1910,6 +1992,20 @@ angular.module('syncthing.core')
             });
         };
 
+        $scope.testIgnores = function () {
+            $scope.ignoreTest = $scope.ignoreTest || {};
+            var paths = ($scope.ignoreTest.paths || '').split('\n').filter(function (path) {
+                return path.trim() !== '';
+            });
+            var ignores = $('#folder-ignores-patterns').val().split('\n');
+            $http.post(urlbase + '/folder/ignores/test?folder=' + encodeURIComponent($scope.currentFolder.id), {
+                paths: paths,
+                ignore: ignores
+            }).success(function (data) {
+                $scope.ignoreTest.results = data;
+            }).error($scope.emitHTTPError);
+        };
+
         $scope.ignoreFolder = function (device, pendingFolder) {
             pendingFolder = angular.copy(pendingFolder);
             // Bump time
@@ -2282,6 +2378,10 @@ angular.module('syncthing.core')
             $http.post(urlbase + "/db/revert?folder=" + encodeURIComponent(folder));
         };
 
+        $scope.cancelFolderOperation = function (folder) {
+            $http.post(urlbase + "/db/operation/cancel?folder=" + encodeURIComponent(folder));
+        };
+
         $scope.canRevert = function (folder) {
             var f = $scope.model[folder];
             if (!f) {
@@ -2332,6 +2432,10 @@ angular.module('syncthing.core')
             $http.post(urlbase + "/db/scan?folder=" + encodeURIComponent(folder));
         };
 
+        $scope.setFolderMaintenance = function (folder, enabled) {
+            $http.post(urlbase + "/folder/maintenance?folder=" + encodeURIComponent(folder) + "&enabled=" + enabled);
+        };
+
         $scope.setAllFoldersPause = function (pause) {
             var folderListCache = $scope.folderList();
 
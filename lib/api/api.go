@@ -35,6 +35,9 @@ import (
 	"github.com/thejerf/suture"
 	"github.com/vitrun/qart/qr"
 	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"golang.org/x/net/websocket"
 
 	"github.com/syncthing/syncthing/lib/build"
 	"github.com/syncthing/syncthing/lib/config"
@@ -43,16 +46,20 @@ import (
 	"github.com/syncthing/syncthing/lib/discover"
 	"github.com/syncthing/syncthing/lib/events"
 	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/ignore"
 	"github.com/syncthing/syncthing/lib/locations"
 	"github.com/syncthing/syncthing/lib/logger"
 	"github.com/syncthing/syncthing/lib/model"
 	"github.com/syncthing/syncthing/lib/protocol"
 	"github.com/syncthing/syncthing/lib/rand"
+	"github.com/syncthing/syncthing/lib/stats"
+	"github.com/syncthing/syncthing/lib/stun"
 	"github.com/syncthing/syncthing/lib/sync"
 	"github.com/syncthing/syncthing/lib/tlsutil"
 	"github.com/syncthing/syncthing/lib/upgrade"
 	"github.com/syncthing/syncthing/lib/ur"
 	"github.com/syncthing/syncthing/lib/util"
+	"github.com/syncthing/syncthing/lib/versioner"
 )
 
 // matches a bcrypt hash and not too much else
@@ -63,6 +70,7 @@ const (
 	DiskEventMask         = events.LocalChangeDetected | events.RemoteChangeDetected
 	EventSubBufferSize    = 1000
 	defaultEventTimeout   = time.Minute
+	webSocketPollInterval = 30 * time.Second
 	httpsCertLifetimeDays = 820
 )
 
@@ -75,12 +83,16 @@ type service struct {
 	model                model.Model
 	eventSubs            map[events.EventType]events.BufferedSubscription
 	eventSubsMut         sync.Mutex
+	persistentSub        events.BufferedSubscription
 	evLogger             events.Logger
 	discoverer           discover.CachingMux
 	connectionsService   connections.Service
 	fss                  model.FolderSummaryService
 	urService            *ur.Service
 	systemConfigMut      sync.Mutex // serializes posts to /rest/system/config
+	natProbe             *stun.ProbeResult
+	natProbeMut          sync.Mutex
+	natProbingMut        sync.Mutex // serializes NAT probes, as they take a while
 	cpu                  Rater
 	contr                Controller
 	noUpgrade            bool
@@ -111,7 +123,7 @@ type Service interface {
 	WaitForStart() error
 }
 
-func New(id protocol.DeviceID, cfg config.Wrapper, assetDir, tlsDefaultCommonName string, m model.Model, defaultSub, diskSub events.BufferedSubscription, evLogger events.Logger, discoverer discover.CachingMux, connectionsService connections.Service, urService *ur.Service, fss model.FolderSummaryService, errors, systemLog logger.Recorder, cpu Rater, contr Controller, noUpgrade bool) Service {
+func New(id protocol.DeviceID, cfg config.Wrapper, assetDir, tlsDefaultCommonName string, m model.Model, defaultSub, diskSub, persistentSub events.BufferedSubscription, evLogger events.Logger, discoverer discover.CachingMux, connectionsService connections.Service, urService *ur.Service, fss model.FolderSummaryService, errors, systemLog logger.Recorder, cpu Rater, contr Controller, noUpgrade bool) Service {
 	s := &service{
 		id:      id,
 		cfg:     cfg,
@@ -122,12 +134,15 @@ func New(id protocol.DeviceID, cfg config.Wrapper, assetDir, tlsDefaultCommonNam
 			DiskEventMask:    diskSub,
 		},
 		eventSubsMut:         sync.NewMutex(),
+		persistentSub:        persistentSub,
 		evLogger:             evLogger,
 		discoverer:           discoverer,
 		connectionsService:   connectionsService,
 		fss:                  fss,
 		urService:            urService,
 		systemConfigMut:      sync.NewMutex(),
+		natProbeMut:          sync.NewMutex(),
+		natProbingMut:        sync.NewMutex(),
 		guiErrors:            errors,
 		systemLog:            systemLog,
 		cpu:                  cpu,
@@ -175,6 +190,10 @@ func (s *service) getListener(guiCfg config.GUIConfiguration) (net.Listener, err
 	}
 	tlsCfg := tlsutil.SecureDefault()
 	tlsCfg.Certificates = []tls.Certificate{cert}
+	if guiCfg.GRPCEnabled {
+		// gRPC requires HTTP/2, which must be negotiated.
+		tlsCfg.NextProtos = []string{"h2", "http/1.1"}
+	}
 
 	if guiCfg.Network() == "unix" {
 		// When listening on a UNIX socket we should unlink before bind,
@@ -241,58 +260,90 @@ func (s *service) serve(ctx context.Context) {
 
 	// The GET handlers
 	getRestMux := http.NewServeMux()
-	getRestMux.HandleFunc("/rest/db/completion", s.getDBCompletion)              // device folder
-	getRestMux.HandleFunc("/rest/db/file", s.getDBFile)                          // folder file
-	getRestMux.HandleFunc("/rest/db/ignores", s.getDBIgnores)                    // folder
-	getRestMux.HandleFunc("/rest/db/need", s.getDBNeed)                          // folder [perpage] [page]
-	getRestMux.HandleFunc("/rest/db/remoteneed", s.getDBRemoteNeed)              // device folder [perpage] [page]
-	getRestMux.HandleFunc("/rest/db/localchanged", s.getDBLocalChanged)          // folder
-	getRestMux.HandleFunc("/rest/db/status", s.getDBStatus)                      // folder
-	getRestMux.HandleFunc("/rest/db/browse", s.getDBBrowse)                      // folder [prefix] [dirsonly] [levels]
-	getRestMux.HandleFunc("/rest/folder/versions", s.getFolderVersions)          // folder
-	getRestMux.HandleFunc("/rest/folder/errors", s.getFolderErrors)              // folder
-	getRestMux.HandleFunc("/rest/folder/pullerrors", s.getFolderErrors)          // folder (deprecated)
-	getRestMux.HandleFunc("/rest/events", s.getIndexEvents)                      // [since] [limit] [timeout] [events]
-	getRestMux.HandleFunc("/rest/events/disk", s.getDiskEvents)                  // [since] [limit] [timeout]
-	getRestMux.HandleFunc("/rest/stats/device", s.getDeviceStats)                // -
-	getRestMux.HandleFunc("/rest/stats/folder", s.getFolderStats)                // -
-	getRestMux.HandleFunc("/rest/svc/deviceid", s.getDeviceID)                   // id
-	getRestMux.HandleFunc("/rest/svc/lang", s.getLang)                           // -
-	getRestMux.HandleFunc("/rest/svc/report", s.getReport)                       // -
-	getRestMux.HandleFunc("/rest/svc/random/string", s.getRandomString)          // [length]
-	getRestMux.HandleFunc("/rest/system/browse", s.getSystemBrowse)              // current
-	getRestMux.HandleFunc("/rest/system/config", s.getSystemConfig)              // -
-	getRestMux.HandleFunc("/rest/system/config/insync", s.getSystemConfigInsync) // -
-	getRestMux.HandleFunc("/rest/system/connections", s.getSystemConnections)    // -
-	getRestMux.HandleFunc("/rest/system/discovery", s.getSystemDiscovery)        // -
-	getRestMux.HandleFunc("/rest/system/error", s.getSystemError)                // -
-	getRestMux.HandleFunc("/rest/system/ping", s.restPing)                       // -
-	getRestMux.HandleFunc("/rest/system/status", s.getSystemStatus)              // -
-	getRestMux.HandleFunc("/rest/system/upgrade", s.getSystemUpgrade)            // -
-	getRestMux.HandleFunc("/rest/system/version", s.getSystemVersion)            // -
-	getRestMux.HandleFunc("/rest/system/debug", s.getSystemDebug)                // -
-	getRestMux.HandleFunc("/rest/system/log", s.getSystemLog)                    // [since]
-	getRestMux.HandleFunc("/rest/system/log.txt", s.getSystemLogTxt)             // [since]
+	getRestMux.HandleFunc("/rest/db/completion", s.getDBCompletion)                              // device folder
+	getRestMux.HandleFunc("/rest/db/file", s.getDBFile)                                          // folder file
+	getRestMux.HandleFunc("/rest/db/provenance", s.getDBProvenance)                              // folder file
+	getRestMux.HandleFunc("/rest/db/filehistory", s.getDBFileHistory)                            // folder path
+	getRestMux.HandleFunc("/rest/db/file-content", s.getDBFileContent)                           // folder file [sequence] [version]
+	getRestMux.HandleFunc("/rest/db/active", s.getDBActive)                                      // [folder]
+	getRestMux.HandleFunc("/rest/db/ignores", s.getDBIgnores)                                    // folder
+	getRestMux.HandleFunc("/rest/db/need", s.getDBNeed)                                          // folder [perpage] [page]
+	getRestMux.HandleFunc("/rest/db/need-all", s.getDBNeedAll)                                   // [perfolder]
+	getRestMux.HandleFunc("/rest/db/remoteneed", s.getDBRemoteNeed)                              // device folder [perpage] [page]
+	getRestMux.HandleFunc("/rest/db/plan", s.getDBPullPlan)                                      // folder
+	getRestMux.HandleFunc("/rest/db/activity", s.getDBActivity)                                  // folder [prefix] [levels]
+	getRestMux.HandleFunc("/rest/db/localchanged", s.getDBLocalChanged)                          // folder
+	getRestMux.HandleFunc("/rest/db/operation", s.getDBOperation)                                // folder
+	getRestMux.HandleFunc("/rest/db/status", s.getDBStatus)                                      // folder
+	getRestMux.HandleFunc("/rest/db/size", s.getDBSize)                                          // folder [prefix]
+	getRestMux.HandleFunc("/rest/db/status-all", s.getDBStatusAll)                               // -
+	getRestMux.HandleFunc("/rest/db/browse", s.getDBBrowse)                                      // folder [prefix] [dirsonly] [levels] [offset] [limit] [sort] [reverse] [token]
+	getRestMux.HandleFunc("/rest/folder/versions", s.getFolderVersions)                          // folder [file]
+	getRestMux.HandleFunc("/rest/folder/versions/file", s.getFolderVersionFile)                  // folder file version
+	getRestMux.HandleFunc("/rest/folder/versions/diff", s.getFolderVersionsDiff)                 // folder file from to
+	getRestMux.HandleFunc("/rest/folder/errors", s.getFolderErrors)                              // folder
+	getRestMux.HandleFunc("/rest/folder/ignores/usage", s.getFolderIgnoresUsage)                 // folder
+	getRestMux.HandleFunc("/rest/folder/pullerrors", s.getFolderErrors)                          // folder (deprecated)
+	getRestMux.HandleFunc("/rest/events", s.getIndexEvents)                                      // [since] [limit] [timeout] [events] [persistent]
+	getRestMux.HandleFunc("/rest/events/disk", s.getDiskEvents)                                  // [since] [limit] [timeout]
+	getRestMux.HandleFunc("/rest/events/ws", s.getEventsWebSocket)                               // [since] [events]
+	getRestMux.HandleFunc("/rest/stats/device", s.getDeviceStats)                                // -
+	getRestMux.HandleFunc("/rest/stats/folder", s.getFolderStats)                                // -
+	getRestMux.HandleFunc("/rest/stats/folder/scans", s.getFolderScanStats)                      // folder
+	getRestMux.HandleFunc("/rest/openapi.json", s.getOpenAPI)                                    // -
+	getRestMux.HandleFunc("/rest/svc/deviceid", s.getDeviceID)                                   // id
+	getRestMux.HandleFunc("/rest/svc/lang", s.getLang)                                           // -
+	getRestMux.HandleFunc("/rest/svc/report", s.getReport)                                       // -
+	getRestMux.HandleFunc("/rest/svc/random/string", s.getRandomString)                          // [length]
+	getRestMux.HandleFunc("/rest/system/browse", s.getSystemBrowse)                              // current
+	getRestMux.HandleFunc("/rest/system/config", s.getSystemConfig)                              // -
+	getRestMux.HandleFunc("/rest/system/config/insync", s.getSystemConfigInsync)                 // -
+	getRestMux.HandleFunc("/rest/system/connections", s.getSystemConnections)                    // -
+	getRestMux.HandleFunc("/rest/system/connections/rejected", s.getSystemConnectionsRejected)   // -
+	getRestMux.HandleFunc("/rest/system/connections/offenders", s.getSystemConnectionsOffenders) // -
+	getRestMux.HandleFunc("/rest/system/discovery", s.getSystemDiscovery)                        // -
+	getRestMux.HandleFunc("/rest/system/error", s.getSystemError)                                // -
+	getRestMux.HandleFunc("/rest/system/ping", s.restPing)                                       // -
+	getRestMux.HandleFunc("/rest/system/status", s.getSystemStatus)                              // -
+	getRestMux.HandleFunc("/rest/system/upgrade", s.getSystemUpgrade)                            // -
+	getRestMux.HandleFunc("/rest/system/version", s.getSystemVersion)                            // -
+	getRestMux.HandleFunc("/rest/system/debug", s.getSystemDebug)                                // -
+	getRestMux.HandleFunc("/rest/system/log", s.getSystemLog)                                    // [since]
+	getRestMux.HandleFunc("/rest/system/log.txt", s.getSystemLogTxt)                             // [since]
+	getRestMux.HandleFunc("/rest/system/natprobe", s.getSystemNATProbe)                          // -
 
 	// The POST handlers
 	postRestMux := http.NewServeMux()
-	postRestMux.HandleFunc("/rest/db/prio", s.postDBPrio)                          // folder file [perpage] [page]
-	postRestMux.HandleFunc("/rest/db/ignores", s.postDBIgnores)                    // folder
-	postRestMux.HandleFunc("/rest/db/override", s.postDBOverride)                  // folder
-	postRestMux.HandleFunc("/rest/db/revert", s.postDBRevert)                      // folder
-	postRestMux.HandleFunc("/rest/db/scan", s.postDBScan)                          // folder [sub...] [delay]
-	postRestMux.HandleFunc("/rest/folder/versions", s.postFolderVersionsRestore)   // folder <body>
-	postRestMux.HandleFunc("/rest/system/config", s.postSystemConfig)              // <body>
-	postRestMux.HandleFunc("/rest/system/error", s.postSystemError)                // <body>
-	postRestMux.HandleFunc("/rest/system/error/clear", s.postSystemErrorClear)     // -
-	postRestMux.HandleFunc("/rest/system/ping", s.restPing)                        // -
-	postRestMux.HandleFunc("/rest/system/reset", s.postSystemReset)                // [folder]
-	postRestMux.HandleFunc("/rest/system/restart", s.postSystemRestart)            // -
-	postRestMux.HandleFunc("/rest/system/shutdown", s.postSystemShutdown)          // -
-	postRestMux.HandleFunc("/rest/system/upgrade", s.postSystemUpgrade)            // -
-	postRestMux.HandleFunc("/rest/system/pause", s.makeDevicePauseHandler(true))   // [device]
-	postRestMux.HandleFunc("/rest/system/resume", s.makeDevicePauseHandler(false)) // [device]
-	postRestMux.HandleFunc("/rest/system/debug", s.postSystemDebug)                // [enable] [disable]
+	postRestMux.HandleFunc("/rest/batch", s.postBatch)                                    // <body>
+	postRestMux.HandleFunc("/rest/db/prio", s.postDBPrio)                                 // folder file [perpage] [page]
+	postRestMux.HandleFunc("/rest/db/pin", s.postDBPin)                                   // folder file [unpin]
+	postRestMux.HandleFunc("/rest/db/ignores", s.postDBIgnores)                           // folder
+	postRestMux.HandleFunc("/rest/db/ignores/edit", s.postDBIgnoresEdit)                  // folder [version]
+	postRestMux.HandleFunc("/rest/db/ignores/unignore", s.postDBIgnoresUnignore)          // folder [version]
+	postRestMux.HandleFunc("/rest/folder/ignores/test", s.postFolderIgnoresTest)          // folder
+	postRestMux.HandleFunc("/rest/db/override", s.postDBOverride)                         // folder
+	postRestMux.HandleFunc("/rest/db/revert", s.postDBRevert)                             // folder
+	postRestMux.HandleFunc("/rest/db/operation/cancel", s.postDBOperationCancel)          // folder
+	postRestMux.HandleFunc("/rest/db/retry", s.postDBRetry)                               // folder [file...]
+	postRestMux.HandleFunc("/rest/db/undelete", s.postDBUndelete)                         // folder file...
+	postRestMux.HandleFunc("/rest/db/normalize", s.postDBNormalize)                       // folder [dryrun]
+	postRestMux.HandleFunc("/rest/db/scan", s.postDBScan)                                 // folder [sub...] [delay]
+	postRestMux.HandleFunc("/rest/folder/versions", s.postFolderVersionsRestore)          // folder <body>
+	postRestMux.HandleFunc("/rest/folder/versions/restore", s.postFolderVersionRestoreTo) // folder file version target
+	postRestMux.HandleFunc("/rest/folder/versions/cleanup", s.postFolderVersionsCleanup)  // folder
+	postRestMux.HandleFunc("/rest/folder/maintenance", s.postFolderMaintenance)           // folder [enabled]
+	postRestMux.HandleFunc("/rest/folder/pull/resume", s.postFolderPullResume)            // folder
+	postRestMux.HandleFunc("/rest/system/config", s.postSystemConfig)                     // <body>
+	postRestMux.HandleFunc("/rest/system/error", s.postSystemError)                       // <body>
+	postRestMux.HandleFunc("/rest/system/error/clear", s.postSystemErrorClear)            // -
+	postRestMux.HandleFunc("/rest/system/ping", s.restPing)                               // -
+	postRestMux.HandleFunc("/rest/system/reset", s.postSystemReset)                       // [folder]
+	postRestMux.HandleFunc("/rest/system/restart", s.postSystemRestart)                   // -
+	postRestMux.HandleFunc("/rest/system/shutdown", s.postSystemShutdown)                 // -
+	postRestMux.HandleFunc("/rest/system/upgrade", s.postSystemUpgrade)                   // -
+	postRestMux.HandleFunc("/rest/system/pause", s.makeDevicePauseHandler(true))          // [device]
+	postRestMux.HandleFunc("/rest/system/resume", s.makeDevicePauseHandler(false))        // [device]
+	postRestMux.HandleFunc("/rest/system/debug", s.postSystemDebug)                       // [enable] [disable]
 
 	// Debug endpoints, not for general use
 	debugMux := http.NewServeMux()
@@ -300,7 +351,8 @@ func (s *service) serve(ctx context.Context) {
 	debugMux.HandleFunc("/rest/debug/httpmetrics", s.getSystemHTTPMetrics)
 	debugMux.HandleFunc("/rest/debug/cpuprof", s.getCPUProf) // duration
 	debugMux.HandleFunc("/rest/debug/heapprof", s.getHeapProf)
-	debugMux.HandleFunc("/rest/debug/support", s.getSupportBundle)
+	debugMux.HandleFunc("/rest/debug/events/subscribers", s.getDebugEventSubscribers)
+	debugMux.HandleFunc("/rest/debug/support", s.getSupportBundle) // [redact]
 	getRestMux.Handle("/rest/debug/", s.whenDebugging(debugMux))
 
 	// A handler that splits requests between the two above and disables
@@ -322,7 +374,8 @@ func (s *service) serve(ctx context.Context) {
 
 	// Wrap everything in CSRF protection. The /rest prefix should be
 	// protected, other requests will grant cookies.
-	var handler http.Handler = newCsrfManager(s.id.String()[:5], "/rest", guiCfg, mux, locations.Get(locations.CsrfTokens))
+	csrf := newCsrfManager(s.id.String()[:5], "/rest", guiCfg, mux, locations.Get(locations.CsrfTokens))
+	var handler http.Handler = csrf
 
 	// Add our version and ID as a header to responses
 	handler = withDetailsMiddleware(s.id, handler)
@@ -332,19 +385,55 @@ func (s *service) serve(ctx context.Context) {
 		handler = basicAuthAndSessionMiddleware("sessionid-"+s.id.String()[:5], guiCfg, s.cfg.LDAP(), handler, s.evLogger)
 	}
 
+	// Limit API tokens to their scopes, before they are let through by the
+	// above.
+	handler = apiTokenMiddleware(guiCfg, handler)
+
+	if guiCfg.GRPCEnabled {
+		// gRPC calls are authenticated by the API key on their own and
+		// bypass the CSRF and session handling above, but not the checks
+		// below.
+		grpcSrv := s.newGRPCServer()
+		defer grpcSrv.Stop()
+		handler = grpcMiddleware(grpcSrv, handler)
+	}
+
 	// Redirect to HTTPS if we are supposed to
 	if guiCfg.UseTLS() {
 		handler = redirectToHTTPSMiddleware(handler)
 	}
 
 	// Add the CORS handling
-	handler = corsMiddleware(handler, guiCfg.InsecureAllowFrameLoading)
+	handler = corsMiddleware(handler, guiCfg)
 
 	if addressIsLocalhost(guiCfg.Address()) && !guiCfg.InsecureSkipHostCheck {
 		// Verify source host
 		handler = localhostMiddleware(handler)
 	}
 
+	// Limit the rate of requests per client, if configured
+	if guiCfg.RateLimitRequestsPerS > 0 {
+		limiter := newClientRateLimiter(guiCfg.RateLimitRequestsPerS, guiCfg.RateLimitBurst)
+		handler = rateLimitMiddleware(limiter, guiCfg, csrf, handler)
+	}
+
+	// Serve everything under the path prefix, if configured
+	if prefix := guiCfg.PathPrefix(); prefix != "" {
+		handler = pathPrefixMiddleware(prefix, handler)
+	}
+
+	if guiCfg.GRPCEnabled && !guiCfg.UseTLS() {
+		// Plaintext HTTP/2 is accepted for gRPC clients, unless everything
+		// must use TLS.
+		handler = h2c.NewHandler(handler, &http2.Server{})
+	}
+
+	// Take the client address and protocol from trusted reverse proxies,
+	// before anything looks at them
+	if len(guiCfg.TrustedProxies) > 0 {
+		handler = forwardedMiddleware(guiCfg, handler)
+	}
+
 	handler = debugMiddleware(handler)
 
 	srv := http.Server{
@@ -356,6 +445,11 @@ func (s *service) serve(ctx context.Context) {
 		// care about we log ourselves from the handlers.
 		ErrorLog: log.New(ioutil.Discard, "", 0),
 	}
+	if guiCfg.GRPCEnabled {
+		if err := http2.ConfigureServer(&srv, &http2.Server{}); err != nil {
+			l.Warnln("Enabling HTTP/2 for gRPC:", err)
+		}
+	}
 
 	l.Infoln("GUI and API listening on", listener.Addr())
 	l.Infoln("Access the GUI via the following URL:", guiCfg.URL())
@@ -427,8 +521,12 @@ func (s *service) VerifyConfiguration(from, to config.Configuration) error {
 func (s *service) CommitConfiguration(from, to config.Configuration) bool {
 	// No action required when this changes, so mask the fact that it changed at all.
 	from.GUI.Debugging = to.GUI.Debugging
+	if len(from.GUI.APITokens) == 0 && len(to.GUI.APITokens) == 0 {
+		// Nil or empty, it's all the same.
+		from.GUI.APITokens = to.GUI.APITokens
+	}
 
-	if to.GUI == from.GUI {
+	if reflect.DeepEqual(to.GUI, from.GUI) {
 		return true
 	}
 
@@ -481,7 +579,7 @@ func debugMiddleware(h http.Handler) http.Handler {
 	})
 }
 
-func corsMiddleware(next http.Handler, allowFrameLoading bool) http.Handler {
+func corsMiddleware(next http.Handler, guiCfg config.GUIConfiguration) http.Handler {
 	// Handle CORS headers and CORS OPTIONS request.
 	// CORS OPTIONS request are typically sent by browser during AJAX preflight
 	// when the browser initiate a POST request.
@@ -490,11 +588,21 @@ func corsMiddleware(next http.Handler, allowFrameLoading bool) http.Handler {
 	// of the chain (hence added at the end).
 	//
 	// See https://www.w3.org/TR/cors/ for details.
+	//
+	// When origins are configured, only those can read the responses.
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if guiCfg.IsCORSOriginAllowed(origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+		}
+
 		// Process OPTIONS requests
 		if r.Method == "OPTIONS" {
-			// Add a generous access-control-allow-origin header for CORS requests
-			w.Header().Add("Access-Control-Allow-Origin", "*")
+			if len(guiCfg.CORSAllowedOrigins) == 0 {
+				// Add a generous access-control-allow-origin header for CORS requests
+				w.Header().Add("Access-Control-Allow-Origin", "*")
+			}
 			// Only GET/POST Methods are supported
 			w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
 			// Only these headers can be set
@@ -511,7 +619,7 @@ func corsMiddleware(next http.Handler, allowFrameLoading bool) http.Handler {
 		// Other security related headers that should be present.
 		// https://www.owasp.org/index.php/Security_Headers
 
-		if !allowFrameLoading {
+		if !guiCfg.InsecureAllowFrameLoading {
 			// We don't want to be rendered in an <iframe>,
 			// <frame> or <object>. (Unless we do it ourselves.
 			// This is also an escape hatch for people who serve
@@ -545,11 +653,10 @@ func metricsMiddleware(h http.Handler) http.Handler {
 
 func redirectToHTTPSMiddleware(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.TLS == nil {
-			// Redirect HTTP requests to HTTPS
-			r.URL.Host = r.Host
-			r.URL.Scheme = "https"
-			http.Redirect(w, r, r.URL.String(), http.StatusTemporaryRedirect)
+		if !isHTTPS(r) {
+			// Redirect HTTP requests to HTTPS, to the path as requested
+			// before any prefix was stripped
+			http.Redirect(w, r, "https://"+r.Host+r.RequestURI, http.StatusTemporaryRedirect)
 		} else {
 			h.ServeHTTP(w, r)
 		}
@@ -655,6 +762,39 @@ func (s *service) getDBBrowse(w http.ResponseWriter, r *http.Request) {
 	prefix := qs.Get("prefix")
 	dirsonly := qs.Get("dirsonly") != ""
 
+	// Asking for a page or an ordering gets a listing of the single
+	// directory level at prefix, rather than the whole tree below it. The
+	// returned token gets the next page, from the same database state.
+	if token := qs.Get("token"); token != "" {
+		limit, _ := strconv.Atoi(qs.Get("limit"))
+		page, err := s.model.GlobalDirectoryListContinue(token, limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		sendJSON(w, page)
+		return
+	}
+	if qs.Get("offset") != "" || qs.Get("limit") != "" || qs.Get("sort") != "" {
+		offset, _ := strconv.Atoi(qs.Get("offset"))
+		limit, _ := strconv.Atoi(qs.Get("limit"))
+		sortBy := model.DirectorySort(qs.Get("sort"))
+		switch sortBy {
+		case "", model.DirectorySortName, model.DirectorySortSize, model.DirectorySortModified:
+		default:
+			http.Error(w, "Unknown sort order", http.StatusBadRequest)
+			return
+		}
+
+		page, err := s.model.GlobalDirectoryList(folder, prefix, dirsonly, sortBy, qs.Get("reverse") != "", offset, limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		sendJSON(w, page)
+		return
+	}
+
 	levels, err := strconv.Atoi(qs.Get("levels"))
 	if err != nil {
 		levels = -1
@@ -663,6 +803,26 @@ func (s *service) getDBBrowse(w http.ResponseWriter, r *http.Request) {
 	sendJSON(w, s.model.GlobalDirectoryTree(folder, prefix, levels, dirsonly))
 }
 
+// getDBActivity returns how many changes were scanned and pulled within
+// each directory over the last day, busiest first. Each directory counts
+// the changes anywhere below it.
+func (s *service) getDBActivity(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	folder := qs.Get("folder")
+	prefix := qs.Get("prefix")
+	levels, err := strconv.Atoi(qs.Get("levels"))
+	if err != nil {
+		levels = -1
+	}
+
+	activity, err := s.model.FolderActivity(folder, prefix, levels)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	sendJSON(w, activity)
+}
+
 func (s *service) getDBCompletion(w http.ResponseWriter, r *http.Request) {
 	var qs = r.URL.Query()
 	var folder = qs.Get("folder")
@@ -687,6 +847,19 @@ func (s *service) getDBStatus(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (s *service) getDBSize(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	if sum, err := s.fss.SubtreeSummary(qs.Get("folder"), qs.Get("prefix")); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+	} else {
+		sendJSON(w, sum)
+	}
+}
+
+func (s *service) getDBStatusAll(w http.ResponseWriter, r *http.Request) {
+	sendJSON(w, s.fss.AggregateSummary())
+}
+
 func (s *service) postDBOverride(w http.ResponseWriter, r *http.Request) {
 	var qs = r.URL.Query()
 	var folder = qs.Get("folder")
@@ -699,6 +872,71 @@ func (s *service) postDBRevert(w http.ResponseWriter, r *http.Request) {
 	go s.model.Revert(folder)
 }
 
+// getDBOperation returns the progress of the running revert or override of
+// the folder, or the report of the last one.
+func (s *service) getDBOperation(w http.ResponseWriter, r *http.Request) {
+	report, err := s.model.FolderOperation(r.URL.Query().Get("folder"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	sendJSON(w, report)
+}
+
+// postDBOperationCancel stops the running revert or override of the folder.
+func (s *service) postDBOperationCancel(w http.ResponseWriter, r *http.Request) {
+	if err := s.model.CancelFolderOperation(r.URL.Query().Get("folder")); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+	}
+}
+
+// postDBRetry retries the items that failed to sync in the folder right
+// away, instead of when they are next due.
+func (s *service) postDBRetry(w http.ResponseWriter, r *http.Request) {
+	var qs = r.URL.Query()
+	var folder = qs.Get("folder")
+	if err := s.model.ResetPullBackoff(folder, qs["file"]); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+	}
+}
+
+// postDBUndelete restores files deleted here from the connected devices
+// that still have them, returning the errors by file.
+func (s *service) postDBUndelete(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	ferr, err := s.model.RestoreDeletedFiles(r.Context(), qs.Get("folder"), qs["file"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	sendJSON(w, ferr)
+}
+
+// postDBNormalize brings the names of existing files into the Unicode
+// normalization form configured for the folder, and returns what was done.
+func (s *service) postDBNormalize(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	folder := qs.Get("folder")
+	dryRun := qs.Get("dryrun") != ""
+	fixes, err := s.model.NormalizeFolder(folder, dryRun)
+	if err != nil && fixes == nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	if fixes == nil {
+		fixes = []model.NormalizationFix{}
+	}
+	res := map[string]interface{}{
+		"dryRun": dryRun,
+		"fixes":  fixes,
+	}
+	if err != nil {
+		// The names were fixed, but rescanning them failed.
+		res["error"] = err.Error()
+	}
+	sendJSON(w, res)
+}
+
 func getPagingParams(qs url.Values) (int, int) {
 	page, err := strconv.Atoi(qs.Get("page"))
 	if err != nil || page < 1 {
@@ -720,16 +958,83 @@ func (s *service) getDBNeed(w http.ResponseWriter, r *http.Request) {
 
 	progress, queued, rest := s.model.NeedFolderFiles(folder, page, perpage)
 
-	// Convert the struct to a more loose structure, and inject the size.
+	var names []string
+	for _, fs := range [][]db.FileInfoTruncated{progress, queued, rest} {
+		for _, f := range fs {
+			names = append(names, f.Name)
+		}
+	}
+	reasons := s.model.NeedReasons(folder, names)
+
+	// Convert the struct to a more loose structure, and inject the size
+	// and why each is out of sync.
 	sendJSON(w, map[string]interface{}{
-		"progress": toJsonFileInfoSlice(progress),
-		"queued":   toJsonFileInfoSlice(queued),
-		"rest":     toJsonFileInfoSlice(rest),
+		"progress": toJsonNeedSlice(progress, reasons, model.NeedReason{}),
+		"queued":   toJsonNeedSlice(queued, reasons, model.NeedReason{}),
+		"rest":     toJsonNeedSlice(rest, reasons, model.NeedReason{}),
 		"page":     page,
 		"perpage":  perpage,
 	})
 }
 
+// getDBNeedAll lists what is out of sync in all the folders, grouped by
+// folder with up to perfolder items each, and the totals.
+func (s *service) getDBNeedAll(w http.ResponseWriter, r *http.Request) {
+	perFolder, err := strconv.Atoi(r.URL.Query().Get("perfolder"))
+	if err != nil || perFolder < 1 {
+		perFolder = 100
+	}
+
+	folders := make([]map[string]interface{}, 0)
+	var totalItems int32
+	var totalBytes int64
+	for _, fcfg := range s.cfg.FolderList() {
+		if fcfg.Paused {
+			continue
+		}
+		snap, err := s.model.DBSnapshot(fcfg.ID)
+		if err != nil {
+			continue
+		}
+		need := snap.NeedSize()
+		snap.Release()
+		if fcfg.IgnoreDelete {
+			need.Deleted = 0
+		}
+		if need.TotalItems() == 0 {
+			continue
+		}
+
+		progress, queued, rest := s.model.NeedFolderFiles(fcfg.ID, 1, perFolder)
+		var names []string
+		for _, fs := range [][]db.FileInfoTruncated{progress, queued, rest} {
+			for _, f := range fs {
+				names = append(names, f.Name)
+			}
+		}
+		reasons := s.model.NeedReasons(fcfg.ID, names)
+
+		folders = append(folders, map[string]interface{}{
+			"folder":    fcfg.ID,
+			"label":     fcfg.Label,
+			"needItems": need.TotalItems(),
+			"needBytes": need.Bytes,
+			"progress":  toJsonNeedSlice(progress, reasons, model.NeedReason{}),
+			"queued":    toJsonNeedSlice(queued, reasons, model.NeedReason{}),
+			"rest":      toJsonNeedSlice(rest, reasons, model.NeedReason{}),
+			"truncated": int(need.TotalItems()) > len(names),
+		})
+		totalItems += need.TotalItems()
+		totalBytes += need.Bytes
+	}
+
+	sendJSON(w, map[string]interface{}{
+		"folders":    folders,
+		"totalItems": totalItems,
+		"totalBytes": totalBytes,
+	})
+}
+
 func (s *service) getDBRemoteNeed(w http.ResponseWriter, r *http.Request) {
 	qs := r.URL.Query()
 
@@ -750,13 +1055,26 @@ func (s *service) getDBRemoteNeed(w http.ResponseWriter, r *http.Request) {
 	}
 	defer snap.Release()
 	files := snap.RemoteNeedFolderFiles(deviceID, page, perpage)
+	reason := s.model.RemoteNeedReason(folder, deviceID)
 	sendJSON(w, map[string]interface{}{
-		"files":   toJsonFileInfoSlice(files),
+		"files":   toJsonNeedSlice(files, nil, reason),
 		"page":    page,
 		"perpage": perpage,
 	})
 }
 
+// getDBPullPlan returns what pulling the folder would do right now,
+// without doing it. This shows the impact of resuming a paused folder.
+func (s *service) getDBPullPlan(w http.ResponseWriter, r *http.Request) {
+	folder := r.URL.Query().Get("folder")
+	plan, err := s.model.PullPlan(folder)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	sendJSON(w, plan)
+}
+
 func (s *service) getDBLocalChanged(w http.ResponseWriter, r *http.Request) {
 	qs := r.URL.Query()
 
@@ -783,6 +1101,18 @@ func (s *service) getSystemConnections(w http.ResponseWriter, r *http.Request) {
 	sendJSON(w, s.model.ConnectionStats())
 }
 
+// getSystemConnectionsRejected lists the latest incoming connections that
+// were rejected, and why.
+func (s *service) getSystemConnectionsRejected(w http.ResponseWriter, r *http.Request) {
+	sendJSON(w, s.connectionsService.RejectedConnections())
+}
+
+// getSystemConnectionsOffenders lists the addresses that connected with
+// unknown device IDs, for spotting probes and sending them to the tarpit.
+func (s *service) getSystemConnectionsOffenders(w http.ResponseWriter, r *http.Request) {
+	sendJSON(w, s.connectionsService.TarpitOffenders())
+}
+
 func (s *service) getDeviceStats(w http.ResponseWriter, r *http.Request) {
 	stats, err := s.model.DeviceStatistics()
 	if err != nil {
@@ -792,6 +1122,12 @@ func (s *service) getDeviceStats(w http.ResponseWriter, r *http.Request) {
 	sendJSON(w, stats)
 }
 
+// getDebugEventSubscribers lists the event subscriptions, with how many
+// events each was sent and had to drop for not keeping up.
+func (s *service) getDebugEventSubscribers(w http.ResponseWriter, r *http.Request) {
+	sendJSON(w, s.evLogger.Subscribers())
+}
+
 func (s *service) getFolderStats(w http.ResponseWriter, r *http.Request) {
 	stats, err := s.model.FolderStatistics()
 	if err != nil {
@@ -801,6 +1137,20 @@ func (s *service) getFolderStats(w http.ResponseWriter, r *http.Request) {
 	sendJSON(w, stats)
 }
 
+// getFolderScanStats returns how long the latest scans of the folder took
+// and how fast they went, oldest first.
+func (s *service) getFolderScanStats(w http.ResponseWriter, r *http.Request) {
+	history, err := s.model.FolderScanHistory(r.URL.Query().Get("folder"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if history == nil {
+		history = []stats.ScanRecord{}
+	}
+	sendJSON(w, history)
+}
+
 func (s *service) getDBFile(w http.ResponseWriter, r *http.Request) {
 	qs := r.URL.Query()
 	folder := qs.Get("folder")
@@ -822,7 +1172,279 @@ func (s *service) getDBFile(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// getDBActive returns the block level progress of the files currently being
+// pulled, per folder.
+func (s *service) getDBActive(w http.ResponseWriter, r *http.Request) {
+	folder := r.URL.Query().Get("folder")
+	folders := []string{folder}
+	if folder == "" {
+		folders = folders[:0]
+		for id := range s.cfg.Folders() {
+			folders = append(folders, id)
+		}
+	}
+
+	res := make(map[string]map[string]*model.PullerProgress, len(folders))
+	for _, folder := range folders {
+		res[folder] = s.model.FolderProgress(folder)
+	}
+	sendJSON(w, res)
+}
+
+// getDBProvenance describes who is responsible for the current version of
+// a file, and which version each device sharing the folder has.
+func (s *service) getDBProvenance(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	folder := qs.Get("folder")
+	file := qs.Get("file")
+
+	fcfg, ok := s.cfg.Folder(folder)
+	if !ok {
+		http.Error(w, "No such folder", http.StatusNotFound)
+		return
+	}
+	snap, err := s.model.DBSnapshot(folder)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	defer snap.Release()
+
+	gf, ok := snap.GetGlobal(file)
+	if !ok {
+		http.Error(w, "No such object in the index", http.StatusNotFound)
+		return
+	}
+
+	devices := s.cfg.Devices()
+	versions := make([]map[string]interface{}, 0, len(gf.Version.Counters))
+	for _, c := range gf.Version.Counters {
+		v := shortIDDevice(devices, c.ID)
+		v["counter"] = c.Value
+		versions = append(versions, v)
+	}
+
+	folderDevices := make([]map[string]interface{}, 0, len(fcfg.Devices))
+	for _, dev := range fcfg.Devices {
+		id := dev.DeviceID
+		if id == s.id {
+			id = protocol.LocalDeviceID
+		}
+		d := map[string]interface{}{
+			"deviceID": dev.DeviceID.String(),
+			"name":     devices[dev.DeviceID].Name,
+		}
+		if f, ok := snap.Get(id, file); ok {
+			d["version"] = jsonVersionVector(f.Version)
+			d["modified"] = f.ModTime()
+			d["modifiedBy"] = shortIDDevice(devices, f.ModifiedBy)
+			d["deleted"] = f.IsDeleted()
+			d["upToDate"] = f.Version.Equal(gf.Version)
+		} else {
+			d["upToDate"] = false
+		}
+		folderDevices = append(folderDevices, d)
+	}
+
+	sendJSON(w, map[string]interface{}{
+		"name":       gf.Name,
+		"modified":   gf.ModTime(),
+		"modifiedBy": shortIDDevice(devices, gf.ModifiedBy),
+		"deleted":    gf.IsDeleted(),
+		"versions":   versions,
+		"devices":    folderDevices,
+	})
+}
+
+// getDBFileHistory returns a timeline of the known versions of a file,
+// newest first. It combines the versions the devices sharing the folder
+// currently have with the versions archived by our versioner, which can be
+// restored through /rest/folder/versions.
+func (s *service) getDBFileHistory(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	folder := qs.Get("folder")
+	file := qs.Get("path")
+
+	fcfg, ok := s.cfg.Folder(folder)
+	if !ok {
+		http.Error(w, "No such folder", http.StatusNotFound)
+		return
+	}
+	snap, err := s.model.DBSnapshot(folder)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	defer snap.Release()
+
+	gf, _ := snap.GetGlobal(file)
+	devices := s.cfg.Devices()
+
+	type historyEntry struct {
+		time time.Time
+		data map[string]interface{}
+	}
+	var entries []historyEntry
+
+	// Each distinct version present on a device is one entry, listing the
+	// devices that have it.
+	seen := make(map[string]map[string]interface{})
+	for _, dev := range fcfg.Devices {
+		id := dev.DeviceID
+		if id == s.id {
+			id = protocol.LocalDeviceID
+		}
+		f, ok := snap.Get(id, file)
+		if !ok {
+			continue
+		}
+		key := f.Version.String()
+		if entry, ok := seen[key]; ok {
+			entry["devices"] = append(entry["devices"].([]string), dev.DeviceID.String())
+			continue
+		}
+		entry := map[string]interface{}{
+			"source":     "index",
+			"modified":   f.ModTime(),
+			"modifiedBy": shortIDDevice(devices, f.ModifiedBy),
+			"version":    jsonVersionVector(f.Version),
+			"size":       f.FileSize(),
+			"deleted":    f.IsDeleted(),
+			"current":    f.Version.Equal(gf.Version),
+			"devices":    []string{dev.DeviceID.String()},
+		}
+		seen[key] = entry
+		entries = append(entries, historyEntry{f.ModTime(), entry})
+	}
+
+	if fcfg.Versioning.Enabled() {
+		versions, err := s.model.GetFolderVersions(folder)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, v := range versions[file] {
+			entries = append(entries, historyEntry{v.VersionTime, map[string]interface{}{
+				"source":      "archive",
+				"versionTime": v.VersionTime,
+				"modified":    v.ModTime,
+				"size":        v.Size,
+				"restore": map[string]interface{}{
+					"method": http.MethodPost,
+					"url":    "/rest/folder/versions?folder=" + url.QueryEscape(folder),
+					"body":   map[string]time.Time{file: v.VersionTime},
+				},
+			}})
+		}
+	}
+
+	if len(entries) == 0 {
+		http.Error(w, "No such object in the index or archive", http.StatusNotFound)
+		return
+	}
+
+	sort.SliceStable(entries, func(a, b int) bool {
+		return entries[a].time.After(entries[b].time)
+	})
+	res := make([]map[string]interface{}, len(entries))
+	for i, entry := range entries {
+		res[i] = entry.data
+	}
+	sendJSON(w, res)
+}
+
+// getDBFileContent streams the content of a file as it's known in the
+// index: the global version, the version we have at the given sequence, or
+// the version given as listed by /rest/db/filehistory. Nothing in the folder
+// is changed, so this can fetch versions we don't have locally.
+func (s *service) getDBFileContent(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	folder := qs.Get("folder")
+	file := qs.Get("file")
+
+	fcfg, ok := s.cfg.Folder(folder)
+	if !ok {
+		http.Error(w, "No such folder", http.StatusNotFound)
+		return
+	}
+	snap, err := s.model.DBSnapshot(folder)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	var f protocol.FileInfo
+	switch {
+	case qs.Get("sequence") != "":
+		seq, err := strconv.ParseInt(qs.Get("sequence"), 10, 64)
+		if err != nil {
+			snap.Release()
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f, ok = snap.Get(protocol.LocalDeviceID, file)
+		ok = ok && f.Sequence == seq
+	case qs.Get("version") != "":
+		version := qs.Get("version")
+		ok = false
+		for _, dev := range fcfg.Devices {
+			id := dev.DeviceID
+			if id == s.id {
+				id = protocol.LocalDeviceID
+			}
+			if df, dok := snap.Get(id, file); dok && versionString(df.Version) == version {
+				f, ok = df, true
+				break
+			}
+		}
+	default:
+		f, ok = snap.GetGlobal(file)
+	}
+	snap.Release()
+	if !ok {
+		http.Error(w, "No such version in the index", http.StatusNotFound)
+		return
+	}
+
+	content, err := s.model.FileContent(r.Context(), folder, f)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(f.Size, 10))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(f.Name)))
+	w.Header().Set("Last-Modified", f.ModTime().UTC().Format(http.TimeFormat))
+	if _, err := io.Copy(w, content); err != nil {
+		// Too late to tell the client anything but by the short response.
+		l.Debugf("Streaming content of %s in %s: %v", f.Name, folder, err)
+	}
+}
+
+// shortIDDevice describes the device with the given short ID, by full ID and
+// name if it's one we know.
+func shortIDDevice(devices map[protocol.DeviceID]config.DeviceConfiguration, id protocol.ShortID) map[string]interface{} {
+	res := map[string]interface{}{
+		"shortID": id.String(),
+	}
+	for devID, devCfg := range devices {
+		if devID.Short() == id {
+			res["deviceID"] = devID.String()
+			res["name"] = devCfg.Name
+			break
+		}
+	}
+	return res
+}
+
 func (s *service) getSystemConfig(w http.ResponseWriter, r *http.Request) {
+	// API tokens may read the configuration, but not the keys and
+	// password in it.
+	if key := r.Header.Get("X-API-Key"); key != "" && !s.cfg.GUI().IsMasterAPIKey(key) {
+		sendJSON(w, getRedactedConfig(s))
+		return
+	}
 	sendJSON(w, s.cfg.RawCopy())
 }
 
@@ -838,6 +1460,9 @@ func (s *service) postSystemConfig(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A configuration read with an API token comes back redacted.
+	unredactConfig(&to, s.cfg.RawCopy())
+
 	if to.GUI.Password != s.cfg.GUI().Password {
 		if to.GUI.Password != "" && !bcryptExpr.MatchString(to.GUI.Password) {
 			hash, err := bcrypt.GenerateFromPassword([]byte(to.GUI.Password), 0)
@@ -942,6 +1567,10 @@ func (s *service) getSystemStatus(w http.ResponseWriter, r *http.Request) {
 
 	res["connectionServiceStatus"] = s.connectionsService.ListenerStatus()
 	res["lastDialStatus"] = s.connectionsService.ConnectionStatus()
+	res["natType"] = s.connectionsService.NATType()
+	s.natProbeMut.Lock()
+	res["natProbe"] = s.natProbe
+	s.natProbeMut.Unlock()
 	// cpuUsage.Rate() is in milliseconds per second, so dividing by ten
 	// gives us percent
 	res["cpuPercent"] = s.cpu.Rate() / 10 / float64(runtime.NumCPU())
@@ -955,6 +1584,31 @@ func (s *service) getSystemStatus(w http.ResponseWriter, r *http.Request) {
 	sendJSON(w, res)
 }
 
+// getSystemNATProbe returns the result of the last NAT probe, if any.
+func (s *service) getSystemNATProbe(w http.ResponseWriter, r *http.Request) {
+	s.natProbeMut.Lock()
+	defer s.natProbeMut.Unlock()
+	sendJSON(w, s.natProbe)
+}
+
+// postSystemNATProbe classifies the NAT using the configured STUN servers,
+// which takes a while, and returns the result.
+func (s *service) postSystemNATProbe(w http.ResponseWriter, r *http.Request) {
+	servers := s.cfg.Options().StunServers()
+	if len(servers) == 0 {
+		http.Error(w, "No STUN servers configured", http.StatusConflict)
+		return
+	}
+	s.natProbingMut.Lock()
+	res := stun.Probe(servers)
+	s.natProbingMut.Unlock()
+
+	s.natProbeMut.Lock()
+	s.natProbe = &res
+	s.natProbeMut.Unlock()
+	sendJSON(w, res)
+}
+
 func (s *service) getSystemError(w http.ResponseWriter, r *http.Request) {
 	sendJSON(w, map[string][]logger.Line{
 		"errors": s.guiErrors.Since(time.Time{}),
@@ -1003,8 +1657,12 @@ type fileEntry struct {
 func (s *service) getSupportBundle(w http.ResponseWriter, r *http.Request) {
 	var files []fileEntry
 
+	redactor := newSupportRedactor(s.cfg.RawCopy(), strings.Split(r.URL.Query().Get("redact"), ","))
+
 	// Redacted configuration as a JSON
-	if jsonConfig, err := json.MarshalIndent(getRedactedConfig(s), "", "  "); err != nil {
+	redactedConfig := getRedactedConfig(s)
+	redactor.config(&redactedConfig)
+	if jsonConfig, err := json.MarshalIndent(redactedConfig, "", "  "); err != nil {
 		l.Warnln("Support bundle: failed to create config.json:", err)
 	} else {
 		files = append(files, fileEntry{name: "config.json.txt", data: jsonConfig})
@@ -1026,6 +1684,44 @@ func (s *service) getSupportBundle(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Recent events as a JSON
+	if evs := s.getEventSub(DefaultEventMask).Since(0, nil, 0); len(evs) > 0 {
+		if jsonEvents, err := json.MarshalIndent(evs, "", "  "); err != nil {
+			l.Warnln("Support bundle: failed to create events.json:", err)
+		} else {
+			files = append(files, fileEntry{name: "events.json.txt", data: jsonEvents})
+		}
+	}
+
+	// Folder summaries and database statistics as a JSON
+	summaries := make(map[string]interface{})
+	dbStats := make(map[string]interface{})
+	for id := range s.cfg.Folders() {
+		if summary, err := s.fss.Summary(id); err == nil {
+			summaries[id] = summary
+		}
+		if snap, err := s.model.DBSnapshot(id); err == nil {
+			dbStats[id] = map[string]interface{}{
+				"local":          snap.LocalSize(),
+				"global":         snap.GlobalSize(),
+				"need":           snap.NeedSize(),
+				"localSequence":  snap.Sequence(protocol.LocalDeviceID),
+				"remoteSequence": snap.RemoteSequence(),
+			}
+			snap.Release()
+		}
+	}
+	if jsonSummaries, err := json.MarshalIndent(summaries, "", "  "); err != nil {
+		l.Warnln("Support bundle: failed to create folder-summaries.json:", err)
+	} else {
+		files = append(files, fileEntry{name: "folder-summaries.json.txt", data: jsonSummaries})
+	}
+	if jsonStats, err := json.MarshalIndent(dbStats, "", "  "); err != nil {
+		l.Warnln("Support bundle: failed to create db-stats.json:", err)
+	} else {
+		files = append(files, fileEntry{name: "db-stats.json.txt", data: jsonStats})
+	}
+
 	// Panic files
 	if panicFiles, err := filepath.Glob(filepath.Join(locations.GetBaseDir(locations.ConfigBaseDir), "panic*")); err == nil {
 		for _, f := range panicFiles {
@@ -1063,6 +1759,10 @@ func (s *service) getSupportBundle(w http.ResponseWriter, r *http.Request) {
 		files = append(files, fileEntry{name: "usage-reporting.json.txt", data: usageReportingData})
 	}
 
+	for i := range files {
+		files[i].data = redactor.text(files[i].data)
+	}
+
 	// Heap and CPU Proofs as a pprof extension
 	var heapBuffer, cpuBuffer bytes.Buffer
 	filename := fmt.Sprintf("syncthing-heap-%s-%s-%s-%s.pprof", runtime.GOOS, runtime.GOARCH, build.Version, time.Now().Format("150405")) // hhmmss
@@ -1105,6 +1805,11 @@ func (s *service) getSupportBundle(w http.ResponseWriter, r *http.Request) {
 func (s *service) getSystemHTTPMetrics(w http.ResponseWriter, r *http.Request) {
 	stats := make(map[string]interface{})
 	metrics.Each(func(name string, intf interface{}) {
+		if c, ok := intf.(*metrics.StandardCounter); ok {
+			stats[name] = map[string]interface{}{
+				"count": c.Count(),
+			}
+		}
 		if m, ok := intf.(*metrics.StandardTimer); ok {
 			pct := m.Percentiles([]float64{0.50, 0.95, 0.99})
 			for i := range pct {
@@ -1166,9 +1871,10 @@ func (s *service) getDBIgnores(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sendJSON(w, map[string][]string{
+	sendJSON(w, map[string]interface{}{
 		"ignore":   ignores,
 		"expanded": patterns,
+		"version":  ignore.Version(ignores),
 	})
 }
 
@@ -1198,9 +1904,105 @@ func (s *service) postDBIgnores(w http.ResponseWriter, r *http.Request) {
 	s.getDBIgnores(w, r)
 }
 
+// postDBIgnoresEdit adds and removes the patterns given like
+// {"add": [...], "remove": [...]}, failing with a conflict if the patterns
+// aren't of the given version any more or contradict the ones to add.
+func (s *service) postDBIgnoresEdit(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	var data struct {
+		Add    []string `json:"add"`
+		Remove []string `json:"remove"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.Body.Close()
+
+	res, err := s.model.EditIgnores(qs.Get("folder"), qs.Get("version"), data.Add, data.Remove)
+	if err == model.ErrIgnoresChanged || err == model.ErrIgnoresConflict {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusConflict)
+		sendJSON(w, map[string]interface{}{
+			"error":  err.Error(),
+			"result": res,
+		})
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	sendJSON(w, res)
+}
+
+// postDBIgnoresUnignore removes the ignore patterns given like
+// {"remove": [...]}, and rescans just what they applied to where possible.
+func (s *service) postDBIgnoresUnignore(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	var data struct {
+		Remove []string `json:"remove"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.Body.Close()
+
+	res, err := s.model.Unignore(qs.Get("folder"), qs.Get("version"), data.Remove)
+	if err == model.ErrIgnoresChanged {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusConflict)
+		sendJSON(w, map[string]interface{}{
+			"error":  err.Error(),
+			"result": res,
+		})
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	sendJSON(w, res)
+}
+
+// postFolderIgnoresTest tells which pattern decides whether each path is
+// ignored, given like {"paths": [...], "ignore": [...]}, where the
+// patterns are optional, to test them instead of the current ones.
+func (s *service) postFolderIgnoresTest(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	var data struct {
+		Paths  []string `json:"paths"`
+		Ignore []string `json:"ignore"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.Body.Close()
+
+	res, err := s.model.ExplainIgnores(qs.Get("folder"), data.Ignore, data.Paths)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	sendJSON(w, res)
+}
+
 func (s *service) getIndexEvents(w http.ResponseWriter, r *http.Request) {
 	s.fss.OnEventRequest()
-	mask := s.getEventMask(r.URL.Query().Get("events"))
+	qs := r.URL.Query()
+	mask := s.getEventMask(qs.Get("events"))
+	if persistent, _ := strconv.ParseBool(qs.Get("persistent")); persistent && s.persistentSub != nil {
+		// The persisted event IDs survive restarts, so a client can pick
+		// up where it left off.
+		s.getEvents(w, r, maskedSubscription{s.persistentSub, mask})
+		return
+	}
 	sub := s.getEventSub(mask)
 	s.getEvents(w, r, sub)
 }
@@ -1239,6 +2041,62 @@ func (s *service) getEvents(w http.ResponseWriter, r *http.Request, eventSub eve
 	sendJSON(w, evs)
 }
 
+// getEventsWebSocket upgrades the request to a WebSocket connection on
+// which events are pushed as they happen, one JSON object per message. The
+// "since" and "events" parameters work as for /rest/events.
+func (s *service) getEventsWebSocket(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	since, _ := strconv.Atoi(qs.Get("since"))
+	sub := s.getEventSub(s.getEventMask(qs.Get("events")))
+
+	srv := websocket.Server{
+		// The request has already passed authentication and CSRF checks,
+		// so there is no need for the default origin check.
+		Handler: func(ws *websocket.Conn) {
+			s.streamEvents(ws, sub, since)
+		},
+	}
+	srv.ServeHTTP(w, r)
+}
+
+func (s *service) streamEvents(ws *websocket.Conn, sub events.BufferedSubscription, since int) {
+	defer ws.Close()
+
+	// The read timeout set by the HTTP server still applies to the
+	// connection we have taken over.
+	_ = ws.SetDeadline(time.Time{})
+
+	// We don't expect anything from the client, but reading is how we
+	// notice that it went away.
+	closed := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(ioutil.Discard, ws)
+		close(closed)
+	}()
+
+	var evs []events.Event
+	for {
+		// An open WebSocket is a listener just like a pending long poll,
+		// so summaries should keep being calculated.
+		s.fss.OnEventRequest()
+
+		evs = sub.Since(since, evs[:0], webSocketPollInterval)
+		for _, ev := range evs {
+			if err := websocket.JSON.Send(ws, ev); err != nil {
+				l.Debugln("Sending event on websocket:", err)
+				return
+			}
+			since = ev.SubscriptionID
+		}
+
+		select {
+		case <-closed:
+			return
+		default:
+		}
+	}
+}
+
 func (s *service) getEventMask(evs string) events.EventType {
 	eventMask := DefaultEventMask
 	if evs != "" {
@@ -1251,6 +2109,32 @@ func (s *service) getEventMask(evs string) events.EventType {
 	return eventMask
 }
 
+// maskedSubscription returns only the events matching the mask from a
+// subscription that may contain other types of events too.
+type maskedSubscription struct {
+	events.BufferedSubscription
+	mask events.EventType
+}
+
+func (s maskedSubscription) Since(id int, into []events.Event, timeout time.Duration) []events.Event {
+	deadline := time.Now().Add(timeout)
+	var evs []events.Event
+	for {
+		evs = s.BufferedSubscription.Since(id, evs[:0], time.Until(deadline))
+		n := len(into)
+		for _, ev := range evs {
+			if ev.Type&s.mask != 0 {
+				into = append(into, ev)
+			}
+		}
+		if len(evs) == 0 || len(into) > n {
+			return into
+		}
+		// Nothing interesting yet; keep waiting for what comes next.
+		id = evs[len(evs)-1].SubscriptionID
+	}
+}
+
 func (s *service) getEventSub(mask events.EventType) events.BufferedSubscription {
 	s.eventSubsMut.Lock()
 	bufsub, ok := s.eventSubs[mask]
@@ -1392,6 +2276,33 @@ func (s *service) postDBScan(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// postFolderMaintenance puts the folder in maintenance, or with
+// enabled=false takes it out of it again, which rescans it.
+func (s *service) postFolderMaintenance(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	enabled := true
+	if str := qs.Get("enabled"); str != "" {
+		var err error
+		if enabled, err = strconv.ParseBool(str); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if err := s.model.SetFolderMaintenance(qs.Get("folder"), enabled); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// postFolderPullResume renews the pull budget of the folder, resuming a
+// pull that stopped at its checkpoint.
+func (s *service) postFolderPullResume(w http.ResponseWriter, r *http.Request) {
+	if err := s.model.ResumeFolderPull(r.URL.Query().Get("folder")); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
 func (s *service) postDBPrio(w http.ResponseWriter, r *http.Request) {
 	qs := r.URL.Query()
 	folder := qs.Get("folder")
@@ -1400,6 +2311,48 @@ func (s *service) postDBPrio(w http.ResponseWriter, r *http.Request) {
 	s.getDBNeed(w, r)
 }
 
+// postDBPin pins a file or directory to the front of the pull queue of the
+// folder, or unpins it. Pins are kept in the folder configuration.
+func (s *service) postDBPin(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	folder := qs.Get("folder")
+	file := filepath.Clean(filepath.FromSlash(strings.Trim(qs.Get("file"), "/")))
+	unpin := qs.Get("unpin") == "true"
+
+	fcfg, ok := s.cfg.Folder(folder)
+	if !ok {
+		http.Error(w, "No such folder", http.StatusNotFound)
+		return
+	}
+	if file == "." {
+		http.Error(w, "No file given", http.StatusBadRequest)
+		return
+	}
+
+	pinned := make([]string, 0, len(fcfg.PinnedPaths)+1)
+	for _, path := range fcfg.PinnedPaths {
+		if path != file {
+			pinned = append(pinned, path)
+		}
+	}
+	if !unpin {
+		pinned = append(pinned, file)
+	}
+	fcfg.PinnedPaths = pinned
+
+	waiter, err := s.cfg.SetFolder(fcfg)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	waiter.Wait()
+	if err := s.cfg.Save(); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	sendJSON(w, pinned)
+}
+
 func (s *service) getQR(w http.ResponseWriter, r *http.Request) {
 	var qs = r.URL.Query()
 	var text = qs.Get("text")
@@ -1437,6 +2390,18 @@ func (s *service) getPeerCompletion(w http.ResponseWriter, r *http.Request) {
 	sendJSON(w, comp)
 }
 
+// getFolderIgnoresUsage tells how many files and bytes on disk are kept
+// from being synced by each of the ignore patterns of the folder.
+func (s *service) getFolderIgnoresUsage(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	usage, err := s.model.IgnoredUsage(qs.Get("folder"))
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+	sendJSON(w, usage)
+}
+
 func (s *service) getFolderVersions(w http.ResponseWriter, r *http.Request) {
 	qs := r.URL.Query()
 	versions, err := s.model.GetFolderVersions(qs.Get("folder"))
@@ -1444,6 +2409,15 @@ func (s *service) getFolderVersions(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), 500)
 		return
 	}
+	if file := qs.Get("file"); file != "" {
+		// Just the versions of the one file
+		fileVersions := versions[file]
+		if fileVersions == nil {
+			fileVersions = []versioner.FileVersion{}
+		}
+		sendJSON(w, fileVersions)
+		return
+	}
 	sendJSON(w, versions)
 }
 
@@ -1616,6 +2590,20 @@ func toJsonFileInfoSlice(fs []db.FileInfoTruncated) []jsonFileInfoTrunc {
 	return res
 }
 
+// toJsonNeedSlice is like toJsonFileInfoSlice, with the reasons the files
+// are out of sync, or else the common one.
+func toJsonNeedSlice(fs []db.FileInfoTruncated, reasons map[string]model.NeedReason, common model.NeedReason) []jsonNeedFile {
+	res := make([]jsonNeedFile, len(fs))
+	for i, f := range fs {
+		reason, ok := reasons[f.Name]
+		if !ok {
+			reason = common
+		}
+		res[i] = jsonNeedFile{f, reason}
+	}
+	return res
+}
+
 // Type wrappers for nice JSON serialization
 
 type jsonFileInfo protocol.FileInfo
@@ -1634,6 +2622,23 @@ func (f jsonFileInfoTrunc) MarshalJSON() ([]byte, error) {
 	return json.Marshal(m)
 }
 
+type jsonNeedFile struct {
+	file   db.FileInfoTruncated
+	reason model.NeedReason
+}
+
+func (f jsonNeedFile) MarshalJSON() ([]byte, error) {
+	m := fileIntfJSONMap(f.file)
+	m["numBlocks"] = nil // explicitly unknown
+	if f.reason.Reason != "" {
+		m["reason"] = f.reason.Reason
+	}
+	if f.reason.Error != "" {
+		m["error"] = f.reason.Error
+	}
+	return json.Marshal(m)
+}
+
 func fileIntfJSONMap(f db.FileIntf) map[string]interface{} {
 	out := map[string]interface{}{
 		"name":          f.FileName(),
@@ -1659,11 +2664,21 @@ func fileIntfJSONMap(f db.FileIntf) map[string]interface{} {
 type jsonVersionVector protocol.Vector
 
 func (v jsonVersionVector) MarshalJSON() ([]byte, error) {
+	return json.Marshal(versionCounters(protocol.Vector(v)))
+}
+
+func versionCounters(v protocol.Vector) []string {
 	res := make([]string, len(v.Counters))
 	for i, c := range v.Counters {
 		res[i] = fmt.Sprintf("%v:%d", c.ID, c.Value)
 	}
-	return json.Marshal(res)
+	return res
+}
+
+// versionString formats the version as accepted in query parameters: the
+// counters as in JSON, separated by commas.
+func versionString(v protocol.Vector) string {
+	return strings.Join(versionCounters(v), ",")
 }
 
 func dirNames(dir string) []string {
@@ -7,8 +7,11 @@
 package api
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/syncthing/syncthing/lib/config"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -44,3 +47,45 @@ func TestStaticAuthPasswordFail(t *testing.T) {
 		t.Fatalf("should fail auth")
 	}
 }
+
+func TestAPITokenScopes(t *testing.T) {
+	guiCfg := config.GUIConfiguration{
+		APIKey: "main",
+		APITokens: []config.APIToken{
+			{Key: "monitoring", Scopes: []config.APIScope{config.APIScopeStatus, config.APIScopeEvents}},
+		},
+	}
+	handler := apiTokenMiddleware(guiCfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	cases := []struct {
+		method, path, key string
+		code              int
+	}{
+		{"GET", "/rest/system/status", "monitoring", http.StatusOK},
+		{"GET", "/rest/events", "monitoring", http.StatusOK},
+		{"GET", "/rest/events/disk", "monitoring", http.StatusOK},
+		{"GET", "/rest/system/config", "monitoring", http.StatusForbidden},
+		{"POST", "/rest/system/config", "monitoring", http.StatusForbidden},
+		{"POST", "/rest/db/scan", "monitoring", http.StatusForbidden},
+		{"GET", "/rest/debug/cpuprof", "monitoring", http.StatusForbidden},
+		{"GET", "/rest/db/file-content", "monitoring", http.StatusForbidden},
+		{"GET", "/rest/folder/versions/file", "monitoring", http.StatusForbidden},
+		{"GET", "/rest/folder/versions/diff", "monitoring", http.StatusForbidden},
+		{"GET", "/rest/system/browse", "monitoring", http.StatusForbidden},
+		{"GET", "/rest/nonexistent", "monitoring", http.StatusForbidden},
+		{"GET", "/rest/db/status", "monitoring", http.StatusOK},
+		{"POST", "/rest/system/config", "main", http.StatusOK},
+		// Others are left to the usual authentication.
+		{"POST", "/rest/system/config", "unknown", http.StatusOK},
+		{"POST", "/rest/system/config", "", http.StatusOK},
+	}
+	for _, tc := range cases {
+		r := httptest.NewRequest(tc.method, tc.path, nil)
+		r.Header.Set("X-API-Key", tc.key)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		if rec.Code != tc.code {
+			t.Errorf("%s %s with key %q: got %d, expected %d", tc.method, tc.path, tc.key, rec.Code, tc.code)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/syncthing/syncthing/lib/events"
+)
+
+const (
+	batchPause    = "pause"
+	batchResume   = "resume"
+	batchRescan   = "rescan"
+	batchOverride = "override"
+	batchRevert   = "revert"
+)
+
+var (
+	errBatchUnknownAction = errors.New("unknown action")
+	errBatchNoSuchFolder  = errors.New("no such folder")
+)
+
+// A batchOperation is one action applied to one or more folders.
+type batchOperation struct {
+	Action  string   `json:"action"`
+	Folders []string `json:"folders"`
+	Subs    []string `json:"subs"` // for rescan, optional
+}
+
+type batchResult struct {
+	Action string  `json:"action"`
+	Folder string  `json:"folder"`
+	Error  *string `json:"error"`
+}
+
+// postBatch performs several folder operations in one request. Pausing and
+// resuming are gathered into a single configuration change, which is
+// committed before any of the other operations run, so that resumed folders
+// are running by then.
+func (s *service) postBatch(w http.ResponseWriter, r *http.Request) {
+	var ops []batchOperation
+	if err := json.NewDecoder(r.Body).Decode(&ops); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	r.Body.Close()
+
+	cfg := s.cfg.RawCopy()
+	folderIdx := make(map[string]int, len(cfg.Folders))
+	for i, fcfg := range cfg.Folders {
+		folderIdx[fcfg.ID] = i
+	}
+
+	var results []batchResult
+	var pauseResults []int // indexes into results
+	changed := false
+	for _, op := range ops {
+		for _, folder := range op.Folders {
+			res := batchResult{Action: op.Action, Folder: folder}
+			idx, ok := folderIdx[folder]
+			switch {
+			case !ok:
+				res.Error = events.Error(errBatchNoSuchFolder)
+			case op.Action == batchPause || op.Action == batchResume:
+				paused := op.Action == batchPause
+				if cfg.Folders[idx].Paused != paused {
+					cfg.Folders[idx].Paused = paused
+					changed = true
+				}
+				pauseResults = append(pauseResults, len(results))
+			case op.Action != batchRescan && op.Action != batchOverride && op.Action != batchRevert:
+				res.Error = events.Error(errBatchUnknownAction)
+			}
+			results = append(results, res)
+		}
+	}
+
+	if changed {
+		if waiter, err := s.cfg.Replace(cfg); err != nil {
+			for _, i := range pauseResults {
+				results[i].Error = events.Error(err)
+			}
+		} else {
+			waiter.Wait()
+		}
+	}
+
+	i := 0
+	for _, op := range ops {
+		for range op.Folders {
+			res := &results[i]
+			i++
+			if res.Error != nil {
+				continue
+			}
+			switch op.Action {
+			case batchRescan:
+				if err := s.model.ScanFolderSubdirs(res.Folder, op.Subs); err != nil {
+					res.Error = events.Error(err)
+				}
+			case batchOverride:
+				go s.model.Override(res.Folder)
+			case batchRevert:
+				go s.model.Revert(res.Folder)
+			}
+		}
+	}
+
+	sendJSON(w, results)
+}
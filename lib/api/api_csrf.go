@@ -90,8 +90,7 @@ func (m *csrfManager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Verify the CSRF token
-	token := r.Header.Get("X-CSRF-Token-" + m.unique)
-	if !m.validToken(token) {
+	if m.requestToken(r) == "" {
 		http.Error(w, "CSRF Error", http.StatusForbidden)
 		return
 	}
@@ -99,6 +98,25 @@ func (m *csrfManager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	m.next.ServeHTTP(w, r)
 }
 
+// requestToken returns the CSRF token of the request, or an empty string if
+// it doesn't have a valid one.
+func (m *csrfManager) requestToken(r *http.Request) string {
+	token := r.Header.Get("X-CSRF-Token-" + m.unique)
+	if token == "" && isWebSocketUpgrade(r) {
+		// Browsers can't set headers on WebSocket requests, so the token
+		// is accepted as a query parameter instead.
+		token = r.URL.Query().Get("csrf")
+	}
+	if !m.validToken(token) {
+		return ""
+	}
+	return token
+}
+
+func isWebSocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
 func (m *csrfManager) validToken(token string) bool {
 	m.tokensMut.Lock()
 	defer m.tokensMut.Unlock()
@@ -0,0 +1,286 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+//go:generate go run ../../script/protofmt.go management.proto
+//go:generate protoc -I ../../ -I . --gogofast_out=plugins=grpc:. management.proto
+
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+const (
+	grpcContentType    = "application/grpc"
+	grpcMaxMessageSize = 4 << 20
+	grpcEventsPoll     = time.Second
+)
+
+// The API token scopes required by the management methods.
+var grpcScopes = map[string]config.APIScope{
+	"/api.Management/Config":       config.APIScopeConfigRead,
+	"/api.Management/SetPaused":    config.APIScopeControl,
+	"/api.Management/FolderStatus": config.APIScopeStatus,
+	"/api.Management/Scan":         config.APIScopeControl,
+	"/api.Management/Events":       config.APIScopeEvents,
+}
+
+// newGRPCServer returns the gRPC server for the management service, which
+// checks the API key of every call.
+func (s *service) newGRPCServer() *grpc.Server {
+	srv := grpc.NewServer(
+		grpc.MaxRecvMsgSize(grpcMaxMessageSize),
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			if err := s.grpcAuthorize(ctx, info.FullMethod); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			if err := s.grpcAuthorize(ss.Context(), info.FullMethod); err != nil {
+				return err
+			}
+			return handler(srv, ss)
+		}),
+	)
+	RegisterManagementServer(srv, &managementServer{s})
+	return srv
+}
+
+// grpcAuthorize checks that the API key passed as the x-api-key metadata
+// of the call is valid, and allows the method.
+func (s *service) grpcAuthorize(ctx context.Context, method string) error {
+	var apiKey string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if keys := md.Get("x-api-key"); len(keys) > 0 {
+			apiKey = keys[0]
+		}
+	}
+	guiCfg := s.cfg.GUI()
+	if !guiCfg.IsValidAPIKey(apiKey) {
+		return status.Error(codes.Unauthenticated, "missing or invalid API key")
+	}
+	if scope, ok := grpcScopes[method]; ok && !guiCfg.APIKeyAllows(apiKey, scope) {
+		return status.Errorf(codes.PermissionDenied, "API token not valid for %s", method)
+	}
+	return nil
+}
+
+// grpcMiddleware passes gRPC calls to the given server, and all other
+// requests to the next handler.
+func grpcMiddleware(srv *grpc.Server, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isGRPCRequest(r) {
+			srv.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isGRPCRequest(r *http.Request) bool {
+	return r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), grpcContentType)
+}
+
+// managementServer implements the management service on top of the API
+// service.
+type managementServer struct {
+	*service
+}
+
+func (s *managementServer) Config(context.Context, *ConfigRequest) (*ConfigResponse, error) {
+	cfg := s.cfg.RawCopy()
+	resp := &ConfigResponse{
+		Folders: make([]FolderConfig, 0, len(cfg.Folders)),
+		Devices: make([]DeviceConfig, 0, len(cfg.Devices)),
+	}
+	for _, fcfg := range cfg.Folders {
+		folder := FolderConfig{
+			ID:     fcfg.ID,
+			Label:  fcfg.Label,
+			Path:   fcfg.Path,
+			Type:   fcfg.Type.String(),
+			Paused: fcfg.Paused,
+		}
+		for _, dev := range fcfg.Devices {
+			folder.DeviceIDs = append(folder.DeviceIDs, dev.DeviceID.String())
+		}
+		resp.Folders = append(resp.Folders, folder)
+	}
+	for _, dcfg := range cfg.Devices {
+		resp.Devices = append(resp.Devices, DeviceConfig{
+			ID:        dcfg.DeviceID.String(),
+			Name:      dcfg.Name,
+			Addresses: dcfg.Addresses,
+			Paused:    dcfg.Paused,
+		})
+	}
+	return resp, nil
+}
+
+func (s *managementServer) SetPaused(_ context.Context, req *SetPausedRequest) (*SetPausedResponse, error) {
+	var waiter config.Waiter
+	var err error
+	switch {
+	case req.Folder != "" && req.Device == "":
+		fcfg, ok := s.cfg.Folder(req.Folder)
+		if !ok {
+			return nil, status.Errorf(codes.NotFound, "no such folder %q", req.Folder)
+		}
+		fcfg.Paused = req.Paused
+		waiter, err = s.cfg.SetFolder(fcfg)
+	case req.Device != "" && req.Folder == "":
+		id, idErr := protocol.DeviceIDFromString(req.Device)
+		if idErr != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "device: %v", idErr)
+		}
+		dcfg, ok := s.cfg.Device(id)
+		if !ok {
+			return nil, status.Errorf(codes.NotFound, "no such device %v", id)
+		}
+		dcfg.Paused = req.Paused
+		waiter, err = s.cfg.SetDevice(dcfg)
+	default:
+		return nil, status.Error(codes.InvalidArgument, "exactly one of folder and device must be given")
+	}
+	if err != nil {
+		return nil, err
+	}
+	waiter.Wait()
+	if err := s.cfg.Save(); err != nil {
+		return nil, err
+	}
+	return &SetPausedResponse{}, nil
+}
+
+func (s *managementServer) FolderStatus(_ context.Context, req *FolderStatusRequest) (*FolderStatusResponse, error) {
+	sum, err := s.fss.Summary(req.Folder)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "%v", err)
+	}
+	resp := &FolderStatusResponse{
+		Sequence:    summaryInt(sum["sequence"]),
+		GlobalFiles: summaryInt(sum["globalFiles"]),
+		GlobalBytes: summaryInt(sum["globalBytes"]),
+		LocalFiles:  summaryInt(sum["localFiles"]),
+		LocalBytes:  summaryInt(sum["localBytes"]),
+		NeedFiles:   summaryInt(sum["needFiles"]),
+		NeedBytes:   summaryInt(sum["needBytes"]),
+	}
+	resp.State, _ = sum["state"].(string)
+	resp.Error, _ = sum["error"].(string)
+	return resp, nil
+}
+
+// summaryInt returns the value of a numeric folder summary field.
+func summaryInt(v interface{}) int64 {
+	switch v := v.(type) {
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	}
+	return 0
+}
+
+func (s *managementServer) Scan(_ context.Context, req *ScanRequest) (*ScanResponse, error) {
+	if _, ok := s.cfg.Folder(req.Folder); !ok {
+		return nil, status.Errorf(codes.NotFound, "no such folder %q", req.Folder)
+	}
+	if err := s.model.ScanFolderSubdirs(req.Folder, req.Subs); err != nil {
+		return nil, err
+	}
+	return &ScanResponse{}, nil
+}
+
+// Events streams events until the client goes away. Requests can keep
+// arriving from the client meanwhile, each replacing the previous one.
+func (s *managementServer) Events(stream Management_EventsServer) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	ctx := stream.Context()
+	reqs := make(chan *EventsRequest)
+	go func() {
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				// The client is done sending, but may still be receiving.
+				return
+			}
+			select {
+			case reqs <- req:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var evs []events.Event
+	for {
+		mask := DefaultEventMask
+		if len(req.Types) > 0 {
+			mask = 0
+			for _, typ := range req.Types {
+				t := events.UnmarshalEventType(typ)
+				if t == 0 {
+					return status.Errorf(codes.InvalidArgument, "unknown event type %q", typ)
+				}
+				mask |= t
+			}
+		}
+		sub := s.getEventSub(mask)
+		since := int(req.Since)
+
+	stream:
+		for {
+			s.fss.OnEventRequest()
+
+			evs = sub.Since(since, evs[:0], grpcEventsPoll)
+			for _, ev := range evs {
+				data, err := json.Marshal(ev.Data)
+				if err != nil {
+					return err
+				}
+				msg := &EventMessage{
+					ID:   int64(ev.SubscriptionID),
+					Type: ev.Type.String(),
+					Time: ev.Time.UnixNano(),
+					Data: data,
+				}
+				if err := stream.Send(msg); err != nil {
+					return err
+				}
+				since = ev.SubscriptionID
+			}
+
+			select {
+			case req = <-reqs:
+				break stream
+			case <-ctx.Done():
+				return nil
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+//go:generate go run ../../script/genopenapi.go -o openapi_gen.go .
+
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/syncthing/syncthing/lib/build"
+)
+
+// restOperation describes an endpoint of the REST API, as generated from
+// its registration in serve().
+type restOperation struct {
+	Method      string
+	Path        string
+	Summary     string
+	Description string
+	Params      []restParam
+	Body        bool // takes a JSON request body
+	Deprecated  bool
+}
+
+// restParam is a query parameter of a REST endpoint.
+type restParam struct {
+	Name     string
+	Required bool
+	Multi    bool // may be given several times
+}
+
+// operationID returns the OpenAPI operation ID, as the handlers may serve
+// several endpoints: "get" and "/rest/db/file-content" give
+// "getDbFileContent".
+func (o restOperation) operationID() string {
+	id := o.Method
+	for _, word := range strings.FieldsFunc(strings.TrimPrefix(o.Path, "/rest/"), func(r rune) bool {
+		return r == '/' || r == '-' || r == '.'
+	}) {
+		id += strings.ToUpper(word[:1]) + word[1:]
+	}
+	return id
+}
+
+func (o restOperation) openAPI() map[string]interface{} {
+	op := map[string]interface{}{
+		"operationId": o.operationID(),
+		"tags":        []string{strings.SplitN(strings.TrimPrefix(o.Path, "/rest/"), "/", 2)[0]},
+		"responses": map[string]interface{}{
+			"200": map[string]interface{}{"description": "Success"},
+		},
+	}
+	if o.Summary != "" {
+		op["summary"] = o.Summary
+	}
+	if o.Description != "" {
+		op["description"] = o.Description
+	}
+	if o.Deprecated {
+		op["deprecated"] = true
+	}
+	if len(o.Params) > 0 {
+		params := make([]map[string]interface{}, len(o.Params))
+		for i, p := range o.Params {
+			schema := map[string]interface{}{"type": "string"}
+			if p.Multi {
+				schema = map[string]interface{}{"type": "array", "items": schema}
+			}
+			params[i] = map[string]interface{}{
+				"name":     p.Name,
+				"in":       "query",
+				"required": p.Required,
+				"schema":   schema,
+			}
+		}
+		op["parameters"] = params
+	}
+	if o.Body {
+		op["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{
+					"schema": map[string]interface{}{},
+				},
+			},
+		}
+	}
+	return op
+}
+
+// openAPIDocument returns the OpenAPI 3 description of the REST API.
+func openAPIDocument() map[string]interface{} {
+	paths := make(map[string]map[string]interface{})
+	for _, o := range restOperations {
+		if paths[o.Path] == nil {
+			paths[o.Path] = make(map[string]interface{})
+		}
+		paths[o.Path][o.Method] = o.openAPI()
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.2",
+		"info": map[string]interface{}{
+			"title":   "Syncthing REST API",
+			"version": build.Version,
+			"license": map[string]interface{}{
+				"name": "MPL-2.0",
+				"url":  "https://mozilla.org/MPL/2.0/",
+			},
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"securitySchemes": map[string]interface{}{
+				"apiKey": map[string]interface{}{
+					"type": "apiKey",
+					"in":   "header",
+					"name": "X-API-Key",
+				},
+			},
+		},
+		"security": []map[string][]string{
+			{"apiKey": {}},
+		},
+	}
+}
+
+// getOpenAPI serves the OpenAPI 3 description of the REST API, for
+// generating clients.
+func (s *service) getOpenAPI(w http.ResponseWriter, r *http.Request) {
+	sendJSON(w, openAPIDocument())
+}
@@ -0,0 +1,99 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/syncthing/syncthing/lib/config"
+)
+
+type forwardedHTTPSKey struct{}
+
+// forwardedMiddleware takes the client address and protocol from the
+// X-Forwarded-For and X-Forwarded-Proto headers of requests coming through
+// a trusted reverse proxy. The headers of other requests are ignored, as
+// anyone could have set them.
+func forwardedMiddleware(guiCfg config.GUIConfiguration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !guiCfg.IsTrustedProxy(remoteIP(r)) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if client := forwardedClient(guiCfg, r.Header["X-Forwarded-For"]); client != nil {
+			r.RemoteAddr = net.JoinHostPort(client.String(), "0")
+		}
+
+		proto := r.Header.Get("X-Forwarded-Proto")
+		if i := strings.IndexByte(proto, ','); i >= 0 {
+			// The protocol the first proxy was reached by
+			proto = proto[:i]
+		}
+		if strings.EqualFold(strings.TrimSpace(proto), "https") {
+			r = r.WithContext(context.WithValue(r.Context(), forwardedHTTPSKey{}, true))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// forwardedClient returns the client address from X-Forwarded-For headers.
+// Each proxy appends the address it got the request from, so that's the
+// last address that isn't one of our trusted proxies.
+func forwardedClient(guiCfg config.GUIConfiguration, headers []string) net.IP {
+	var addrs []string
+	for _, header := range headers {
+		addrs = append(addrs, strings.Split(header, ",")...)
+	}
+	var client net.IP
+	for i := len(addrs) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(addrs[i]))
+		if ip == nil {
+			break
+		}
+		client = ip
+		if !guiCfg.IsTrustedProxy(ip) {
+			break
+		}
+	}
+	return client
+}
+
+func remoteIP(r *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+// isHTTPS returns whether the client made the request over HTTPS, to us or
+// to a trusted reverse proxy.
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || r.Context().Value(forwardedHTTPSKey{}) != nil
+}
+
+// pathPrefixMiddleware serves the GUI and API under a path prefix, for
+// sharing a reverse proxy with other services. Requests outside of it are
+// not found.
+func pathPrefixMiddleware(prefix string, next http.Handler) http.Handler {
+	strip := http.StripPrefix(prefix, next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == prefix:
+			http.Redirect(w, r, prefix+"/", http.StatusMovedPermanently)
+		case strings.HasPrefix(r.URL.Path, prefix+"/"):
+			strip.ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
@@ -0,0 +1,140 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package api
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	metrics "github.com/rcrowley/go-metrics"
+	"golang.org/x/time/rate"
+
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+const (
+	// Requests over the limit that can be served within this time are
+	// held until then, instead of being refused.
+	rateLimitMaxWait = time.Second
+	// Clients not seen for this long are forgotten.
+	rateLimitIdleTime = 10 * time.Minute
+)
+
+// A clientRateLimiter limits the rate of requests to the REST API per
+// client, so that a single misbehaving client can't keep the GUI and
+// everything else busy. Clients are identified by their API key or GUI
+// session, or by their address when they have neither.
+type clientRateLimiter struct {
+	limit rate.Limit
+	burst int
+
+	mut       sync.Mutex
+	clients   map[string]*clientRate
+	lastPrune time.Time
+}
+
+type clientRate struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func newClientRateLimiter(perS, burst int) *clientRateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &clientRateLimiter{
+		limit:     rate.Limit(perS),
+		burst:     burst,
+		mut:       sync.NewMutex(),
+		clients:   make(map[string]*clientRate),
+		lastPrune: time.Now(),
+	}
+}
+
+// reserve returns how long the client has to wait before making its
+// request, and false if that's longer than it's allowed to wait.
+func (l *clientRateLimiter) reserve(client string, now time.Time) (time.Duration, bool) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	if now.Sub(l.lastPrune) > time.Minute {
+		for id, c := range l.clients {
+			if now.Sub(c.lastSeen) > rateLimitIdleTime {
+				delete(l.clients, id)
+			}
+		}
+		l.lastPrune = now
+	}
+
+	c, ok := l.clients[client]
+	if !ok {
+		c = &clientRate{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[client] = c
+	}
+	c.lastSeen = now
+
+	res := c.limiter.ReserveN(now, 1)
+	delay := res.DelayFrom(now)
+	if delay > rateLimitMaxWait {
+		res.CancelAt(now)
+		return delay, false
+	}
+	return delay, true
+}
+
+func rateLimitClient(validator apiKeyValidator, csrf *csrfManager, r *http.Request) string {
+	// Only valid keys and tokens count, or new random ones could be made up
+	// to get around the limit.
+	if key := r.Header.Get("X-API-Key"); validator.IsValidAPIKey(key) {
+		return "key:" + key
+	}
+	// Each browser session of the GUI has its own CSRF token.
+	if token := csrf.requestToken(r); token != "" {
+		return "csrf:" + token
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "addr:" + host
+}
+
+// rateLimitMiddleware holds or refuses REST and gRPC requests from clients
+// over their rate. Refused requests get a 429 with the time to retry after,
+// and are counted in the HTTP metrics.
+func rateLimitMiddleware(limiter *clientRateLimiter, validator apiKeyValidator, csrf *csrfManager, next http.Handler) http.Handler {
+	throttled := metrics.GetOrRegisterCounter("throttled", nil)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/rest/") && !isGRPCRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		delay, ok := limiter.reserve(rateLimitClient(validator, csrf, r), time.Now())
+		if !ok {
+			throttled.Inc(1)
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+		if delay > 0 {
+			t := time.NewTimer(delay)
+			select {
+			case <-t.C:
+			case <-r.Context().Done():
+				t.Stop()
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
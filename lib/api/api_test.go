@@ -9,13 +9,19 @@ package api
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"go/ast"
+	"go/parser"
+	gotoken "go/token"
 	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -26,6 +32,8 @@ import (
 
 	"github.com/d4l3k/messagediff"
 	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/db/backend"
 	"github.com/syncthing/syncthing/lib/events"
 	"github.com/syncthing/syncthing/lib/fs"
 	"github.com/syncthing/syncthing/lib/locations"
@@ -33,7 +41,14 @@ import (
 	"github.com/syncthing/syncthing/lib/sync"
 	"github.com/syncthing/syncthing/lib/tlsutil"
 	"github.com/syncthing/syncthing/lib/ur"
+	"github.com/syncthing/syncthing/lib/versioner"
 	"github.com/thejerf/suture"
+	"golang.org/x/net/websocket"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
 )
 
 var (
@@ -107,7 +122,7 @@ func TestStopAfterBrokenConfig(t *testing.T) {
 	}
 	w := config.Wrap("/dev/null", cfg, events.NoopLogger)
 
-	srv := New(protocol.LocalDeviceID, w, "", "syncthing", nil, nil, nil, events.NoopLogger, nil, nil, nil, nil, nil, nil, nil, nil, false).(*service)
+	srv := New(protocol.LocalDeviceID, w, "", "syncthing", nil, nil, nil, nil, events.NoopLogger, nil, nil, nil, nil, nil, nil, nil, nil, false).(*service)
 	defer os.Remove(token)
 	srv.started = make(chan string)
 
@@ -274,6 +289,18 @@ func TestAPIServiceRequests(t *testing.T) {
 			Type:   "application/json",
 			Prefix: "{",
 		},
+		{
+			URL:    "/rest/db/active",
+			Code:   200,
+			Type:   "application/json",
+			Prefix: "{",
+		},
+		{
+			URL:    "/rest/db/status-all",
+			Code:   200,
+			Type:   "application/json",
+			Prefix: "{",
+		},
 		{
 			URL:    "/rest/db/browse?folder=default",
 			Code:   200,
@@ -389,6 +416,13 @@ func TestAPIServiceRequests(t *testing.T) {
 			Type:   "text/plain",
 			Prefix: "",
 		},
+		{
+			// Nothing probed yet
+			URL:    "/rest/system/natprobe",
+			Code:   200,
+			Type:   "application/json",
+			Prefix: "null",
+		},
 	}
 
 	for _, tc := range cases {
@@ -527,7 +561,7 @@ func startHTTP(cfg *mockedConfig) (string, *suture.Supervisor, error) {
 
 	// Instantiate the API service
 	urService := ur.New(cfg, m, connections, false)
-	svc := New(protocol.LocalDeviceID, cfg, assetDir, "syncthing", m, eventSub, diskEventSub, events.NoopLogger, discoverer, connections, urService, &mockedFolderSummaryService{}, errorLog, systemLog, cpu, nil, false).(*service)
+	svc := New(protocol.LocalDeviceID, cfg, assetDir, "syncthing", m, eventSub, diskEventSub, nil, events.NoopLogger, discoverer, connections, urService, &mockedFolderSummaryService{}, errorLog, systemLog, cpu, nil, false).(*service)
 	defer os.Remove(token)
 	svc.started = addrChan
 
@@ -1026,7 +1060,7 @@ func TestEventMasks(t *testing.T) {
 	cfg := new(mockedConfig)
 	defSub := new(mockedEventSub)
 	diskSub := new(mockedEventSub)
-	svc := New(protocol.LocalDeviceID, cfg, "", "syncthing", nil, defSub, diskSub, events.NoopLogger, nil, nil, nil, nil, nil, nil, nil, nil, false).(*service)
+	svc := New(protocol.LocalDeviceID, cfg, "", "syncthing", nil, defSub, diskSub, nil, events.NoopLogger, nil, nil, nil, nil, nil, nil, nil, nil, false).(*service)
 	defer os.Remove(token)
 
 	if mask := svc.getEventMask(""); mask != DefaultEventMask {
@@ -1054,6 +1088,198 @@ func TestEventMasks(t *testing.T) {
 	}
 }
 
+func TestEventsWebSocket(t *testing.T) {
+	t.Parallel()
+
+	evLogger := events.NewLogger()
+	go evLogger.Serve()
+	defer evLogger.Stop()
+
+	cfg := new(mockedConfig)
+	svc := New(protocol.LocalDeviceID, cfg, "", "syncthing", nil, new(mockedEventSub), new(mockedEventSub), nil, evLogger, nil, nil, nil, &mockedFolderSummaryService{}, nil, nil, nil, nil, false).(*service)
+	defer os.Remove(token)
+
+	srv := httptest.NewServer(http.HandlerFunc(svc.getEventsWebSocket))
+	defer srv.Close()
+
+	// Make sure the subscription exists before the event is logged.
+	svc.getEventSub(events.FolderSummary)
+	evLogger.Log(events.FolderSummary, map[string]string{"folder": "default"})
+
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/rest/events/ws?events=FolderSummary", "", srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	_ = ws.SetReadDeadline(time.Now().Add(10 * time.Second))
+
+	var ev struct {
+		ID   int
+		Type string
+		Data map[string]string
+	}
+	if err := websocket.JSON.Receive(ws, &ev); err != nil {
+		t.Fatal(err)
+	}
+	if ev.ID != 1 || ev.Type != "FolderSummary" || ev.Data["folder"] != "default" {
+		t.Errorf("unexpected event %+v", ev)
+	}
+}
+
+// provenanceModel serves database snapshots from a real file set.
+type provenanceModel struct {
+	mockedModel
+	fset *db.FileSet
+}
+
+func (m *provenanceModel) DBSnapshot(_ string) (*db.Snapshot, error) {
+	return m.fset.Snapshot(), nil
+}
+
+func TestDBProvenance(t *testing.T) {
+	t.Parallel()
+
+	myID := protocol.LocalDeviceID
+	remote, err := protocol.DeviceIDFromString("AIR6LPZ7K4PTTUXQSMUUCPQ5YWOEDFIIQJUG7772YQXXR5YD6AWQ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rawCfg := config.New(myID)
+	rawCfg.Devices = append(rawCfg.Devices, config.NewDeviceConfiguration(remote, "remote"))
+	fcfg := config.NewFolderConfiguration(myID, "default", "default", fs.FilesystemTypeFake, "provenance")
+	fcfg.Devices = append(fcfg.Devices, config.FolderDeviceConfiguration{DeviceID: remote})
+	rawCfg.Folders = []config.FolderConfiguration{fcfg}
+	cfg := config.Wrap("/dev/null", rawCfg, events.NoopLogger)
+
+	fset := db.NewFileSet("default", fcfg.Filesystem(), db.NewLowlevel(backend.OpenMemory()))
+	v1 := protocol.Vector{}.Update(myID.Short())
+	v2 := v1.Update(remote.Short())
+	fset.Update(protocol.LocalDeviceID, []protocol.FileInfo{{Name: "file", Version: v1, ModifiedBy: myID.Short(), Sequence: 1}})
+	fset.Update(remote, []protocol.FileInfo{{Name: "file", Version: v2, ModifiedBy: remote.Short(), Sequence: 1}})
+
+	svc := New(myID, cfg, "", "syncthing", &provenanceModel{fset: fset}, nil, nil, nil, events.NoopLogger, nil, nil, nil, nil, nil, nil, nil, nil, false).(*service)
+	defer os.Remove(token)
+
+	rec := httptest.NewRecorder()
+	svc.getDBProvenance(rec, httptest.NewRequest("GET", "/rest/db/provenance?folder=default&file=file", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatal("Unexpected status", rec.Code, rec.Body.String())
+	}
+
+	var res struct {
+		ModifiedBy struct{ Name string }
+		Versions   []struct {
+			Name    string
+			Counter int
+		}
+		Devices []struct {
+			Name     string
+			UpToDate bool
+		}
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.ModifiedBy.Name != "remote" {
+		t.Errorf("Global version modified by %q, expected remote", res.ModifiedBy.Name)
+	}
+	if len(res.Versions) != 2 {
+		t.Fatalf("Expected two version counters, got %v", res.Versions)
+	}
+	for _, dev := range res.Devices {
+		if exp := dev.Name == "remote"; dev.UpToDate != exp {
+			t.Errorf("Device %q up to date is %v, expected %v", dev.Name, dev.UpToDate, exp)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	svc.getDBProvenance(rec, httptest.NewRequest("GET", "/rest/db/provenance?folder=default&file=missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Error("Expected not found for missing file, got", rec.Code)
+	}
+}
+
+// historyModel adds archived versions to a provenanceModel.
+type historyModel struct {
+	provenanceModel
+	versions map[string][]versioner.FileVersion
+}
+
+func (m *historyModel) GetFolderVersions(_ string) (map[string][]versioner.FileVersion, error) {
+	return m.versions, nil
+}
+
+func TestDBFileHistory(t *testing.T) {
+	t.Parallel()
+
+	myID := protocol.LocalDeviceID
+	remote, err := protocol.DeviceIDFromString("AIR6LPZ7K4PTTUXQSMUUCPQ5YWOEDFIIQJUG7772YQXXR5YD6AWQ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rawCfg := config.New(myID)
+	rawCfg.Devices = append(rawCfg.Devices, config.NewDeviceConfiguration(remote, "remote"))
+	fcfg := config.NewFolderConfiguration(myID, "default", "default", fs.FilesystemTypeFake, "filehistory")
+	fcfg.Devices = append(fcfg.Devices, config.FolderDeviceConfiguration{DeviceID: remote})
+	fcfg.Versioning.Type = "simple"
+	rawCfg.Folders = []config.FolderConfiguration{fcfg}
+	cfg := config.Wrap("/dev/null", rawCfg, events.NoopLogger)
+
+	now := time.Now().Truncate(time.Second)
+	fset := db.NewFileSet("default", fcfg.Filesystem(), db.NewLowlevel(backend.OpenMemory()))
+	v1 := protocol.Vector{}.Update(myID.Short())
+	v2 := v1.Update(remote.Short())
+	fset.Update(protocol.LocalDeviceID, []protocol.FileInfo{{Name: "file", Version: v1, ModifiedS: now.Add(-time.Hour).Unix(), Sequence: 1}})
+	fset.Update(remote, []protocol.FileInfo{{Name: "file", Version: v2, ModifiedS: now.Unix(), Sequence: 1}})
+
+	m := &historyModel{
+		provenanceModel: provenanceModel{fset: fset},
+		versions: map[string][]versioner.FileVersion{
+			"file": {{VersionTime: now.Add(-30 * time.Minute), ModTime: now.Add(-2 * time.Hour)}},
+		},
+	}
+	svc := New(myID, cfg, "", "syncthing", m, nil, nil, nil, events.NoopLogger, nil, nil, nil, nil, nil, nil, nil, nil, false).(*service)
+	defer os.Remove(token)
+
+	rec := httptest.NewRecorder()
+	svc.getDBFileHistory(rec, httptest.NewRequest("GET", "/rest/db/filehistory?folder=default&path=file", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatal("Unexpected status", rec.Code, rec.Body.String())
+	}
+
+	var res []struct {
+		Source  string
+		Current bool
+		Restore struct {
+			URL  string
+			Body map[string]time.Time
+		}
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 3 {
+		t.Fatalf("Expected three history entries, got %+v", res)
+	}
+	if res[0].Source != "index" || !res[0].Current {
+		t.Errorf("Expected the current global version first, got %+v", res[0])
+	}
+	if res[1].Source != "archive" || res[1].Restore.URL != "/rest/folder/versions?folder=default" || res[1].Restore.Body["file"].IsZero() {
+		t.Errorf("Expected the archived version second, got %+v", res[1])
+	}
+	if res[2].Source != "index" || res[2].Current {
+		t.Errorf("Expected the outdated local version last, got %+v", res[2])
+	}
+
+	rec = httptest.NewRecorder()
+	svc.getDBFileHistory(rec, httptest.NewRequest("GET", "/rest/db/filehistory?folder=default&path=missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Error("Expected not found for missing file, got", rec.Code)
+	}
+}
+
 func TestBrowse(t *testing.T) {
 	t.Parallel()
 
@@ -1200,3 +1426,654 @@ func runningInContainer() bool {
 	}
 	return false
 }
+
+// batchModel records the folders it was asked to scan.
+type batchModel struct {
+	mockedModel
+	mut     sync.Mutex
+	scanned []string
+}
+
+func (m *batchModel) ScanFolderSubdirs(folder string, subs []string) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.scanned = append(m.scanned, folder)
+	return nil
+}
+
+func TestPostBatch(t *testing.T) {
+	t.Parallel()
+
+	rawCfg := config.New(protocol.LocalDeviceID)
+	for _, id := range []string{"a", "b", "c"} {
+		rawCfg.Folders = append(rawCfg.Folders, config.NewFolderConfiguration(protocol.LocalDeviceID, id, id, fs.FilesystemTypeFake, id))
+	}
+	cfg := config.Wrap("/dev/null", rawCfg, events.NoopLogger)
+	m := &batchModel{mut: sync.NewMutex()}
+
+	svc := New(protocol.LocalDeviceID, cfg, "", "syncthing", m, nil, nil, nil, events.NoopLogger, nil, nil, nil, nil, nil, nil, nil, nil, false).(*service)
+	defer os.Remove(token)
+
+	body := `[
+		{"action": "pause", "folders": ["a", "b"]},
+		{"action": "rescan", "folders": ["c", "missing"]},
+		{"action": "explode", "folders": ["c"]}
+	]`
+	rec := httptest.NewRecorder()
+	svc.postBatch(rec, httptest.NewRequest("POST", "/rest/batch", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatal("Unexpected status", rec.Code, rec.Body.String())
+	}
+
+	var results []batchResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 5 {
+		t.Fatalf("Expected five results, got %v", results)
+	}
+	for i, expErr := range []bool{false, false, false, true, true} {
+		if (results[i].Error != nil) != expErr {
+			t.Errorf("Result %d (%s %s) has error %v, expected error: %v", i, results[i].Action, results[i].Folder, results[i].Error, expErr)
+		}
+	}
+
+	for id, exp := range map[string]bool{"a": true, "b": true, "c": false} {
+		if fcfg, _ := cfg.Folder(id); fcfg.Paused != exp {
+			t.Errorf("Folder %s paused is %v, expected %v", id, fcfg.Paused, exp)
+		}
+	}
+	if len(m.scanned) != 1 || m.scanned[0] != "c" {
+		t.Errorf("Expected only c to be scanned, got %v", m.scanned)
+	}
+}
+
+// startingModel runs the unpaused folders only once a configuration change
+// has been committed, like the real model does.
+type startingModel struct {
+	batchModel
+	running map[string]bool
+}
+
+func (m *startingModel) VerifyConfiguration(from, to config.Configuration) error {
+	return nil
+}
+
+func (m *startingModel) CommitConfiguration(from, to config.Configuration) bool {
+	time.Sleep(10 * time.Millisecond)
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	for _, fcfg := range to.Folders {
+		m.running[fcfg.ID] = !fcfg.Paused
+	}
+	return true
+}
+
+func (m *startingModel) String() string {
+	return "startingModel"
+}
+
+func (m *startingModel) ScanFolderSubdirs(folder string, subs []string) error {
+	m.mut.Lock()
+	running := m.running[folder]
+	m.mut.Unlock()
+	if !running {
+		return errors.New("folder is not running")
+	}
+	return m.batchModel.ScanFolderSubdirs(folder, subs)
+}
+
+func TestPostBatchResumeRescan(t *testing.T) {
+	t.Parallel()
+
+	rawCfg := config.New(protocol.LocalDeviceID)
+	fcfg := config.NewFolderConfiguration(protocol.LocalDeviceID, "a", "a", fs.FilesystemTypeFake, "a")
+	fcfg.Paused = true
+	rawCfg.Folders = append(rawCfg.Folders, fcfg)
+	cfg := config.Wrap("/dev/null", rawCfg, events.NoopLogger)
+	m := &startingModel{batchModel: batchModel{mut: sync.NewMutex()}, running: make(map[string]bool)}
+	cfg.Subscribe(m)
+
+	svc := New(protocol.LocalDeviceID, cfg, "", "syncthing", m, nil, nil, nil, events.NoopLogger, nil, nil, nil, nil, nil, nil, nil, nil, false).(*service)
+	defer os.Remove(token)
+
+	body := `[
+		{"action": "resume", "folders": ["a"]},
+		{"action": "rescan", "folders": ["a"]}
+	]`
+	rec := httptest.NewRecorder()
+	svc.postBatch(rec, httptest.NewRequest("POST", "/rest/batch", strings.NewReader(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatal("Unexpected status", rec.Code, rec.Body.String())
+	}
+
+	var results []batchResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+		t.Fatal(err)
+	}
+	for _, res := range results {
+		if res.Error != nil {
+			t.Errorf("Unexpected error on %s: %s", res.Action, *res.Error)
+		}
+	}
+	if len(m.scanned) != 1 || m.scanned[0] != "a" {
+		t.Errorf("Expected a to be scanned, got %v", m.scanned)
+	}
+}
+
+type fileContentModel struct {
+	provenanceModel
+}
+
+func (m *fileContentModel) FileContent(_ context.Context, _ string, file protocol.FileInfo) (io.Reader, error) {
+	return strings.NewReader(file.Name + " " + file.Version.String()), nil
+}
+
+func TestDBFileContent(t *testing.T) {
+	t.Parallel()
+
+	myID := protocol.LocalDeviceID
+	remote, err := protocol.DeviceIDFromString("AIR6LPZ7K4PTTUXQSMUUCPQ5YWOEDFIIQJUG7772YQXXR5YD6AWQ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rawCfg := config.New(myID)
+	rawCfg.Devices = append(rawCfg.Devices, config.NewDeviceConfiguration(remote, "remote"))
+	fcfg := config.NewFolderConfiguration(myID, "default", "default", fs.FilesystemTypeFake, "filecontent")
+	fcfg.Devices = append(fcfg.Devices, config.FolderDeviceConfiguration{DeviceID: remote})
+	rawCfg.Folders = []config.FolderConfiguration{fcfg}
+	cfg := config.Wrap("/dev/null", rawCfg, events.NoopLogger)
+
+	fset := db.NewFileSet("default", fcfg.Filesystem(), db.NewLowlevel(backend.OpenMemory()))
+	v1 := protocol.Vector{}.Update(myID.Short())
+	v2 := v1.Update(remote.Short())
+	fset.Update(protocol.LocalDeviceID, []protocol.FileInfo{{Name: "file", Version: v1, Sequence: 1}})
+	fset.Update(remote, []protocol.FileInfo{{Name: "file", Version: v2, Sequence: 1}})
+
+	m := &fileContentModel{provenanceModel{fset: fset}}
+	svc := New(myID, cfg, "", "syncthing", m, nil, nil, nil, events.NoopLogger, nil, nil, nil, nil, nil, nil, nil, nil, false).(*service)
+	defer os.Remove(token)
+
+	cases := []struct {
+		query   string
+		code    int
+		version protocol.Vector
+	}{
+		{"", http.StatusOK, v2},
+		{"&sequence=1", http.StatusOK, v1},
+		{"&sequence=2", http.StatusNotFound, protocol.Vector{}},
+		{"&version=" + url.QueryEscape(versionString(v1)), http.StatusOK, v1},
+		{"&version=" + url.QueryEscape(versionString(v2)), http.StatusOK, v2},
+		{"&version=nonexistent", http.StatusNotFound, protocol.Vector{}},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		svc.getDBFileContent(rec, httptest.NewRequest("GET", "/rest/db/file-content?folder=default&file=file"+tc.query, nil))
+		if rec.Code != tc.code {
+			t.Errorf("%q: unexpected status %d", tc.query, rec.Code)
+			continue
+		}
+		if tc.code == http.StatusOK && rec.Body.String() != "file "+tc.version.String() {
+			t.Errorf("%q: got %q, expected version %v", tc.query, rec.Body.String(), tc.version)
+		}
+	}
+}
+
+func TestGRPCManagement(t *testing.T) {
+	t.Parallel()
+
+	rawCfg := config.New(protocol.LocalDeviceID)
+	rawCfg.GUI.APIKey = "abc123"
+	rawCfg.Folders = []config.FolderConfiguration{config.NewFolderConfiguration(protocol.LocalDeviceID, "default", "default", fs.FilesystemTypeFake, "grpc")}
+	// SetPaused saves the config.
+	tmpDir, err := ioutil.TempDir("", "syncthing-grpc-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	cfg := config.Wrap(filepath.Join(tmpDir, "config.xml"), rawCfg, events.NoopLogger)
+	svc := New(protocol.LocalDeviceID, cfg, "", "syncthing", &mockedModel{}, nil, nil, nil, events.NoopLogger, nil, nil, nil, nil, nil, nil, nil, nil, false).(*service)
+	defer os.Remove(token)
+
+	srv := svc.newGRPCServer()
+	defer srv.Stop()
+	lis := bufconn.Listen(1 << 20)
+	go srv.Serve(lis)
+
+	conn, err := grpc.Dial("bufconn", grpc.WithInsecure(), grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+		return lis.Dial()
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := NewManagementClient(conn)
+
+	withKey := func(apiKey string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", apiKey)
+	}
+	ctx := withKey("abc123")
+
+	if _, err := client.Config(withKey("wrong"), &ConfigRequest{}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Unexpected error %v for wrong API key", err)
+	}
+
+	conf, err := client.Config(ctx, &ConfigRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(conf.Folders) != 1 || conf.Folders[0].ID != "default" || conf.Folders[0].Paused {
+		t.Errorf("Unexpected folders %+v", conf.Folders)
+	}
+
+	if _, err := client.SetPaused(ctx, &SetPausedRequest{Folder: "default", Paused: true}); err != nil {
+		t.Fatal(err)
+	}
+	if fcfg, _ := cfg.Folder("default"); !fcfg.Paused {
+		t.Error("Folder not paused")
+	}
+
+	if _, err := client.SetPaused(ctx, &SetPausedRequest{Paused: true}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Unexpected error %v for missing folder and device", err)
+	}
+	if _, err := client.Scan(ctx, &ScanRequest{Folder: "nonexistent"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Unexpected error %v for unknown folder", err)
+	}
+	if err := conn.Invoke(ctx, "/api.Management/Nonexistent", &ConfigRequest{}, &ConfigResponse{}); status.Code(err) != codes.Unimplemented {
+		t.Errorf("Unexpected error %v for unknown method", err)
+	}
+}
+
+func TestGetSystemConfigRedacted(t *testing.T) {
+	t.Parallel()
+
+	rawCfg := config.New(protocol.LocalDeviceID)
+	rawCfg.GUI.APIKey = "main"
+	rawCfg.GUI.Password = "$2a$10$hash"
+	rawCfg.GUI.APITokens = []config.APIToken{
+		{Name: "reader", Key: "reader-key", Scopes: []config.APIScope{config.APIScopeConfigRead}},
+	}
+	cfg := config.Wrap("/dev/null", rawCfg, events.NoopLogger)
+	svc := New(protocol.LocalDeviceID, cfg, "", "syncthing", &mockedModel{}, nil, nil, nil, events.NoopLogger, nil, nil, nil, nil, nil, nil, nil, nil, false).(*service)
+	defer os.Remove(token)
+
+	get := func(key string) config.Configuration {
+		t.Helper()
+		r := httptest.NewRequest("GET", "/rest/system/config", nil)
+		r.Header.Set("X-API-Key", key)
+		rec := httptest.NewRecorder()
+		svc.getSystemConfig(rec, r)
+		var got config.Configuration
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	got := get("reader-key")
+	if got.GUI.APIKey == "main" || got.GUI.Password == rawCfg.GUI.Password || got.GUI.APITokens[0].Key == "reader-key" {
+		t.Errorf("Secrets visible with an API token: %+v", got.GUI)
+	}
+	unredactConfig(&got, cfg.RawCopy())
+	if got.GUI.APIKey != "main" || got.GUI.Password != rawCfg.GUI.Password || got.GUI.APITokens[0].Key != "reader-key" {
+		t.Errorf("Secrets not restored from the current config: %+v", got.GUI)
+	}
+
+	if got := get("main"); got.GUI.APIKey != "main" || got.GUI.APITokens[0].Key != "reader-key" {
+		t.Errorf("Secrets not visible with the main API key: %+v", got.GUI)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	t.Parallel()
+
+	guiCfg := config.GUIConfiguration{APIKey: "abc123"}
+	limiter := newClientRateLimiter(1, 2)
+	csrf := newCsrfManager("unique", "/rest", guiCfg, nil, "")
+	handler := rateLimitMiddleware(limiter, guiCfg, csrf, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	request := func(path, addr, key string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		r.RemoteAddr = addr
+		r.Header.Set("X-API-Key", key)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		return rec
+	}
+
+	// The burst is served right away.
+	for i := 0; i < 2; i++ {
+		if rec := request("/rest/db/status", "192.0.2.1:1234", "abc123"); rec.Code != http.StatusOK {
+			t.Fatalf("Request %d: unexpected status %d", i, rec.Code)
+		}
+	}
+
+	// The next is held until the rate allows it.
+	t0 := time.Now()
+	if rec := request("/rest/db/status", "192.0.2.1:1234", "abc123"); rec.Code != http.StatusOK {
+		t.Fatalf("Unexpected status %d", rec.Code)
+	} else if d := time.Since(t0); d < 500*time.Millisecond {
+		t.Errorf("Request was served after %v, expected it to be held", d)
+	}
+
+	// Further requests would have to wait too long and are refused.
+	limiter.reserve("key:abc123", time.Now())
+	rec := request("/rest/db/status", "192.0.2.1:1234", "abc123")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Unexpected status %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("Missing Retry-After")
+	}
+
+	// gRPC calls are limited the same.
+	r := httptest.NewRequest("POST", "/syncthing.Management/Config", nil)
+	r.ProtoMajor = 2
+	r.RemoteAddr = "192.0.2.1:1234"
+	r.Header.Set("X-API-Key", "abc123")
+	r.Header.Set("Content-Type", grpcContentType)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Unexpected status %d for a gRPC call", rec.Code)
+	}
+
+	// Other clients, and requests outside of the API, are unaffected. An
+	// invalid API key counts as no API key.
+	if rec := request("/rest/db/status", "192.0.2.1:1234", "invalid"); rec.Code != http.StatusOK {
+		t.Errorf("Unexpected status %d for another client", rec.Code)
+	}
+	if rec := request("/index.html", "192.0.2.1:1234", "abc123"); rec.Code != http.StatusOK {
+		t.Errorf("Unexpected status %d outside of the API", rec.Code)
+	}
+}
+
+func TestRateLimitSessions(t *testing.T) {
+	t.Parallel()
+
+	guiCfg := config.GUIConfiguration{APIKey: "abc123"}
+	limiter := newClientRateLimiter(1, 1)
+	csrf := newCsrfManager("unique", "/rest", guiCfg, nil, "")
+	handler := rateLimitMiddleware(limiter, guiCfg, csrf, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	request := func(csrfToken string) int {
+		r := httptest.NewRequest("GET", "/rest/system/status", nil)
+		r.RemoteAddr = "127.0.0.1:1234"
+		if csrfToken != "" {
+			r.Header.Set("X-CSRF-Token-unique", csrfToken)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		return rec.Code
+	}
+
+	// Two GUI sessions and a script without an API key, all on the same
+	// address. The first session uses up its rate.
+	gui1, gui2 := csrf.newToken(), csrf.newToken()
+	for i := 0; i < 3; i++ {
+		limiter.reserve("csrf:"+gui1, time.Now())
+	}
+	if code := request(gui1); code != http.StatusTooManyRequests {
+		t.Fatalf("Unexpected status %d for the throttled session", code)
+	}
+
+	if code := request(gui2); code != http.StatusOK {
+		t.Errorf("Unexpected status %d for another session", code)
+	}
+	if code := request(""); code != http.StatusOK {
+		t.Errorf("Unexpected status %d for a client without a session", code)
+	}
+	// Made up tokens don't make for a new session.
+	limiter.reserve("addr:127.0.0.1", time.Now())
+	limiter.reserve("addr:127.0.0.1", time.Now())
+	if code := request("made-up"); code != http.StatusTooManyRequests {
+		t.Errorf("Unexpected status %d for an invalid CSRF token", code)
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	// The document must cover exactly the endpoints registered in serve(),
+	// or it needs to be regenerated.
+	fset := gotoken.NewFileSet()
+	f, err := parser.ParseFile(fset, "api.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registered := make(map[string]bool)
+	ast.Inspect(f, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) != 2 {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || sel.Sel.Name != "HandleFunc" {
+			return true
+		}
+		mux, ok := sel.X.(*ast.Ident)
+		if !ok || (mux.Name != "getRestMux" && mux.Name != "postRestMux") {
+			return true
+		}
+		path, _ := strconv.Unquote(call.Args[0].(*ast.BasicLit).Value)
+		registered[strings.TrimSuffix(mux.Name, "RestMux")+" "+path] = true
+		return true
+	})
+	documented := make(map[string]bool)
+	for _, o := range restOperations {
+		documented[o.Method+" "+o.Path] = true
+	}
+	if diff, equal := messagediff.PrettyDiff(registered, documented); !equal {
+		t.Errorf("The OpenAPI operations are out of date, run go generate:\n%s", diff)
+	}
+
+	svc := New(protocol.LocalDeviceID, config.Wrap("/dev/null", config.New(protocol.LocalDeviceID), events.NoopLogger), "", "syncthing", new(mockedModel), nil, nil, nil, events.NoopLogger, nil, nil, nil, nil, nil, nil, nil, nil, false).(*service)
+	defer os.Remove(token)
+
+	rec := httptest.NewRecorder()
+	svc.getOpenAPI(rec, httptest.NewRequest("GET", "/rest/openapi.json", nil))
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]map[string]struct {
+			OperationID string `json:"operationId"`
+			Parameters  []struct {
+				Name     string `json:"name"`
+				Required bool   `json:"required"`
+			} `json:"parameters"`
+			RequestBody *struct{} `json:"requestBody"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Errorf("Unexpected OpenAPI version %q", doc.OpenAPI)
+	}
+
+	op := doc.Paths["/rest/db/need"]["get"]
+	if op.OperationID != "getDbNeed" {
+		t.Errorf("Unexpected operation ID %q", op.OperationID)
+	}
+	if len(op.Parameters) != 3 || op.Parameters[0].Name != "folder" || !op.Parameters[0].Required || op.Parameters[1].Required {
+		t.Errorf("Unexpected parameters %+v", op.Parameters)
+	}
+	if doc.Paths["/rest/system/config"]["post"].RequestBody == nil {
+		t.Error("Missing request body")
+	}
+
+	ids := make(map[string]bool)
+	for _, ops := range doc.Paths {
+		for _, op := range ops {
+			if ids[op.OperationID] {
+				t.Errorf("Duplicate operation ID %q", op.OperationID)
+			}
+			ids[op.OperationID] = true
+		}
+	}
+}
+
+func TestCORSAllowedOrigins(t *testing.T) {
+	t.Parallel()
+
+	guiCfg := config.GUIConfiguration{CORSAllowedOrigins: []string{"https://app.example.com"}}
+	handler := corsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), guiCfg)
+
+	request := func(method, origin string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/rest/system/status", nil)
+		r.Header.Set("Origin", origin)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		return rec
+	}
+
+	for _, method := range []string{"OPTIONS", "GET"} {
+		if rec := request(method, "https://app.example.com"); rec.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+			t.Errorf("%s: allowed origin got %q", method, rec.Header().Get("Access-Control-Allow-Origin"))
+		}
+		if rec := request(method, "https://evil.example.com"); rec.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Errorf("%s: other origin got %q", method, rec.Header().Get("Access-Control-Allow-Origin"))
+		}
+	}
+
+	// Without configured origins, preflights are allowed from anywhere as
+	// before.
+	handler = corsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), config.GUIConfiguration{})
+	if rec := request("OPTIONS", "https://evil.example.com"); rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("Preflight got %q", rec.Header().Get("Access-Control-Allow-Origin"))
+	}
+}
+
+func TestForwardedMiddleware(t *testing.T) {
+	t.Parallel()
+
+	guiCfg := config.GUIConfiguration{TrustedProxies: []string{"10.0.0.0/8", "192.0.2.1"}}
+	var remoteAddr string
+	var https bool
+	handler := forwardedMiddleware(guiCfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remoteAddr = r.RemoteAddr
+		https = isHTTPS(r)
+	}))
+
+	cases := []struct {
+		remote, forwardedFor, proto string
+		expectedAddr                string
+		expectedHTTPS               bool
+	}{
+		// Through a trusted proxy, and a chain of them
+		{"192.0.2.1:1234", "198.51.100.7", "https", "198.51.100.7:0", true},
+		{"10.1.2.3:1234", "198.51.100.7, 10.0.0.1", "http", "198.51.100.7:0", false},
+		// The client can't pretend to be someone else by adding to the
+		// header its proxy appends to
+		{"10.1.2.3:1234", "203.0.113.9, 198.51.100.7", "", "198.51.100.7:0", false},
+		// Not from a trusted proxy
+		{"198.51.100.7:1234", "203.0.113.9", "https", "198.51.100.7:1234", false},
+	}
+	for _, tc := range cases {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tc.remote
+		r.Header.Set("X-Forwarded-For", tc.forwardedFor)
+		r.Header.Set("X-Forwarded-Proto", tc.proto)
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+		if remoteAddr != tc.expectedAddr || https != tc.expectedHTTPS {
+			t.Errorf("%+v: got %s, https %v", tc, remoteAddr, https)
+		}
+	}
+}
+
+func TestPathPrefixMiddleware(t *testing.T) {
+	t.Parallel()
+
+	var path string
+	handler := pathPrefixMiddleware("/syncthing", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+	}))
+
+	request := func(p string) *httptest.ResponseRecorder {
+		path = ""
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", p, nil))
+		return rec
+	}
+
+	if rec := request("/syncthing/rest/system/status"); rec.Code != http.StatusOK || path != "/rest/system/status" {
+		t.Errorf("Got status %d, path %q", rec.Code, path)
+	}
+	if rec := request("/syncthing"); rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/syncthing/" {
+		t.Errorf("Got status %d, location %q", rec.Code, rec.Header().Get("Location"))
+	}
+	for _, p := range []string{"/rest/system/status", "/syncthingx/"} {
+		if rec := request(p); rec.Code != http.StatusNotFound || path != "" {
+			t.Errorf("%s: got status %d, path %q", p, rec.Code, path)
+		}
+	}
+}
+
+func TestSupportRedactor(t *testing.T) {
+	cfg := config.Configuration{
+		Folders: []config.FolderConfiguration{
+			{ID: "default", Label: "Holiday pictures", Path: `C:\Users\jane\Pictures`},
+		},
+		Devices: []config.DeviceConfiguration{
+			{DeviceID: protocol.LocalDeviceID, Name: "janes-laptop", Addresses: []string{"dynamic", "tcp://192.0.2.42:22000"}},
+		},
+	}
+	text := []byte(`Ready to synchronize "Holiday pictures" (default) at "C:\\Users\\jane\\Pictures" with janes-laptop at tcp://192.0.2.42:22000`)
+
+	r := newSupportRedactor(cfg, []string{""})
+	if res := r.text(text); !bytes.Equal(res, text) {
+		t.Error("Expected nothing to be redacted by default, got", string(res))
+	}
+
+	r = newSupportRedactor(cfg, []string{"paths", "devices"})
+	expected := `Ready to synchronize "<label of default>" (default) at "<path of default>" with <name of 7777777> at <address of 7777777>`
+	if res := string(r.text(text)); res != expected {
+		t.Errorf("Got %q, expected %q", res, expected)
+	}
+
+	r.config(&cfg)
+	if cfg.Folders[0].Path != "REDACTED" || cfg.Folders[0].Label != "REDACTED" || cfg.Devices[0].Name != "REDACTED" {
+		t.Error("Expected the configuration to be redacted, got", cfg)
+	}
+	if addrs := cfg.Devices[0].Addresses; addrs[0] != "dynamic" || addrs[1] != "REDACTED" {
+		t.Error("Expected the static address to be redacted, got", addrs)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	cases := []struct {
+		a, b     []string
+		expected string
+	}{
+		{nil, nil, ""},
+		{[]string{"a\n"}, []string{"a\n"}, " a"},
+		{nil, []string{"a\n", "b\n"}, "+a+b"},
+		{[]string{"a\n", "b\n"}, nil, "-a-b"},
+		{[]string{"a\n", "b\n", "c\n"}, []string{"a\n", "c\n"}, " a-b c"},
+		{[]string{"a\n", "c\n"}, []string{"a\n", "b\n", "c\n", "d\n"}, " a+b c+d"},
+		{[]string{"a\n", "b\n", "c\n", "a\n", "b\n", "b\n", "a\n"}, []string{"c\n", "b\n", "a\n", "b\n", "a\n", "c\n"}, "-a-b c+b a b-b a+c"},
+	}
+	ops := map[string]string{diffEqual: " ", diffDelete: "-", diffInsert: "+"}
+	for _, tc := range cases {
+		lines, err := diffLines(tc.a, tc.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got strings.Builder
+		for _, l := range lines {
+			got.WriteString(ops[l.Op] + strings.TrimSuffix(l.Text, "\n"))
+		}
+		if got.String() != tc.expected {
+			t.Errorf("diff of %q and %q: got %q, expected %q", tc.a, tc.b, got.String(), tc.expected)
+		}
+	}
+
+	// Too different to bother
+	a := make([]string, maxDiffEdits+1)
+	b := make([]string, maxDiffEdits+1)
+	for i := range a {
+		a[i] = fmt.Sprintln("a", i)
+		b[i] = fmt.Sprintln("b", i)
+	}
+	if _, err := diffLines(a, b); err != errDiffTooChanged {
+		t.Errorf("got %v, expected %v", err, errDiffTooChanged)
+	}
+}
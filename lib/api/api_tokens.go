@@ -0,0 +1,99 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/syncthing/syncthing/lib/config"
+)
+
+// apiTokenMiddleware rejects requests made with an API token that doesn't
+// have the scope the request requires. Everything else is passed on, to be
+// authenticated as usual.
+func apiTokenMiddleware(guiCfg config.GUIConfiguration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("X-API-Key")
+		if guiCfg.IsValidAPIKey(key) && !guiCfg.APIKeyAllows(key, requiredAPIScope(r)) {
+			http.Error(w, "API token not valid for this request", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// statusAPIPaths are the GET endpoints that only report on the state of
+// things. Other GET endpoints, such as those returning file contents,
+// listing the local filesystem or profiling, require the control scope, as
+// does anything added without being listed here.
+var statusAPIPaths = map[string]struct{}{
+	"/rest/db/active":                    {},
+	"/rest/db/activity":                  {},
+	"/rest/db/browse":                    {},
+	"/rest/db/completion":                {},
+	"/rest/db/file":                      {},
+	"/rest/db/filehistory":               {},
+	"/rest/db/localchanged":              {},
+	"/rest/db/need":                      {},
+	"/rest/db/need-all":                  {},
+	"/rest/db/operation":                 {},
+	"/rest/db/plan":                      {},
+	"/rest/db/provenance":                {},
+	"/rest/db/remoteneed":                {},
+	"/rest/db/size":                      {},
+	"/rest/db/status":                    {},
+	"/rest/db/status-all":                {},
+	"/rest/folder/errors":                {},
+	"/rest/folder/ignores/usage":         {},
+	"/rest/folder/pullerrors":            {},
+	"/rest/folder/versions":              {},
+	"/rest/openapi.json":                 {},
+	"/rest/stats/device":                 {},
+	"/rest/stats/folder":                 {},
+	"/rest/stats/folder/scans":           {},
+	"/rest/svc/deviceid":                 {},
+	"/rest/svc/lang":                     {},
+	"/rest/svc/random/string":            {},
+	"/rest/svc/report":                   {},
+	"/rest/system/config/insync":         {},
+	"/rest/system/connections":           {},
+	"/rest/system/connections/offenders": {},
+	"/rest/system/connections/rejected":  {},
+	"/rest/system/debug":                 {},
+	"/rest/system/discovery":             {},
+	"/rest/system/error":                 {},
+	"/rest/system/log":                   {},
+	"/rest/system/log.txt":               {},
+	"/rest/system/ping":                  {},
+	"/rest/system/status":                {},
+	"/rest/system/upgrade":               {},
+	"/rest/system/version":               {},
+}
+
+// requiredAPIScope returns the scope an API token needs for the request.
+func requiredAPIScope(r *http.Request) config.APIScope {
+	path := r.URL.Path
+	switch {
+	case path == "/rest/events" || strings.HasPrefix(path, "/rest/events/"):
+		return config.APIScopeEvents
+	case path == "/rest/system/config":
+		if r.Method == http.MethodGet {
+			return config.APIScopeConfigRead
+		}
+		return config.APIScopeConfigWrite
+	case r.Method != http.MethodGet:
+		return config.APIScopeControl
+	case !strings.HasPrefix(path, "/rest/"):
+		// The GUI assets and QR codes.
+		return config.APIScopeStatus
+	}
+	if _, ok := statusAPIPaths[path]; ok {
+		return config.APIScopeStatus
+	}
+	return config.APIScopeControl
+}
@@ -0,0 +1,253 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// Versions are only diffed when they're small text files...
+	maxDiffSize = 1 << 20
+	// ... and not too different, to keep the work reasonable.
+	maxDiffEdits = 2000
+)
+
+var (
+	errDiffTooLarge   = errors.New("too large to diff")
+	errDiffNotText    = errors.New("not a text file")
+	errDiffTooChanged = errors.New("too many differences to diff")
+)
+
+// The kinds of lines in a diff
+const (
+	diffEqual  = "equal"
+	diffDelete = "delete"
+	diffInsert = "insert"
+)
+
+type diffLine struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// parseVersionTime parses the time of a version, as given in the version
+// listing.
+func parseVersionTime(qs url.Values, key string) (time.Time, error) {
+	v := qs.Get(key)
+	if v == "" {
+		return time.Time{}, fmt.Errorf("missing %s", key)
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+// getFolderVersionFile streams the contents of an archived version.
+func (s *service) getFolderVersionFile(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	file := qs.Get("file")
+	version, err := parseVersionTime(qs, "version")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fd, err := s.model.OpenFolderVersion(qs.Get("folder"), file, version)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	defer fd.Close()
+	info, err := fd.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filepath.Base(file)))
+	http.ServeContent(w, r, "", info.ModTime(), fd)
+}
+
+// postFolderVersionRestoreTo restores a copy of an archived version to
+// another place in the folder.
+func (s *service) postFolderVersionRestoreTo(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	version, err := parseVersionTime(qs, "version")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	target := qs.Get("target")
+	if target == "" {
+		http.Error(w, "missing target", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.model.RestoreFolderVersionTo(qs.Get("folder"), qs.Get("file"), version, target); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+}
+
+// getFolderVersionsDiff returns the line by line differences between two
+// archived versions of a small text file.
+func (s *service) getFolderVersionsDiff(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+	from, err := parseVersionTime(qs, "from")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	to, err := parseVersionTime(qs, "to")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	fromLines, err := s.versionLines(qs.Get("folder"), qs.Get("file"), from)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	toLines, err := s.versionLines(qs.Get("folder"), qs.Get("file"), to)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	lines, err := diffLines(fromLines, toLines)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	sendJSON(w, map[string]interface{}{
+		"from":  from,
+		"to":    to,
+		"lines": lines,
+	})
+}
+
+// postFolderVersionsCleanup prunes the archived versions of the folder now
+// and reports how many files and bytes that removed.
+func (s *service) postFolderVersionsCleanup(w http.ResponseWriter, r *http.Request) {
+	res, err := s.model.CleanFolderVersions(r.URL.Query().Get("folder"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sendJSON(w, res)
+}
+
+// versionLines returns the lines of an archived version, if it's small and
+// text.
+func (s *service) versionLines(folder, file string, version time.Time) ([]string, error) {
+	fd, err := s.model.OpenFolderVersion(folder, file, version)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+	bs, err := ioutil.ReadAll(io.LimitReader(fd, maxDiffSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(bs) > maxDiffSize {
+		return nil, errDiffTooLarge
+	}
+	if !utf8.Valid(bs) || strings.IndexByte(string(bs), 0) >= 0 {
+		return nil, errDiffNotText
+	}
+	if len(bs) == 0 {
+		return nil, nil
+	}
+	return strings.SplitAfter(strings.TrimSuffix(string(bs), "\n"), "\n"), nil
+}
+
+// diffLines returns the shortest edit from a to b, as computed by Myers'
+// algorithm. For each number of edits it keeps how far each diagonal got,
+// to find the way back once the end is reached.
+func diffLines(a, b []string) ([]diffLine, error) {
+	n, m := len(a), len(b)
+	max := n + m
+	if max > 2*maxDiffEdits {
+		max = 2 * maxDiffEdits
+	}
+	off := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+
+	for d := 0; d <= max; d++ {
+		// Diagonals -d to d are all that round d looks at
+		trace = append(trace, append([]int(nil), v[off-d:off+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[off+k-1] < v[off+k+1]) {
+				x = v[off+k+1]
+			} else {
+				x = v[off+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[off+k] = x
+			if x >= n && y >= m {
+				return backtrackDiff(a, b, trace), nil
+			}
+		}
+	}
+	return nil, errDiffTooChanged
+}
+
+func backtrackDiff(a, b []string, trace [][]int) []diffLine {
+	var rev []diffLine
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		at := func(k int) int { return v[k+d] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			rev = append(rev, diffLine{diffEqual, a[x]})
+		}
+		if x == prevX {
+			rev = append(rev, diffLine{diffInsert, b[prevY]})
+		} else {
+			rev = append(rev, diffLine{diffDelete, a[prevX]})
+		}
+		x, y = prevX, prevY
+	}
+	// What's left is the common start
+	for x > 0 {
+		x--
+		rev = append(rev, diffLine{diffEqual, a[x]})
+	}
+
+	lines := make([]diffLine, len(rev))
+	for i := range rev {
+		lines[i] = rev[len(rev)-1-i]
+	}
+	return lines
+}
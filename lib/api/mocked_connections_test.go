@@ -20,6 +20,14 @@ func (m *mockedConnections) ConnectionStatus() map[string]connections.Connection
 	return nil
 }
 
+func (m *mockedConnections) RejectedConnections() []connections.RejectedConnection {
+	return nil
+}
+
+func (m *mockedConnections) TarpitOffenders() []connections.Offender {
+	return nil
+}
+
 func (m *mockedConnections) NATType() string {
 	return ""
 }
@@ -7,11 +7,15 @@
 package api
 
 import (
+	"context"
+	"io"
 	"net"
 	"time"
 
 	"github.com/syncthing/syncthing/lib/connections"
 	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/ignore"
 	"github.com/syncthing/syncthing/lib/model"
 	"github.com/syncthing/syncthing/lib/protocol"
 	"github.com/syncthing/syncthing/lib/stats"
@@ -24,6 +28,14 @@ func (m *mockedModel) GlobalDirectoryTree(folder, prefix string, levels int, dir
 	return nil
 }
 
+func (m *mockedModel) GlobalDirectoryList(folder, prefix string, dirsonly bool, sortBy model.DirectorySort, reverse bool, offset, limit int) (model.DirectoryPage, error) {
+	return model.DirectoryPage{}, nil
+}
+
+func (m *mockedModel) GlobalDirectoryListContinue(token string, limit int) (model.DirectoryPage, error) {
+	return model.DirectoryPage{}, nil
+}
+
 func (m *mockedModel) Completion(device protocol.DeviceID, folder string) model.FolderCompletion {
 	return model.FolderCompletion{}
 }
@@ -32,6 +44,14 @@ func (m *mockedModel) Override(folder string) {}
 
 func (m *mockedModel) Revert(folder string) {}
 
+func (m *mockedModel) FolderOperation(folder string) (model.OperationReport, error) {
+	return model.OperationReport{}, nil
+}
+
+func (m *mockedModel) CancelFolderOperation(folder string) error {
+	return nil
+}
+
 func (m *mockedModel) NeedFolderFiles(folder string, page, perpage int) ([]db.FileInfoTruncated, []db.FileInfoTruncated, []db.FileInfoTruncated) {
 	return nil, nil, nil
 }
@@ -48,6 +68,10 @@ func (m *mockedModel) DeviceStatistics() (map[string]stats.DeviceStatistics, err
 	return nil, nil
 }
 
+func (m *mockedModel) FolderScanHistory(folder string) ([]stats.ScanRecord, error) {
+	return nil, nil
+}
+
 func (m *mockedModel) FolderStatistics() (map[string]stats.FolderStatistics, error) {
 	return nil, nil
 }
@@ -67,6 +91,34 @@ func (m *mockedModel) Availability(folder string, file protocol.FileInfo, block
 	return nil
 }
 
+func (m *mockedModel) FileContent(ctx context.Context, folder string, file protocol.FileInfo) (io.Reader, error) {
+	return nil, nil
+}
+
+func (m *mockedModel) RestoreDeletedFiles(ctx context.Context, folder string, files []string) (map[string]string, error) {
+	return nil, nil
+}
+
+func (m *mockedModel) NormalizeFolder(folder string, dryRun bool) ([]model.NormalizationFix, error) {
+	return nil, nil
+}
+
+func (m *mockedModel) NeedReasons(folder string, files []string) map[string]model.NeedReason {
+	return nil
+}
+
+func (m *mockedModel) RemoteNeedReason(folder string, device protocol.DeviceID) model.NeedReason {
+	return model.NeedReason{}
+}
+
+func (m *mockedModel) PullPlan(folder string) (model.PullPlan, error) {
+	return model.PullPlan{}, nil
+}
+
+func (m *mockedModel) FolderActivity(folder, prefix string, levels int) ([]model.DirectoryActivity, error) {
+	return nil, nil
+}
+
 func (m *mockedModel) GetIgnores(folder string) ([]string, []string, error) {
 	return nil, nil, nil
 }
@@ -75,6 +127,22 @@ func (m *mockedModel) SetIgnores(folder string, content []string) error {
 	return nil
 }
 
+func (m *mockedModel) EditIgnores(folder, version string, add, remove []string) (model.IgnoreEditResult, error) {
+	return model.IgnoreEditResult{}, nil
+}
+
+func (m *mockedModel) Unignore(folder, version string, patterns []string) (model.UnignoreResult, error) {
+	return model.UnignoreResult{}, nil
+}
+
+func (m *mockedModel) IgnoredUsage(folder string) (model.IgnoredUsage, error) {
+	return model.IgnoredUsage{}, nil
+}
+
+func (m *mockedModel) ExplainIgnores(folder string, patterns, paths []string) ([]ignore.Explanation, error) {
+	return nil, nil
+}
+
 func (m *mockedModel) GetFolderVersions(folder string) (map[string][]versioner.FileVersion, error) {
 	return nil, nil
 }
@@ -83,6 +151,18 @@ func (m *mockedModel) RestoreFolderVersions(folder string, versions map[string]t
 	return nil, nil
 }
 
+func (m *mockedModel) OpenFolderVersion(folder, file string, version time.Time) (fs.File, error) {
+	return nil, nil
+}
+
+func (m *mockedModel) RestoreFolderVersionTo(folder, file string, version time.Time, target string) error {
+	return nil
+}
+
+func (m *mockedModel) CleanFolderVersions(folder string) (versioner.CleanupResult, error) {
+	return versioner.CleanupResult{}, nil
+}
+
 func (m *mockedModel) PauseDevice(device protocol.DeviceID) {
 }
 
@@ -102,8 +182,20 @@ func (m *mockedModel) ScanFolderSubdirs(folder string, subs []string) error {
 	return nil
 }
 
+func (m *mockedModel) SetFolderMaintenance(folder string, enabled bool) error {
+	return nil
+}
+
+func (m *mockedModel) ResumeFolderPull(folder string) error {
+	return nil
+}
+
 func (m *mockedModel) BringToFront(folder, file string) {}
 
+func (m *mockedModel) ResetPullBackoff(folder string, files []string) error {
+	return nil
+}
+
 func (m *mockedModel) Connection(deviceID protocol.DeviceID) (connections.Connection, bool) {
 	return nil, false
 }
@@ -161,6 +253,10 @@ func (m *mockedModel) GetHello(protocol.DeviceID) protocol.HelloIntf {
 
 func (m *mockedModel) StartDeadlockDetector(timeout time.Duration) {}
 
+func (m *mockedModel) FolderProgress(folder string) map[string]*model.PullerProgress {
+	return nil
+}
+
 func (m *mockedModel) DBSnapshot(_ string) (*db.Snapshot, error) {
 	return nil, nil
 }
@@ -175,4 +271,12 @@ func (m *mockedFolderSummaryService) Summary(folder string) (map[string]interfac
 	return map[string]interface{}{"mocked": true}, nil
 }
 
+func (m *mockedFolderSummaryService) SubtreeSummary(folder, prefix string) (map[string]interface{}, error) {
+	return map[string]interface{}{"mocked": true}, nil
+}
+
+func (m *mockedFolderSummaryService) AggregateSummary() map[string]interface{} {
+	return map[string]interface{}{"mocked": true}
+}
+
 func (m *mockedFolderSummaryService) OnEventRequest() {}
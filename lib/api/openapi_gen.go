@@ -0,0 +1,587 @@
+// Code generated by genopenapi.go - DO NOT EDIT.
+
+package api
+
+var restOperations = []restOperation{
+	{
+		Method: "get",
+		Path:   "/rest/db/completion",
+		Params: []restParam{
+			{Name: "device", Required: true},
+			{Name: "folder", Required: true},
+		},
+	},
+	{
+		Method: "get",
+		Path:   "/rest/db/file",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+			{Name: "file", Required: true},
+		},
+	},
+	{
+		Method:  "get",
+		Path:    "/rest/db/provenance",
+		Summary: "Describes who is responsible for the current version of a file, and which version each device sharing the folder has.",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+			{Name: "file", Required: true},
+		},
+	},
+	{
+		Method:      "get",
+		Path:        "/rest/db/filehistory",
+		Summary:     "Returns a timeline of the known versions of a file, newest first.",
+		Description: "It combines the versions the devices sharing the folder currently have with the versions archived by our versioner, which can be restored through /rest/folder/versions.",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+			{Name: "path", Required: true},
+		},
+	},
+	{
+		Method:      "get",
+		Path:        "/rest/db/file-content",
+		Summary:     "Streams the content of a file as it's known in the index: the global version, the version we have at the given sequence, or the version given as listed by /rest/db/filehistory.",
+		Description: "Nothing in the folder is changed, so this can fetch versions we don't have locally.",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+			{Name: "file", Required: true},
+			{Name: "sequence"},
+			{Name: "version"},
+		},
+	},
+	{
+		Method:  "get",
+		Path:    "/rest/db/active",
+		Summary: "Returns the block level progress of the files currently being pulled, per folder.",
+		Params: []restParam{
+			{Name: "folder"},
+		},
+	},
+	{
+		Method: "get",
+		Path:   "/rest/db/ignores",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+		},
+	},
+	{
+		Method: "get",
+		Path:   "/rest/db/need",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+			{Name: "perpage"},
+			{Name: "page"},
+		},
+	},
+	{
+		Method:  "get",
+		Path:    "/rest/db/need-all",
+		Summary: "Lists what is out of sync in all the folders, grouped by folder with up to perfolder items each, and the totals.",
+		Params: []restParam{
+			{Name: "perfolder"},
+		},
+	},
+	{
+		Method: "get",
+		Path:   "/rest/db/remoteneed",
+		Params: []restParam{
+			{Name: "device", Required: true},
+			{Name: "folder", Required: true},
+			{Name: "perpage"},
+			{Name: "page"},
+		},
+	},
+	{
+		Method:      "get",
+		Path:        "/rest/db/plan",
+		Summary:     "Returns what pulling the folder would do right now, without doing it.",
+		Description: "This shows the impact of resuming a paused folder.",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+		},
+	},
+	{
+		Method:      "get",
+		Path:        "/rest/db/activity",
+		Summary:     "Returns how many changes were scanned and pulled within each directory over the last day, busiest first.",
+		Description: "Each directory counts the changes anywhere below it.",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+			{Name: "prefix"},
+			{Name: "levels"},
+		},
+	},
+	{
+		Method: "get",
+		Path:   "/rest/db/localchanged",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+		},
+	},
+	{
+		Method:  "get",
+		Path:    "/rest/db/operation",
+		Summary: "Returns the progress of the running revert or override of the folder, or the report of the last one.",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+		},
+	},
+	{
+		Method: "get",
+		Path:   "/rest/db/status",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+		},
+	},
+	{
+		Method: "get",
+		Path:   "/rest/db/size",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+			{Name: "prefix"},
+		},
+	},
+	{
+		Method: "get",
+		Path:   "/rest/db/status-all",
+	},
+	{
+		Method: "get",
+		Path:   "/rest/db/browse",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+			{Name: "prefix"},
+			{Name: "dirsonly"},
+			{Name: "levels"},
+			{Name: "offset"},
+			{Name: "limit"},
+			{Name: "sort"},
+			{Name: "reverse"},
+			{Name: "token"},
+		},
+	},
+	{
+		Method: "get",
+		Path:   "/rest/folder/versions",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+			{Name: "file"},
+		},
+	},
+	{
+		Method:  "get",
+		Path:    "/rest/folder/versions/file",
+		Summary: "Streams the contents of an archived version.",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+			{Name: "file", Required: true},
+			{Name: "version", Required: true},
+		},
+	},
+	{
+		Method: "get",
+		Path:   "/rest/folder/versions/diff",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+			{Name: "file", Required: true},
+			{Name: "from", Required: true},
+			{Name: "to", Required: true},
+		},
+	},
+	{
+		Method: "get",
+		Path:   "/rest/folder/errors",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+		},
+	},
+	{
+		Method:  "get",
+		Path:    "/rest/folder/ignores/usage",
+		Summary: "Tells how many files and bytes on disk are kept from being synced by each of the ignore patterns of the folder.",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+		},
+	},
+	{
+		Method: "get",
+		Path:   "/rest/folder/pullerrors",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+		},
+		Deprecated: true,
+	},
+	{
+		Method: "get",
+		Path:   "/rest/events",
+		Params: []restParam{
+			{Name: "since"},
+			{Name: "limit"},
+			{Name: "timeout"},
+			{Name: "events"},
+			{Name: "persistent"},
+		},
+	},
+	{
+		Method: "get",
+		Path:   "/rest/events/disk",
+		Params: []restParam{
+			{Name: "since"},
+			{Name: "limit"},
+			{Name: "timeout"},
+		},
+	},
+	{
+		Method:      "get",
+		Path:        "/rest/events/ws",
+		Summary:     "Upgrades the request to a WebSocket connection on which events are pushed as they happen, one JSON object per message.",
+		Description: "The \"since\" and \"events\" parameters work as for /rest/events.",
+		Params: []restParam{
+			{Name: "since"},
+			{Name: "events"},
+		},
+	},
+	{
+		Method: "get",
+		Path:   "/rest/stats/device",
+	},
+	{
+		Method: "get",
+		Path:   "/rest/stats/folder",
+	},
+	{
+		Method:  "get",
+		Path:    "/rest/stats/folder/scans",
+		Summary: "Returns how long the latest scans of the folder took and how fast they went, oldest first.",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+		},
+	},
+	{
+		Method:  "get",
+		Path:    "/rest/openapi.json",
+		Summary: "Serves the OpenAPI 3 description of the REST API, for generating clients.",
+	},
+	{
+		Method: "get",
+		Path:   "/rest/svc/deviceid",
+		Params: []restParam{
+			{Name: "id", Required: true},
+		},
+	},
+	{
+		Method: "get",
+		Path:   "/rest/svc/lang",
+	},
+	{
+		Method: "get",
+		Path:   "/rest/svc/report",
+	},
+	{
+		Method: "get",
+		Path:   "/rest/svc/random/string",
+		Params: []restParam{
+			{Name: "length"},
+		},
+	},
+	{
+		Method: "get",
+		Path:   "/rest/system/browse",
+		Params: []restParam{
+			{Name: "current", Required: true},
+		},
+	},
+	{
+		Method: "get",
+		Path:   "/rest/system/config",
+	},
+	{
+		Method: "get",
+		Path:   "/rest/system/config/insync",
+	},
+	{
+		Method: "get",
+		Path:   "/rest/system/connections",
+	},
+	{
+		Method:  "get",
+		Path:    "/rest/system/connections/rejected",
+		Summary: "Lists the latest incoming connections that were rejected, and why.",
+	},
+	{
+		Method:  "get",
+		Path:    "/rest/system/connections/offenders",
+		Summary: "Lists the addresses that connected with unknown device IDs, for spotting probes and sending them to the tarpit.",
+	},
+	{
+		Method: "get",
+		Path:   "/rest/system/discovery",
+	},
+	{
+		Method: "get",
+		Path:   "/rest/system/error",
+	},
+	{
+		Method: "get",
+		Path:   "/rest/system/ping",
+	},
+	{
+		Method: "get",
+		Path:   "/rest/system/status",
+	},
+	{
+		Method: "get",
+		Path:   "/rest/system/upgrade",
+	},
+	{
+		Method: "get",
+		Path:   "/rest/system/version",
+	},
+	{
+		Method: "get",
+		Path:   "/rest/system/debug",
+	},
+	{
+		Method: "get",
+		Path:   "/rest/system/log",
+		Params: []restParam{
+			{Name: "since"},
+		},
+	},
+	{
+		Method: "get",
+		Path:   "/rest/system/log.txt",
+		Params: []restParam{
+			{Name: "since"},
+		},
+	},
+	{
+		Method:  "get",
+		Path:    "/rest/system/natprobe",
+		Summary: "Returns the result of the last NAT probe, if any.",
+	},
+	{
+		Method:      "post",
+		Path:        "/rest/batch",
+		Summary:     "Performs several folder operations in one request.",
+		Description: "Pausing and resuming are gathered into a single configuration change, which is committed before any of the other operations run.",
+		Body:        true,
+	},
+	{
+		Method: "post",
+		Path:   "/rest/db/prio",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+			{Name: "file", Required: true},
+			{Name: "perpage"},
+			{Name: "page"},
+		},
+	},
+	{
+		Method:      "post",
+		Path:        "/rest/db/pin",
+		Summary:     "Pins a file or directory to the front of the pull queue of the folder, or unpins it.",
+		Description: "Pins are kept in the folder configuration.",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+			{Name: "file", Required: true},
+			{Name: "unpin"},
+		},
+	},
+	{
+		Method: "post",
+		Path:   "/rest/db/ignores",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+		},
+	},
+	{
+		Method:  "post",
+		Path:    "/rest/db/ignores/edit",
+		Summary: "Adds and removes the patterns given like {\"add\": [...], \"remove\": [...]}, failing with a conflict if the patterns aren't of the given version any more or contradict the ones to add.",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+			{Name: "version"},
+		},
+	},
+	{
+		Method:  "post",
+		Path:    "/rest/db/ignores/unignore",
+		Summary: "Removes the ignore patterns given like {\"remove\": [...]}, and rescans just what they applied to where possible.",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+			{Name: "version"},
+		},
+	},
+	{
+		Method:  "post",
+		Path:    "/rest/folder/ignores/test",
+		Summary: "Tells which pattern decides whether each path is ignored, given like {\"paths\": [...], \"ignore\": [...]}, where the patterns are optional, to test them instead of the current ones.",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+		},
+	},
+	{
+		Method: "post",
+		Path:   "/rest/db/override",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+		},
+	},
+	{
+		Method: "post",
+		Path:   "/rest/db/revert",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+		},
+	},
+	{
+		Method:  "post",
+		Path:    "/rest/db/operation/cancel",
+		Summary: "Stops the running revert or override of the folder.",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+		},
+	},
+	{
+		Method:  "post",
+		Path:    "/rest/db/retry",
+		Summary: "Retries the items that failed to sync in the folder right away, instead of when they are next due.",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+			{Name: "file", Multi: true},
+		},
+	},
+	{
+		Method:  "post",
+		Path:    "/rest/db/undelete",
+		Summary: "Restores files deleted here from the connected devices that still have them, returning the errors by file.",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+			{Name: "file", Required: true, Multi: true},
+		},
+	},
+	{
+		Method:  "post",
+		Path:    "/rest/db/normalize",
+		Summary: "Brings the names of existing files into the Unicode normalization form configured for the folder, and returns what was done.",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+			{Name: "dryrun"},
+		},
+	},
+	{
+		Method: "post",
+		Path:   "/rest/db/scan",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+			{Name: "sub", Multi: true},
+			{Name: "delay"},
+		},
+	},
+	{
+		Method: "post",
+		Path:   "/rest/folder/versions",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+		},
+		Body: true,
+	},
+	{
+		Method:  "post",
+		Path:    "/rest/folder/versions/restore",
+		Summary: "Restores a copy of an archived version to another place in the folder.",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+			{Name: "file", Required: true},
+			{Name: "version", Required: true},
+			{Name: "target", Required: true},
+		},
+	},
+	{
+		Method:      "post",
+		Path:        "/rest/folder/versions/cleanup",
+		Summary:     "getFolderVersionsDiff returns the line by line differences between two archived versions of a small text file.",
+		Description: "postFolderVersionsCleanup prunes the archived versions of the folder now and reports how many files and bytes that removed.",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+		},
+	},
+	{
+		Method:  "post",
+		Path:    "/rest/folder/maintenance",
+		Summary: "Puts the folder in maintenance, or with enabled=false takes it out of it again, which rescans it.",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+			{Name: "enabled"},
+		},
+	},
+	{
+		Method:  "post",
+		Path:    "/rest/folder/pull/resume",
+		Summary: "Renews the pull budget of the folder, resuming a pull that stopped at its checkpoint.",
+		Params: []restParam{
+			{Name: "folder", Required: true},
+		},
+	},
+	{
+		Method: "post",
+		Path:   "/rest/system/config",
+		Body:   true,
+	},
+	{
+		Method: "post",
+		Path:   "/rest/system/error",
+		Body:   true,
+	},
+	{
+		Method: "post",
+		Path:   "/rest/system/error/clear",
+	},
+	{
+		Method: "post",
+		Path:   "/rest/system/ping",
+	},
+	{
+		Method: "post",
+		Path:   "/rest/system/reset",
+		Params: []restParam{
+			{Name: "folder"},
+		},
+	},
+	{
+		Method: "post",
+		Path:   "/rest/system/restart",
+	},
+	{
+		Method: "post",
+		Path:   "/rest/system/shutdown",
+	},
+	{
+		Method: "post",
+		Path:   "/rest/system/upgrade",
+	},
+	{
+		Method: "post",
+		Path:   "/rest/system/pause",
+		Params: []restParam{
+			{Name: "device"},
+		},
+	},
+	{
+		Method: "post",
+		Path:   "/rest/system/resume",
+		Params: []restParam{
+			{Name: "device"},
+		},
+	},
+	{
+		Method: "post",
+		Path:   "/rest/system/debug",
+		Params: []restParam{
+			{Name: "enable"},
+			{Name: "disable"},
+		},
+	},
+}
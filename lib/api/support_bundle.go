@@ -8,7 +8,11 @@ package api
 
 import (
 	"archive/zip"
+	"encoding/json"
+	"fmt"
 	"io"
+	"sort"
+	"strings"
 
 	"github.com/syncthing/syncthing/lib/config"
 )
@@ -17,6 +21,9 @@ import (
 func getRedactedConfig(s *service) config.Configuration {
 	rawConf := s.cfg.RawCopy()
 	rawConf.GUI.APIKey = "REDACTED"
+	for i := range rawConf.GUI.APITokens {
+		rawConf.GUI.APITokens[i].Key = "REDACTED"
+	}
 	if rawConf.GUI.Password != "" {
 		rawConf.GUI.Password = "REDACTED"
 	}
@@ -26,6 +33,131 @@ func getRedactedConfig(s *service) config.Configuration {
 	return rawConf
 }
 
+// unredactConfig puts the current values back in place of those redacted by
+// getRedactedConfig, with API tokens matched by name.
+func unredactConfig(to *config.Configuration, from config.Configuration) {
+	if to.GUI.APIKey == "REDACTED" {
+		to.GUI.APIKey = from.GUI.APIKey
+	}
+	if to.GUI.Password == "REDACTED" {
+		to.GUI.Password = from.GUI.Password
+	}
+	if to.GUI.User == "REDACTED" {
+		to.GUI.User = from.GUI.User
+	}
+	for i, token := range to.GUI.APITokens {
+		if token.Key != "REDACTED" {
+			continue
+		}
+		for _, cur := range from.GUI.APITokens {
+			if cur.Name == token.Name {
+				to.GUI.APITokens[i].Key = cur.Key
+				break
+			}
+		}
+	}
+}
+
+// supportRedactor removes details about the user from the support bundle,
+// beyond the secrets that are always removed. With "paths", folder paths and
+// labels are replaced; with "devices", device names and addresses.
+type supportRedactor struct {
+	paths, devices bool
+	replacer       *strings.Replacer
+}
+
+func newSupportRedactor(cfg config.Configuration, options []string) *supportRedactor {
+	r := &supportRedactor{}
+	for _, opt := range options {
+		switch strings.TrimSpace(opt) {
+		case "paths":
+			r.paths = true
+		case "devices":
+			r.devices = true
+		case "all":
+			r.paths, r.devices = true, true
+		}
+	}
+
+	replacements := make(map[string]string)
+	if r.paths {
+		for _, folder := range cfg.Folders {
+			replacements[folder.Path] = fmt.Sprintf("<path of %s>", folder.ID)
+			replacements[folder.Label] = fmt.Sprintf("<label of %s>", folder.ID)
+		}
+	}
+	if r.devices {
+		for _, device := range cfg.Devices {
+			replacements[device.Name] = fmt.Sprintf("<name of %s>", device.DeviceID.Short())
+			for _, addr := range device.Addresses {
+				if addr != "dynamic" {
+					replacements[addr] = fmt.Sprintf("<address of %s>", device.DeviceID.Short())
+				}
+			}
+		}
+	}
+
+	// Short values would match too much unrelated text. They are only
+	// removed from the configuration.
+	var originals []string
+	for orig, repl := range replacements {
+		if len(orig) < 4 {
+			continue
+		}
+		originals = append(originals, orig)
+		// Also as it appears escaped in JSON, e.g. Windows paths
+		if bs, err := json.Marshal(orig); err == nil {
+			if escaped := string(bs[1 : len(bs)-1]); escaped != orig {
+				replacements[escaped] = repl
+				originals = append(originals, escaped)
+			}
+		}
+	}
+	// The longest first, so that a path is replaced before a label that is
+	// part of it.
+	sort.Slice(originals, func(a, b int) bool {
+		if len(originals[a]) != len(originals[b]) {
+			return len(originals[a]) > len(originals[b])
+		}
+		return originals[a] < originals[b]
+	})
+	var oldnew []string
+	for _, orig := range originals {
+		oldnew = append(oldnew, orig, replacements[orig])
+	}
+	r.replacer = strings.NewReplacer(oldnew...)
+	return r
+}
+
+// config redacts the configuration.
+func (r *supportRedactor) config(cfg *config.Configuration) {
+	if r.paths {
+		for i := range cfg.Folders {
+			cfg.Folders[i].Path = "REDACTED"
+			if cfg.Folders[i].Label != "" {
+				cfg.Folders[i].Label = "REDACTED"
+			}
+		}
+	}
+	if r.devices {
+		for i := range cfg.Devices {
+			if cfg.Devices[i].Name != "" {
+				cfg.Devices[i].Name = "REDACTED"
+			}
+			for j, addr := range cfg.Devices[i].Addresses {
+				if addr != "dynamic" {
+					cfg.Devices[i].Addresses[j] = "REDACTED"
+				}
+			}
+		}
+	}
+}
+
+// text redacts the occurrences of the details in logs and the like.
+func (r *supportRedactor) text(bs []byte) []byte {
+	return []byte(r.replacer.Replace(string(bs)))
+}
+
 // writeZip writes a zip file containing the given entries
 func writeZip(writer io.Writer, files []fileEntry) error {
 	zipWriter := zip.NewWriter(writer)
@@ -12,18 +12,22 @@ import (
 	"time"
 )
 
-func NewBroadcast(port int) Interface {
+// NewBroadcast returns a beacon that broadcasts on the given port, on the
+// named interfaces or all of them if none are given. The unicast hosts,
+// IPv4 addresses or names optionally with a port, are sent to directly as
+// well, for networks where broadcasts don't get through.
+func NewBroadcast(port int, intfs, unicast []string) Interface {
 	c := newCast("broadcastBeacon")
 	c.addReader(func(ctx context.Context) error {
 		return readBroadcasts(ctx, c.outbox, port)
 	})
 	c.addWriter(func(ctx context.Context) error {
-		return writeBroadcasts(ctx, c.inbox, port)
+		return writeBroadcasts(ctx, c.inbox, port, intfs, unicast)
 	})
 	return c
 }
 
-func writeBroadcasts(ctx context.Context, inbox <-chan []byte, port int) error {
+func writeBroadcasts(ctx context.Context, inbox <-chan []byte, port int, intfs, unicast []string) error {
 	conn, err := net.ListenUDP("udp4", nil)
 	if err != nil {
 		l.Debugln(err)
@@ -44,31 +48,37 @@ func writeBroadcasts(ctx context.Context, inbox <-chan []byte, port int) error {
 			return nil
 		}
 
-		addrs, err := net.InterfaceAddrs()
+		addrs, err := interfaceAddrs(intfs)
 		if err != nil {
 			l.Debugln(err)
 			return err
 		}
 
-		var dsts []net.IP
+		var dsts []*net.UDPAddr
 		for _, addr := range addrs {
 			if iaddr, ok := addr.(*net.IPNet); ok && len(iaddr.IP) >= 4 && iaddr.IP.IsGlobalUnicast() && iaddr.IP.To4() != nil {
 				baddr := bcast(iaddr)
-				dsts = append(dsts, baddr.IP)
+				dsts = append(dsts, &net.UDPAddr{IP: baddr.IP, Port: port})
 			}
 		}
 
-		if len(dsts) == 0 {
+		if len(dsts) == 0 && len(intfs) == 0 {
 			// Fall back to the general IPv4 broadcast address
-			dsts = append(dsts, net.IP{0xff, 0xff, 0xff, 0xff})
+			dsts = append(dsts, &net.UDPAddr{IP: net.IP{0xff, 0xff, 0xff, 0xff}, Port: port})
+		}
+
+		dsts = append(dsts, unicastAddrs(unicast, port)...)
+
+		if len(dsts) == 0 {
+			// None of the chosen interfaces is up, at least for now
+			l.Debugln("no addresses to send to")
+			continue
 		}
 
 		l.Debugln("addresses:", dsts)
 
 		success := 0
-		for _, ip := range dsts {
-			dst := &net.UDPAddr{IP: ip, Port: port}
-
+		for _, dst := range dsts {
 			conn.SetWriteDeadline(time.Now().Add(time.Second))
 			_, err = conn.WriteTo(bs, dst)
 			conn.SetWriteDeadline(time.Time{})
@@ -0,0 +1,82 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package beacon
+
+import (
+	"net"
+	"strconv"
+)
+
+// interfaceAllowed returns whether beacons are to use the interface, given
+// the names of the interfaces to use. No names means all of them.
+func interfaceAllowed(name string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		if a == name {
+			return true
+		}
+	}
+	return false
+}
+
+// interfaces returns the network interfaces beacons are to use.
+func interfaces(allowed []string) ([]net.Interface, error) {
+	intfs, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	filtered := intfs[:0]
+	for _, intf := range intfs {
+		if interfaceAllowed(intf.Name, allowed) {
+			filtered = append(filtered, intf)
+		}
+	}
+	return filtered, nil
+}
+
+// interfaceAddrs returns the addresses of the network interfaces beacons
+// are to use.
+func interfaceAddrs(allowed []string) ([]net.Addr, error) {
+	if len(allowed) == 0 {
+		return net.InterfaceAddrs()
+	}
+	intfs, err := interfaces(allowed)
+	if err != nil {
+		return nil, err
+	}
+	var addrs []net.Addr
+	for _, intf := range intfs {
+		intfAddrs, err := intf.Addrs()
+		if err != nil {
+			l.Debugln(intf.Name, err)
+			continue
+		}
+		addrs = append(addrs, intfAddrs...)
+	}
+	return addrs, nil
+}
+
+// unicastAddrs resolves the hosts, with or without a port, that beacons
+// are sent to directly. Those without a port get the given one. Hosts that
+// don't resolve are skipped, as they may well resolve next time.
+func unicastAddrs(hosts []string, port int) []*net.UDPAddr {
+	var addrs []*net.UDPAddr
+	for _, host := range hosts {
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			host = net.JoinHostPort(host, strconv.Itoa(port))
+		}
+		addr, err := net.ResolveUDPAddr("udp4", host)
+		if err != nil {
+			l.Debugln(err)
+			continue
+		}
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
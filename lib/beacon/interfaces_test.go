@@ -0,0 +1,43 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package beacon
+
+import (
+	"testing"
+)
+
+func TestInterfaceAllowed(t *testing.T) {
+	cases := []struct {
+		name    string
+		allowed []string
+		ok      bool
+	}{
+		{"eth0", nil, true},
+		{"eth0", []string{"eth0"}, true},
+		{"eth0", []string{"wlan0", "eth0"}, true},
+		{"eth1", []string{"eth0"}, false},
+		{"eth", []string{"eth0"}, false},
+	}
+	for _, tc := range cases {
+		if ok := interfaceAllowed(tc.name, tc.allowed); ok != tc.ok {
+			t.Errorf("interfaceAllowed(%q, %v) = %v, expected %v", tc.name, tc.allowed, ok, tc.ok)
+		}
+	}
+}
+
+func TestUnicastAddrs(t *testing.T) {
+	addrs := unicastAddrs([]string{"192.0.2.42", "192.0.2.43:22027", "[2001:db8::1]:21027", "192.0.2.44:notaport"}, 21027)
+	expected := []string{"192.0.2.42:21027", "192.0.2.43:22027"}
+	if len(addrs) != len(expected) {
+		t.Fatalf("got %v, expected %v", addrs, expected)
+	}
+	for i, addr := range addrs {
+		if addr.String() != expected[i] {
+			t.Errorf("got %v, expected %v", addr, expected[i])
+		}
+	}
+}
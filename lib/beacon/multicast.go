@@ -15,18 +15,20 @@ import (
 	"golang.org/x/net/ipv6"
 )
 
-func NewMulticast(addr string) Interface {
+// NewMulticast returns a beacon that multicasts to the given group address,
+// on the named interfaces or all of them if none are given.
+func NewMulticast(addr string, intfs []string) Interface {
 	c := newCast("multicastBeacon")
 	c.addReader(func(ctx context.Context) error {
-		return readMulticasts(ctx, c.outbox, addr)
+		return readMulticasts(ctx, c.outbox, addr, intfs)
 	})
 	c.addWriter(func(ctx context.Context) error {
-		return writeMulticasts(ctx, c.inbox, addr)
+		return writeMulticasts(ctx, c.inbox, addr, intfs)
 	})
 	return c
 }
 
-func writeMulticasts(ctx context.Context, inbox <-chan []byte, addr string) error {
+func writeMulticasts(ctx context.Context, inbox <-chan []byte, addr string, allowed []string) error {
 	gaddr, err := net.ResolveUDPAddr("udp6", addr)
 	if err != nil {
 		l.Debugln(err)
@@ -59,7 +61,7 @@ func writeMulticasts(ctx context.Context, inbox <-chan []byte, addr string) erro
 			return nil
 		}
 
-		intfs, err := net.Interfaces()
+		intfs, err := interfaces(allowed)
 		if err != nil {
 			l.Debugln(err)
 			return err
@@ -94,7 +96,7 @@ func writeMulticasts(ctx context.Context, inbox <-chan []byte, addr string) erro
 	}
 }
 
-func readMulticasts(ctx context.Context, outbox chan<- recv, addr string) error {
+func readMulticasts(ctx context.Context, outbox chan<- recv, addr string, allowed []string) error {
 	gaddr, err := net.ResolveUDPAddr("udp6", addr)
 	if err != nil {
 		l.Debugln(err)
@@ -113,7 +115,7 @@ func readMulticasts(ctx context.Context, outbox chan<- recv, addr string) error
 		conn.Close()
 	}()
 
-	intfs, err := net.Interfaces()
+	intfs, err := interfaces(allowed)
 	if err != nil {
 		l.Debugln(err)
 		return err
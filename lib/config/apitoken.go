@@ -0,0 +1,57 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package config
+
+import (
+	"time"
+)
+
+// An APIScope is a part of the API that an APIToken gives access to.
+type APIScope string
+
+const (
+	// Read only access to status and statistics, not including the config.
+	APIScopeStatus APIScope = "status"
+	// Access to the event streams only.
+	APIScopeEvents APIScope = "events"
+	// Reading the config, including any secrets in it.
+	APIScopeConfigRead APIScope = "config-read"
+	// Changing the config.
+	APIScopeConfigWrite APIScope = "config-write"
+	// All other actions, such as scanning, pausing or restarting.
+	APIScopeControl APIScope = "control"
+)
+
+// An APIToken is an additional API key with access limited to some scopes,
+// and optionally a time after which it stops being valid. Unlike the main
+// API key, tokens are meant to be handed out to monitoring and other tools.
+type APIToken struct {
+	Name    string     `xml:"name,attr" json:"name"`
+	Key     string     `xml:"key" json:"key"`
+	Scopes  []APIScope `xml:"scope" json:"scopes"`
+	Expires time.Time  `xml:"expires,attr" json:"expires"`
+}
+
+func (t APIToken) IsExpired(now time.Time) bool {
+	return !t.Expires.IsZero() && !now.Before(t.Expires)
+}
+
+func (t APIToken) HasScope(scope APIScope) bool {
+	for _, s := range t.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (t APIToken) Copy() APIToken {
+	c := t
+	c.Scopes = make([]APIScope, len(t.Scopes))
+	copy(c.Scopes, t.Scopes)
+	return c
+}
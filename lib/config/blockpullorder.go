@@ -0,0 +1,41 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package config
+
+type BlockPullOrder int
+
+const (
+	BlockPullOrderRandom      BlockPullOrder = iota // default is random
+	BlockPullOrderRarestFirst                       // blocks fewest devices have in temporary files first
+)
+
+func (o BlockPullOrder) String() string {
+	switch o {
+	case BlockPullOrderRandom:
+		return "random"
+	case BlockPullOrderRarestFirst:
+		return "rarestFirst"
+	default:
+		return "unknown"
+	}
+}
+
+func (o BlockPullOrder) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+func (o *BlockPullOrder) UnmarshalText(bs []byte) error {
+	switch string(bs) {
+	case "random":
+		*o = BlockPullOrderRandom
+	case "rarestFirst":
+		*o = BlockPullOrderRarestFirst
+	default:
+		*o = BlockPullOrderRandom
+	}
+	return nil
+}
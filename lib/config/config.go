@@ -357,6 +357,11 @@ func (cfg *Configuration) clean() error {
 	if cfg.GUI.APIKey == "" {
 		cfg.GUI.APIKey = rand.String(32)
 	}
+	for i := range cfg.GUI.APITokens {
+		if cfg.GUI.APITokens[i].Key == "" {
+			cfg.GUI.APITokens[i].Key = rand.String(32)
+		}
+	}
 
 	// The list of ignored devices should not contain any devices that have
 	// been manually added to the config.
@@ -413,12 +418,24 @@ nextPendingDevice:
 	if cfg.PendingDevices == nil {
 		cfg.PendingDevices = []ObservedDevice{}
 	}
+	if cfg.Options.LocalAnnInterfaces == nil {
+		cfg.Options.LocalAnnInterfaces = []string{}
+	}
+	if cfg.Options.LocalAnnUnicastHosts == nil {
+		cfg.Options.LocalAnnUnicastHosts = []string{}
+	}
 	if cfg.Options.AlwaysLocalNets == nil {
 		cfg.Options.AlwaysLocalNets = []string{}
 	}
 	if cfg.Options.UnackedNotificationIDs == nil {
 		cfg.Options.UnackedNotificationIDs = []string{}
 	}
+	if cfg.Options.IncomingAllowedNetworks == nil {
+		cfg.Options.IncomingAllowedNetworks = []string{}
+	}
+	if _, err := ParseAllowedNetworks(cfg.Options.IncomingAllowedNetworks); err != nil {
+		return fmt.Errorf("incoming allowed networks: %v", err)
+	}
 
 	return nil
 }
@@ -11,6 +11,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -18,12 +19,14 @@ import (
 	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/d4l3k/messagediff"
 	"github.com/syncthing/syncthing/lib/events"
 	"github.com/syncthing/syncthing/lib/fs"
 	"github.com/syncthing/syncthing/lib/osutil"
 	"github.com/syncthing/syncthing/lib/protocol"
+	"golang.org/x/text/unicode/norm"
 )
 
 var device1, device2, device3, device4 protocol.DeviceID
@@ -37,44 +40,54 @@ func init() {
 
 func TestDefaultValues(t *testing.T) {
 	expected := OptionsConfiguration{
-		RawListenAddresses:      []string{"default"},
-		RawGlobalAnnServers:     []string{"default"},
-		GlobalAnnEnabled:        true,
-		LocalAnnEnabled:         true,
-		LocalAnnPort:            21027,
-		LocalAnnMCAddr:          "[ff12::8384]:21027",
-		MaxSendKbps:             0,
-		MaxRecvKbps:             0,
-		ReconnectIntervalS:      60,
-		RelaysEnabled:           true,
-		RelayReconnectIntervalM: 10,
-		StartBrowser:            true,
-		NATEnabled:              true,
-		NATLeaseM:               60,
-		NATRenewalM:             30,
-		NATTimeoutS:             10,
-		RestartOnWakeup:         true,
-		AutoUpgradeIntervalH:    12,
-		KeepTemporariesH:        24,
-		CacheIgnoredFiles:       false,
-		ProgressUpdateIntervalS: 5,
-		LimitBandwidthInLan:     false,
-		MinHomeDiskFree:         Size{1, "%"},
-		URURL:                   "https://data.syncthing.net/newdata",
-		URInitialDelayS:         1800,
-		URPostInsecurely:        false,
-		ReleasesURL:             "https://upgrades.syncthing.net/meta.json",
-		AlwaysLocalNets:         []string{},
-		OverwriteRemoteDevNames: false,
-		TempIndexMinBlocks:      10,
-		UnackedNotificationIDs:  []string{},
-		DefaultFolderPath:       "~",
-		SetLowPriority:          true,
-		CRURL:                   "https://crash.syncthing.net/newcrash",
-		CREnabled:               true,
-		StunKeepaliveStartS:     180,
-		StunKeepaliveMinS:       20,
-		RawStunServers:          []string{"default"},
+		RawListenAddresses:       []string{"default"},
+		RawGlobalAnnServers:      []string{"default"},
+		GlobalAnnEnabled:         true,
+		LocalAnnEnabled:          true,
+		LocalAnnPort:             21027,
+		LocalAnnMCAddr:           "[ff12::8384]:21027",
+		MaxSendKbps:              0,
+		MaxRecvKbps:              0,
+		ReconnectIntervalS:       60,
+		RelaysEnabled:            true,
+		RelayReconnectIntervalM:  10,
+		StartBrowser:             true,
+		NATEnabled:               true,
+		NATLeaseM:                60,
+		NATRenewalM:              30,
+		NATTimeoutS:              10,
+		RestartOnWakeup:          true,
+		AutoUpgradeIntervalH:     12,
+		KeepTemporariesH:         24,
+		CacheIgnoredFiles:        false,
+		ProgressUpdateIntervalS:  5,
+		LimitBandwidthInLan:      false,
+		MinHomeDiskFree:          Size{1, "%"},
+		URURL:                    "https://data.syncthing.net/newdata",
+		URInitialDelayS:          1800,
+		URPostInsecurely:         false,
+		ReleasesURL:              "https://upgrades.syncthing.net/meta.json",
+		LocalAnnInterfaces:       []string{},
+		LocalAnnUnicastHosts:     []string{},
+		AlwaysLocalNets:          []string{},
+		OverwriteRemoteDevNames:  false,
+		TempIndexMinBlocks:       10,
+		UnackedNotificationIDs:   []string{},
+		DefaultFolderPath:        "~",
+		SetLowPriority:           true,
+		CRURL:                    "https://crash.syncthing.net/newcrash",
+		CREnabled:                true,
+		StunKeepaliveStartS:      180,
+		StunKeepaliveMinS:        20,
+		ConnectionCycleIntervalS: 600,
+		RawStunServers:           []string{"default"},
+		LegacyCompletionEvents:   true,
+		TarpitDelayS:             60,
+		IncomingAllowedNetworks:  []string{},
+		DatabaseSyncIntervalS:    10,
+		PersistentEventTypes:     []string{"ItemFinished", "FolderErrors", "DeviceConnected", "DeviceDisconnected"},
+		PersistentEventsMax:      10000,
+		PersistentEventsMaxAgeH:  168,
 	}
 
 	cfg := New(device1)
@@ -188,46 +201,60 @@ func TestNoListenAddresses(t *testing.T) {
 
 func TestOverriddenValues(t *testing.T) {
 	expected := OptionsConfiguration{
-		RawListenAddresses:      []string{"tcp://:23000"},
-		RawGlobalAnnServers:     []string{"udp4://syncthing.nym.se:22026"},
-		GlobalAnnEnabled:        false,
-		LocalAnnEnabled:         false,
-		LocalAnnPort:            42123,
-		LocalAnnMCAddr:          "quux:3232",
-		MaxSendKbps:             1234,
-		MaxRecvKbps:             2341,
-		ReconnectIntervalS:      6000,
-		RelaysEnabled:           false,
-		RelayReconnectIntervalM: 20,
-		StartBrowser:            false,
-		NATEnabled:              false,
-		NATLeaseM:               90,
-		NATRenewalM:             15,
-		NATTimeoutS:             15,
-		RestartOnWakeup:         false,
-		AutoUpgradeIntervalH:    24,
-		KeepTemporariesH:        48,
-		CacheIgnoredFiles:       true,
-		ProgressUpdateIntervalS: 10,
-		LimitBandwidthInLan:     true,
-		MinHomeDiskFree:         Size{5.2, "%"},
-		URSeen:                  8,
-		URAccepted:              4,
-		URURL:                   "https://localhost/newdata",
-		URInitialDelayS:         800,
-		URPostInsecurely:        true,
-		ReleasesURL:             "https://localhost/releases",
-		AlwaysLocalNets:         []string{},
-		OverwriteRemoteDevNames: true,
-		TempIndexMinBlocks:      100,
-		UnackedNotificationIDs:  []string{"asdfasdf"},
-		DefaultFolderPath:       "/media/syncthing",
-		SetLowPriority:          false,
-		CRURL:                   "https://localhost/newcrash",
-		CREnabled:               false,
-		StunKeepaliveStartS:     9000,
-		StunKeepaliveMinS:       900,
-		RawStunServers:          []string{"foo"},
+		RawListenAddresses:        []string{"tcp://:23000"},
+		RawGlobalAnnServers:       []string{"udp4://syncthing.nym.se:22026"},
+		GlobalAnnEnabled:          false,
+		LocalAnnEnabled:           false,
+		LocalAnnPort:              42123,
+		LocalAnnMCAddr:            "quux:3232",
+		MaxSendKbps:               1234,
+		MaxRecvKbps:               2341,
+		ReconnectIntervalS:        6000,
+		RelaysEnabled:             false,
+		RelayReconnectIntervalM:   20,
+		StartBrowser:              false,
+		NATEnabled:                false,
+		NATLeaseM:                 90,
+		NATRenewalM:               15,
+		NATTimeoutS:               15,
+		RestartOnWakeup:           false,
+		AutoUpgradeIntervalH:      24,
+		KeepTemporariesH:          48,
+		CacheIgnoredFiles:         true,
+		ProgressUpdateIntervalS:   10,
+		LimitBandwidthInLan:       true,
+		MinHomeDiskFree:           Size{5.2, "%"},
+		URSeen:                    8,
+		URAccepted:                4,
+		URURL:                     "https://localhost/newdata",
+		URInitialDelayS:           800,
+		URPostInsecurely:          true,
+		ReleasesURL:               "https://localhost/releases",
+		LocalAnnInterfaces:        []string{"eth0", "wlan0"},
+		LocalAnnUnicastHosts:      []string{"192.0.2.42", "nas.local:21025"},
+		AlwaysLocalNets:           []string{},
+		OverwriteRemoteDevNames:   true,
+		TempIndexMinBlocks:        100,
+		UnackedNotificationIDs:    []string{"asdfasdf"},
+		DefaultFolderPath:         "/media/syncthing",
+		SetLowPriority:            false,
+		CRURL:                     "https://localhost/newcrash",
+		CREnabled:                 false,
+		StunKeepaliveStartS:       9000,
+		StunKeepaliveMinS:         900,
+		RawStunServers:            []string{"foo"},
+		MaxConnections:            50,
+		ConnectionCycleIntervalS:  300,
+		LegacyCompletionEvents:    false,
+		TarpitUnknownDevicesAfter: 5,
+		TarpitDelayS:              120,
+		IncomingAllowedNetworks:   []string{"192.168.0.0/16", "!192.168.1.0/24"},
+		DatabaseDurability:        DatabaseDurabilityPeriodic,
+		DatabaseSyncIntervalS:     60,
+		PersistentEventsEnabled:   true,
+		PersistentEventTypes:      []string{"ItemFinished"},
+		PersistentEventsMax:       500,
+		PersistentEventsMaxAgeH:   24,
 	}
 
 	os.Unsetenv("STNOUPGRADE")
@@ -403,6 +430,14 @@ func TestVersioningConfig(t *testing.T) {
 	if diff, equal := messagediff.PrettyDiff(expected, vc.Params); !equal {
 		t.Errorf("vc.Params differ. Diff:\n%s", diff)
 	}
+
+	expectedOverrides := []VersioningOverride{
+		{Pattern: "cache", Params: map[string]string{}},
+		{Pattern: "documents", Type: "staggered", Params: map[string]string{"maxAge": "7776000"}},
+	}
+	if diff, equal := messagediff.PrettyDiff(expectedOverrides, vc.Overrides); !equal {
+		t.Errorf("vc.Overrides differ. Diff:\n%s", diff)
+	}
 }
 
 func TestIssue1262(t *testing.T) {
@@ -722,6 +757,22 @@ func TestDuplicateFolders(t *testing.T) {
 	}
 }
 
+func TestInvalidIncomingAllowedNetworks(t *testing.T) {
+	// Networks that can't be parsed are a loading error, rather than being
+	// skipped and letting in what they were meant to keep out.
+
+	cfg := New(device1)
+	cfg.Options.IncomingAllowedNetworks = []string{"192.168.0.0/16", "!10.0.0.0/88"}
+	if err := cfg.prepare(device1); err == nil {
+		t.Error("Expected an error for an invalid network")
+	}
+
+	cfg.Options.IncomingAllowedNetworks = []string{"192.0.2.1", "!2001:db8::1"}
+	if err := cfg.prepare(device1); err != nil {
+		t.Error("Unexpected error for bare addresses:", err)
+	}
+}
+
 func TestEmptyFolderPaths(t *testing.T) {
 	// Empty folder paths are not allowed at the loading stage, and should not
 	// get messed up by the prepare steps (e.g., become the current dir or
@@ -1180,3 +1231,257 @@ func load(path string, myID protocol.DeviceID) (Wrapper, error) {
 func wrap(path string, cfg Configuration) Wrapper {
 	return Wrap(path, cfg, events.NoopLogger)
 }
+
+func TestTempIndexPolicy(t *testing.T) {
+	cases := []struct {
+		policy    TempIndexPolicy
+		disabled  bool
+		advertise bool
+		use       bool
+	}{
+		{TempIndexAll, false, true, true},
+		{TempIndexAdvertiseOnly, false, true, false},
+		{TempIndexUseOnly, false, false, true},
+		{TempIndexNone, false, false, false},
+		{TempIndexAll, true, false, false},
+	}
+
+	for _, tc := range cases {
+		fcfg := FolderConfiguration{TempIndexPolicy: tc.policy, DisableTempIndexes: tc.disabled}
+		if res := fcfg.AdvertisesTempIndexes(); res != tc.advertise {
+			t.Errorf("%v (disabled %v): advertises %v, expected %v", tc.policy, tc.disabled, res, tc.advertise)
+		}
+		if res := fcfg.UsesTempIndexes(); res != tc.use {
+			t.Errorf("%v (disabled %v): uses %v, expected %v", tc.policy, tc.disabled, res, tc.use)
+		}
+
+		bs, _ := tc.policy.MarshalText()
+		var p TempIndexPolicy
+		if err := p.UnmarshalText(bs); err != nil || p != tc.policy {
+			t.Errorf("%v: round trip gave %v, %v", tc.policy, p, err)
+		}
+	}
+}
+
+func TestUnicodeNormalization(t *testing.T) {
+	native := norm.NFC
+	if runtime.GOOS == "darwin" {
+		native = norm.NFD
+	}
+	cases := []struct {
+		policy UnicodeNormalization
+		form   norm.Form
+	}{
+		{NormalizationNative, native},
+		{NormalizationNFC, norm.NFC},
+		{NormalizationNFD, norm.NFD},
+	}
+
+	for _, tc := range cases {
+		if form := tc.policy.Form(); form != tc.form {
+			t.Errorf("%v: form %v, expected %v", tc.policy, form, tc.form)
+		}
+
+		bs, _ := tc.policy.MarshalText()
+		var n UnicodeNormalization
+		if err := n.UnmarshalText(bs); err != nil || n != tc.policy {
+			t.Errorf("%v: round trip gave %v, %v", tc.policy, n, err)
+		}
+	}
+}
+
+func TestInvalidNamePolicy(t *testing.T) {
+	for _, policy := range []InvalidNamePolicy{InvalidNameFail, InvalidNameEscape} {
+		bs, _ := policy.MarshalText()
+		var p InvalidNamePolicy
+		if err := p.UnmarshalText(bs); err != nil || p != policy {
+			t.Errorf("%v: round trip gave %v, %v", policy, p, err)
+		}
+	}
+}
+
+func TestDatabaseDurability(t *testing.T) {
+	for _, d := range []DatabaseDurability{DatabaseDurabilityDefault, DatabaseDurabilityBatch, DatabaseDurabilityPeriodic, DatabaseDurabilityRelaxed} {
+		bs, _ := d.MarshalText()
+		var u DatabaseDurability
+		if err := u.UnmarshalText(bs); err != nil || u != d {
+			t.Errorf("%v: round trip gave %v, %v", d, u, err)
+		}
+	}
+	var d DatabaseDurability
+	if err := d.UnmarshalText([]byte("sometimes")); err == nil {
+		t.Error("Expected an error for an unknown durability")
+	}
+}
+
+func TestFolderDeviceDeniesPath(t *testing.T) {
+	fcfg := FolderConfiguration{
+		Devices: []FolderDeviceConfiguration{
+			{DeviceID: device1, DeniedPaths: []string{"/secret/", "a/b"}},
+			{DeviceID: device2},
+		},
+	}
+	fcfg.prepare()
+
+	dev, ok := fcfg.Device(device1)
+	if !ok {
+		t.Fatal("device1 not found")
+	}
+	cases := map[string]bool{
+		"secret":                                 true,
+		filepath.Join("secret", "file"):          true,
+		"secrets":                                false,
+		"a":                                      false,
+		filepath.Join("a", "b"):                  true,
+		filepath.Join("a", "b", "c"):             true,
+		filepath.Join("a", "bc"):                 false,
+		filepath.Join("other", "secret", "file"): false,
+	}
+	for name, exp := range cases {
+		if res := dev.DeniesPath(name); res != exp {
+			t.Errorf("DeniesPath(%q) = %v, expected %v", name, res, exp)
+		}
+	}
+
+	dev, _ = fcfg.Device(device2)
+	if dev.DeniesPath("secret") {
+		t.Error("device2 should not be denied anything")
+	}
+
+	// Copies don't share the list of denied paths.
+	c := fcfg.Copy()
+	c.Devices[0].DeniedPaths[0] = "changed"
+	if fcfg.Devices[0].DeniedPaths[0] != "secret" {
+		t.Error("Copy shares denied paths with the original")
+	}
+}
+
+func TestMarkerID(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syncthing-marker-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fcfg := FolderConfiguration{
+		FilesystemType: fs.FilesystemTypeBasic,
+		Path:           dir,
+	}
+	fcfg.prepare()
+	if id := fcfg.MarkerID(); id != "" {
+		t.Errorf("Got marker ID %q without a marker", id)
+	}
+
+	if err := fcfg.CreateMarker(); err != nil {
+		t.Fatal(err)
+	}
+	id := fcfg.MarkerID()
+	if id == "" {
+		t.Fatal("Expected a marker ID for a new marker")
+	}
+	if again := fcfg.MarkerID(); again != id {
+		t.Errorf("Marker ID changed from %q to %q", id, again)
+	}
+
+	if err := fcfg.SetMarkerID("adopted"); err != nil {
+		t.Fatal(err)
+	}
+	if id := fcfg.MarkerID(); id != "adopted" {
+		t.Errorf("Got marker ID %q after setting it, expected adopted", id)
+	}
+
+	// Markers predating IDs don't get one by reading it.
+	if err := fcfg.Filesystem().Remove(filepath.Join(DefaultMarkerName, markerIDFile)); err != nil {
+		t.Fatal(err)
+	}
+	if id := fcfg.MarkerID(); id != "" {
+		t.Errorf("Got marker ID %q for a marker predating IDs", id)
+	}
+	if _, err := fcfg.Filesystem().Lstat(filepath.Join(DefaultMarkerName, markerIDFile)); !fs.IsNotExist(err) {
+		t.Errorf("Reading the marker ID created it: %v", err)
+	}
+
+	fcfg.MarkerName = "custom"
+	if id := fcfg.MarkerID(); id != "" {
+		t.Errorf("Got marker ID %q for a custom marker", id)
+	}
+}
+
+func TestAPITokens(t *testing.T) {
+	gui := GUIConfiguration{
+		APIKey: "main",
+		APITokens: []APIToken{
+			{Name: "monitoring", Key: "status", Scopes: []APIScope{APIScopeStatus, APIScopeEvents}},
+			{Name: "expired", Key: "expired", Scopes: []APIScope{APIScopeStatus}, Expires: time.Now().Add(-time.Minute)},
+			{Name: "temporary", Key: "temporary", Scopes: []APIScope{APIScopeControl}, Expires: time.Now().Add(time.Hour)},
+		},
+	}
+
+	cases := []struct {
+		key   string
+		valid bool
+		scope APIScope
+		allow bool
+	}{
+		{"main", true, APIScopeConfigWrite, true},
+		{"status", true, APIScopeStatus, true},
+		{"status", true, APIScopeEvents, true},
+		{"status", true, APIScopeConfigRead, false},
+		{"expired", false, APIScopeStatus, false},
+		{"temporary", true, APIScopeControl, true},
+		{"temporary", true, APIScopeStatus, false},
+		{"unknown", false, APIScopeStatus, false},
+		{"", false, APIScopeStatus, false},
+	}
+	for _, tc := range cases {
+		if valid := gui.IsValidAPIKey(tc.key); valid != tc.valid {
+			t.Errorf("IsValidAPIKey(%q) = %v, expected %v", tc.key, valid, tc.valid)
+		}
+		if allow := gui.APIKeyAllows(tc.key, tc.scope); allow != tc.allow {
+			t.Errorf("APIKeyAllows(%q, %v) = %v, expected %v", tc.key, tc.scope, allow, tc.allow)
+		}
+	}
+
+	cp := gui.Copy()
+	cp.APITokens[0].Scopes[0] = APIScopeControl
+	if gui.APITokens[0].Scopes[0] != APIScopeStatus {
+		t.Error("Copy is not a deep copy")
+	}
+}
+
+func TestGUIProxyOptions(t *testing.T) {
+	gui := GUIConfiguration{
+		RawAddress:    "192.0.2.42:8080",
+		RawPathPrefix: "syncthing/",
+		TrustedProxies: []string{
+			"10.0.0.0/8",
+			"2001:db8::1",
+		},
+	}
+
+	if p := gui.PathPrefix(); p != "/syncthing" {
+		t.Errorf("Unexpected path prefix %q", p)
+	}
+	if u := gui.URL(); u != "http://192.0.2.42:8080/syncthing/" {
+		t.Errorf("Unexpected URL %q", u)
+	}
+
+	for addr, trusted := range map[string]bool{
+		"10.1.2.3":    true,
+		"2001:db8::1": true,
+		"2001:db8::2": false,
+		"192.0.2.1":   false,
+	} {
+		if gui.IsTrustedProxy(net.ParseIP(addr)) != trusted {
+			t.Errorf("%s: expected trusted %v", addr, trusted)
+		}
+	}
+}
+
+func TestMaxSyncingFolders(t *testing.T) {
+	for input, output := range map[int]int{-1: 0, 0: 0, 2: 2} {
+		if res := (OptionsConfiguration{RawMaxSyncingFolders: input}).MaxSyncingFolders(); res != output {
+			t.Errorf("Wrong MaxSyncingFolders, %d => %d, expected %d", input, res, output)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package config
+
+import "fmt"
+
+// DatabaseDurability is how often the database makes sure that what is
+// written to it is on disk, trading writes for what may be lost on a crash
+// of the OS or a power loss. Crashes of Syncthing itself lose nothing in
+// any mode.
+type DatabaseDurability int
+
+const (
+	// N.b. these constants must match those in lib/db/backend.Durability!
+
+	// DatabaseDurabilityDefault syncs the tables of the database, but
+	// leaves the journal of recent writes to the OS. Recent writes may be
+	// lost, but the database stays consistent.
+	DatabaseDurabilityDefault DatabaseDurability = iota
+	// DatabaseDurabilityBatch also syncs the journal on every write, so
+	// nothing written is lost. It writes the most.
+	DatabaseDurabilityBatch
+	// DatabaseDurabilityPeriodic syncs the journal every sync interval
+	// when there were writes since, so at most about an interval of
+	// writes is lost.
+	DatabaseDurabilityPeriodic
+	// DatabaseDurabilityRelaxed syncs nothing and writes the least, e.g.
+	// for SD cards. The database may get corrupted by a power loss, in
+	// which case it is recreated and all folders are scanned and their
+	// indexes exchanged again.
+	DatabaseDurabilityRelaxed
+)
+
+func (d DatabaseDurability) String() string {
+	switch d {
+	case DatabaseDurabilityDefault:
+		return "default"
+	case DatabaseDurabilityBatch:
+		return "batch"
+	case DatabaseDurabilityPeriodic:
+		return "periodic"
+	case DatabaseDurabilityRelaxed:
+		return "relaxed"
+	default:
+		return "unknown"
+	}
+}
+
+func (d DatabaseDurability) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *DatabaseDurability) UnmarshalText(bs []byte) error {
+	switch string(bs) {
+	case "default":
+		*d = DatabaseDurabilityDefault
+	case "batch":
+		*d = DatabaseDurabilityBatch
+	case "periodic":
+		*d = DatabaseDurabilityPeriodic
+	case "relaxed":
+		*d = DatabaseDurabilityRelaxed
+	default:
+		return fmt.Errorf("unknown database durability %q", bs)
+	}
+	return nil
+}
@@ -30,6 +30,11 @@ type DeviceConfiguration struct {
 	IgnoredFolders           []ObservedFolder     `xml:"ignoredFolder" json:"ignoredFolders"`
 	PendingFolders           []ObservedFolder     `xml:"pendingFolder" json:"pendingFolders"`
 	MaxRequestKiB            int                  `xml:"maxRequestKiB" json:"maxRequestKiB"`
+	DonorMode                bool                 `xml:"donorMode" json:"donorMode"`                     // Only serve requests while we are not busy pulling ourselves.
+	DonorModeMaxSyncing      int                  `xml:"donorModeMaxSyncing" json:"donorModeMaxSyncing"` // In donor mode, the number of our folders that may be syncing while still serving requests.
+	Priority                 int                  `xml:"priority" json:"priority"`                       // Devices with a higher priority stay connected first when there are more than maxConnections.
+	Via                      []protocol.DeviceID  `xml:"via,omitempty" json:"via"`                       // Devices to have forward our connections to this one, when it can't be reached directly.
+	ForwardTo                []protocol.DeviceID  `xml:"forwardTo,omitempty" json:"forwardTo"`           // Devices this one may have us forward its connections to.
 }
 
 func NewDeviceConfiguration(id protocol.DeviceID, name string) DeviceConfiguration {
@@ -54,9 +59,28 @@ func (cfg DeviceConfiguration) Copy() DeviceConfiguration {
 	copy(c.IgnoredFolders, cfg.IgnoredFolders)
 	c.PendingFolders = make([]ObservedFolder, len(cfg.PendingFolders))
 	copy(c.PendingFolders, cfg.PendingFolders)
+	if cfg.Via != nil {
+		c.Via = make([]protocol.DeviceID, len(cfg.Via))
+		copy(c.Via, cfg.Via)
+	}
+	if cfg.ForwardTo != nil {
+		c.ForwardTo = make([]protocol.DeviceID, len(cfg.ForwardTo))
+		copy(c.ForwardTo, cfg.ForwardTo)
+	}
 	return c
 }
 
+// MayForwardTo returns whether the device may have us forward its
+// connections to the given one.
+func (cfg DeviceConfiguration) MayForwardTo(id protocol.DeviceID) bool {
+	for _, fwd := range cfg.ForwardTo {
+		if fwd == id {
+			return true
+		}
+	}
+	return false
+}
+
 func (cfg *DeviceConfiguration) prepare(sharedFolders []string) {
 	if len(cfg.Addresses) == 0 || len(cfg.Addresses) == 1 && cfg.Addresses[0] == "" {
 		cfg.Addresses = []string{"dynamic"}
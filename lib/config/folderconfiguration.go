@@ -9,6 +9,8 @@ package config
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"time"
@@ -17,6 +19,7 @@ import (
 
 	"github.com/syncthing/syncthing/lib/fs"
 	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/rand"
 	"github.com/syncthing/syncthing/lib/util"
 )
 
@@ -28,6 +31,9 @@ var (
 
 const DefaultMarkerName = ".stfolder"
 
+// The file within the default marker directory that holds the marker ID.
+const markerIDFile = "id"
+
 type FolderConfiguration struct {
 	ID                      string                      `xml:"id,attr" json:"id"`
 	Label                   string                      `xml:"label,attr" json:"label" restart:"false"`
@@ -40,26 +46,47 @@ type FolderConfiguration struct {
 	FSWatcherDelayS         int                         `xml:"fsWatcherDelayS,attr" json:"fsWatcherDelayS" default:"10"`
 	IgnorePerms             bool                        `xml:"ignorePerms,attr" json:"ignorePerms"`
 	AutoNormalize           bool                        `xml:"autoNormalize,attr" json:"autoNormalize" default:"true"`
+	UnicodeNormalization    UnicodeNormalization        `xml:"unicodeNormalization" json:"unicodeNormalization"`
+	InvalidNamePolicy       InvalidNamePolicy           `xml:"invalidNamePolicy" json:"invalidNamePolicy"`
+	CaseConflictSuffix      string                      `xml:"caseConflictSuffix" json:"caseConflictSuffix"` // On a case insensitive filesystem, the local item an incoming one differs from only in case is renamed with this before its extension, instead of reporting the conflict. Only reported if empty.
 	MinDiskFree             Size                        `xml:"minDiskFree" json:"minDiskFree" default:"1%"`
 	Versioning              VersioningConfiguration     `xml:"versioning" json:"versioning"`
 	Copiers                 int                         `xml:"copiers" json:"copiers"` // This defines how many files are handled concurrently.
 	PullerMaxPendingKiB     int                         `xml:"pullerMaxPendingKiB" json:"pullerMaxPendingKiB"`
 	Hashers                 int                         `xml:"hashers" json:"hashers"` // Less than one sets the value to the number of cores. These are CPU bound due to hashing.
 	Order                   PullOrder                   `xml:"order" json:"order"`
+	Priority                int                         `xml:"priority" json:"priority"`                // Folders with a higher priority are scanned and pulled first when waiting for their turn.
+	PinnedPaths             []string                    `xml:"pinnedPath,omitempty" json:"pinnedPaths"` // Files and subtrees pulled before anything else in the folder.
+	SyncWindows             []string                    `xml:"syncWindow,omitempty" json:"syncWindows"` // When the folder may be scanned and pulled, see SyncWindow. Any time if empty.
+	AtomicApply             bool                        `xml:"atomicApply" json:"atomicApply"`          // Apply all changes of a pull together at the end, so that they're never seen half applied.
+	BlockPullOrder          BlockPullOrder              `xml:"blockPullOrder" json:"blockPullOrder"`
 	IgnoreDelete            bool                        `xml:"ignoreDelete" json:"ignoreDelete"`
 	ScanProgressIntervalS   int                         `xml:"scanProgressIntervalS" json:"scanProgressIntervalS"` // Set to a negative value to disable. Value of 0 will get replaced with value of 2 (default value)
 	PullerPauseS            int                         `xml:"pullerPauseS" json:"pullerPauseS"`
 	MaxConflicts            int                         `xml:"maxConflicts" json:"maxConflicts" default:"-1"`
 	DisableSparseFiles      bool                        `xml:"disableSparseFiles" json:"disableSparseFiles"`
 	DisableTempIndexes      bool                        `xml:"disableTempIndexes" json:"disableTempIndexes"`
+	TempIndexPolicy         TempIndexPolicy             `xml:"tempIndexPolicy" json:"tempIndexPolicy"`
 	Paused                  bool                        `xml:"paused" json:"paused"`
 	WeakHashThresholdPct    int                         `xml:"weakHashThresholdPct" json:"weakHashThresholdPct"` // Use weak hash if more than X percent of the file has changed. Set to -1 to always use weak hash.
 	MarkerName              string                      `xml:"markerName" json:"markerName"`
 	CopyOwnershipFromParent bool                        `xml:"copyOwnershipFromParent" json:"copyOwnershipFromParent"`
 	RawModTimeWindowS       int                         `xml:"modTimeWindowS" json:"modTimeWindowS"`
+	IgnoreMarkerMismatch    bool                        `xml:"ignoreMarkerMismatch" json:"ignoreMarkerMismatch"` // Share with devices whose folder seems unrelated to ours.
+	SyncIgnores             bool                        `xml:"syncIgnores" json:"syncIgnores"`                   // Keep a copy of .stignore in the synced .stignore-sync, taking the patterns of other devices from it.
+	HonorGitignore          bool                        `xml:"honorGitignore" json:"honorGitignore"`             // Also ignore what the .gitignore files anywhere in the folder do.
+	MountPath               string                      `xml:"mountPath" json:"mountPath"`                       // Where the global versions of the files are mounted read only, read from other devices as needed. Not mounted if empty.
+	OverlayPath             string                      `xml:"overlayPath" json:"overlayPath"`                   // Where changes are written instead of to the path, which is then only read from, e.g. on read only media or a snapshot. The path is written to if empty.
+	SyncXattrs              bool                        `xml:"syncXattrs" json:"syncXattrs"`                     // Scan and apply the extended attributes of files, including ACLs on Linux, between devices on the same OS.
+	XattrFilter             XattrFilter                 `xml:"xattrFilter,omitempty" json:"xattrFilter"`         // The extended attributes that are synced, those in the user namespace if empty.
+	ScanSnapshot            ScanSnapshot                `xml:"scanSnapshot" json:"scanSnapshot"`                 // Snapshot the folder before each scan and scan the snapshot instead, for files to be hashed consistently while being written.
+	ScanSnapshotCommand     string                      `xml:"scanSnapshotCommand" json:"scanSnapshotCommand"`   // With the "command" scan snapshot, takes a snapshot of %FOLDER_PATH% and prints the path of the folder within it.
+	ScanSnapshotRelease     string                      `xml:"scanSnapshotRelease" json:"scanSnapshotRelease"`   // With the "command" scan snapshot, removes the snapshot at %SNAPSHOT_PATH% after the scan. Nothing is run if empty.
+	PullBudgetMiB           int                         `xml:"pullBudgetMiB" json:"pullBudgetMiB"`               // How much is pulled from other devices before pulling stops at a checkpoint, until it is resumed through the API. No limit if 0.
 
 	cachedFilesystem    fs.Filesystem
 	cachedModTimeWindow time.Duration
+	cachedSyncWindows   []SyncWindow
 
 	DeprecatedReadOnly       bool    `xml:"ro,attr,omitempty" json:"-"`
 	DeprecatedMinDiskFreePct float64 `xml:"minDiskFreePct,omitempty" json:"-"`
@@ -67,8 +94,21 @@ type FolderConfiguration struct {
 }
 
 type FolderDeviceConfiguration struct {
-	DeviceID     protocol.DeviceID `xml:"id,attr" json:"deviceID"`
-	IntroducedBy protocol.DeviceID `xml:"introducedBy,attr" json:"introducedBy"`
+	DeviceID       protocol.DeviceID `xml:"id,attr" json:"deviceID"`
+	IntroducedBy   protocol.DeviceID `xml:"introducedBy,attr" json:"introducedBy"`
+	DeniedPaths    []string          `xml:"deniedPath,omitempty" json:"deniedPaths"`       // Subtrees the device may never read.
+	IgnorePatterns []string          `xml:"ignorePattern,omitempty" json:"ignorePatterns"` // Patterns like those of .stignore for what isn't synced to the device, which gets those files as invalid.
+}
+
+// DeniesPath returns true if the file or directory at the given path is
+// within one of the subtrees the device may not read.
+func (d FolderDeviceConfiguration) DeniesPath(name string) bool {
+	for _, denied := range d.DeniedPaths {
+		if name == denied || strings.HasPrefix(name, denied+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
 }
 
 func NewFolderConfiguration(myID protocol.DeviceID, id, label string, fsType fs.FilesystemType, path string) FolderConfiguration {
@@ -90,20 +130,60 @@ func (f FolderConfiguration) Copy() FolderConfiguration {
 	c := f
 	c.Devices = make([]FolderDeviceConfiguration, len(f.Devices))
 	copy(c.Devices, f.Devices)
+	for i := range c.Devices {
+		c.Devices[i].DeniedPaths = append([]string(nil), f.Devices[i].DeniedPaths...)
+		c.Devices[i].IgnorePatterns = append([]string(nil), f.Devices[i].IgnorePatterns...)
+	}
+	c.PinnedPaths = append([]string(nil), f.PinnedPaths...)
+	c.SyncWindows = append([]string(nil), f.SyncWindows...)
+	c.XattrFilter = append(XattrFilter(nil), f.XattrFilter...)
 	c.Versioning = f.Versioning.Copy()
 	return c
 }
 
+// PinsPath returns true if the file or directory at the given path is one
+// of, or within one of, the pinned paths.
+func (f FolderConfiguration) PinsPath(name string) bool {
+	for _, pinned := range f.PinnedPaths {
+		if name == pinned || strings.HasPrefix(name, pinned+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
 func (f FolderConfiguration) Filesystem() fs.Filesystem {
 	// This is intentionally not a pointer method, because things like
 	// cfg.Folders["default"].Filesystem() should be valid.
 	if f.cachedFilesystem == nil {
 		l.Infoln("bug: uncached filesystem call (should only happen in tests)")
-		return fs.NewFilesystem(f.FilesystemType, f.Path)
+		return f.newFilesystem()
 	}
 	return f.cachedFilesystem
 }
 
+func (f FolderConfiguration) newFilesystem() fs.Filesystem {
+	filesystem := fs.NewFilesystem(f.FilesystemType, f.Path)
+	if f.OverlayPath != "" {
+		filesystem = fs.NewOverlayFS(filesystem, fs.NewFilesystem(fs.FilesystemTypeBasic, f.OverlayPath))
+	}
+	return filesystem
+}
+
+// InSyncWindow returns whether the folder may be scanned and pulled at the
+// given time, which is always when it has no (valid) sync windows.
+func (f FolderConfiguration) InSyncWindow(t time.Time) bool {
+	if len(f.cachedSyncWindows) == 0 {
+		return true
+	}
+	for _, w := range f.cachedSyncWindows {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
 func (f FolderConfiguration) ModTimeWindow() time.Duration {
 	return f.cachedModTimeWindow
 }
@@ -137,9 +217,50 @@ func (f *FolderConfiguration) CreateMarker() error {
 	}
 	fs.Hide(DefaultMarkerName)
 
+	if err := f.SetMarkerID(rand.String(32)); err != nil {
+		l.Debugln("folder marker: writing ID failed:", err)
+	}
+
 	return nil
 }
 
+// MarkerID returns the random identifier stored in the folder marker, which
+// tells apart unrelated folders that happen to have the same folder ID, or
+// an empty string if it's unknown. Markers predating identifiers and custom
+// markers don't have one.
+func (f *FolderConfiguration) MarkerID() string {
+	if f.MarkerName != DefaultMarkerName {
+		return ""
+	}
+	fd, err := f.Filesystem().Open(filepath.Join(DefaultMarkerName, markerIDFile))
+	if err != nil {
+		return ""
+	}
+	defer fd.Close()
+	bs, err := ioutil.ReadAll(fd)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(bs))
+}
+
+// SetMarkerID replaces the identifier stored in the folder marker, so that
+// the folder is recognized as the same as the one it was synced from.
+func (f *FolderConfiguration) SetMarkerID(id string) error {
+	if f.MarkerName != DefaultMarkerName {
+		return nil
+	}
+	fd, err := f.Filesystem().Create(filepath.Join(DefaultMarkerName, markerIDFile))
+	if err != nil {
+		return err
+	}
+	if _, err := fd.Write([]byte(id + "\n")); err != nil {
+		fd.Close()
+		return err
+	}
+	return fd.Close()
+}
+
 // CheckPath returns nil if the folder root exists and contains the marker file
 func (f *FolderConfiguration) CheckPath() error {
 	fi, err := f.Filesystem().Stat(".")
@@ -206,7 +327,7 @@ func (f *FolderConfiguration) DeviceIDs() []protocol.DeviceID {
 }
 
 func (f *FolderConfiguration) prepare() {
-	f.cachedFilesystem = fs.NewFilesystem(f.FilesystemType, f.Path)
+	f.cachedFilesystem = f.newFilesystem()
 
 	if f.RescanIntervalS > MaxRescanIntervalS {
 		f.RescanIntervalS = MaxRescanIntervalS
@@ -231,6 +352,26 @@ func (f *FolderConfiguration) prepare() {
 		f.MarkerName = DefaultMarkerName
 	}
 
+	// Denied paths are compared to file names as they are in the index.
+	for i := range f.Devices {
+		for j, denied := range f.Devices[i].DeniedPaths {
+			f.Devices[i].DeniedPaths[j] = filepath.Clean(filepath.FromSlash(strings.Trim(denied, "/")))
+		}
+	}
+	for i, pinned := range f.PinnedPaths {
+		f.PinnedPaths[i] = filepath.Clean(filepath.FromSlash(strings.Trim(pinned, "/")))
+	}
+
+	f.cachedSyncWindows = nil
+	for _, s := range f.SyncWindows {
+		w, err := ParseSyncWindow(s)
+		if err != nil {
+			l.Warnf("Ignoring sync window of folder %s: %v", f.Description(), err)
+			continue
+		}
+		f.cachedSyncWindows = append(f.cachedSyncWindows, w)
+	}
+
 	switch {
 	case f.RawModTimeWindowS > 0:
 		f.cachedModTimeWindow = time.Duration(f.RawModTimeWindowS) * time.Second
@@ -238,15 +379,29 @@ func (f *FolderConfiguration) prepare() {
 		if usage, err := disk.Usage(f.Filesystem().URI()); err != nil {
 			f.cachedModTimeWindow = 2 * time.Second
 			l.Debugf(`Detecting FS at "%v" on android: Setting mtime window to 2s: err == "%v"`, f.Path, err)
-		} else if usage.Fstype == "" || strings.Contains(strings.ToLower(usage.Fstype), "fat") {
+		} else if usage.Fstype == "" || hasCoarseModTimes(usage.Fstype) {
 			f.cachedModTimeWindow = 2 * time.Second
 			l.Debugf(`Detecting FS at "%v" on android: Setting mtime window to 2s: usage.Fstype == "%v"`, f.Path, usage.Fstype)
 		} else {
 			l.Debugf(`Detecting FS at %v on android: Leaving mtime window at 0: usage.Fstype == "%v"`, f.Path, usage.Fstype)
 		}
+	default:
+		// Elsewhere the filesystem type isn't always known, so only a
+		// filesystem known to be in the FAT family gets the window.
+		if usage, err := disk.Usage(f.Filesystem().URI()); err == nil && hasCoarseModTimes(usage.Fstype) {
+			f.cachedModTimeWindow = 2 * time.Second
+			l.Debugf(`Detecting FS at "%v": Setting mtime window to 2s: usage.Fstype == "%v"`, f.Path, usage.Fstype)
+		}
 	}
 }
 
+// hasCoarseModTimes returns true for filesystems that store modification
+// times with a precision of two seconds, such as FAT.
+func hasCoarseModTimes(fstype string) bool {
+	fstype = strings.ToLower(fstype)
+	return strings.Contains(fstype, "fat") || fstype == "msdos"
+}
+
 // RequiresRestartOnly returns a copy with only the attributes that require
 // restart on change.
 func (f FolderConfiguration) RequiresRestartOnly() FolderConfiguration {
@@ -275,6 +430,35 @@ func (f *FolderConfiguration) SharedWith(device protocol.DeviceID) bool {
 	return false
 }
 
+// AdvertisesTempIndexes returns true if partially downloaded files in this
+// folder should be announced to and served to other devices.
+func (f *FolderConfiguration) AdvertisesTempIndexes() bool {
+	if f.DisableTempIndexes {
+		return false
+	}
+	return f.TempIndexPolicy == TempIndexAll || f.TempIndexPolicy == TempIndexAdvertiseOnly
+}
+
+// UsesTempIndexes returns true if blocks from partially downloaded files on
+// other devices may be used when pulling this folder.
+func (f *FolderConfiguration) UsesTempIndexes() bool {
+	if f.DisableTempIndexes {
+		return false
+	}
+	return f.TempIndexPolicy == TempIndexAll || f.TempIndexPolicy == TempIndexUseOnly
+}
+
+// Device returns the configuration for how the folder is shared with the
+// given device, and false if it isn't.
+func (f *FolderConfiguration) Device(device protocol.DeviceID) (FolderDeviceConfiguration, bool) {
+	for _, dev := range f.Devices {
+		if dev.DeviceID == device {
+			return dev, true
+		}
+	}
+	return FolderDeviceConfiguration{}, false
+}
+
 func (f *FolderConfiguration) CheckAvailableSpace(req int64) error {
 	val := f.MinDiskFree.BaseValue()
 	if val <= 0 {
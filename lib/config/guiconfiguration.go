@@ -7,24 +7,33 @@
 package config
 
 import (
+	"net"
 	"net/url"
 	"os"
 	"strings"
+	"time"
 )
 
 type GUIConfiguration struct {
-	Enabled                   bool     `xml:"enabled,attr" json:"enabled" default:"true"`
-	RawAddress                string   `xml:"address" json:"address" default:"127.0.0.1:8384"`
-	User                      string   `xml:"user,omitempty" json:"user"`
-	Password                  string   `xml:"password,omitempty" json:"password"`
-	AuthMode                  AuthMode `xml:"authMode,omitempty" json:"authMode"`
-	RawUseTLS                 bool     `xml:"tls,attr" json:"useTLS"`
-	APIKey                    string   `xml:"apikey,omitempty" json:"apiKey"`
-	InsecureAdminAccess       bool     `xml:"insecureAdminAccess,omitempty" json:"insecureAdminAccess"`
-	Theme                     string   `xml:"theme" json:"theme" default:"default"`
-	Debugging                 bool     `xml:"debugging,attr" json:"debugging"`
-	InsecureSkipHostCheck     bool     `xml:"insecureSkipHostcheck,omitempty" json:"insecureSkipHostcheck"`
-	InsecureAllowFrameLoading bool     `xml:"insecureAllowFrameLoading,omitempty" json:"insecureAllowFrameLoading"`
+	Enabled                   bool       `xml:"enabled,attr" json:"enabled" default:"true"`
+	RawAddress                string     `xml:"address" json:"address" default:"127.0.0.1:8384"`
+	User                      string     `xml:"user,omitempty" json:"user"`
+	Password                  string     `xml:"password,omitempty" json:"password"`
+	AuthMode                  AuthMode   `xml:"authMode,omitempty" json:"authMode"`
+	RawUseTLS                 bool       `xml:"tls,attr" json:"useTLS"`
+	APIKey                    string     `xml:"apikey,omitempty" json:"apiKey"`
+	InsecureAdminAccess       bool       `xml:"insecureAdminAccess,omitempty" json:"insecureAdminAccess"`
+	Theme                     string     `xml:"theme" json:"theme" default:"default"`
+	Debugging                 bool       `xml:"debugging,attr" json:"debugging"`
+	InsecureSkipHostCheck     bool       `xml:"insecureSkipHostcheck,omitempty" json:"insecureSkipHostcheck"`
+	InsecureAllowFrameLoading bool       `xml:"insecureAllowFrameLoading,omitempty" json:"insecureAllowFrameLoading"`
+	GRPCEnabled               bool       `xml:"grpcEnabled,omitempty" json:"grpcEnabled"`
+	APITokens                 []APIToken `xml:"apiToken" json:"apiTokens"`
+	RateLimitRequestsPerS     int        `xml:"rateLimitRequestsPerS,omitempty" json:"rateLimitRequestsPerS"`
+	RateLimitBurst            int        `xml:"rateLimitBurst,omitempty" json:"rateLimitBurst" default:"20"`
+	CORSAllowedOrigins        []string   `xml:"corsAllowedOrigin" json:"corsAllowedOrigins"`
+	TrustedProxies            []string   `xml:"trustedProxy" json:"trustedProxies"`
+	RawPathPrefix             string     `xml:"pathPrefix,omitempty" json:"pathPrefix"`
 }
 
 func (c GUIConfiguration) IsAuthEnabled() bool {
@@ -95,7 +104,7 @@ func (c GUIConfiguration) URL() string {
 	u := url.URL{
 		Scheme: "http",
 		Host:   c.Address(),
-		Path:   "/",
+		Path:   c.PathPrefix() + "/",
 	}
 
 	if c.UseTLS() {
@@ -116,8 +125,49 @@ func (c GUIConfiguration) URL() string {
 	return u.String()
 }
 
+// PathPrefix returns the path the GUI and API are served under, as
+// "/prefix" without trailing slash, or the empty string when served at the
+// root.
+func (c GUIConfiguration) PathPrefix() string {
+	prefix := strings.Trim(c.RawPathPrefix, "/")
+	if prefix == "" {
+		return ""
+	}
+	return "/" + prefix
+}
+
+// IsCORSOriginAllowed returns true when cross origin requests from the given
+// origin are allowed, by it or "*" being in the list of allowed origins.
+func (c GUIConfiguration) IsCORSOriginAllowed(origin string) bool {
+	if origin == "" {
+		return false
+	}
+	for _, allowed := range c.CORSAllowedOrigins {
+		if allowed == "*" || strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTrustedProxy returns true when the given address is that of a reverse
+// proxy whose X-Forwarded-For and X-Forwarded-Proto headers can be trusted.
+// Trusted proxies are given as addresses or networks in CIDR notation.
+func (c GUIConfiguration) IsTrustedProxy(ip net.IP) bool {
+	for _, proxy := range c.TrustedProxies {
+		if _, network, err := net.ParseCIDR(proxy); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+		} else if proxyIP := net.ParseIP(proxy); proxyIP != nil && proxyIP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
 // IsValidAPIKey returns true when the given API key is valid, including both
-// the value in config and any overrides
+// the value in config and any overrides, or is an unexpired API token
 func (c GUIConfiguration) IsValidAPIKey(apiKey string) bool {
 	switch apiKey {
 	case "":
@@ -127,10 +177,50 @@ func (c GUIConfiguration) IsValidAPIKey(apiKey string) bool {
 		return true
 
 	default:
+		_, ok := c.apiToken(apiKey)
+		return ok
+	}
+}
+
+// APIKeyAllows returns true when the given API key gives access to the
+// scope. The main API key gives access to everything.
+func (c GUIConfiguration) APIKeyAllows(apiKey string, scope APIScope) bool {
+	switch apiKey {
+	case "":
 		return false
+
+	case c.APIKey, os.Getenv("STGUIAPIKEY"):
+		return true
+
+	default:
+		token, ok := c.apiToken(apiKey)
+		return ok && token.HasScope(scope)
 	}
 }
 
+// IsMasterAPIKey returns true when the given API key is the value in config
+// or its override, rather than an API token.
+func (c GUIConfiguration) IsMasterAPIKey(apiKey string) bool {
+	return apiKey != "" && (apiKey == c.APIKey || apiKey == os.Getenv("STGUIAPIKEY"))
+}
+
+func (c GUIConfiguration) apiToken(key string) (APIToken, bool) {
+	now := time.Now()
+	for _, token := range c.APITokens {
+		if token.Key == key && !token.IsExpired(now) {
+			return token, true
+		}
+	}
+	return APIToken{}, false
+}
+
 func (c GUIConfiguration) Copy() GUIConfiguration {
-	return c
+	newC := c
+	newC.APITokens = make([]APIToken, len(c.APITokens))
+	for i := range newC.APITokens {
+		newC.APITokens[i] = c.APITokens[i].Copy()
+	}
+	newC.CORSAllowedOrigins = append([]string(nil), c.CORSAllowedOrigins...)
+	newC.TrustedProxies = append([]string(nil), c.TrustedProxies...)
+	return newC
 }
@@ -0,0 +1,43 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package config
+
+// InvalidNamePolicy controls what happens to files with names that can't
+// be used on this OS, such as reserved names on Windows.
+type InvalidNamePolicy int
+
+const (
+	InvalidNameFail   InvalidNamePolicy = iota // default is to not sync them, with an error
+	InvalidNameEscape                          // store them under escaped names, presented as the original
+)
+
+func (p InvalidNamePolicy) String() string {
+	switch p {
+	case InvalidNameFail:
+		return "fail"
+	case InvalidNameEscape:
+		return "escape"
+	default:
+		return "unknown"
+	}
+}
+
+func (p InvalidNamePolicy) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+func (p *InvalidNamePolicy) UnmarshalText(bs []byte) error {
+	switch string(bs) {
+	case "fail":
+		*p = InvalidNameFail
+	case "escape":
+		*p = InvalidNameEscape
+	default:
+		*p = InvalidNameFail
+	}
+	return nil
+}
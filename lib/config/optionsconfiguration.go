@@ -8,7 +8,9 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"runtime"
+	"strings"
 
 	"github.com/syncthing/syncthing/lib/protocol"
 	"github.com/syncthing/syncthing/lib/rand"
@@ -16,52 +18,71 @@ import (
 )
 
 type OptionsConfiguration struct {
-	RawListenAddresses      []string `xml:"listenAddress" json:"listenAddresses" default:"default"`
-	RawGlobalAnnServers     []string `xml:"globalAnnounceServer" json:"globalAnnounceServers" default:"default" restart:"true"`
-	GlobalAnnEnabled        bool     `xml:"globalAnnounceEnabled" json:"globalAnnounceEnabled" default:"true" restart:"true"`
-	LocalAnnEnabled         bool     `xml:"localAnnounceEnabled" json:"localAnnounceEnabled" default:"true" restart:"true"`
-	LocalAnnPort            int      `xml:"localAnnouncePort" json:"localAnnouncePort" default:"21027" restart:"true"`
-	LocalAnnMCAddr          string   `xml:"localAnnounceMCAddr" json:"localAnnounceMCAddr" default:"[ff12::8384]:21027" restart:"true"`
-	MaxSendKbps             int      `xml:"maxSendKbps" json:"maxSendKbps"`
-	MaxRecvKbps             int      `xml:"maxRecvKbps" json:"maxRecvKbps"`
-	ReconnectIntervalS      int      `xml:"reconnectionIntervalS" json:"reconnectionIntervalS" default:"60"`
-	RelaysEnabled           bool     `xml:"relaysEnabled" json:"relaysEnabled" default:"true"`
-	RelayReconnectIntervalM int      `xml:"relayReconnectIntervalM" json:"relayReconnectIntervalM" default:"10"`
-	StartBrowser            bool     `xml:"startBrowser" json:"startBrowser" default:"true"`
-	NATEnabled              bool     `xml:"natEnabled" json:"natEnabled" default:"true"`
-	NATLeaseM               int      `xml:"natLeaseMinutes" json:"natLeaseMinutes" default:"60"`
-	NATRenewalM             int      `xml:"natRenewalMinutes" json:"natRenewalMinutes" default:"30"`
-	NATTimeoutS             int      `xml:"natTimeoutSeconds" json:"natTimeoutSeconds" default:"10"`
-	URAccepted              int      `xml:"urAccepted" json:"urAccepted"`                                    // Accepted usage reporting version; 0 for off (undecided), -1 for off (permanently)
-	URSeen                  int      `xml:"urSeen" json:"urSeen"`                                            // Report which the user has been prompted for.
-	URUniqueID              string   `xml:"urUniqueID" json:"urUniqueId"`                                    // Unique ID for reporting purposes, regenerated when UR is turned on.
-	URURL                   string   `xml:"urURL" json:"urURL" default:"https://data.syncthing.net/newdata"` // usage reporting URL
-	URPostInsecurely        bool     `xml:"urPostInsecurely" json:"urPostInsecurely" default:"false"`        // For testing
-	URInitialDelayS         int      `xml:"urInitialDelayS" json:"urInitialDelayS" default:"1800"`
-	RestartOnWakeup         bool     `xml:"restartOnWakeup" json:"restartOnWakeup" default:"true" restart:"true"`
-	AutoUpgradeIntervalH    int      `xml:"autoUpgradeIntervalH" json:"autoUpgradeIntervalH" default:"12" restart:"true"` // 0 for off
-	UpgradeToPreReleases    bool     `xml:"upgradeToPreReleases" json:"upgradeToPreReleases" restart:"true"`              // when auto upgrades are enabled
-	KeepTemporariesH        int      `xml:"keepTemporariesH" json:"keepTemporariesH" default:"24"`                        // 0 for off
-	CacheIgnoredFiles       bool     `xml:"cacheIgnoredFiles" json:"cacheIgnoredFiles" default:"false" restart:"true"`
-	ProgressUpdateIntervalS int      `xml:"progressUpdateIntervalS" json:"progressUpdateIntervalS" default:"5"`
-	LimitBandwidthInLan     bool     `xml:"limitBandwidthInLan" json:"limitBandwidthInLan" default:"false"`
-	MinHomeDiskFree         Size     `xml:"minHomeDiskFree" json:"minHomeDiskFree" default:"1 %"`
-	ReleasesURL             string   `xml:"releasesURL" json:"releasesURL" default:"https://upgrades.syncthing.net/meta.json" restart:"true"`
-	AlwaysLocalNets         []string `xml:"alwaysLocalNet" json:"alwaysLocalNets"`
-	OverwriteRemoteDevNames bool     `xml:"overwriteRemoteDeviceNamesOnConnect" json:"overwriteRemoteDeviceNamesOnConnect" default:"false"`
-	TempIndexMinBlocks      int      `xml:"tempIndexMinBlocks" json:"tempIndexMinBlocks" default:"10"`
-	UnackedNotificationIDs  []string `xml:"unackedNotificationID" json:"unackedNotificationIDs"`
-	TrafficClass            int      `xml:"trafficClass" json:"trafficClass"`
-	DefaultFolderPath       string   `xml:"defaultFolderPath" json:"defaultFolderPath" default:"~"`
-	SetLowPriority          bool     `xml:"setLowPriority" json:"setLowPriority" default:"true"`
-	RawMaxFolderConcurrency int      `xml:"maxFolderConcurrency" json:"maxFolderConcurrency"`
-	CRURL                   string   `xml:"crashReportingURL" json:"crURL" default:"https://crash.syncthing.net/newcrash"` // crash reporting URL
-	CREnabled               bool     `xml:"crashReportingEnabled" json:"crashReportingEnabled" default:"true" restart:"true"`
-	StunKeepaliveStartS     int      `xml:"stunKeepaliveStartS" json:"stunKeepaliveStartS" default:"180"` // 0 for off
-	StunKeepaliveMinS       int      `xml:"stunKeepaliveMinS" json:"stunKeepaliveMinS" default:"20"`      // 0 for off
-	RawStunServers          []string `xml:"stunServer" json:"stunServers" default:"default"`
-	DatabaseTuning          Tuning   `xml:"databaseTuning" json:"databaseTuning" restart:"true"`
-	RawMaxCIRequestKiB      int      `xml:"maxConcurrentIncomingRequestKiB" json:"maxConcurrentIncomingRequestKiB"`
+	RawListenAddresses         []string           `xml:"listenAddress" json:"listenAddresses" default:"default"`
+	RawGlobalAnnServers        []string           `xml:"globalAnnounceServer" json:"globalAnnounceServers" default:"default" restart:"true"`
+	GlobalAnnEnabled           bool               `xml:"globalAnnounceEnabled" json:"globalAnnounceEnabled" default:"true" restart:"true"`
+	LocalAnnEnabled            bool               `xml:"localAnnounceEnabled" json:"localAnnounceEnabled" default:"true" restart:"true"`
+	LocalAnnPort               int                `xml:"localAnnouncePort" json:"localAnnouncePort" default:"21027" restart:"true"`
+	LocalAnnMCAddr             string             `xml:"localAnnounceMCAddr" json:"localAnnounceMCAddr" default:"[ff12::8384]:21027" restart:"true"`
+	LocalAnnInterfaces         []string           `xml:"localAnnounceInterface" json:"localAnnounceInterfaces" restart:"true"`     // network interfaces to announce on, all if empty
+	LocalAnnUnicastHosts       []string           `xml:"localAnnounceUnicastHost" json:"localAnnounceUnicastHosts" restart:"true"` // IPv4 hosts, optionally with a port, to announce to directly where broadcasts don't reach
+	MaxSendKbps                int                `xml:"maxSendKbps" json:"maxSendKbps"`
+	MaxRecvKbps                int                `xml:"maxRecvKbps" json:"maxRecvKbps"`
+	ReconnectIntervalS         int                `xml:"reconnectionIntervalS" json:"reconnectionIntervalS" default:"60"`
+	RelaysEnabled              bool               `xml:"relaysEnabled" json:"relaysEnabled" default:"true"`
+	RelayReconnectIntervalM    int                `xml:"relayReconnectIntervalM" json:"relayReconnectIntervalM" default:"10"`
+	StartBrowser               bool               `xml:"startBrowser" json:"startBrowser" default:"true"`
+	NATEnabled                 bool               `xml:"natEnabled" json:"natEnabled" default:"true"`
+	NATLeaseM                  int                `xml:"natLeaseMinutes" json:"natLeaseMinutes" default:"60"`
+	NATRenewalM                int                `xml:"natRenewalMinutes" json:"natRenewalMinutes" default:"30"`
+	NATTimeoutS                int                `xml:"natTimeoutSeconds" json:"natTimeoutSeconds" default:"10"`
+	URAccepted                 int                `xml:"urAccepted" json:"urAccepted"`                                    // Accepted usage reporting version; 0 for off (undecided), -1 for off (permanently)
+	URSeen                     int                `xml:"urSeen" json:"urSeen"`                                            // Report which the user has been prompted for.
+	URUniqueID                 string             `xml:"urUniqueID" json:"urUniqueId"`                                    // Unique ID for reporting purposes, regenerated when UR is turned on.
+	URURL                      string             `xml:"urURL" json:"urURL" default:"https://data.syncthing.net/newdata"` // usage reporting URL
+	URPostInsecurely           bool               `xml:"urPostInsecurely" json:"urPostInsecurely" default:"false"`        // For testing
+	URInitialDelayS            int                `xml:"urInitialDelayS" json:"urInitialDelayS" default:"1800"`
+	RestartOnWakeup            bool               `xml:"restartOnWakeup" json:"restartOnWakeup" default:"true" restart:"true"`
+	AutoUpgradeIntervalH       int                `xml:"autoUpgradeIntervalH" json:"autoUpgradeIntervalH" default:"12" restart:"true"` // 0 for off
+	UpgradeToPreReleases       bool               `xml:"upgradeToPreReleases" json:"upgradeToPreReleases" restart:"true"`              // when auto upgrades are enabled
+	KeepTemporariesH           int                `xml:"keepTemporariesH" json:"keepTemporariesH" default:"24"`                        // 0 for off
+	CacheIgnoredFiles          bool               `xml:"cacheIgnoredFiles" json:"cacheIgnoredFiles" default:"false" restart:"true"`
+	ProgressUpdateIntervalS    int                `xml:"progressUpdateIntervalS" json:"progressUpdateIntervalS" default:"5"`
+	LimitBandwidthInLan        bool               `xml:"limitBandwidthInLan" json:"limitBandwidthInLan" default:"false"`
+	MinHomeDiskFree            Size               `xml:"minHomeDiskFree" json:"minHomeDiskFree" default:"1 %"`
+	ReleasesURL                string             `xml:"releasesURL" json:"releasesURL" default:"https://upgrades.syncthing.net/meta.json" restart:"true"`
+	AlwaysLocalNets            []string           `xml:"alwaysLocalNet" json:"alwaysLocalNets"`
+	OverwriteRemoteDevNames    bool               `xml:"overwriteRemoteDeviceNamesOnConnect" json:"overwriteRemoteDeviceNamesOnConnect" default:"false"`
+	TempIndexMinBlocks         int                `xml:"tempIndexMinBlocks" json:"tempIndexMinBlocks" default:"10"`
+	UnackedNotificationIDs     []string           `xml:"unackedNotificationID" json:"unackedNotificationIDs"`
+	TrafficClass               int                `xml:"trafficClass" json:"trafficClass"`
+	DefaultFolderPath          string             `xml:"defaultFolderPath" json:"defaultFolderPath" default:"~"`
+	SetLowPriority             bool               `xml:"setLowPriority" json:"setLowPriority" default:"true"`
+	RawMaxFolderConcurrency    int                `xml:"maxFolderConcurrency" json:"maxFolderConcurrency"`
+	RawMaxSyncingFolders       int                `xml:"maxSyncingFolders" json:"maxSyncingFolders"`                                    // on top of maxFolderConcurrency, which also counts scans
+	CRURL                      string             `xml:"crashReportingURL" json:"crURL" default:"https://crash.syncthing.net/newcrash"` // crash reporting URL
+	CREnabled                  bool               `xml:"crashReportingEnabled" json:"crashReportingEnabled" default:"true" restart:"true"`
+	StunKeepaliveStartS        int                `xml:"stunKeepaliveStartS" json:"stunKeepaliveStartS" default:"180"` // 0 for off
+	StunKeepaliveMinS          int                `xml:"stunKeepaliveMinS" json:"stunKeepaliveMinS" default:"20"`      // 0 for off
+	RawStunServers             []string           `xml:"stunServer" json:"stunServers" default:"default"`
+	DatabaseTuning             Tuning             `xml:"databaseTuning" json:"databaseTuning" restart:"true"`
+	DatabaseDurability         DatabaseDurability `xml:"databaseDurability" json:"databaseDurability" restart:"true"`                    // when database writes are synced to disk, see DatabaseDurability
+	DatabaseSyncIntervalS      int                `xml:"databaseSyncIntervalS" json:"databaseSyncIntervalS" default:"10" restart:"true"` // how often writes are synced with the periodic database durability
+	RawMaxCIRequestKiB         int                `xml:"maxConcurrentIncomingRequestKiB" json:"maxConcurrentIncomingRequestKiB"`
+	SuppressDuplicateSummaries bool               `xml:"suppressDuplicateSummaries" json:"suppressDuplicateSummaries"`           // don't repeat unchanged FolderSummary and FolderCompletion events
+	MaxConnections             int                `xml:"maxConnections" json:"maxConnections"`                                   // 0 for no limit; above it the devices with the highest priority stay connected
+	ConnectionCycleIntervalS   int                `xml:"connectionCycleIntervalS" json:"connectionCycleIntervalS" default:"600"` // how long a device may keep its connection when others of the same priority are waiting, 0 for as long as it likes
+	TorProxyAddress            string             `xml:"torProxyAddress" json:"torProxyAddress"`                                 // the Tor SOCKS proxy to dial onion:// addresses through, such as 127.0.0.1:9050; they aren't dialed if empty
+	I2PSAMAddress              string             `xml:"i2pSamAddress" json:"i2pSamAddress"`                                     // the SAM bridge of the I2P router to dial i2p:// addresses through, such as 127.0.0.1:7656; they aren't dialed if empty
+	LegacyCompletionEvents     bool               `xml:"legacyCompletionEvents" json:"legacyCompletionEvents" default:"true"`    // also send a FolderCompletion event per device, besides the CompletionSummary per folder
+	TarpitUnknownDevicesAfter  int                `xml:"tarpitUnknownDevicesAfter" json:"tarpitUnknownDevicesAfter"`             // hold connections from an address after this many attempts with unknown device IDs, 0 for never
+	TarpitDelayS               int                `xml:"tarpitDelayS" json:"tarpitDelayS" default:"60"`                          // how long a connection is held in the tarpit before it's closed
+	IncomingAllowedNetworks    []string           `xml:"incomingAllowedNetwork" json:"incomingAllowedNetworks"`                  // the networks to accept connections from, like 192.168.0.0/16, or with a "!" prefix not to; all of them if empty
+
+	PersistentEventsEnabled bool     `xml:"persistentEventsEnabled" json:"persistentEventsEnabled" restart:"true"`                                                                 // keep events in the database for API clients to catch up on after a restart
+	PersistentEventTypes    []string `xml:"persistentEventType" json:"persistentEventTypes" default:"ItemFinished,FolderErrors,DeviceConnected,DeviceDisconnected" restart:"true"` // the types of events that are persisted
+	PersistentEventsMax     int      `xml:"persistentEventsMax" json:"persistentEventsMax" default:"10000" restart:"true"`                                                         // how many persistent events are kept at most
+	PersistentEventsMaxAgeH int      `xml:"persistentEventsMaxAgeH" json:"persistentEventsMaxAgeH" default:"168" restart:"true"`                                                   // how long persistent events are kept, 0 for as long as there is room
 
 	DeprecatedUPnPEnabled        bool     `xml:"upnpEnabled,omitempty" json:"-"`
 	DeprecatedUPnPLeaseM         int      `xml:"upnpLeaseMinutes,omitempty" json:"-"`
@@ -78,10 +99,18 @@ func (opts OptionsConfiguration) Copy() OptionsConfiguration {
 	copy(optsCopy.RawListenAddresses, opts.RawListenAddresses)
 	optsCopy.RawGlobalAnnServers = make([]string, len(opts.RawGlobalAnnServers))
 	copy(optsCopy.RawGlobalAnnServers, opts.RawGlobalAnnServers)
+	optsCopy.LocalAnnInterfaces = make([]string, len(opts.LocalAnnInterfaces))
+	copy(optsCopy.LocalAnnInterfaces, opts.LocalAnnInterfaces)
+	optsCopy.LocalAnnUnicastHosts = make([]string, len(opts.LocalAnnUnicastHosts))
+	copy(optsCopy.LocalAnnUnicastHosts, opts.LocalAnnUnicastHosts)
 	optsCopy.AlwaysLocalNets = make([]string, len(opts.AlwaysLocalNets))
 	copy(optsCopy.AlwaysLocalNets, opts.AlwaysLocalNets)
 	optsCopy.UnackedNotificationIDs = make([]string, len(opts.UnackedNotificationIDs))
 	copy(optsCopy.UnackedNotificationIDs, opts.UnackedNotificationIDs)
+	optsCopy.IncomingAllowedNetworks = make([]string, len(opts.IncomingAllowedNetworks))
+	copy(optsCopy.IncomingAllowedNetworks, opts.IncomingAllowedNetworks)
+	optsCopy.PersistentEventTypes = make([]string, len(opts.PersistentEventTypes))
+	copy(optsCopy.PersistentEventTypes, opts.PersistentEventTypes)
 	return optsCopy
 }
 
@@ -99,6 +128,41 @@ func (opts OptionsConfiguration) RequiresRestartOnly() OptionsConfiguration {
 	return optsCopy
 }
 
+// An AllowedNetwork is a network to accept connections from, or with Deny
+// set, not to.
+type AllowedNetwork struct {
+	*net.IPNet
+	Deny bool
+}
+
+// ParseAllowedNetworks parses networks given like "192.168.0.0/16", or
+// "!10.0.0.0/8" for those to deny. A bare address is a network of just that
+// address.
+func ParseAllowedNetworks(networks []string) ([]AllowedNetwork, error) {
+	res := make([]AllowedNetwork, 0, len(networks))
+	for _, n := range networks {
+		var an AllowedNetwork
+		n = strings.TrimSpace(n)
+		if strings.HasPrefix(n, "!") {
+			an.Deny = true
+			n = n[1:]
+		}
+		if ip := net.ParseIP(n); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			an.IPNet = &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+		} else if _, cidr, err := net.ParseCIDR(n); err == nil {
+			an.IPNet = cidr
+		} else {
+			return nil, fmt.Errorf("invalid network %q", n)
+		}
+		res = append(res, an)
+	}
+	return res, nil
+}
+
 func (opts OptionsConfiguration) IsStunDisabled() bool {
 	return opts.StunKeepaliveMinS < 1 || opts.StunKeepaliveStartS < 1 || !opts.NATEnabled
 }
@@ -178,6 +242,15 @@ func (opts OptionsConfiguration) MaxFolderConcurrency() int {
 	return 4 // https://xkcd.com/221/
 }
 
+// MaxSyncingFolders returns how many folders may be pulling at once, zero
+// meaning no limit other than MaxFolderConcurrency.
+func (opts OptionsConfiguration) MaxSyncingFolders() int {
+	if opts.RawMaxSyncingFolders < 0 {
+		return 0
+	}
+	return opts.RawMaxSyncingFolders
+}
+
 func (opts OptionsConfiguration) MaxConcurrentIncomingRequestKiB() int {
 	// Negative is disabled, which in limiter land is spelled zero
 	if opts.RawMaxCIRequestKiB < 0 {
@@ -15,6 +15,8 @@ const (
 	OrderLargestFirst
 	OrderOldestFirst
 	OrderNewestFirst
+	OrderDirectoryFirst         // a directory at a time, alphabetically
+	OrderSmallestDirectoryFirst // a directory at a time, the one with the least to pull first
 )
 
 func (o PullOrder) String() string {
@@ -31,6 +33,10 @@ func (o PullOrder) String() string {
 		return "oldestFirst"
 	case OrderNewestFirst:
 		return "newestFirst"
+	case OrderDirectoryFirst:
+		return "directoryFirst"
+	case OrderSmallestDirectoryFirst:
+		return "smallestDirectoryFirst"
 	default:
 		return "unknown"
 	}
@@ -54,6 +60,10 @@ func (o *PullOrder) UnmarshalText(bs []byte) error {
 		*o = OrderOldestFirst
 	case "newestFirst":
 		*o = OrderNewestFirst
+	case "directoryFirst":
+		*o = OrderDirectoryFirst
+	case "smallestDirectoryFirst":
+		*o = OrderSmallestDirectoryFirst
 	default:
 		*o = OrderRandom
 	}
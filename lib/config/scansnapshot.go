@@ -0,0 +1,54 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package config
+
+// ScanSnapshot is how a folder is snapshotted before it is scanned, so that
+// files being written meanwhile are hashed as they were at one point in
+// time.
+type ScanSnapshot int
+
+const (
+	ScanSnapshotNone    ScanSnapshot = iota // default is to scan the folder itself
+	ScanSnapshotZFS                         // a ZFS snapshot of the dataset of the folder
+	ScanSnapshotBtrfs                       // a read only snapshot of the Btrfs subvolume of the folder
+	ScanSnapshotCommand                     // whatever the scan snapshot command of the folder does
+)
+
+func (s ScanSnapshot) String() string {
+	switch s {
+	case ScanSnapshotNone:
+		return "none"
+	case ScanSnapshotZFS:
+		return "zfs"
+	case ScanSnapshotBtrfs:
+		return "btrfs"
+	case ScanSnapshotCommand:
+		return "command"
+	default:
+		return "unknown"
+	}
+}
+
+func (s ScanSnapshot) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *ScanSnapshot) UnmarshalText(bs []byte) error {
+	switch string(bs) {
+	case "none":
+		*s = ScanSnapshotNone
+	case "zfs":
+		*s = ScanSnapshotZFS
+	case "btrfs":
+		*s = ScanSnapshotBtrfs
+	case "command":
+		*s = ScanSnapshotCommand
+	default:
+		*s = ScanSnapshotNone
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// A SyncWindow is a time of day, on some or all days of the week, when a
+// folder may be scanned and pulled. It's written as "22:00-06:00" for
+// every day, or with days first as in "mon-fri 09:00-17:00" or
+// "sat,sun 00:00-24:00". A window that ends before it starts runs past
+// midnight, into the day after the one it starts on.
+type SyncWindow struct {
+	days       [7]bool // by time.Weekday
+	start, end int     // minutes into the day
+}
+
+var weekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+func ParseSyncWindow(s string) (SyncWindow, error) {
+	var w SyncWindow
+	fields := strings.Fields(strings.ToLower(s))
+	switch len(fields) {
+	case 1:
+		for i := range w.days {
+			w.days[i] = true
+		}
+	case 2:
+		if err := w.parseDays(fields[0]); err != nil {
+			return SyncWindow{}, err
+		}
+		fields = fields[1:]
+	default:
+		return SyncWindow{}, fmt.Errorf("sync window %q: expected [days] start-end", s)
+	}
+
+	times := strings.Split(fields[0], "-")
+	if len(times) != 2 {
+		return SyncWindow{}, fmt.Errorf("sync window %q: expected start-end", s)
+	}
+	var err error
+	if w.start, err = parseTimeOfDay(times[0]); err != nil {
+		return SyncWindow{}, fmt.Errorf("sync window %q: %v", s, err)
+	}
+	if w.end, err = parseTimeOfDay(times[1]); err != nil {
+		return SyncWindow{}, fmt.Errorf("sync window %q: %v", s, err)
+	}
+	if w.start == 24*60 {
+		return SyncWindow{}, fmt.Errorf("sync window %q: starts at the end of the day", s)
+	}
+	return w, nil
+}
+
+func (w *SyncWindow) parseDays(s string) error {
+	for _, part := range strings.Split(s, ",") {
+		bounds := strings.Split(part, "-")
+		if len(bounds) > 2 {
+			return fmt.Errorf("invalid days %q", part)
+		}
+		first, err := parseWeekday(bounds[0])
+		if err != nil {
+			return err
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = parseWeekday(bounds[1]); err != nil {
+				return err
+			}
+		}
+		// Ranges may wrap around the week, as in fri-mon
+		for d := first; ; d = (d + 1) % 7 {
+			w.days[d] = true
+			if d == last {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func parseWeekday(s string) (int, error) {
+	for i, day := range weekdays {
+		if s == day {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid day %q", s)
+}
+
+// parseTimeOfDay returns the minutes into the day of a time like "06:30",
+// with "24:00" being the end of the day.
+func parseTimeOfDay(s string) (int, error) {
+	var hour, minute int
+	if n, err := fmt.Sscanf(s, "%d:%d", &hour, &minute); err != nil || n != 2 {
+		return 0, fmt.Errorf("invalid time of day %q", s)
+	}
+	if hour < 0 || minute < 0 || minute > 59 || hour > 24 || hour == 24 && minute != 0 {
+		return 0, errors.New("time of day out of range: " + s)
+	}
+	return hour*60 + minute, nil
+}
+
+// Contains returns whether the given time is within the window, in the
+// time's own location.
+func (w SyncWindow) Contains(t time.Time) bool {
+	day := int(t.Weekday())
+	minute := t.Hour()*60 + t.Minute()
+	if w.start < w.end {
+		return w.days[day] && minute >= w.start && minute < w.end
+	}
+	// Runs past midnight, or the whole day round if it ends where it
+	// starts.
+	return w.days[day] && minute >= w.start || w.days[(day+6)%7] && minute < w.end
+}
@@ -0,0 +1,76 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package config
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSyncWindow(t *testing.T) {
+	// 2019-06-07 is a Friday
+	at := func(day int, clock string) time.Time {
+		tm, err := time.Parse("2006-01-02 15:04", fmt.Sprintf("2019-06-%02d %s", day, clock))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+
+	cases := []struct {
+		window string
+		in     []time.Time
+		out    []time.Time
+	}{
+		{"22:00-06:00", []time.Time{at(7, "22:00"), at(8, "05:59"), at(3, "23:30")}, []time.Time{at(7, "06:00"), at(7, "21:59")}},
+		{"mon-fri 09:00-17:00", []time.Time{at(7, "09:00"), at(3, "16:59")}, []time.Time{at(8, "12:00"), at(9, "12:00"), at(7, "17:00")}},
+		{"fri-sun 23:00-01:00", []time.Time{at(7, "23:30"), at(8, "00:30"), at(9, "23:30")}, []time.Time{at(6, "23:30"), at(7, "00:30")}},
+		{"Sat,Sun 00:00-24:00", []time.Time{at(8, "00:00"), at(9, "23:59")}, []time.Time{at(7, "23:59")}},
+		{"wed 12:00-12:00", []time.Time{at(5, "12:00"), at(6, "11:59")}, []time.Time{at(5, "11:59"), at(6, "12:00")}},
+	}
+	for _, tc := range cases {
+		w, err := ParseSyncWindow(tc.window)
+		if err != nil {
+			t.Errorf("Unexpected error parsing %q: %v", tc.window, err)
+			continue
+		}
+		for _, tm := range tc.in {
+			if !w.Contains(tm) {
+				t.Errorf("%q should contain %v", tc.window, tm)
+			}
+		}
+		for _, tm := range tc.out {
+			if w.Contains(tm) {
+				t.Errorf("%q should not contain %v", tc.window, tm)
+			}
+		}
+	}
+
+	for _, invalid := range []string{"", "22:00", "mon", "mon 22:00-25:00", "xyz 01:00-02:00", "mon 24:00-01:00", "mon tue 01:00-02:00", "1:60-2:00"} {
+		if _, err := ParseSyncWindow(invalid); err == nil {
+			t.Errorf("Expected error parsing %q", invalid)
+		}
+	}
+}
+
+func TestInSyncWindow(t *testing.T) {
+	fcfg := FolderConfiguration{SyncWindows: []string{"01:00-02:00", "invalid", "mon 12:00-13:00"}}
+	fcfg.prepare()
+
+	mon := time.Date(2019, 6, 3, 0, 0, 0, 0, time.UTC)
+	if !fcfg.InSyncWindow(mon.Add(90*time.Minute)) || !fcfg.InSyncWindow(mon.Add(12*time.Hour)) {
+		t.Error("Should be in sync window")
+	}
+	if fcfg.InSyncWindow(mon.Add(3 * time.Hour)) {
+		t.Error("Should not be in sync window")
+	}
+
+	if !(FolderConfiguration{}).InSyncWindow(mon) {
+		t.Error("Should always be in sync window without windows")
+	}
+}
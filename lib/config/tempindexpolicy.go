@@ -0,0 +1,53 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package config
+
+// TempIndexPolicy controls how a folder takes part in the exchange of
+// temporary indexes, i.e. the advertising of partially downloaded files.
+type TempIndexPolicy int
+
+const (
+	TempIndexAll           TempIndexPolicy = iota // default is to advertise and use
+	TempIndexAdvertiseOnly                        // serve our partial files, but don't pull from others'
+	TempIndexUseOnly                              // pull from others' partial files, but don't serve ours
+	TempIndexNone
+)
+
+func (p TempIndexPolicy) String() string {
+	switch p {
+	case TempIndexAll:
+		return "all"
+	case TempIndexAdvertiseOnly:
+		return "advertiseOnly"
+	case TempIndexUseOnly:
+		return "useOnly"
+	case TempIndexNone:
+		return "none"
+	default:
+		return "unknown"
+	}
+}
+
+func (p TempIndexPolicy) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+func (p *TempIndexPolicy) UnmarshalText(bs []byte) error {
+	switch string(bs) {
+	case "all":
+		*p = TempIndexAll
+	case "advertiseOnly":
+		*p = TempIndexAdvertiseOnly
+	case "useOnly":
+		*p = TempIndexUseOnly
+	case "none":
+		*p = TempIndexNone
+	default:
+		*p = TempIndexAll
+	}
+	return nil
+}
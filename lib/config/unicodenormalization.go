@@ -0,0 +1,72 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package config
+
+import (
+	"runtime"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// UnicodeNormalization selects the Unicode normalization form used for file
+// names in a folder, on disk and in the database.
+type UnicodeNormalization int
+
+const (
+	NormalizationNative UnicodeNormalization = iota // default is NFD on macOS, NFC elsewhere
+	NormalizationNFC
+	NormalizationNFD
+)
+
+func (n UnicodeNormalization) String() string {
+	switch n {
+	case NormalizationNative:
+		return "native"
+	case NormalizationNFC:
+		return "nfc"
+	case NormalizationNFD:
+		return "nfd"
+	default:
+		return "unknown"
+	}
+}
+
+func (n UnicodeNormalization) MarshalText() ([]byte, error) {
+	return []byte(n.String()), nil
+}
+
+func (n *UnicodeNormalization) UnmarshalText(bs []byte) error {
+	switch string(bs) {
+	case "native":
+		*n = NormalizationNative
+	case "nfc":
+		*n = NormalizationNFC
+	case "nfd":
+		*n = NormalizationNFD
+	default:
+		*n = NormalizationNative
+	}
+	return nil
+}
+
+// Form returns the normalization form that file names are kept in.
+func (n UnicodeNormalization) Form() norm.Form {
+	switch n {
+	case NormalizationNFC:
+		return norm.NFC
+	case NormalizationNFD:
+		return norm.NFD
+	}
+	if runtime.GOOS == "darwin" {
+		// Mac OS X file names should always be NFD normalized.
+		return norm.NFD
+	}
+	// Every other OS in the known universe uses NFC or just plain doesn't
+	// bother to define an encoding. In our case *we* do care, so we
+	// enforce NFC regardless.
+	return norm.NFC
+}
@@ -9,13 +9,31 @@ package config
 import "encoding/xml"
 
 type VersioningConfiguration struct {
-	Type   string            `xml:"type,attr" json:"type"`
-	Params map[string]string `json:"params"`
+	Type      string               `xml:"type,attr" json:"type"`
+	Params    map[string]string    `json:"params"`
+	Overrides []VersioningOverride `json:"overrides"`
+}
+
+// A VersioningOverride replaces the versioning of the folder for the paths
+// matching the pattern, which is written as a line of .stignore. The first
+// matching override applies. An empty type means no versioning for those
+// paths.
+type VersioningOverride struct {
+	Pattern string            `json:"pattern"`
+	Type    string            `json:"type"`
+	Params  map[string]string `json:"params"`
 }
 
 type InternalVersioningConfiguration struct {
-	Type   string          `xml:"type,attr,omitempty"`
-	Params []InternalParam `xml:"param"`
+	Type      string                       `xml:"type,attr,omitempty"`
+	Params    []InternalParam              `xml:"param"`
+	Overrides []InternalVersioningOverride `xml:"override"`
+}
+
+type InternalVersioningOverride struct {
+	Pattern string          `xml:"pattern,attr"`
+	Type    string          `xml:"type,attr,omitempty"`
+	Params  []InternalParam `xml:"param"`
 }
 
 type InternalParam struct {
@@ -29,15 +47,51 @@ func (c VersioningConfiguration) Copy() VersioningConfiguration {
 	for k, v := range c.Params {
 		cp.Params[k] = v
 	}
+	if c.Overrides != nil {
+		cp.Overrides = make([]VersioningOverride, len(c.Overrides))
+		for i, o := range c.Overrides {
+			cp.Overrides[i] = o.Copy()
+		}
+	}
 	return cp
 }
 
+func (o VersioningOverride) Copy() VersioningOverride {
+	cp := o
+	cp.Params = make(map[string]string, len(o.Params))
+	for k, v := range o.Params {
+		cp.Params[k] = v
+	}
+	return cp
+}
+
+// Config returns the configuration of the versioning for the paths the
+// override applies to.
+func (o VersioningOverride) Config() VersioningConfiguration {
+	return VersioningConfiguration{
+		Type:   o.Type,
+		Params: o.Copy().Params,
+	}
+}
+
+// Enabled returns whether any files of the folder are versioned.
+func (c VersioningConfiguration) Enabled() bool {
+	return c.Type != "" || len(c.Overrides) > 0
+}
+
 func (c *VersioningConfiguration) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
 	var tmp InternalVersioningConfiguration
 	tmp.Type = c.Type
 	for k, v := range c.Params {
 		tmp.Params = append(tmp.Params, InternalParam{k, v})
 	}
+	for _, o := range c.Overrides {
+		to := InternalVersioningOverride{Pattern: o.Pattern, Type: o.Type}
+		for k, v := range o.Params {
+			to.Params = append(to.Params, InternalParam{k, v})
+		}
+		tmp.Overrides = append(tmp.Overrides, to)
+	}
 
 	return e.EncodeElement(tmp, start)
 
@@ -55,5 +109,17 @@ func (c *VersioningConfiguration) UnmarshalXML(d *xml.Decoder, start xml.StartEl
 	for _, p := range tmp.Params {
 		c.Params[p.Key] = p.Val
 	}
+	c.Overrides = nil
+	for _, to := range tmp.Overrides {
+		o := VersioningOverride{
+			Pattern: to.Pattern,
+			Type:    to.Type,
+			Params:  make(map[string]string, len(to.Params)),
+		}
+		for _, p := range to.Params {
+			o.Params[p.Key] = p.Val
+		}
+		c.Overrides = append(c.Overrides, o)
+	}
 	return nil
 }
@@ -0,0 +1,37 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package config
+
+import (
+	"path"
+	"strings"
+)
+
+// An XattrFilter decides which extended attributes of the files of a
+// folder are synced. It's a list of glob patterns of attribute names, like
+// "user.*", or "!security.*" for attributes that aren't synced. The first
+// pattern matching a name decides, and attributes that no pattern matches
+// are synced. An empty filter is the default one.
+type XattrFilter []string
+
+// DefaultXattrFilter syncs only the attributes in the user namespace. The
+// others are specific to the system or security policy, like ACLs and
+// SELinux labels, and are synced only when asked for.
+var DefaultXattrFilter = XattrFilter{"user.*", "!*"}
+
+func (p XattrFilter) Permit(name string) bool {
+	if len(p) == 0 {
+		p = DefaultXattrFilter
+	}
+	for _, pattern := range p {
+		permit := !strings.HasPrefix(pattern, "!")
+		if ok, _ := path.Match(strings.TrimPrefix(pattern, "!"), name); ok {
+			return permit
+		}
+	}
+	return true
+}
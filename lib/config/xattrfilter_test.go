@@ -0,0 +1,29 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package config
+
+import "testing"
+
+func TestXattrFilter(t *testing.T) {
+	filter := XattrFilter{"security.selinux", "!security.*", "!trusted.*"}
+	cases := map[string]bool{
+		"user.foo":                true,
+		"system.posix_acl_access": true,
+		"security.selinux":        true,
+		"security.capability":     false,
+		"trusted.foo":             false,
+		"com.apple.FinderInfo":    true,
+	}
+	for name, permit := range cases {
+		if res := filter.Permit(name); res != permit {
+			t.Errorf("Permit(%q) = %v, expected %v", name, res, permit)
+		}
+	}
+	if !(XattrFilter{}).Permit("user.foo") || (XattrFilter{}).Permit("system.posix_acl_access") {
+		t.Error("Expected an empty filter to permit only user attributes")
+	}
+}
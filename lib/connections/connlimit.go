@@ -0,0 +1,119 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+var errTooManyConnections = errors.New("too many connections")
+
+type connectedDevice struct {
+	id       protocol.DeviceID
+	priority int
+	since    time.Time
+}
+
+// byKeep sorts connected devices with the ones to keep connected the
+// longest first: higher priority, and among equals the most recently
+// connected.
+type byKeep []connectedDevice
+
+func (l byKeep) Len() int      { return len(l) }
+func (l byKeep) Swap(a, b int) { l[a], l[b] = l[b], l[a] }
+func (l byKeep) Less(a, b int) bool {
+	if l[a].priority != l[b].priority {
+		return l[a].priority > l[b].priority
+	}
+	return l[a].since.After(l[b].since)
+}
+
+// makeRoom returns whether a device with the given priority may connect
+// while the given devices are, and which of them is to be disconnected for
+// it if any. A device takes the place of one with a lower priority, or of
+// one with the same priority that has been connected for at least the
+// cycle interval, so that devices of the same priority take turns.
+func makeRoom(priority int, connected []connectedDevice, max int, cycle time.Duration, now time.Time) (protocol.DeviceID, bool) {
+	if max <= 0 || len(connected) < max {
+		return protocol.EmptyDeviceID, true
+	}
+	sort.Sort(byKeep(connected))
+	last := connected[len(connected)-1]
+	switch {
+	case last.priority < priority:
+		return last.id, true
+	case last.priority == priority && cycle > 0 && now.Sub(last.since) >= cycle:
+		return last.id, true
+	}
+	return protocol.EmptyDeviceID, false
+}
+
+// connectedDevices returns the devices in the config that are connected,
+// other than the given one.
+func (s *service) connectedDevices(cfg config.Configuration, except protocol.DeviceID) []connectedDevice {
+	s.connectedAtMut.Lock()
+	defer s.connectedAtMut.Unlock()
+	var connected []connectedDevice
+	for _, dev := range cfg.Devices {
+		if dev.DeviceID == except || dev.DeviceID == s.myID {
+			continue
+		}
+		if _, ok := s.model.Connection(dev.DeviceID); !ok {
+			continue
+		}
+		connected = append(connected, connectedDevice{
+			id:       dev.DeviceID,
+			priority: dev.Priority,
+			since:    s.connectedAt[dev.DeviceID],
+		})
+	}
+	return connected
+}
+
+// mayConnect returns whether the device may be connected, as well as the
+// connected device, if any, that is to make room for it.
+func (s *service) mayConnect(cfg config.Configuration, device config.DeviceConfiguration) (protocol.DeviceID, bool) {
+	opts := cfg.Options
+	if opts.MaxConnections <= 0 {
+		return protocol.EmptyDeviceID, true
+	}
+	cycle := time.Duration(opts.ConnectionCycleIntervalS) * time.Second
+	return makeRoom(device.Priority, s.connectedDevices(cfg, device.DeviceID), opts.MaxConnections, cycle, time.Now())
+}
+
+func (s *service) setConnected(device protocol.DeviceID) {
+	s.connectedAtMut.Lock()
+	s.connectedAt[device] = time.Now()
+	s.connectedAtMut.Unlock()
+}
+
+// enforceMaxConnections disconnects the devices with the lowest priority
+// when there are more connected than allowed, as there may be after
+// lowering the maximum.
+func (s *service) enforceMaxConnections(cfg config.Configuration) {
+	max := cfg.Options.MaxConnections
+	if max <= 0 {
+		return
+	}
+	connected := s.connectedDevices(cfg, protocol.EmptyDeviceID)
+	if len(connected) <= max {
+		return
+	}
+	sort.Sort(byKeep(connected))
+	for _, dev := range connected[max:] {
+		if conn, ok := s.model.Connection(dev.id); ok {
+			l.Infof("Disconnecting from %s, as there are more than %d connections", dev.id, max)
+			conn.Close(errTooManyConnections)
+		}
+	}
+}
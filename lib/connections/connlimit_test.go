@@ -0,0 +1,57 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestMakeRoom(t *testing.T) {
+	now := time.Now()
+	dev1 := protocol.DeviceID{1}
+	dev2 := protocol.DeviceID{2}
+	dev3 := protocol.DeviceID{3}
+	connected := func() []connectedDevice {
+		return []connectedDevice{
+			{id: dev1, priority: 10, since: now.Add(-time.Hour)},
+			{id: dev2, priority: 0, since: now.Add(-time.Minute)},
+			{id: dev3, priority: 0, since: now.Add(-20 * time.Minute)},
+		}
+	}
+	cycle := 10 * time.Minute
+
+	cases := []struct {
+		priority int
+		max      int
+		cycle    time.Duration
+		victim   protocol.DeviceID
+		ok       bool
+	}{
+		// No limit, or below it
+		{0, 0, cycle, protocol.EmptyDeviceID, true},
+		{0, 4, cycle, protocol.EmptyDeviceID, true},
+		// A higher priority takes the place of the lowest priority
+		// device that has been connected the longest
+		{5, 3, cycle, dev3, true},
+		// The same priority only when it's time for a turn
+		{0, 3, cycle, dev3, true},
+		{0, 3, time.Hour, protocol.EmptyDeviceID, false},
+		{0, 3, 0, protocol.EmptyDeviceID, false},
+		// A lower priority never
+		{-1, 3, cycle, protocol.EmptyDeviceID, false},
+	}
+
+	for i, tc := range cases {
+		victim, ok := makeRoom(tc.priority, connected(), tc.max, tc.cycle, now)
+		if ok != tc.ok || victim != tc.victim {
+			t.Errorf("case %d: got %v %v, expected %v %v", i, victim, ok, tc.victim, tc.ok)
+		}
+	}
+}
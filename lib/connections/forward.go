@@ -0,0 +1,303 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/dialer"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// A device can forward connections between two of its peers that can't
+// reach each other directly, when both are configured for it: the one
+// connecting lists it in "via" for the other, and it lists the other in
+// "forwardTo" for the one connecting. The connecting device makes a TLS
+// connection to the forwarding device, negotiating the forwarding
+// protocol, and names the device to forward to. The forwarding device
+// connects to that one over TCP and from then on just copies the data
+// between the two, which make their own TLS connection through it as if
+// connected directly.
+
+const (
+	forwardProtocolName = "bep-forward/1.0"
+	forwardScheme       = "forward"
+	forwardPriority     = 150 // better than a relay, as the forwarding device is one of our own
+)
+
+// The answers to a forwarding request
+const (
+	forwardOK byte = iota
+	forwardRefused
+	forwardUnreachable
+)
+
+var (
+	errForwardRefused     = errors.New("forwarding refused")
+	errForwardUnreachable = errors.New("forwarding device can't reach the device")
+	errForwardUnsupported = errors.New("device doesn't support forwarding")
+)
+
+// forwardAddress is the address to dial a device at through the given
+// forwarding device.
+func forwardAddress(via protocol.DeviceID) string {
+	return forwardScheme + "://" + via.String()
+}
+
+// forwardTarget returns the device to forward to, if the requesting device
+// may have its connections forwarded to it from the given address.
+func forwardTarget(cfg config.Configuration, from, to protocol.DeviceID, addr string) (config.DeviceConfiguration, error) {
+	devices := cfg.DeviceMap()
+	fromCfg, ok := devices[from]
+	if !ok || fromCfg.Paused {
+		return config.DeviceConfiguration{}, errors.New("requesting device is unknown or paused")
+	}
+	if len(fromCfg.AllowedNetworks) > 0 && !IsAllowedNetwork(addr, fromCfg.AllowedNetworks) {
+		return config.DeviceConfiguration{}, fmt.Errorf("requesting device not allowed to connect from %s", addr)
+	}
+	if !fromCfg.MayForwardTo(to) {
+		return config.DeviceConfiguration{}, fmt.Errorf("not configured to forward to %s", to)
+	}
+	toCfg, ok := devices[to]
+	if !ok || toCfg.Paused {
+		return config.DeviceConfiguration{}, errors.New("device to forward to is unknown or paused")
+	}
+	return toCfg, nil
+}
+
+// handleForward serves a connection from a known device that negotiated
+// the forwarding protocol, until either side closes it.
+func (s *service) handleForward(ctx context.Context, c internalConn, fromID protocol.DeviceID) {
+	defer c.Close()
+
+	_ = c.SetDeadline(time.Now().Add(20 * time.Second))
+	var toID protocol.DeviceID
+	if _, err := io.ReadFull(c, toID[:]); err != nil {
+		l.Debugln("Reading forwarding request from", fromID, "at", c, "failed:", err)
+		return
+	}
+
+	toCfg, err := forwardTarget(s.cfg.RawCopy(), fromID, toID, c.RemoteAddr().String())
+	if err != nil {
+		l.Infof("Not forwarding connection from %s to %s: %v", fromID, toID, err)
+		_, _ = c.Write([]byte{forwardRefused})
+		return
+	}
+
+	conn, err := s.dialForward(ctx, toCfg)
+	if err != nil {
+		l.Infof("Forwarding connection from %s to %s: %v", fromID, toID, err)
+		_, _ = c.Write([]byte{forwardUnreachable})
+		return
+	}
+	defer conn.Close()
+
+	if _, err := c.Write([]byte{forwardOK}); err != nil {
+		return
+	}
+	_ = c.SetDeadline(time.Time{})
+
+	// Both ends are subject to the rate limits of their devices, as any
+	// other connection.
+	fromRd, fromWr := s.limiter.getLimiters(fromID, c, s.isLAN(c.RemoteAddr()))
+	toRd, toWr := s.limiter.getLimiters(toID, conn, s.isLAN(conn.RemoteAddr()))
+
+	l.Infof("Forwarding connection from %s to %s at %s", fromID, toID, conn.RemoteAddr())
+	pipe(ctx, readWriter{fromRd, fromWr}, readWriter{toRd, toWr})
+	l.Debugln("Forwarding from", fromID, "to", toID, "done")
+}
+
+// dialForward makes the plain TCP connection to the device to forward to,
+// at the first of its addresses that works.
+func (s *service) dialForward(ctx context.Context, toCfg config.DeviceConfiguration) (net.Conn, error) {
+	err := errors.New("no TCP addresses")
+	for _, addr := range s.deviceAddresses(toCfg) {
+		uri, perr := url.Parse(addr)
+		if perr != nil || !isTCPScheme(uri.Scheme) {
+			continue
+		}
+		uri = fixupPort(uri, config.DefaultTCPPort)
+		if len(toCfg.AllowedNetworks) > 0 && !IsAllowedNetwork(uri.Host, toCfg.AllowedNetworks) {
+			continue
+		}
+		timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		var conn net.Conn
+		conn, err = dialer.DialContext(timeoutCtx, uri.Scheme, uri.Host)
+		cancel()
+		if err == nil {
+			return conn, nil
+		}
+	}
+	return nil, err
+}
+
+func isTCPScheme(scheme string) bool {
+	return scheme == "tcp" || scheme == "tcp4" || scheme == "tcp6"
+}
+
+// pipe copies data both ways between the connections until either is done.
+func pipe(ctx context.Context, a, b io.ReadWriter) {
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(a, b)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(b, a)
+		done <- struct{}{}
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+type readWriter struct {
+	io.Reader
+	io.Writer
+}
+
+// requestForward asks the forwarding device at the other end of the
+// connection to forward it to the given device.
+func requestForward(c net.Conn, to protocol.DeviceID) error {
+	_ = c.SetDeadline(time.Now().Add(20 * time.Second))
+	defer c.SetDeadline(time.Time{})
+	if _, err := c.Write(to[:]); err != nil {
+		return err
+	}
+	var answer [1]byte
+	if _, err := io.ReadFull(c, answer[:]); err != nil {
+		return err
+	}
+	switch answer[0] {
+	case forwardOK:
+		return nil
+	case forwardRefused:
+		return errForwardRefused
+	case forwardUnreachable:
+		return errForwardUnreachable
+	default:
+		return fmt.Errorf("unexpected answer %d to forwarding request", answer[0])
+	}
+}
+
+type forwardDialer struct {
+	commonDialer
+	s *service
+}
+
+// Dial connects to the device through the forwarding device given as the
+// host of the URI, trying each of its TCP addresses.
+func (d *forwardDialer) Dial(ctx context.Context, id protocol.DeviceID, uri *url.URL) (internalConn, error) {
+	via, err := protocol.DeviceIDFromString(uri.Host)
+	if err != nil {
+		return internalConn{}, err
+	}
+	viaCfg, ok := d.s.cfg.Device(via)
+	if !ok {
+		return internalConn{}, fmt.Errorf("forwarding device %s is unknown", via)
+	}
+
+	err = errors.New("no TCP addresses for forwarding device")
+	for _, addr := range d.s.deviceAddresses(viaCfg) {
+		viaURI, perr := url.Parse(addr)
+		if perr != nil || !isTCPScheme(viaURI.Scheme) {
+			continue
+		}
+		var conn internalConn
+		conn, err = d.dialVia(ctx, via, fixupPort(viaURI, config.DefaultTCPPort), id)
+		if err == nil {
+			return conn, nil
+		}
+		l.Debugln("Dial (BEP/forward): via", via, "at", viaURI, err)
+	}
+	return internalConn{}, err
+}
+
+func (d *forwardDialer) dialVia(ctx context.Context, via protocol.DeviceID, viaURI *url.URL, id protocol.DeviceID) (internalConn, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	conn, err := dialer.DialContext(timeoutCtx, viaURI.Scheme, viaURI.Host)
+	if err != nil {
+		return internalConn{}, err
+	}
+
+	err = dialer.SetTCPOptions(conn)
+	if err != nil {
+		l.Debugln("Dial (BEP/forward): setting tcp options:", err)
+	}
+
+	fwdCfg := d.tlsCfg.Clone()
+	fwdCfg.NextProtos = []string{forwardProtocolName}
+	outer := tls.Client(conn, fwdCfg)
+	if err := tlsTimedHandshake(outer); err != nil {
+		outer.Close()
+		return internalConn{}, err
+	}
+	if err := d.s.validateIdentity(internalConn{outer, connTypeForwardClient, forwardPriority}, via); err != nil {
+		outer.Close()
+		return internalConn{}, err
+	}
+	if outer.ConnectionState().NegotiatedProtocol != forwardProtocolName {
+		outer.Close()
+		return internalConn{}, errForwardUnsupported
+	}
+
+	if err := requestForward(outer, id); err != nil {
+		outer.Close()
+		return internalConn{}, err
+	}
+
+	tc := tls.Client(outer, d.tlsCfg)
+	if err := tlsTimedHandshake(tc); err != nil {
+		tc.Close()
+		return internalConn{}, err
+	}
+
+	return internalConn{tc, connTypeForwardClient, forwardPriority}, nil
+}
+
+type forwardDialerFactory struct {
+	s *service
+}
+
+func (f forwardDialerFactory) New(opts config.OptionsConfiguration, tlsCfg *tls.Config) genericDialer {
+	return &forwardDialer{
+		commonDialer: commonDialer{
+			trafficClass:      opts.TrafficClass,
+			reconnectInterval: time.Duration(opts.ReconnectIntervalS) * time.Second,
+			tlsCfg:            tlsCfg,
+		},
+		s: f.s,
+	}
+}
+
+func (forwardDialerFactory) Priority() int {
+	return forwardPriority
+}
+
+func (forwardDialerFactory) AlwaysWAN() bool {
+	return true
+}
+
+func (forwardDialerFactory) Valid(_ config.Configuration) error {
+	// Always valid
+	return nil
+}
+
+func (forwardDialerFactory) String() string {
+	return "Forward Dialer"
+}
@@ -0,0 +1,96 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"io"
+	"net"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestForwardTarget(t *testing.T) {
+	from := protocol.DeviceID{1}
+	to := protocol.DeviceID{2}
+	other := protocol.DeviceID{3}
+	paused := protocol.DeviceID{4}
+	limited := protocol.DeviceID{6}
+
+	fromCfg := config.NewDeviceConfiguration(from, "from")
+	fromCfg.ForwardTo = []protocol.DeviceID{to, paused}
+	pausedCfg := config.NewDeviceConfiguration(paused, "paused")
+	pausedCfg.Paused = true
+	limitedCfg := config.NewDeviceConfiguration(limited, "limited")
+	limitedCfg.ForwardTo = []protocol.DeviceID{to}
+	limitedCfg.AllowedNetworks = []string{"192.168.0.0/16"}
+	cfg := config.Configuration{
+		Devices: []config.DeviceConfiguration{
+			fromCfg,
+			config.NewDeviceConfiguration(to, "to"),
+			config.NewDeviceConfiguration(other, "other"),
+			pausedCfg,
+			limitedCfg,
+		},
+	}
+
+	const addr = "10.0.0.1:22000"
+	cases := []struct {
+		from, to protocol.DeviceID
+		addr     string
+		ok       bool
+	}{
+		{from, to, addr, true},
+		// Only to the devices configured for the requesting one
+		{from, other, addr, false},
+		{other, to, addr, false},
+		// Not to paused or unknown devices
+		{from, paused, addr, false},
+		{from, protocol.DeviceID{5}, addr, false},
+		{protocol.DeviceID{5}, to, addr, false},
+		// Only from the networks the requesting device is allowed in
+		{limited, to, addr, false},
+		{limited, to, "192.168.1.1:22000", true},
+	}
+	for _, tc := range cases {
+		toCfg, err := forwardTarget(cfg, tc.from, tc.to, tc.addr)
+		if ok := err == nil; ok != tc.ok {
+			t.Errorf("forwarding from %v to %v: got %v, expected ok=%v", tc.from, tc.to, err, tc.ok)
+		} else if ok && toCfg.DeviceID != tc.to {
+			t.Errorf("forwarding from %v to %v: got config for %v", tc.from, tc.to, toCfg.DeviceID)
+		}
+	}
+}
+
+func TestRequestForward(t *testing.T) {
+	to := protocol.DeviceID{2}
+	cases := []struct {
+		answer byte
+		err    error
+	}{
+		{forwardOK, nil},
+		{forwardRefused, errForwardRefused},
+		{forwardUnreachable, errForwardUnreachable},
+	}
+	for _, tc := range cases {
+		a, b := net.Pipe()
+		go func(answer byte) {
+			var id protocol.DeviceID
+			if _, err := io.ReadFull(b, id[:]); err != nil || id != to {
+				b.Close()
+				return
+			}
+			b.Write([]byte{answer})
+		}(tc.answer)
+		if err := requestForward(a, to); err != tc.err {
+			t.Errorf("answer %d: got %v, expected %v", tc.answer, err, tc.err)
+		}
+		a.Close()
+		b.Close()
+	}
+}
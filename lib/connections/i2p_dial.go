@@ -0,0 +1,87 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"context"
+	"crypto/tls"
+	"net/url"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+const i2pPriority = 190
+
+func init() {
+	dialers["i2p"] = i2pDialerFactory{}
+}
+
+// i2pDialer connects to I2P destinations, given by their .b32.i2p address,
+// through the SAM bridge. Each connection gets a transient session of its
+// own, so they can't be linked to each other or to our listener.
+type i2pDialer struct {
+	commonDialer
+	samAddr string
+}
+
+func (d *i2pDialer) Dial(ctx context.Context, _ protocol.DeviceID, uri *url.URL) (internalConn, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, 2*samTunnelTimeout)
+	defer cancel()
+
+	session, err := newSAMSession(timeoutCtx, d.samAddr, "")
+	if err != nil {
+		return internalConn{}, err
+	}
+	conn, err := session.connect(timeoutCtx, uri.Hostname())
+	if err != nil {
+		session.Close()
+		return internalConn{}, err
+	}
+
+	tc := tls.Client(&sessionConn{conn, session}, d.tlsCfg)
+	err = tlsTimedHandshake(tc)
+	if err != nil {
+		tc.Close()
+		return internalConn{}, err
+	}
+
+	return internalConn{tc, connTypeI2PClient, i2pPriority}, nil
+}
+
+type i2pDialerFactory struct{}
+
+func (i2pDialerFactory) New(opts config.OptionsConfiguration, tlsCfg *tls.Config) genericDialer {
+	return &i2pDialer{
+		commonDialer: commonDialer{
+			trafficClass:      opts.TrafficClass,
+			reconnectInterval: time.Duration(opts.ReconnectIntervalS) * time.Second,
+			tlsCfg:            tlsCfg,
+		},
+		samAddr: opts.I2PSAMAddress,
+	}
+}
+
+func (i2pDialerFactory) Priority() int {
+	return i2pPriority
+}
+
+func (i2pDialerFactory) AlwaysWAN() bool {
+	return true
+}
+
+func (i2pDialerFactory) Valid(cfg config.Configuration) error {
+	if cfg.Options.I2PSAMAddress == "" {
+		return errDisabled
+	}
+	return nil
+}
+
+func (i2pDialerFactory) String() string {
+	return "I2P Dialer"
+}
@@ -0,0 +1,224 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/locations"
+	"github.com/syncthing/syncthing/lib/nat"
+	"github.com/syncthing/syncthing/lib/osutil"
+	"github.com/syncthing/syncthing/lib/util"
+)
+
+func init() {
+	listeners["i2p"] = &i2pListenerFactory{}
+}
+
+// An i2pListener accepts connections to a destination of ours, through
+// the SAM bridge given as the host of the listen address, as in
+// "i2p://127.0.0.1:7656". The keys for the destination are kept, so that
+// its .b32.i2p address, which is what's announced, stays the same.
+type i2pListener struct {
+	util.ServiceWithError
+	onAddressesChangedNotifier
+
+	uri     *url.URL
+	cfg     config.Wrapper
+	tlsCfg  *tls.Config
+	conns   chan internalConn
+	factory listenerFactory
+
+	address *url.URL
+	mut     sync.RWMutex
+}
+
+func (t *i2pListener) serve(ctx context.Context) error {
+	keysFile := locations.Get(locations.I2PKeys)
+	keys, err := loadI2PKeys(keysFile)
+	if err != nil {
+		l.Infoln("Listen (BEP/i2p):", err)
+		return err
+	}
+
+	session, err := newSAMSession(ctx, t.uri.Host, keys)
+	if err != nil {
+		l.Infoln("Listen (BEP/i2p):", err)
+		return err
+	}
+	defer session.Close()
+
+	if session.keys != keys {
+		if err := saveI2PKeys(keysFile, session.keys); err != nil {
+			l.Warnln("Listen (BEP/i2p): saving keys, the address will change next time:", err)
+		}
+	}
+	b32, err := i2pB32Address(session.dest)
+	if err != nil {
+		l.Infoln("Listen (BEP/i2p):", err)
+		return err
+	}
+
+	t.mut.Lock()
+	t.address = &url.URL{Scheme: "i2p", Host: b32}
+	t.mut.Unlock()
+	t.notifyAddressesChanged(t)
+	defer func() {
+		t.mut.Lock()
+		t.address = nil
+		t.mut.Unlock()
+		t.notifyAddressesChanged(t)
+	}()
+
+	l.Infof("I2P listener (%v via %v) starting", b32, t.uri.Host)
+	defer l.Infof("I2P listener (%v via %v) shutting down", b32, t.uri.Host)
+
+	// The session is gone when the bridge closes its control connection,
+	// and so is the listener then.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	sessionDone := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(ioutil.Discard, session.ctrl)
+		close(sessionDone)
+		cancel()
+	}()
+
+	acceptFailures := 0
+	const maxAcceptFailures = 10
+
+	for {
+		conn, from, err := session.accept(ctx)
+		select {
+		case <-sessionDone:
+			if err == nil {
+				conn.Close()
+			}
+			return errors.New("SAM session closed")
+		case <-ctx.Done():
+			if err == nil {
+				conn.Close()
+			}
+			return nil
+		default:
+		}
+		if err != nil {
+			l.Warnln("Listen (BEP/i2p): Accepting connection:", err)
+
+			acceptFailures++
+			if acceptFailures > maxAcceptFailures {
+				// Return to restart the listener, because something
+				// seems permanently damaged.
+				return err
+			}
+
+			// Slightly increased delay for each failure.
+			time.Sleep(time.Duration(acceptFailures) * time.Second)
+			continue
+		}
+
+		acceptFailures = 0
+		if b32, err := i2pB32Address(from); err == nil {
+			l.Debugln("Listen (BEP/i2p): connect from", b32)
+		}
+
+		tc := tls.Server(conn, t.tlsCfg)
+		if err := tlsTimedHandshake(tc); err != nil {
+			l.Infoln("Listen (BEP/i2p): TLS handshake:", err)
+			tc.Close()
+			continue
+		}
+
+		t.conns <- internalConn{tc, connTypeI2PServer, i2pPriority}
+	}
+}
+
+// loadI2PKeys returns the kept destination keys, if any.
+func loadI2PKeys(path string) (string, error) {
+	bs, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(bs)), nil
+}
+
+// saveI2PKeys keeps the destination keys, readable only by us like any
+// other private key.
+func saveI2PKeys(path, keys string) error {
+	fd, err := osutil.CreateAtomic(path)
+	if err != nil {
+		return err
+	}
+	if _, err := fd.Write([]byte(keys + "\n")); err != nil {
+		fd.Close()
+		return err
+	}
+	return fd.Close()
+}
+
+func (t *i2pListener) URI() *url.URL {
+	return t.uri
+}
+
+func (t *i2pListener) WANAddresses() []*url.URL {
+	t.mut.RLock()
+	defer t.mut.RUnlock()
+	if t.address == nil {
+		return nil
+	}
+	return []*url.URL{t.address}
+}
+
+func (t *i2pListener) LANAddresses() []*url.URL {
+	// There's no way in other than through I2P
+	return nil
+}
+
+func (t *i2pListener) String() string {
+	return t.uri.String()
+}
+
+func (t *i2pListener) Factory() listenerFactory {
+	return t.factory
+}
+
+func (t *i2pListener) NATType() string {
+	return "unknown"
+}
+
+type i2pListenerFactory struct{}
+
+func (f *i2pListenerFactory) New(uri *url.URL, cfg config.Wrapper, tlsCfg *tls.Config, conns chan internalConn, _ *nat.Service) genericListener {
+	l := &i2pListener{
+		uri:     fixupPort(uri, 7656),
+		cfg:     cfg,
+		tlsCfg:  tlsCfg,
+		conns:   conns,
+		factory: f,
+	}
+	l.ServiceWithError = util.AsServiceWithError(l.serve, l.String())
+	return l
+}
+
+func (i2pListenerFactory) Valid(_ config.Configuration) error {
+	// Always valid
+	return nil
+}
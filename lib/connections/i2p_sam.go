@@ -0,0 +1,264 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/syncthing/syncthing/lib/dialer"
+	"github.com/syncthing/syncthing/lib/rand"
+)
+
+// This is just enough of the SAM v3 protocol, as spoken by the SAM bridge
+// of the I2P router, to make and accept stream connections. See
+// https://geti2p.net/en/docs/api/samv3.
+
+const (
+	samTimeout = 10 * time.Second
+	// Tunnels take a while to build, both when creating a session and when
+	// connecting to a destination through it.
+	samTunnelTimeout = 2 * time.Minute
+)
+
+// I2P uses its own base64 alphabet for destinations
+var i2pEncoding = base64.NewEncoding("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-~")
+
+// i2pB32Address returns the .b32.i2p address of a destination.
+func i2pB32Address(dest string) (string, error) {
+	bs, err := i2pEncoding.DecodeString(dest)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(bs)
+	b32 := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(hash[:])
+	return strings.ToLower(b32) + ".b32.i2p", nil
+}
+
+// parseSAMReply splits a reply into the command and result, such as
+// "SESSION STATUS", and the values, which may be quoted.
+func parseSAMReply(line string) (string, map[string]string) {
+	line = strings.TrimRight(line, "\r\n")
+	var words []string
+	var word strings.Builder
+	quoted := false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ' ' && !quoted:
+			if word.Len() > 0 {
+				words = append(words, word.String())
+				word.Reset()
+			}
+		default:
+			word.WriteRune(r)
+		}
+	}
+	if word.Len() > 0 {
+		words = append(words, word.String())
+	}
+
+	var cmd []string
+	values := make(map[string]string)
+	for _, w := range words {
+		if i := strings.IndexByte(w, '='); i > 0 {
+			values[w[:i]] = w[i+1:]
+		} else if len(values) == 0 {
+			cmd = append(cmd, w)
+		}
+	}
+	return strings.Join(cmd, " "), values
+}
+
+// samConn is a connection to the SAM bridge. Replies are read through the
+// buffered reader, so that's where any data following them is as well.
+type samConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *samConn) Read(bs []byte) (int, error) {
+	return c.r.Read(bs)
+}
+
+// command sends the command and returns the values of the reply, which
+// must be the expected one and successful.
+func (c *samConn) command(cmd, expected string, timeout time.Duration) (map[string]string, error) {
+	_ = c.SetDeadline(time.Now().Add(timeout))
+	defer c.SetDeadline(time.Time{})
+	if _, err := c.Write([]byte(cmd + "\n")); err != nil {
+		return nil, err
+	}
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	reply, values := parseSAMReply(line)
+	if reply != expected {
+		return nil, fmt.Errorf("SAM: unexpected reply %q to %s", reply, strings.Fields(cmd)[0])
+	}
+	if res := values["RESULT"]; res != "OK" {
+		if msg := values["MESSAGE"]; msg != "" {
+			return nil, fmt.Errorf("SAM: %s: %s", res, msg)
+		}
+		return nil, fmt.Errorf("SAM: %s", res)
+	}
+	return values, nil
+}
+
+// dialSAM connects to the SAM bridge and agrees on the protocol version.
+func dialSAM(ctx context.Context, addr string) (*samConn, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, samTimeout)
+	defer cancel()
+	conn, err := dialer.DialContext(timeoutCtx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	c := &samConn{Conn: conn, r: bufio.NewReader(conn)}
+	if _, err := c.command("HELLO VERSION MIN=3.0 MAX=3.1", "HELLO REPLY", samTimeout); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// A samSession is a stream session at the SAM bridge, which lasts as long
+// as its control connection.
+type samSession struct {
+	addr string
+	id   string
+	ctrl *samConn
+	// The destination, both the public part that others connect to and
+	// the keys to recreate it with.
+	dest string
+	keys string
+}
+
+// newSAMSession creates a session with the given keys, or a new
+// destination if there are none.
+func newSAMSession(ctx context.Context, addr, keys string) (*samSession, error) {
+	ctrl, err := dialSAM(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &samSession{
+		addr: addr,
+		id:   "syncthing-" + rand.String(8),
+		ctrl: ctrl,
+	}
+	if keys == "" {
+		keys = "TRANSIENT SIGNATURE_TYPE=EdDSA_SHA512_Ed25519"
+	}
+	values, err := ctrl.command(fmt.Sprintf("SESSION CREATE STYLE=STREAM ID=%s DESTINATION=%s", s.id, keys), "SESSION STATUS", samTunnelTimeout)
+	if err != nil {
+		ctrl.Close()
+		return nil, err
+	}
+	s.keys = values["DESTINATION"]
+	if s.dest, err = s.lookup("ME"); err != nil {
+		ctrl.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// lookup returns the destination for a name such as a .b32.i2p address.
+func (s *samSession) lookup(name string) (string, error) {
+	values, err := s.ctrl.command("NAMING LOOKUP NAME="+name, "NAMING REPLY", samTimeout)
+	if err != nil {
+		return "", err
+	}
+	return values["VALUE"], nil
+}
+
+// connect makes a stream connection to the named destination.
+func (s *samSession) connect(ctx context.Context, name string) (net.Conn, error) {
+	dest, err := s.lookup(name)
+	if err != nil {
+		return nil, err
+	}
+	c, err := dialSAM(ctx, s.addr)
+	if err != nil {
+		return nil, err
+	}
+	stop := closeOnDone(ctx, c)
+	defer stop()
+	if _, err := c.command(fmt.Sprintf("STREAM CONNECT ID=%s DESTINATION=%s SILENT=false", s.id, dest), "STREAM STATUS", samTunnelTimeout); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// accept waits for a stream connection to the session's destination, and
+// returns it with the destination it comes from.
+func (s *samSession) accept(ctx context.Context) (net.Conn, string, error) {
+	c, err := dialSAM(ctx, s.addr)
+	if err != nil {
+		return nil, "", err
+	}
+	stop := closeOnDone(ctx, c)
+	defer stop()
+	if _, err := c.command(fmt.Sprintf("STREAM ACCEPT ID=%s SILENT=false", s.id), "STREAM STATUS", samTimeout); err != nil {
+		c.Close()
+		return nil, "", err
+	}
+	// When a connection comes in, the bridge tells where from on a line
+	// of its own before the data.
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		c.Close()
+		return nil, "", err
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		c.Close()
+		return nil, "", errors.New("SAM: no destination for accepted connection")
+	}
+	return c, fields[0], nil
+}
+
+func (s *samSession) Close() error {
+	return s.ctrl.Close()
+}
+
+// closeOnDone closes the connection when the context is done, until the
+// returned function is called, to interrupt waiting for the bridge.
+func closeOnDone(ctx context.Context, c net.Conn) func() {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.Close()
+		case <-done:
+		}
+	}()
+	return func() { close(done) }
+}
+
+// sessionConn is a connection over a session of its own, which is closed
+// with it.
+type sessionConn struct {
+	net.Conn
+	session *samSession
+}
+
+func (c *sessionConn) Close() error {
+	err := c.Conn.Close()
+	c.session.Close()
+	return err
+}
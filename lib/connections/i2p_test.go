@@ -0,0 +1,121 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestParseSAMReply(t *testing.T) {
+	cmd, values := parseSAMReply(`STREAM STATUS RESULT=I2P_ERROR MESSAGE="no route to destination" ID=x` + "\n")
+	if cmd != "STREAM STATUS" {
+		t.Errorf("got command %q", cmd)
+	}
+	expected := map[string]string{
+		"RESULT":  "I2P_ERROR",
+		"MESSAGE": "no route to destination",
+		"ID":      "x",
+	}
+	if len(values) != len(expected) {
+		t.Errorf("got values %v, expected %v", values, expected)
+	}
+	for k, v := range expected {
+		if values[k] != v {
+			t.Errorf("got %s=%q, expected %q", k, values[k], v)
+		}
+	}
+}
+
+// fakeSAMBridge answers just enough of the SAM protocol for a session to
+// connect to a destination, which echoes what's sent to it.
+func fakeSAMBridge(ln net.Listener, dest string) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func(conn net.Conn) {
+			defer conn.Close()
+			r := bufio.NewReader(conn)
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				var reply string
+				switch cmd := strings.Fields(line); cmd[0] + " " + cmd[1] {
+				case "HELLO VERSION":
+					reply = "HELLO REPLY RESULT=OK VERSION=3.1"
+				case "SESSION CREATE":
+					reply = "SESSION STATUS RESULT=OK DESTINATION=privatekeys"
+				case "NAMING LOOKUP":
+					reply = "NAMING REPLY RESULT=OK NAME=x VALUE=" + dest
+				case "STREAM CONNECT":
+					if !strings.Contains(line, "DESTINATION="+dest+" ") {
+						reply = `STREAM STATUS RESULT=CANT_REACH_PEER MESSAGE="unknown destination"`
+						break
+					}
+					fmt.Fprintln(conn, "STREAM STATUS RESULT=OK")
+					_, _ = io.Copy(conn, r)
+					return
+				default:
+					reply = "UNKNOWN RESULT=I2P_ERROR"
+				}
+				fmt.Fprintln(conn, reply)
+			}
+		}(conn)
+	}
+}
+
+func TestSAMSession(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	dest := strings.Repeat("AAAA", 96) + "-~Bw"
+	go fakeSAMBridge(ln, dest)
+
+	ctx := context.Background()
+	session, err := newSAMSession(ctx, ln.Addr().String(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+	if session.keys != "privatekeys" || session.dest != dest {
+		t.Errorf("got keys %q and destination %q", session.keys, session.dest)
+	}
+	b32, err := i2pB32Address(session.dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b32) != 52+len(".b32.i2p") || !strings.HasSuffix(b32, ".b32.i2p") {
+		t.Errorf("bad address %q", b32)
+	}
+
+	conn, err := session.connect(ctx, b32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("hello\n")); err != nil {
+		t.Fatal(err)
+	}
+	bs := make([]byte, 6)
+	if _, err := io.ReadFull(conn, bs); err != nil {
+		t.Fatal(err)
+	}
+	if string(bs) != "hello\n" {
+		t.Errorf("got %q back", bs)
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/syncthing/syncthing/lib/config"
+)
+
+// splitListenerNetworks returns the listen address without the networks to
+// accept connections from, given like "?allow=192.168.0.0/16,!10.0.0.0/8",
+// and those networks. The networks aren't part of the address announced to
+// other devices.
+func splitListenerNetworks(uri *url.URL) (*url.URL, []string) {
+	query := uri.Query()
+	allow := query.Get("allow")
+	if allow == "" {
+		return uri, nil
+	}
+	query.Del("allow")
+	copyURI := *uri
+	copyURI.RawQuery = query.Encode()
+	return &copyURI, strings.Split(allow, ",")
+}
+
+// incomingAllowed returns whether a connection from the address may be
+// accepted, by the networks of the listener as well as those of the
+// options.
+func incomingAllowed(cfg config.Wrapper, addr net.Addr, listenerNets []string) bool {
+	return networksAllow(addr, listenerNets) && networksAllow(addr, cfg.Options().IncomingAllowedNetworks)
+}
+
+// checkListenerNetworks warns about networks of a listener that can't be
+// parsed, in which case it doesn't accept any connections.
+func checkListenerNetworks(uri *url.URL, networks []string) {
+	if _, err := config.ParseAllowedNetworks(networks); err != nil {
+		l.Warnf("Listener %v not accepting any connections: %v", uri, err)
+	}
+}
+
+// networksAllow returns whether the networks allow the address. The first
+// network containing the address decides, where a "!" prefix denies it.
+// An address in none of them is allowed only if all of them are denied,
+// so that a list of just those to deny works as expected. No networks
+// allow everything, while networks that can't be parsed allow nothing.
+func networksAllow(addr net.Addr, networks []string) bool {
+	if len(networks) == 0 {
+		return true
+	}
+	nets, err := config.ParseAllowedNetworks(networks)
+	if err != nil {
+		return false
+	}
+
+	host := addr.String()
+	if hostNoPort, _, err := net.SplitHostPort(host); err == nil {
+		host = hostNoPort
+	}
+	if i := strings.IndexByte(host, '%'); i >= 0 {
+		// Without the zone of link local IPv6 addresses
+		host = host[:i]
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+
+	onlyDenied := true
+	for _, n := range nets {
+		if !n.Deny {
+			onlyDenied = false
+		}
+		if n.Contains(ip) {
+			return !n.Deny
+		}
+	}
+	return onlyDenied
+}
@@ -0,0 +1,70 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"net"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestNetworksAllow(t *testing.T) {
+	cases := []struct {
+		host     string
+		networks []string
+		allowed  bool
+	}{
+		// No networks allow everything
+		{"192.0.2.1:22000", nil, true},
+		// The first network containing the address decides
+		{"192.168.1.1:22000", []string{"192.168.0.0/16"}, true},
+		{"192.168.1.1:22000", []string{"!192.168.1.0/24", "192.168.0.0/16"}, false},
+		{"192.168.2.1:22000", []string{"!192.168.1.0/24", "192.168.0.0/16"}, true},
+		// Not in any network to allow
+		{"192.0.2.1:22000", []string{"192.168.0.0/16"}, false},
+		// Only networks to deny
+		{"192.0.2.1:22000", []string{"!10.0.0.0/8"}, true},
+		{"10.1.2.3:22000", []string{"!10.0.0.0/8"}, false},
+		// IPv6, with a zone
+		{"[fe80::1%eth0]:22000", []string{"fe80::/10"}, true},
+		{"[2001:db8::1]:22000", []string{"fe80::/10"}, false},
+		// Bare addresses are networks of just that address
+		{"192.0.2.1:22000", []string{"192.0.2.1"}, true},
+		{"192.0.2.2:22000", []string{"192.0.2.1"}, false},
+		{"[2001:db8::1]:22000", []string{"!2001:db8::1"}, false},
+		// Networks that can't be parsed allow nothing
+		{"10.1.2.3:22000", []string{"!10.0.0.0/88"}, false},
+		{"192.0.2.1:22000", []string{"!10.0.0.0/88"}, false},
+	}
+
+	for _, tc := range cases {
+		addr, err := net.ResolveTCPAddr("tcp", tc.host)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res := networksAllow(addr, tc.networks); res != tc.allowed {
+			t.Errorf("networksAllow(%q, %v) => %v, expected %v", tc.host, tc.networks, res, tc.allowed)
+		}
+	}
+}
+
+func TestSplitListenerNetworks(t *testing.T) {
+	uri, _ := url.Parse("tcp://0.0.0.0:22000?allow=192.168.0.0/16,!10.0.0.0/8")
+	announced, networks := splitListenerNetworks(uri)
+	if announced.String() != "tcp://0.0.0.0:22000" {
+		t.Errorf("Unexpected listen address %v", announced)
+	}
+	if expected := []string{"192.168.0.0/16", "!10.0.0.0/8"}; !reflect.DeepEqual(networks, expected) {
+		t.Errorf("Unexpected networks %v, expected %v", networks, expected)
+	}
+
+	uri, _ = url.Parse("tcp://0.0.0.0:22000")
+	if announced, networks := splitListenerNetworks(uri); announced != uri || networks != nil {
+		t.Errorf("Unexpected split %v %v", announced, networks)
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"net"
+	"strings"
+)
+
+// An obfuscator disguises TCP connections, for networks where the
+// recognizable handshake of ours is blocked. It wraps the connection on
+// both ends, before our own TLS handshake. Which one to use is given after
+// the transport in the address scheme, as in "tcp+tls://192.0.2.42:22000",
+// for both the listener and the devices dialing it.
+type obfuscator interface {
+	Client(conn net.Conn, host string) (net.Conn, error)
+	Server(conn net.Conn) (net.Conn, error)
+}
+
+var obfuscators = make(map[string]obfuscator)
+
+// registerObfuscator makes the obfuscator available for all the TCP
+// schemes.
+func registerObfuscator(name string, obfs obfuscator) {
+	obfuscators[name] = obfs
+	for _, scheme := range []string{"tcp", "tcp4", "tcp6"} {
+		dialers[scheme+"+"+name] = &tcpDialerFactory{obfs: name}
+		listeners[scheme+"+"+name] = &tcpListenerFactory{obfs: name}
+	}
+}
+
+// splitObfuscation splits a scheme into the transport and the obfuscation,
+// if any.
+func splitObfuscation(scheme string) (string, string) {
+	if i := strings.IndexByte(scheme, '+'); i >= 0 {
+		return scheme[:i], scheme[i+1:]
+	}
+	return scheme, ""
+}
@@ -0,0 +1,73 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"io"
+	"net"
+	"testing"
+)
+
+func TestSplitObfuscation(t *testing.T) {
+	cases := []struct {
+		scheme, transport, obfs string
+	}{
+		{"tcp", "tcp", ""},
+		{"tcp4+tls", "tcp4", "tls"},
+		{"tcp+tls", "tcp", "tls"},
+	}
+	for _, tc := range cases {
+		transport, obfs := splitObfuscation(tc.scheme)
+		if transport != tc.transport || obfs != tc.obfs {
+			t.Errorf("splitObfuscation(%q) = %q, %q, expected %q, %q", tc.scheme, transport, obfs, tc.transport, tc.obfs)
+		}
+	}
+
+	for _, scheme := range []string{"tcp+tls", "tcp4+tls", "tcp6+tls"} {
+		if _, ok := dialers[scheme]; !ok {
+			t.Errorf("no dialer for %q", scheme)
+		}
+		if _, ok := listeners[scheme]; !ok {
+			t.Errorf("no listener for %q", scheme)
+		}
+	}
+}
+
+func TestTLSObfuscator(t *testing.T) {
+	obfs := obfuscators["tls"]
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		sc, err := obfs.Server(b)
+		if err != nil {
+			done <- err
+			return
+		}
+		_, err = io.Copy(sc, sc)
+		done <- err
+	}()
+
+	cc, err := obfs.Client(a, "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cc.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(cc, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "hello" {
+		t.Errorf("got %q, expected %q", buf, "hello")
+	}
+	cc.Close()
+	<-done
+}
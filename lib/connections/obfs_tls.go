@@ -0,0 +1,62 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"crypto/tls"
+	"net"
+	"sync"
+
+	"github.com/syncthing/syncthing/lib/tlsutil"
+)
+
+func init() {
+	registerObfuscator("tls", &tlsObfuscator{})
+}
+
+// tlsObfuscator wraps the connection in another TLS connection that looks
+// like one to any HTTPS server, with the usual protocols and a throwaway
+// certificate. It authenticates nothing, as our own TLS inside does that.
+type tlsObfuscator struct {
+	once    sync.Once
+	cert    tls.Certificate
+	certErr error
+}
+
+func (o *tlsObfuscator) Client(conn net.Conn, host string) (net.Conn, error) {
+	cfg := &tls.Config{
+		// The server name is only sent when it's a name
+		ServerName:         host,
+		InsecureSkipVerify: true,
+		NextProtos:         []string{"h2", "http/1.1"},
+		MinVersion:         tls.VersionTLS12,
+	}
+	tc := tls.Client(conn, cfg)
+	if err := tlsTimedHandshake(tc); err != nil {
+		return nil, err
+	}
+	return tc, nil
+}
+
+func (o *tlsObfuscator) Server(conn net.Conn) (net.Conn, error) {
+	o.once.Do(func() {
+		o.cert, o.certErr = tlsutil.GenerateCertificate("localhost", 365)
+	})
+	if o.certErr != nil {
+		return nil, o.certErr
+	}
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{o.cert},
+		NextProtos:   []string{"h2", "http/1.1"},
+		MinVersion:   tls.VersionTLS12,
+	}
+	tc := tls.Server(conn, cfg)
+	if err := tlsTimedHandshake(tc); err != nil {
+		return nil, err
+	}
+	return tc, nil
+}
@@ -0,0 +1,95 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"context"
+	"crypto/tls"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/proxy"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+const onionPriority = 180
+
+func init() {
+	dialers["onion"] = onionDialerFactory{}
+}
+
+// onionDialer connects to onion services through the Tor SOCKS proxy,
+// which resolves the onion address itself.
+type onionDialer struct {
+	commonDialer
+	proxyAddr string
+}
+
+func (d *onionDialer) Dial(ctx context.Context, _ protocol.DeviceID, uri *url.URL) (internalConn, error) {
+	uri = fixupPort(uri, config.DefaultTCPPort)
+
+	socks, err := proxy.SOCKS5("tcp", d.proxyAddr, nil, proxy.Direct)
+	if err != nil {
+		return internalConn{}, err
+	}
+	ctxDialer, ok := socks.(proxy.ContextDialer)
+	if !ok {
+		return internalConn{}, errors.New("SOCKS dialer doesn't support contexts")
+	}
+
+	// Circuits take a while to build
+	timeoutCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+	conn, err := ctxDialer.DialContext(timeoutCtx, "tcp", uri.Host)
+	if err != nil {
+		return internalConn{}, err
+	}
+
+	tc := tls.Client(conn, d.tlsCfg)
+	err = tlsTimedHandshake(tc)
+	if err != nil {
+		tc.Close()
+		return internalConn{}, err
+	}
+
+	return internalConn{tc, connTypeOnionClient, onionPriority}, nil
+}
+
+type onionDialerFactory struct{}
+
+func (onionDialerFactory) New(opts config.OptionsConfiguration, tlsCfg *tls.Config) genericDialer {
+	return &onionDialer{
+		commonDialer: commonDialer{
+			trafficClass:      opts.TrafficClass,
+			reconnectInterval: time.Duration(opts.ReconnectIntervalS) * time.Second,
+			tlsCfg:            tlsCfg,
+		},
+		proxyAddr: opts.TorProxyAddress,
+	}
+}
+
+func (onionDialerFactory) Priority() int {
+	return onionPriority
+}
+
+func (onionDialerFactory) AlwaysWAN() bool {
+	return true
+}
+
+func (onionDialerFactory) Valid(cfg config.Configuration) error {
+	if cfg.Options.TorProxyAddress == "" {
+		return errDisabled
+	}
+	return nil
+}
+
+func (onionDialerFactory) String() string {
+	return "Onion Dialer"
+}
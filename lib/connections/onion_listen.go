@@ -0,0 +1,187 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/nat"
+	"github.com/syncthing/syncthing/lib/util"
+)
+
+func init() {
+	listeners["onion"] = &onionListenerFactory{}
+}
+
+// An onionListener accepts the connections that Tor forwards from an
+// onion service, and announces the onion address. The onion service is
+// set up in Tor, with a HiddenServicePort pointing at the local address
+// to listen at, which is given in the listen address as in
+// "onion://abcdef...xyz.onion:22000?local=127.0.0.1:22001".
+type onionListener struct {
+	util.ServiceWithError
+	onAddressesChangedNotifier
+
+	uri      *url.URL
+	announce *url.URL
+	local    string
+	err      error
+	cfg      config.Wrapper
+	tlsCfg   *tls.Config
+	conns    chan internalConn
+	factory  listenerFactory
+}
+
+// parseOnionURI returns the address to announce and the local address to
+// listen at for an onion listen address.
+func parseOnionURI(uri *url.URL) (*url.URL, string, error) {
+	host, _, err := net.SplitHostPort(uri.Host)
+	if err != nil {
+		return nil, "", err
+	}
+	if !strings.HasSuffix(host, ".onion") {
+		return nil, "", fmt.Errorf("%q is not an onion address", host)
+	}
+	local := uri.Query().Get("local")
+	if local == "" {
+		return nil, "", fmt.Errorf("no local address to listen at in %s", uri)
+	}
+	if _, _, err := net.SplitHostPort(local); err != nil {
+		return nil, "", err
+	}
+	announce := *uri
+	announce.RawQuery = ""
+	return &announce, local, nil
+}
+
+func (t *onionListener) serve(ctx context.Context) error {
+	if t.err != nil {
+		l.Infoln("Listen (BEP/onion):", t.err)
+		return t.err
+	}
+
+	tcaddr, err := net.ResolveTCPAddr("tcp", t.local)
+	if err != nil {
+		l.Infoln("Listen (BEP/onion):", err)
+		return err
+	}
+
+	listener, err := net.ListenTCP("tcp", tcaddr)
+	if err != nil {
+		l.Infoln("Listen (BEP/onion):", err)
+		return err
+	}
+	defer listener.Close()
+
+	l.Infof("Onion listener (%v for %v) starting", listener.Addr(), t.announce)
+	defer l.Infof("Onion listener (%v for %v) shutting down", listener.Addr(), t.announce)
+
+	acceptFailures := 0
+	const maxAcceptFailures = 10
+
+	for {
+		listener.SetDeadline(time.Now().Add(time.Second))
+		conn, err := listener.Accept()
+		select {
+		case <-ctx.Done():
+			if err == nil {
+				conn.Close()
+			}
+			return nil
+		default:
+		}
+		if err != nil {
+			if err, ok := err.(*net.OpError); !ok || !err.Timeout() {
+				l.Warnln("Listen (BEP/onion): Accepting connection:", err)
+
+				acceptFailures++
+				if acceptFailures > maxAcceptFailures {
+					// Return to restart the listener, because something
+					// seems permanently damaged.
+					return err
+				}
+
+				// Slightly increased delay for each failure.
+				time.Sleep(time.Duration(acceptFailures) * time.Second)
+			}
+			continue
+		}
+
+		acceptFailures = 0
+		// Everything comes from the local Tor daemon, so the remote
+		// address says nothing about the other side.
+		l.Debugln("Listen (BEP/onion): connect from Tor at", conn.RemoteAddr())
+
+		tc := tls.Server(conn, t.tlsCfg)
+		if err := tlsTimedHandshake(tc); err != nil {
+			l.Infoln("Listen (BEP/onion): TLS handshake:", err)
+			tc.Close()
+			continue
+		}
+
+		t.conns <- internalConn{tc, connTypeOnionServer, onionPriority}
+	}
+}
+
+func (t *onionListener) URI() *url.URL {
+	return t.uri
+}
+
+func (t *onionListener) WANAddresses() []*url.URL {
+	if t.announce == nil {
+		return nil
+	}
+	return []*url.URL{t.announce}
+}
+
+func (t *onionListener) LANAddresses() []*url.URL {
+	// There's no way in other than through Tor
+	return nil
+}
+
+func (t *onionListener) String() string {
+	return t.uri.String()
+}
+
+func (t *onionListener) Factory() listenerFactory {
+	return t.factory
+}
+
+func (t *onionListener) NATType() string {
+	return "unknown"
+}
+
+type onionListenerFactory struct{}
+
+func (f *onionListenerFactory) New(uri *url.URL, cfg config.Wrapper, tlsCfg *tls.Config, conns chan internalConn, _ *nat.Service) genericListener {
+	uri = fixupPort(uri, config.DefaultTCPPort)
+	announce, local, err := parseOnionURI(uri)
+	l := &onionListener{
+		uri:      uri,
+		announce: announce,
+		local:    local,
+		err:      err,
+		cfg:      cfg,
+		tlsCfg:   tlsCfg,
+		conns:    conns,
+		factory:  f,
+	}
+	l.ServiceWithError = util.AsServiceWithError(l.serve, l.String())
+	return l
+}
+
+func (onionListenerFactory) Valid(_ config.Configuration) error {
+	// Always valid
+	return nil
+}
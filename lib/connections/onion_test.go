@@ -0,0 +1,58 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/config"
+)
+
+func TestParseOnionURI(t *testing.T) {
+	cases := []struct {
+		uri      string
+		announce string
+		local    string
+		ok       bool
+	}{
+		{"onion://abcdef.onion:22000?local=127.0.0.1:22001", "onion://abcdef.onion:22000", "127.0.0.1:22001", true},
+		// Not an onion address
+		{"onion://example.com:22000?local=127.0.0.1:22001", "", "", false},
+		// Nowhere to listen
+		{"onion://abcdef.onion:22000", "", "", false},
+		{"onion://abcdef.onion:22000?local=127.0.0.1", "", "", false},
+	}
+	for _, tc := range cases {
+		uri, err := url.Parse(tc.uri)
+		if err != nil {
+			t.Fatal(err)
+		}
+		announce, local, err := parseOnionURI(uri)
+		if ok := err == nil; ok != tc.ok {
+			t.Errorf("%s: got %v, expected ok=%v", tc.uri, err, tc.ok)
+			continue
+		}
+		if !tc.ok {
+			continue
+		}
+		if announce.String() != tc.announce || local != tc.local {
+			t.Errorf("%s: got %v and %v, expected %v and %v", tc.uri, announce, local, tc.announce, tc.local)
+		}
+	}
+}
+
+func TestOnionDialerValid(t *testing.T) {
+	cfg := config.New(device1)
+	if err := (onionDialerFactory{}).Valid(cfg); err != errDisabled {
+		t.Errorf("without a proxy: got %v, expected %v", err, errDisabled)
+	}
+	cfg.Options.TorProxyAddress = "127.0.0.1:9050"
+	if err := (onionDialerFactory{}).Valid(cfg); err != nil {
+		t.Errorf("with a proxy: got %v, expected no error", err)
+	}
+}
@@ -41,6 +41,7 @@ type quicListener struct {
 	onAddressesChangedNotifier
 
 	uri     *url.URL
+	allowed []string // the networks to accept connections from, besides those of the options
 	cfg     config.Wrapper
 	tlsCfg  *tls.Config
 	conns   chan internalConn
@@ -140,6 +141,14 @@ func (t *quicListener) serve(ctx context.Context) error {
 
 		l.Debugln("connect from", session.RemoteAddr())
 
+		// The TLS handshake is part of establishing the QUIC session, so
+		// unlike for TCP this can't be checked before it.
+		if !incomingAllowed(t.cfg, session.RemoteAddr(), t.allowed) {
+			l.Debugln("connection from", session.RemoteAddr(), "not from an allowed network")
+			_ = session.Close()
+			continue
+		}
+
 		streamCtx, cancel := context.WithTimeout(ctx, quicOperationTimeout)
 		stream, err := session.AcceptStream(streamCtx)
 		cancel()
@@ -194,8 +203,11 @@ func (f *quicListenerFactory) Valid(config.Configuration) error {
 }
 
 func (f *quicListenerFactory) New(uri *url.URL, cfg config.Wrapper, tlsCfg *tls.Config, conns chan internalConn, natService *nat.Service) genericListener {
+	uri, allowed := splitListenerNetworks(uri)
+	checkListenerNetworks(uri, allowed)
 	l := &quicListener{
 		uri:     fixupPort(uri, config.DefaultQUICPort),
+		allowed: allowed,
 		cfg:     cfg,
 		tlsCfg:  tlsCfg,
 		conns:   conns,
@@ -0,0 +1,95 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"time"
+
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// How many of the latest rejected connections are kept
+const maxRejectedConnections = 100
+
+// The reasons for rejecting incoming connections
+const (
+	RejectCertificate = "certificate" // not exactly one certificate, or the wrong name on it
+	RejectSelf        = "self"        // from our own device ID
+	RejectHello       = "hello"       // the hello exchange failed
+	RejectDevice      = "device"      // unknown, ignored, paused or not from an allowed network
+	RejectDuplicate   = "duplicate"   // already connected, and not better than that
+	RejectRemoved     = "removed"     // the device was removed while connecting
+	RejectLimit       = "limit"       // at the maximum number of connections
+	RejectTarpit      = "tarpit"      // held in the tarpit after repeated attempts with an unknown device ID
+)
+
+// A RejectedConnection is an incoming connection we didn't take, as in the
+// ConnectionRejected events.
+type RejectedConnection struct {
+	When    time.Time `json:"when"`
+	Address string    `json:"address"`
+	Device  string    `json:"device"` // as claimed by the certificate, empty without one
+	Type    string    `json:"type"`
+	Reason  string    `json:"reason"`
+	Error   string    `json:"error,omitempty"`
+}
+
+func (t connType) isIncoming() bool {
+	switch t {
+	case connTypeRelayServer, connTypeTCPServer, connTypeQUICServer, connTypeOnionServer, connTypeI2PServer:
+		return true
+	default:
+		return false
+	}
+}
+
+// rejectConnection closes the connection, and unless it's one we made
+// records why it was rejected and emits an event about it.
+func (s *service) rejectConnection(c internalConn, device protocol.DeviceID, reason string, err error) {
+	c.Close()
+	if !c.connType.isIncoming() {
+		return
+	}
+
+	rej := RejectedConnection{
+		When:    time.Now().UTC().Truncate(time.Second),
+		Address: c.RemoteAddr().String(),
+		Type:    c.Type(),
+		Reason:  reason,
+	}
+	if device != protocol.EmptyDeviceID {
+		rej.Device = device.String()
+	}
+	if err != nil {
+		rej.Error = err.Error()
+	}
+
+	s.rejectedMut.Lock()
+	if len(s.rejected) == maxRejectedConnections {
+		copy(s.rejected, s.rejected[1:])
+		s.rejected = s.rejected[:maxRejectedConnections-1]
+	}
+	s.rejected = append(s.rejected, rej)
+	s.rejectedMut.Unlock()
+
+	s.evLogger.Log(events.ConnectionRejected, map[string]string{
+		"address": rej.Address,
+		"device":  rej.Device,
+		"type":    rej.Type,
+		"reason":  rej.Reason,
+		"error":   rej.Error,
+	})
+}
+
+// RejectedConnections returns the latest incoming connections that were
+// rejected, oldest first.
+func (s *service) RejectedConnections() []RejectedConnection {
+	s.rejectedMut.Lock()
+	defer s.rejectedMut.Unlock()
+	return append([]RejectedConnection(nil), s.rejected...)
+}
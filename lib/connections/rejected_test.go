@@ -0,0 +1,82 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+type fakeTLSConn struct {
+	net.Conn
+}
+
+func (fakeTLSConn) ConnectionState() tls.ConnectionState {
+	return tls.ConnectionState{}
+}
+
+func TestRejectConnection(t *testing.T) {
+	evLogger := events.NewLogger()
+	go evLogger.Serve()
+	defer evLogger.Stop()
+	sub := evLogger.Subscribe(events.ConnectionRejected)
+	defer sub.Unsubscribe()
+
+	s := &service{
+		evLogger:    evLogger,
+		rejectedMut: sync.NewMutex(),
+	}
+	newConn := func(ct connType) internalConn {
+		c1, c2 := net.Pipe()
+		c2.Close()
+		return internalConn{fakeTLSConn{c1}, ct, 0}
+	}
+
+	// Connections we made ourselves are not recorded
+	s.rejectConnection(newConn(connTypeTCPClient), protocol.EmptyDeviceID, RejectCertificate, nil)
+	if l := len(s.RejectedConnections()); l != 0 {
+		t.Fatalf("expected no rejected connections, got %d", l)
+	}
+
+	s.rejectConnection(newConn(connTypeTCPServer), protocol.LocalDeviceID, RejectDevice, errors.New("unknown device"))
+	rej := s.RejectedConnections()
+	if len(rej) != 1 {
+		t.Fatalf("expected one rejected connection, got %d", len(rej))
+	}
+	if rej[0].Device != protocol.LocalDeviceID.String() || rej[0].Reason != RejectDevice || rej[0].Error != "unknown device" {
+		t.Errorf("unexpected rejected connection %+v", rej[0])
+	}
+
+	ev, err := sub.Poll(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data := ev.Data.(map[string]string); data["reason"] != RejectDevice || data["device"] != rej[0].Device {
+		t.Errorf("unexpected event data %v", data)
+	}
+
+	// Only the latest are kept
+	for i := 0; i < maxRejectedConnections; i++ {
+		s.rejectConnection(newConn(connTypeTCPServer), protocol.EmptyDeviceID, RejectLimit, nil)
+	}
+	rej = s.RejectedConnections()
+	if len(rej) != maxRejectedConnections {
+		t.Fatalf("expected %d rejected connections, got %d", maxRejectedConnections, len(rej))
+	}
+	for _, r := range rej {
+		if r.Reason != RejectLimit {
+			t.Fatalf("expected only the latest rejected connections, got %+v", r)
+		}
+	}
+}
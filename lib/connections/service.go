@@ -9,6 +9,7 @@ package connections
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"net"
 	"net/url"
@@ -95,6 +96,8 @@ type Service interface {
 	ListenerStatus() map[string]ListenerStatusEntry
 	ConnectionStatus() map[string]ConnectionStatusEntry
 	NATType() string
+	RejectedConnections() []RejectedConnection
+	TarpitOffenders() []Offender
 }
 
 type ListenerStatusEntry struct {
@@ -114,6 +117,7 @@ type service struct {
 	myID                 protocol.DeviceID
 	model                Model
 	tlsCfg               *tls.Config
+	listenTLSCfg         *tls.Config // also offering to forward connections
 	discoverer           discover.Finder
 	conns                chan internalConn
 	bepProtocolName      string
@@ -130,6 +134,14 @@ type service struct {
 
 	connectionStatusMut sync.RWMutex
 	connectionStatus    map[string]ConnectionStatusEntry // address -> latest error/status
+
+	connectedAtMut sync.Mutex
+	connectedAt    map[protocol.DeviceID]time.Time
+
+	rejectedMut sync.Mutex
+	rejected    []RejectedConnection // the latest, oldest first
+
+	offenders *offenders
 }
 
 func NewService(cfg config.Wrapper, myID protocol.DeviceID, mdl Model, tlsCfg *tls.Config, discoverer discover.Finder, bepProtocolName string, tlsDefaultCommonName string, evLogger events.Logger) Service {
@@ -144,6 +156,7 @@ func NewService(cfg config.Wrapper, myID protocol.DeviceID, mdl Model, tlsCfg *t
 		myID:                 myID,
 		model:                mdl,
 		tlsCfg:               tlsCfg,
+		listenTLSCfg:         listenTLSConfig(tlsCfg),
 		discoverer:           discoverer,
 		conns:                make(chan internalConn),
 		bepProtocolName:      bepProtocolName,
@@ -171,6 +184,13 @@ func NewService(cfg config.Wrapper, myID protocol.DeviceID, mdl Model, tlsCfg *t
 
 		connectionStatusMut: sync.NewRWMutex(),
 		connectionStatus:    make(map[string]ConnectionStatusEntry),
+
+		connectedAtMut: sync.NewMutex(),
+		connectedAt:    make(map[protocol.DeviceID]time.Time),
+
+		rejectedMut: sync.NewMutex(),
+
+		offenders: newOffenders(),
 	}
 	cfg.Subscribe(service)
 
@@ -209,12 +229,13 @@ func (s *service) handle(ctx context.Context) {
 		}
 
 		cs := c.ConnectionState()
+		forward := cs.NegotiatedProtocol == forwardProtocolName
 
 		// We should have negotiated the next level protocol "bep/1.0" as part
 		// of the TLS handshake. Unfortunately this can't be a hard error,
 		// because there are implementations out there that don't support
 		// protocol negotiation (iOS for one...).
-		if !cs.NegotiatedProtocolIsMutual || cs.NegotiatedProtocol != s.bepProtocolName {
+		if !forward && (!cs.NegotiatedProtocolIsMutual || cs.NegotiatedProtocol != s.bepProtocolName) {
 			l.Infof("Peer at %s did not negotiate bep/1.0", c)
 		}
 
@@ -224,7 +245,7 @@ func (s *service) handle(ctx context.Context) {
 		certs := cs.PeerCertificates
 		if cl := len(certs); cl != 1 {
 			l.Infof("Got peer certificate list of length %d != 1 from peer at %s; protocol error", cl, c)
-			c.Close()
+			s.rejectConnection(c, protocol.EmptyDeviceID, RejectCertificate, fmt.Errorf("got %d certificates", cl))
 			continue
 		}
 		remoteCert := certs[0]
@@ -235,7 +256,38 @@ func (s *service) handle(ctx context.Context) {
 		// clients between the same NAT gateway, and global discovery.
 		if remoteID == s.myID {
 			l.Infof("Connected to myself (%s) at %s - should not happen", remoteID, c)
-			c.Close()
+			s.rejectConnection(c, remoteID, RejectSelf, nil)
+			continue
+		}
+
+		// Those that keep connecting with a device ID we don't know are
+		// held in the tarpit for a while instead of getting an answer.
+		_, known := s.cfg.Device(remoteID)
+		tracked := !known && c.connType.isIncoming() && c.connType != connTypeRelayServer
+		if opts := s.cfg.Options(); tracked && s.offenders.startTarpit(c.RemoteAddr(), opts.TarpitUnknownDevicesAfter) {
+			s.offenders.record(c.RemoteAddr(), remoteID, time.Now())
+			go s.tarpit(ctx, c, remoteID, time.Duration(opts.TarpitDelayS)*time.Second)
+			continue
+		}
+
+		// Forwarding is only for devices we know, with the right name on
+		// their certificate.
+		if forward {
+			deviceCfg, ok := s.cfg.Device(remoteID)
+			if !ok {
+				l.Infof("Forwarding request from unknown device %s at %s rejected", remoteID, c)
+				if tracked {
+					s.offenders.record(c.RemoteAddr(), remoteID, time.Now())
+				}
+				s.rejectConnection(c, remoteID, RejectDevice, errors.New("unknown device"))
+				continue
+			}
+			if err := s.verifyCertName(deviceCfg, remoteCert); err != nil {
+				l.Warnf("Bad certificate from %s at %s: %v", remoteID, c, err)
+				s.rejectConnection(c, remoteID, RejectCertificate, err)
+				continue
+			}
+			go s.handleForward(ctx, c, remoteID)
 			continue
 		}
 
@@ -260,7 +312,7 @@ func (s *service) handle(ctx context.Context) {
 				// It's something else - connection reset or whatever
 				l.Infof("Failed to exchange Hello messages with %s at %s: %s", remoteID, c, err)
 			}
-			c.Close()
+			s.rejectConnection(c, remoteID, RejectHello, err)
 			continue
 		}
 		_ = c.SetDeadline(time.Time{})
@@ -269,7 +321,10 @@ func (s *service) handle(ctx context.Context) {
 		// have a connection with for whatever reason, for example unknown devices.
 		if err := s.model.OnHello(remoteID, c.RemoteAddr(), hello); err != nil {
 			l.Infof("Connection from %s at %s (%s) rejected: %v", remoteID, c.RemoteAddr(), c.Type(), err)
-			c.Close()
+			if tracked {
+				s.offenders.record(c.RemoteAddr(), remoteID, time.Now())
+			}
+			s.rejectConnection(c, remoteID, RejectDevice, err)
 			continue
 		}
 
@@ -288,33 +343,41 @@ func (s *service) handle(ctx context.Context) {
 			// in parallel we don't want to do that or we end up with no
 			// connections still established...
 			l.Infof("Connected to already connected device %s (existing: %s new: %s)", remoteID, ct, c)
-			c.Close()
+			s.rejectConnection(c, remoteID, RejectDuplicate, nil)
 			continue
 		}
 
 		deviceCfg, ok := s.cfg.Device(remoteID)
 		if !ok {
 			l.Infof("Device %s removed from config during connection attempt at %s", remoteID, c)
-			c.Close()
+			s.rejectConnection(c, remoteID, RejectRemoved, nil)
 			continue
 		}
 
-		// Verify the name on the certificate. By default we set it to
-		// "syncthing" when generating, but the user may have replaced
-		// the certificate and used another name.
-		certName := deviceCfg.CertName
-		if certName == "" {
-			certName = s.tlsDefaultCommonName
-		}
-		if err := remoteCert.VerifyHostname(certName); err != nil {
+		if err := s.verifyCertName(deviceCfg, remoteCert); err != nil {
 			// Incorrect certificate name is something the user most
 			// likely wants to know about, since it's an advanced
 			// config. Warn instead of Info.
 			l.Warnf("Bad certificate from %s at %s: %v", remoteID, c, err)
-			c.Close()
+			s.rejectConnection(c, remoteID, RejectCertificate, err)
 			continue
 		}
 
+		// Above the maximum number of connections only devices that
+		// outrank a connected one, or get their turn, are let in.
+		if !connected {
+			victim, ok := s.mayConnect(s.cfg.RawCopy(), deviceCfg)
+			if !ok {
+				l.Debugf("Connection from %s at %s rejected: at the maximum number of connections", remoteID, c)
+				s.rejectConnection(c, remoteID, RejectLimit, nil)
+				continue
+			}
+			if victimConn, ok := s.model.Connection(victim); ok && victim != protocol.EmptyDeviceID {
+				l.Infof("Disconnecting from %s to make room for %s", victim, remoteID)
+				victimConn.Close(errTooManyConnections)
+			}
+		}
+
 		// Wrap the connection in rate limiters. The limiter itself will
 		// keep up with config changes to the rate and whether or not LAN
 		// connections are limited.
@@ -326,11 +389,23 @@ func (s *service) handle(ctx context.Context) {
 
 		l.Infof("Established secure connection to %s at %s", remoteID, c)
 
+		s.setConnected(remoteID)
 		s.model.AddConnection(modelConn, hello)
 		continue
 	}
 }
 
+// verifyCertName verifies the name on the certificate of the device. By
+// default we set it to "syncthing" when generating, but the user may have
+// replaced the certificate and used another name.
+func (s *service) verifyCertName(deviceCfg config.DeviceConfiguration, cert *x509.Certificate) error {
+	certName := deviceCfg.CertName
+	if certName == "" {
+		certName = s.tlsDefaultCommonName
+	}
+	return cert.VerifyHostname(certName)
+}
+
 func (s *service) connect(ctx context.Context) {
 	nextDial := make(map[string]time.Time)
 
@@ -356,6 +431,8 @@ func (s *service) connect(ctx context.Context) {
 
 		l.Debugln("Reconnect loop")
 
+		s.enforceMaxConnections(cfg)
+
 		now := time.Now()
 		var seen []string
 
@@ -376,20 +453,17 @@ func (s *service) connect(ctx context.Context) {
 				continue
 			}
 
-			var addrs []string
-			for _, addr := range deviceCfg.Addresses {
-				if addr == "dynamic" {
-					if s.discoverer != nil {
-						if t, err := s.discoverer.Lookup(deviceID); err == nil {
-							addrs = append(addrs, t...)
-						}
-					}
-				} else {
-					addrs = append(addrs, addr)
+			if !connected {
+				if _, ok := s.mayConnect(cfg, deviceCfg); !ok {
+					l.Debugln("Not dialing", deviceID, "as we are at the maximum number of connections")
+					continue
 				}
 			}
 
-			addrs = util.UniqueTrimmedStrings(addrs)
+			addrs := s.deviceAddresses(deviceCfg)
+			for _, via := range deviceCfg.Via {
+				addrs = append(addrs, forwardAddress(via))
+			}
 
 			l.Debugln("Reconnect loop for", deviceID, addrs)
 
@@ -415,7 +489,7 @@ func (s *service) connect(ctx context.Context) {
 					continue
 				}
 
-				if len(deviceCfg.AllowedNetworks) > 0 {
+				if len(deviceCfg.AllowedNetworks) > 0 && uri.Scheme != forwardScheme {
 					if !IsAllowedNetwork(uri.Host, deviceCfg.AllowedNetworks) {
 						s.setConnectionStatus(addr, errors.New("network disallowed"))
 						l.Debugln("Network for", uri, "is disallowed")
@@ -423,7 +497,7 @@ func (s *service) connect(ctx context.Context) {
 					}
 				}
 
-				dialerFactory, err := getDialerFactory(cfg, uri)
+				dialerFactory, err := s.getDialerFactory(cfg, uri)
 				if err != nil {
 					s.setConnectionStatus(addr, err)
 				}
@@ -553,7 +627,7 @@ func (s *service) createListener(factory listenerFactory, uri *url.URL) bool {
 
 	l.Debugln("Starting listener", uri)
 
-	listener := factory.New(uri, s.cfg, s.tlsCfg, s.conns, s.natService)
+	listener := factory.New(uri, s.cfg, s.listenTLSCfg, s.conns, s.natService)
 	listener.OnAddressesChanged(s.logListenAddressesChangedEvent)
 	s.listeners[uri.String()] = listener
 	s.listenerTokens[uri.String()] = s.listenerSupervisor.Add(listener)
@@ -734,6 +808,44 @@ func (s *service) NATType() string {
 	return "unknown"
 }
 
+// deviceAddresses returns the addresses to dial the device at, looking up
+// the dynamic ones.
+func (s *service) deviceAddresses(deviceCfg config.DeviceConfiguration) []string {
+	var addrs []string
+	for _, addr := range deviceCfg.Addresses {
+		if addr == "dynamic" {
+			if s.discoverer != nil {
+				if t, err := s.discoverer.Lookup(deviceCfg.DeviceID); err == nil {
+					addrs = append(addrs, t...)
+				}
+			}
+		} else {
+			addrs = append(addrs, addr)
+		}
+	}
+	return util.UniqueTrimmedStrings(addrs)
+}
+
+// getDialerFactory is getDialerFactory that also knows about forwarding,
+// which needs the service to find the forwarding device.
+func (s *service) getDialerFactory(cfg config.Configuration, uri *url.URL) (dialerFactory, error) {
+	if uri.Scheme == forwardScheme {
+		return forwardDialerFactory{s}, nil
+	}
+	return getDialerFactory(cfg, uri)
+}
+
+// listenTLSConfig returns the TLS config for listeners, which let the
+// other side choose to have its connection forwarded instead of BEP.
+func listenTLSConfig(tlsCfg *tls.Config) *tls.Config {
+	if tlsCfg == nil {
+		return nil
+	}
+	listenCfg := tlsCfg.Clone()
+	listenCfg.NextProtos = append(append([]string{}, tlsCfg.NextProtos...), forwardProtocolName)
+	return listenCfg
+}
+
 func getDialerFactory(cfg config.Configuration, uri *url.URL) (dialerFactory, error) {
 	dialerFactory, ok := dialers[uri.Scheme]
 	if !ok {
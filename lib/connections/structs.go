@@ -71,6 +71,11 @@ const (
 	connTypeTCPServer
 	connTypeQUICClient
 	connTypeQUICServer
+	connTypeForwardClient
+	connTypeOnionClient
+	connTypeOnionServer
+	connTypeI2PClient
+	connTypeI2PServer
 )
 
 func (t connType) String() string {
@@ -87,6 +92,16 @@ func (t connType) String() string {
 		return "quic-client"
 	case connTypeQUICServer:
 		return "quic-server"
+	case connTypeForwardClient:
+		return "forward-client"
+	case connTypeOnionClient:
+		return "onion-client"
+	case connTypeOnionServer:
+		return "onion-server"
+	case connTypeI2PClient:
+		return "i2p-client"
+	case connTypeI2PServer:
+		return "i2p-server"
 	default:
 		return "unknown-type"
 	}
@@ -100,6 +115,12 @@ func (t connType) Transport() string {
 		return "tcp"
 	case connTypeQUICClient, connTypeQUICServer:
 		return "quic"
+	case connTypeForwardClient:
+		return "forward"
+	case connTypeOnionClient, connTypeOnionServer:
+		return "onion"
+	case connTypeI2PClient, connTypeI2PServer:
+		return "i2p"
 	default:
 		return "unknown"
 	}
@@ -0,0 +1,175 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"context"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+const (
+	// How many addresses connecting with unknown device IDs are tracked
+	maxOffenders = 1000
+	// How many connections are held in the tarpit at once, beyond that
+	// they are closed right away
+	maxTarpitted = 256
+	// How many of the device IDs used from an address are kept, the most
+	// recent ones
+	maxOffenderDevices = 16
+)
+
+// An Offender is an address that connected to us with device IDs we don't
+// know.
+type Offender struct {
+	Address   string    `json:"address"`
+	Devices   []string  `json:"devices"` // the most recently used last
+	Attempts  int       `json:"attempts"`
+	Tarpitted int       `json:"tarpitted"` // how many of the attempts were held in the tarpit
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+// offenders keeps track of the addresses that connect with unknown device
+// IDs, and of the connections held in the tarpit.
+type offenders struct {
+	mut       sync.Mutex
+	addrs     map[string]*Offender
+	tarpitted int
+}
+
+func newOffenders() *offenders {
+	return &offenders{
+		mut:   sync.NewMutex(),
+		addrs: make(map[string]*Offender),
+	}
+}
+
+// offenderHost returns the host part of the address, as an address is
+// tracked regardless of the port it connects from.
+func offenderHost(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+// record counts a connection attempt from the address with the unknown
+// device ID.
+func (o *offenders) record(addr net.Addr, device protocol.DeviceID, now time.Time) {
+	host := offenderHost(addr)
+
+	o.mut.Lock()
+	defer o.mut.Unlock()
+
+	off, ok := o.addrs[host]
+	if !ok {
+		if len(o.addrs) >= maxOffenders {
+			o.evictOldestLocked()
+		}
+		off = &Offender{Address: host, FirstSeen: now}
+		o.addrs[host] = off
+	}
+	off.Attempts++
+	off.LastSeen = now
+	dev := device.String()
+	for i, d := range off.Devices {
+		if d == dev {
+			off.Devices = append(off.Devices[:i], off.Devices[i+1:]...)
+			break
+		}
+	}
+	off.Devices = append(off.Devices, dev)
+	if len(off.Devices) > maxOffenderDevices {
+		off.Devices = append([]string(nil), off.Devices[len(off.Devices)-maxOffenderDevices:]...)
+	}
+}
+
+func (o *offenders) evictOldestLocked() {
+	var oldest *Offender
+	for _, off := range o.addrs {
+		if oldest == nil || off.LastSeen.Before(oldest.LastSeen) {
+			oldest = off
+		}
+	}
+	if oldest != nil {
+		delete(o.addrs, oldest.Address)
+	}
+}
+
+// startTarpit returns whether a connection from the address should be held
+// in the tarpit, given the number of attempts after which that happens. If
+// so, doneTarpit must be called when the connection is let go.
+func (o *offenders) startTarpit(addr net.Addr, after int) bool {
+	if after <= 0 {
+		return false
+	}
+
+	o.mut.Lock()
+	defer o.mut.Unlock()
+
+	off, ok := o.addrs[offenderHost(addr)]
+	if !ok || off.Attempts < after || o.tarpitted >= maxTarpitted {
+		return false
+	}
+	off.Tarpitted++
+	o.tarpitted++
+	return true
+}
+
+func (o *offenders) doneTarpit() {
+	o.mut.Lock()
+	o.tarpitted--
+	o.mut.Unlock()
+}
+
+// list returns the offenders, the one with the most attempts first.
+func (o *offenders) list() []Offender {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+
+	res := make([]Offender, 0, len(o.addrs))
+	for _, off := range o.addrs {
+		cp := *off
+		cp.Devices = append([]string(nil), off.Devices...)
+		res = append(res, cp)
+	}
+	sort.Slice(res, func(a, b int) bool {
+		if res[a].Attempts != res[b].Attempts {
+			return res[a].Attempts > res[b].Attempts
+		}
+		return res[a].Address < res[b].Address
+	})
+	return res
+}
+
+// tarpit holds on to the connection from an unknown device for the delay,
+// without ever answering its hello, and then rejects it. This keeps those
+// enumerating or repeatedly connecting busy for little cost on our side.
+func (s *service) tarpit(ctx context.Context, c internalConn, remoteID protocol.DeviceID, delay time.Duration) {
+	defer s.offenders.doneTarpit()
+	l.Debugf("Holding connection from unknown device %s at %s for %v", remoteID, c, delay)
+
+	t := time.NewTimer(delay)
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+		t.Stop()
+	}
+	s.rejectConnection(c, remoteID, RejectTarpit, nil)
+}
+
+// TarpitOffenders returns the addresses that connected with unknown device
+// IDs, the one with the most attempts first.
+func (s *service) TarpitOffenders() []Offender {
+	return s.offenders.list()
+}
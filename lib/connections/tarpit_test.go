@@ -0,0 +1,91 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package connections
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestOffenders(t *testing.T) {
+	o := newOffenders()
+	now := time.Now()
+	addr1 := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 1234}
+	addr1b := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 4321}
+	addr2 := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 2), Port: 1234}
+
+	o.record(addr1, protocol.DeviceID{1}, now)
+	o.record(addr1b, protocol.DeviceID{2}, now)
+	o.record(addr2, protocol.DeviceID{1}, now)
+
+	// Tarpitting is off, or not enough attempts yet
+	if o.startTarpit(addr1, 0) {
+		t.Error("tarpitted with tarpitting disabled")
+	}
+	if o.startTarpit(addr2, 2) {
+		t.Error("tarpitted after a single attempt")
+	}
+	// The port doesn't matter
+	if !o.startTarpit(addr1b, 2) {
+		t.Error("not tarpitted after two attempts")
+	}
+	o.doneTarpit()
+
+	list := o.list()
+	if len(list) != 2 {
+		t.Fatalf("expected two offenders, got %d", len(list))
+	}
+	if list[0].Address != "192.0.2.1" || list[0].Attempts != 2 || len(list[0].Devices) != 2 || list[0].Tarpitted != 1 {
+		t.Errorf("unexpected first offender %+v", list[0])
+	}
+	if list[1].Address != "192.0.2.2" || list[1].Attempts != 1 {
+		t.Errorf("unexpected second offender %+v", list[1])
+	}
+}
+
+func TestOffendersEviction(t *testing.T) {
+	o := newOffenders()
+	now := time.Now()
+	for i := 0; i < maxOffenders+1; i++ {
+		addr := &net.TCPAddr{IP: net.IPv4(10, 0, byte(i>>8), byte(i)), Port: 22000}
+		o.record(addr, protocol.DeviceID{1}, now.Add(time.Duration(i)*time.Second))
+	}
+	list := o.list()
+	if len(list) != maxOffenders {
+		t.Fatalf("expected %d offenders, got %d", maxOffenders, len(list))
+	}
+	for _, off := range list {
+		if off.Address == "10.0.0.0" {
+			t.Error("the oldest offender wasn't evicted")
+		}
+	}
+}
+
+func TestOffenderDevicesCapped(t *testing.T) {
+	o := newOffenders()
+	now := time.Now()
+	addr := &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 1234}
+	for i := 0; i < maxOffenderDevices+5; i++ {
+		o.record(addr, protocol.DeviceID{byte(i)}, now)
+	}
+	// A repeated device ID becomes the most recent one.
+	o.record(addr, protocol.DeviceID{10}, now)
+
+	devices := o.list()[0].Devices
+	if len(devices) != maxOffenderDevices {
+		t.Fatalf("expected %d devices, got %d", maxOffenderDevices, len(devices))
+	}
+	if devices[len(devices)-1] != (protocol.DeviceID{10}).String() {
+		t.Errorf("expected the repeated device last, got %v", devices[len(devices)-1])
+	}
+	if devices[0] != (protocol.DeviceID{5}).String() {
+		t.Errorf("expected the oldest devices to be dropped, got %v first", devices[0])
+	}
+}
@@ -28,14 +28,16 @@ func init() {
 
 type tcpDialer struct {
 	commonDialer
+	obfs obfuscator
 }
 
 func (d *tcpDialer) Dial(ctx context.Context, _ protocol.DeviceID, uri *url.URL) (internalConn, error) {
 	uri = fixupPort(uri, config.DefaultTCPPort)
+	network, _ := splitObfuscation(uri.Scheme)
 
 	timeoutCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
-	conn, err := dialer.DialContext(timeoutCtx, uri.Scheme, uri.Host)
+	conn, err := dialer.DialContext(timeoutCtx, network, uri.Host)
 	if err != nil {
 		return internalConn{}, err
 	}
@@ -50,6 +52,15 @@ func (d *tcpDialer) Dial(ctx context.Context, _ protocol.DeviceID, uri *url.URL)
 		l.Debugln("Dial (BEP/tcp): setting traffic class:", err)
 	}
 
+	if d.obfs != nil {
+		obfsConn, err := d.obfs.Client(conn, uri.Hostname())
+		if err != nil {
+			conn.Close()
+			return internalConn{}, err
+		}
+		conn = obfsConn
+	}
+
 	tc := tls.Client(conn, d.tlsCfg)
 	err = tlsTimedHandshake(tc)
 	if err != nil {
@@ -60,14 +71,19 @@ func (d *tcpDialer) Dial(ctx context.Context, _ protocol.DeviceID, uri *url.URL)
 	return internalConn{tc, connTypeTCPClient, tcpPriority}, nil
 }
 
-type tcpDialerFactory struct{}
+type tcpDialerFactory struct {
+	obfs string // the name of the obfuscator to use, if any
+}
 
-func (tcpDialerFactory) New(opts config.OptionsConfiguration, tlsCfg *tls.Config) genericDialer {
-	return &tcpDialer{commonDialer{
-		trafficClass:      opts.TrafficClass,
-		reconnectInterval: time.Duration(opts.ReconnectIntervalS) * time.Second,
-		tlsCfg:            tlsCfg,
-	}}
+func (f tcpDialerFactory) New(opts config.OptionsConfiguration, tlsCfg *tls.Config) genericDialer {
+	return &tcpDialer{
+		commonDialer: commonDialer{
+			trafficClass:      opts.TrafficClass,
+			reconnectInterval: time.Duration(opts.ReconnectIntervalS) * time.Second,
+			tlsCfg:            tlsCfg,
+		},
+		obfs: obfuscators[f.obfs],
+	}
 }
 
 func (tcpDialerFactory) Priority() int {
@@ -83,6 +99,9 @@ func (tcpDialerFactory) Valid(_ config.Configuration) error {
 	return nil
 }
 
-func (tcpDialerFactory) String() string {
+func (f tcpDialerFactory) String() string {
+	if f.obfs != "" {
+		return "TCP Dialer (" + f.obfs + " obfuscation)"
+	}
 	return "TCP Dialer"
 }
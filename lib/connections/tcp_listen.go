@@ -32,6 +32,7 @@ type tcpListener struct {
 	onAddressesChangedNotifier
 
 	uri     *url.URL
+	allowed []string // the networks to accept connections from, besides those of the options
 	cfg     config.Wrapper
 	tlsCfg  *tls.Config
 	conns   chan internalConn
@@ -39,18 +40,20 @@ type tcpListener struct {
 
 	natService *nat.Service
 	mapping    *nat.Mapping
+	obfs       obfuscator
 
 	mut sync.RWMutex
 }
 
 func (t *tcpListener) serve(ctx context.Context) error {
-	tcaddr, err := net.ResolveTCPAddr(t.uri.Scheme, t.uri.Host)
+	network, _ := splitObfuscation(t.uri.Scheme)
+	tcaddr, err := net.ResolveTCPAddr(network, t.uri.Host)
 	if err != nil {
 		l.Infoln("Listen (BEP/tcp):", err)
 		return err
 	}
 
-	listener, err := net.ListenTCP(t.uri.Scheme, tcaddr)
+	listener, err := net.ListenTCP(network, tcaddr)
 	if err != nil {
 		l.Infoln("Listen (BEP/tcp):", err)
 		return err
@@ -107,6 +110,12 @@ func (t *tcpListener) serve(ctx context.Context) error {
 		acceptFailures = 0
 		l.Debugln("Listen (BEP/tcp): connect from", conn.RemoteAddr())
 
+		if !incomingAllowed(t.cfg, conn.RemoteAddr(), t.allowed) {
+			l.Debugln("Listen (BEP/tcp): connection from", conn.RemoteAddr(), "not from an allowed network")
+			conn.Close()
+			continue
+		}
+
 		if err := dialer.SetTCPOptions(conn); err != nil {
 			l.Debugln("Listen (BEP/tcp): setting tcp options:", err)
 		}
@@ -117,6 +126,16 @@ func (t *tcpListener) serve(ctx context.Context) error {
 			}
 		}
 
+		if t.obfs != nil {
+			obfsConn, err := t.obfs.Server(conn)
+			if err != nil {
+				l.Infoln("Listen (BEP/tcp): obfuscation:", err)
+				conn.Close()
+				continue
+			}
+			conn = obfsConn
+		}
+
 		tc := tls.Server(conn, t.tlsCfg)
 		if err := tlsTimedHandshake(tc); err != nil {
 			l.Infoln("Listen (BEP/tcp): TLS handshake:", err)
@@ -173,15 +192,21 @@ func (t *tcpListener) NATType() string {
 	return "unknown"
 }
 
-type tcpListenerFactory struct{}
+type tcpListenerFactory struct {
+	obfs string // the name of the obfuscator to use, if any
+}
 
 func (f *tcpListenerFactory) New(uri *url.URL, cfg config.Wrapper, tlsCfg *tls.Config, conns chan internalConn, natService *nat.Service) genericListener {
+	uri, allowed := splitListenerNetworks(uri)
+	checkListenerNetworks(uri, allowed)
 	l := &tcpListener{
 		uri:        fixupPort(uri, config.DefaultTCPPort),
+		allowed:    allowed,
 		cfg:        cfg,
 		tlsCfg:     tlsCfg,
 		conns:      conns,
 		natService: natService,
+		obfs:       obfuscators[f.obfs],
 		factory:    f,
 	}
 	l.ServiceWithError = util.AsServiceWithError(l.serve, l.String())
@@ -8,6 +8,7 @@ package backend
 
 import (
 	"sync"
+	"time"
 )
 
 // The Reader interface specifies the read-only operations available on the
@@ -112,8 +113,21 @@ const (
 	TuningLarge
 )
 
-func Open(path string, tuning Tuning) (Backend, error) {
-	return OpenLevelDB(path, tuning)
+// Durability is how often writes are synced to disk.
+type Durability int
+
+const (
+	// N.b. these constants must match those in lib/config.DatabaseDurability!
+	DurabilityDefault Durability = iota
+	DurabilityBatch
+	DurabilityPeriodic
+	DurabilityRelaxed
+)
+
+// Open opens the database at path. The sync interval is how often writes
+// are synced with DurabilityPeriodic.
+func Open(path string, tuning Tuning, durability Durability, syncInterval time.Duration) (Backend, error) {
+	return OpenLevelDB(path, tuning, durability, syncInterval)
 }
 
 func OpenMemory() Backend {
@@ -8,6 +8,7 @@ package backend
 
 import (
 	"sync"
+	"time"
 
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/iterator"
@@ -24,9 +25,19 @@ const (
 // leveldbBackend implements Backend on top of a leveldb
 type leveldbBackend struct {
 	ldb     *leveldb.DB
+	syncer  *syncer
 	closeWG sync.WaitGroup
 }
 
+func newLeveldbBackend(ldb *leveldb.DB, durability Durability, syncInterval time.Duration) *leveldbBackend {
+	return &leveldbBackend{
+		ldb: ldb,
+		syncer: newSyncer(durability, syncInterval, func() error {
+			return ldb.Delete(syncKey, syncWrite)
+		}),
+	}
+}
+
 func (b *leveldbBackend) NewReadTransaction() (ReadTransaction, error) {
 	return b.newSnapshot()
 }
@@ -50,6 +61,7 @@ func (b *leveldbBackend) NewWriteTransaction() (WriteTransaction, error) {
 	return &leveldbTransaction{
 		leveldbSnapshot: snap,
 		ldb:             b.ldb,
+		syncer:          b.syncer,
 		batch:           new(leveldb.Batch),
 		rel:             newReleaser(&b.closeWG),
 	}, nil
@@ -57,6 +69,7 @@ func (b *leveldbBackend) NewWriteTransaction() (WriteTransaction, error) {
 
 func (b *leveldbBackend) Close() error {
 	b.closeWG.Wait()
+	b.syncer.close()
 	return wrapLeveldbErr(b.ldb.Close())
 }
 
@@ -74,11 +87,11 @@ func (b *leveldbBackend) NewRangeIterator(first, last []byte) (Iterator, error)
 }
 
 func (b *leveldbBackend) Put(key, val []byte) error {
-	return wrapLeveldbErr(b.ldb.Put(key, val, nil))
+	return wrapLeveldbErr(b.ldb.Put(key, val, b.syncer.writeOptions()))
 }
 
 func (b *leveldbBackend) Delete(key []byte) error {
-	return wrapLeveldbErr(b.ldb.Delete(key, nil))
+	return wrapLeveldbErr(b.ldb.Delete(key, b.syncer.writeOptions()))
 }
 
 func (b *leveldbBackend) Compact() error {
@@ -113,9 +126,10 @@ func (l leveldbSnapshot) Release() {
 // an actual leveldb transaction)
 type leveldbTransaction struct {
 	leveldbSnapshot
-	ldb   *leveldb.DB
-	batch *leveldb.Batch
-	rel   *releaser
+	ldb    *leveldb.DB
+	syncer *syncer
+	batch  *leveldb.Batch
+	rel    *releaser
 }
 
 func (t *leveldbTransaction) Delete(key []byte) error {
@@ -156,7 +170,7 @@ func (t *leveldbTransaction) flush() error {
 	if t.batch.Len() == 0 {
 		return nil
 	}
-	if err := t.ldb.Write(t.batch, nil); err != nil {
+	if err := t.ldb.Write(t.batch, t.syncer.writeOptions()); err != nil {
 		return wrapLeveldbErr(err)
 	}
 	t.batch.Reset()
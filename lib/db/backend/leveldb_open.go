@@ -11,6 +11,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/syndtr/goleveldb/leveldb"
 	"github.com/syndtr/goleveldb/leveldb/errors"
@@ -36,13 +37,16 @@ const (
 // Open attempts to open the database at the given location, and runs
 // recovery on it if opening fails. Worst case, if recovery is not possible,
 // the database is erased and created from scratch.
-func OpenLevelDB(location string, tuning Tuning) (Backend, error) {
+func OpenLevelDB(location string, tuning Tuning, durability Durability, syncInterval time.Duration) (Backend, error) {
 	opts := optsFor(location, tuning)
+	if durability == DurabilityRelaxed {
+		opts.NoSync = true
+	}
 	ldb, err := open(location, opts)
 	if err != nil {
 		return nil, err
 	}
-	return &leveldbBackend{ldb: ldb}, nil
+	return newLeveldbBackend(ldb, durability, syncInterval), nil
 }
 
 // OpenRO attempts to open the database at the given location, read only.
@@ -55,13 +59,13 @@ func OpenLevelDBRO(location string) (Backend, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &leveldbBackend{ldb: ldb}, nil
+	return newLeveldbBackend(ldb, DurabilityDefault, 0), nil
 }
 
 // OpenMemory returns a new Backend referencing an in-memory database.
 func OpenLevelDBMemory() Backend {
 	ldb, _ := leveldb.Open(storage.NewMemStorage(), nil)
-	return &leveldbBackend{ldb: ldb}
+	return newLeveldbBackend(ldb, DurabilityDefault, 0)
 }
 
 // optsFor returns the database options to use when opening a database with
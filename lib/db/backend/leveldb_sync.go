@@ -0,0 +1,115 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package backend
+
+import (
+	"sync"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb/opt"
+)
+
+var syncWrite = &opt.WriteOptions{Sync: true}
+
+// Nothing is stored under this key, it's deleted with a synced write to
+// sync the writes before it. The key types of the database stay well below.
+var syncKey = []byte{0xff, 's', 'y', 'n', 'c'}
+
+// The syncer decides which writes sync the journal of the database to disk.
+// Syncing a write also makes sure all writes before it are on disk, as the
+// journal is only appended to. What wasn't synced may be lost on a crash of
+// the OS or a power loss, but the database stays consistent as long as its
+// tables are synced, i.e. with all durabilities but the relaxed one.
+//
+// With the periodic durability writes aren't synced themselves. Instead
+// the journal is synced every interval when there were writes since the
+// last time, and once more when stopped.
+type syncer struct {
+	durability Durability
+	sync       func() error
+
+	mut     sync.Mutex
+	pending bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// defaultSyncInterval is the interval of the periodic durability when none
+// is given.
+const defaultSyncInterval = 10 * time.Second
+
+// newSyncer returns a syncer for the durability, which calls sync to sync
+// the journal periodically if needed.
+func newSyncer(durability Durability, interval time.Duration, sync func() error) *syncer {
+	s := &syncer{
+		durability: durability,
+		sync:       sync,
+	}
+	if durability == DurabilityPeriodic {
+		if interval <= 0 {
+			interval = defaultSyncInterval
+		}
+		s.stop = make(chan struct{})
+		s.done = make(chan struct{})
+		go s.serve(interval)
+	}
+	return s
+}
+
+// writeOptions returns the options for the next write.
+func (s *syncer) writeOptions() *opt.WriteOptions {
+	switch s.durability {
+	case DurabilityBatch:
+		return syncWrite
+	case DurabilityPeriodic:
+		s.mut.Lock()
+		s.pending = true
+		s.mut.Unlock()
+		return nil
+	default:
+		return nil
+	}
+}
+
+func (s *syncer) serve(interval time.Duration) {
+	defer close(s.done)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			s.syncPending()
+		case <-s.stop:
+			s.syncPending()
+			return
+		}
+	}
+}
+
+func (s *syncer) syncPending() {
+	s.mut.Lock()
+	pending := s.pending
+	s.pending = false
+	s.mut.Unlock()
+	if !pending {
+		return
+	}
+	if err := s.sync(); err != nil {
+		l.Debugln("Syncing database journal:", err)
+	}
+}
+
+// close stops the periodic syncing, after syncing what is pending. It
+// must be called before the database is closed.
+func (s *syncer) close() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	<-s.done
+}
@@ -0,0 +1,81 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package backend
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSyncer(t *testing.T) {
+	isSync := func(s *syncer) bool {
+		opts := s.writeOptions()
+		return opts != nil && opts.Sync
+	}
+	noSync := func() error {
+		t.Error("Unexpected periodic sync")
+		return nil
+	}
+
+	for _, d := range []Durability{DurabilityDefault, DurabilityRelaxed} {
+		if isSync(newSyncer(d, time.Second, noSync)) {
+			t.Errorf("Unexpected sync with durability %d", d)
+		}
+	}
+
+	batch := newSyncer(DurabilityBatch, time.Hour, noSync)
+	if !isSync(batch) || !isSync(batch) {
+		t.Error("Expected every write to sync with the batch durability")
+	}
+	batch.close()
+}
+
+func TestSyncerPeriodic(t *testing.T) {
+	syncs := make(chan struct{}, 10)
+	periodic := newSyncer(DurabilityPeriodic, 10*time.Millisecond, func() error {
+		syncs <- struct{}{}
+		return nil
+	})
+
+	if opts := periodic.writeOptions(); opts != nil && opts.Sync {
+		t.Error("Unexpected synced write with the periodic durability")
+	}
+	select {
+	case <-syncs:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the pending write to be synced")
+	}
+
+	// Nothing is synced without writes.
+	select {
+	case <-syncs:
+		t.Fatal("Unexpected sync without writes")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// What is pending is synced when closing.
+	periodic.writeOptions()
+	periodic.close()
+	select {
+	case <-syncs:
+	default:
+		t.Error("Expected a sync when closing")
+	}
+
+	// Without an interval it runs with the default one.
+	periodic = newSyncer(DurabilityPeriodic, 0, func() error {
+		syncs <- struct{}{}
+		return nil
+	})
+	periodic.writeOptions()
+	periodic.close()
+	select {
+	case <-syncs:
+	default:
+		t.Error("Expected a sync when closing without an interval")
+	}
+}
@@ -8,6 +8,7 @@ package db
 
 import (
 	"testing"
+	"time"
 
 	"github.com/syncthing/syncthing/lib/db/backend"
 	"github.com/syncthing/syncthing/lib/fs"
@@ -267,3 +268,101 @@ func TestDowngrade(t *testing.T) {
 		t.Fatalf("Error has %v as min Syncthing version, expected %v", err.minSyncthingVersion, dbMinSyncthingVersion)
 	}
 }
+
+func TestRepairCorruptFolder(t *testing.T) {
+	ldb := NewLowlevel(backend.OpenMemory())
+	defer ldb.Close()
+
+	repaired := make(chan FolderRepair, 1)
+	ldb.SetRepairHandler(func(r FolderRepair) {
+		repaired <- r
+	})
+
+	folder := "test"
+	s := NewFileSet(folder, fs.NewFilesystem(fs.FilesystemTypeBasic, "."), ldb)
+	s.Update(protocol.LocalDeviceID, []protocol.FileInfo{
+		{Name: "a", Version: protocol.Vector{}.Update(1), Sequence: 1},
+		{Name: "b", Version: protocol.Vector{}.Update(1), Sequence: 2},
+	})
+	s.SetIndexID(protocol.DeviceID{1}, 42)
+	escaped := NewEscapedNamesNamespace(ldb, folder)
+	if err := escaped.PutString("a_", "a:"); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ldb.keyer.GenerateDeviceFileKey(nil, []byte(folder), protocol.LocalDeviceID[:], []byte("b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ldb.Put(key, []byte("not a file info")); err != nil {
+		t.Fatal(err)
+	}
+
+	snap := s.Snapshot()
+	snap.WithHaveTruncated(protocol.LocalDeviceID, func(FileIntf) bool { return true })
+	snap.Release()
+
+	select {
+	case r := <-repaired:
+		if r.Folder != folder || !IsCorrupt(r.Error) || r.QuarantinedKeys == 0 {
+			t.Errorf("Unexpected repair %+v", r)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out waiting for the repair")
+	}
+
+	snap = s.Snapshot()
+	defer snap.Release()
+	snap.WithHaveTruncated(protocol.LocalDeviceID, func(f FileIntf) bool {
+		t.Error("Unexpected file left in the index:", f.FileName())
+		return true
+	})
+	if c := snap.LocalSize(); c.Files != 0 {
+		t.Error("Expected no local files counted, got", c.Files)
+	}
+	if seq := s.Sequence(protocol.LocalDeviceID); seq != 0 {
+		t.Error("Expected the sequence to start over, got", seq)
+	}
+	if id := s.IndexID(protocol.DeviceID{1}); id != 0 {
+		t.Error("Expected the remote index ID to be gone, got", id)
+	}
+
+	qk, err := ldb.keyer.GenerateQuarantineKey(nil, []byte(folder), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := countKeys(t, ldb, qk.WithoutOriginal()); n == 0 {
+		t.Error("Expected the index to have been quarantined")
+	}
+	if _, ok, _ := escaped.String("a_"); ok {
+		t.Error("Expected the escaped names to have been quarantined")
+	}
+
+	// Quarantined entries are kept for a while, then pruned.
+	if err := ldb.pruneQuarantine(time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if n := countKeys(t, ldb, qk.WithoutOriginal()); n == 0 {
+		t.Error("Expected recently quarantined entries to be kept")
+	}
+	if err := ldb.pruneQuarantine(0); err != nil {
+		t.Fatal(err)
+	}
+	if n := countKeys(t, ldb, qk.WithoutOriginal()); n != 0 {
+		t.Errorf("Expected quarantined entries to be pruned, %d left", n)
+	}
+}
+
+func countKeys(t *testing.T, ldb *Lowlevel, prefix []byte) int {
+	t.Helper()
+	it, err := ldb.NewPrefixIterator(prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Release()
+	n := 0
+	for it.Next() {
+		n++
+	}
+	return n
+}
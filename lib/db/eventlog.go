@@ -0,0 +1,218 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package db
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/thejerf/suture"
+
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/sync"
+	"github.com/syncthing/syncthing/lib/util"
+)
+
+// How often stored events are checked for having passed their maximum age.
+const eventLogExpireInterval = time.Hour
+
+// EventLog is an events.BufferedSubscription that keeps the most recent
+// events in the database. Event IDs continue where they left off after a
+// restart, so clients can resume from an ID they saw before. It is a
+// service storing the events while it runs, and unsubscribes when stopped.
+type EventLog struct {
+	suture.Service
+	db     *Lowlevel
+	sub    events.Subscription
+	size   int64
+	maxAge time.Duration
+	first  int64 // ID of the oldest stored event
+	cur    int64 // ID of the newest stored event
+	mut    sync.Mutex
+	cond   *sync.TimeoutCond
+}
+
+// NewEventLog returns an EventLog that persists the events received on the
+// given subscription once it is served, keeping at most size of them and
+// none older than maxAge, unless that is zero.
+func NewEventLog(db *Lowlevel, s events.Subscription, size int, maxAge time.Duration) (*EventLog, error) {
+	e := &EventLog{
+		db:     db,
+		sub:    s,
+		size:   int64(size),
+		maxAge: maxAge,
+		mut:    sync.NewMutex(),
+	}
+	e.cond = sync.NewTimeoutCond(e.mut)
+	e.Service = util.AsService(e.serve, "EventLog")
+
+	it, err := db.NewPrefixIterator([]byte{KeyTypeEventLog})
+	if err != nil {
+		return nil, err
+	}
+	for it.Next() {
+		id := eventLogID(it.Key())
+		if e.first == 0 {
+			e.first = id
+		}
+		e.cur = id
+	}
+	it.Release()
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (e *EventLog) serve(ctx context.Context) {
+	var expire <-chan time.Time
+	if e.maxAge > 0 {
+		e.mut.Lock()
+		e.expire(time.Now().Add(-e.maxAge))
+		e.mut.Unlock()
+		t := time.NewTicker(eventLogExpireInterval)
+		defer t.Stop()
+		expire = t.C
+	}
+
+	for {
+		select {
+		case <-expire:
+			e.mut.Lock()
+			e.expire(time.Now().Add(-e.maxAge))
+			e.mut.Unlock()
+		case ev, ok := <-e.sub.C():
+			if !ok {
+				return
+			}
+			e.mut.Lock()
+			e.append(ev)
+			e.cond.Broadcast()
+			e.mut.Unlock()
+		case <-ctx.Done():
+			e.sub.Unsubscribe()
+			return
+		}
+	}
+}
+
+// append stores the event under the next ID and drops the oldest event if
+// the log is full. Must be called with e.mut held.
+func (e *EventLog) append(ev events.Event) {
+	ev.SubscriptionID = int(e.cur + 1)
+	bs, err := json.Marshal(ev)
+	if err != nil {
+		l.Debugln("Marshalling event:", err)
+		return
+	}
+	if err := e.db.Put(eventLogKey(e.cur+1), bs); err != nil {
+		l.Warnln("Persisting event:", err)
+		return
+	}
+	e.cur++
+	if e.first == 0 {
+		e.first = e.cur
+	}
+
+	for e.cur-e.first >= e.size {
+		if err := e.db.Delete(eventLogKey(e.first)); err != nil {
+			l.Debugln("Removing old event:", err)
+			break
+		}
+		e.first++
+	}
+}
+
+// expire drops the stored events that happened before the given time. Must
+// be called with e.mut held.
+func (e *EventLog) expire(before time.Time) {
+	for e.first != 0 && e.first <= e.cur {
+		bs, err := e.db.Get(eventLogKey(e.first))
+		if err != nil {
+			l.Debugln("Reading old event:", err)
+			return
+		}
+		var ev struct{ Time time.Time }
+		if err := json.Unmarshal(bs, &ev); err == nil && !ev.Time.Before(before) {
+			return
+		}
+		if err := e.db.Delete(eventLogKey(e.first)); err != nil {
+			l.Debugln("Removing old event:", err)
+			return
+		}
+		e.first++
+	}
+}
+
+// Since returns the stored events with an ID larger than the given one,
+// waiting up to timeout for new events if there are none.
+func (e *EventLog) Since(id int, into []events.Event, timeout time.Duration) []events.Event {
+	e.mut.Lock()
+	defer e.mut.Unlock()
+
+	// Check once first before generating the TimeoutCondWaiter
+	if int64(id) >= e.cur {
+		waiter := e.cond.SetupWait(timeout)
+		defer waiter.Stop()
+
+		for int64(id) >= e.cur {
+			if eventsAvailable := waiter.Wait(); !eventsAvailable {
+				// Timed out
+				return into
+			}
+		}
+	}
+
+	it, err := e.db.NewRangeIterator(eventLogKey(int64(id)+1), eventLogKey(math.MaxInt64))
+	if err != nil {
+		l.Debugln("Reading event log:", err)
+		return into
+	}
+	defer it.Release()
+	for it.Next() {
+		var ev events.Event
+		if err := json.Unmarshal(it.Value(), &ev); err != nil {
+			l.Debugln("Unmarshalling event:", err)
+			continue
+		}
+		into = append(into, ev)
+	}
+	if err := it.Error(); err != nil {
+		l.Debugln("Reading event log:", err)
+	}
+	return into
+}
+
+// DropEventLog removes all events stored by an EventLog.
+func DropEventLog(db *Lowlevel) error {
+	it, err := db.NewPrefixIterator([]byte{KeyTypeEventLog})
+	if err != nil {
+		return err
+	}
+	defer it.Release()
+	for it.Next() {
+		if err := db.Delete(it.Key()); err != nil {
+			return err
+		}
+	}
+	return it.Error()
+}
+
+func eventLogKey(id int64) []byte {
+	key := make([]byte, 9)
+	key[0] = KeyTypeEventLog
+	binary.BigEndian.PutUint64(key[1:], uint64(id))
+	return key
+}
+
+func eventLogID(key []byte) int64 {
+	return int64(binary.BigEndian.Uint64(key[1:]))
+}
@@ -0,0 +1,103 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/db/backend"
+	"github.com/syncthing/syncthing/lib/events"
+)
+
+func TestEventLogSurvivesRestart(t *testing.T) {
+	ldb := NewLowlevel(backend.OpenMemory())
+	evLogger := events.NewLogger()
+	go evLogger.Serve()
+	defer evLogger.Stop()
+
+	sub := evLogger.Subscribe(events.AllEvents)
+	elog, err := NewEventLog(ldb, sub, 3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go elog.Serve()
+	for i := 0; i < 5; i++ {
+		evLogger.Log(events.ItemFinished, map[string]interface{}{"item": i})
+	}
+	var evs []events.Event
+	for len(evs) == 0 || evs[len(evs)-1].SubscriptionID < 5 {
+		evs = elog.Since(0, evs[:0], time.Second)
+		if len(evs) == 0 {
+			t.Fatal("timed out waiting for events")
+		}
+	}
+	if len(evs) != 3 || evs[0].SubscriptionID != 3 {
+		t.Fatalf("expected the three last events, got %v", evs)
+	}
+	if evs[0].Type != events.ItemFinished || evs[0].Data.(map[string]interface{})["item"] != 2.0 {
+		t.Errorf("unexpected event %v", evs[0])
+	}
+	elog.Stop()
+
+	// A new log on the same database continues the sequence.
+	elog, err = NewEventLog(ldb, evLogger.Subscribe(events.AllEvents), 3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go elog.Serve()
+	defer elog.Stop()
+	if evs := elog.Since(4, nil, 0); len(evs) != 1 || evs[0].SubscriptionID != 5 {
+		t.Fatalf("expected the last persisted event, got %v", evs)
+	}
+	evLogger.Log(events.ItemFinished, nil)
+	if evs := elog.Since(5, nil, time.Second); len(evs) != 1 || evs[0].SubscriptionID != 6 {
+		t.Fatalf("expected a new event with ID 6, got %v", evs)
+	}
+}
+
+func TestEventLogExpire(t *testing.T) {
+	ldb := NewLowlevel(backend.OpenMemory())
+	evLogger := events.NewLogger()
+	go evLogger.Serve()
+	defer evLogger.Stop()
+
+	elog, err := NewEventLog(ldb, evLogger.Subscribe(events.AllEvents), 10, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go elog.Serve()
+	defer elog.Stop()
+	for i := 0; i < 3; i++ {
+		evLogger.Log(events.ItemFinished, map[string]interface{}{"item": i})
+	}
+	var evs []events.Event
+	for len(evs) == 0 || evs[len(evs)-1].SubscriptionID < 3 {
+		evs = elog.Since(0, evs[:0], time.Second)
+		if len(evs) == 0 {
+			t.Fatal("timed out waiting for events")
+		}
+	}
+
+	elog.mut.Lock()
+	elog.expire(evs[1].Time)
+	elog.mut.Unlock()
+	if evs := elog.Since(0, nil, 0); len(evs) != 2 || evs[0].SubscriptionID != 2 {
+		t.Fatalf("expected the two last events, got %v", evs)
+	}
+
+	if err := DropEventLog(ldb); err != nil {
+		t.Fatal(err)
+	}
+	elog, err = NewEventLog(ldb, evLogger.Subscribe(events.AllEvents), 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elog.cur != 0 {
+		t.Errorf("expected an empty log after dropping it, got events up to %d", elog.cur)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package db
+
+import (
+	"encoding/binary"
+)
+
+// An IgnoreCache keeps the cached ignore match results of a folder across
+// restarts. Only the results for the latest patterns are kept, as those
+// for others are of no use.
+type IgnoreCache struct {
+	db  *Lowlevel
+	key []byte
+}
+
+// NewIgnoreCache returns the ignore cache of the given folder.
+func NewIgnoreCache(db *Lowlevel, folder string) *IgnoreCache {
+	return &IgnoreCache{
+		db:  db,
+		key: ignoreCacheKey(folder),
+	}
+}
+
+func ignoreCacheKey(folder string) []byte {
+	return append([]byte{KeyTypeIgnoreCache}, folder...)
+}
+
+// LoadCache returns the results stored for the patterns with the given
+// hash, or nil if there are none.
+func (c *IgnoreCache) LoadCache(hash string) ([]byte, error) {
+	bs, err := c.db.Get(c.key)
+	if err != nil {
+		return nil, filterNotFound(err)
+	}
+	size, n := binary.Uvarint(bs)
+	if n <= 0 || uint64(len(bs)-n) < size {
+		l.Debugln("Invalid ignore cache entry")
+		return nil, nil
+	}
+	if string(bs[n:n+int(size)]) != hash {
+		return nil, nil
+	}
+	return bs[n+int(size):], nil
+}
+
+// SaveCache stores the results for the patterns with the given hash,
+// replacing those stored before.
+func (c *IgnoreCache) SaveCache(hash string, results []byte) error {
+	bs := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+len(hash)+len(results))
+	n := binary.PutUvarint(bs, uint64(len(hash)))
+	bs = append(bs[:n], hash...)
+	bs = append(bs, results...)
+	return c.db.Put(c.key, bs)
+}
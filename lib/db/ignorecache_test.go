@@ -0,0 +1,43 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package db
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/db/backend"
+)
+
+func TestIgnoreCache(t *testing.T) {
+	ldb := NewLowlevel(backend.OpenMemory())
+	defer ldb.Close()
+
+	c := NewIgnoreCache(ldb, "default")
+	if bs, err := c.LoadCache("hash"); err != nil || bs != nil {
+		t.Fatalf("Got %v, %v from empty cache", bs, err)
+	}
+
+	results := []byte("results")
+	if err := c.SaveCache("hash", results); err != nil {
+		t.Fatal(err)
+	}
+	if bs, err := c.LoadCache("hash"); err != nil || !bytes.Equal(bs, results) {
+		t.Errorf("Got %q, %v, expected %q", bs, err, results)
+	}
+	if bs, err := c.LoadCache("other"); err != nil || bs != nil {
+		t.Errorf("Got %q, %v for other patterns", bs, err)
+	}
+	if bs, err := NewIgnoreCache(ldb, "other").LoadCache("hash"); err != nil || bs != nil {
+		t.Errorf("Got %q, %v for other folder", bs, err)
+	}
+
+	DropFolder(ldb, "default")
+	if bs, err := c.LoadCache("hash"); err != nil || bs != nil {
+		t.Errorf("Got %q, %v after dropping the folder", bs, err)
+	}
+}
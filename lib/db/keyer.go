@@ -62,6 +62,21 @@ const (
 
 	// KeyTypeBlockList <block list hash> = BlockList
 	KeyTypeBlockList = 13
+
+	// KeyTypeEventLog <int64 event ID> = JSON encoded events.Event
+	KeyTypeEventLog = 14
+
+	// KeyTypeEscapedName <folder ID as string> "/" <escaped name> = original name
+	KeyTypeEscapedName = 15
+
+	// KeyTypeQuarantine <int32 folder ID> <original key> = original value
+	KeyTypeQuarantine = 16
+
+	// KeyTypeVersionIndex <folder ID as string> "/" <file name> = JSON encoded []ArchivedVersion
+	KeyTypeVersionIndex = 17
+
+	// KeyTypeIgnoreCache <folder ID as string> = patterns hash + encoded ignore match results
+	KeyTypeIgnoreCache = 18
 )
 
 type keyer interface {
@@ -99,6 +114,9 @@ type keyer interface {
 
 	// Block lists
 	GenerateBlockListKey(key []byte, hash []byte) blockListKey
+
+	// Quarantined index entries
+	GenerateQuarantineKey(key, folder, orig []byte) (quarantineKey, error)
 }
 
 // defaultKeyer implements our key scheme. It needs folder and device
@@ -300,6 +318,24 @@ func (k blockListKey) BlocksHash() []byte {
 	return k[keyPrefixLen:]
 }
 
+type quarantineKey []byte
+
+func (k quarantineKey) WithoutOriginal() []byte {
+	return k[:keyPrefixLen+keyFolderLen]
+}
+
+func (k defaultKeyer) GenerateQuarantineKey(key, folder, orig []byte) (quarantineKey, error) {
+	folderID, err := k.folderIdx.ID(folder)
+	if err != nil {
+		return nil, err
+	}
+	key = resize(key, keyPrefixLen+keyFolderLen+len(orig))
+	key[0] = KeyTypeQuarantine
+	binary.BigEndian.PutUint32(key[keyPrefixLen:], folderID)
+	copy(key[keyPrefixLen+keyFolderLen:], orig)
+	return key, nil
+}
+
 // resize returns a byte slice of the specified size, reusing bs if possible
 func resize(bs []byte, size int) []byte {
 	if cap(bs) < size {
@@ -51,6 +51,8 @@ type Lowlevel struct {
 	gcMut      sync.RWMutex
 	gcKeyCount int
 	gcStop     chan struct{}
+	repairMut  sync.Mutex
+	repairFn   func(FolderRepair)
 }
 
 func NewLowlevel(backend backend.Backend) *Lowlevel {
@@ -60,6 +62,7 @@ func NewLowlevel(backend backend.Backend) *Lowlevel {
 		deviceIdx: newSmallIndex(backend, []byte{KeyTypeDeviceIdx}),
 		gcMut:     sync.NewRWMutex(),
 		gcStop:    make(chan struct{}),
+		repairMut: sync.NewMutex(),
 	}
 	db.keyer = newDefaultKeyer(db.folderIdx, db.deviceIdx)
 	go db.gcRunner()
@@ -414,6 +417,49 @@ func (db *Lowlevel) checkGlobals(folder []byte, meta *metadataTracker) error {
 	return t.commit()
 }
 
+// recountNeed counts what the local device needs by the need index,
+// recording with each need entry what was counted for it.
+func (db *Lowlevel) recountNeed(folder []byte, meta *metadataTracker) error {
+	t, err := db.newReadWriteTransaction()
+	if err != nil {
+		return err
+	}
+	defer t.close()
+
+	key, err := db.keyer.GenerateNeedFileKey(nil, folder, nil)
+	if err != nil {
+		return err
+	}
+	dbi, err := t.NewPrefixIterator(key.WithoutName())
+	if err != nil {
+		return err
+	}
+	defer dbi.Release()
+
+	meta.addNeeded(Counts{})
+	var gk []byte
+	for dbi.Next() {
+		var f FileIntf
+		var ok bool
+		gk, f, ok, err = t.getGlobal(gk, folder, db.keyer.NameFromGlobalVersionKey(dbi.Key()), true)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		counted := neededCounts(f)
+		if err := t.Put(dbi.Key(), mustMarshal(&counted)); err != nil {
+			return err
+		}
+		meta.addNeeded(counted)
+	}
+	if err := dbi.Error(); err != nil {
+		return err
+	}
+	return t.commit()
+}
+
 func (db *Lowlevel) getIndexID(device, folder []byte) (protocol.IndexID, error) {
 	key, err := db.keyer.GenerateIndexIDKey(nil, device, folder)
 	if err != nil {
@@ -459,6 +505,18 @@ func (db *Lowlevel) dropFolderMeta(folder []byte) error {
 	return db.dropPrefix(key)
 }
 
+func (db *Lowlevel) dropEscapedNames(folder []byte) error {
+	return db.dropPrefix(NewEscapedNamesNamespace(db, string(folder)).prefix)
+}
+
+func (db *Lowlevel) dropVersionIndex(folder []byte) error {
+	return db.dropPrefix(versionIndexPrefix(string(folder)))
+}
+
+func (db *Lowlevel) dropIgnoreCache(folder []byte) error {
+	return db.Delete(ignoreCacheKey(string(folder)))
+}
+
 func (db *Lowlevel) dropPrefix(prefix []byte) error {
 	t, err := db.newReadWriteTransaction()
 	if err != nil {
@@ -483,6 +541,9 @@ func (db *Lowlevel) gcRunner() {
 			if err := db.gcBlocks(); err != nil {
 				l.Warnln("Database block GC failed:", err)
 			}
+			if err := db.pruneQuarantine(quarantineMaxAge); err != nil {
+				l.Warnln("Pruning quarantined database entries:", err)
+			}
 			db.recordTime(blockGCTimeKey)
 			t.Reset(db.timeUntil(blockGCTimeKey, blockGCInterval))
 		}
@@ -232,12 +232,81 @@ func (m *metadataTracker) removeFileLocked(dev protocol.DeviceID, flags uint32,
 	}
 }
 
+// needFlag marks the counts of what the local device needs. It's not a
+// real local flag, and those counts follow the need index rather than the
+// files of the device, each need entry holding what was counted for it.
+const needFlag = 1 << 31
+
+// neededCounts returns what counts for the global file when needed.
+func neededCounts(f FileIntf) Counts {
+	switch {
+	case f.IsDeleted():
+		return Counts{Deleted: 1}
+	case f.IsDirectory():
+		return Counts{Directories: 1}
+	case f.IsSymlink():
+		return Counts{Symlinks: 1}
+	default:
+		return Counts{Files: 1, Bytes: f.FileSize()}
+	}
+}
+
+func sameCounts(a, b Counts) bool {
+	return a.Files == b.Files && a.Directories == b.Directories && a.Symlinks == b.Symlinks && a.Deleted == b.Deleted && a.Bytes == b.Bytes
+}
+
+// addNeeded adds to the counts of what the local device needs
+func (m *metadataTracker) addNeeded(c Counts) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	m.dirty = true
+
+	cp := m.countsPtr(protocol.LocalDeviceID, needFlag)
+	cp.Files += c.Files
+	cp.Directories += c.Directories
+	cp.Symlinks += c.Symlinks
+	cp.Deleted += c.Deleted
+	cp.Bytes += c.Bytes
+}
+
+// removeNeeded removes from the counts of what the local device needs
+func (m *metadataTracker) removeNeeded(c Counts) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	m.dirty = true
+
+	cp := m.countsPtr(protocol.LocalDeviceID, needFlag)
+	cp.Files -= c.Files
+	cp.Directories -= c.Directories
+	cp.Symlinks -= c.Symlinks
+	cp.Deleted -= c.Deleted
+	cp.Bytes -= c.Bytes
+
+	// As for the other counts, an impossible situation makes the metadata
+	// be recalculated on the next start.
+	if cp.Files < 0 || cp.Directories < 0 || cp.Symlinks < 0 || cp.Deleted < 0 || cp.Bytes < 0 {
+		*cp = Counts{DeviceID: cp.DeviceID, LocalFlags: cp.LocalFlags}
+		m.counts.Created = 0
+	}
+}
+
+// tracksNeed returns whether there are counts of what the local device
+// needs, which metadata stored by older versions lacks.
+func (m *metadataTracker) tracksNeed() bool {
+	m.mut.RLock()
+	defer m.mut.RUnlock()
+	_, ok := m.indexes[metaKey{protocol.LocalDeviceID, needFlag}]
+	return ok
+}
+
 // resetAll resets all metadata for the given device
 func (m *metadataTracker) resetAll(dev protocol.DeviceID) {
 	m.mut.Lock()
 	m.dirty = true
 	for i, c := range m.counts.Counts {
-		if bytes.Equal(c.DeviceID, dev[:]) {
+		if bytes.Equal(c.DeviceID, dev[:]) && c.LocalFlags != needFlag {
 			m.counts.Counts[i] = Counts{
 				DeviceID:   c.DeviceID,
 				LocalFlags: c.LocalFlags,
@@ -254,7 +323,7 @@ func (m *metadataTracker) resetCounts(dev protocol.DeviceID) {
 	m.dirty = true
 
 	for i, c := range m.counts.Counts {
-		if bytes.Equal(c.DeviceID, dev[:]) {
+		if bytes.Equal(c.DeviceID, dev[:]) && c.LocalFlags != needFlag {
 			m.counts.Counts[i] = Counts{
 				DeviceID:   c.DeviceID,
 				Sequence:   c.Sequence,
@@ -266,6 +335,16 @@ func (m *metadataTracker) resetCounts(dev protocol.DeviceID) {
 	m.mut.Unlock()
 }
 
+// reset forgets all counts and sequence numbers, for when the index they
+// describe is gone
+func (m *metadataTracker) reset() {
+	m.mut.Lock()
+	m.counts = CountsSet{Created: time.Now().UnixNano()}
+	m.indexes = make(map[metaKey]int)
+	m.dirty = true
+	m.mut.Unlock()
+}
+
 func (m *countsMap) Counts(dev protocol.DeviceID, flag uint32) Counts {
 	if bits.OnesCount32(flag) > 1 {
 		panic("incorrect usage: set at most one bit in flag")
@@ -148,6 +148,12 @@ func NewFolderStatisticsNamespace(db *Lowlevel, folder string) *NamespacedKV {
 	return NewNamespacedKV(db, string(KeyTypeFolderStatistic)+folder)
 }
 
+// NewEscapedNamesNamespace creates a KV namespace for the file names that
+// are escaped on disk in the given folder.
+func NewEscapedNamesNamespace(db *Lowlevel, folder string) *NamespacedKV {
+	return NewNamespacedKV(db, string(rune(KeyTypeEscapedName))+folder+"/")
+}
+
 // NewMiscDateNamespace creates a KV namespace for miscellaneous metadata.
 func NewMiscDataNamespace(db *Lowlevel) *NamespacedKV {
 	return NewNamespacedKV(db, string(KeyTypeMiscData))
@@ -0,0 +1,244 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package db
+
+import (
+	"bytes"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/db/backend"
+)
+
+// corruptError is returned when an entry in the database can't be decoded,
+// or refers to one that doesn't exist.
+type corruptError struct {
+	what string
+	err  error
+}
+
+func (e *corruptError) Error() string {
+	return fmt.Sprintf("corrupt %s in database: %v", e.what, e.err)
+}
+
+// IsCorrupt returns whether the error is caused by corrupt database
+// contents, as opposed to failing to access the database.
+func IsCorrupt(err error) bool {
+	_, ok := err.(*corruptError)
+	return ok
+}
+
+// A FolderRepair describes how the index of a folder was repaired after
+// finding it corrupt.
+type FolderRepair struct {
+	Folder string
+	// The corruption that was found
+	Error error
+	// How many entries of the index were moved out of the way
+	QuarantinedKeys int
+}
+
+// SetRepairHandler sets a function to be called after the index of a
+// folder was found corrupt and quarantined. It's then up to the handler to
+// rebuild it, by rescanning the folder and getting the indexes of other
+// devices again. Without a handler, finding corruption is fatal.
+func (db *Lowlevel) SetRepairHandler(fn func(FolderRepair)) {
+	db.repairMut.Lock()
+	db.repairFn = fn
+	db.repairMut.Unlock()
+}
+
+func (db *Lowlevel) repairHandler() func(FolderRepair) {
+	db.repairMut.Lock()
+	defer db.repairMut.Unlock()
+	return db.repairFn
+}
+
+// Quarantined entries are removed after this long.
+const quarantineMaxAge = 30 * 24 * time.Hour
+
+// quarantineFolder moves everything indexed for the folder out of the way,
+// leaving it as if the folder was new. The entries are kept under
+// KeyTypeQuarantine for later inspection, replacing any earlier ones for
+// the folder, until they are pruned by pruneQuarantine. It returns how many
+// entries were moved.
+func (db *Lowlevel) quarantineFolder(folder []byte) (int, error) {
+	db.gcMut.RLock()
+	defer db.gcMut.RUnlock()
+
+	t, err := db.newReadWriteTransaction()
+	if err != nil {
+		return 0, err
+	}
+	defer t.close()
+
+	qk, err := db.keyer.GenerateQuarantineKey(nil, folder, nil)
+	if err != nil {
+		return 0, err
+	}
+	if err := t.deleteKeyPrefix(qk.WithoutOriginal()); err != nil {
+		return 0, err
+	}
+
+	var prefixes [][]byte
+	k0, err := db.keyer.GenerateDeviceFileKey(nil, folder, nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	prefixes = append(prefixes, k0.WithoutNameAndDevice())
+	k1, err := db.keyer.GenerateSequenceKey(nil, folder, 0)
+	if err != nil {
+		return 0, err
+	}
+	prefixes = append(prefixes, k1.WithoutSequence())
+	k2, err := db.keyer.GenerateGlobalVersionKey(nil, folder, nil)
+	if err != nil {
+		return 0, err
+	}
+	prefixes = append(prefixes, k2.WithoutName())
+	k3, err := db.keyer.GenerateNeedFileKey(nil, folder, nil)
+	if err != nil {
+		return 0, err
+	}
+	prefixes = append(prefixes, k3.WithoutName())
+	k4, err := db.keyer.GenerateBlockMapKey(nil, folder, nil, nil)
+	if err != nil {
+		return 0, err
+	}
+	prefixes = append(prefixes, k4.WithoutHashAndName())
+	k5, err := db.keyer.GenerateFolderMetaKey(nil, folder)
+	if err != nil {
+		return 0, err
+	}
+	prefixes = append(prefixes, k5)
+	prefixes = append(prefixes, NewEscapedNamesNamespace(db, string(folder)).prefix)
+	// The index IDs are keyed by device first, so they are picked out of
+	// all of them by their folder part.
+	prefixes = append(prefixes, []byte{KeyTypeIndexID})
+	folderID := k5[keyPrefixLen:]
+
+	moved := 0
+	for _, prefix := range prefixes {
+		dbi, err := t.NewPrefixIterator(prefix)
+		if err != nil {
+			return 0, err
+		}
+		for dbi.Next() {
+			key := dbi.Key()
+			if key[0] == KeyTypeIndexID && !bytes.Equal(key[keyPrefixLen+keyDeviceLen:], folderID) {
+				continue
+			}
+			qk, err = db.keyer.GenerateQuarantineKey(qk, folder, key)
+			if err != nil {
+				dbi.Release()
+				return 0, err
+			}
+			if err := t.Put(qk, dbi.Value()); err != nil {
+				dbi.Release()
+				return 0, err
+			}
+			if err := t.Delete(key); err != nil {
+				dbi.Release()
+				return 0, err
+			}
+			moved++
+		}
+		dbi.Release()
+		if err := dbi.Error(); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := t.commit(); err != nil {
+		return 0, err
+	}
+	db.recordTime(quarantineTimeKey(folder))
+	return moved, nil
+}
+
+// pruneQuarantine removes the quarantined entries of folders that were
+// quarantined more than maxAge ago.
+func (db *Lowlevel) pruneQuarantine(maxAge time.Duration) error {
+	miscDB := NewMiscDataNamespace(db)
+	for _, folder := range db.ListFolders() {
+		when, ok, err := miscDB.Int64(quarantineTimeKey([]byte(folder)))
+		if err != nil {
+			return err
+		}
+		if !ok || time.Since(time.Unix(when, 0)) < maxAge {
+			continue
+		}
+		if err := db.dropQuarantine([]byte(folder)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropQuarantine removes the quarantined entries of the folder.
+func (db *Lowlevel) dropQuarantine(folder []byte) error {
+	qk, err := db.keyer.GenerateQuarantineKey(nil, folder, nil)
+	if err != nil {
+		return err
+	}
+	if err := db.dropPrefix(qk.WithoutOriginal()); err != nil {
+		return err
+	}
+	return NewMiscDataNamespace(db).Delete(quarantineTimeKey(folder))
+}
+
+func quarantineTimeKey(folder []byte) string {
+	return "quarantineTime/" + string(folder)
+}
+
+// fatalError panics on the database error, unless the error is corruption
+// within the folder that can be repaired.
+func (s *FileSet) fatalError(err error) {
+	if !IsCorrupt(err) {
+		panic(err)
+	}
+	fn := s.db.repairHandler()
+	if fn == nil {
+		panic(err)
+	}
+	if !atomic.CompareAndSwapInt32(&s.repairing, 0, 1) {
+		// Already on it
+		return
+	}
+
+	l.Warnf("Database index of folder %q is corrupt (%v); rebuilding it", s.folder, err)
+
+	// The error may have happened with the update lock held, so the repair
+	// is done separately.
+	go func() {
+		defer atomic.StoreInt32(&s.repairing, 0)
+
+		s.updateMutex.Lock()
+		moved, qerr := s.db.quarantineFolder([]byte(s.folder))
+		if qerr == nil {
+			s.meta.reset()
+			qerr = s.meta.toDB(s.db, []byte(s.folder))
+		}
+		s.updateMutex.Unlock()
+
+		if backend.IsClosed(qerr) {
+			return
+		} else if qerr != nil {
+			panic(qerr)
+		}
+		fn(FolderRepair{
+			Folder:          s.folder,
+			Error:           err,
+			QuarantinedKeys: moved,
+		})
+	}()
+}
+
+func (s *Snapshot) fatalError(err error) {
+	s.fset.fatalError(err)
+}
@@ -14,6 +14,8 @@ package db
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/syncthing/syncthing/lib/db/backend"
@@ -30,6 +32,7 @@ type FileSet struct {
 	meta   *metadataTracker
 
 	updateMutex sync.Mutex // protects database updates and the corresponding metadata changes
+	repairing   int32      // set while the folder's index is being quarantined
 }
 
 // FileIntf is the set of methods implemented by both protocol.FileInfo and
@@ -84,14 +87,21 @@ func NewFileSet(folder string, fs fs.Filesystem, db *Lowlevel) *FileSet {
 		if err := s.recalcCounts(); backend.IsClosed(err) {
 			return nil
 		} else if err != nil {
-			panic(err)
+			s.fatalError(err)
+		}
+	} else if !s.meta.tracksNeed() {
+		l.Infof("Stored folder metadata for %q lacks the need counts; recalculating", folder)
+		if err := s.recalcCounts(); backend.IsClosed(err) {
+			return nil
+		} else if err != nil {
+			s.fatalError(err)
 		}
 	} else if age := time.Since(s.meta.Created()); age > databaseRecheckInterval {
 		l.Infof("Stored folder metadata for %q is %v old; recalculating", folder, age)
 		if err := s.recalcCounts(); backend.IsClosed(err) {
 			return nil
 		} else if err != nil {
-			panic(err)
+			s.fatalError(err)
 		}
 	}
 
@@ -118,6 +128,9 @@ func (s *FileSet) recalcCounts() error {
 	if err != nil {
 		return err
 	}
+	if err := s.db.recountNeed([]byte(s.folder), s.meta); err != nil {
+		return err
+	}
 
 	s.meta.SetCreated()
 	return s.meta.toDB(s.db, []byte(s.folder))
@@ -132,7 +145,7 @@ func (s *FileSet) Drop(device protocol.DeviceID) {
 	if err := s.db.dropDeviceFolder(device[:], []byte(s.folder), s.meta); backend.IsClosed(err) {
 		return
 	} else if err != nil {
-		panic(err)
+		s.fatalError(err)
 	}
 
 	if device == protocol.LocalDeviceID {
@@ -153,7 +166,7 @@ func (s *FileSet) Drop(device protocol.DeviceID) {
 	if err := s.meta.toDB(s.db, []byte(s.folder)); backend.IsClosed(err) {
 		return
 	} else if err != nil {
-		panic(err)
+		s.fatalError(err)
 	}
 }
 
@@ -170,25 +183,26 @@ func (s *FileSet) Update(device protocol.DeviceID, fs []protocol.FileInfo) {
 
 	defer func() {
 		if err := s.meta.toDB(s.db, []byte(s.folder)); err != nil && !backend.IsClosed(err) {
-			panic(err)
+			s.fatalError(err)
 		}
 	}()
 
 	if device == protocol.LocalDeviceID {
 		// For the local device we have a bunch of metadata to track.
 		if err := s.db.updateLocalFiles([]byte(s.folder), fs, s.meta); err != nil && !backend.IsClosed(err) {
-			panic(err)
+			s.fatalError(err)
 		}
 		return
 	}
 	// Easy case, just update the files and we're done.
 	if err := s.db.updateRemoteFiles([]byte(s.folder), device[:], fs, s.meta); err != nil && !backend.IsClosed(err) {
-		panic(err)
+		s.fatalError(err)
 	}
 }
 
 type Snapshot struct {
 	folder string
+	fset   *FileSet
 	t      readOnlyTransaction
 	meta   *countsMap
 }
@@ -200,6 +214,7 @@ func (s *FileSet) Snapshot() *Snapshot {
 	}
 	return &Snapshot{
 		folder: s.folder,
+		fset:   s,
 		t:      t,
 		meta:   s.meta.Snapshot(),
 	}
@@ -212,35 +227,35 @@ func (s *Snapshot) Release() {
 func (s *Snapshot) WithNeed(device protocol.DeviceID, fn Iterator) {
 	l.Debugf("%s WithNeed(%v)", s.folder, device)
 	if err := s.t.withNeed([]byte(s.folder), device[:], false, nativeFileIterator(fn)); err != nil && !backend.IsClosed(err) {
-		panic(err)
+		s.fatalError(err)
 	}
 }
 
 func (s *Snapshot) WithNeedTruncated(device protocol.DeviceID, fn Iterator) {
 	l.Debugf("%s WithNeedTruncated(%v)", s.folder, device)
 	if err := s.t.withNeed([]byte(s.folder), device[:], true, nativeFileIterator(fn)); err != nil && !backend.IsClosed(err) {
-		panic(err)
+		s.fatalError(err)
 	}
 }
 
 func (s *Snapshot) WithHave(device protocol.DeviceID, fn Iterator) {
 	l.Debugf("%s WithHave(%v)", s.folder, device)
 	if err := s.t.withHave([]byte(s.folder), device[:], nil, false, nativeFileIterator(fn)); err != nil && !backend.IsClosed(err) {
-		panic(err)
+		s.fatalError(err)
 	}
 }
 
 func (s *Snapshot) WithHaveTruncated(device protocol.DeviceID, fn Iterator) {
 	l.Debugf("%s WithHaveTruncated(%v)", s.folder, device)
 	if err := s.t.withHave([]byte(s.folder), device[:], nil, true, nativeFileIterator(fn)); err != nil && !backend.IsClosed(err) {
-		panic(err)
+		s.fatalError(err)
 	}
 }
 
 func (s *Snapshot) WithHaveSequence(startSeq int64, fn Iterator) {
 	l.Debugf("%s WithHaveSequence(%v)", s.folder, startSeq)
 	if err := s.t.withHaveSequence([]byte(s.folder), startSeq, nativeFileIterator(fn)); err != nil && !backend.IsClosed(err) {
-		panic(err)
+		s.fatalError(err)
 	}
 }
 
@@ -249,21 +264,21 @@ func (s *Snapshot) WithHaveSequence(startSeq int64, fn Iterator) {
 func (s *Snapshot) WithPrefixedHaveTruncated(device protocol.DeviceID, prefix string, fn Iterator) {
 	l.Debugf(`%s WithPrefixedHaveTruncated(%v, "%v")`, s.folder, device, prefix)
 	if err := s.t.withHave([]byte(s.folder), device[:], []byte(osutil.NormalizedFilename(prefix)), true, nativeFileIterator(fn)); err != nil && !backend.IsClosed(err) {
-		panic(err)
+		s.fatalError(err)
 	}
 }
 
 func (s *Snapshot) WithGlobal(fn Iterator) {
 	l.Debugf("%s WithGlobal()", s.folder)
 	if err := s.t.withGlobal([]byte(s.folder), nil, false, nativeFileIterator(fn)); err != nil && !backend.IsClosed(err) {
-		panic(err)
+		s.fatalError(err)
 	}
 }
 
 func (s *Snapshot) WithGlobalTruncated(fn Iterator) {
 	l.Debugf("%s WithGlobalTruncated()", s.folder)
 	if err := s.t.withGlobal([]byte(s.folder), nil, true, nativeFileIterator(fn)); err != nil && !backend.IsClosed(err) {
-		panic(err)
+		s.fatalError(err)
 	}
 }
 
@@ -272,7 +287,17 @@ func (s *Snapshot) WithGlobalTruncated(fn Iterator) {
 func (s *Snapshot) WithPrefixedGlobalTruncated(prefix string, fn Iterator) {
 	l.Debugf(`%s WithPrefixedGlobalTruncated("%v")`, s.folder, prefix)
 	if err := s.t.withGlobal([]byte(s.folder), []byte(osutil.NormalizedFilename(prefix)), true, nativeFileIterator(fn)); err != nil && !backend.IsClosed(err) {
-		panic(err)
+		s.fatalError(err)
+	}
+}
+
+// WithGlobalChildrenTruncated iterates the items directly within the
+// directory dir, in name order. If after is given, iteration starts with the
+// first item sorting after it, which allows resuming a listing.
+func (s *Snapshot) WithGlobalChildrenTruncated(dir, after string, fn Iterator) {
+	l.Debugf(`%s WithGlobalChildrenTruncated("%v", "%v")`, s.folder, dir, after)
+	if err := s.t.withGlobalChildren([]byte(s.folder), []byte(osutil.NormalizedFilename(dir)), []byte(osutil.NormalizedFilename(after)), true, nativeFileIterator(fn)); err != nil && !backend.IsClosed(err) {
+		s.fatalError(err)
 	}
 }
 
@@ -281,7 +306,7 @@ func (s *Snapshot) Get(device protocol.DeviceID, file string) (protocol.FileInfo
 	if backend.IsClosed(err) {
 		return protocol.FileInfo{}, false
 	} else if err != nil {
-		panic(err)
+		s.fatalError(err)
 	}
 	f.Name = osutil.NativeFilename(f.Name)
 	return f, ok
@@ -292,7 +317,7 @@ func (s *Snapshot) GetGlobal(file string) (protocol.FileInfo, bool) {
 	if backend.IsClosed(err) {
 		return protocol.FileInfo{}, false
 	} else if err != nil {
-		panic(err)
+		s.fatalError(err)
 	}
 	if !ok {
 		return protocol.FileInfo{}, false
@@ -307,7 +332,7 @@ func (s *Snapshot) GetGlobalTruncated(file string) (FileInfoTruncated, bool) {
 	if backend.IsClosed(err) {
 		return FileInfoTruncated{}, false
 	} else if err != nil {
-		panic(err)
+		s.fatalError(err)
 	}
 	if !ok {
 		return FileInfoTruncated{}, false
@@ -322,7 +347,7 @@ func (s *Snapshot) Availability(file string) []protocol.DeviceID {
 	if backend.IsClosed(err) {
 		return nil
 	} else if err != nil {
-		panic(err)
+		s.fatalError(err)
 	}
 	return av
 }
@@ -359,9 +384,80 @@ func (s *Snapshot) GlobalSize() Counts {
 	return global.Add(recvOnlyChanged)
 }
 
+// NeedSize returns the counts of what the local device needs, as kept up
+// to date with the need index.
 func (s *Snapshot) NeedSize() Counts {
+	need := s.meta.Counts(protocol.LocalDeviceID, needFlag)
+	return Counts{
+		Files:       need.Files,
+		Directories: need.Directories,
+		Symlinks:    need.Symlinks,
+		Deleted:     need.Deleted,
+		Bytes:       need.Bytes,
+	}
+}
+
+// GlobalSizePrefixed is like GlobalSize, for the item at the prefix and
+// the items below it.
+func (s *Snapshot) GlobalSizePrefixed(prefix string) Counts {
+	var result Counts
+	s.WithPrefixedGlobalTruncated(prefix, func(f FileIntf) bool {
+		if countedInSize(f) {
+			addToCounts(&result, f)
+		}
+		return true
+	})
+	return result
+}
+
+// LocalSizePrefixed is like LocalSize, for the item at the prefix and the
+// items below it.
+func (s *Snapshot) LocalSizePrefixed(prefix string) Counts {
+	var result Counts
+	s.WithPrefixedHaveTruncated(protocol.LocalDeviceID, prefix, func(f FileIntf) bool {
+		if countedInSize(f) {
+			addToCounts(&result, f)
+		}
+		return true
+	})
+	return result
+}
+
+// countedInSize returns whether the item counts for the global and local
+// sizes, which are those of the items without local flags except for
+// receive only changes.
+func countedInSize(f FileIntf) bool {
+	flags := f.FileLocalFlags()
+	if flags == 0 {
+		return !f.IsInvalid()
+	}
+	return flags&protocol.FlagLocalReceiveOnly != 0
+}
+
+// addToCounts counts the item like the metadata tracker does.
+func addToCounts(c *Counts, f FileIntf) {
+	switch {
+	case f.IsDeleted():
+		c.Deleted++
+	case f.IsDirectory() && !f.IsSymlink():
+		c.Directories++
+	case f.IsSymlink():
+		c.Symlinks++
+	default:
+		c.Files++
+	}
+	c.Bytes += f.FileSize()
+}
+
+// NeedSizePrefixed is like NeedSize, for the item at the prefix and the
+// items below it.
+func (s *Snapshot) NeedSizePrefixed(prefix string) Counts {
+	prefix = osutil.NativeFilename(prefix)
 	var result Counts
 	s.WithNeedTruncated(protocol.LocalDeviceID, func(f FileIntf) bool {
+		if name := f.FileName(); prefix != "" && name != prefix && !strings.HasPrefix(name, prefix+string(filepath.Separator)) {
+			return true
+		}
 		switch {
 		case f.IsDeleted():
 			result.Deleted++
@@ -436,7 +532,7 @@ func (s *FileSet) IndexID(device protocol.DeviceID) protocol.IndexID {
 	if backend.IsClosed(err) {
 		return 0
 	} else if err != nil {
-		panic(err)
+		s.fatalError(err)
 	}
 	if id == 0 && device == protocol.LocalDeviceID {
 		// No index ID set yet. We create one now.
@@ -445,7 +541,7 @@ func (s *FileSet) IndexID(device protocol.DeviceID) protocol.IndexID {
 		if backend.IsClosed(err) {
 			return 0
 		} else if err != nil {
-			panic(err)
+			s.fatalError(err)
 		}
 	}
 	return id
@@ -456,19 +552,25 @@ func (s *FileSet) SetIndexID(device protocol.DeviceID, id protocol.IndexID) {
 		panic("do not explicitly set index ID for local device")
 	}
 	if err := s.db.setIndexID(device[:], []byte(s.folder), id); err != nil && !backend.IsClosed(err) {
-		panic(err)
+		s.fatalError(err)
 	}
 }
 
 func (s *FileSet) MtimeFS() *fs.MtimeFS {
+	return s.MtimeFSFor(s.fs)
+}
+
+// MtimeFSFor is like MtimeFS, but for another filesystem with the same
+// files, like a snapshot of the folder.
+func (s *FileSet) MtimeFSFor(filesystem fs.Filesystem) *fs.MtimeFS {
 	prefix, err := s.db.keyer.GenerateMtimesKey(nil, []byte(s.folder))
 	if backend.IsClosed(err) {
 		return nil
 	} else if err != nil {
-		panic(err)
+		s.fatalError(err)
 	}
 	kv := NewNamespacedKV(s.db, string(prefix))
-	return fs.NewMtimeFS(s.fs, kv)
+	return fs.NewMtimeFS(filesystem, kv)
 }
 
 func (s *FileSet) ListDevices() []protocol.DeviceID {
@@ -482,6 +584,10 @@ func DropFolder(db *Lowlevel, folder string) {
 		db.dropFolder,
 		db.dropMtimes,
 		db.dropFolderMeta,
+		db.dropEscapedNames,
+		db.dropVersionIndex,
+		db.dropIgnoreCache,
+		db.dropQuarantine,
 		db.folderIdx.Delete,
 	}
 	for _, drop := range droppers {
@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"sort"
 	"testing"
 	"time"
@@ -712,7 +713,7 @@ func BenchmarkUpdateOneFile(b *testing.B) {
 		protocol.FileInfo{Name: "zajksdhaskjdh/askjdhaskjdashkajshd/kasjdhaskjdhaskdjhaskdjash/dkjashdaksjdhaskdjahskdjh", Version: protocol.Vector{Counters: []protocol.Counter{{ID: myID, Value: 1000}}}, Blocks: genBlocks(8)},
 	}
 
-	be, err := backend.Open("testdata/benchmarkupdate.db", backend.TuningAuto)
+	be, err := backend.Open("testdata/benchmarkupdate.db", backend.TuningAuto, backend.DurabilityDefault, 0)
 	if err != nil {
 		b.Fatal(err)
 	}
@@ -1481,6 +1482,51 @@ func TestIgnoreAfterReceiveOnly(t *testing.T) {
 	}
 }
 
+func TestWithGlobalChildren(t *testing.T) {
+	ldb := db.NewLowlevel(backend.OpenMemory())
+	s := db.NewFileSet("test", fs.NewFilesystem(fs.FilesystemTypeBasic, "."), ldb)
+
+	v := protocol.Vector{Counters: []protocol.Counter{{ID: myID, Value: 1}}}
+	var local []protocol.FileInfo
+	for _, name := range []string{"a", "a/b", "a/b/c", "a.txt", "a0", "b", "b/c", "c"} {
+		local = append(local, protocol.FileInfo{Name: filepath.FromSlash(name), Version: v})
+	}
+	replace(s, protocol.LocalDeviceID, local)
+
+	snap := s.Snapshot()
+	defer snap.Release()
+
+	children := func(dir, after string, limit int) []string {
+		var names []string
+		snap.WithGlobalChildrenTruncated(dir, after, func(fi db.FileIntf) bool {
+			names = append(names, filepath.ToSlash(fi.FileName()))
+			return len(names) != limit
+		})
+		return names
+	}
+
+	cases := []struct {
+		dir, after string
+		limit      int
+		exp        []string
+	}{
+		{"", "", 0, []string{"a", "a.txt", "a0", "b", "c"}},
+		{"", "", 2, []string{"a", "a.txt"}},
+		{"", "a", 0, []string{"a.txt", "a0", "b", "c"}},
+		{"", "a0", 0, []string{"b", "c"}},
+		{"a", "", 0, []string{"a/b"}},
+		{"a/", "", 0, []string{"a/b"}},
+		{"a/b", "", 0, []string{"a/b/c"}},
+		{"b", "b/c", 0, nil},
+		{"nonexistent", "", 0, nil},
+	}
+	for _, tc := range cases {
+		if names := children(tc.dir, filepath.FromSlash(tc.after), tc.limit); !reflect.DeepEqual(names, tc.exp) {
+			t.Errorf("Children of %q after %q: got %v, expected %v", tc.dir, tc.after, names, tc.exp)
+		}
+	}
+}
+
 func replace(fs *db.FileSet, device protocol.DeviceID, files []protocol.FileInfo) {
 	fs.Drop(device)
 	fs.Update(device, files)
@@ -1503,3 +1549,107 @@ func receiveOnlyChangedSize(fs *db.FileSet) db.Counts {
 	defer snap.Release()
 	return snap.ReceiveOnlyChangedSize()
 }
+
+func TestPrefixedSizes(t *testing.T) {
+	ldb := db.NewLowlevel(backend.OpenMemory())
+	defer ldb.Close()
+
+	s := db.NewFileSet("test", fs.NewFilesystem(fs.FilesystemTypeBasic, "."), ldb)
+
+	v1 := protocol.Vector{Counters: []protocol.Counter{{ID: myID, Value: 1000}}}
+	v2 := protocol.Vector{Counters: []protocol.Counter{{ID: myID, Value: 1001}}}
+	local := []protocol.FileInfo{
+		{Name: "dir", Type: protocol.FileInfoTypeDirectory, Version: v1},
+		{Name: filepath.Join("dir", "a"), Size: 10, Version: v1},
+		{Name: filepath.Join("dir", "b"), Size: 20, Version: v1},
+		{Name: "dir.file", Size: 40, Version: v1},
+	}
+	remote := []protocol.FileInfo{
+		{Name: "dir", Type: protocol.FileInfoTypeDirectory, Version: v1},
+		{Name: filepath.Join("dir", "a"), Size: 10, Version: v1},
+		{Name: filepath.Join("dir", "b"), Size: 25, Version: v2},
+		{Name: filepath.Join("dir", "c"), Size: 5, Version: v1},
+		{Name: "dir.file", Size: 40, Version: v1},
+	}
+	replace(s, protocol.LocalDeviceID, local)
+	replace(s, remoteDevice0, remote)
+
+	snap := s.Snapshot()
+	defer snap.Release()
+
+	if global := snap.GlobalSizePrefixed("dir"); global.Files != 3 || global.Directories != 1 || global.Bytes != 10+25+5+protocol.SyntheticDirectorySize {
+		t.Errorf("Unexpected global size %+v", global)
+	}
+	if local := snap.LocalSizePrefixed("dir"); local.Files != 2 || local.Bytes != 10+20+protocol.SyntheticDirectorySize {
+		t.Errorf("Unexpected local size %+v", local)
+	}
+	if need := snap.NeedSizePrefixed("dir"); need.Files != 2 || need.Bytes != 25+5 {
+		t.Errorf("Unexpected need size %+v", need)
+	}
+	if need := snap.NeedSizePrefixed("dir.file"); need.TotalItems() != 0 {
+		t.Errorf("Unexpected need size %+v", need)
+	}
+	if global, all := snap.GlobalSizePrefixed(""), snap.GlobalSize(); global.Files != all.Files || global.Bytes != all.Bytes {
+		t.Errorf("Global size without prefix %+v differs from %+v", global, all)
+	}
+}
+
+func TestNeedCounts(t *testing.T) {
+	ldb := db.NewLowlevel(backend.OpenMemory())
+	defer ldb.Close()
+
+	s := db.NewFileSet("test", fs.NewFilesystem(fs.FilesystemTypeBasic, "."), ldb)
+
+	v1 := protocol.Vector{Counters: []protocol.Counter{{ID: myID, Value: 1000}}}
+	v2 := protocol.Vector{Counters: []protocol.Counter{{ID: myID, Value: 1001}}}
+	v3 := protocol.Vector{Counters: []protocol.Counter{{ID: myID, Value: 1002}}}
+
+	check := func(s *db.FileSet, files, dirs, deleted int32, bytes int64) {
+		t.Helper()
+		snap := s.Snapshot()
+		defer snap.Release()
+		need, iterated := snap.NeedSize(), snap.NeedSizePrefixed("")
+		if !reflect.DeepEqual(need, iterated) {
+			t.Errorf("Need counts %+v differ from the need %+v", need, iterated)
+		}
+		if need.Files != files || need.Directories != dirs || need.Deleted != deleted || need.Bytes != bytes {
+			t.Errorf("Unexpected need counts %+v", need)
+		}
+	}
+
+	replace(s, protocol.LocalDeviceID, []protocol.FileInfo{
+		{Name: "a", Size: 10, Version: v1},
+		{Name: "b", Size: 20, Version: v1},
+	})
+	check(s, 0, 0, 0, 0)
+
+	s.Update(remoteDevice0, []protocol.FileInfo{
+		{Name: "a", Size: 15, Version: v2},
+		{Name: "c", Size: 5, Version: v1},
+		{Name: "dir", Type: protocol.FileInfoTypeDirectory, Version: v1},
+	})
+	check(s, 2, 1, 0, 15+5)
+
+	// A newer version of a needed file replaces its counts.
+	s.Update(remoteDevice0, []protocol.FileInfo{{Name: "a", Size: 30, Version: v3}})
+	check(s, 2, 1, 0, 30+5)
+
+	// Deletions of what we have are needed, those of what we don't aren't.
+	s.Update(remoteDevice0, []protocol.FileInfo{
+		{Name: "b", Version: v2, Deleted: true},
+		{Name: "c", Version: v2, Deleted: true},
+	})
+	check(s, 1, 1, 1, 30)
+
+	// Pulling satisfies the need.
+	s.Update(protocol.LocalDeviceID, []protocol.FileInfo{{Name: "a", Size: 30, Version: v3}})
+	check(s, 0, 1, 1, 0)
+
+	// Dropping the remote device leaves nothing to need.
+	s.Drop(remoteDevice0)
+	check(s, 0, 0, 0, 0)
+
+	// The counts survive reopening the set.
+	s.Update(remoteDevice0, []protocol.FileInfo{{Name: "d", Size: 7, Version: v1}})
+	check(db.NewFileSet("test", fs.NewFilesystem(fs.FilesystemTypeBasic, "."), ldb), 1, 0, 0, 7)
+}
@@ -70,14 +70,14 @@ func (t readOnlyTransaction) unmarshalTrunc(bs []byte, trunc bool) (FileIntf, er
 		var tf FileInfoTruncated
 		err := tf.Unmarshal(bs)
 		if err != nil {
-			return nil, err
+			return nil, &corruptError{"file info", err}
 		}
 		return tf, nil
 	}
 
 	var tf protocol.FileInfo
 	if err := tf.Unmarshal(bs); err != nil {
-		return nil, err
+		return nil, &corruptError{"file info", err}
 	}
 	if err := t.fillBlockList(&tf); err != nil {
 		return nil, err
@@ -91,12 +91,14 @@ func (t readOnlyTransaction) fillBlockList(fi *protocol.FileInfo) error {
 	}
 	blocksKey := t.keyer.GenerateBlockListKey(nil, fi.BlocksHash)
 	bs, err := t.Get(blocksKey)
-	if err != nil {
+	if backend.IsNotFound(err) {
+		return &corruptError{"block list", err}
+	} else if err != nil {
 		return err
 	}
 	var bl BlockList
 	if err := bl.Unmarshal(bs); err != nil {
-		return err
+		return &corruptError{"block list", err}
 	}
 	fi.Blocks = bl.Blocks
 	return nil
@@ -163,22 +165,33 @@ func (t *readOnlyTransaction) withHave(folder, device, prefix []byte, truncate b
 	}
 	defer dbi.Release()
 
+	// Entries that can't be decoded are skipped, but the first such error
+	// is returned in the end so that the corruption gets repaired.
+	var corrupt error
 	for dbi.Next() {
 		name := t.keyer.NameFromDeviceFileKey(dbi.Key())
 		if len(prefix) > 0 && !bytes.HasPrefix(name, prefix) {
-			return nil
+			return corrupt
 		}
 
 		f, err := t.unmarshalTrunc(dbi.Value(), truncate)
-		if err != nil {
+		if IsCorrupt(err) {
 			l.Debugln("unmarshal error:", err)
+			if corrupt == nil {
+				corrupt = err
+			}
 			continue
+		} else if err != nil {
+			return err
 		}
 		if !fn(f) {
-			return nil
+			return corrupt
 		}
 	}
-	return dbi.Error()
+	if err := dbi.Error(); err != nil {
+		return err
+	}
+	return corrupt
 }
 
 func (t *readOnlyTransaction) withHaveSequence(folder []byte, startSeq int64, fn Iterator) error {
@@ -280,6 +293,82 @@ func (t *readOnlyTransaction) withGlobal(folder, prefix []byte, truncate bool, f
 	return dbi.Error()
 }
 
+// withGlobalChildren iterates the global files directly within the
+// directory dir (the folder root if empty), in name order, starting after
+// the name after. Deeper levels are skipped over without being read.
+func (t *readOnlyTransaction) withGlobalChildren(folder, dir, after []byte, truncate bool, fn Iterator) error {
+	if len(dir) > 0 && !bytes.HasSuffix(dir, []byte{'/'}) {
+		dir = append(dir, '/')
+	}
+
+	// Names never contain NUL or 0xff, so these bound the range of keys
+	// below dir, starting just after the given name.
+	start := append([]byte(nil), dir...)
+	if len(after) > 0 {
+		start = append(append([]byte(nil), after...), 0)
+	}
+	last, err := t.keyer.GenerateGlobalVersionKey(nil, folder, append(append([]byte(nil), dir...), 0xff))
+	if err != nil {
+		return err
+	}
+
+	var dk []byte
+	for {
+		first, err := t.keyer.GenerateGlobalVersionKey(nil, folder, start)
+		if err != nil {
+			return err
+		}
+		dbi, err := t.NewRangeIterator(first, last)
+		if err != nil {
+			return err
+		}
+
+		// skip is set to the first name after a subdirectory we need to
+		// jump over, which means restarting the iteration from there.
+		var skip []byte
+		for dbi.Next() {
+			name := t.keyer.NameFromGlobalVersionKey(dbi.Key())
+			if i := bytes.IndexByte(name[len(dir):], '/'); i >= 0 {
+				// '0' is the byte after '/', so this is the first name
+				// outside of the subdirectory.
+				skip = append(append([]byte(nil), name[:len(dir)+i]...), '0')
+				break
+			}
+
+			vl, ok := unmarshalVersionList(dbi.Value())
+			if !ok {
+				continue
+			}
+
+			dk, err = t.keyer.GenerateDeviceFileKey(dk, folder, vl.Versions[0].Device, name)
+			if err != nil {
+				dbi.Release()
+				return err
+			}
+
+			f, ok, err := t.getFileTrunc(dk, truncate)
+			if err != nil {
+				dbi.Release()
+				return err
+			}
+			if !ok {
+				continue
+			}
+
+			if !fn(f) {
+				dbi.Release()
+				return nil
+			}
+		}
+		err = dbi.Error()
+		dbi.Release()
+		if err != nil || skip == nil {
+			return err
+		}
+		start = skip
+	}
+}
+
 func (t *readOnlyTransaction) availability(folder, file []byte) ([]protocol.DeviceID, error) {
 	k, err := t.keyer.GenerateGlobalVersionKey(nil, folder, file)
 	if err != nil {
@@ -514,7 +603,7 @@ func (t readWriteTransaction) updateGlobal(gk, keyBuf, folder, device []byte, fi
 	}
 
 	// Fixup the list of files we need.
-	keyBuf, err = t.updateLocalNeed(keyBuf, folder, name, fl, global)
+	keyBuf, err = t.updateLocalNeed(keyBuf, folder, name, fl, global, meta)
 	if err != nil {
 		return nil, false, err
 	}
@@ -562,34 +651,54 @@ func (t readWriteTransaction) updateGlobal(gk, keyBuf, folder, device []byte, fi
 
 // updateLocalNeed checks whether the given file is still needed on the local
 // device according to the version list and global FileInfo given and updates
-// the db accordingly.
-func (t readWriteTransaction) updateLocalNeed(keyBuf, folder, name []byte, fl VersionList, global protocol.FileInfo) ([]byte, error) {
+// the db and the need counts accordingly.
+func (t readWriteTransaction) updateLocalNeed(keyBuf, folder, name []byte, fl VersionList, global protocol.FileInfo, meta *metadataTracker) ([]byte, error) {
 	var err error
 	keyBuf, err = t.keyer.GenerateNeedFileKey(keyBuf, folder, name)
 	if err != nil {
 		return nil, err
 	}
-	_, err = t.Get(keyBuf)
-	if err != nil && !backend.IsNotFound(err) {
+	counted, hasNeeded, err := t.getNeedCounts(keyBuf)
+	if err != nil {
 		return nil, err
 	}
-	hasNeeded := err == nil
 	if localFV, haveLocalFV := fl.Get(protocol.LocalDeviceID[:]); need(global, haveLocalFV, localFV.Version) {
 		if !hasNeeded {
 			l.Debugf("local need insert; folder=%q, name=%q", folder, name)
-			if err := t.Put(keyBuf, nil); err != nil {
+		}
+		if now := neededCounts(global); !hasNeeded || !sameCounts(now, counted) {
+			if err := t.Put(keyBuf, mustMarshal(&now)); err != nil {
 				return nil, err
 			}
+			meta.removeNeeded(counted)
+			meta.addNeeded(now)
 		}
 	} else if hasNeeded {
 		l.Debugf("local need delete; folder=%q, name=%q", folder, name)
 		if err := t.Delete(keyBuf); err != nil {
 			return nil, err
 		}
+		meta.removeNeeded(counted)
 	}
 	return keyBuf, nil
 }
 
+// getNeedCounts returns what was counted for the need entry, and whether
+// there is one. Entries written by older versions have nothing counted.
+func (t readWriteTransaction) getNeedCounts(key []byte) (Counts, bool, error) {
+	bs, err := t.Get(key)
+	if backend.IsNotFound(err) {
+		return Counts{}, false, nil
+	} else if err != nil {
+		return Counts{}, false, err
+	}
+	var counted Counts
+	if err := counted.Unmarshal(bs); err != nil {
+		return Counts{}, true, nil
+	}
+	return counted, true, nil
+}
+
 func need(global FileIntf, haveLocal bool, localVersion protocol.Vector) bool {
 	// We never need an invalid file.
 	if global.IsInvalid() {
@@ -652,9 +761,16 @@ func (t readWriteTransaction) removeFromGlobal(gk, keyBuf, folder, device []byte
 		if err != nil {
 			return nil, err
 		}
-		if err := t.Delete(keyBuf); err != nil {
+		counted, hasNeeded, err := t.getNeedCounts(keyBuf)
+		if err != nil {
 			return nil, err
 		}
+		if hasNeeded {
+			if err := t.Delete(keyBuf); err != nil {
+				return nil, err
+			}
+			meta.removeNeeded(counted)
+		}
 		if err := t.Delete(gk); err != nil {
 			return nil, err
 		}
@@ -670,7 +786,7 @@ func (t readWriteTransaction) removeFromGlobal(gk, keyBuf, folder, device []byte
 		if err != nil || !ok {
 			return keyBuf, err
 		}
-		keyBuf, err = t.updateLocalNeed(keyBuf, folder, file, fl, global)
+		keyBuf, err = t.updateLocalNeed(keyBuf, folder, file, fl, global, meta)
 		if err != nil {
 			return nil, err
 		}
@@ -727,7 +843,7 @@ func (t *readWriteTransaction) withAllFolderTruncated(folder []byte, fn func(dev
 		// we need to copy it.
 		err := f.Unmarshal(append([]byte{}, dbi.Value()...))
 		if err != nil {
-			return err
+			return &corruptError{"file info", err}
 		}
 
 		switch f.Name {
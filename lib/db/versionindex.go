@@ -0,0 +1,133 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package db
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+// An ArchivedVersion describes a version of a file that was archived by the
+// versioner of its folder.
+type ArchivedVersion struct {
+	VersionTime time.Time        `json:"versionTime"` // when archiving started, to the second
+	ArchivedAt  time.Time        `json:"archivedAt"`  // when archiving was done
+	ModTime     time.Time        `json:"modTime"`
+	Size        int64            `json:"size"`
+	Device      protocol.ShortID `json:"device"` // the device that last modified the file
+}
+
+// Matches returns whether the version time given by a versioner, which
+// takes it sometime while archiving, is the one of this version.
+func (v ArchivedVersion) Matches(versionTime time.Time) bool {
+	return !versionTime.Before(v.VersionTime) && !versionTime.After(v.ArchivedAt)
+}
+
+// A VersionIndex keeps the metadata of the archived versions of the files
+// in a folder, by their original path. The versions of a file are kept in
+// the order they were added.
+type VersionIndex struct {
+	db     *Lowlevel
+	prefix []byte
+	mut    sync.Mutex // serializes the read-modify-write of the version lists
+}
+
+// NewVersionIndex returns the version index of the given folder.
+func NewVersionIndex(db *Lowlevel, folder string) *VersionIndex {
+	return &VersionIndex{
+		db:     db,
+		prefix: versionIndexPrefix(folder),
+		mut:    sync.NewMutex(),
+	}
+}
+
+func versionIndexPrefix(folder string) []byte {
+	return append([]byte{KeyTypeVersionIndex}, folder+"/"...)
+}
+
+func (i *VersionIndex) key(name string) []byte {
+	key := make([]byte, len(i.prefix), len(i.prefix)+len(name))
+	copy(key, i.prefix)
+	return append(key, name...)
+}
+
+// Add records a version of the named file.
+func (i *VersionIndex) Add(name string, v ArchivedVersion) error {
+	i.mut.Lock()
+	defer i.mut.Unlock()
+
+	versions, err := i.versions(name)
+	if err != nil {
+		return err
+	}
+	return i.put(name, append(versions, v))
+}
+
+// Versions returns the recorded versions of the named file.
+func (i *VersionIndex) Versions(name string) ([]ArchivedVersion, error) {
+	i.mut.Lock()
+	defer i.mut.Unlock()
+	return i.versions(name)
+}
+
+// Set replaces the recorded versions of the named file, removing the file
+// from the index when there are none.
+func (i *VersionIndex) Set(name string, versions []ArchivedVersion) error {
+	i.mut.Lock()
+	defer i.mut.Unlock()
+	return i.put(name, versions)
+}
+
+// All returns the recorded versions of all files.
+func (i *VersionIndex) All() (map[string][]ArchivedVersion, error) {
+	i.mut.Lock()
+	defer i.mut.Unlock()
+
+	it, err := i.db.NewPrefixIterator(i.prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Release()
+	res := make(map[string][]ArchivedVersion)
+	for it.Next() {
+		var versions []ArchivedVersion
+		if err := json.Unmarshal(it.Value(), &versions); err != nil {
+			l.Debugln("Unmarshalling archived versions:", err)
+			continue
+		}
+		res[string(it.Key()[len(i.prefix):])] = versions
+	}
+	return res, it.Error()
+}
+
+func (i *VersionIndex) versions(name string) ([]ArchivedVersion, error) {
+	bs, err := i.db.Get(i.key(name))
+	if err != nil {
+		return nil, filterNotFound(err)
+	}
+	var versions []ArchivedVersion
+	if err := json.Unmarshal(bs, &versions); err != nil {
+		// Not worth failing over, the versions are still there
+		l.Debugln("Unmarshalling archived versions:", err)
+		return nil, nil
+	}
+	return versions, nil
+}
+
+func (i *VersionIndex) put(name string, versions []ArchivedVersion) error {
+	if len(versions) == 0 {
+		return i.db.Delete(i.key(name))
+	}
+	bs, err := json.Marshal(versions)
+	if err != nil {
+		return err
+	}
+	return i.db.Put(i.key(name), bs)
+}
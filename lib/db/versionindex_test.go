@@ -0,0 +1,71 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/db/backend"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestVersionIndex(t *testing.T) {
+	ldb := NewLowlevel(backend.OpenMemory())
+	defer ldb.Close()
+
+	idx := NewVersionIndex(ldb, "default")
+	other := NewVersionIndex(ldb, "other")
+
+	now := time.Now().Truncate(time.Second)
+	v1 := ArchivedVersion{VersionTime: now, ArchivedAt: now.Add(time.Second), Size: 1, Device: protocol.ShortID(42)}
+	v2 := ArchivedVersion{VersionTime: now.Add(time.Minute), ArchivedAt: now.Add(time.Minute), Size: 2}
+	if err := idx.Add("a", v1); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.Add("a", v2); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Add("b", v1); err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := idx.Versions("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 2 || versions[0].Device != 42 || versions[1].Size != 2 {
+		t.Errorf("Unexpected versions %+v", versions)
+	}
+	if !versions[0].Matches(now.Add(time.Second)) || versions[0].Matches(now.Add(2*time.Second)) {
+		t.Error("Unexpected matching of version times")
+	}
+
+	all, err := idx.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 || len(all["a"]) != 2 {
+		t.Errorf("Unexpected index contents %+v", all)
+	}
+
+	if err := idx.Set("a", nil); err != nil {
+		t.Fatal(err)
+	}
+	if all, err := idx.All(); err != nil {
+		t.Fatal(err)
+	} else if len(all) != 0 {
+		t.Errorf("Expected no versions, got %+v", all)
+	}
+
+	DropFolder(ldb, "other")
+	if versions, err := other.Versions("b"); err != nil {
+		t.Fatal(err)
+	} else if len(versions) != 0 {
+		t.Errorf("Expected the versions to be dropped with the folder, got %+v", versions)
+	}
+}
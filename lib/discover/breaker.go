@@ -0,0 +1,99 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package discover
+
+import (
+	"fmt"
+	stdsync "sync"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/rand"
+)
+
+const (
+	breakerThreshold  = 3 // failures in a row before lookups are suspended
+	breakerBackoffMin = 30 * time.Second
+	breakerBackoffMax = 30 * time.Minute
+)
+
+// A breaker suspends lookups to a discovery server that keeps failing, so
+// that it isn't asked again for every device we want to connect to. The
+// suspension doubles, with some jitter, each time the server still fails
+// when it's over.
+type breaker struct {
+	failures  int
+	openUntil time.Time
+	lastErr   error
+	mut       stdsync.Mutex // stdlib sync for the same reasons as errorHolder
+}
+
+// A breakerError is returned for lookups while they are suspended.
+type breakerError struct {
+	failures int
+	until    time.Time
+	err      error
+}
+
+func (e *breakerError) Error() string {
+	return fmt.Sprintf("lookups suspended until %s after %d failures: %v", e.until.Format(time.RFC3339), e.failures, e.err)
+}
+
+// allow returns an error if lookups are suspended at the given time.
+func (b *breaker) allow(now time.Time) *breakerError {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	if now.Before(b.openUntil) {
+		return &breakerError{failures: b.failures, until: b.openUntil, err: b.lastErr}
+	}
+	// Once the suspension is over there is one lookup to see whether the
+	// server is back, as the next failure suspends again.
+	return nil
+}
+
+// success records that the server answered, returning whether it had been
+// failing before.
+func (b *breaker) success() bool {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	wasOpen := b.failures >= breakerThreshold
+	b.failures = 0
+	b.openUntil = time.Time{}
+	b.lastErr = nil
+	return wasOpen
+}
+
+// failure records that the server couldn't be reached, returning how long
+// lookups are suspended for, if at all.
+func (b *breaker) failure(err error, now time.Time) time.Duration {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	b.failures++
+	b.lastErr = err
+	if b.failures < breakerThreshold {
+		return 0
+	}
+	backoff := breakerBackoffMin
+	for i := breakerThreshold; i < b.failures && backoff < breakerBackoffMax; i++ {
+		backoff *= 2
+	}
+	if backoff > breakerBackoffMax {
+		backoff = breakerBackoffMax
+	}
+	// Up to a quarter more, so that devices that lost the server at the
+	// same time don't all come back to it at the same time.
+	backoff += time.Duration(rand.Int63() % int64(backoff/4+1))
+	b.openUntil = now.Add(backoff)
+	return backoff
+}
+
+// error returns the suspension, if lookups are currently suspended.
+func (b *breaker) error() error {
+	if err := b.allow(time.Now()); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package discover
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestBreaker(t *testing.T) {
+	var b breaker
+	now := time.Now()
+	errFailed := errors.New("failed")
+
+	for i := 1; i < breakerThreshold; i++ {
+		if backoff := b.failure(errFailed, now); backoff != 0 {
+			t.Fatalf("suspended after %d failures", i)
+		}
+	}
+	backoff := b.failure(errFailed, now)
+	if backoff < breakerBackoffMin || backoff > breakerBackoffMin*5/4 {
+		t.Fatalf("unexpected backoff %v", backoff)
+	}
+	if err := b.allow(now.Add(backoff - time.Second)); err == nil {
+		t.Error("lookup allowed while suspended")
+	}
+	if err := b.allow(now.Add(backoff)); err != nil {
+		t.Error("lookup not allowed after suspension:", err)
+	}
+
+	// Failing again after the suspension doubles it
+	if backoff := b.failure(errFailed, now); backoff < 2*breakerBackoffMin {
+		t.Errorf("unexpected backoff %v", backoff)
+	}
+	if !b.success() {
+		t.Error("expected success to close the breaker")
+	}
+	if err := b.allow(now); err != nil {
+		t.Error("lookup not allowed after success:", err)
+	}
+
+	// It never gets longer than the maximum, plus jitter
+	for i := 0; i < 20; i++ {
+		backoff = b.failure(errFailed, now)
+	}
+	if backoff < breakerBackoffMax || backoff > breakerBackoffMax*5/4 {
+		t.Errorf("unexpected backoff %v", backoff)
+	}
+}
+
+func TestGlobalLookupBreaker(t *testing.T) {
+	list, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer list.Close()
+
+	var requests int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	})
+	go func() { _ = http.Serve(list, mux) }()
+
+	disco, err := NewGlobal("http://"+list.Addr().String()+"?insecure&noannounce", tls.Certificate{}, nil, events.NoopLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < breakerThreshold+2; i++ {
+		if _, err := disco.Lookup(protocol.LocalDeviceID); err == nil {
+			t.Fatal("unexpected nil error")
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != breakerThreshold {
+		t.Errorf("got %d requests, expected %d", n, breakerThreshold)
+	}
+	if disco.Error() == nil {
+		t.Error("expected the suspension to be reported")
+	}
+}
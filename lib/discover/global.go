@@ -37,6 +37,7 @@ type globalClient struct {
 	noAnnounce     bool
 	noLookup       bool
 	evLogger       events.Logger
+	breaker        breaker
 	errorHolder
 }
 
@@ -156,11 +157,28 @@ func (c *globalClient) Lookup(device protocol.DeviceID) (addresses []string, err
 	q.Set("device", device.String())
 	qURL.RawQuery = q.Encode()
 
+	now := time.Now()
+	if err := c.breaker.allow(now); err != nil {
+		l.Debugln("globalClient.Lookup", qURL, err)
+		// Don't ask about this device again until then.
+		return nil, lookupError{
+			error:    err,
+			cacheFor: err.until.Sub(now),
+		}
+	}
+
 	resp, err := c.queryClient.Get(qURL.String())
 	if err != nil {
 		l.Debugln("globalClient.Lookup", qURL, err)
+		c.lookupFailed(err, now)
 		return nil, err
 	}
+	if resp.StatusCode >= 500 {
+		// The server is there, but not in a state to answer.
+		c.lookupFailed(errors.New(resp.Status), now)
+	} else if c.breaker.success() {
+		l.Infof("Global discovery server %s is answering lookups again", c.server)
+	}
 	if resp.StatusCode != 200 {
 		resp.Body.Close()
 		l.Debugln("globalClient.Lookup", qURL, resp.Status)
@@ -185,6 +203,21 @@ func (c *globalClient) Lookup(device protocol.DeviceID) (addresses []string, err
 	return ann.Addresses, err
 }
 
+func (c *globalClient) lookupFailed(err error, now time.Time) {
+	if backoff := c.breaker.failure(err, now); backoff > 0 {
+		l.Infof("Global discovery server %s is failing lookups (%v); suspending them for %v", c.server, err, backoff.Truncate(time.Second))
+	}
+}
+
+// Error returns the announcement error, or the suspension of lookups if
+// they have been failing.
+func (c *globalClient) Error() error {
+	if err := c.errorHolder.Error(); err != nil {
+		return err
+	}
+	return c.breaker.error()
+}
+
 func (c *globalClient) String() string {
 	return "global@" + c.server
 }
@@ -50,7 +50,13 @@ const (
 	v13Magic          = uint32(0x7D79BC40) // previous version
 )
 
-func NewLocal(id protocol.DeviceID, addr string, addrList AddressLister, evLogger events.Logger) (FinderService, error) {
+// LocalOptions adjust where local discovery announces.
+type LocalOptions struct {
+	Interfaces []string // network interfaces to announce on, all if empty
+	Unicast    []string // hosts to announce to directly, for the IPv4 broadcast client
+}
+
+func NewLocal(id protocol.DeviceID, addr string, addrList AddressLister, opts LocalOptions, evLogger events.Logger) (FinderService, error) {
 	c := &localClient{
 		Supervisor: suture.New("local", suture.Spec{
 			PassThroughPanics: true,
@@ -76,11 +82,11 @@ func NewLocal(id protocol.DeviceID, addr string, addrList AddressLister, evLogge
 		if err != nil {
 			return nil, err
 		}
-		c.beacon = beacon.NewBroadcast(bcPort)
+		c.beacon = beacon.NewBroadcast(bcPort, opts.Interfaces, opts.Unicast)
 	} else {
 		// A multicast client
 		c.name = "IPv6 local"
-		c.beacon = beacon.NewMulticast(addr)
+		c.beacon = beacon.NewMulticast(addr, opts.Interfaces)
 	}
 	c.Add(c.beacon)
 	c.Add(util.AsService(c.recvAnnouncements, fmt.Sprintf("%s/recv", c)))
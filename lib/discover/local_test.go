@@ -16,7 +16,7 @@ import (
 )
 
 func TestLocalInstanceID(t *testing.T) {
-	c, err := NewLocal(protocol.LocalDeviceID, ":0", &fakeAddressLister{}, events.NoopLogger)
+	c, err := NewLocal(protocol.LocalDeviceID, ":0", &fakeAddressLister{}, LocalOptions{}, events.NoopLogger)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -39,7 +39,7 @@ func TestLocalInstanceID(t *testing.T) {
 }
 
 func TestLocalInstanceIDShouldTriggerNew(t *testing.T) {
-	c, err := NewLocal(protocol.LocalDeviceID, ":0", &fakeAddressLister{}, events.NoopLogger)
+	c, err := NewLocal(protocol.LocalDeviceID, ":0", &fakeAddressLister{}, LocalOptions{}, events.NoopLogger)
 	if err != nil {
 		t.Fatal(err)
 	}
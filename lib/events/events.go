@@ -13,6 +13,7 @@ import (
 	"errors"
 	"fmt"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/thejerf/suture"
@@ -52,6 +53,15 @@ const (
 	FolderWatchStateChanged
 	ListenAddressesChanged
 	LoginAttempt
+	AggregateSummary
+	FolderMismatch
+	ClockSkewDetected
+	DatabaseRepaired
+	FolderOperationProgress
+	FolderOperationFinished
+	EventOverflow
+	CompletionSummary
+	ConnectionRejected
 
 	AllEvents = (1 << iota) - 1
 )
@@ -121,6 +131,24 @@ func (t EventType) String() string {
 		return "LoginAttempt"
 	case FolderWatchStateChanged:
 		return "FolderWatchStateChanged"
+	case AggregateSummary:
+		return "AggregateSummary"
+	case FolderMismatch:
+		return "FolderMismatch"
+	case ClockSkewDetected:
+		return "ClockSkewDetected"
+	case DatabaseRepaired:
+		return "DatabaseRepaired"
+	case FolderOperationProgress:
+		return "FolderOperationProgress"
+	case FolderOperationFinished:
+		return "FolderOperationFinished"
+	case EventOverflow:
+		return "EventOverflow"
+	case CompletionSummary:
+		return "CompletionSummary"
+	case ConnectionRejected:
+		return "ConnectionRejected"
 	default:
 		return "Unknown"
 	}
@@ -200,6 +228,24 @@ func UnmarshalEventType(s string) EventType {
 		return LoginAttempt
 	case "FolderWatchStateChanged":
 		return FolderWatchStateChanged
+	case "AggregateSummary":
+		return AggregateSummary
+	case "FolderMismatch":
+		return FolderMismatch
+	case "ClockSkewDetected":
+		return ClockSkewDetected
+	case "DatabaseRepaired":
+		return DatabaseRepaired
+	case "FolderOperationProgress":
+		return FolderOperationProgress
+	case "FolderOperationFinished":
+		return FolderOperationFinished
+	case "EventOverflow":
+		return EventOverflow
+	case "CompletionSummary":
+		return CompletionSummary
+	case "ConnectionRejected":
+		return ConnectionRejected
 	default:
 		return 0
 	}
@@ -207,10 +253,34 @@ func UnmarshalEventType(s string) EventType {
 
 const BufferSize = 64
 
+const (
+	// A subscription with this many events waiting counts as falling
+	// behind, before events are dropped when the buffer is full.
+	softBacklogLimit = BufferSize * 3 / 4
+	// How often an EventOverflow event is sent at most.
+	overflowReportInterval = 10 * time.Second
+)
+
 type Logger interface {
 	suture.Service
 	Log(t EventType, data interface{})
 	Subscribe(mask EventType) Subscription
+	Subscribers() []SubscriberInfo
+}
+
+// SubscriberInfo describes a subscription, for diagnosing the ones that
+// don't keep up with the events.
+type SubscriberInfo struct {
+	Subscriber   string         `json:"subscriber"` // the function that subscribed
+	Since        time.Time      `json:"since"`
+	Types        []EventType    `json:"types"`
+	Delivered    int            `json:"delivered"`
+	Dropped      int            `json:"dropped"`
+	DroppedTypes map[string]int `json:"droppedTypes"`
+	LastDropped  time.Time      `json:"lastDropped"`
+	Backlog      int            `json:"backlog"`    // events waiting now
+	MaxBacklog   int            `json:"maxBacklog"` // the most events ever waiting
+	Behind       int            `json:"behind"`     // times the backlog went over the soft limit
 }
 
 type logger struct {
@@ -223,6 +293,7 @@ type logger struct {
 	funcs               chan func(context.Context)
 	toUnsubscribe       chan *subscription
 	stop                chan struct{}
+	lastOverflowReport  time.Time
 }
 
 type Event struct {
@@ -247,6 +318,11 @@ type subscription struct {
 	toUnsubscribe chan *subscription
 	timeout       *time.Timer
 	ctx           context.Context
+
+	// Diagnostics, only accessed from the logger's serve goroutine
+	info          SubscriberInfo
+	reportedDrops int
+	overSoftLimit bool
 }
 
 var (
@@ -313,6 +389,7 @@ func (l *logger) sendEvent(e Event) {
 
 	e.GlobalID = l.nextGlobalID
 
+	overflowed := false
 	for i, s := range l.subs {
 		if s.mask&e.Type != 0 {
 			e.SubscriptionID = l.nextSubscriptionIDs[i]
@@ -334,14 +411,74 @@ func (l *logger) sendEvent(e Event) {
 			if !l.timeout.Stop() && !timedOut {
 				<-l.timeout.C
 			}
+
+			if s.account(e, timedOut) {
+				overflowed = true
+			}
 		}
 	}
+
+	// The overflow event is sent after the event that caused it, and drops
+	// of it don't cause further ones. It's one for all subscriptions, to
+	// not make things worse for them.
+	if e.Type == EventOverflow || !overflowed {
+		return
+	}
+	now := time.Now()
+	if now.Sub(l.lastOverflowReport) < overflowReportInterval {
+		return
+	}
+	l.lastOverflowReport = now
+	subscribers := make([]map[string]interface{}, 0, len(l.subs))
+	for _, s := range l.subs {
+		if s.info.Dropped == s.reportedDrops {
+			continue
+		}
+		dl.Debugf("subscription of %s dropped %d events", s.info.Subscriber, s.info.Dropped-s.reportedDrops)
+		subscribers = append(subscribers, map[string]interface{}{
+			"subscriber":   s.info.Subscriber,
+			"dropped":      s.info.Dropped - s.reportedDrops,
+			"totalDropped": s.info.Dropped,
+		})
+		s.reportedDrops = s.info.Dropped
+	}
+	l.sendEvent(Event{
+		Time: now,
+		Type: EventOverflow,
+		Data: map[string]interface{}{
+			"subscribers": subscribers,
+			"lastType":    e.Type,
+		},
+	})
+}
+
+// account records the delivery or drop of the event, returning whether it
+// was dropped.
+func (s *subscription) account(e Event, dropped bool) bool {
+	if dropped {
+		s.info.Dropped++
+		s.info.DroppedTypes[e.Type.String()]++
+		s.info.LastDropped = e.Time
+		return true
+	}
+	s.info.Delivered++
+	backlog := len(s.events)
+	if backlog > s.info.MaxBacklog {
+		s.info.MaxBacklog = backlog
+	}
+	if backlog >= softBacklogLimit && !s.overSoftLimit {
+		s.info.Behind++
+		dl.Debugf("subscription of %s is falling behind, %d events waiting", s.info.Subscriber, backlog)
+	}
+	s.overSoftLimit = backlog >= softBacklogLimit
+	return false
 }
 
 func (l *logger) Subscribe(mask EventType) Subscription {
+	subscriber := callerName(2)
 	res := make(chan Subscription)
 	l.funcs <- func(ctx context.Context) {
-		dl.Debugln("subscribe", mask)
+		dl.Debugln("subscribe", mask, "by", subscriber)
 
 		s := &subscription{
 			mask:          mask,
@@ -349,6 +486,12 @@ func (l *logger) Subscribe(mask EventType) Subscription {
 			toUnsubscribe: l.toUnsubscribe,
 			timeout:       time.NewTimer(0),
 			ctx:           ctx,
+			info: SubscriberInfo{
+				Subscriber:   subscriber,
+				Since:        time.Now(),
+				Types:        maskTypes(mask),
+				DroppedTypes: make(map[string]int),
+			},
 		}
 
 		// We need to create the timeout timer in the stopped, non-fired state so
@@ -372,6 +515,51 @@ func (l *logger) Subscribe(mask EventType) Subscription {
 	return <-res
 }
 
+// Subscribers returns the current subscriptions, with how well they keep
+// up with the events.
+func (l *logger) Subscribers() []SubscriberInfo {
+	res := make(chan []SubscriberInfo)
+	l.funcs <- func(ctx context.Context) {
+		infos := make([]SubscriberInfo, len(l.subs))
+		for i, s := range l.subs {
+			info := s.info
+			info.Backlog = len(s.events)
+			info.DroppedTypes = make(map[string]int, len(s.info.DroppedTypes))
+			for t, n := range s.info.DroppedTypes {
+				info.DroppedTypes[t] = n
+			}
+			infos[i] = info
+		}
+		res <- infos
+	}
+	return <-res
+}
+
+// callerName returns the name of the function the given number of frames
+// up the stack, without the module path.
+func callerName(skip int) string {
+	pc, _, _, ok := runtime.Caller(skip)
+	if !ok {
+		return "unknown"
+	}
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return "unknown"
+	}
+	return strings.TrimPrefix(fn.Name(), "github.com/syncthing/syncthing/")
+}
+
+// maskTypes returns the event types in the mask.
+func maskTypes(mask EventType) []EventType {
+	var types []EventType
+	for t := EventType(1); t != 0 && t <= AllEvents; t <<= 1 {
+		if mask&t != 0 {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
 func (l *logger) unsubscribe(s *subscription) {
 	dl.Debugln("unsubscribe", s.mask)
 	for i, ss := range l.subs {
@@ -530,6 +718,10 @@ func (*noopLogger) Subscribe(mask EventType) Subscription {
 	return &noopSubscription{}
 }
 
+func (*noopLogger) Subscribers() []SubscriberInfo {
+	return nil
+}
+
 type noopSubscription struct{}
 
 func (*noopSubscription) C() <-chan Event {
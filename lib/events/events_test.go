@@ -9,6 +9,7 @@ package events
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -134,6 +135,49 @@ func TestBufferOverflow(t *testing.T) {
 	}
 }
 
+func TestOverflowDiagnostics(t *testing.T) {
+	l := NewLogger()
+	defer l.Stop()
+	go l.Serve()
+
+	slow := l.Subscribe(AllEvents &^ EventOverflow)
+	defer slow.Unsubscribe()
+	watcher := l.Subscribe(EventOverflow)
+	defer watcher.Unsubscribe()
+
+	// The last one doesn't fit in the buffer of the slow subscription.
+	for i := 0; i < BufferSize+1; i++ {
+		l.Log(DeviceConnected, "foo")
+	}
+
+	ev, err := watcher.Poll(timeout)
+	if err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	data := ev.Data.(map[string]interface{})
+	subscribers := data["subscribers"].([]map[string]interface{})
+	if len(subscribers) != 1 || subscribers[0]["dropped"] != 1 || !strings.Contains(subscribers[0]["subscriber"].(string), "TestOverflowDiagnostics") {
+		t.Errorf("Unexpected overflow event data %v", data)
+	}
+
+	subs := l.Subscribers()
+	if len(subs) != 2 {
+		t.Fatalf("Expected 2 subscribers, got %d", len(subs))
+	}
+	for _, sub := range subs {
+		if len(sub.Types) == 1 {
+			// The watcher
+			continue
+		}
+		if sub.Delivered != BufferSize || sub.Dropped != 1 || sub.DroppedTypes["DeviceConnected"] != 1 {
+			t.Errorf("Unexpected counts for the slow subscription: %+v", sub)
+		}
+		if sub.Backlog != BufferSize || sub.Behind != 1 {
+			t.Errorf("Unexpected backlog for the slow subscription: %+v", sub)
+		}
+	}
+}
+
 func TestUnsubscribe(t *testing.T) {
 	l := NewLogger()
 	defer l.Stop()
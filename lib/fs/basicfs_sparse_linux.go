@@ -0,0 +1,24 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+func (f basicFile) PunchHole(offset, size int64) error {
+	err := unix.Fallocate(int(f.Fd()), unix.FALLOC_FL_PUNCH_HOLE|unix.FALLOC_FL_KEEP_SIZE, offset, size)
+	if err == unix.EOPNOTSUPP || err == unix.ENOSYS {
+		err = errPunchHoleNotSupported
+	}
+	if err != nil {
+		return &os.PathError{Op: "fallocate", Path: f.name, Err: err}
+	}
+	return nil
+}
@@ -7,9 +7,12 @@
 package fs
 
 import (
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"sort"
 	"strings"
@@ -572,3 +575,85 @@ func TestBasicWalkSkipSymlink(t *testing.T) {
 	defer os.RemoveAll(dir)
 	testWalkSkipSymlink(t, FilesystemTypeBasic, dir)
 }
+
+type prefixXattrFilter string
+
+func (p prefixXattrFilter) Permit(name string) bool {
+	return strings.HasPrefix(name, string(p))
+}
+
+func TestXattr(t *testing.T) {
+	fs, dir := setup(t)
+	defer os.RemoveAll(dir)
+
+	if fd, err := fs.Create("file"); err != nil {
+		t.Fatal(err)
+	} else {
+		fd.Close()
+	}
+
+	filter := prefixXattrFilter("user.synced")
+	xattrs := []Xattr{
+		{Name: "user.synced.a", Value: []byte("a")},
+		{Name: "user.synced.b", Value: []byte("b")},
+	}
+	if err := fs.SetXattr("file", xattrs, filter); errors.Is(err, ErrXattrsNotSupported) {
+		t.Skip(err)
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.SetXattr("file", []Xattr{{Name: "user.other", Value: []byte("other")}}, prefixXattrFilter("user.other")); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := fs.GetXattr("file", filter); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(got, xattrs) {
+		t.Errorf("Unexpected attributes %v, expected %v", got, xattrs)
+	}
+
+	// Only what the filter permits is removed.
+	if err := fs.SetXattr("file", xattrs[1:], filter); err != nil {
+		t.Fatal(err)
+	}
+	expected := []Xattr{{Name: "user.other", Value: []byte("other")}, xattrs[1]}
+	if got, err := fs.GetXattr("file", nil); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected attributes %v, expected %v", got, expected)
+	}
+
+	// Attributes the filter denies are neither set nor changed, even when
+	// given.
+	denied := []Xattr{
+		{Name: "user.other", Value: []byte("changed")},
+		{Name: "user.new", Value: []byte("new")},
+		xattrs[1],
+	}
+	if err := fs.SetXattr("file", denied, filter); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := fs.GetXattr("file", nil); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected attributes %v, expected %v", got, expected)
+	}
+
+	// Nor are attributes over the size limits.
+	large := []Xattr{{Name: "user.synced.large", Value: make([]byte, MaxXattrValueSize+1)}}
+	if err := fs.SetXattr("file", large, filter); !errors.Is(err, ErrXattrsTooLarge) {
+		t.Errorf("Unexpected error setting a large attribute: %v", err)
+	}
+	var many []Xattr
+	for i := 0; i <= MaxXattrsSize/MaxXattrValueSize; i++ {
+		many = append(many, Xattr{Name: fmt.Sprintf("user.synced.%d", i), Value: make([]byte, MaxXattrValueSize)})
+	}
+	if err := fs.SetXattr("file", many, filter); !errors.Is(err, ErrXattrsTooLarge) {
+		t.Errorf("Unexpected error setting many attributes: %v", err)
+	}
+	if got, err := fs.GetXattr("file", nil); err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected attributes %v, expected %v", got, expected)
+	}
+}
@@ -0,0 +1,12 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import "golang.org/x/sys/unix"
+
+// The error for an attribute that isn't there
+const errNoXattr = unix.ENOATTR
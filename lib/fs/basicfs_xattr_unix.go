@@ -0,0 +1,139 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build linux darwin
+
+package fs
+
+import (
+	"bytes"
+	"os"
+	"sort"
+
+	"golang.org/x/sys/unix"
+)
+
+func (f *BasicFilesystem) GetXattr(name string, filter XattrFilter) ([]Xattr, error) {
+	path, err := f.rooted(name)
+	if err != nil {
+		return nil, err
+	}
+	names, err := listXattrs(path)
+	if err != nil {
+		return nil, xattrError("listxattr", name, err)
+	}
+
+	var xattrs []Xattr
+	for _, attr := range names {
+		if !xattrPermitted(filter, attr) {
+			continue
+		}
+		value, err := getXattr(path, attr)
+		if err == errNoXattr {
+			// Removed since listed
+			continue
+		} else if err != nil {
+			return nil, xattrError("getxattr", name, err)
+		}
+		xattrs = append(xattrs, Xattr{Name: attr, Value: value})
+		if err := xattrSizeError(xattrs); err != nil {
+			return nil, xattrError("getxattr", name, err)
+		}
+	}
+	sort.Slice(xattrs, func(a, b int) bool {
+		return xattrs[a].Name < xattrs[b].Name
+	})
+	return xattrs, nil
+}
+
+func (f *BasicFilesystem) SetXattr(name string, xattrs []Xattr, filter XattrFilter) error {
+	path, err := f.rooted(name)
+	if err != nil {
+		return err
+	}
+	if err := xattrSizeError(xattrs); err != nil {
+		return xattrError("setxattr", name, err)
+	}
+	current, err := listXattrs(path)
+	if err != nil {
+		return xattrError("listxattr", name, err)
+	}
+
+	keep := make(map[string]struct{}, len(xattrs))
+	for _, xattr := range xattrs {
+		if !xattrPermitted(filter, xattr.Name) {
+			// Not ours to change, whatever the other side has.
+			continue
+		}
+		keep[xattr.Name] = struct{}{}
+		if value, err := getXattr(path, xattr.Name); err == nil && bytes.Equal(value, xattr.Value) {
+			continue
+		}
+		if err := unix.Lsetxattr(path, xattr.Name, xattr.Value, 0); err != nil {
+			return xattrError("setxattr", name, err)
+		}
+	}
+	for _, attr := range current {
+		if _, ok := keep[attr]; ok || !xattrPermitted(filter, attr) {
+			continue
+		}
+		if err := unix.Lremovexattr(path, attr); err != nil && err != errNoXattr {
+			return xattrError("removexattr", name, err)
+		}
+	}
+	return nil
+}
+
+func listXattrs(path string) ([]string, error) {
+	buf, err := readXattr(func(dest []byte) (int, error) {
+		return unix.Llistxattr(path, dest)
+	})
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, name := range bytes.Split(buf, []byte{0}) {
+		if len(name) > 0 {
+			names = append(names, string(name))
+		}
+	}
+	return names, nil
+}
+
+func getXattr(path, name string) ([]byte, error) {
+	return readXattr(func(dest []byte) (int, error) {
+		return unix.Lgetxattr(path, name, dest)
+	})
+}
+
+// readXattr gets the size first, as the calls do when given no buffer,
+// trying again when it changed in between.
+func readXattr(read func(dest []byte) (int, error)) ([]byte, error) {
+	for {
+		size, err := read(nil)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return nil, nil
+		}
+		buf := make([]byte, size)
+		n, err := read(buf)
+		if err == unix.ERANGE {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+}
+
+func xattrError(op, name string, err error) error {
+	if err == unix.ENOTSUP || err == unix.EOPNOTSUPP {
+		err = ErrXattrsNotSupported
+	}
+	return &os.PathError{Op: op, Path: name, Err: err}
+}
@@ -0,0 +1,17 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build !linux,!darwin
+
+package fs
+
+func (f *BasicFilesystem) GetXattr(name string, filter XattrFilter) ([]Xattr, error) {
+	return nil, ErrXattrsNotSupported
+}
+
+func (f *BasicFilesystem) SetXattr(name string, xattrs []Xattr, filter XattrFilter) error {
+	return ErrXattrsNotSupported
+}
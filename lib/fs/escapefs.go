@@ -0,0 +1,287 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"context"
+	"encoding/hex"
+	"runtime"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/syncthing/syncthing/lib/sha256"
+)
+
+// Characters that don't work in names are replaced by ones in this part of
+// the Unicode private use area, as is done by Cygwin and the Windows
+// Services for Macintosh: '*' becomes U+F02A and so on.
+const escapeBase = 0xF000
+
+// The escapeFS stores files with names that are invalid on this OS under an
+// escaped name that is valid, and presents them under their original name.
+// Each escaped name is recorded in the database along with the original,
+// and only recorded names are translated back; a file that just happens to
+// look like an escaped name keeps its name.
+type escapeFS struct {
+	Filesystem
+	db database
+	// Returns why a path component needs escaping, or the empty string
+	invalid func(name string) string
+}
+
+// NewEscapeFS returns a filesystem escaping names on top of the given one.
+// It does nothing but pass names through on OSes other than Windows, where
+// everything but the path separator is fine in a name.
+func NewEscapeFS(underlying Filesystem, db database) Filesystem {
+	f := &escapeFS{
+		Filesystem: underlying,
+		db:         db,
+		invalid:    func(string) string { return "" },
+	}
+	if runtime.GOOS == "windows" {
+		f.invalid = windowsInvalidName
+	}
+	// The walk uses our DirNames and Lstat, so it sees the original names.
+	return NewWalkFilesystem(f)
+}
+
+// escapeWindowsName returns a name that works on Windows for a name that
+// doesn't.
+func escapeWindowsName(name string) string {
+	rs := []rune(name)
+	for i, r := range rs {
+		if r < escapeBase && strings.ContainsRune(windowsDisallowedCharacters, r) {
+			rs[i] = escapeBase + r
+		}
+	}
+	for i := len(rs) - 1; i >= 0 && (rs[i] == ' ' || rs[i] == '.'); i-- {
+		rs[i] = escapeBase + rs[i]
+	}
+	if windowsReservedName(string(rs)) {
+		// All reserved names have at least three characters.
+		rs[2] += escapeBase
+	}
+	if len(utf16.Encode(rs)) > windowsMaxNameLen {
+		// Shorten the name, and keep it unique by a hash of it in full.
+		sum := sha256.Sum256([]byte(name))
+		suffix := []rune("~" + hex.EncodeToString(sum[:4]))
+		for len(utf16.Encode(rs)) > windowsMaxNameLen-len(suffix) {
+			rs = rs[:len(rs)-1]
+		}
+		rs = append(rs, suffix...)
+	}
+	return string(rs)
+}
+
+// couldBeEscaped returns false if the name certainly wasn't produced by
+// escapeWindowsName, saving database lookups.
+func couldBeEscaped(name string) bool {
+	if strings.IndexByte(name, '~') >= 0 {
+		return true
+	}
+	for _, r := range name {
+		if r >= escapeBase && r < escapeBase+0x80 {
+			return true
+		}
+	}
+	return false
+}
+
+// toDisk returns the name a file is stored under, recording any escaped
+// path components.
+func (f *escapeFS) toDisk(name string) string {
+	parts := strings.Split(name, string(PathSeparator))
+	changed := false
+	for i, part := range parts {
+		if f.invalid(part) == "" {
+			continue
+		}
+		escaped := escapeWindowsName(part)
+		if orig, ok, _ := f.db.Bytes(escaped); !ok || string(orig) != part {
+			if err := f.db.PutBytes(escaped, []byte(part)); err != nil {
+				l.Debugln("recording escaped name:", err)
+			}
+		}
+		parts[i] = escaped
+		changed = true
+	}
+	if !changed {
+		return name
+	}
+	return strings.Join(parts, string(PathSeparator))
+}
+
+// fromDisk returns the original name of a stored file.
+func (f *escapeFS) fromDisk(name string) string {
+	if !couldBeEscaped(name) {
+		return name
+	}
+	parts := strings.Split(name, string(PathSeparator))
+	for i, part := range parts {
+		if !couldBeEscaped(part) {
+			continue
+		}
+		if orig, ok, _ := f.db.Bytes(part); ok {
+			parts[i] = string(orig)
+		}
+	}
+	return strings.Join(parts, string(PathSeparator))
+}
+
+func (f *escapeFS) Chmod(name string, mode FileMode) error {
+	return f.Filesystem.Chmod(f.toDisk(name), mode)
+}
+
+func (f *escapeFS) Lchown(name string, uid, gid int) error {
+	return f.Filesystem.Lchown(f.toDisk(name), uid, gid)
+}
+
+func (f *escapeFS) Chtimes(name string, atime, mtime time.Time) error {
+	return f.Filesystem.Chtimes(f.toDisk(name), atime, mtime)
+}
+
+func (f *escapeFS) Create(name string) (File, error) {
+	return f.Filesystem.Create(f.toDisk(name))
+}
+
+func (f *escapeFS) CreateSymlink(target, name string) error {
+	return f.Filesystem.CreateSymlink(target, f.toDisk(name))
+}
+
+func (f *escapeFS) DirNames(name string) ([]string, error) {
+	names, err := f.Filesystem.DirNames(f.toDisk(name))
+	for i := range names {
+		names[i] = f.fromDisk(names[i])
+	}
+	return names, err
+}
+
+func (f *escapeFS) Lstat(name string) (FileInfo, error) {
+	info, err := f.Filesystem.Lstat(f.toDisk(name))
+	if err != nil {
+		return nil, err
+	}
+	return escapedFileInfo{info, f.fromDisk(info.Name())}, nil
+}
+
+func (f *escapeFS) Mkdir(name string, perm FileMode) error {
+	return f.Filesystem.Mkdir(f.toDisk(name), perm)
+}
+
+func (f *escapeFS) MkdirAll(name string, perm FileMode) error {
+	return f.Filesystem.MkdirAll(f.toDisk(name), perm)
+}
+
+func (f *escapeFS) Open(name string) (File, error) {
+	return f.Filesystem.Open(f.toDisk(name))
+}
+
+func (f *escapeFS) OpenFile(name string, flags int, mode FileMode) (File, error) {
+	return f.Filesystem.OpenFile(f.toDisk(name), flags, mode)
+}
+
+func (f *escapeFS) ReadSymlink(name string) (string, error) {
+	return f.Filesystem.ReadSymlink(f.toDisk(name))
+}
+
+func (f *escapeFS) Remove(name string) error {
+	return f.Filesystem.Remove(f.toDisk(name))
+}
+
+func (f *escapeFS) RemoveAll(name string) error {
+	return f.Filesystem.RemoveAll(f.toDisk(name))
+}
+
+func (f *escapeFS) Rename(oldname, newname string) error {
+	return f.Filesystem.Rename(f.toDisk(oldname), f.toDisk(newname))
+}
+
+func (f *escapeFS) Stat(name string) (FileInfo, error) {
+	info, err := f.Filesystem.Stat(f.toDisk(name))
+	if err != nil {
+		return nil, err
+	}
+	return escapedFileInfo{info, f.fromDisk(info.Name())}, nil
+}
+
+func (f *escapeFS) Watch(path string, ignore Matcher, ctx context.Context, ignorePerms bool) (<-chan Event, <-chan error, error) {
+	events, errs, err := f.Filesystem.Watch(f.toDisk(path), escapedMatcher{ignore, f}, ctx, ignorePerms)
+	if err != nil {
+		return nil, nil, err
+	}
+	out := make(chan Event)
+	go func() {
+		defer close(out)
+		for {
+			select {
+			case ev, ok := <-events:
+				if !ok {
+					return
+				}
+				ev.Name = f.fromDisk(ev.Name)
+				select {
+				case out <- ev:
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, errs, nil
+}
+
+func (f *escapeFS) Hide(name string) error {
+	return f.Filesystem.Hide(f.toDisk(name))
+}
+
+func (f *escapeFS) Unhide(name string) error {
+	return f.Filesystem.Unhide(f.toDisk(name))
+}
+
+func (f *escapeFS) Glob(pattern string) ([]string, error) {
+	names, err := f.Filesystem.Glob(pattern)
+	for i := range names {
+		names[i] = f.fromDisk(names[i])
+	}
+	return names, err
+}
+
+func (f *escapeFS) Usage(name string) (Usage, error) {
+	return f.Filesystem.Usage(f.toDisk(name))
+}
+
+func (f *escapeFS) GetXattr(name string, filter XattrFilter) ([]Xattr, error) {
+	return f.Filesystem.GetXattr(f.toDisk(name), filter)
+}
+
+func (f *escapeFS) SetXattr(name string, xattrs []Xattr, filter XattrFilter) error {
+	return f.Filesystem.SetXattr(f.toDisk(name), xattrs, filter)
+}
+
+// escapedFileInfo presents a file under its original name.
+type escapedFileInfo struct {
+	FileInfo
+	name string
+}
+
+func (fi escapedFileInfo) Name() string {
+	return fi.name
+}
+
+// escapedMatcher lets the watcher match ignore patterns against the
+// original names.
+type escapedMatcher struct {
+	Matcher
+	fs *escapeFS
+}
+
+func (m escapedMatcher) ShouldIgnore(name string) bool {
+	return m.Matcher.ShouldIgnore(m.fs.fromDisk(name))
+}
@@ -0,0 +1,140 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestWindowsFilenameError(t *testing.T) {
+	cases := []struct {
+		name    string
+		invalid bool
+	}{
+		{"file.txt", false},
+		{"con", true},
+		{"CON.txt", true},
+		{"console", false},
+		{"LPT1", true},
+		{"LPT0", false},
+		{"trailing.", true},
+		{"trailing ", true},
+		{"a*b", true},
+		{"a:b", true},
+		{strings.Repeat("a", windowsMaxNameLen), false},
+		{strings.Repeat("a", windowsMaxNameLen+1), true},
+		{filepath.Join("dir", "NUL"), true},
+		{filepath.Join("nul.d", "file"), true},
+	}
+
+	for _, tc := range cases {
+		err := WindowsFilenameError(tc.name)
+		if (err != nil) != tc.invalid {
+			t.Errorf("%q: got %v, expected invalid %v", tc.name, err, tc.invalid)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidFilename) {
+			t.Errorf("%q: %v is not an ErrInvalidFilename", tc.name, err)
+		}
+	}
+}
+
+func TestEscapeWindowsName(t *testing.T) {
+	long := strings.Repeat("a", 300)
+	names := []string{"CON", "con.txt", "a*b?", "trailing. .", "\x01", long, long + "b"}
+	seen := make(map[string]string)
+	for _, name := range names {
+		escaped := escapeWindowsName(name)
+		if reason := windowsInvalidName(escaped); reason != "" {
+			t.Errorf("%q escaped to %q, which %s", name, escaped, reason)
+		}
+		if !couldBeEscaped(escaped) {
+			t.Errorf("%q escaped to %q, which isn't recognized", name, escaped)
+		}
+		if len(utf16.Encode([]rune(escaped))) > windowsMaxNameLen {
+			t.Errorf("%q escaped to a name that is too long", name)
+		}
+		if prev, ok := seen[escaped]; ok {
+			t.Errorf("%q and %q both escaped to %q", prev, name, escaped)
+		}
+		seen[escaped] = name
+	}
+}
+
+func TestEscapeFS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	underlying := NewFilesystem(FilesystemTypeBasic, dir)
+	efs := NewEscapeFS(underlying, make(mapStore))
+	efs.(*walkFilesystem).Filesystem.(*escapeFS).invalid = windowsInvalidName
+
+	// A file that looks escaped, but wasn't, keeps its name.
+	lookalike := "x\uF02A"
+	if err := ioutil.WriteFile(filepath.Join(dir, lookalike), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := efs.MkdirAll("a*b", 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{filepath.Join("a*b", "CON.txt"), "trailing.", "ok"} {
+		fd, err := efs.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fd.Close()
+	}
+
+	// On disk, the names are escaped.
+	for _, name := range []string{"a\uF02Ab", filepath.Join("a\uF02Ab", "CO\uF04E.txt"), "trailing\uF02E", "ok"} {
+		if _, err := underlying.Lstat(name); err != nil {
+			t.Errorf("On disk: %v", err)
+		}
+	}
+
+	// Through the escaping fs, they have their original names.
+	var walked []string
+	err = efs.Walk(".", func(path string, info FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != "." && info.Name() != filepath.Base(path) {
+			t.Errorf("%q: lstat name %q", path, info.Name())
+		}
+		walked = append(walked, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{".", "a*b", filepath.Join("a*b", "CON.txt"), "ok", "trailing.", lookalike}
+	sort.Strings(walked)
+	sort.Strings(expected)
+	if strings.Join(walked, "|") != strings.Join(expected, "|") {
+		t.Errorf("Walked %q, expected %q", walked, expected)
+	}
+
+	if err := efs.Rename("trailing.", "AUX"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := efs.Lstat("AUX"); err != nil {
+		t.Error(err)
+	}
+	if _, err := underlying.Lstat("AU\uF058"); err != nil {
+		t.Error(err)
+	}
+}
@@ -49,12 +49,24 @@ const randomBlockShift = 14 // 128k
 //     seed=n     to set the initial random seed (default 0)
 //     insens=b   "true" makes filesystem case-insensitive Windows- or OSX-style (default false)
 //
+// - Faults can be injected with more such parameters, or changed at runtime
+//   with SetFakeFaults (see FakeFaults):
+//
+//     latency=d      to add latency d (like "10ms") to each operation (default 0)
+//     latencydist=s  "fixed", "uniform" or "exponential" (default fixed)
+//     errorrate=f    to fail a share f of operations with an I/O error (default 0)
+//     diskfull=b     "true" makes writes fail for lack of space (default false)
+//
 // - Two fakefs:s pointing at the same root path see the same files.
 //
 type fakefs struct {
 	mut    sync.Mutex
 	root   *fakeEntry
 	insens bool
+
+	faultMut sync.Mutex
+	faults   FakeFaults
+	faultRng *rand.Rand
 }
 
 var (
@@ -128,6 +140,10 @@ func newFakeFilesystem(root string) *fakefs {
 	// Also create a default folder marker for good measure
 	fs.Mkdir(".stfolder", 0700)
 
+	// Faults only from here on, not while setting up
+	fs.faults = parseFakeFaults(params)
+	fs.faultRng = rand.New(rand.NewSource(int64(seed)))
+
 	fakefsFs[root] = fs
 	return fs
 }
@@ -181,6 +197,9 @@ func (fs *fakefs) entryForName(name string) *fakeEntry {
 }
 
 func (fs *fakefs) Chmod(name string, mode FileMode) error {
+	if err := fs.fault("chmod", name); err != nil {
+		return err
+	}
 	fs.mut.Lock()
 	defer fs.mut.Unlock()
 	entry := fs.entryForName(name)
@@ -192,6 +211,9 @@ func (fs *fakefs) Chmod(name string, mode FileMode) error {
 }
 
 func (fs *fakefs) Lchown(name string, uid, gid int) error {
+	if err := fs.fault("lchown", name); err != nil {
+		return err
+	}
 	fs.mut.Lock()
 	defer fs.mut.Unlock()
 	entry := fs.entryForName(name)
@@ -204,6 +226,9 @@ func (fs *fakefs) Lchown(name string, uid, gid int) error {
 }
 
 func (fs *fakefs) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	if err := fs.fault("chtimes", name); err != nil {
+		return err
+	}
 	fs.mut.Lock()
 	defer fs.mut.Unlock()
 	entry := fs.entryForName(name)
@@ -251,17 +276,23 @@ func (fs *fakefs) create(name string) (*fakeEntry, error) {
 }
 
 func (fs *fakefs) Create(name string) (File, error) {
+	if err := fs.full("open", name); err != nil {
+		return nil, err
+	}
 	entry, err := fs.create(name)
 	if err != nil {
 		return nil, err
 	}
 	if fs.insens {
-		return &fakeFile{fakeEntry: entry, presentedName: filepath.Base(name)}, nil
+		return &fakeFile{fakeEntry: entry, fs: fs, presentedName: filepath.Base(name)}, nil
 	}
-	return &fakeFile{fakeEntry: entry}, nil
+	return &fakeFile{fakeEntry: entry, fs: fs}, nil
 }
 
 func (fs *fakefs) CreateSymlink(target, name string) error {
+	if err := fs.full("symlink", name); err != nil {
+		return err
+	}
 	entry, err := fs.create(name)
 	if err != nil {
 		return err
@@ -272,6 +303,9 @@ func (fs *fakefs) CreateSymlink(target, name string) error {
 }
 
 func (fs *fakefs) DirNames(name string) ([]string, error) {
+	if err := fs.fault("readdir", name); err != nil {
+		return nil, err
+	}
 	fs.mut.Lock()
 	defer fs.mut.Unlock()
 
@@ -289,6 +323,9 @@ func (fs *fakefs) DirNames(name string) ([]string, error) {
 }
 
 func (fs *fakefs) Lstat(name string) (FileInfo, error) {
+	if err := fs.fault("lstat", name); err != nil {
+		return nil, err
+	}
 	fs.mut.Lock()
 	defer fs.mut.Unlock()
 
@@ -306,6 +343,9 @@ func (fs *fakefs) Lstat(name string) (FileInfo, error) {
 }
 
 func (fs *fakefs) Mkdir(name string, perm FileMode) error {
+	if err := fs.full("mkdir", name); err != nil {
+		return err
+	}
 	fs.mut.Lock()
 	defer fs.mut.Unlock()
 
@@ -338,6 +378,9 @@ func (fs *fakefs) Mkdir(name string, perm FileMode) error {
 }
 
 func (fs *fakefs) MkdirAll(name string, perm FileMode) error {
+	if err := fs.full("mkdir", name); err != nil {
+		return err
+	}
 	name = filepath.ToSlash(name)
 	name = strings.Trim(name, "/")
 	comps := strings.Split(name, "/")
@@ -370,6 +413,9 @@ func (fs *fakefs) MkdirAll(name string, perm FileMode) error {
 }
 
 func (fs *fakefs) Open(name string) (File, error) {
+	if err := fs.fault("open", name); err != nil {
+		return nil, err
+	}
 	fs.mut.Lock()
 	defer fs.mut.Unlock()
 
@@ -379,15 +425,18 @@ func (fs *fakefs) Open(name string) (File, error) {
 	}
 
 	if fs.insens {
-		return &fakeFile{fakeEntry: entry, presentedName: filepath.Base(name)}, nil
+		return &fakeFile{fakeEntry: entry, fs: fs, presentedName: filepath.Base(name)}, nil
 	}
-	return &fakeFile{fakeEntry: entry}, nil
+	return &fakeFile{fakeEntry: entry, fs: fs}, nil
 }
 
 func (fs *fakefs) OpenFile(name string, flags int, mode FileMode) (File, error) {
 	if flags&os.O_CREATE == 0 {
 		return fs.Open(name)
 	}
+	if err := fs.full("open", name); err != nil {
+		return nil, err
+	}
 
 	fs.mut.Lock()
 	defer fs.mut.Unlock()
@@ -419,10 +468,13 @@ func (fs *fakefs) OpenFile(name string, flags int, mode FileMode) (File, error)
 	}
 
 	entry.children[key] = newEntry
-	return &fakeFile{fakeEntry: newEntry}, nil
+	return &fakeFile{fakeEntry: newEntry, fs: fs}, nil
 }
 
 func (fs *fakefs) ReadSymlink(name string) (string, error) {
+	if err := fs.fault("readlink", name); err != nil {
+		return "", err
+	}
 	fs.mut.Lock()
 	defer fs.mut.Unlock()
 
@@ -436,6 +488,9 @@ func (fs *fakefs) ReadSymlink(name string) (string, error) {
 }
 
 func (fs *fakefs) Remove(name string) error {
+	if err := fs.fault("remove", name); err != nil {
+		return err
+	}
 	fs.mut.Lock()
 	defer fs.mut.Unlock()
 
@@ -457,6 +512,9 @@ func (fs *fakefs) Remove(name string) error {
 }
 
 func (fs *fakefs) RemoveAll(name string) error {
+	if err := fs.fault("removeall", name); err != nil {
+		return err
+	}
 	fs.mut.Lock()
 	defer fs.mut.Unlock()
 
@@ -476,6 +534,9 @@ func (fs *fakefs) RemoveAll(name string) error {
 }
 
 func (fs *fakefs) Rename(oldname, newname string) error {
+	if err := fs.fault("rename", oldname); err != nil {
+		return err
+	}
 	fs.mut.Lock()
 	defer fs.mut.Unlock()
 
@@ -557,6 +618,12 @@ func (fs *fakefs) Roots() ([]string, error) {
 }
 
 func (fs *fakefs) Usage(name string) (Usage, error) {
+	fs.faultMut.Lock()
+	full := fs.faults.DiskFull
+	fs.faultMut.Unlock()
+	if full {
+		return Usage{Free: 0, Total: 1 << 40}, nil
+	}
 	return Usage{}, errors.New("not implemented")
 }
 
@@ -583,10 +650,19 @@ func (fs *fakefs) SameFile(fi1, fi2 FileInfo) bool {
 	return ok && fi1.ModTime().Equal(fi2.ModTime()) && fi1.Mode() == fi2.Mode() && fi1.IsDir() == fi2.IsDir() && fi1.IsRegular() == fi2.IsRegular() && fi1.IsSymlink() == fi2.IsSymlink() && fi1.Owner() == fi2.Owner() && fi1.Group() == fi2.Group()
 }
 
+func (fs *fakefs) GetXattr(name string, filter XattrFilter) ([]Xattr, error) {
+	return nil, ErrXattrsNotSupported
+}
+
+func (fs *fakefs) SetXattr(name string, xattrs []Xattr, filter XattrFilter) error {
+	return ErrXattrsNotSupported
+}
+
 // fakeFile is the representation of an open file. We don't care if it's
 // opened for reading or writing, it's all good.
 type fakeFile struct {
 	*fakeEntry
+	fs            *fakefs
 	mut           sync.Mutex
 	rng           io.Reader
 	seed          int64
@@ -600,12 +676,18 @@ func (f *fakeFile) Close() error {
 }
 
 func (f *fakeFile) Read(p []byte) (int, error) {
+	if err := f.fs.fault("read", f.name); err != nil {
+		return 0, err
+	}
 	f.mut.Lock()
 	defer f.mut.Unlock()
 	return f.readShortAt(p, f.offset)
 }
 
 func (f *fakeFile) ReadAt(p []byte, offs int64) (int, error) {
+	if err := f.fs.fault("read", f.name); err != nil {
+		return 0, err
+	}
 	f.mut.Lock()
 	defer f.mut.Unlock()
 
@@ -739,6 +821,9 @@ func (f *fakeFile) Write(p []byte) (int, error) {
 }
 
 func (f *fakeFile) WriteAt(p []byte, off int64) (int, error) {
+	if err := f.fs.full("write", f.name); err != nil {
+		return 0, err
+	}
 	f.mut.Lock()
 	defer f.mut.Unlock()
 
@@ -762,6 +847,9 @@ func (f *fakeFile) Name() string {
 }
 
 func (f *fakeFile) Truncate(size int64) error {
+	if err := f.fs.full("truncate", f.name); err != nil {
+		return err
+	}
 	f.mut.Lock()
 	defer f.mut.Unlock()
 
@@ -0,0 +1,140 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// FakeFaults are the faults injected by a fake filesystem, to see how
+// things hold up against slow or failing storage. They are set with
+// parameters in the root path at creation (see fakefs), or changed later
+// with SetFakeFaults.
+type FakeFaults struct {
+	// Latency is added to each operation, as is or on average depending
+	// on the distribution.
+	Latency             time.Duration
+	LatencyDistribution LatencyDistribution
+	// ErrorRate is the share of operations, between 0 and 1, that fail
+	// with an I/O error.
+	ErrorRate float64
+	// DiskFull makes everything that would take more space fail.
+	DiskFull bool
+}
+
+// A LatencyDistribution is how the latency of a fake filesystem varies
+// between operations.
+type LatencyDistribution int
+
+const (
+	LatencyFixed       LatencyDistribution = iota // always Latency
+	LatencyUniform                                // between zero and twice Latency
+	LatencyExponential                            // mostly short, sometimes much longer
+)
+
+func (d LatencyDistribution) String() string {
+	switch d {
+	case LatencyFixed:
+		return "fixed"
+	case LatencyUniform:
+		return "uniform"
+	case LatencyExponential:
+		return "exponential"
+	default:
+		return "unknown"
+	}
+}
+
+func parseLatencyDistribution(s string) (LatencyDistribution, error) {
+	switch s {
+	case "", "fixed":
+		return LatencyFixed, nil
+	case "uniform":
+		return LatencyUniform, nil
+	case "exponential":
+		return LatencyExponential, nil
+	default:
+		return LatencyFixed, errors.New("unknown latency distribution " + s)
+	}
+}
+
+// parseFakeFaults reads the faults from the root path parameters, leaving
+// out the ones that don't parse.
+func parseFakeFaults(params url.Values) FakeFaults {
+	var faults FakeFaults
+	faults.Latency, _ = time.ParseDuration(params.Get("latency"))
+	faults.LatencyDistribution, _ = parseLatencyDistribution(params.Get("latencydist"))
+	faults.ErrorRate, _ = strconv.ParseFloat(params.Get("errorrate"), 64)
+	faults.DiskFull = params.Get("diskfull") == "true"
+	return faults
+}
+
+// SetFakeFaults changes the faults injected by the fake filesystem at the
+// given root, which must already exist.
+func SetFakeFaults(root string, faults FakeFaults) error {
+	if uri, err := url.Parse(root); err == nil {
+		root = uri.Path
+	}
+
+	fakefsMut.Lock()
+	fs, ok := fakefsFs[root]
+	fakefsMut.Unlock()
+	if !ok {
+		return errors.New("no fake filesystem at " + root)
+	}
+
+	fs.faultMut.Lock()
+	fs.faults = faults
+	fs.faultMut.Unlock()
+	return nil
+}
+
+// fault waits the latency for an operation, and returns an error if it is
+// to fail.
+func (fs *fakefs) fault(op, name string) error {
+	fs.faultMut.Lock()
+	faults := fs.faults
+	latency := faults.Latency
+	if latency > 0 {
+		switch faults.LatencyDistribution {
+		case LatencyUniform:
+			latency = time.Duration(fs.faultRng.Int63n(2*int64(latency) + 1))
+		case LatencyExponential:
+			latency = time.Duration(fs.faultRng.ExpFloat64() * float64(latency))
+		}
+	}
+	failed := faults.ErrorRate > 0 && fs.faultRng.Float64() < faults.ErrorRate
+	fs.faultMut.Unlock()
+
+	if latency > 0 {
+		time.Sleep(latency)
+	}
+	if failed {
+		return &os.PathError{Op: op, Path: name, Err: syscall.EIO}
+	}
+	return nil
+}
+
+// full returns an error if the disk is full, as well as any fault for the
+// operation.
+func (fs *fakefs) full(op, name string) error {
+	if err := fs.fault(op, name); err != nil {
+		return err
+	}
+	fs.faultMut.Lock()
+	full := fs.faults.DiskFull
+	fs.faultMut.Unlock()
+	if full {
+		return &os.PathError{Op: op, Path: name, Err: syscall.ENOSPC}
+	}
+	return nil
+}
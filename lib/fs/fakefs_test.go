@@ -16,6 +16,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"sort"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -908,3 +909,49 @@ func cleanup(fs Filesystem) error {
 
 	return nil
 }
+
+func TestFakeFSFaults(t *testing.T) {
+	fs := newFakeFilesystem("/TestFakeFSFaults?latency=20ms&errorrate=0.5&seed=1")
+
+	t0 := time.Now()
+	failed := 0
+	for i := 0; i < 20; i++ {
+		if _, err := fs.Lstat("."); err != nil {
+			if pe, ok := err.(*os.PathError); !ok || pe.Err != syscall.EIO {
+				t.Fatal("Unexpected error:", err)
+			}
+			failed++
+		}
+	}
+	if d := time.Since(t0); d < 20*20*time.Millisecond {
+		t.Errorf("Expected latency, took %v", d)
+	}
+	if failed == 0 || failed == 20 {
+		t.Errorf("Expected about half to fail, %d of 20 did", failed)
+	}
+
+	// Disk full, without the other faults
+	if err := SetFakeFaults("/TestFakeFSFaults", FakeFaults{DiskFull: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Mkdir("dir", 0755); err == nil || err.(*os.PathError).Err != syscall.ENOSPC {
+		t.Error("Expected mkdir to fail for lack of space, got", err)
+	}
+	if _, err := fs.Lstat(".stfolder"); err != nil {
+		t.Error("Expected lstat to work with a full disk, got", err)
+	}
+	if usage, err := fs.Usage("."); err != nil || usage.Free != 0 {
+		t.Errorf("Expected no free space, got %v, %v", usage, err)
+	}
+
+	if err := SetFakeFaults("/TestFakeFSFaults", FakeFaults{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Mkdir("dir", 0755); err != nil {
+		t.Error(err)
+	}
+
+	if err := SetFakeFaults("/nonexistent", FakeFaults{}); err == nil {
+		t.Error("Expected error setting faults on nonexistent filesystem")
+	}
+}
@@ -48,6 +48,10 @@ type Filesystem interface {
 	Type() FilesystemType
 	URI() string
 	SameFile(fi1, fi2 FileInfo) bool
+	// Returns the extended attributes the filter permits, by name.
+	GetXattr(name string, filter XattrFilter) ([]Xattr, error)
+	// Sets the extended attributes, removing others the filter permits.
+	SetXattr(name string, xattrs []Xattr, filter XattrFilter) error
 }
 
 // The File interface abstracts access to a regular file, being a somewhat
@@ -179,6 +183,18 @@ func NewFilesystem(fsType FilesystemType, uri string) Filesystem {
 		fs = newBasicFilesystem(uri)
 	case FilesystemTypeFake:
 		fs = newFakeFilesystem(uri)
+	case FilesystemTypeProvider:
+		if p := registeredContentProvider(); p != nil {
+			fs = newProviderFilesystem(uri, p)
+		} else {
+			fs = &errorFilesystem{fsType: fsType, uri: uri, err: errNoContentProvider}
+		}
+	case FilesystemTypeS3:
+		if s3fs, err := newS3Filesystem(uri); err == nil {
+			fs = s3fs
+		} else {
+			fs = &errorFilesystem{fsType: fsType, uri: uri, err: err}
+		}
 	default:
 		l.Debugln("Unknown filesystem", fsType, uri)
 		fs = &errorFilesystem{
@@ -203,8 +219,9 @@ func NewFilesystem(fsType FilesystemType, uri string) Filesystem {
 // root, represents an internal file that should always be ignored. The file
 // path must be clean (i.e., in canonical shortest form).
 func IsInternal(file string) bool {
-	// fs cannot import config, so we hard code .stfolder here (config.DefaultMarkerName)
-	internals := []string{".stfolder", ".stignore", ".stversions"}
+	// fs cannot import config, so we hard code .stfolder here (config.DefaultMarkerName),
+	// and likewise .stsnapshots (snapshot.BtrfsSnapshotDir)
+	internals := []string{".stfolder", ".stignore", ".stversions", ".stsnapshots"}
 	for _, internal := range internals {
 		if file == internal {
 			return true
@@ -23,6 +23,8 @@ func TestIsInternal(t *testing.T) {
 		{".stfolder/foo", true},
 		{".stignore/foo", true},
 		{".stversions/foo", true},
+		{".stsnapshots", true},
+		{".stsnapshots/foo", true},
 
 		{".stfolderfoo", false},
 		{".stignorefoo", false},
@@ -7,9 +7,30 @@
 package fs
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"unicode"
 )
 
+var ErrCaseConflict = errors.New("case conflict")
+
+// CaseConflictError is the reason an item can't be synced on a case
+// insensitive filesystem: another one exists whose name differs only in
+// case. It is an ErrCaseConflict as seen by errors.Is.
+type CaseConflictError struct {
+	Name     string
+	Existing string
+}
+
+func (e *CaseConflictError) Error() string {
+	return fmt.Sprintf("%v with existing %q", ErrCaseConflict, e.Existing)
+}
+
+func (e *CaseConflictError) Unwrap() error {
+	return ErrCaseConflict
+}
+
 func UnicodeLowercase(s string) string {
 	rs := []rune(s)
 	for i, r := range rs {
@@ -17,3 +38,26 @@ func UnicodeLowercase(s string) string {
 	}
 	return string(rs)
 }
+
+// IsCaseInsensitive returns whether the filesystem takes names that differ
+// only in case to be the same, as told by looking up the given existing
+// item under another case. It's false if the item doesn't exist or has no
+// letters to change.
+func IsCaseInsensitive(filesystem Filesystem, existing string) bool {
+	other := strings.ToUpper(existing)
+	if other == existing {
+		other = strings.ToLower(existing)
+	}
+	if other == existing {
+		return false
+	}
+	fi, err := filesystem.Lstat(existing)
+	if err != nil {
+		return false
+	}
+	otherFi, err := filesystem.Lstat(other)
+	if err != nil {
+		return false
+	}
+	return filesystem.SameFile(fi, otherFi)
+}
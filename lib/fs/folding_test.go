@@ -6,7 +6,10 @@
 
 package fs
 
-import "testing"
+import (
+	"fmt"
+	"testing"
+)
 
 func TestUnicodeLowercase(t *testing.T) {
 	cases := [][2]string{
@@ -48,3 +51,19 @@ func TestUnicodeLowercase(t *testing.T) {
 		}
 	}
 }
+
+func TestIsCaseInsensitive(t *testing.T) {
+	for _, insens := range []bool{false, true} {
+		// Comes with a folder marker
+		fs := newFakeFilesystem(fmt.Sprintf("/TestIsCaseInsensitive%v?insens=%v", insens, insens))
+		if res := IsCaseInsensitive(fs, ".stfolder"); res != insens {
+			t.Errorf("insens=%v: got %v", insens, res)
+		}
+		if IsCaseInsensitive(fs, ".missing") {
+			t.Errorf("insens=%v: missing item taken to be case insensitive", insens)
+		}
+		if IsCaseInsensitive(fs, ".123") {
+			t.Errorf("insens=%v: item without letters taken to be case insensitive", insens)
+		}
+	}
+}
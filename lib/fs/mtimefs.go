@@ -228,6 +228,10 @@ func (f *mtimeFile) Stat() (FileInfo, error) {
 	return info, nil
 }
 
+func (f *mtimeFile) PunchHole(offset, size int64) error {
+	return PunchHole(f.File, offset, size)
+}
+
 // The dbMtime is our database representation
 
 type dbMtime struct {
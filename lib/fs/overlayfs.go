@@ -0,0 +1,546 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// A file with this prefix in the upper layer hides the item named by
+	// the rest of it in the lower layer, i.e. records its deletion.
+	overlayWhiteoutPrefix = ".stwhiteout~"
+	// A file with this name in a directory of the upper layer hides what
+	// the lower layer has in it, as the directory was deleted and created
+	// again.
+	overlayOpaqueName = ".stopaque"
+)
+
+var (
+	errOverlayRenameDir = errors.New("renaming a directory from the read only layer is not supported")
+	errOverlayNotDir    = errors.New("not a directory")
+	errOverlayNotEmpty  = errors.New("directory not empty")
+)
+
+// The overlayFS presents the files of a lower filesystem that is never
+// written to, such as read only media or a snapshot, with all changes made
+// in an upper filesystem. Items are read from the upper layer when they
+// are there and from the lower otherwise; files are copied up before being
+// changed, and deletions of what's in the lower layer are recorded as
+// whiteout files in the upper.
+type overlayFS struct {
+	lower Filesystem
+	upper Filesystem
+}
+
+// NewOverlayFS returns a filesystem showing the upper one over the lower
+// one, writing only to the upper. Type and URI are those of the upper, for
+// things like versions to be put there as well.
+func NewOverlayFS(lower, upper Filesystem) Filesystem {
+	// The walk uses our DirNames and Lstat, so it sees both layers.
+	return NewWalkFilesystem(&overlayFS{lower: lower, upper: upper})
+}
+
+func (f *overlayFS) Chmod(name string, mode FileMode) error {
+	if err := f.copyUp(name); err != nil {
+		return err
+	}
+	return f.upper.Chmod(name, mode)
+}
+
+func (f *overlayFS) Lchown(name string, uid, gid int) error {
+	if err := f.copyUp(name); err != nil {
+		return err
+	}
+	return f.upper.Lchown(name, uid, gid)
+}
+
+func (f *overlayFS) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	if err := f.copyUp(name); err != nil {
+		return err
+	}
+	return f.upper.Chtimes(name, atime, mtime)
+}
+
+func (f *overlayFS) Create(name string) (File, error) {
+	return f.OpenFile(name, OptReadWrite|OptCreate|OptTruncate, 0666)
+}
+
+func (f *overlayFS) CreateSymlink(target, name string) error {
+	if _, err := f.Lstat(name); err == nil {
+		return &os.PathError{Op: "symlink", Path: name, Err: os.ErrExist}
+	}
+	if err := f.prepareUpper(name); err != nil {
+		return err
+	}
+	return f.upper.CreateSymlink(target, name)
+}
+
+func (f *overlayFS) DirNames(name string) ([]string, error) {
+	upperNames, upperErr := f.upper.DirNames(name)
+	if upperErr != nil && !IsNotExist(upperErr) {
+		return nil, upperErr
+	}
+
+	seen := make(map[string]struct{})
+	var names []string
+	opaque := false
+	for _, n := range upperNames {
+		switch {
+		case n == overlayOpaqueName:
+			opaque = true
+		case strings.HasPrefix(n, overlayWhiteoutPrefix):
+			seen[strings.TrimPrefix(n, overlayWhiteoutPrefix)] = struct{}{}
+		}
+	}
+	for _, n := range upperNames {
+		if n == overlayOpaqueName || strings.HasPrefix(n, overlayWhiteoutPrefix) {
+			continue
+		}
+		seen[n] = struct{}{}
+		names = append(names, n)
+	}
+
+	if opaque || !f.lowerVisible(name) {
+		if upperErr != nil {
+			return nil, upperErr
+		}
+		return names, nil
+	}
+	lowerNames, lowerErr := f.lower.DirNames(name)
+	if lowerErr != nil {
+		if upperErr != nil || !IsNotExist(lowerErr) {
+			return nil, lowerErr
+		}
+		return names, nil
+	}
+	for _, n := range lowerNames {
+		if _, ok := seen[n]; !ok {
+			names = append(names, n)
+		}
+	}
+	return names, nil
+}
+
+func (f *overlayFS) Lstat(name string) (FileInfo, error) {
+	info, err := f.upper.Lstat(name)
+	if err == nil || !IsNotExist(err) {
+		return info, err
+	}
+	if !f.lowerVisible(name) {
+		return nil, err
+	}
+	return f.lower.Lstat(name)
+}
+
+func (f *overlayFS) Stat(name string) (FileInfo, error) {
+	info, err := f.upper.Stat(name)
+	if err == nil || !IsNotExist(err) {
+		return info, err
+	}
+	if !f.lowerVisible(name) {
+		return nil, err
+	}
+	return f.lower.Stat(name)
+}
+
+func (f *overlayFS) Mkdir(name string, perm FileMode) error {
+	if _, err := f.Lstat(name); err == nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrExist}
+	}
+	whitedOut := f.whitedOut(name)
+	if err := f.prepareUpper(name); err != nil {
+		return err
+	}
+	if err := f.upper.Mkdir(name, perm); err != nil {
+		return err
+	}
+	if whitedOut && f.lowerHas(name) {
+		// What was in the directory before it was deleted must stay gone.
+		fd, err := f.upper.Create(filepath.Join(name, overlayOpaqueName))
+		if err != nil {
+			return err
+		}
+		return fd.Close()
+	}
+	return nil
+}
+
+func (f *overlayFS) MkdirAll(name string, perm FileMode) error {
+	name = filepath.Clean(name)
+	if info, err := f.Lstat(name); err == nil {
+		if !info.IsDir() {
+			return &os.PathError{Op: "mkdir", Path: name, Err: errOverlayNotDir}
+		}
+		return nil
+	}
+	if parent := filepath.Dir(name); parent != name {
+		if err := f.MkdirAll(parent, perm); err != nil {
+			return err
+		}
+	}
+	return f.Mkdir(name, perm)
+}
+
+func (f *overlayFS) Open(name string) (File, error) {
+	fd, err := f.upper.Open(name)
+	if err == nil || !IsNotExist(err) {
+		return fd, err
+	}
+	if !f.lowerVisible(name) {
+		return nil, err
+	}
+	return f.lower.Open(name)
+}
+
+func (f *overlayFS) OpenFile(name string, flags int, mode FileMode) (File, error) {
+	if flags&(OptWriteOnly|OptReadWrite|OptCreate|OptTruncate|OptAppend) == 0 {
+		return f.Open(name)
+	}
+	if _, err := f.upper.Lstat(name); IsNotExist(err) {
+		info, err := f.Lstat(name)
+		switch {
+		case err == nil && flags&OptCreate != 0 && flags&OptExclusive != 0:
+			return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
+		case err == nil && flags&OptTruncate == 0 && info.IsRegular():
+			if err := f.copyUp(name); err != nil {
+				return nil, err
+			}
+		case err == nil || flags&OptCreate != 0:
+			if err := f.prepareUpper(name); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, err
+		}
+	}
+	return f.upper.OpenFile(name, flags, mode)
+}
+
+func (f *overlayFS) ReadSymlink(name string) (string, error) {
+	target, err := f.upper.ReadSymlink(name)
+	if err == nil || !IsNotExist(err) {
+		return target, err
+	}
+	if !f.lowerVisible(name) {
+		return "", err
+	}
+	return f.lower.ReadSymlink(name)
+}
+
+func (f *overlayFS) Remove(name string) error {
+	info, err := f.Lstat(name)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		if names, err := f.DirNames(name); err != nil {
+			return err
+		} else if len(names) > 0 {
+			return &os.PathError{Op: "remove", Path: name, Err: errOverlayNotEmpty}
+		}
+	}
+	return f.remove(name)
+}
+
+func (f *overlayFS) RemoveAll(name string) error {
+	if _, err := f.Lstat(name); IsNotExist(err) {
+		return nil
+	}
+	return f.remove(name)
+}
+
+// remove removes the item from the upper layer, whiting it out if it's
+// in the lower as well.
+func (f *overlayFS) remove(name string) error {
+	if _, err := f.upper.Lstat(name); err == nil {
+		// A directory may still have whiteouts in it.
+		if err := f.upper.RemoveAll(name); err != nil {
+			return err
+		}
+	}
+	if !f.lowerHas(name) {
+		return nil
+	}
+	if err := f.prepareUpper(filepath.Dir(name)); err != nil {
+		return err
+	}
+	fd, err := f.upper.Create(overlayWhiteoutName(name))
+	if err != nil {
+		return err
+	}
+	return fd.Close()
+}
+
+func (f *overlayFS) Rename(oldname, newname string) error {
+	info, err := f.Lstat(oldname)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() && f.lowerHas(oldname) {
+		return &os.PathError{Op: "rename", Path: oldname, Err: errOverlayRenameDir}
+	}
+	if err := f.copyUp(oldname); err != nil {
+		return err
+	}
+	if _, err := f.Lstat(newname); err == nil {
+		if err := f.copyUp(newname); err != nil {
+			return err
+		}
+	} else if err := f.prepareUpper(newname); err != nil {
+		return err
+	}
+	if err := f.upper.Rename(oldname, newname); err != nil {
+		return err
+	}
+	return f.remove(oldname)
+}
+
+func (f *overlayFS) SymlinksSupported() bool {
+	return f.upper.SymlinksSupported()
+}
+
+func (f *overlayFS) Walk(name string, walkFn WalkFunc) error {
+	// Done by the walkFilesystem wrapping us.
+	return errors.New("not implemented")
+}
+
+func (f *overlayFS) Watch(path string, ignore Matcher, ctx context.Context, ignorePerms bool) (<-chan Event, <-chan error, error) {
+	// Whatever changes in the upper layer is changed by us, and the lower
+	// one isn't meant to change.
+	return nil, nil, ErrWatchNotSupported
+}
+
+func (f *overlayFS) Hide(name string) error {
+	if _, err := f.upper.Lstat(name); err != nil {
+		return nil
+	}
+	return f.upper.Hide(name)
+}
+
+func (f *overlayFS) Unhide(name string) error {
+	if _, err := f.upper.Lstat(name); err != nil {
+		return nil
+	}
+	return f.upper.Unhide(name)
+}
+
+func (f *overlayFS) Glob(pattern string) ([]string, error) {
+	dir := filepath.Dir(pattern)
+	names, err := f.DirNames(dir)
+	if err != nil {
+		if IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var matches []string
+	for _, n := range names {
+		if ok, err := filepath.Match(filepath.Base(pattern), n); err != nil {
+			return nil, err
+		} else if ok {
+			matches = append(matches, filepath.Join(dir, n))
+		}
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+func (f *overlayFS) Roots() ([]string, error) {
+	return f.upper.Roots()
+}
+
+func (f *overlayFS) Usage(name string) (Usage, error) {
+	// What's free is where things are written.
+	return f.upper.Usage(".")
+}
+
+func (f *overlayFS) Type() FilesystemType {
+	return f.upper.Type()
+}
+
+func (f *overlayFS) URI() string {
+	return f.upper.URI()
+}
+
+func (f *overlayFS) SameFile(fi1, fi2 FileInfo) bool {
+	return f.upper.SameFile(fi1, fi2) || f.lower.SameFile(fi1, fi2)
+}
+
+func (f *overlayFS) GetXattr(name string, filter XattrFilter) ([]Xattr, error) {
+	xattrs, err := f.upper.GetXattr(name, filter)
+	if err == nil || !IsNotExist(err) {
+		return xattrs, err
+	}
+	if !f.lowerVisible(name) {
+		return nil, err
+	}
+	return f.lower.GetXattr(name, filter)
+}
+
+func (f *overlayFS) SetXattr(name string, xattrs []Xattr, filter XattrFilter) error {
+	if err := f.copyUp(name); err != nil {
+		return err
+	}
+	return f.upper.SetXattr(name, xattrs, filter)
+}
+
+// lowerVisible returns whether the item of the lower layer at the path is
+// not hidden by a whiteout of it or one of its parents, or an opaque
+// directory above it.
+func (f *overlayFS) lowerVisible(name string) bool {
+	name = filepath.Clean(name)
+	if name == "." {
+		return true
+	}
+	parts := strings.Split(name, string(PathSeparator))
+	for i := range parts {
+		path := filepath.Join(parts[:i+1]...)
+		if f.whitedOut(path) {
+			return false
+		}
+		if i < len(parts)-1 {
+			if _, err := f.upper.Lstat(filepath.Join(path, overlayOpaqueName)); err == nil {
+				return false
+			}
+		}
+	}
+	if _, err := f.upper.Lstat(overlayOpaqueName); err == nil {
+		return false
+	}
+	return true
+}
+
+func (f *overlayFS) whitedOut(name string) bool {
+	_, err := f.upper.Lstat(overlayWhiteoutName(name))
+	return err == nil
+}
+
+// lowerHas returns whether the lower layer has the item, ignoring
+// whiteouts.
+func (f *overlayFS) lowerHas(name string) bool {
+	if f.whitedOut(name) || !f.lowerVisible(filepath.Dir(name)) {
+		return false
+	}
+	_, err := f.lower.Lstat(name)
+	return err == nil
+}
+
+// prepareUpper gets the upper layer ready for the item to be created in
+// it: the parent directories exist there and the item isn't whited out
+// anymore.
+func (f *overlayFS) prepareUpper(name string) error {
+	name = filepath.Clean(name)
+	if name == "." {
+		return f.upper.MkdirAll(".", 0777)
+	}
+	if err := f.copyUpDir(filepath.Dir(name)); err != nil {
+		return err
+	}
+	if err := f.upper.Remove(overlayWhiteoutName(name)); err != nil && !IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// copyUpDir creates the directory and its parents in the upper layer, as
+// they are in the overlay.
+func (f *overlayFS) copyUpDir(name string) error {
+	name = filepath.Clean(name)
+	if name == "." {
+		return f.upper.MkdirAll(".", 0777)
+	}
+	if info, err := f.upper.Lstat(name); err == nil {
+		if !info.IsDir() {
+			return &os.PathError{Op: "mkdir", Path: name, Err: errOverlayNotDir}
+		}
+		return nil
+	}
+	info, err := f.Lstat(name)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return &os.PathError{Op: "mkdir", Path: name, Err: errOverlayNotDir}
+	}
+	if err := f.copyUpDir(filepath.Dir(name)); err != nil {
+		return err
+	}
+	if err := f.upper.Mkdir(name, info.Mode()&ModePerm); err != nil {
+		return err
+	}
+	f.copyUpXattrs(name)
+	_ = f.upper.Chtimes(name, info.ModTime(), info.ModTime())
+	return nil
+}
+
+// copyUp copies the item from the lower layer to the upper, unless it's
+// there already. Directories are created without their contents, which
+// still show through.
+func (f *overlayFS) copyUp(name string) error {
+	if _, err := f.upper.Lstat(name); err == nil {
+		return nil
+	}
+	info, err := f.Lstat(name)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return f.copyUpDir(name)
+	}
+	if err := f.prepareUpper(name); err != nil {
+		return err
+	}
+	if info.IsSymlink() {
+		target, err := f.lower.ReadSymlink(name)
+		if err != nil {
+			return err
+		}
+		return f.upper.CreateSymlink(target, name)
+	}
+
+	src, err := f.lower.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := f.upper.OpenFile(name, OptWriteOnly|OptCreate|OptTruncate, info.Mode()&ModePerm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		f.upper.Remove(name)
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		f.upper.Remove(name)
+		return err
+	}
+	f.copyUpXattrs(name)
+	_ = f.upper.Chtimes(name, info.ModTime(), info.ModTime())
+	return nil
+}
+
+// copyUpXattrs copies the extended attributes of the item, as far as both
+// layers support them.
+func (f *overlayFS) copyUpXattrs(name string) {
+	if xattrs, err := f.lower.GetXattr(name, nil); err == nil && len(xattrs) > 0 {
+		_ = f.upper.SetXattr(name, xattrs, nil)
+	}
+}
+
+func overlayWhiteoutName(name string) string {
+	return filepath.Join(filepath.Dir(name), overlayWhiteoutPrefix+filepath.Base(name))
+}
@@ -0,0 +1,148 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestOverlayFS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syncthing-overlay-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	lowerDir := filepath.Join(dir, "lower")
+	for name, data := range map[string]string{
+		"file":       "lower file",
+		"a/changed":  "lower changed",
+		"a/kept":     "lower kept",
+		"d/old":      "lower old",
+		"gone/inner": "lower inner",
+	} {
+		path := filepath.Join(lowerDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lower := NewFilesystem(FilesystemTypeBasic, lowerDir)
+	ofs := NewOverlayFS(lower, NewFilesystem(FilesystemTypeBasic, filepath.Join(dir, "upper")))
+
+	expectNames := func(name string, expected ...string) {
+		t.Helper()
+		names, err := ofs.DirNames(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(names)
+		if !reflect.DeepEqual(names, expected) {
+			t.Errorf("Unexpected names in %q: %v, expected %v", name, names, expected)
+		}
+	}
+	expectContent := func(fs Filesystem, name, expected string) {
+		t.Helper()
+		fd, err := fs.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer fd.Close()
+		data, err := ioutil.ReadAll(fd)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != expected {
+			t.Errorf("Unexpected content of %q: %q, expected %q", name, data, expected)
+		}
+	}
+
+	expectContent(ofs, "a/changed", "lower changed")
+	expectNames(".", "a", "d", "file", "gone")
+
+	// Writing to a file copies it up, keeping what it had.
+	fd, err := ofs.OpenFile("a/changed", OptReadWrite, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.WriteAt([]byte("upper"), 0); err != nil {
+		t.Fatal(err)
+	}
+	fd.Close()
+	expectContent(ofs, "a/changed", "upper changed")
+	expectContent(lower, "a/changed", "lower changed")
+
+	if fd, err := ofs.Create("a/new"); err != nil {
+		t.Fatal(err)
+	} else {
+		fd.Close()
+	}
+	expectNames("a", "changed", "kept", "new")
+
+	// Deleting hides what's in the lower layer.
+	if err := ofs.Remove("file"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ofs.Lstat("file"); !IsNotExist(err) {
+		t.Errorf("Expected the removed file to be gone, got %v", err)
+	}
+	if err := ofs.Remove("gone"); err == nil {
+		t.Error("Unexpected removal of a directory that isn't empty")
+	}
+	if err := ofs.RemoveAll("gone"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ofs.Lstat("gone/inner"); !IsNotExist(err) {
+		t.Errorf("Expected the removed directory to be gone, got %v", err)
+	}
+	expectNames(".", "a", "d")
+	expectContent(lower, "file", "lower file")
+
+	// A directory created again is empty.
+	if err := ofs.RemoveAll("d"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ofs.Mkdir("d", 0755); err != nil {
+		t.Fatal(err)
+	}
+	expectNames("d")
+	if _, err := ofs.Lstat("d/old"); !IsNotExist(err) {
+		t.Errorf("Expected the old file to be gone, got %v", err)
+	}
+
+	// Renaming moves a copy.
+	if err := ofs.Rename("a/kept", "d/kept"); err != nil {
+		t.Fatal(err)
+	}
+	expectNames("a", "changed", "new")
+	expectNames("d", "kept")
+	expectContent(ofs, "d/kept", "lower kept")
+	expectContent(lower, "a/kept", "lower kept")
+
+	var walked []string
+	if err := ofs.Walk(".", func(path string, info FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		walked = append(walked, path)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(walked)
+	expected := []string{".", "a", filepath.Join("a", "changed"), filepath.Join("a", "new"), "d", filepath.Join("d", "kept")}
+	if !reflect.DeepEqual(walked, expected) {
+		t.Errorf("Unexpected walk %v, expected %v", walked, expected)
+	}
+}
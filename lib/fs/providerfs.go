@@ -0,0 +1,616 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A ContentProvider gives access to trees of documents the way the Android
+// Storage Access Framework does: documents are known by opaque IDs rather
+// than by path, and files are read and written through handles opened on
+// them. It is implemented outside of Syncthing, by the app embedding it,
+// and made available with RegisterContentProvider before the configuration
+// is loaded. Folders then use the filesystem type "provider" with the URI of
+// the tree as path.
+//
+// Methods return an error satisfying os.IsNotExist for documents that
+// don't exist.
+type ContentProvider interface {
+	// RootDocument returns the ID of the root document of the tree.
+	RootDocument(treeURI string) (string, error)
+	// Document returns the metadata of a document.
+	Document(id string) (Document, error)
+	// Children lists the documents in a directory.
+	Children(id string) ([]Document, error)
+	// Create creates an empty file or, if dir is set, a directory with the
+	// given name in the given directory.
+	Create(parentID, name string, dir bool) (Document, error)
+	// Open opens a file for reading, or also for writing if write is set.
+	Open(id string, write bool) (ProviderFile, error)
+	// Delete deletes a document, and all documents within a directory.
+	Delete(id string) error
+	// Rename renames a document within its directory, returning its
+	// possibly changed ID.
+	Rename(id, name string) (string, error)
+	// Move moves a document to another directory, returning its possibly
+	// changed ID.
+	Move(id, fromParentID, toParentID string) (string, error)
+	// Usage returns the free and total space where the document is stored.
+	Usage(id string) (free, total int64, err error)
+}
+
+// Document is the metadata of a document of a ContentProvider.
+type Document struct {
+	ID      string
+	Name    string
+	IsDir   bool
+	Size    int64
+	ModTime time.Time
+}
+
+// A ProviderFile is an open file of a ContentProvider.
+type ProviderFile interface {
+	io.ReaderAt
+	io.WriterAt
+	io.Closer
+	Truncate(size int64) error
+	Sync() error
+}
+
+var (
+	contentProvider    ContentProvider
+	contentProviderMut sync.Mutex
+
+	errNoContentProvider = errors.New("no content provider registered")
+	errProviderSymlinks  = errors.New("symlinks not supported by content providers")
+	errProviderChtimes   = errors.New("setting modification times not supported by content providers")
+)
+
+// RegisterContentProvider sets the provider behind filesystems of type
+// provider.
+func RegisterContentProvider(p ContentProvider) {
+	contentProviderMut.Lock()
+	contentProvider = p
+	contentProviderMut.Unlock()
+}
+
+func registeredContentProvider() ContentProvider {
+	contentProviderMut.Lock()
+	defer contentProviderMut.Unlock()
+	return contentProvider
+}
+
+// providerFilesystem is a Filesystem on top of a ContentProvider. Paths
+// are resolved to document IDs component by component, and the IDs are
+// cached until something changes.
+type providerFilesystem struct {
+	uri string
+	p   ContentProvider
+
+	mut    sync.Mutex
+	rootID string
+	ids    map[string]string // path -> document ID
+}
+
+func newProviderFilesystem(uri string, p ContentProvider) *providerFilesystem {
+	return &providerFilesystem{
+		uri: uri,
+		p:   p,
+		ids: make(map[string]string),
+	}
+}
+
+func (f *providerFilesystem) root() (string, error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	if f.rootID == "" {
+		id, err := f.p.RootDocument(f.uri)
+		if err != nil {
+			return "", err
+		}
+		f.rootID = id
+	}
+	return f.rootID, nil
+}
+
+// resolve returns the document at the given path. Cached IDs may have gone
+// stale when the tree was changed by someone else, so on failure it tries
+// again from scratch.
+func (f *providerFilesystem) resolve(op, name string) (Document, error) {
+	name, err := Canonicalize(name)
+	if err != nil {
+		return Document{}, err
+	}
+	doc, err := f.resolveCanonical(name, true)
+	if err != nil {
+		f.forget(".")
+		doc, err = f.resolveCanonical(name, false)
+	}
+	if err != nil {
+		return Document{}, &os.PathError{Op: op, Path: name, Err: err}
+	}
+	return doc, nil
+}
+
+func (f *providerFilesystem) resolveCanonical(name string, cached bool) (Document, error) {
+	id, err := f.root()
+	if err != nil {
+		return Document{}, err
+	}
+	if name == "." {
+		return f.p.Document(id)
+	}
+
+	path := ""
+	for _, part := range strings.Split(name, string(PathSeparator)) {
+		path = filepath.Join(path, part)
+		f.mut.Lock()
+		childID, ok := f.ids[path]
+		f.mut.Unlock()
+		if !ok || !cached {
+			if childID, err = f.lookup(id, path); err != nil {
+				return Document{}, err
+			}
+		}
+		id = childID
+	}
+	return f.p.Document(id)
+}
+
+// lookup finds the document at path among the children of its parent,
+// caching the IDs of all of them.
+func (f *providerFilesystem) lookup(parentID, path string) (string, error) {
+	children, err := f.p.Children(parentID)
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Dir(path)
+	id := ""
+	f.mut.Lock()
+	for _, child := range children {
+		childPath := filepath.Join(dir, child.Name)
+		f.ids[childPath] = child.ID
+		if childPath == path {
+			id = child.ID
+		}
+	}
+	f.mut.Unlock()
+	if id == "" {
+		return "", os.ErrNotExist
+	}
+	return id, nil
+}
+
+// forget drops the cached IDs of the path and everything within it.
+func (f *providerFilesystem) forget(name string) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	if name == "." {
+		f.ids = make(map[string]string)
+		return
+	}
+	for path := range f.ids {
+		if path == name || IsParent(path, name) {
+			delete(f.ids, path)
+		}
+	}
+}
+
+// parent resolves the directory of the given path, returning it and the
+// name within it.
+func (f *providerFilesystem) parent(op, name string) (Document, string, error) {
+	name, err := Canonicalize(name)
+	if err != nil {
+		return Document{}, "", err
+	}
+	dir, err := f.resolve(op, filepath.Dir(name))
+	if err != nil {
+		return Document{}, "", err
+	}
+	if !dir.IsDir {
+		return Document{}, "", &os.PathError{Op: op, Path: name, Err: errors.New("not a directory")}
+	}
+	return dir, filepath.Base(name), nil
+}
+
+func (f *providerFilesystem) Chmod(name string, mode FileMode) error {
+	// There are no permissions, only what the provider allows.
+	_, err := f.resolve("chmod", name)
+	return err
+}
+
+func (f *providerFilesystem) Lchown(name string, uid, gid int) error {
+	_, err := f.resolve("lchown", name)
+	return err
+}
+
+func (f *providerFilesystem) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	// The MtimeFS on top makes up for this.
+	return &os.PathError{Op: "chtimes", Path: name, Err: errProviderChtimes}
+}
+
+func (f *providerFilesystem) Create(name string) (File, error) {
+	return f.OpenFile(name, OptReadWrite|OptCreate|OptTruncate, 0666)
+}
+
+func (f *providerFilesystem) CreateSymlink(target, name string) error {
+	return errProviderSymlinks
+}
+
+func (f *providerFilesystem) DirNames(name string) ([]string, error) {
+	doc, err := f.resolve("readdirent", name)
+	if err != nil {
+		return nil, err
+	}
+	children, err := f.p.Children(doc.ID)
+	if err != nil {
+		return nil, &os.PathError{Op: "readdirent", Path: name, Err: err}
+	}
+	names := make([]string, len(children))
+	f.mut.Lock()
+	for i, child := range children {
+		names[i] = child.Name
+		f.ids[filepath.Join(name, child.Name)] = child.ID
+	}
+	f.mut.Unlock()
+	return names, nil
+}
+
+func (f *providerFilesystem) Lstat(name string) (FileInfo, error) {
+	doc, err := f.resolve("lstat", name)
+	if err != nil {
+		return nil, err
+	}
+	return providerFileInfo{doc}, nil
+}
+
+func (f *providerFilesystem) Mkdir(name string, perm FileMode) error {
+	if _, err := f.resolve("mkdir", name); err == nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrExist}
+	}
+	dir, base, err := f.parent("mkdir", name)
+	if err != nil {
+		return err
+	}
+	if _, err := f.p.Create(dir.ID, base, true); err != nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: err}
+	}
+	return nil
+}
+
+func (f *providerFilesystem) MkdirAll(name string, perm FileMode) error {
+	name, err := Canonicalize(name)
+	if err != nil {
+		return err
+	}
+	if doc, err := f.resolve("mkdir", name); err == nil {
+		if !doc.IsDir {
+			return &os.PathError{Op: "mkdir", Path: name, Err: errors.New("not a directory")}
+		}
+		return nil
+	}
+	if dir := filepath.Dir(name); dir != "." {
+		if err := f.MkdirAll(dir, perm); err != nil {
+			return err
+		}
+	}
+	return f.Mkdir(name, perm)
+}
+
+func (f *providerFilesystem) Open(name string) (File, error) {
+	return f.OpenFile(name, OptReadOnly, 0)
+}
+
+func (f *providerFilesystem) OpenFile(name string, flags int, mode FileMode) (File, error) {
+	doc, err := f.resolve("open", name)
+	switch {
+	case err == nil && flags&OptCreate != 0 && flags&OptExclusive != 0:
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
+	case IsNotExist(err) && flags&OptCreate != 0:
+		dir, base, err := f.parent("open", name)
+		if err != nil {
+			return nil, err
+		}
+		if doc, err = f.p.Create(dir.ID, base, false); err != nil {
+			return nil, &os.PathError{Op: "open", Path: name, Err: err}
+		}
+	case err != nil:
+		return nil, err
+	}
+	if doc.IsDir {
+		return nil, &os.PathError{Op: "open", Path: name, Err: errors.New("is a directory")}
+	}
+
+	write := flags&(OptWriteOnly|OptReadWrite) != 0
+	fd, err := f.p.Open(doc.ID, write)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	if write && flags&OptTruncate != 0 {
+		if err := fd.Truncate(0); err != nil {
+			fd.Close()
+			return nil, &os.PathError{Op: "open", Path: name, Err: err}
+		}
+	}
+	return &providerFile{ProviderFile: fd, fs: f, name: name, id: doc.ID}, nil
+}
+
+func (f *providerFilesystem) ReadSymlink(name string) (string, error) {
+	return "", errProviderSymlinks
+}
+
+func (f *providerFilesystem) Remove(name string) error {
+	doc, err := f.resolve("remove", name)
+	if err != nil {
+		return err
+	}
+	if doc.IsDir {
+		children, err := f.p.Children(doc.ID)
+		if err != nil {
+			return &os.PathError{Op: "remove", Path: name, Err: err}
+		}
+		if len(children) > 0 {
+			return &os.PathError{Op: "remove", Path: name, Err: errors.New("directory not empty")}
+		}
+	}
+	return f.delete(name, doc)
+}
+
+func (f *providerFilesystem) RemoveAll(name string) error {
+	doc, err := f.resolve("remove", name)
+	if IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return f.delete(name, doc)
+}
+
+func (f *providerFilesystem) delete(name string, doc Document) error {
+	defer f.forget(name)
+	if err := f.p.Delete(doc.ID); err != nil {
+		return &os.PathError{Op: "remove", Path: name, Err: err}
+	}
+	return nil
+}
+
+func (f *providerFilesystem) Rename(oldname, newname string) error {
+	doc, err := f.resolve("rename", oldname)
+	if err != nil {
+		return err
+	}
+	fromDir, _, err := f.parent("rename", oldname)
+	if err != nil {
+		return err
+	}
+	toDir, base, err := f.parent("rename", newname)
+	if err != nil {
+		return err
+	}
+	// Like rename(2), replace an existing file.
+	if existing, err := f.resolve("rename", newname); err == nil {
+		if existing.ID == doc.ID {
+			return nil
+		}
+		if existing.IsDir {
+			return &os.PathError{Op: "rename", Path: newname, Err: os.ErrExist}
+		}
+		if err := f.delete(newname, existing); err != nil {
+			return err
+		}
+	}
+
+	defer f.forget(oldname)
+	defer f.forget(newname)
+	id := doc.ID
+	if fromDir.ID != toDir.ID {
+		if id, err = f.p.Move(id, fromDir.ID, toDir.ID); err != nil {
+			return &os.PathError{Op: "rename", Path: oldname, Err: err}
+		}
+	}
+	if base != doc.Name {
+		if _, err = f.p.Rename(id, base); err != nil {
+			return &os.PathError{Op: "rename", Path: oldname, Err: err}
+		}
+	}
+	return nil
+}
+
+func (f *providerFilesystem) Stat(name string) (FileInfo, error) {
+	return f.Lstat(name)
+}
+
+func (f *providerFilesystem) SymlinksSupported() bool {
+	return false
+}
+
+func (f *providerFilesystem) Walk(name string, walkFn WalkFunc) error {
+	return errors.New("not implemented")
+}
+
+func (f *providerFilesystem) Watch(path string, ignore Matcher, ctx context.Context, ignorePerms bool) (<-chan Event, <-chan error, error) {
+	return nil, nil, ErrWatchNotSupported
+}
+
+func (f *providerFilesystem) Hide(name string) error {
+	return nil
+}
+
+func (f *providerFilesystem) Unhide(name string) error {
+	return nil
+}
+
+func (f *providerFilesystem) Glob(pattern string) ([]string, error) {
+	// Matching in the last path component is enough for our use.
+	dir, base := filepath.Split(pattern)
+	dir = filepath.Clean(dir)
+	names, err := f.DirNames(dir)
+	if IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var matches []string
+	for _, name := range names {
+		if ok, err := filepath.Match(base, name); err != nil {
+			return nil, err
+		} else if ok {
+			matches = append(matches, filepath.Join(dir, name))
+		}
+	}
+	return matches, nil
+}
+
+func (f *providerFilesystem) Roots() ([]string, error) {
+	return []string{f.uri}, nil
+}
+
+func (f *providerFilesystem) Usage(name string) (Usage, error) {
+	doc, err := f.resolve("usage", name)
+	if err != nil {
+		return Usage{}, err
+	}
+	free, total, err := f.p.Usage(doc.ID)
+	return Usage{Free: free, Total: total}, err
+}
+
+func (f *providerFilesystem) Type() FilesystemType {
+	return FilesystemTypeProvider
+}
+
+func (f *providerFilesystem) URI() string {
+	return f.uri
+}
+
+func (f *providerFilesystem) SameFile(fi1, fi2 FileInfo) bool {
+	f1, ok1 := fi1.(providerFileInfo)
+	f2, ok2 := fi2.(providerFileInfo)
+	return ok1 && ok2 && f1.ID == f2.ID
+}
+
+func (f *providerFilesystem) GetXattr(name string, filter XattrFilter) ([]Xattr, error) {
+	return nil, ErrXattrsNotSupported
+}
+
+func (f *providerFilesystem) SetXattr(name string, xattrs []Xattr, filter XattrFilter) error {
+	return ErrXattrsNotSupported
+}
+
+// providerFile adds the file position and the rest of what makes a File on
+// top of a ProviderFile.
+type providerFile struct {
+	ProviderFile
+	fs   *providerFilesystem
+	name string
+	id   string
+
+	mut    sync.Mutex
+	offset int64
+}
+
+func (f *providerFile) Read(p []byte) (int, error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	n, err := f.ReadAt(p, f.offset)
+	f.offset += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+func (f *providerFile) Write(p []byte) (int, error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	n, err := f.WriteAt(p, f.offset)
+	f.offset += int64(n)
+	return n, err
+}
+
+func (f *providerFile) Seek(offset int64, whence int) (int64, error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		doc, err := f.fs.p.Document(f.id)
+		if err != nil {
+			return f.offset, err
+		}
+		offset += doc.Size
+	}
+	if offset < 0 {
+		return f.offset, errors.New("negative position")
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *providerFile) Name() string {
+	return f.name
+}
+
+func (f *providerFile) Stat() (FileInfo, error) {
+	doc, err := f.fs.p.Document(f.id)
+	if err != nil {
+		return nil, err
+	}
+	return providerFileInfo{doc}, nil
+}
+
+type providerFileInfo struct {
+	Document
+}
+
+func (fi providerFileInfo) Name() string {
+	return fi.Document.Name
+}
+
+func (fi providerFileInfo) Mode() FileMode {
+	if fi.Document.IsDir {
+		return FileMode(os.ModeDir | 0755)
+	}
+	return 0644
+}
+
+func (fi providerFileInfo) Size() int64 {
+	return fi.Document.Size
+}
+
+func (fi providerFileInfo) ModTime() time.Time {
+	return fi.Document.ModTime
+}
+
+func (fi providerFileInfo) IsDir() bool {
+	return fi.Document.IsDir
+}
+
+func (fi providerFileInfo) IsRegular() bool {
+	return !fi.Document.IsDir
+}
+
+func (fi providerFileInfo) IsSymlink() bool {
+	return false
+}
+
+func (fi providerFileInfo) Owner() int {
+	return -1
+}
+
+func (fi providerFileInfo) Group() int {
+	return -1
+}
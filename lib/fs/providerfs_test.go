@@ -0,0 +1,321 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memProvider is a ContentProvider keeping documents in memory. Like SAF
+// providers it changes the ID of documents that are moved.
+type memProvider struct {
+	mut      sync.Mutex
+	next     int
+	docs     map[string]*memDocument
+	children map[string]map[string]bool
+}
+
+type memDocument struct {
+	Document
+	data []byte
+}
+
+func newMemProvider() *memProvider {
+	p := &memProvider{
+		docs:     make(map[string]*memDocument),
+		children: make(map[string]map[string]bool),
+	}
+	p.docs["root"] = &memDocument{Document: Document{ID: "root", IsDir: true}}
+	p.children["root"] = make(map[string]bool)
+	return p
+}
+
+func (p *memProvider) RootDocument(treeURI string) (string, error) {
+	return "root", nil
+}
+
+func (p *memProvider) Document(id string) (Document, error) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	doc, ok := p.docs[id]
+	if !ok {
+		return Document{}, os.ErrNotExist
+	}
+	doc.Size = int64(len(doc.data))
+	return doc.Document, nil
+}
+
+func (p *memProvider) Children(id string) ([]Document, error) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	children, ok := p.children[id]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	var docs []Document
+	for child := range children {
+		docs = append(docs, p.docs[child].Document)
+	}
+	return docs, nil
+}
+
+func (p *memProvider) Create(parentID, name string, dir bool) (Document, error) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	if _, ok := p.children[parentID]; !ok {
+		return Document{}, os.ErrNotExist
+	}
+	p.next++
+	doc := &memDocument{Document: Document{ID: strconv.Itoa(p.next), Name: name, IsDir: dir, ModTime: now()}}
+	p.docs[doc.ID] = doc
+	p.children[parentID][doc.ID] = true
+	if dir {
+		p.children[doc.ID] = make(map[string]bool)
+	}
+	return doc.Document, nil
+}
+
+func (p *memProvider) Open(id string, write bool) (ProviderFile, error) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	if _, ok := p.docs[id]; !ok {
+		return nil, os.ErrNotExist
+	}
+	return &memFile{p, id}, nil
+}
+
+func (p *memProvider) Delete(id string) error {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	if _, ok := p.docs[id]; !ok {
+		return os.ErrNotExist
+	}
+	p.delete(id)
+	for _, children := range p.children {
+		delete(children, id)
+	}
+	return nil
+}
+
+func (p *memProvider) delete(id string) {
+	for child := range p.children[id] {
+		p.delete(child)
+	}
+	delete(p.children, id)
+	delete(p.docs, id)
+}
+
+func (p *memProvider) Rename(id, name string) (string, error) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	doc, ok := p.docs[id]
+	if !ok {
+		return "", os.ErrNotExist
+	}
+	doc.Name = name
+	return id, nil
+}
+
+func (p *memProvider) Move(id, fromParentID, toParentID string) (string, error) {
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	doc, ok := p.docs[id]
+	if !ok || !p.children[fromParentID][id] {
+		return "", os.ErrNotExist
+	}
+	p.next++
+	newID := strconv.Itoa(p.next)
+	doc.ID = newID
+	delete(p.docs, id)
+	p.docs[newID] = doc
+	delete(p.children[fromParentID], id)
+	p.children[toParentID][newID] = true
+	if children, ok := p.children[id]; ok {
+		delete(p.children, id)
+		p.children[newID] = children
+	}
+	return newID, nil
+}
+
+func (p *memProvider) Usage(id string) (int64, int64, error) {
+	return 1 << 20, 1 << 30, nil
+}
+
+type memFile struct {
+	p  *memProvider
+	id string
+}
+
+func (f *memFile) ReadAt(bs []byte, off int64) (int, error) {
+	f.p.mut.Lock()
+	defer f.p.mut.Unlock()
+	data := f.p.docs[f.id].data
+	if off >= int64(len(data)) {
+		return 0, io.EOF
+	}
+	n := copy(bs, data[off:])
+	if n < len(bs) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f *memFile) WriteAt(bs []byte, off int64) (int, error) {
+	f.p.mut.Lock()
+	defer f.p.mut.Unlock()
+	doc := f.p.docs[f.id]
+	if end := off + int64(len(bs)); end > int64(len(doc.data)) {
+		doc.data = append(doc.data, make([]byte, end-int64(len(doc.data)))...)
+	}
+	copy(doc.data[off:], bs)
+	doc.ModTime = now()
+	return len(bs), nil
+}
+
+func (f *memFile) Truncate(size int64) error {
+	f.p.mut.Lock()
+	defer f.p.mut.Unlock()
+	doc := f.p.docs[f.id]
+	if size < int64(len(doc.data)) {
+		doc.data = doc.data[:size]
+	}
+	return nil
+}
+
+func (f *memFile) Sync() error  { return nil }
+func (f *memFile) Close() error { return nil }
+
+func TestProviderFilesystem(t *testing.T) {
+	RegisterContentProvider(newMemProvider())
+	defer RegisterContentProvider(nil)
+
+	fs := NewFilesystem(FilesystemTypeProvider, "content://tree/primary")
+	if fs.Type() != FilesystemTypeProvider {
+		t.Fatal("unexpected type", fs.Type())
+	}
+
+	if err := fs.MkdirAll("a/b", 0755); err != nil {
+		t.Fatal(err)
+	}
+	fd, err := fs.Create("a/b/file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.Write([]byte("hello ")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.Write([]byte("world")); err != nil {
+		t.Fatal(err)
+	}
+	fd.Close()
+
+	if info, err := fs.Lstat("a/b/file"); err != nil {
+		t.Fatal(err)
+	} else if !info.IsRegular() || info.Size() != 11 {
+		t.Errorf("unexpected file info: %v regular, size %d", info.IsRegular(), info.Size())
+	}
+	if _, err := fs.Lstat("a/missing"); !IsNotExist(err) {
+		t.Error("expected not exist error, got", err)
+	}
+
+	// Moving to another directory changes the document ID, which must not
+	// confuse the cache.
+	if err := fs.Rename("a/b/file", "a/moved"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Lstat("a/b/file"); !IsNotExist(err) {
+		t.Error("expected not exist error, got", err)
+	}
+	fd, err = fs.Open("a/moved")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.Seek(6, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if bs, err := ioutil.ReadAll(fd); err != nil {
+		t.Fatal(err)
+	} else if string(bs) != "world" {
+		t.Errorf("read %q, expected %q", bs, "world")
+	}
+	fd.Close()
+
+	var walked []string
+	err = fs.Walk(".", func(path string, info FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		walked = append(walked, path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(walked)
+	expected := []string{".", "a", "a/b", "a/moved"}
+	if len(walked) != len(expected) {
+		t.Fatalf("walked %v, expected %v", walked, expected)
+	}
+	for i := range walked {
+		if walked[i] != filepath.FromSlash(expected[i]) {
+			t.Fatalf("walked %v, expected %v", walked, expected)
+		}
+	}
+
+	if err := fs.Remove("a"); err == nil {
+		t.Error("removing a non-empty directory should fail")
+	}
+	if err := fs.RemoveAll("a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Lstat("a/moved"); !IsNotExist(err) {
+		t.Error("expected not exist error, got", err)
+	}
+}
+
+func TestProviderFilesystemMtime(t *testing.T) {
+	RegisterContentProvider(newMemProvider())
+	defer RegisterContentProvider(nil)
+
+	// Modification times can't be set, so they are kept in the database.
+	fs := NewMtimeFS(NewFilesystem(FilesystemTypeProvider, "content://tree/primary"), make(mapStore))
+	fd, err := fs.Create("file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd.Close()
+
+	mtime := time.Unix(1234567890, 0)
+	if err := fs.Chtimes("file", mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := fs.Lstat("file"); err != nil {
+		t.Fatal(err)
+	} else if !info.ModTime().Equal(mtime) {
+		t.Errorf("mtime %v, expected %v", info.ModTime(), mtime)
+	}
+}
+
+func TestProviderFilesystemUnregistered(t *testing.T) {
+	fs := NewFilesystem(FilesystemTypeProvider, "content://tree/primary")
+	if _, err := fs.Lstat("."); err != errNoContentProvider {
+		t.Error("expected no provider error, got", err)
+	}
+}
+
+// now returns the time at the precision of SAF's last modified times.
+func now() time.Time {
+	return time.Now().Truncate(time.Millisecond)
+}
@@ -0,0 +1,709 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	errS3Symlinks = errors.New("symlinks not supported by object storage")
+	errS3Chtimes  = errors.New("setting modification times not supported by object storage")
+	errS3Usage    = errors.New("usage not known for object storage")
+	errS3NotDir   = errors.New("not a directory")
+	errS3NotEmpty = errors.New("directory not empty")
+	errS3IsDir    = errors.New("is a directory")
+)
+
+// s3Filesystem is a Filesystem in a bucket of S3 compatible object
+// storage. Folders use the filesystem type "s3" with a path like
+//
+//	s3://bucket/prefix?endpoint=minio.example.com:9000&region=eu-west-1
+//
+// where the endpoint defaults to AWS and the region to us-east-1, and
+// "insecure" selects plain HTTP. The credentials are taken from the usual
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
+// environment variables rather than the configuration.
+//
+// Files are objects, with keys of the path below the prefix. Directories
+// are empty objects with a trailing slash, or implied by the objects
+// within them. Files are read with ranged requests, while written files
+// are kept in a local temporary file until uploaded as a whole when synced
+// or closed, so only the files being written take local disk space.
+type s3Filesystem struct {
+	uri    string
+	prefix string
+	client *s3Client
+}
+
+func newS3Filesystem(uri string) (*s3Filesystem, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "s3" || u.Host == "" {
+		return nil, errors.New("object storage path must be like s3://bucket/prefix")
+	}
+	query := u.Query()
+
+	endpoint := &url.URL{Scheme: "https", Host: query.Get("endpoint")}
+	if query.Get("insecure") != "" {
+		endpoint.Scheme = "http"
+	}
+	region := query.Get("region")
+	if region == "" {
+		region = s3DefaultRegion
+	}
+	if endpoint.Host == "" {
+		endpoint.Host = s3DefaultEndpoint
+		if region != s3DefaultRegion {
+			endpoint.Host = "s3." + region + ".amazonaws.com"
+		}
+	}
+
+	return &s3Filesystem{
+		uri:    uri,
+		prefix: strings.Trim(u.Path, "/"),
+		client: &s3Client{
+			endpoint:     endpoint,
+			bucket:       u.Host,
+			region:       region,
+			accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+			secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+			http:         &http.Client{Timeout: s3RequestTimeout},
+			now:          time.Now,
+		},
+	}, nil
+}
+
+// key returns the key of the object at the path, which is empty for the
+// root without a prefix.
+func (f *s3Filesystem) key(op, name string) (string, error) {
+	name, err := Canonicalize(name)
+	if err != nil {
+		return "", &os.PathError{Op: op, Path: name, Err: err}
+	}
+	if name == "." {
+		return f.prefix, nil
+	}
+	return path.Join(f.prefix, filepath.ToSlash(name)), nil
+}
+
+// dirPrefix returns the prefix of the keys within the directory with the
+// given key.
+func dirPrefix(key string) string {
+	if key == "" {
+		return ""
+	}
+	return key + "/"
+}
+
+// dirExists returns whether there is a directory marker or any objects
+// with the key as directory. The root always exists.
+func (f *s3Filesystem) dirExists(key string) (bool, error) {
+	if key == f.prefix {
+		return true, nil
+	}
+	exists := false
+	err := f.client.list(dirPrefix(key), "", 1, func(res *s3ListResult) bool {
+		exists = len(res.Contents) > 0
+		return false
+	})
+	return exists, err
+}
+
+func (f *s3Filesystem) Chmod(name string, mode FileMode) error {
+	// There are no permissions on objects.
+	_, err := f.Lstat(name)
+	return err
+}
+
+func (f *s3Filesystem) Lchown(name string, uid, gid int) error {
+	_, err := f.Lstat(name)
+	return err
+}
+
+func (f *s3Filesystem) Chtimes(name string, atime time.Time, mtime time.Time) error {
+	// The MtimeFS on top makes up for this.
+	return &os.PathError{Op: "chtimes", Path: name, Err: errS3Chtimes}
+}
+
+func (f *s3Filesystem) Create(name string) (File, error) {
+	return f.OpenFile(name, OptReadWrite|OptCreate|OptTruncate, 0666)
+}
+
+func (f *s3Filesystem) CreateSymlink(target, name string) error {
+	return errS3Symlinks
+}
+
+func (f *s3Filesystem) DirNames(name string) ([]string, error) {
+	key, err := f.key("readdirent", name)
+	if err != nil {
+		return nil, err
+	}
+	prefix := dirPrefix(key)
+	exists := key == f.prefix
+	var names []string
+	err = f.client.list(prefix, "/", s3ListPageSize, func(res *s3ListResult) bool {
+		for _, obj := range res.Contents {
+			exists = true
+			if obj.Key != prefix {
+				names = append(names, obj.Key[len(prefix):])
+			}
+		}
+		for _, p := range res.CommonPrefixes {
+			exists = true
+			names = append(names, strings.TrimSuffix(p.Prefix[len(prefix):], "/"))
+		}
+		return true
+	})
+	if err == nil && !exists {
+		err = os.ErrNotExist
+	}
+	if err != nil {
+		return nil, &os.PathError{Op: "readdirent", Path: name, Err: err}
+	}
+	return names, nil
+}
+
+func (f *s3Filesystem) Lstat(name string) (FileInfo, error) {
+	key, err := f.key("lstat", name)
+	if err != nil {
+		return nil, err
+	}
+	if key != f.prefix {
+		size, modTime, err := f.client.head(key)
+		if err == nil {
+			return s3FileInfo{key: key, size: size, modTime: modTime}, nil
+		} else if !IsNotExist(err) {
+			return nil, &os.PathError{Op: "lstat", Path: name, Err: err}
+		}
+	}
+	if ok, err := f.dirExists(key); err != nil {
+		return nil, &os.PathError{Op: "lstat", Path: name, Err: err}
+	} else if !ok {
+		return nil, &os.PathError{Op: "lstat", Path: name, Err: os.ErrNotExist}
+	}
+	return s3FileInfo{key: key, dir: true}, nil
+}
+
+func (f *s3Filesystem) Mkdir(name string, perm FileMode) error {
+	key, err := f.key("mkdir", name)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Lstat(name); err == nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrExist}
+	}
+	if err := f.client.put(dirPrefix(key), nil, 0); err != nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: err}
+	}
+	return nil
+}
+
+func (f *s3Filesystem) MkdirAll(name string, perm FileMode) error {
+	name, err := Canonicalize(name)
+	if err != nil {
+		return err
+	}
+	if info, err := f.Lstat(name); err == nil {
+		if !info.IsDir() {
+			return &os.PathError{Op: "mkdir", Path: name, Err: errS3NotDir}
+		}
+		return nil
+	}
+	if dir := filepath.Dir(name); dir != "." {
+		if err := f.MkdirAll(dir, perm); err != nil {
+			return err
+		}
+	}
+	return f.Mkdir(name, perm)
+}
+
+func (f *s3Filesystem) Open(name string) (File, error) {
+	return f.OpenFile(name, OptReadOnly, 0)
+}
+
+func (f *s3Filesystem) OpenFile(name string, flags int, mode FileMode) (File, error) {
+	key, err := f.key("open", name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Lstat(name)
+	switch {
+	case err == nil && flags&OptCreate != 0 && flags&OptExclusive != 0:
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrExist}
+	case IsNotExist(err) && flags&OptCreate != 0:
+		// Created right away, like any other filesystem would.
+		if err := f.client.put(key, nil, 0); err != nil {
+			return nil, &os.PathError{Op: "open", Path: name, Err: err}
+		}
+		info = s3FileInfo{key: key, modTime: time.Now()}
+	case err != nil:
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, &os.PathError{Op: "open", Path: name, Err: errS3IsDir}
+	}
+
+	fd := &s3File{
+		fs:    f,
+		name:  name,
+		key:   key,
+		write: flags&(OptWriteOnly|OptReadWrite) != 0,
+		size:  info.Size(),
+	}
+	if fd.write && flags&OptTruncate != 0 && info.Size() > 0 {
+		if err := fd.truncateSpool(); err != nil {
+			return nil, &os.PathError{Op: "open", Path: name, Err: err}
+		}
+	}
+	return fd, nil
+}
+
+func (f *s3Filesystem) ReadSymlink(name string) (string, error) {
+	return "", errS3Symlinks
+}
+
+func (f *s3Filesystem) Remove(name string) error {
+	key, err := f.key("remove", name)
+	if err != nil {
+		return err
+	}
+	info, err := f.Lstat(name)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		names, err := f.DirNames(name)
+		if err != nil {
+			return err
+		}
+		if len(names) > 0 {
+			return &os.PathError{Op: "remove", Path: name, Err: errS3NotEmpty}
+		}
+		key = dirPrefix(key)
+	}
+	if err := f.client.delete(key); err != nil {
+		return &os.PathError{Op: "remove", Path: name, Err: err}
+	}
+	return nil
+}
+
+func (f *s3Filesystem) RemoveAll(name string) error {
+	key, err := f.key("remove", name)
+	if err != nil {
+		return err
+	}
+	if key != f.prefix {
+		if err := f.client.delete(key); err != nil && !IsNotExist(err) {
+			return &os.PathError{Op: "remove", Path: name, Err: err}
+		}
+	}
+	var keys []string
+	err = f.client.list(dirPrefix(key), "", s3ListPageSize, func(res *s3ListResult) bool {
+		for _, obj := range res.Contents {
+			keys = append(keys, obj.Key)
+		}
+		return true
+	})
+	if err != nil {
+		return &os.PathError{Op: "remove", Path: name, Err: err}
+	}
+	for _, k := range keys {
+		if err := f.client.delete(k); err != nil && !IsNotExist(err) {
+			return &os.PathError{Op: "remove", Path: name, Err: err}
+		}
+	}
+	return nil
+}
+
+// Rename copies and deletes, as objects can't be renamed. A directory is
+// renamed by doing that for everything within it.
+func (f *s3Filesystem) Rename(oldname, newname string) error {
+	from, err := f.key("rename", oldname)
+	if err != nil {
+		return err
+	}
+	to, err := f.key("rename", newname)
+	if err != nil {
+		return err
+	}
+	if from == to {
+		return nil
+	}
+	info, err := f.Lstat(oldname)
+	if err != nil {
+		return err
+	}
+	// Like rename(2), replace an existing file, but not a directory.
+	if existing, err := f.Lstat(newname); err == nil && existing.IsDir() {
+		return &os.PathError{Op: "rename", Path: newname, Err: os.ErrExist}
+	}
+
+	if !info.IsDir() {
+		if err := f.client.copy(from, to); err != nil {
+			return &os.PathError{Op: "rename", Path: oldname, Err: err}
+		}
+		if err := f.client.delete(from); err != nil {
+			return &os.PathError{Op: "rename", Path: oldname, Err: err}
+		}
+		return nil
+	}
+
+	var keys []string
+	err = f.client.list(dirPrefix(from), "", s3ListPageSize, func(res *s3ListResult) bool {
+		for _, obj := range res.Contents {
+			keys = append(keys, obj.Key)
+		}
+		return true
+	})
+	if err == nil && len(keys) == 0 {
+		// Only implied by what's been removed since
+		err = os.ErrNotExist
+	}
+	for _, k := range keys {
+		if err != nil {
+			break
+		}
+		if err = f.client.copy(k, dirPrefix(to)+k[len(dirPrefix(from)):]); err == nil {
+			err = f.client.delete(k)
+		}
+	}
+	if err != nil {
+		return &os.PathError{Op: "rename", Path: oldname, Err: err}
+	}
+	return nil
+}
+
+func (f *s3Filesystem) Stat(name string) (FileInfo, error) {
+	return f.Lstat(name)
+}
+
+func (f *s3Filesystem) SymlinksSupported() bool {
+	return false
+}
+
+func (f *s3Filesystem) Walk(name string, walkFn WalkFunc) error {
+	return errors.New("not implemented")
+}
+
+func (f *s3Filesystem) Watch(path string, ignore Matcher, ctx context.Context, ignorePerms bool) (<-chan Event, <-chan error, error) {
+	return nil, nil, ErrWatchNotSupported
+}
+
+func (f *s3Filesystem) Hide(name string) error {
+	return nil
+}
+
+func (f *s3Filesystem) Unhide(name string) error {
+	return nil
+}
+
+func (f *s3Filesystem) Glob(pattern string) ([]string, error) {
+	// Matching in the last path component is enough for our use.
+	dir, base := filepath.Split(pattern)
+	dir = filepath.Clean(dir)
+	names, err := f.DirNames(dir)
+	if IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var matches []string
+	for _, name := range names {
+		if ok, err := filepath.Match(base, name); err != nil {
+			return nil, err
+		} else if ok {
+			matches = append(matches, filepath.Join(dir, name))
+		}
+	}
+	return matches, nil
+}
+
+func (f *s3Filesystem) Roots() ([]string, error) {
+	return []string{f.uri}, nil
+}
+
+func (f *s3Filesystem) Usage(name string) (Usage, error) {
+	// Buckets have no set size.
+	return Usage{}, errS3Usage
+}
+
+func (f *s3Filesystem) Type() FilesystemType {
+	return FilesystemTypeS3
+}
+
+func (f *s3Filesystem) URI() string {
+	return f.uri
+}
+
+func (f *s3Filesystem) SameFile(fi1, fi2 FileInfo) bool {
+	f1, ok1 := fi1.(s3FileInfo)
+	f2, ok2 := fi2.(s3FileInfo)
+	return ok1 && ok2 && f1.key == f2.key && f1.dir == f2.dir
+}
+
+func (f *s3Filesystem) GetXattr(name string, filter XattrFilter) ([]Xattr, error) {
+	return nil, ErrXattrsNotSupported
+}
+
+func (f *s3Filesystem) SetXattr(name string, xattrs []Xattr, filter XattrFilter) error {
+	return ErrXattrsNotSupported
+}
+
+// s3File is an open object. Reads go to the object until something is
+// written, from when on the object is kept in a local spool file until
+// uploaded.
+type s3File struct {
+	fs    *s3Filesystem
+	name  string
+	key   string
+	write bool
+
+	mut    sync.Mutex
+	offset int64
+	size   int64    // of the object, while not spooled
+	spool  *os.File // nil while not spooled
+	dirty  bool
+}
+
+// ensureSpool downloads the object into a spool file, unless already done.
+// Must be called with the lock held.
+func (f *s3File) ensureSpool() error {
+	if f.spool != nil {
+		return nil
+	}
+	if !f.write {
+		return os.ErrPermission
+	}
+	spool, err := ioutil.TempFile("", "syncthing-s3-")
+	if err != nil {
+		return err
+	}
+	if f.size > 0 {
+		if err := f.fs.client.get(f.key, spool); err != nil {
+			spool.Close()
+			os.Remove(spool.Name())
+			return err
+		}
+	}
+	f.spool = spool
+	return nil
+}
+
+// truncateSpool starts an empty spool file, to replace the object.
+func (f *s3File) truncateSpool() error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	f.size = 0
+	if err := f.ensureSpool(); err != nil {
+		return err
+	}
+	f.dirty = true
+	return f.spool.Truncate(0)
+}
+
+func (f *s3File) ReadAt(p []byte, off int64) (int, error) {
+	f.mut.Lock()
+	spool := f.spool
+	f.mut.Unlock()
+	if spool != nil {
+		return spool.ReadAt(p, off)
+	}
+	return f.fs.client.getRange(f.key, p, off)
+}
+
+func (f *s3File) Read(p []byte) (int, error) {
+	f.mut.Lock()
+	offset := f.offset
+	f.mut.Unlock()
+	n, err := f.ReadAt(p, offset)
+	f.mut.Lock()
+	f.offset = offset + int64(n)
+	f.mut.Unlock()
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+func (f *s3File) WriteAt(p []byte, off int64) (int, error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	if err := f.ensureSpool(); err != nil {
+		return 0, &os.PathError{Op: "write", Path: f.name, Err: err}
+	}
+	f.dirty = true
+	return f.spool.WriteAt(p, off)
+}
+
+func (f *s3File) Write(p []byte) (int, error) {
+	f.mut.Lock()
+	offset := f.offset
+	f.mut.Unlock()
+	n, err := f.WriteAt(p, offset)
+	f.mut.Lock()
+	f.offset = offset + int64(n)
+	f.mut.Unlock()
+	return n, err
+}
+
+func (f *s3File) Seek(offset int64, whence int) (int64, error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		size, err := f.sizeLocked()
+		if err != nil {
+			return f.offset, err
+		}
+		offset += size
+	}
+	if offset < 0 {
+		return f.offset, errors.New("negative position")
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+func (f *s3File) sizeLocked() (int64, error) {
+	if f.spool == nil {
+		return f.size, nil
+	}
+	info, err := f.spool.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func (f *s3File) Truncate(size int64) error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	if err := f.ensureSpool(); err != nil {
+		return &os.PathError{Op: "truncate", Path: f.name, Err: err}
+	}
+	f.dirty = true
+	return f.spool.Truncate(size)
+}
+
+// Sync uploads what has been written.
+func (f *s3File) Sync() error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	return f.syncLocked()
+}
+
+func (f *s3File) syncLocked() error {
+	if !f.dirty {
+		return nil
+	}
+	size, err := f.sizeLocked()
+	if err != nil {
+		return err
+	}
+	if err := f.fs.client.put(f.key, io.NewSectionReader(f.spool, 0, size), size); err != nil {
+		return &os.PathError{Op: "sync", Path: f.name, Err: err}
+	}
+	f.dirty = false
+	return nil
+}
+
+func (f *s3File) Close() error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	if f.spool == nil {
+		return nil
+	}
+	err := f.syncLocked()
+	f.spool.Close()
+	os.Remove(f.spool.Name())
+	f.spool = nil
+	return err
+}
+
+func (f *s3File) Name() string {
+	return f.name
+}
+
+func (f *s3File) Stat() (FileInfo, error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	if f.spool == nil {
+		return f.fs.Lstat(f.name)
+	}
+	size, err := f.sizeLocked()
+	if err != nil {
+		return nil, err
+	}
+	return s3FileInfo{key: f.key, size: size, modTime: time.Now()}, nil
+}
+
+type s3FileInfo struct {
+	key     string
+	size    int64
+	modTime time.Time
+	dir     bool
+}
+
+func (fi s3FileInfo) Name() string {
+	if fi.key == "" {
+		return "."
+	}
+	return path.Base(fi.key)
+}
+
+func (fi s3FileInfo) Mode() FileMode {
+	if fi.dir {
+		return FileMode(os.ModeDir | 0755)
+	}
+	return 0644
+}
+
+func (fi s3FileInfo) Size() int64 {
+	return fi.size
+}
+
+func (fi s3FileInfo) ModTime() time.Time {
+	return fi.modTime
+}
+
+func (fi s3FileInfo) IsDir() bool {
+	return fi.dir
+}
+
+func (fi s3FileInfo) IsRegular() bool {
+	return !fi.dir
+}
+
+func (fi s3FileInfo) IsSymlink() bool {
+	return false
+}
+
+func (fi s3FileInfo) Owner() int {
+	return -1
+}
+
+func (fi s3FileInfo) Group() int {
+	return -1
+}
@@ -0,0 +1,60 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import "errors"
+
+var errPunchHoleNotSupported = errors.New("punching holes is not supported")
+
+// holePuncher is a File that can deallocate ranges of itself.
+type holePuncher interface {
+	PunchHole(offset, size int64) error
+}
+
+// IsZeroes returns true if the data is all zero bytes, i.e. it can be a
+// hole in a sparse file.
+func IsZeroes(data []byte) bool {
+	for _, b := range data {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// PunchHole makes the range of the file read as zeroes. Where the file
+// can be sparse the range is deallocated, otherwise zeroes are written to
+// it. The size of the file doesn't change, as long as the range is within
+// it.
+func PunchHole(fd File, offset, size int64) error {
+	if p, ok := fd.(holePuncher); ok {
+		err := p.PunchHole(offset, size)
+		if err == nil {
+			return nil
+		}
+		l.Debugf("Punching hole in %s: %v; writing zeroes", fd.Name(), err)
+	}
+	return writeZeroes(fd, offset, size)
+}
+
+func writeZeroes(fd File, offset, size int64) error {
+	const maxChunk = 128 << 10
+	zeroes := make([]byte, maxChunk)
+	for size > 0 {
+		chunk := zeroes
+		if size < maxChunk {
+			chunk = zeroes[:size]
+		}
+		n, err := fd.WriteAt(chunk, offset)
+		if err != nil {
+			return err
+		}
+		offset += int64(n)
+		size -= int64(n)
+	}
+	return nil
+}
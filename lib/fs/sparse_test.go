@@ -0,0 +1,67 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIsZeroes(t *testing.T) {
+	if !IsZeroes(nil) || !IsZeroes(make([]byte, 100)) {
+		t.Error("Expected zeroes")
+	}
+	data := make([]byte, 100)
+	data[99] = 1
+	if IsZeroes(data) {
+		t.Error("Unexpected zeroes")
+	}
+}
+
+func TestPunchHole(t *testing.T) {
+	fs, dir := setup(t)
+	defer os.RemoveAll(dir)
+
+	data := bytes.Repeat([]byte{0xff}, 3<<16)
+	expected := append([]byte(nil), data...)
+	for i := 1 << 16; i < 2<<16+10; i++ {
+		expected[i] = 0
+	}
+
+	// Files that can't punch holes get zeroes written instead.
+	for _, wrap := range []func(File) File{
+		func(fd File) File { return fd },
+		func(fd File) File { return noHolesFile{fd} },
+	} {
+		fd, err := fs.Create("file")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fd.Write(data); err != nil {
+			t.Fatal(err)
+		}
+		if err := PunchHole(wrap(fd), 1<<16, 1<<16+10); err != nil {
+			t.Fatal(err)
+		}
+		fd.Close()
+
+		read, err := ioutil.ReadFile(filepath.Join(dir, "file"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(read, expected) {
+			t.Errorf("Unexpected data after punching a hole in %T", wrap(fd))
+		}
+	}
+}
+
+type noHolesFile struct {
+	File
+}
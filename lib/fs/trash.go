@@ -0,0 +1,28 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"errors"
+	"path/filepath"
+)
+
+var ErrTrashUnsupported = errors.New("moving to the trash is not supported for this filesystem or platform")
+
+// MoveToTrash moves the named file or directory to the trash of the
+// operating system, where it can be restored from like anything else
+// deleted by the user. Only basic filesystems have one.
+func MoveToTrash(filesystem Filesystem, name string) error {
+	if filesystem.Type() != FilesystemTypeBasic {
+		return ErrTrashUnsupported
+	}
+	name, err := Canonicalize(name)
+	if err != nil {
+		return err
+	}
+	return moveToTrash(filepath.Join(filesystem.URI(), name))
+}
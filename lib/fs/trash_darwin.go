@@ -0,0 +1,52 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// moveToTrash moves things to the trash in the home directory, or when
+// that's on another volume, to the trash of the volume.
+func moveToTrash(path string) error {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+	err = moveToTrashDir(filepath.Join(home, ".Trash"), path)
+	if !isCrossDevice(err) {
+		return err
+	}
+
+	top, err := mountRoot(path)
+	if err != nil {
+		return err
+	}
+	return moveToTrashDir(filepath.Join(top, ".Trashes", strconv.Itoa(os.Getuid())), path)
+}
+
+// moveToTrashDir moves the path into the trash directory, under a name
+// like the Finder's when there's already something by the same name.
+func moveToTrashDir(trash, path string) error {
+	if err := os.MkdirAll(trash, 0700); err != nil {
+		return err
+	}
+	base := filepath.Base(path)
+	ext := filepath.Ext(base)
+	name := base
+	for i := 2; ; i++ {
+		if _, err := os.Lstat(filepath.Join(trash, name)); os.IsNotExist(err) {
+			break
+		} else if err != nil {
+			return err
+		}
+		name = base[:len(base)-len(ext)] + " " + strconv.Itoa(i) + ext
+	}
+	return os.Rename(path, filepath.Join(trash, name))
+}
@@ -0,0 +1,89 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build !windows,!darwin
+
+package fs
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// moveToTrash follows the FreeDesktop.org trash specification. Things go
+// to the trash in the home directory, or when that's on another mount, to
+// a trash at the top of the mount they're on.
+func moveToTrash(path string) error {
+	home, err := homeTrash()
+	if err != nil {
+		return err
+	}
+	err = moveToTrashDir(home, path, path)
+	if !isCrossDevice(err) {
+		return err
+	}
+
+	top, err := mountRoot(path)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(top, path)
+	if err != nil {
+		return err
+	}
+	return moveToTrashDir(filepath.Join(top, ".Trash-"+strconv.Itoa(os.Getuid())), path, rel)
+}
+
+func homeTrash() (string, error) {
+	if dataHome := os.Getenv("XDG_DATA_HOME"); dataHome != "" {
+		return filepath.Join(dataHome, "Trash"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", "Trash"), nil
+}
+
+// moveToTrashDir moves the path into the trash directory, recording the
+// original path, relative to the top used for the trash, so that it can be
+// restored. The info file is created first, to claim the name.
+func moveToTrashDir(trash, path, origPath string) error {
+	files, info := filepath.Join(trash, "files"), filepath.Join(trash, "info")
+	for _, dir := range []string{files, info} {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return err
+		}
+	}
+
+	base := filepath.Base(path)
+	name := base
+	for i := 2; ; i++ {
+		fd, err := os.OpenFile(filepath.Join(info, name+".trashinfo"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if os.IsExist(err) {
+			name = base + "." + strconv.Itoa(i)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(fd, "[Trash Info]\nPath=%s\nDeletionDate=%s\n", (&url.URL{Path: origPath}).EscapedPath(), time.Now().Format("2006-01-02T15:04:05"))
+		if cerr := fd.Close(); err == nil {
+			err = cerr
+		}
+		if err == nil {
+			err = os.Rename(path, filepath.Join(files, name))
+		}
+		if err != nil {
+			os.Remove(fd.Name())
+		}
+		return err
+	}
+}
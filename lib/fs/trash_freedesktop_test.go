@@ -0,0 +1,68 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build !windows,!darwin
+
+package fs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMoveToTrash(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	oldDataHome := os.Getenv("XDG_DATA_HOME")
+	defer os.Setenv("XDG_DATA_HOME", oldDataHome)
+	os.Setenv("XDG_DATA_HOME", filepath.Join(dir, "data"))
+	trash := filepath.Join(dir, "data", "Trash")
+
+	fs := NewFilesystem(FilesystemTypeBasic, filepath.Join(dir, "folder"))
+	if err := fs.MkdirAll("sub dir", 0755); err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join("sub dir", "file")
+	for i := 0; i < 2; i++ {
+		fd, err := fs.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fd.Close()
+		if err := MoveToTrash(fs, name); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fs.Lstat(name); !IsNotExist(err) {
+			t.Errorf("file still there after moving to the trash: %v", err)
+		}
+	}
+
+	// The second one doesn't overwrite the first
+	for _, trashed := range []string{"file", "file.2"} {
+		if _, err := os.Lstat(filepath.Join(trash, "files", trashed)); err != nil {
+			t.Error(err)
+		}
+		bs, err := ioutil.ReadFile(filepath.Join(trash, "info", trashed+".trashinfo"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := "Path=" + filepath.Join(dir, "folder", "sub%20dir", "file") + "\n"
+		if !strings.HasPrefix(string(bs), "[Trash Info]\n") || !strings.Contains(string(bs), expected) {
+			t.Errorf("unexpected trash info %q", bs)
+		}
+	}
+
+	if err := MoveToTrash(NewFilesystem(FilesystemTypeFake, "/TestMoveToTrash"), "file"); err != ErrTrashUnsupported {
+		t.Errorf("fake filesystem: got %v, expected %v", err, ErrTrashUnsupported)
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build !windows
+
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// mountRoot returns the top directory of the mount the path is on, where
+// the trash for the mount lives.
+func mountRoot(path string) (string, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return "", err
+	}
+	dev := info.Sys().(*syscall.Stat_t).Dev
+	for {
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path, nil
+		}
+		info, err := os.Lstat(parent)
+		if err != nil {
+			return "", err
+		}
+		if info.Sys().(*syscall.Stat_t).Dev != dev {
+			return path, nil
+		}
+		path = parent
+	}
+}
+
+// isCrossDevice returns whether a rename failed for being across mounts.
+func isCrossDevice(err error) bool {
+	if le, ok := err.(*os.LinkError); ok {
+		return le.Err == syscall.EXDEV
+	}
+	return false
+}
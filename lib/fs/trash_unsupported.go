@@ -0,0 +1,13 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build windows,386 windows,arm
+
+package fs
+
+func moveToTrash(path string) error {
+	return ErrTrashUnsupported
+}
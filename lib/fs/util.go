@@ -13,6 +13,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"unicode/utf16"
 )
 
 var errNoHome = errors.New("no home directory found - set $HOME (or the platform equivalent)")
@@ -55,20 +56,77 @@ var windowsDisallowedCharacters = string([]rune{
 	31,
 })
 
+// The longest file name (not path) possible on Windows, in UTF-16 code
+// units. Longer paths are fine given the long filename support of the basic
+// filesystem.
+const windowsMaxNameLen = 255
+
+var windowsReservedNames = map[string]struct{}{
+	"CON": {}, "PRN": {}, "AUX": {}, "NUL": {},
+	"COM1": {}, "COM2": {}, "COM3": {}, "COM4": {}, "COM5": {}, "COM6": {}, "COM7": {}, "COM8": {}, "COM9": {},
+	"LPT1": {}, "LPT2": {}, "LPT3": {}, "LPT4": {}, "LPT5": {}, "LPT6": {}, "LPT7": {}, "LPT8": {}, "LPT9": {},
+}
+
+// InvalidFilenameError is the reason a file name can't be used on Windows.
+// It is an ErrInvalidFilename as seen by errors.Is.
+type InvalidFilenameError struct {
+	Name   string
+	Reason string
+}
+
+func (e *InvalidFilenameError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrInvalidFilename, e.Reason)
+}
+
+func (e *InvalidFilenameError) Unwrap() error {
+	return ErrInvalidFilename
+}
+
 func WindowsInvalidFilename(name string) bool {
-	// None of the path components should end in space
-	for _, part := range strings.Split(name, `\`) {
-		if len(part) == 0 {
-			continue
-		}
-		if part[len(part)-1] == ' ' {
-			// Names ending in space are not valid.
-			return true
+	return WindowsFilenameError(name) != nil
+}
+
+// WindowsFilenameError returns an *InvalidFilenameError if the name can't
+// be used on Windows, or nil.
+func WindowsFilenameError(name string) error {
+	for _, part := range strings.Split(name, string(PathSeparator)) {
+		if reason := windowsInvalidName(part); reason != "" {
+			return &InvalidFilenameError{Name: name, Reason: reason}
 		}
 	}
+	return nil
+}
 
-	// The path must not contain any disallowed characters
-	return strings.ContainsAny(name, windowsDisallowedCharacters)
+// windowsInvalidName returns why a single path component can't be used on
+// Windows, or the empty string.
+func windowsInvalidName(part string) string {
+	if part == "" || part == "." || part == ".." {
+		return ""
+	}
+	switch {
+	case part[len(part)-1] == ' ' || part[len(part)-1] == '.':
+		// Names ending in space or period are not valid (the period
+		// would be silently removed).
+		return "ends with a space or period"
+	case strings.ContainsAny(part, windowsDisallowedCharacters):
+		return "contains a character that is not allowed"
+	case windowsReservedName(part):
+		return "is a reserved name"
+	case len(utf16.Encode([]rune(part))) > windowsMaxNameLen:
+		return fmt.Sprintf("is longer than %d characters", windowsMaxNameLen)
+	}
+	return ""
+}
+
+// windowsReservedName returns true for the device names, which are
+// reserved regardless of case or extension.
+func windowsReservedName(part string) bool {
+	stem := part
+	if i := strings.IndexByte(stem, '.'); i >= 0 {
+		stem = stem[:i]
+	}
+	_, ok := windowsReservedNames[strings.ToUpper(stem)]
+	return ok
 }
 
 // IsParent compares paths purely lexicographically, meaning it returns false
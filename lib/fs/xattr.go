@@ -0,0 +1,56 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fs
+
+import "errors"
+
+var (
+	ErrXattrsNotSupported = errors.New("extended attributes are not supported")
+	ErrXattrsTooLarge     = errors.New("extended attributes are too large")
+)
+
+// The extended attributes of a file are sent along with it in the index,
+// so their size is limited, per attribute value and in total with the
+// names. Reading or setting more than that fails with ErrXattrsTooLarge.
+const (
+	MaxXattrValueSize = 64 << 10 // the largest that Linux supports
+	MaxXattrsSize     = 256 << 10
+)
+
+// Xattr is an extended attribute of a file or directory. On Linux that
+// includes the POSIX ACLs, as system.posix_acl_access and
+// system.posix_acl_default, and the SELinux label, as security.selinux.
+type Xattr struct {
+	Name  string
+	Value []byte
+}
+
+// XattrFilter decides which extended attributes are handled, by name. A
+// nil filter permits all of them.
+type XattrFilter interface {
+	Permit(name string) bool
+}
+
+func xattrPermitted(filter XattrFilter, name string) bool {
+	return filter == nil || filter.Permit(name)
+}
+
+// xattrSizeError returns ErrXattrsTooLarge if the attributes are over the
+// limits.
+func xattrSizeError(xattrs []Xattr) error {
+	total := 0
+	for _, xattr := range xattrs {
+		if len(xattr.Value) > MaxXattrValueSize {
+			return ErrXattrsTooLarge
+		}
+		total += len(xattr.Name) + len(xattr.Value)
+	}
+	if total > MaxXattrsSize {
+		return ErrXattrsTooLarge
+	}
+	return nil
+}
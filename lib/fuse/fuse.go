@@ -0,0 +1,38 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// Package fuse mounts a read only tree of files as a FUSE filesystem,
+// served by go-fuse.
+package fuse
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrNotSupported = errors.New("FUSE mounts are not supported on this platform")
+
+// A Tree is what's mounted. Names are relative to the root, which is ".".
+// Methods return an error satisfying os.IsNotExist for items that don't
+// exist. They are called concurrently.
+type Tree interface {
+	Stat(name string) (Entry, error)
+	// List returns the items directly within a directory.
+	List(dir string) ([]Entry, error)
+	// ReadAt reads from a file like io.ReaderAt, which may take a while.
+	ReadAt(name string, p []byte, off int64) (int, error)
+	ReadLink(name string) (string, error)
+}
+
+// An Entry is an item of a Tree.
+type Entry struct {
+	Name        string // within its directory
+	Dir         bool
+	Symlink     bool
+	Size        int64
+	ModTime     time.Time
+	Permissions uint32
+}
@@ -0,0 +1,16 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build !linux
+
+package fuse
+
+import "context"
+
+// Serve mounts the tree at the directory until the context is cancelled.
+func Serve(ctx context.Context, dir string, tree Tree) error {
+	return ErrNotSupported
+}
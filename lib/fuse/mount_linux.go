@@ -0,0 +1,76 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fuse
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// validTime is how long the kernel may cache what it's told.
+const validTime = time.Second
+
+// Serve mounts the tree at the directory until the context is cancelled,
+// or it's unmounted by someone else. Mounting needs the fusermount helper.
+func Serve(ctx context.Context, dir string, tree Tree) error {
+	timeout := validTime
+	server, err := fs.Mount(dir, &node{tree: tree, name: "."}, &fs.Options{
+		MountOptions: fuse.MountOptions{
+			FsName:  "syncthing",
+			Name:    "syncthing",
+			Options: []string{"ro", "default_permissions"},
+		},
+		EntryTimeout: &timeout,
+		AttrTimeout:  &timeout,
+		UID:          uint32(os.Getuid()),
+		GID:          uint32(os.Getgid()),
+	})
+	if err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	go func() {
+		server.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		if err := server.Unmount(); err != nil {
+			// Files are still open, so it goes away once they are
+			// closed.
+			l.Debugf("Unmounting %s: %v", dir, err)
+			if err := unmountLazily(dir); err != nil {
+				l.Infof("Unmounting %s: %v", dir, err)
+			}
+		}
+		<-done
+	}
+	return nil
+}
+
+func unmountLazily(dir string) error {
+	for _, name := range []string{"fusermount3", "fusermount"} {
+		helper, err := exec.LookPath(name)
+		if err != nil {
+			continue
+		}
+		if out, err := exec.Command(helper, "-u", "-z", dir).CombinedOutput(); err != nil {
+			return fmt.Errorf("%s: %v: %s", helper, err, out)
+		}
+		return nil
+	}
+	return fmt.Errorf("fusermount not found")
+}
@@ -0,0 +1,151 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fuse
+
+import (
+	"context"
+	"hash/fnv"
+	"io"
+	"os"
+	"syscall"
+
+	"github.com/hanwen/go-fuse/v2/fs"
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+// node is an item of the tree, by its name, as the kernel knows it.
+type node struct {
+	fs.Inode
+	tree Tree
+	name string
+}
+
+var (
+	_ fs.NodeLookuper   = (*node)(nil)
+	_ fs.NodeGetattrer  = (*node)(nil)
+	_ fs.NodeReaddirer  = (*node)(nil)
+	_ fs.NodeReadlinker = (*node)(nil)
+	_ fs.NodeOpener     = (*node)(nil)
+	_ fs.NodeReader     = (*node)(nil)
+)
+
+func (n *node) Lookup(ctx context.Context, name string, out *fuse.EntryOut) (*fs.Inode, syscall.Errno) {
+	child := &node{tree: n.tree, name: joinName(n.name, name)}
+	entry, err := n.tree.Stat(child.name)
+	if err != nil {
+		return nil, errno(err)
+	}
+	setAttr(&out.Attr, entry)
+	return n.NewInode(ctx, child, fs.StableAttr{Mode: entry.mode() & syscall.S_IFMT, Ino: inode(child.name)}), 0
+}
+
+func (n *node) Getattr(ctx context.Context, _ fs.FileHandle, out *fuse.AttrOut) syscall.Errno {
+	entry, err := n.tree.Stat(n.name)
+	if err != nil {
+		return errno(err)
+	}
+	setAttr(&out.Attr, entry)
+	return 0
+}
+
+func (n *node) Readdir(ctx context.Context) (fs.DirStream, syscall.Errno) {
+	entries, err := n.tree.List(n.name)
+	if err != nil {
+		return nil, errno(err)
+	}
+	list := make([]fuse.DirEntry, len(entries))
+	for i, entry := range entries {
+		list[i] = fuse.DirEntry{
+			Name: entry.Name,
+			Mode: entry.mode(),
+			Ino:  inode(joinName(n.name, entry.Name)),
+		}
+	}
+	return fs.NewListDirStream(list), 0
+}
+
+func (n *node) Readlink(ctx context.Context) ([]byte, syscall.Errno) {
+	target, err := n.tree.ReadLink(n.name)
+	if err != nil {
+		return nil, errno(err)
+	}
+	return []byte(target), 0
+}
+
+func (n *node) Open(ctx context.Context, flags uint32) (fs.FileHandle, uint32, syscall.Errno) {
+	if flags&syscall.O_ACCMODE != syscall.O_RDONLY {
+		return nil, 0, syscall.EROFS
+	}
+	return nil, 0, 0
+}
+
+func (n *node) Read(ctx context.Context, _ fs.FileHandle, dest []byte, off int64) (fuse.ReadResult, syscall.Errno) {
+	// Anything short of the size asked for is taken as the end of the
+	// file.
+	read, err := n.tree.ReadAt(n.name, dest, off)
+	if err != nil && err != io.EOF {
+		return nil, errno(err)
+	}
+	return fuse.ReadResultData(dest[:read]), 0
+}
+
+// setAttr sets the attributes of the entry. The owner is filled in from
+// the mount options.
+func setAttr(attr *fuse.Attr, entry Entry) {
+	attr.Mode = entry.mode()
+	attr.Size = uint64(entry.Size)
+	attr.Blocks = (attr.Size + 511) / 512
+	attr.Blksize = 4096
+	attr.Nlink = 1
+	if entry.Dir {
+		attr.Nlink = 2
+	}
+	attr.SetTimes(&entry.ModTime, &entry.ModTime, &entry.ModTime)
+}
+
+// mode returns the file mode of the entry, without write permissions as
+// everything is read only.
+func (entry Entry) mode() uint32 {
+	perm := entry.Permissions & 0555
+	switch {
+	case entry.Dir:
+		return syscall.S_IFDIR | perm
+	case entry.Symlink:
+		return syscall.S_IFLNK | 0777
+	default:
+		return syscall.S_IFREG | perm
+	}
+}
+
+func joinName(dir, name string) string {
+	if dir == "." {
+		return name
+	}
+	return dir + "/" + name
+}
+
+// inode returns a stable inode number for the name.
+func inode(name string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return h.Sum64()
+}
+
+// errno returns the error number to return to the kernel for the error.
+func errno(err error) syscall.Errno {
+	switch {
+	case os.IsNotExist(err):
+		return syscall.ENOENT
+	case os.IsPermission(err):
+		return syscall.EACCES
+	}
+	if en, ok := err.(syscall.Errno); ok {
+		return en
+	}
+	l.Debugln("fuse:", err)
+	return syscall.EIO
+}
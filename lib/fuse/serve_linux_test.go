@@ -0,0 +1,146 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package fuse
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hanwen/go-fuse/v2/fuse"
+)
+
+type memTree map[string]string // name -> content, or "/" for directories
+
+func (t memTree) Stat(name string) (Entry, error) {
+	content, ok := t[name]
+	if !ok && name != "." {
+		return Entry{}, os.ErrNotExist
+	}
+	if name == "." || content == "/" {
+		return Entry{Name: name, Dir: true, Permissions: 0755}, nil
+	}
+	return Entry{Name: name, Size: int64(len(content)), Permissions: 0644, ModTime: time.Unix(1500000000, 0)}, nil
+}
+
+func (t memTree) List(dir string) ([]Entry, error) {
+	var entries []Entry
+	for name := range t {
+		if joinName(dir, name[strings.LastIndex(name, "/")+1:]) == name {
+			entry, _ := t.Stat(name)
+			entry.Name = name[strings.LastIndex(name, "/")+1:]
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(a, b int) bool { return entries[a].Name < entries[b].Name })
+	return entries, nil
+}
+
+func (t memTree) ReadAt(name string, p []byte, off int64) (int, error) {
+	content := t[name]
+	if off >= int64(len(content)) {
+		return 0, nil
+	}
+	return copy(p, content[off:]), nil
+}
+
+func (t memTree) ReadLink(name string) (string, error) {
+	return "", syscall.EINVAL
+}
+
+func TestNode(t *testing.T) {
+	ctx := context.Background()
+	tree := memTree{"dir": "/", "dir/file": "hello world", "top": "x"}
+
+	// Attributes, without write permissions
+	var out fuse.AttrOut
+	if errno := (&node{tree: tree, name: "dir"}).Getattr(ctx, nil, &out); errno != 0 || out.Mode != syscall.S_IFDIR|0555 {
+		t.Errorf("Unexpected directory attributes %v %o", errno, out.Mode)
+	}
+	file := &node{tree: tree, name: "dir/file"}
+	if errno := file.Getattr(ctx, nil, &out); errno != 0 || out.Size != 11 || out.Mtime != 1500000000 {
+		t.Errorf("Unexpected file attributes %v %+v", errno, out)
+	}
+	if errno := (&node{tree: tree, name: "nonexistent"}).Getattr(ctx, nil, &out); errno != syscall.ENOENT {
+		t.Errorf("Unexpected attributes of a missing file %v", errno)
+	}
+
+	// Opening and reading
+	if _, _, errno := file.Open(ctx, syscall.O_RDWR); errno != syscall.EROFS {
+		t.Errorf("Unexpected open for writing %v", errno)
+	}
+	if _, _, errno := file.Open(ctx, syscall.O_RDONLY); errno != 0 {
+		t.Errorf("Unexpected open for reading %v", errno)
+	}
+	res, errno := file.Read(ctx, nil, make([]byte, 4096), 6)
+	if errno != 0 {
+		t.Fatal(errno)
+	}
+	if bs, status := res.Bytes(nil); !status.Ok() || string(bs) != "world" {
+		t.Errorf("Unexpected read %v %q", status, bs)
+	}
+
+	// Listing the root
+	stream, errno := (&node{tree: tree, name: "."}).Readdir(ctx)
+	if errno != 0 {
+		t.Fatal(errno)
+	}
+	var names []string
+	for stream.HasNext() {
+		entry, errno := stream.Next()
+		if errno != 0 {
+			t.Fatal(errno)
+		}
+		names = append(names, entry.Name)
+	}
+	if strings.Join(names, " ") != "dir top" {
+		t.Errorf("Unexpected listing %v", names)
+	}
+}
+
+func TestServe(t *testing.T) {
+	if _, err := exec.LookPath("fusermount"); err != nil {
+		t.Skip("fusermount not available")
+	}
+	dir, err := ioutil.TempDir("", "fuse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- Serve(ctx, dir, memTree{"dir": "/", "dir/file": "hello world"}) }()
+
+	// Wait for the mount to show up
+	var bs []byte
+	for start := time.Now(); time.Since(start) < 10*time.Second; time.Sleep(10 * time.Millisecond) {
+		select {
+		case err := <-done:
+			t.Skip("Mounting:", err)
+		default:
+		}
+		if bs, err = ioutil.ReadFile(filepath.Join(dir, "dir", "file")); err == nil {
+			break
+		}
+	}
+	if err != nil || string(bs) != "hello world" {
+		t.Errorf("Unexpected read through the mount %v %q", err, bs)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Error(err)
+	}
+}
@@ -6,7 +6,10 @@
 
 package ignore
 
-import "time"
+import (
+	"encoding/binary"
+	"time"
+)
 
 type nower interface {
 	Now() time.Time
@@ -17,6 +20,14 @@ var clock = nower(defaultClock{})
 type cache struct {
 	patterns []Pattern
 	entries  map[string]cacheEntry
+	dirty    bool // changed since loaded or saved
+}
+
+// A CacheStore keeps the cached match results across restarts, for the
+// patterns with the given hash. The results are opaque to the store.
+type CacheStore interface {
+	LoadCache(hash string) ([]byte, error)
+	SaveCache(hash string, results []byte) error
 }
 
 type cacheEntry struct {
@@ -35,6 +46,7 @@ func (c *cache) clean(d time.Duration) {
 	for k, v := range c.entries {
 		if clock.Now().Sub(v.access) > d {
 			delete(c.entries, k)
+			c.dirty = true
 		}
 	}
 }
@@ -50,6 +62,37 @@ func (c *cache) get(key string) (Result, bool) {
 
 func (c *cache) set(key string, result Result) {
 	c.entries[key] = cacheEntry{result, time.Now()}
+	c.dirty = true
+}
+
+// encode returns the results as a sequence of the length of the name, the
+// name and the result for each entry.
+func (c *cache) encode() []byte {
+	var bs []byte
+	var buf [binary.MaxVarintLen64]byte
+	for k, v := range c.entries {
+		n := binary.PutUvarint(buf[:], uint64(len(k)))
+		bs = append(bs, buf[:n]...)
+		bs = append(bs, k...)
+		bs = append(bs, byte(v.result))
+	}
+	return bs
+}
+
+// decode adds the encoded results, as accessed now, returning false if
+// they're invalid.
+func (c *cache) decode(bs []byte) bool {
+	now := clock.Now()
+	for len(bs) > 0 {
+		size, n := binary.Uvarint(bs)
+		if n <= 0 || uint64(len(bs)-n) <= size {
+			return false
+		}
+		bs = bs[n:]
+		c.entries[string(bs[:size])] = cacheEntry{Result(bs[size]), now}
+		bs = bs[size+1:]
+	}
+	return true
 }
 
 func (c *cache) len() int {
@@ -7,8 +7,11 @@
 package ignore
 
 import (
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/syncthing/syncthing/lib/fs"
 )
 
 func TestCache(t *testing.T) {
@@ -83,6 +86,70 @@ func TestCache(t *testing.T) {
 	}
 }
 
+func TestCacheStore(t *testing.T) {
+	store := make(fakeCacheStore)
+	ffs := fs.NewFilesystem(fs.FilesystemTypeFake, "")
+
+	pats := New(ffs, WithCache(true), WithCacheStore(store))
+	if err := pats.Parse(strings.NewReader("*.iso\n"), ".stignore"); err != nil {
+		t.Fatal(err)
+	}
+	pats.Match("image.iso")
+	pats.Match("text")
+	if err := pats.SaveCache(); err != nil {
+		t.Fatal(err)
+	}
+	hash := pats.Hash()
+	if len(store[hash]) == 0 {
+		t.Fatal("Cache not stored")
+	}
+
+	// A new matcher for the same patterns starts out with the results
+	pats = New(ffs, WithCache(true), WithCacheStore(store))
+	if err := pats.Parse(strings.NewReader("*.iso\n"), ".stignore"); err != nil {
+		t.Fatal(err)
+	}
+	if n := pats.matches.len(); n != 2 {
+		t.Fatalf("Expected two cached results, got %d", n)
+	}
+	if res, ok := pats.matches.get("image.iso"); !ok || !res.IsIgnored() {
+		t.Error("Expected image.iso to be cached as ignored")
+	}
+	if res, ok := pats.matches.get("text"); !ok || res.IsIgnored() {
+		t.Error("Expected text to be cached as not ignored")
+	}
+
+	// Nothing changed, so there is nothing to save
+	saved := store[hash]
+	delete(store, hash)
+	if err := pats.SaveCache(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store[hash]; ok {
+		t.Error("Unchanged cache saved")
+	}
+
+	// Other patterns don't get the results
+	pats = New(ffs, WithCache(true), WithCacheStore(fakeCacheStore{"other": saved}))
+	if err := pats.Parse(strings.NewReader("*.iso\n"), ".stignore"); err != nil {
+		t.Fatal(err)
+	}
+	if n := pats.matches.len(); n != 0 {
+		t.Errorf("Expected no cached results, got %d", n)
+	}
+}
+
+type fakeCacheStore map[string][]byte
+
+func (s fakeCacheStore) LoadCache(hash string) ([]byte, error) {
+	return s[hash], nil
+}
+
+func (s fakeCacheStore) SaveCache(hash string, results []byte) error {
+	s[hash] = results
+	return nil
+}
+
 type fakeClock int64 // milliseconds
 
 func (f *fakeClock) Now() time.Time {
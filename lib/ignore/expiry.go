@@ -0,0 +1,74 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package ignore
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const untilPrefix = "(?until:"
+
+// expired returns whether the pattern has an expiry, like
+// "(?until:2024-12-31)", which has passed.
+func (p Pattern) expired(now time.Time) bool {
+	return !p.until.IsZero() && !now.Before(p.until)
+}
+
+// parseUntil parses the expiry the line starts with, returning the time it
+// expires, its text and the rest of the line. It returns false if the line
+// doesn't start with one.
+func parseUntil(line string) (time.Time, string, string, bool, error) {
+	if !strings.HasPrefix(line, untilPrefix) {
+		return time.Time{}, "", line, false, nil
+	}
+	rest := line[len(untilPrefix):]
+	end := strings.IndexByte(rest, ')')
+	if end < 0 {
+		return time.Time{}, "", line, true, fmt.Errorf("unterminated expiry %q", line)
+	}
+	text := line[:len(untilPrefix)+end+1]
+	until, err := parseExpiry(rest[:end])
+	if err != nil {
+		return time.Time{}, "", line, true, fmt.Errorf("expiry %s: %v", text, err)
+	}
+	return until, text, rest[end+1:], true, nil
+}
+
+// parseExpiry parses a date, which means the pattern applies through the
+// end of that day in local time, or a time in RFC 3339 format.
+func parseExpiry(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t.AddDate(0, 0, 1), nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02T15:04", s, time.Local); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q", s)
+	}
+	return t, nil
+}
+
+// activePatterns returns the patterns that haven't expired at the given
+// time, and when the next of those does, if any.
+func activePatterns(patterns []Pattern, now time.Time) ([]Pattern, time.Time) {
+	var next time.Time
+	active := patterns[:0:0]
+	for _, p := range patterns {
+		if p.expired(now) {
+			continue
+		}
+		if !p.until.IsZero() && (next.IsZero() || p.until.Before(next)) {
+			next = p.until
+		}
+		active = append(active, p)
+	}
+	return active, next
+}
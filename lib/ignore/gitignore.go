@@ -0,0 +1,215 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package ignore
+
+import (
+	"bufio"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/gobwas/glob"
+)
+
+// GitignoreFile is the name of the ignore files of Git. When asked to, the
+// matcher honors them in any directory of the folder, with Git semantics:
+// the patterns are relative to the directory of the file, those of deeper
+// files and later lines take precedence, and nothing is unignored within a
+// directory that is ignored. They only apply to what the patterns of the
+// .stignore don't match, and not at all in whitelist mode.
+const GitignoreFile = ".gitignore"
+
+// A gitPattern is a line of a .gitignore, matching paths relative to the
+// directory of the file.
+type gitPattern struct {
+	match   []glob.Glob // any of which matches
+	negate  bool
+	dirOnly bool
+	source  string
+	lineNo  int
+}
+
+// A gitignore is the parsed .gitignore of a directory, nil if there is
+// none.
+type gitignore struct {
+	file     string
+	patterns []gitPattern
+}
+
+// WithGitignore makes the matcher honor the .gitignore files in the
+// folder, as described for GitignoreFile.
+func WithGitignore(v bool) Option {
+	return func(m *Matcher) {
+		m.gitignore = v
+	}
+}
+
+func parseGitignore(r io.Reader) []gitPattern {
+	var patterns []gitPattern
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		source := scanner.Text()
+		if p, ok := parseGitLine(source); ok {
+			p.source = source
+			p.lineNo = lineNo
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// parseGitLine parses a line of a .gitignore, returning false for those
+// without a pattern. Git takes invalid patterns as not matching, so those
+// are left out as well.
+func parseGitLine(line string) (gitPattern, bool) {
+	var p gitPattern
+
+	// Trailing spaces don't count unless escaped
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+	switch {
+	case line == "" || strings.HasPrefix(line, "#"):
+		return p, false
+	case strings.HasPrefix(line, "!"):
+		p.negate = true
+		line = line[1:]
+	case strings.HasPrefix(line, "\\#") || strings.HasPrefix(line, "\\!"):
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return p, false
+	}
+	if defaultResult.IsCaseFolded() {
+		line = strings.ToLower(line)
+	}
+
+	// Patterns with a slash are relative to the directory, others match
+	// at any depth.
+	if strings.Contains(line, "/") {
+		line = strings.TrimPrefix(line, "/")
+	} else {
+		line = "**/" + line
+	}
+	variants := []string{line}
+	if strings.HasPrefix(line, "**/") {
+		variants = append(variants, line[3:])
+	}
+	if strings.Contains(line, "/**/") {
+		variants = append(variants, strings.Replace(line, "/**/", "/", -1))
+	}
+	for _, v := range variants {
+		g, err := glob.Compile(v, '/')
+		if err != nil {
+			return p, false
+		}
+		p.match = append(p.match, g)
+	}
+	return p, true
+}
+
+func (p gitPattern) matches(rel string) bool {
+	for _, g := range p.match {
+		if g.Match(rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// A gitDecision is what the .gitignore files say about a path.
+type gitDecision struct {
+	ignored bool
+	file    *gitignore  // with the deciding pattern, if any
+	pattern *gitPattern // the deciding pattern, if any
+}
+
+// resetGitignoresLocked forgets the .gitignore files read, so that they
+// are read again as needed.
+func (m *Matcher) resetGitignoresLocked() {
+	m.gitignores = make(map[string]*gitignore)
+	m.gitDirs = make(map[string]gitDecision)
+}
+
+// gitignoreLocked returns the .gitignore of the directory, in slash form
+// with "" for the root.
+func (m *Matcher) gitignoreLocked(dir string) *gitignore {
+	if gi, ok := m.gitignores[dir]; ok {
+		return gi
+	}
+	var gi *gitignore
+	file := filepath.FromSlash(path.Join(dir, GitignoreFile))
+	if fd, err := m.fs.Open(file); err == nil {
+		gi = &gitignore{file: file, patterns: parseGitignore(fd)}
+		fd.Close()
+	}
+	m.gitignores[dir] = gi
+	return gi
+}
+
+// gitMatchLocked returns what the .gitignore files say about the file, in
+// slash form. A directory containing it being ignored ignores it too.
+func (m *Matcher) gitMatchLocked(file string, st *Stat) gitDecision {
+	if defaultResult.IsCaseFolded() {
+		file = strings.ToLower(file)
+	}
+	parts := strings.Split(file, "/")
+	for i := 1; i < len(parts); i++ {
+		dir := strings.Join(parts[:i], "/")
+		dec, ok := m.gitDirs[dir]
+		if !ok {
+			dec = m.gitDecideLocked(dir, func() bool { return true })
+			m.gitDirs[dir] = dec
+		}
+		if dec.ignored {
+			return dec
+		}
+	}
+	return m.gitDecideLocked(file, func() bool {
+		if st != nil {
+			return st.IsDir
+		}
+		info, err := m.fs.Lstat(filepath.FromSlash(file))
+		return err == nil && info.IsDir()
+	})
+}
+
+// gitDecideLocked returns whether the patterns of the .gitignore files
+// ignore the file itself, by the last matching pattern of the deepest file
+// that has one.
+func (m *Matcher) gitDecideLocked(file string, isDir func() bool) gitDecision {
+	dir := file
+	for dir != "" {
+		if i := strings.LastIndexByte(dir, '/'); i >= 0 {
+			dir = dir[:i]
+		} else {
+			dir = ""
+		}
+		gi := m.gitignoreLocked(dir)
+		if gi == nil {
+			continue
+		}
+		rel := file
+		if dir != "" {
+			rel = file[len(dir)+1:]
+		}
+		for i := len(gi.patterns) - 1; i >= 0; i-- {
+			p := &gi.patterns[i]
+			if p.matches(rel) && (!p.dirOnly || isDir()) {
+				return gitDecision{ignored: !p.negate, file: gi, pattern: p}
+			}
+		}
+	}
+	return gitDecision{}
+}
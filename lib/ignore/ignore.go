@@ -41,9 +41,15 @@ func init() {
 }
 
 type Pattern struct {
-	pattern string
-	match   glob.Glob
-	result  Result
+	pattern    string
+	match      glob.Glob
+	result     Result
+	predicates []predicate // all of which must hold for a match
+	until      time.Time   // when the pattern stops applying, zero for never
+	untilText  string
+	source     string // the line the pattern is from
+	file       string
+	lineNo     int
 }
 
 func (p Pattern) String() string {
@@ -57,7 +63,10 @@ func (p Pattern) String() string {
 	if p.result&resultDeletable == resultDeletable {
 		ret = "(?d)" + ret
 	}
-	return ret
+	for i := len(p.predicates) - 1; i >= 0; i-- {
+		ret = p.predicates[i].text + ret
+	}
+	return p.untilText + ret
 }
 
 func (p Pattern) allowsSkippingIgnoredDirs() bool {
@@ -105,12 +114,32 @@ type ChangeDetector interface {
 	Reset()
 }
 
+// SyncedIgnoresFile is the copy of .stignore that is synced like any other
+// file, for folders that share their ignore patterns between devices.
+const SyncedIgnoresFile = ".stignore-sync"
+
+// WhitelistDirective is the line of a .stignore that makes its patterns
+// say what to sync rather than what to ignore. Everything else is ignored,
+// except for the directories that lead to what is synced. A "!" prefix then
+// excludes things from being synced. The directive is only heeded in the
+// .stignore itself, not in included files.
+const WhitelistDirective = "#whitelist"
+
 type Matcher struct {
 	fs              fs.Filesystem
 	lines           []string  // exact lines read from .stignore
-	patterns        []Pattern // patterns including those from included files
+	patterns        []Pattern // patterns including those from included files, which haven't expired
+	parsed          []Pattern // all the patterns, including expired ones
+	nextExpiry      time.Time // when the next of the patterns expires, zero for never
+	whitelist       bool
+	predicates      bool            // whether any pattern has predicates
+	parents         []parentPattern // in whitelist mode, of the directories leading to synced items
 	withCache       bool
 	matches         *cache
+	cacheStore      CacheStore
+	gitignore       bool                   // whether to honor .gitignore files
+	gitignores      map[string]*gitignore  // by directory, as read so far
+	gitDirs         map[string]gitDecision // by directory, as decided so far
 	curHash         string
 	stop            chan struct{}
 	changeDetector  ChangeDetector
@@ -128,6 +157,14 @@ func WithCache(v bool) Option {
 	}
 }
 
+// WithCacheStore keeps the lookup cache in the store across restarts. It
+// only has an effect together with WithCache(true).
+func WithCacheStore(s CacheStore) Option {
+	return func(m *Matcher) {
+		m.cacheStore = s
+	}
+}
+
 // WithChangeDetector sets a custom ChangeDetector. The default is to simply
 // use the on disk modtime for comparison.
 func WithChangeDetector(cd ChangeDetector) Option {
@@ -149,6 +186,9 @@ func New(fs fs.Filesystem, opts ...Option) *Matcher {
 	if m.changeDetector == nil {
 		m.changeDetector = newModtimeChecker()
 	}
+	if m.gitignore {
+		m.resetGitignoresLocked()
+	}
 	if m.withCache {
 		go m.clean(2 * time.Hour)
 	}
@@ -159,7 +199,17 @@ func (m *Matcher) Load(file string) error {
 	m.mut.Lock()
 	defer m.mut.Unlock()
 
+	// The .gitignore files are read again for each load, as they may have
+	// changed with the files.
+	if m.gitignore {
+		m.resetGitignoresLocked()
+	}
+
 	if m.changeDetector.Seen(m.fs, file) && !m.changeDetector.Changed() {
+		if !m.nextExpiry.IsZero() && !clock.Now().Before(m.nextExpiry) {
+			// Some patterns expired since they were loaded.
+			m.activateLocked(m.parsed, m.whitelist)
+		}
 		return nil
 	}
 
@@ -194,10 +244,40 @@ func (m *Matcher) parseLocked(r io.Reader, file string) error {
 
 	m.lines = lines
 
+	whitelist := false
+	for _, line := range lines {
+		if line == WhitelistDirective {
+			whitelist = true
+			break
+		}
+	}
+	if whitelist {
+		for i := range patterns {
+			patterns[i].result ^= resultInclude
+		}
+	}
+
+	m.activateLocked(patterns, whitelist)
+	return err
+}
+
+// activateLocked puts the patterns that haven't expired into use. The hash
+// only covers those, so that it changes when some of them expire.
+func (m *Matcher) activateLocked(parsed []Pattern, whitelist bool) {
+	m.parsed = parsed
+	var patterns []Pattern
+	patterns, m.nextExpiry = activePatterns(parsed, clock.Now())
+
 	newHash := hashPatterns(patterns)
+	if whitelist {
+		newHash = "whitelist:" + newHash
+	}
+	if m.gitignore {
+		newHash = "gitignore:" + newHash
+	}
 	if newHash == m.curHash {
 		// We've already loaded exactly these patterns.
-		return err
+		return
 	}
 
 	m.skipIgnoredDirs = true
@@ -219,11 +299,55 @@ func (m *Matcher) parseLocked(r io.Reader, file string) error {
 
 	m.curHash = newHash
 	m.patterns = patterns
+	m.whitelist = whitelist
+	m.predicates = false
+	for _, p := range patterns {
+		if len(p.predicates) > 0 {
+			m.predicates = true
+			break
+		}
+	}
+	m.parents = nil
+	if whitelist {
+		m.parents = parentPatterns(patterns)
+	}
 	if m.withCache {
 		m.matches = newCache(patterns)
+		m.loadCacheLocked()
 	}
+}
 
-	return err
+// loadCacheLocked fills the new cache with the results stored for the
+// patterns, if any. Without them the cache just starts out empty.
+func (m *Matcher) loadCacheLocked() {
+	if m.cacheStore == nil {
+		return
+	}
+	bs, err := m.cacheStore.LoadCache(m.curHash)
+	if err != nil || !m.matches.decode(bs) {
+		m.matches = newCache(m.patterns)
+	}
+}
+
+// SaveCache stores the cached results in the cache store, if there is one
+// and they changed.
+func (m *Matcher) SaveCache() error {
+	m.mut.Lock()
+	if m.cacheStore == nil || m.matches == nil || !m.matches.dirty {
+		m.mut.Unlock()
+		return nil
+	}
+	matches, hash, bs := m.matches, m.curHash, m.matches.encode()
+	matches.dirty = false
+	m.mut.Unlock()
+
+	if err := m.cacheStore.SaveCache(hash, bs); err != nil {
+		m.mut.Lock()
+		matches.dirty = true
+		m.mut.Unlock()
+		return err
+	}
+	return nil
 }
 
 func (m *Matcher) Match(file string) (result Result) {
@@ -234,10 +358,12 @@ func (m *Matcher) Match(file string) (result Result) {
 	m.mut.Lock()
 	defer m.mut.Unlock()
 
-	if len(m.patterns) == 0 {
+	if len(m.patterns) == 0 && !m.whitelist && !m.gitignore {
 		return resultNotMatched
 	}
 
+	// What depends on the disk isn't cached, as it may change
+	fromDisk := false
 	if m.matches != nil {
 		// Check the cache for a known result.
 		res, ok := m.matches.get(file)
@@ -247,30 +373,241 @@ func (m *Matcher) Match(file string) (result Result) {
 
 		// Update the cache with the result at return time
 		defer func() {
-			m.matches.set(file, result)
+			if !fromDisk {
+				m.matches.set(file, result)
+			}
 		}()
 	}
 
+	result, fromDisk = m.matchLocked(filepath.ToSlash(file), nil)
+	return result
+}
+
+// MatchStat is like Match, but also heeds the patterns with predicates on
+// the size and age of files by the stat. Without a stat it's just Match.
+func (m *Matcher) MatchStat(file string, st *Stat) Result {
+	if st == nil || file == "." || !m.hasPredicates() {
+		return m.Match(file)
+	}
+
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	// The results of predicates change with the files, so aren't cached.
+	res, _ := m.matchLocked(filepath.ToSlash(file), st)
+	return res
+}
+
+func (m *Matcher) hasPredicates() bool {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	return m.predicates
+}
+
+// matchLocked returns the result for the file in slash form, and whether
+// telling it took looking at the disk.
+func (m *Matcher) matchLocked(file string, st *Stat) (Result, bool) {
 	// Check all the patterns for a match.
-	file = filepath.ToSlash(file)
+	if i := m.firstMatchLocked(file, st); i >= 0 {
+		return m.patterns[i].result, false
+	}
+
+	// What the .gitignore files say isn't cached, as they may change
+	if m.gitignore && !m.whitelist {
+		if m.gitMatchLocked(file, st).ignored {
+			return resultInclude, true
+		}
+		return resultNotMatched, true
+	}
+
+	if m.whitelist {
+		isParent, fromDisk := m.isParentLocked(file)
+		if isParent {
+			return resultNotMatched, fromDisk
+		}
+		return resultInclude, fromDisk
+	}
+
+	// Default to not matching.
+	return resultNotMatched, false
+}
+
+// firstMatchLocked returns the index of the first pattern matching the
+// file in slash form, or -1 if there is none. The patterns with predicates
+// only match with a stat they hold for.
+func (m *Matcher) firstMatchLocked(file string, st *Stat) int {
 	var lowercaseFile string
-	for _, pattern := range m.patterns {
+	var now time.Time
+	if st != nil {
+		now = time.Now()
+	}
+	for i, pattern := range m.patterns {
+		if !pattern.holds(st, now) {
+			continue
+		}
 		if pattern.result.IsCaseFolded() {
 			if lowercaseFile == "" {
 				lowercaseFile = strings.ToLower(file)
 			}
 			if pattern.match.Match(lowercaseFile) {
-				return pattern.result
+				return i
 			}
 		} else {
 			if pattern.match.Match(file) {
-				return pattern.result
+				return i
 			}
 		}
 	}
+	return -1
+}
 
-	// Default to not matching.
-	return resultNotMatched
+// The reasons for the results of an Explanation
+const (
+	ReasonNone             = ""                 // no pattern matched
+	ReasonPattern          = "pattern"          // by the pattern
+	ReasonWhitelistParent  = "whitelistParent"  // leads to something synced in whitelist mode
+	ReasonWhitelistDefault = "whitelistDefault" // not synced in whitelist mode
+	ReasonInternal         = "internal"         // one of our own files
+	ReasonGitignore        = "gitignore"        // by the pattern of a .gitignore
+	ReasonTemporary        = "temporary"
+)
+
+// An Explanation tells why a file is ignored or not.
+type Explanation struct {
+	Path      string `json:"path"`
+	Ignored   bool   `json:"ignored"`
+	Deletable bool   `json:"deletable"`
+	Reason    string `json:"reason"`
+	Pattern   string `json:"pattern,omitempty"` // the line with the pattern that matched
+	File      string `json:"file,omitempty"`    // the file and line number of that
+	Line      int    `json:"line,omitempty"`
+}
+
+// Explain returns whether the file is ignored like ShouldIgnore does, and
+// why.
+func (m *Matcher) Explain(file string) Explanation {
+	return m.ExplainStat(file, nil)
+}
+
+// ExplainStat is like Explain, with the patterns matched like MatchStat
+// does.
+func (m *Matcher) ExplainStat(file string, st *Stat) Explanation {
+	exp := Explanation{Path: file}
+	switch {
+	case fs.IsTemporary(file):
+		exp.Ignored, exp.Reason = true, ReasonTemporary
+		return exp
+	case fs.IsInternal(file):
+		exp.Ignored, exp.Reason = true, ReasonInternal
+		return exp
+	case file == ".":
+		return exp
+	}
+
+	m.mut.Lock()
+	defer m.mut.Unlock()
+
+	if i := m.firstMatchLocked(filepath.ToSlash(file), st); i >= 0 {
+		p := m.patterns[i]
+		exp.Ignored = p.result.IsIgnored()
+		exp.Deletable = p.result.IsDeletable()
+		exp.Reason = ReasonPattern
+		exp.Pattern = p.source
+		exp.File = p.file
+		exp.Line = p.lineNo
+	} else if m.gitignore && !m.whitelist {
+		if dec := m.gitMatchLocked(filepath.ToSlash(file), st); dec.pattern != nil {
+			exp.Ignored = dec.ignored
+			exp.Reason = ReasonGitignore
+			exp.Pattern = dec.pattern.source
+			exp.File = dec.file.file
+			exp.Line = dec.pattern.lineNo
+		}
+	} else if m.whitelist {
+		if isParent, _ := m.isParentLocked(filepath.ToSlash(file)); isParent {
+			exp.Reason = ReasonWhitelistParent
+		} else {
+			exp.Ignored, exp.Reason = true, ReasonWhitelistDefault
+		}
+	}
+	return exp
+}
+
+// A parentPattern matches the directories containing what a pattern
+// matches, by its path components. A "**" component matches any number of
+// further directories, which can only be told by looking at the disk.
+type parentPattern struct {
+	components []glob.Glob // nil for "**"
+	foldCase   bool
+}
+
+// parentPatterns returns the parent patterns of the patterns that include
+// something in whitelist mode.
+func parentPatterns(patterns []Pattern) []parentPattern {
+	var parents []parentPattern
+	for _, p := range patterns {
+		if p.result.IsIgnored() {
+			continue
+		}
+		parts := strings.Split(strings.TrimPrefix(p.pattern, "/"), "/")
+		parent := parentPattern{foldCase: p.result.IsCaseFolded()}
+		for _, part := range parts[:len(parts)-1] {
+			if part == "**" {
+				parent.components = append(parent.components, nil)
+				break
+			}
+			g, err := glob.Compile(part, '/')
+			if err != nil {
+				// Can't happen, as the whole pattern compiled
+				break
+			}
+			parent.components = append(parent.components, g)
+		}
+		if len(parent.components) > 0 {
+			parents = append(parents, parent)
+		}
+	}
+	return parents
+}
+
+// isParentLocked returns whether the file, in slash form, is a directory
+// leading to something that is included in whitelist mode, and whether
+// that had to be looked up on disk.
+func (m *Matcher) isParentLocked(file string) (bool, bool) {
+	parts := strings.Split(file, "/")
+	lowerParts := strings.Split(strings.ToLower(file), "/")
+	isDir := -1 // unknown
+	for _, parent := range m.parents {
+		components := parts
+		if parent.foldCase {
+			components = lowerParts
+		}
+		for i, part := range components {
+			if i >= len(parent.components) {
+				break
+			}
+			g := parent.components[i]
+			if g == nil {
+				if isDir < 0 {
+					isDir = 0
+					if info, err := m.fs.Lstat(filepath.FromSlash(file)); err == nil && info.IsDir() {
+						isDir = 1
+					}
+				}
+				if isDir == 1 {
+					return true, true
+				}
+				break
+			}
+			if !g.Match(part) {
+				break
+			}
+			if i == len(components)-1 {
+				return true, isDir >= 0
+			}
+		}
+	}
+	return false, isDir >= 0
 }
 
 // Lines return a list of the unprocessed lines in .stignore at last load
@@ -296,6 +633,14 @@ func (m *Matcher) String() string {
 	return fmt.Sprintf("Matcher/%v@%p", m.Patterns(), m)
 }
 
+// NextExpiry returns when the next of the patterns expires, or the zero
+// time if none do. They stop applying with the next Load after that.
+func (m *Matcher) NextExpiry() time.Time {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	return m.nextExpiry
+}
+
 func (m *Matcher) Hash() string {
 	m.mut.Lock()
 	defer m.mut.Unlock()
@@ -339,6 +684,23 @@ func (m *Matcher) ShouldIgnore(filename string) bool {
 	return false
 }
 
+// ShouldIgnoreStat is like ShouldIgnore, with the patterns matched like
+// MatchStat does.
+func (m *Matcher) ShouldIgnoreStat(filename string, st *Stat) bool {
+	switch {
+	case fs.IsTemporary(filename):
+		return true
+
+	case fs.IsInternal(filename):
+		return true
+
+	case m.MatchStat(filename, st).IsIgnored():
+		return true
+	}
+
+	return false
+}
+
 func (m *Matcher) SkipIgnoredDirs() bool {
 	m.mut.Lock()
 	defer m.mut.Unlock()
@@ -417,6 +779,18 @@ func parseLine(line string) ([]Pattern, error) {
 			seenPrefix[2] = true
 			pattern.result |= resultDeletable
 			line = line[4:]
+		} else if until, text, rest, ok, err := parseUntil(line); ok && pattern.until.IsZero() {
+			if err != nil {
+				return nil, err
+			}
+			pattern.until, pattern.untilText = until, text
+			line = rest
+		} else if pred, rest, ok, err := parsePredicate(line); ok {
+			if err != nil {
+				return nil, err
+			}
+			pattern.predicates = append(pattern.predicates, pred)
+			line = rest
 		} else {
 			break
 		}
@@ -478,11 +852,18 @@ func parseIgnoreFile(fs fs.Filesystem, fd io.Reader, currentFile string, cd Chan
 	var lines []string
 	var patterns []Pattern
 
+	var source string
+	lineNo := 0
 	addPattern := func(line string) error {
 		newPatterns, err := parseLine(line)
 		if err != nil {
 			return errors.Wrapf(err, "invalid pattern %q in ignore file", line)
 		}
+		for i := range newPatterns {
+			newPatterns[i].source = source
+			newPatterns[i].file = currentFile
+			newPatterns[i].lineNo = lineNo
+		}
 		patterns = append(patterns, newPatterns...)
 		return nil
 	}
@@ -492,6 +873,8 @@ func parseIgnoreFile(fs fs.Filesystem, fd io.Reader, currentFile string, cd Chan
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
 		lines = append(lines, line)
+		source = line
+		lineNo++
 		if _, ok := linesSeen[line]; ok {
 			continue
 		}
@@ -501,6 +884,8 @@ func parseIgnoreFile(fs fs.Filesystem, fd io.Reader, currentFile string, cd Chan
 			continue
 		case strings.HasPrefix(line, "//"):
 			continue
+		case line == WhitelistDirective:
+			continue
 		}
 
 		line = filepath.ToSlash(line)
@@ -574,6 +959,61 @@ func WriteIgnores(filesystem fs.Filesystem, path string, content []string) error
 	return nil
 }
 
+// ReadIgnores returns the lines of the ignore file, trimmed like when it's
+// loaded, without parsing them. A missing file has no lines.
+func ReadIgnores(filesystem fs.Filesystem, path string) ([]string, error) {
+	fd, err := filesystem.Open(path)
+	if fs.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(fd)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimSpace(scanner.Text()))
+	}
+	return lines, scanner.Err()
+}
+
+// PatternRoot returns the directory, or file, that everything the line of
+// an ignore file can match is within, for rooted patterns like
+// "/photos/2019/*.jpg". It returns false for patterns that can match
+// anywhere in the folder, and for lines that aren't plain patterns.
+func PatternRoot(line string) (string, bool) {
+	if strings.HasPrefix(line, "#") {
+		return "", false
+	}
+	patterns, err := parseLine(line)
+	if err != nil || len(patterns) != 1 || patterns[0].result.IsCaseFolded() {
+		return "", false
+	}
+	var root []string
+	for _, part := range strings.Split(patterns[0].pattern[1:], "/") {
+		if part == "" || part != glob.QuoteMeta(part) {
+			break
+		}
+		root = append(root, part)
+	}
+	if len(root) == 0 {
+		return "", false
+	}
+	return strings.Join(root, "/"), true
+}
+
+// Version returns a token for the given lines of an ignore file, for
+// telling whether it was changed since they were read.
+func Version(lines []string) string {
+	h := md5.New()
+	for _, line := range lines {
+		h.Write([]byte(line))
+		h.Write([]byte("\n"))
+	}
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
 type modtimeCheckerKey struct {
 	fs   fs.Filesystem
 	name string
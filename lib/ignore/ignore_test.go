@@ -1168,3 +1168,293 @@ func TestEmptyPatterns(t *testing.T) {
 		}
 	}
 }
+
+func TestWhitelist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, d := range []string{"photos/2019", "other"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stignore := `
+	#whitelist
+	/documents/work/report.txt
+	!/music/podcasts
+	/music
+	**/*.jpg
+	`
+	pats := New(fs.NewFilesystem(fs.FilesystemTypeBasic, dir), WithCache(true))
+	if err := pats.Parse(bytes.NewBufferString(stignore), ".stignore"); err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		f string
+		r bool
+	}{
+		{"documents", false},
+		{"documents/work", false},
+		{"documents/work/report.txt", false},
+		{"documents/work/other.txt", true},
+		{"documents/private", true},
+		{"music", false},
+		{"music/song.mp3", false},
+		{"music/podcasts", true},
+		{"music/podcasts/episode.mp3", true},
+		{"photos", false},
+		{"photos/2019", false},
+		{"photos/2019/beach.jpg", false},
+		{"photos/2019/notes.txt", true},
+		{"other", false}, // might have pictures in it
+		{"file.txt", true},
+	}
+	for _, tc := range tests {
+		if r := pats.Match(filepath.FromSlash(tc.f)).IsIgnored(); r != tc.r {
+			t.Errorf("Incorrect ignore result for %q: %v != %v", tc.f, r, tc.r)
+		}
+	}
+
+	// Without patterns it syncs nothing.
+	if err := pats.Parse(bytes.NewBufferString(WhitelistDirective), ".stignore"); err != nil {
+		t.Fatal(err)
+	}
+	if !pats.Match("file.txt").IsIgnored() {
+		t.Error("Expected everything to be ignored")
+	}
+}
+
+func TestExplain(t *testing.T) {
+	stignore := `
+	// Comment
+	(?d).DS_Store
+	!/keep
+	/top
+	`
+	pats := New(fs.NewFilesystem(fs.FilesystemTypeBasic, "."), WithCache(true))
+	if err := pats.Parse(bytes.NewBufferString(stignore), ".stignore"); err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []Explanation{
+		{Path: filepath.Join("dir", ".DS_Store"), Ignored: true, Deletable: true, Reason: ReasonPattern, Pattern: "(?d).DS_Store", File: ".stignore", Line: 3},
+		{Path: "keep", Reason: ReasonPattern, Pattern: "!/keep", File: ".stignore", Line: 4},
+		{Path: filepath.Join("top", "file"), Ignored: true, Reason: ReasonPattern, Pattern: "/top", File: ".stignore", Line: 5},
+		{Path: "other", Reason: ReasonNone},
+		{Path: ".stfolder", Ignored: true, Reason: ReasonInternal},
+	}
+	for _, tc := range tests {
+		if exp := pats.Explain(tc.Path); exp != tc {
+			t.Errorf("Unexpected explanation %+v, expected %+v", exp, tc)
+		}
+	}
+}
+
+func TestPredicates(t *testing.T) {
+	stignore := `
+	!(?size<1k)*.iso
+	(?size>100M)*
+	(?older-than:30d)(?i)*.LOG
+	`
+	pats := New(fs.NewFilesystem(fs.FilesystemTypeBasic, "."), WithCache(true))
+	if err := pats.Parse(bytes.NewBufferString(stignore), ".stignore"); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	var tests = []struct {
+		file    string
+		st      *Stat
+		ignored bool
+	}{
+		{"big.bin", &Stat{Size: 200 << 20, ModTime: now}, true},
+		{"small.bin", &Stat{Size: 10, ModTime: now}, false},
+		{"big.bin", nil, false},
+		{"bigdir", &Stat{Size: 200 << 20, ModTime: now, IsDir: true}, false},
+		{"tiny.iso", &Stat{Size: 200 << 20, ModTime: now}, true},
+		{"tiny.iso", &Stat{Size: 10, ModTime: now}, false},
+		{"old.log", &Stat{Size: 10, ModTime: now.Add(-31 * 24 * time.Hour)}, true},
+		{"new.log", &Stat{Size: 10, ModTime: now.Add(-time.Hour)}, false},
+	}
+	for _, tc := range tests {
+		if res := pats.MatchStat(tc.file, tc.st).IsIgnored(); res != tc.ignored {
+			t.Errorf("Incorrect result for %q with %+v: %v != %v", tc.file, tc.st, res, tc.ignored)
+		}
+	}
+
+	if res := pats.Match("big.bin").IsIgnored(); res {
+		t.Error("Patterns with predicates should not match by name only")
+	}
+
+	for _, invalid := range []string{"(?size>100X)foo", "(?older-than:30)foo", "(?size>100Mfoo"} {
+		if err := New(fs.NewFilesystem(fs.FilesystemTypeBasic, ".")).Parse(bytes.NewBufferString(invalid), ".stignore"); err == nil {
+			t.Errorf("Expected an error parsing %q", invalid)
+		}
+	}
+}
+
+func TestGitignore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		".gitignore":          "# build output\n*.o\n/root-only\nbuild/\n!keep.o\n",
+		"sub/.gitignore":      "!*.o\ngenerated\nlog/*.txt\n",
+		"build/.gitignore":    "!*\n",
+		"sub/deep/.gitignore": "*.o\n",
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"build", "sub/build", "sub/generated"} {
+		if err := os.MkdirAll(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pats := New(fs.NewFilesystem(fs.FilesystemTypeBasic, dir), WithCache(true), WithGitignore(true))
+	if err := pats.Parse(bytes.NewBufferString("!main.o\nsecret\n"), ".stignore"); err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		file    string
+		ignored bool
+	}{
+		{"a.o", true},
+		{"a.c", false},
+		{"keep.o", false},
+		{"main.o", false},   // unignored by .stignore
+		{"x/secret", true},  // ignored by .stignore
+		{"x/y/a.o", true},   // at any depth
+		{"root-only", true}, // anchored
+		{"x/root-only", false},
+		{"build", true},      // directories only
+		{"build/file", true}, // nothing is unignored in an ignored directory
+		{"sub/build/a", true},
+		{"sub/a.o", false},           // deeper files take precedence
+		{"sub/deep/a.o", true},       // and deeper still
+		{"sub/generated/file", true}, // relative to their directory
+		{"generated", false},
+		{"sub/log/a.txt", true},
+		{"sub/x/log/a.txt", false},
+	}
+	for _, tc := range tests {
+		if res := pats.Match(filepath.FromSlash(tc.file)).IsIgnored(); res != tc.ignored {
+			t.Errorf("Incorrect result for %q: %v != %v", tc.file, res, tc.ignored)
+		}
+	}
+
+	if !strings.HasPrefix(pats.Hash(), "gitignore:") {
+		t.Error("Expected the hash to tell the .gitignore files are honored")
+	}
+
+	exp := pats.Explain(filepath.FromSlash("x/y/a.o"))
+	if !exp.Ignored || exp.Reason != ReasonGitignore || exp.Pattern != "*.o" || exp.File != ".gitignore" || exp.Line != 2 {
+		t.Errorf("Unexpected explanation %+v", exp)
+	}
+
+	// Without the option the .gitignore files are just files
+	pats = New(fs.NewFilesystem(fs.FilesystemTypeBasic, dir))
+	if err := pats.Parse(bytes.NewBufferString(""), ".stignore"); err != nil {
+		t.Fatal(err)
+	}
+	if pats.Match("a.o").IsIgnored() {
+		t.Error("Unexpected match without the option")
+	}
+}
+
+func TestExpiringPatterns(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	stignore := "(?until:2024-12-31)build\n(?until:2020-01-01T12:00:00Z)old\n(?until:2025-06-30)(?i)Temp\nplain\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, ".stignore"), []byte(stignore), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fc := fakeClock(time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local).UnixNano() / int64(time.Millisecond))
+	oldClock := clock
+	clock = &fc
+	defer func() {
+		clock = oldClock
+	}()
+
+	pats := New(fs.NewFilesystem(fs.FilesystemTypeBasic, dir), WithCache(true))
+	if err := pats.Load(".stignore"); err != nil {
+		t.Fatal(err)
+	}
+	for file, ignored := range map[string]bool{"build": true, "old": false, "temp": true, "plain": true} {
+		if res := pats.Match(file).IsIgnored(); res != ignored {
+			t.Errorf("Incorrect result for %q before expiry: %v != %v", file, res, ignored)
+		}
+	}
+	if next, expected := pats.NextExpiry(), time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local); !next.Equal(expected) {
+		t.Errorf("Next expiry %v, expected %v", next, expected)
+	}
+
+	// Loading the unchanged file after the expiry drops the pattern
+	hash := pats.Hash()
+	fc = fakeClock(time.Date(2025, 1, 2, 0, 0, 0, 0, time.Local).UnixNano() / int64(time.Millisecond))
+	if err := pats.Load(".stignore"); err != nil {
+		t.Fatal(err)
+	}
+	if pats.Hash() == hash {
+		t.Error("Hash didn't change with the expiry")
+	}
+	for file, ignored := range map[string]bool{"build": false, "old": false, "temp": true, "plain": true} {
+		if res := pats.Match(file).IsIgnored(); res != ignored {
+			t.Errorf("Incorrect result for %q after expiry: %v != %v", file, res, ignored)
+		}
+	}
+	if next, expected := pats.NextExpiry(), time.Date(2025, 7, 1, 0, 0, 0, 0, time.Local); !next.Equal(expected) {
+		t.Errorf("Next expiry %v, expected %v", next, expected)
+	}
+
+	for _, invalid := range []string{"(?until:tomorrow)foo", "(?until:2024-12-31foo"} {
+		if err := New(fs.NewFilesystem(fs.FilesystemTypeBasic, ".")).Parse(bytes.NewBufferString(invalid), ".stignore"); err == nil {
+			t.Errorf("Expected an error parsing %q", invalid)
+		}
+	}
+}
+
+func TestPatternRoot(t *testing.T) {
+	tests := []struct {
+		line string
+		root string
+		ok   bool
+	}{
+		{"/photos/2019", "photos/2019", true},
+		{"/photos/2019/**", "photos/2019", true},
+		{"!/photos/*/raw", "photos", true},
+		{"(?d)/build/*.o", "build", true},
+		{"(?size>1G)/videos", "videos", true},
+		{"/*.tmp", "", false},
+		{"/photos/20[0-9]9", "photos", true},
+		{"photos", "", false},
+		{"**/photos", "", false},
+		{"(?i)/Photos", "", false},
+		{"#include more", "", false},
+		{"// a comment", "", false},
+	}
+	for _, tc := range tests {
+		if root, ok := PatternRoot(tc.line); root != tc.root || ok != tc.ok {
+			t.Errorf("PatternRoot(%q) = %q, %v; expected %q, %v", tc.line, root, ok, tc.root, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package ignore
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/fs"
+)
+
+// A Stat is what the predicates of patterns, like "(?size>100M)" or
+// "(?older-than:30d)", are evaluated against.
+type Stat struct {
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+}
+
+// StatOf returns the stat of a file on disk.
+func StatOf(info fs.FileInfo) *Stat {
+	return &Stat{
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		IsDir:   info.IsDir(),
+	}
+}
+
+type predicateKind int
+
+const (
+	predicateLarger predicateKind = iota
+	predicateSmaller
+	predicateOlder
+	predicateNewer
+)
+
+// A predicate restricts a pattern to the files of some sizes or ages. The
+// patterns with predicates never match directories, nor anything when
+// there's no stat to evaluate them against.
+type predicate struct {
+	kind  predicateKind
+	value int64 // bytes or nanoseconds
+	text  string
+}
+
+func (p predicate) holds(st *Stat, now time.Time) bool {
+	switch p.kind {
+	case predicateLarger:
+		return st.Size > p.value
+	case predicateSmaller:
+		return st.Size < p.value
+	case predicateOlder:
+		return now.Sub(st.ModTime) > time.Duration(p.value)
+	case predicateNewer:
+		return now.Sub(st.ModTime) < time.Duration(p.value)
+	}
+	return false
+}
+
+func (p Pattern) holds(st *Stat, now time.Time) bool {
+	if len(p.predicates) == 0 {
+		return true
+	}
+	if st == nil || st.IsDir {
+		return false
+	}
+	for _, pred := range p.predicates {
+		if !pred.holds(st, now) {
+			return false
+		}
+	}
+	return true
+}
+
+// parsePredicate parses the predicate the line starts with, returning the
+// rest of the line. It returns false if the line doesn't start with one.
+func parsePredicate(line string) (predicate, string, bool, error) {
+	var kind predicateKind
+	var rest string
+	switch {
+	case strings.HasPrefix(line, "(?size>"):
+		kind, rest = predicateLarger, line[len("(?size>"):]
+	case strings.HasPrefix(line, "(?size<"):
+		kind, rest = predicateSmaller, line[len("(?size<"):]
+	case strings.HasPrefix(line, "(?older-than:"):
+		kind, rest = predicateOlder, line[len("(?older-than:"):]
+	case strings.HasPrefix(line, "(?newer-than:"):
+		kind, rest = predicateNewer, line[len("(?newer-than:"):]
+	default:
+		return predicate{}, line, false, nil
+	}
+
+	end := strings.IndexByte(rest, ')')
+	if end < 0 {
+		return predicate{}, line, true, fmt.Errorf("unterminated predicate %q", line)
+	}
+	text := line[:len(line)-len(rest)+end+1]
+
+	var value int64
+	var err error
+	if kind == predicateLarger || kind == predicateSmaller {
+		value, err = parsePredicateSize(rest[:end])
+	} else {
+		var age time.Duration
+		age, err = parsePredicateAge(rest[:end])
+		value = int64(age)
+	}
+	if err != nil {
+		return predicate{}, line, true, fmt.Errorf("predicate %s: %v", text, err)
+	}
+	return predicate{kind: kind, value: value, text: text}, rest[end+1:], true, nil
+}
+
+var sizeUnits = map[string]int64{
+	"":   1,
+	"k":  1000,
+	"m":  1000 * 1000,
+	"g":  1000 * 1000 * 1000,
+	"t":  1000 * 1000 * 1000 * 1000,
+	"ki": 1 << 10,
+	"mi": 1 << 20,
+	"gi": 1 << 30,
+	"ti": 1 << 40,
+}
+
+// parsePredicateSize parses a size like "100M", "1.5GiB" or "512".
+func parsePredicateSize(s string) (int64, error) {
+	num, unit := splitNumber(s)
+	mult, ok := sizeUnits[strings.TrimSuffix(strings.ToLower(unit), "b")]
+	if !ok {
+		return 0, fmt.Errorf("unknown size unit %q", unit)
+	}
+	val, err := strconv.ParseFloat(num, 64)
+	if err != nil || val < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(val * float64(mult)), nil
+}
+
+var ageUnits = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// parsePredicateAge parses an age like "30d", "12h" or "2w".
+func parsePredicateAge(s string) (time.Duration, error) {
+	num, unit := splitNumber(s)
+	mult, ok := ageUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("unknown age unit %q", unit)
+	}
+	val, err := strconv.ParseFloat(num, 64)
+	if err != nil || val < 0 {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return time.Duration(val * float64(mult)), nil
+}
+
+func splitNumber(s string) (string, string) {
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], s[i:]
+}
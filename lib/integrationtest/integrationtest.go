@@ -0,0 +1,321 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// Package integrationtest runs clusters of Syncthing instances within the
+// test process, for black box tests of how devices sync with each other.
+// The instances keep their databases in memory and their folders on fake
+// filesystems, and are connected to each other directly over in-memory
+// pipes instead of through discovery and dialing. Note that files on fake
+// filesystems have contents generated from their names; what is written
+// to them only sets their size.
+package integrationtest
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/build"
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/db/backend"
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/model"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/rand"
+	"github.com/thejerf/suture"
+)
+
+// A Cluster is a set of instances that know about each other. They share
+// no folders and aren't connected until told to.
+type Cluster struct {
+	Instances []*Instance
+
+	key string // keeps the fake filesystems apart from other clusters
+	dir string // for the config files
+}
+
+// An Instance is a running Syncthing, without the GUI and connection
+// handling.
+type Instance struct {
+	ID     protocol.DeviceID
+	Name   string
+	Config config.Wrapper
+	Model  model.Model
+	Events events.Logger
+
+	cluster *Cluster
+	db      *db.Lowlevel
+	sup     *suture.Supervisor
+}
+
+// NewCluster starts the given number of instances.
+func NewCluster(instances int) (*Cluster, error) {
+	dir, err := ioutil.TempDir("", "syncthing-integrationtest-")
+	if err != nil {
+		return nil, err
+	}
+	c := &Cluster{
+		key: rand.String(8),
+		dir: dir,
+	}
+
+	devices := make([]config.DeviceConfiguration, instances)
+	for i := range devices {
+		id := protocol.NewDeviceID([]byte(fmt.Sprintf("integrationtest %s %d", c.key, i)))
+		devices[i] = config.NewDeviceConfiguration(id, fmt.Sprintf("device%d", i))
+	}
+
+	for i := range devices {
+		evLogger := events.NewLogger()
+		cfg := config.New(devices[i].DeviceID)
+		cfg.Devices = append([]config.DeviceConfiguration(nil), devices...)
+		cfg.Options.GlobalAnnEnabled = false
+		cfg.Options.LocalAnnEnabled = false
+		cfg.Options.RelaysEnabled = false
+		cfg.Options.NATEnabled = false
+		cfg.Options.URAccepted = -1
+		cfg.Options.CREnabled = false
+		wrapper := config.Wrap(filepath.Join(dir, devices[i].Name+".xml"), cfg, evLogger)
+
+		ldb := db.NewLowlevel(backend.OpenMemory())
+		m := model.NewModel(wrapper, devices[i].DeviceID, "syncthing", build.Version, ldb, nil, evLogger)
+
+		sup := suture.New(devices[i].Name, suture.Spec{
+			PassThroughPanics: true,
+		})
+		sup.Add(evLogger)
+		sup.Add(m)
+		sup.ServeBackground()
+
+		c.Instances = append(c.Instances, &Instance{
+			ID:      devices[i].DeviceID,
+			Name:    devices[i].Name,
+			Config:  wrapper,
+			Model:   m,
+			Events:  evLogger,
+			cluster: c,
+			db:      ldb,
+			sup:     sup,
+		})
+	}
+
+	return c, nil
+}
+
+// Close stops all instances.
+func (c *Cluster) Close() {
+	for _, inst := range c.Instances {
+		inst.sup.Stop()
+		inst.db.Close()
+	}
+	os.RemoveAll(c.dir)
+}
+
+// ShareFolder adds the folder on each of the instances, shared with each
+// other, and waits for them to be scanned. The folders are on fake
+// filesystems, empty unless the same folder was shared before.
+func (c *Cluster) ShareFolder(folder string, instances ...*Instance) error {
+	var devices []config.FolderDeviceConfiguration
+	for _, inst := range instances {
+		devices = append(devices, config.FolderDeviceConfiguration{DeviceID: inst.ID})
+	}
+	for _, inst := range instances {
+		fcfg := config.NewFolderConfiguration(inst.ID, folder, folder, fs.FilesystemTypeFake, inst.folderRoot(folder))
+		fcfg.Devices = devices
+		fcfg.FSWatcherEnabled = false
+		fcfg.RescanIntervalS = 0
+		waiter, err := inst.Config.SetFolder(fcfg)
+		if err != nil {
+			return err
+		}
+		waiter.Wait()
+	}
+	for _, inst := range instances {
+		if err := inst.waitRunning(folder, 10*time.Second); err != nil {
+			return err
+		}
+		if err := inst.Scan(folder); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Connect connects the two instances to each other.
+func (c *Cluster) Connect(a, b *Instance) error {
+	pa, pb := net.Pipe()
+	if err := a.addConnection(b, pa); err != nil {
+		pa.Close()
+		pb.Close()
+		return err
+	}
+	if err := b.addConnection(a, pb); err != nil {
+		pa.Close()
+		pb.Close()
+		return err
+	}
+	return nil
+}
+
+// ConnectAll connects each instance to each of the others.
+func (c *Cluster) ConnectAll() error {
+	for i, a := range c.Instances {
+		for _, b := range c.Instances[i+1:] {
+			if err := c.Connect(a, b); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Disconnect closes the connection between the two instances.
+func (c *Cluster) Disconnect(a, b *Instance) {
+	if conn, ok := a.Model.Connection(b.ID); ok {
+		conn.Close(errors.New("disconnected by test"))
+	}
+	if conn, ok := b.Model.Connection(a.ID); ok {
+		conn.Close(errors.New("disconnected by test"))
+	}
+}
+
+// DisconnectAll closes all connections between the instances, and waits
+// for them to be gone.
+func (c *Cluster) DisconnectAll() {
+	for i, a := range c.Instances {
+		for _, b := range c.Instances[i+1:] {
+			c.Disconnect(a, b)
+		}
+	}
+	for _, inst := range c.Instances {
+		for _, other := range c.Instances {
+			for {
+				if _, ok := inst.Model.Connection(other.ID); !ok {
+					break
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		}
+	}
+}
+
+// WaitInSync waits until the instances sharing the folder are done scanning
+// and pulling it, and all have the same files.
+func (c *Cluster) WaitInSync(folder string, timeout time.Duration) error {
+	for start := time.Now(); time.Since(start) < timeout; time.Sleep(50 * time.Millisecond) {
+		if c.inSync(folder) {
+			return nil
+		}
+	}
+	return fmt.Errorf("folder %q not in sync after %v", folder, timeout)
+}
+
+func (c *Cluster) inSync(folder string) bool {
+	var first map[string]protocol.Vector
+	for _, inst := range c.Instances {
+		if _, ok := inst.Config.Folder(folder); !ok {
+			continue
+		}
+		if state, _, err := inst.Model.State(folder); err != nil || state != "idle" {
+			return false
+		}
+		snap, err := inst.Model.DBSnapshot(folder)
+		if err != nil {
+			return false
+		}
+		need := snap.NeedSize()
+		have := make(map[string]protocol.Vector)
+		snap.WithHaveTruncated(protocol.LocalDeviceID, func(f db.FileIntf) bool {
+			have[f.FileName()] = f.FileVersion()
+			return true
+		})
+		snap.Release()
+		if need.TotalItems() > 0 {
+			return false
+		}
+		if first == nil {
+			first = have
+		} else if !reflect.DeepEqual(first, have) {
+			return false
+		}
+	}
+	return true
+}
+
+// Filesystem returns the filesystem of the instance's folder.
+func (i *Instance) Filesystem(folder string) fs.Filesystem {
+	return fs.NewFilesystem(fs.FilesystemTypeFake, i.folderRoot(folder))
+}
+
+// Scan scans the instance's folder and waits for it to finish.
+func (i *Instance) Scan(folder string) error {
+	return i.Model.ScanFolder(folder)
+}
+
+// waitRunning waits for the instance's folder to be started, which
+// happens in the background after it was added to the config.
+func (i *Instance) waitRunning(folder string, timeout time.Duration) error {
+	for start := time.Now(); time.Since(start) < timeout; time.Sleep(10 * time.Millisecond) {
+		state, _, err := i.Model.State(folder)
+		if err != nil {
+			return err
+		}
+		if state != "" {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: folder %s not running after %v", i.Name, folder, timeout)
+}
+
+func (i *Instance) folderRoot(folder string) string {
+	return fmt.Sprintf("/%s/%s/%s", i.cluster.key, i.Name, folder)
+}
+
+func (i *Instance) addConnection(remote *Instance, nc net.Conn) error {
+	hello := protocol.HelloResult{
+		DeviceName:    remote.Name,
+		ClientName:    "syncthing",
+		ClientVersion: build.Version,
+	}
+	if err := i.Model.OnHello(remote.ID, nc.RemoteAddr(), hello); err != nil {
+		return err
+	}
+	deviceCfg, _ := i.Config.Device(remote.ID)
+	name := fmt.Sprintf("pipe-%s-%s", i.Name, remote.Name)
+	conn := pipeConn{
+		Connection: protocol.NewConnection(remote.ID, nc, nc, i.Model, name, deviceCfg.Compression),
+		nc:         nc,
+		name:       name,
+	}
+	i.Model.AddConnection(conn, hello)
+	return nil
+}
+
+// pipeConn is a connection between two instances, over a net.Pipe.
+type pipeConn struct {
+	protocol.Connection
+	nc   net.Conn
+	name string
+}
+
+func (c pipeConn) Close(err error) {
+	c.Connection.Close(err)
+	c.nc.Close()
+}
+
+func (c pipeConn) Type() string         { return "pipe" }
+func (c pipeConn) Transport() string    { return "pipe" }
+func (c pipeConn) RemoteAddr() net.Addr { return c.nc.RemoteAddr() }
+func (c pipeConn) Priority() int        { return 0 }
+func (c pipeConn) String() string       { return c.name }
+func (c pipeConn) Crypto() string       { return "none" }
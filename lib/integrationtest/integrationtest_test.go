@@ -0,0 +1,141 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package integrationtest
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/fs"
+)
+
+const syncTimeout = 30 * time.Second
+
+func writeFile(t *testing.T, fs fs.Filesystem, name, content string) {
+	t.Helper()
+	fd, err := fs.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := fd.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, fs fs.Filesystem, name string) string {
+	t.Helper()
+	fd, err := fs.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	bs, err := ioutil.ReadAll(fd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(bs)
+}
+
+func newCluster(t *testing.T, instances int) *Cluster {
+	t.Helper()
+	c, err := NewCluster(instances)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.ShareFolder("default", c.Instances...); err != nil {
+		c.Close()
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestSyncFile(t *testing.T) {
+	c := newCluster(t, 3)
+	defer c.Close()
+	if err := c.ConnectAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	a, b := c.Instances[0], c.Instances[2]
+	writeFile(t, a.Filesystem("default"), "file", "hello")
+	if err := a.Scan("default"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.WaitInSync("default", syncTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	// Fake files have contents generated from their names, so what matters
+	// is that this is the same on both sides.
+	if ca, cb := readFile(t, a.Filesystem("default"), "file"), readFile(t, b.Filesystem("default"), "file"); ca != cb || len(cb) != len("hello") {
+		t.Errorf("Got %q on the other device, expected %q", cb, ca)
+	}
+}
+
+func TestConflict(t *testing.T) {
+	c := newCluster(t, 2)
+	defer c.Close()
+	a, b := c.Instances[0], c.Instances[1]
+
+	// Changed on both while apart. The sizes differ to get different
+	// contents on the fake filesystems.
+	writeFile(t, a.Filesystem("default"), "file", "from a")
+	writeFile(t, b.Filesystem("default"), "file", "from b, longer")
+	for _, inst := range c.Instances {
+		if err := inst.Scan("default"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := c.Connect(a, b); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.WaitInSync("default", syncTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, inst := range c.Instances {
+		names, err := inst.Filesystem("default").DirNames(".")
+		if err != nil {
+			t.Fatal(err)
+		}
+		conflicts := 0
+		for _, name := range names {
+			if strings.Contains(name, ".sync-conflict-") {
+				conflicts++
+			}
+		}
+		if conflicts != 1 {
+			t.Errorf("Expected a conflict copy on %s, got %v", inst.Name, names)
+		}
+	}
+}
+
+func TestSimulation(t *testing.T) {
+	c := newCluster(t, 3)
+	defer c.Close()
+
+	sc := Scenario{
+		Seed:    1,
+		Rounds:  3,
+		Changes: 10,
+		Files:   20,
+		MaxSize: 300 << 10,
+		Timeout: syncTimeout,
+	}
+	if testing.Short() {
+		sc.Rounds = 1
+	}
+	if err := c.Simulate("default", sc); err != nil {
+		t.Fatal(err)
+	}
+}
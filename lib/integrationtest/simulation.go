@@ -0,0 +1,187 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package integrationtest
+
+import (
+	"fmt"
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// A Scenario is a random but reproducible sequence of changes to a folder.
+// In each round the instances are disconnected from each other and each
+// makes its own changes to the folder: writing, deleting and renaming
+// files. They are then connected again and must end up with the same
+// files. The changes, their order, and the modification times they get
+// from a simulated clock depend only on the seed, so a failing seed can be
+// replayed. How the instances exchange the changes is not simulated, so
+// only the outcome is expected to be the same from one run to the next.
+type Scenario struct {
+	Seed    int64
+	Rounds  int
+	Changes int // per instance and round
+	Files   int // how many different file names there are to change
+	MaxSize int64
+	Timeout time.Duration // to get in sync after each round
+}
+
+// The simulated clock starts at a fixed time and ticks one second per
+// change.
+var simulationEpoch = time.Unix(1500000000, 0)
+
+const simulationDirs = 4
+
+// Simulate runs the scenario on the folder, which must be shared between
+// the instances.
+func (c *Cluster) Simulate(folder string, sc Scenario) error {
+	rng := rand.New(rand.NewSource(sc.Seed))
+	clock := simulationEpoch
+
+	for _, inst := range c.Instances {
+		ffs := inst.Filesystem(folder)
+		for d := 0; d < simulationDirs; d++ {
+			if err := ffs.MkdirAll(fmt.Sprintf("dir%d", d), 0755); err != nil {
+				return err
+			}
+		}
+	}
+
+	for round := 0; round < sc.Rounds; round++ {
+		c.DisconnectAll()
+
+		for _, inst := range c.Instances {
+			ffs := inst.Filesystem(folder)
+			for i := 0; i < sc.Changes; i++ {
+				clock = clock.Add(time.Second)
+				if err := simulateChange(ffs, rng, sc, clock); err != nil {
+					return fmt.Errorf("seed %d, round %d: %s: %v", sc.Seed, round, inst.Name, err)
+				}
+			}
+			if err := inst.Scan(folder); err != nil {
+				return fmt.Errorf("seed %d, round %d: %s: %v", sc.Seed, round, inst.Name, err)
+			}
+		}
+
+		if err := c.ConnectAll(); err != nil {
+			return err
+		}
+		if err := c.WaitInSync(folder, sc.Timeout); err != nil {
+			return fmt.Errorf("seed %d, round %d: %v", sc.Seed, round, err)
+		}
+		if err := c.compareFiles(folder); err != nil {
+			return fmt.Errorf("seed %d, round %d: %v", sc.Seed, round, err)
+		}
+	}
+
+	return nil
+}
+
+func simulateFileName(rng *rand.Rand, sc Scenario) string {
+	i := rng.Intn(sc.Files)
+	return filepath.Join(fmt.Sprintf("dir%d", i%simulationDirs), fmt.Sprintf("file%d", i))
+}
+
+// simulateChange makes one random change to the filesystem. Changes that
+// don't apply, like deleting a file that doesn't exist, are no changes.
+func simulateChange(ffs fs.Filesystem, rng *rand.Rand, sc Scenario, now time.Time) error {
+	name := simulateFileName(rng, sc)
+	_, err := ffs.Lstat(name)
+	exists := err == nil
+
+	switch op := rng.Intn(4); {
+	case op < 2 || !exists:
+		// Write it with a new size, which on the fake filesystem also
+		// means new contents.
+		fd, err := ffs.Create(name)
+		if err != nil {
+			return err
+		}
+		err = fd.Truncate(rng.Int63n(sc.MaxSize + 1))
+		fd.Close()
+		if err != nil {
+			return err
+		}
+		return ffs.Chtimes(name, now, now)
+
+	case op == 2:
+		return ffs.Remove(name)
+
+	default:
+		to := simulateFileName(rng, sc)
+		if to == name {
+			return nil
+		}
+		if _, err := ffs.Lstat(to); err == nil {
+			if err := ffs.Remove(to); err != nil {
+				return err
+			}
+		}
+		return ffs.Rename(name, to)
+	}
+}
+
+// compareFiles checks that the files in the folder are the same on all the
+// instances sharing it, and match what's in their databases.
+func (c *Cluster) compareFiles(folder string) error {
+	var first map[string]int64
+	var firstName string
+	for _, inst := range c.Instances {
+		if _, ok := inst.Config.Folder(folder); !ok {
+			continue
+		}
+
+		onDisk := make(map[string]int64)
+		err := inst.Filesystem(folder).Walk(".", func(path string, info fs.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if fs.IsInternal(path) || fs.IsTemporary(path) {
+				if info.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if info.IsRegular() {
+				onDisk[path] = info.Size()
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %v", inst.Name, err)
+		}
+
+		inDB := make(map[string]int64)
+		snap, err := inst.Model.DBSnapshot(folder)
+		if err != nil {
+			return fmt.Errorf("%s: %v", inst.Name, err)
+		}
+		snap.WithHave(protocol.LocalDeviceID, func(intf db.FileIntf) bool {
+			f := intf.(protocol.FileInfo)
+			if !f.IsDeleted() && f.Type == protocol.FileInfoTypeFile {
+				inDB[f.Name] = f.Size
+			}
+			return true
+		})
+		snap.Release()
+		if !reflect.DeepEqual(onDisk, inDB) {
+			return fmt.Errorf("%s: files on disk %v differ from database %v", inst.Name, onDisk, inDB)
+		}
+
+		if first == nil {
+			first, firstName = onDisk, inst.Name
+		} else if !reflect.DeepEqual(first, onDisk) {
+			return fmt.Errorf("files on %s %v differ from %s %v", inst.Name, onDisk, firstName, first)
+		}
+	}
+	return nil
+}
@@ -34,6 +34,7 @@ const (
 	AuditLog      LocationEnum = "auditLog"
 	GUIAssets     LocationEnum = "GUIAssets"
 	DefFolder     LocationEnum = "defFolder"
+	I2PKeys       LocationEnum = "i2pKeys"
 )
 
 type BaseDirEnum string
@@ -88,6 +89,7 @@ var locationTemplates = map[LocationEnum]string{
 	AuditLog:      "${config}/audit-${timestamp}.log",
 	GUIAssets:     "${config}/gui",
 	DefFolder:     "${home}/Sync",
+	I2PKeys:       "${config}/i2p-keys.dat",
 }
 
 var locations = make(map[LocationEnum]string)
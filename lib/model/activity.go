@@ -0,0 +1,186 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+// Changes are counted in hourly buckets over the last day.
+const (
+	activityBucketDuration = time.Hour
+	activityBuckets        = 24
+)
+
+// A DirectoryActivity is how many changes were made within a directory
+// and everything below it over the last day, found by scanning (local) or
+// pulled from other devices (remote).
+type DirectoryActivity struct {
+	Path   string `json:"path"`
+	Local  int    `json:"local"`
+	Remote int    `json:"remote"`
+}
+
+// activityTracker keeps rolling counts of changes per directory of each
+// folder. They are kept in memory only, as a hint of where things are
+// happening.
+type activityTracker struct {
+	mut     sync.Mutex
+	folders map[string]map[string]*activityCounter // folder -> directory -> counter
+}
+
+type activityCounter struct {
+	local, remote [activityBuckets]int
+	bucket        int64 // the bucket last counted in
+}
+
+func newActivityTracker() *activityTracker {
+	return &activityTracker{
+		mut:     sync.NewMutex(),
+		folders: make(map[string]map[string]*activityCounter),
+	}
+}
+
+func activityBucket(t time.Time) int64 {
+	return t.UnixNano() / int64(activityBucketDuration)
+}
+
+// advance moves the counter to the given bucket, clearing the buckets that
+// have fallen out of the day. It returns false when nothing is left.
+func (c *activityCounter) advance(bucket int64) bool {
+	for b := c.bucket + 1; b <= bucket && b <= c.bucket+activityBuckets; b++ {
+		c.local[b%activityBuckets] = 0
+		c.remote[b%activityBuckets] = 0
+	}
+	if bucket > c.bucket {
+		c.bucket = bucket
+	}
+	for i := range c.local {
+		if c.local[i] != 0 || c.remote[i] != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *activityCounter) totals() (local, remote int) {
+	for i := range c.local {
+		local += c.local[i]
+		remote += c.remote[i]
+	}
+	return local, remote
+}
+
+// record counts the changed files in their parent directories.
+func (t *activityTracker) record(folder string, files []protocol.FileInfo, remote bool, now time.Time) {
+	bucket := activityBucket(now)
+
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	dirs, ok := t.folders[folder]
+	if !ok {
+		dirs = make(map[string]*activityCounter)
+		t.folders[folder] = dirs
+	}
+	for _, file := range files {
+		if file.IsInvalid() {
+			continue
+		}
+		dir := filepath.Dir(file.Name)
+		c, ok := dirs[dir]
+		if !ok {
+			c = &activityCounter{bucket: bucket}
+			dirs[dir] = c
+		}
+		c.advance(bucket)
+		if remote {
+			c.remote[bucket%activityBuckets]++
+		} else {
+			c.local[bucket%activityBuckets]++
+		}
+	}
+}
+
+// report returns the activity within the directories down to the given
+// number of levels below prefix, or all of them for levels < 0, busiest
+// first. The prefix itself is included as the total.
+func (t *activityTracker) report(folder, prefix string, levels int, now time.Time) []DirectoryActivity {
+	bucket := activityBucket(now)
+	prefix = filepath.Clean(prefix)
+	if prefix == string(filepath.Separator) {
+		prefix = "."
+	}
+
+	t.mut.Lock()
+	sums := make(map[string]*DirectoryActivity)
+	for dir, c := range t.folders[folder] {
+		if !c.advance(bucket) {
+			delete(t.folders[folder], dir)
+			continue
+		}
+		var rel string
+		switch {
+		case prefix == "." || dir == prefix:
+			rel = dir
+		case strings.HasPrefix(dir, prefix+string(filepath.Separator)):
+			rel = dir[len(prefix)+1:]
+		default:
+			continue
+		}
+		parts := []string{}
+		if rel != "." && dir != prefix {
+			parts = strings.Split(rel, string(filepath.Separator))
+		}
+
+		local, remote := c.totals()
+		for k := 0; k <= len(parts) && (levels < 0 || k <= levels); k++ {
+			path := filepath.Join(append([]string{prefix}, parts[:k]...)...)
+			sum, ok := sums[path]
+			if !ok {
+				sum = &DirectoryActivity{Path: path}
+				sums[path] = sum
+			}
+			sum.Local += local
+			sum.Remote += remote
+		}
+	}
+	t.mut.Unlock()
+
+	res := make([]DirectoryActivity, 0, len(sums))
+	for _, sum := range sums {
+		res = append(res, *sum)
+	}
+	sort.Slice(res, func(a, b int) bool {
+		if ta, tb := res[a].Local+res[a].Remote, res[b].Local+res[b].Remote; ta != tb {
+			return ta > tb
+		}
+		return res[a].Path < res[b].Path
+	})
+	return res
+}
+
+func (t *activityTracker) forget(folder string) {
+	t.mut.Lock()
+	delete(t.folders, folder)
+	t.mut.Unlock()
+}
+
+// FolderActivity returns how many changes were made where in the folder
+// over the last day. See activityTracker.report.
+func (m *model) FolderActivity(folder, prefix string, levels int) ([]DirectoryActivity, error) {
+	if _, ok := m.cfg.Folder(folder); !ok {
+		return nil, errFolderMissing
+	}
+	return m.activity.report(folder, prefix, levels, time.Now()), nil
+}
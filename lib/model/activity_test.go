@@ -0,0 +1,62 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestActivityTracker(t *testing.T) {
+	files := func(names ...string) []protocol.FileInfo {
+		fs := make([]protocol.FileInfo, len(names))
+		for i, name := range names {
+			fs[i] = protocol.FileInfo{Name: filepath.FromSlash(name)}
+		}
+		return fs
+	}
+
+	tr := newActivityTracker()
+	t0 := time.Unix(1500000000, 0)
+	tr.record("default", files("top", "a/one", "a/b/two", "a/b/three"), false, t0)
+	tr.record("default", files("a/b/four", "c/five"), true, t0.Add(12*time.Hour))
+	tr.record("other", files("a/six"), false, t0)
+
+	expected := []DirectoryActivity{
+		{Path: ".", Local: 4, Remote: 2},
+		{Path: "a", Local: 3, Remote: 1},
+		{Path: "c", Local: 0, Remote: 1},
+	}
+	if res := tr.report("default", "", 1, t0.Add(12*time.Hour)); !reflect.DeepEqual(res, expected) {
+		t.Errorf("Got %v, expected %v", res, expected)
+	}
+
+	expected = []DirectoryActivity{
+		{Path: "a", Local: 3, Remote: 1},
+		{Path: filepath.FromSlash("a/b"), Local: 2, Remote: 1},
+	}
+	if res := tr.report("default", "a", -1, t0.Add(12*time.Hour)); !reflect.DeepEqual(res, expected) {
+		t.Errorf("Got %v, expected %v", res, expected)
+	}
+
+	// A day later the first changes have rolled off.
+	expected = []DirectoryActivity{
+		{Path: ".", Local: 0, Remote: 2},
+		{Path: "a", Local: 0, Remote: 1},
+		{Path: "c", Local: 0, Remote: 1},
+	}
+	if res := tr.report("default", ".", 1, t0.Add(25*time.Hour)); !reflect.DeepEqual(res, expected) {
+		t.Errorf("Got %v, expected %v", res, expected)
+	}
+	if res := tr.report("default", ".", 1, t0.Add(40*time.Hour)); len(res) != 0 {
+		t.Errorf("Expected nothing left, got %v", res)
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"container/list"
+	"errors"
+	"path/filepath"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/rand"
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+// Listings that aren't continued within this time are dropped, releasing
+// the database snapshot they were read from.
+const browseCursorTimeout = time.Minute
+
+// At most this many listings are kept for continuing, dropping the least
+// recently used one when another is added.
+const maxBrowseCursors = 64
+
+var errBrowseTokenUnknown = errors.New("unknown or expired listing token")
+
+// A DirectoryEntry is an item in the listing of a single directory.
+type DirectoryEntry struct {
+	Name    string    `json:"name"`
+	Type    string    `json:"type"`
+	ModTime time.Time `json:"modTime"`
+	Size    int64     `json:"size"`
+}
+
+// DirectorySort is the order of the items in a directory listing.
+type DirectorySort string
+
+const (
+	DirectorySortName     DirectorySort = "name"
+	DirectorySortSize     DirectorySort = "size"
+	DirectorySortModified DirectorySort = "modified"
+)
+
+// A DirectoryPage is one page of a directory listing. All pages of a
+// listing are read from the same database state, identified by Sequence.
+// Token is set when there are more pages and is used to get the next one.
+type DirectoryPage struct {
+	Entries  []DirectoryEntry `json:"entries"`
+	More     bool             `json:"more"`
+	Sequence int64            `json:"sequence"`
+	Token    string           `json:"token,omitempty"`
+}
+
+// A browseCursor is the position in a directory listing being paged
+// through.
+type browseCursor struct {
+	token    string
+	prefix   string
+	dirsonly bool
+	sequence int64
+
+	// Listings in name order are read lazily from the pinned snapshot,
+	// continuing after the last returned item.
+	snap  *db.Snapshot
+	after string
+
+	// Other orders require reading the whole directory up front, so what
+	// remains is kept instead.
+	remaining []DirectoryEntry
+
+	timer *time.Timer
+}
+
+func (c *browseCursor) release() {
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	if c.snap != nil {
+		c.snap.Release()
+	}
+}
+
+// page returns up to limit entries from the cursor, and whether there are
+// more after them.
+func (c *browseCursor) page(limit int) ([]DirectoryEntry, bool) {
+	var entries []DirectoryEntry
+	var more bool
+	if c.snap != nil {
+		entries, more = readDirectory(c.snap, c.prefix, c.after, c.dirsonly, 0, limit)
+		if len(entries) > 0 {
+			c.after = filepath.Join(c.prefix, entries[len(entries)-1].Name)
+		}
+	} else {
+		entries = c.remaining
+		if limit > 0 && len(entries) > limit {
+			entries, more = entries[:limit], true
+		}
+		c.remaining = c.remaining[len(entries):]
+	}
+	return entries, more
+}
+
+// browseCursors holds the listings that can be continued, by token.
+type browseCursors struct {
+	max     int
+	mut     sync.Mutex
+	order   *list.List // of *browseCursor, most recently used first
+	cursors map[string]*list.Element
+}
+
+func newBrowseCursors() *browseCursors {
+	return &browseCursors{
+		max:     maxBrowseCursors,
+		mut:     sync.NewMutex(),
+		order:   list.New(),
+		cursors: make(map[string]*list.Element),
+	}
+}
+
+// add keeps the cursor for continuing until it times out or is evicted and
+// returns the token to continue with.
+func (b *browseCursors) add(c *browseCursor) string {
+	token := rand.String(32)
+	c.token = token
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	b.cursors[token] = b.order.PushFront(c)
+	c.timer = time.AfterFunc(browseCursorTimeout, func() {
+		if c, ok := b.take(token); ok {
+			c.release()
+		}
+	})
+	for b.order.Len() > b.max {
+		b.removeLocked(b.order.Back()).release()
+	}
+	return token
+}
+
+// take removes and returns the cursor for the token.
+func (b *browseCursors) take(token string) (*browseCursor, bool) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	el, ok := b.cursors[token]
+	if !ok {
+		return nil, false
+	}
+	c := b.removeLocked(el)
+	c.timer.Stop()
+	return c, true
+}
+
+func (b *browseCursors) removeLocked(el *list.Element) *browseCursor {
+	c := b.order.Remove(el).(*browseCursor)
+	delete(b.cursors, c.token)
+	return c
+}
+
+func (b *browseCursors) releaseAll() {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	for b.order.Len() > 0 {
+		b.removeLocked(b.order.Front()).release()
+	}
+}
+
+// continuePage returns the next page of the listing for the token, keeping
+// the cursor for another page if there is one.
+func (b *browseCursors) continuePage(token string, limit int) (DirectoryPage, error) {
+	c, ok := b.take(token)
+	if !ok {
+		return DirectoryPage{}, errBrowseTokenUnknown
+	}
+	return b.nextPage(c, limit), nil
+}
+
+func (b *browseCursors) nextPage(c *browseCursor, limit int) DirectoryPage {
+	entries, more := c.page(limit)
+	if entries == nil {
+		entries = []DirectoryEntry{}
+	}
+	page := DirectoryPage{
+		Entries:  entries,
+		More:     more,
+		Sequence: c.sequence,
+	}
+	if more {
+		page.Token = b.add(c)
+	} else {
+		c.release()
+	}
+	return page
+}
+
+// readDirectory reads the items directly within prefix from the snapshot,
+// after the given name and skipping the first skip of them. It returns up
+// to limit items and whether there are more.
+func readDirectory(snap *db.Snapshot, prefix, after string, dirsonly bool, skip, limit int) ([]DirectoryEntry, bool) {
+	var entries []DirectoryEntry
+	more := false
+	snap.WithGlobalChildrenTruncated(prefix, after, func(fi db.FileIntf) bool {
+		f := fi.(db.FileInfoTruncated)
+		if f.IsInvalid() || f.IsDeleted() || (dirsonly && !f.IsDirectory()) {
+			return true
+		}
+		if skip > 0 {
+			skip--
+			return true
+		}
+		if limit > 0 && len(entries) == limit {
+			more = true
+			return false
+		}
+		entries = append(entries, directoryEntry(f))
+		return true
+	})
+	return entries, more
+}
+
+func directoryEntry(f db.FileInfoTruncated) DirectoryEntry {
+	entry := DirectoryEntry{
+		Name:    filepath.Base(f.Name),
+		Type:    "file",
+		ModTime: f.ModTime(),
+		Size:    f.FileSize(),
+	}
+	switch {
+	case f.IsSymlink():
+		entry.Type = "symlink"
+	case f.IsDirectory():
+		entry.Type = "directory"
+	}
+	return entry
+}
+
+// snapshotSequence identifies the state of the database a snapshot sees.
+func snapshotSequence(snap *db.Snapshot) int64 {
+	return snap.Sequence(protocol.LocalDeviceID) + snap.RemoteSequence()
+}
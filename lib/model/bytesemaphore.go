@@ -13,6 +13,7 @@ import (
 type byteSemaphore struct {
 	max       int
 	available int
+	waiting   map[int]int // priority -> number of waiting takes
 	mut       sync.Mutex
 	cond      *sync.Cond
 }
@@ -24,26 +25,52 @@ func newByteSemaphore(max int) *byteSemaphore {
 	s := byteSemaphore{
 		max:       max,
 		available: max,
+		waiting:   make(map[int]int),
 	}
 	s.cond = sync.NewCond(&s.mut)
 	return &s
 }
 
 func (s *byteSemaphore) take(bytes int) {
+	s.takeWithPriority(bytes, 0)
+}
+
+// takeWithPriority is like take, but waits for any waiting takes of a
+// higher priority to go first.
+func (s *byteSemaphore) takeWithPriority(bytes, priority int) {
 	s.mut.Lock()
 	if bytes > s.max {
 		bytes = s.max
 	}
-	for bytes > s.available {
-		s.cond.Wait()
-		if bytes > s.max {
-			bytes = s.max
+	if bytes > s.available || s.higherWaiting(priority) {
+		s.waiting[priority]++
+		for bytes > s.available || s.higherWaiting(priority) {
+			s.cond.Wait()
+			if bytes > s.max {
+				bytes = s.max
+			}
 		}
+		if s.waiting[priority]--; s.waiting[priority] == 0 {
+			delete(s.waiting, priority)
+		}
+		// We may have been what someone of lower priority was waiting for.
+		s.cond.Broadcast()
 	}
 	s.available -= bytes
 	s.mut.Unlock()
 }
 
+// higherWaiting returns whether takes of a higher priority are waiting.
+// Must be called with the mutex held.
+func (s *byteSemaphore) higherWaiting(priority int) bool {
+	for p := range s.waiting {
+		if p > priority {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *byteSemaphore) give(bytes int) {
 	s.mut.Lock()
 	if bytes > s.max {
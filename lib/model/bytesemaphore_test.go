@@ -6,7 +6,10 @@
 
 package model
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestZeroByteSempahore(t *testing.T) {
 	// A semaphore with zero capacity is just a no-op.
@@ -111,3 +114,40 @@ func TestByteSempahoreGiveMore(t *testing.T) {
 		t.Errorf("bad state after large take + give with adjustment")
 	}
 }
+
+func TestByteSemaphorePriority(t *testing.T) {
+	// Waiting takes of a higher priority go first
+
+	s := newByteSemaphore(1)
+	s.take(1)
+
+	waitFor := func(priority int) {
+		for start := time.Now(); time.Since(start) < 10*time.Second; time.Sleep(time.Millisecond) {
+			s.mut.Lock()
+			n := s.waiting[priority]
+			s.mut.Unlock()
+			if n > 0 {
+				return
+			}
+		}
+		t.Fatal("timed out waiting for take with priority", priority)
+	}
+
+	order := make(chan int, 2)
+	for _, priority := range []int{0, 10} {
+		go func(priority int) {
+			s.takeWithPriority(1, priority)
+			order <- priority
+		}(priority)
+		waitFor(priority)
+	}
+
+	s.give(1)
+	if p := <-order; p != 10 {
+		t.Error("expected the high priority take first, got", p)
+	}
+	s.give(1)
+	if p := <-order; p != 0 {
+		t.Error("expected the low priority take second, got", p)
+	}
+}
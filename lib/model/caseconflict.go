@@ -0,0 +1,126 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"path/filepath"
+
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/fs"
+)
+
+// A caseChecker finds the items that can't be synced on a case insensitive
+// filesystem because another one is there, or is being synced in the same
+// iteration, whose name differs only in case. Directory listings are
+// cached for the iteration, so each directory is only read once.
+type caseChecker struct {
+	fs   fs.Filesystem
+	dirs map[string]map[string]string // directory -> lowercased name -> name
+}
+
+func newCaseChecker(filesystem fs.Filesystem) *caseChecker {
+	return &caseChecker{
+		fs:   filesystem,
+		dirs: make(map[string]map[string]string),
+	}
+}
+
+func (c *caseChecker) names(dir string) map[string]string {
+	names, ok := c.dirs[dir]
+	if ok {
+		return names
+	}
+	names = make(map[string]string)
+	// A directory that can't be listed, most likely because it doesn't
+	// exist yet, has nothing in it to conflict with.
+	onDisk, _ := c.fs.DirNames(dir)
+	for _, name := range onDisk {
+		names[fs.UnicodeLowercase(name)] = name
+	}
+	c.dirs[dir] = names
+	return names
+}
+
+// conflict returns the existing item that the given one differs from only
+// in case, if any. Otherwise the item is taken to exist from now on, for
+// any that conflict with it later in the iteration.
+func (c *caseChecker) conflict(name string) (string, bool) {
+	dir, base := filepath.Dir(name), filepath.Base(name)
+	names := c.names(dir)
+	lower := fs.UnicodeLowercase(base)
+	if existing, ok := names[lower]; ok && existing != base {
+		return filepath.Join(dir, existing), true
+	}
+	names[lower] = base
+	return "", false
+}
+
+// renamed records that an item was renamed, to resolve a conflict.
+func (c *caseChecker) renamed(from, to string) {
+	names := c.names(filepath.Dir(from))
+	delete(names, fs.UnicodeLowercase(filepath.Base(from)))
+	names[fs.UnicodeLowercase(filepath.Base(to))] = filepath.Base(to)
+}
+
+// caseConflictName returns the name to rename an item to with the given
+// suffix, which goes before the extension.
+func caseConflictName(name, suffix string) string {
+	ext := filepath.Ext(name)
+	return name[:len(name)-len(ext)] + suffix + ext
+}
+
+// checkCaseConflict returns whether the item can't be synced because of a
+// case conflict, which is then reported as a pull error. With a case
+// conflict suffix configured, the local item in the way is renamed instead
+// where possible, to be picked up by the scanner like any other rename.
+// An existing item that is deleted globally doesn't conflict, as the
+// incoming item is what it was renamed to.
+func (f *sendReceiveFolder) checkCaseConflict(name string, snap *db.Snapshot, scanChan chan<- string) bool {
+	if f.caseCheck == nil {
+		return false
+	}
+	existing, ok := f.caseCheck.conflict(name)
+	if !ok {
+		return false
+	}
+	if global, ok := snap.GetGlobal(existing); ok && global.IsDeleted() {
+		return false
+	}
+
+	if f.CaseConflictSuffix != "" {
+		newName := caseConflictName(existing, f.CaseConflictSuffix)
+		err := f.renameCaseConflict(existing, newName)
+		if err == nil {
+			l.Infof("Folder %v: renamed %q to %q, as it differs only in case from %q", f.Description(), existing, newName, name)
+			f.caseCheck.renamed(existing, newName)
+			f.caseCheck.conflict(name)
+			scanChan <- existing
+			scanChan <- newName
+			return false
+		}
+		l.Debugf("%v renaming %q for case conflict with %q: %v", f, existing, name, err)
+	}
+
+	f.newPullError(name, &fs.CaseConflictError{Name: name, Existing: existing})
+	return true
+}
+
+func (f *sendReceiveFolder) renameCaseConflict(from, to string) error {
+	if _, err := f.fs.Lstat(from); err != nil {
+		// Not on disk yet, as it's being synced in the same iteration
+		return err
+	}
+	if _, err := f.fs.Lstat(to); !fs.IsNotExist(err) {
+		if err == nil {
+			err = errCaseConflictTargetExists
+		}
+		return err
+	}
+	return f.inWritableDir(func(name string) error {
+		return f.fs.Rename(name, to)
+	}, from)
+}
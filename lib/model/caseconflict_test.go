@@ -0,0 +1,48 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/fs"
+)
+
+func TestCaseChecker(t *testing.T) {
+	ffs := fs.NewFilesystem(fs.FilesystemTypeFake, "/TestCaseChecker?insens=true")
+	must(t, ffs.MkdirAll("dir", 0755))
+	fd, err := ffs.Create(filepath.Join("dir", "Foo"))
+	must(t, err)
+	fd.Close()
+
+	c := newCaseChecker(ffs)
+	if _, ok := c.conflict(filepath.Join("dir", "Foo")); ok {
+		t.Error("Unexpected conflict of Foo with itself")
+	}
+	if existing, ok := c.conflict(filepath.Join("dir", "FOO")); !ok || existing != filepath.Join("dir", "Foo") {
+		t.Errorf("Expected FOO to conflict with Foo, got %q, %v", existing, ok)
+	}
+
+	// Items synced in the same iteration conflict with each other too
+	if _, ok := c.conflict(filepath.Join("dir", "bar")); ok {
+		t.Error("Unexpected conflict for bar")
+	}
+	if existing, ok := c.conflict(filepath.Join("dir", "Bar")); !ok || existing != filepath.Join("dir", "bar") {
+		t.Errorf("Expected Bar to conflict with bar, got %q, %v", existing, ok)
+	}
+
+	// Until one is renamed
+	c.renamed(filepath.Join("dir", "Foo"), filepath.Join("dir", "Foo.case"))
+	if _, ok := c.conflict(filepath.Join("dir", "FOO")); ok {
+		t.Error("Unexpected conflict for FOO after renaming Foo")
+	}
+
+	if name := caseConflictName(filepath.Join("dir", "Foo.txt"), ".case"); name != filepath.Join("dir", "Foo.case.txt") {
+		t.Error("Unexpected case conflict name", name)
+	}
+}
@@ -0,0 +1,32 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"time"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// Conflicts are resolved in favour of the most recently modified file, so
+// clocks that differ by more than this between devices are warned about.
+const maxClockSkew = 30 * time.Second
+
+// clockSkew returns how far ahead of ours the clock of the other device is,
+// given that its hello message was received at now. The result includes the
+// network latency, which is negligible compared to skew worth caring about.
+// Older clients don't send the time and so always appear to be in sync.
+func clockSkew(hello protocol.HelloResult, now time.Time) time.Duration {
+	if hello.Timestamp == 0 {
+		return 0
+	}
+	return time.Unix(0, hello.Timestamp).Sub(now)
+}
+
+func isClockSkewed(skew time.Duration) bool {
+	return skew > maxClockSkew || skew < -maxClockSkew
+}
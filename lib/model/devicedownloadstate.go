@@ -85,6 +85,47 @@ func (p *deviceFolderDownloadState) GetBlockCounts() map[string]int {
 	return res
 }
 
+// BytesDownloaded returns the number of bytes of the given version of the
+// file that the remote device has already downloaded into its temporary
+// file. Size and blockSize describe the file, and are used to account for a
+// shorter last block.
+func (p *deviceFolderDownloadState) BytesDownloaded(file string, version protocol.Vector, size int64, blockSize int) int64 {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	local, ok := p.files[file]
+	if !ok || !local.version.Equal(version) {
+		return 0
+	}
+
+	var bytes int64
+	for _, index := range local.blockIndexes {
+		offset := int64(index) * int64(blockSize)
+		switch {
+		case offset >= size:
+			continue
+		case size-offset < int64(blockSize):
+			bytes += size - offset
+		default:
+			bytes += int64(blockSize)
+		}
+	}
+	return bytes
+}
+
+// BlockIndexes returns the indexes of the blocks of the given version of the
+// file that are available from the remote device's temporary file.
+func (p *deviceFolderDownloadState) BlockIndexes(file string, version protocol.Vector) []int32 {
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+
+	local, ok := p.files[file]
+	if !ok || !local.version.Equal(version) {
+		return nil
+	}
+	return append([]int32(nil), local.blockIndexes...)
+}
+
 // deviceDownloadState represents the state of all in progress downloads
 // for all folders of a specific device.
 type deviceDownloadState struct {
@@ -150,6 +191,42 @@ func (t *deviceDownloadState) GetBlockCounts(folder string) map[string]int {
 	return nil
 }
 
+// BytesDownloaded returns the number of bytes of the given version of the
+// file in the given folder that the remote device has already downloaded
+// into its temporary file.
+func (t *deviceDownloadState) BytesDownloaded(folder, file string, version protocol.Vector, size int64, blockSize int) int64 {
+	if t == nil {
+		return 0
+	}
+	t.mut.RLock()
+	f, ok := t.folders[folder]
+	t.mut.RUnlock()
+
+	if !ok {
+		return 0
+	}
+
+	return f.BytesDownloaded(file, version, size, blockSize)
+}
+
+// BlockIndexes returns the indexes of the blocks of the given version of the
+// file in the given folder that are available from the remote device's
+// temporary file.
+func (t *deviceDownloadState) BlockIndexes(folder, file string, version protocol.Vector) []int32 {
+	if t == nil {
+		return nil
+	}
+	t.mut.RLock()
+	f, ok := t.folders[folder]
+	t.mut.RUnlock()
+
+	if !ok {
+		return nil
+	}
+
+	return f.BlockIndexes(file, version)
+}
+
 func newDeviceDownloadState() *deviceDownloadState {
 	return &deviceDownloadState{
 		mut:     sync.NewRWMutex(),
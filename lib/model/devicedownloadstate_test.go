@@ -109,3 +109,35 @@ func TestDeviceDownloadState(t *testing.T) {
 		}
 	}
 }
+
+func TestDeviceDownloadStateBytesDownloaded(t *testing.T) {
+	v1 := (protocol.Vector{}).Update(0)
+	v2 := (protocol.Vector{}).Update(1)
+
+	s := newDeviceDownloadState()
+	s.Update("folder", []protocol.FileDownloadProgressUpdate{
+		{UpdateType: protocol.UpdateTypeAppend, Name: "f1", Version: v1, BlockIndexes: []int32{0, 1, 3}},
+	})
+
+	const blockSize = 128 << 10
+	size := int64(3*blockSize + 1000)
+
+	// Three full blocks would be too much; the last block is short.
+	if got, exp := s.BytesDownloaded("folder", "f1", v1, size, blockSize), int64(2*blockSize+1000); got != exp {
+		t.Errorf("got %d bytes, expected %d", got, exp)
+	}
+	if got := s.BytesDownloaded("folder", "f1", v2, size, blockSize); got != 0 {
+		t.Errorf("got %d bytes for other version, expected 0", got)
+	}
+	if got := s.BytesDownloaded("folder", "f2", v1, size, blockSize); got != 0 {
+		t.Errorf("got %d bytes for unknown file, expected 0", got)
+	}
+	if got := s.BytesDownloaded("other", "f1", v1, size, blockSize); got != 0 {
+		t.Errorf("got %d bytes for unknown folder, expected 0", got)
+	}
+
+	var nilState *deviceDownloadState
+	if got := nilState.BytesDownloaded("folder", "f1", v1, size, blockSize); got != 0 {
+		t.Errorf("got %d bytes for nil state, expected 0", got)
+	}
+}
@@ -0,0 +1,251 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+var (
+	errNotAFile       = errors.New("not a regular file")
+	errFileExists     = errors.New("file exists")
+	errNoDeviceHasIt  = errors.New("no connected device has the file")
+	errUndeleteIgnore = errors.New("file is ignored")
+)
+
+// FileContent returns a reader for the content of the given version of a
+// file, without writing anything to the folder. Each block is read from
+// whichever local file has it according to the block index, or else
+// requested from a connected device having exactly this version of the
+// file. Blocks are verified against their hashes before being returned.
+func (m *model) FileContent(ctx context.Context, folder string, file protocol.FileInfo) (io.Reader, error) {
+	if file.IsDeleted() || file.IsInvalid() || file.Type != protocol.FileInfoTypeFile {
+		return nil, errNotAFile
+	}
+
+	m.fmut.RLock()
+	cfg, ok := m.folderCfgs[folder]
+	fset := m.folderFiles[folder]
+	m.fmut.RUnlock()
+	if !ok {
+		return nil, errFolderMissing
+	}
+
+	// The devices that can be asked for the blocks.
+	var devices []protocol.DeviceID
+	snap := fset.Snapshot()
+	for _, dev := range cfg.Devices {
+		if dev.DeviceID == m.id {
+			continue
+		}
+		if f, ok := snap.Get(dev.DeviceID, file.Name); ok && f.Version.Equal(file.Version) && !f.IsInvalid() {
+			devices = append(devices, dev.DeviceID)
+		}
+	}
+	snap.Release()
+
+	return &fileContentReader{
+		ctx:     ctx,
+		model:   m,
+		folder:  folder,
+		fs:      m.folderFilesystem(cfg),
+		file:    file,
+		devices: devices,
+	}, nil
+}
+
+type fileContentReader struct {
+	ctx     context.Context
+	model   *model
+	folder  string
+	fs      fs.Filesystem
+	file    protocol.FileInfo
+	devices []protocol.DeviceID
+
+	next int    // index of the next block to read
+	buf  []byte // what remains of the current block
+}
+
+func (r *fileContentReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.next == len(r.file.Blocks) {
+			return 0, io.EOF
+		}
+		buf, err := r.readBlock(r.file.Blocks[r.next])
+		if err != nil {
+			return 0, err
+		}
+		r.buf = buf
+		r.next++
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *fileContentReader) readBlock(block protocol.BlockInfo) ([]byte, error) {
+	buf := make([]byte, block.Size)
+	found := r.model.finder.Iterate([]string{r.folder}, block.Hash, func(_, path string, index int32) bool {
+		fd, err := r.fs.Open(path)
+		if err != nil {
+			return false
+		}
+		_, err = fd.ReadAt(buf, int64(r.file.BlockSize())*int64(index))
+		fd.Close()
+		return err == nil && verifyBuffer(buf, block) == nil
+	})
+	if found {
+		return buf, nil
+	}
+
+	for _, dev := range r.devices {
+		buf, err := r.model.requestGlobal(r.ctx, dev, r.folder, r.file.Name, block.Offset, int(block.Size), block.Hash, block.WeakHash, false)
+		if err != nil {
+			l.Debugf("Requesting block of %v from %v: %v", r.file.Name, dev, err)
+			continue
+		}
+		if err := verifyBuffer(buf, block); err != nil {
+			l.Debugf("Block of %v from %v: %v", r.file.Name, dev, err)
+			continue
+		}
+		return buf, nil
+	}
+
+	return nil, fmt.Errorf("block at offset %d is not available", block.Offset)
+}
+
+// RestoreDeletedFiles brings back files that are deleted here from the
+// connected devices that still have them, like the ones that keep deleted
+// files or haven't synced the deletion yet. For each file the newest
+// version any of them has is fetched as by FileContent and written to the
+// folder, and the folder is rescanned for the files to be announced as new
+// changes. Errors are returned by file, as for RestoreFolderVersions.
+func (m *model) RestoreDeletedFiles(ctx context.Context, folder string, files []string) (map[string]string, error) {
+	m.fmut.RLock()
+	err := m.checkFolderRunningLocked(folder)
+	cfg := m.folderCfgs[folder]
+	fset := m.folderFiles[folder]
+	ignores := m.folderIgnores[folder]
+	m.fmut.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	ffs := m.folderFilesystem(cfg)
+	restoreErrors := make(map[string]string)
+	var restored []string
+
+	for _, file := range files {
+		name, err := fs.Canonicalize(file)
+		if err == nil {
+			name = cfg.UnicodeNormalization.Form().String(name)
+			if fs.IsInternal(name) || ignores.Match(name).IsIgnored() {
+				err = errUndeleteIgnore
+			}
+		}
+		if err == nil {
+			err = m.restoreDeletedFile(ctx, cfg, ffs, fset, name)
+		}
+		if err != nil {
+			restoreErrors[file] = err.Error()
+			continue
+		}
+		restored = append(restored, name)
+	}
+
+	if len(restored) > 0 {
+		go func() { _ = m.ScanFolderSubdirs(folder, restored) }()
+	}
+
+	return restoreErrors, nil
+}
+
+func (m *model) restoreDeletedFile(ctx context.Context, cfg config.FolderConfiguration, ffs fs.Filesystem, fset *db.FileSet, name string) error {
+	if _, err := ffs.Lstat(name); err == nil {
+		return errFileExists
+	} else if !fs.IsNotExist(err) {
+		return err
+	}
+
+	file, ok := m.deletedFileSource(cfg, fset, name)
+	if !ok {
+		return errNoDeviceHasIt
+	}
+	content, err := m.FileContent(ctx, cfg.ID, file)
+	if err != nil {
+		return err
+	}
+
+	if err := ffs.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	tempName := fs.TempName(name)
+	fd, err := ffs.Create(tempName)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(fd, content)
+	if cerr := fd.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && !cfg.IgnorePerms && !file.NoPermissions {
+		err = ffs.Chmod(tempName, fs.FileMode(file.Permissions&0777))
+	}
+	if err == nil {
+		ffs.Chtimes(tempName, file.ModTime(), file.ModTime()) // never fails
+		// Something else may have created the file meanwhile.
+		if _, serr := ffs.Lstat(name); serr == nil {
+			err = errFileExists
+		}
+	}
+	if err == nil {
+		err = ffs.Rename(tempName, name)
+	}
+	if err != nil {
+		ffs.Remove(tempName)
+		return err
+	}
+	return nil
+}
+
+// deletedFileSource returns the newest version of the file among the
+// connected devices that have it as a regular file.
+func (m *model) deletedFileSource(cfg config.FolderConfiguration, fset *db.FileSet, name string) (protocol.FileInfo, bool) {
+	snap := fset.Snapshot()
+	defer snap.Release()
+
+	var best protocol.FileInfo
+	found := false
+	for _, dev := range cfg.Devices {
+		if dev.DeviceID == m.id {
+			continue
+		}
+		m.pmut.RLock()
+		_, connected := m.conn[dev.DeviceID]
+		m.pmut.RUnlock()
+		if !connected {
+			continue
+		}
+		f, ok := snap.Get(dev.DeviceID, name)
+		if !ok || f.IsDeleted() || f.IsInvalid() || f.Type != protocol.FileInfoTypeFile {
+			continue
+		}
+		if !found || f.ModTime().After(best.ModTime()) {
+			best, found = f, true
+		}
+	}
+	return best, found
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/syncthing/syncthing/lib/osutil"
 	"github.com/syncthing/syncthing/lib/protocol"
 	"github.com/syncthing/syncthing/lib/scanner"
+	"github.com/syncthing/syncthing/lib/snapshot"
 	"github.com/syncthing/syncthing/lib/stats"
 	"github.com/syncthing/syncthing/lib/sync"
 	"github.com/syncthing/syncthing/lib/watchaggregator"
@@ -53,17 +54,28 @@ type folder struct {
 	scanNow             chan rescanRequest
 	scanDelay           chan time.Duration
 	initialScanFinished chan struct{}
+	ignoreExpiry        <-chan time.Time // fires when the next ignore pattern expires, nil if none do
 	scanErrors          []FileError
 	scanErrorsMut       sync.Mutex
 
 	pullScheduled chan struct{}
 
+	// In maintenance, local changes aren't scanned and nothing is pulled
+	// until it ends with one full scan. Only used by the serve goroutine.
+	maintenance    bool
+	maintenanceReq chan maintenanceRequest
+
 	watchCancel      context.CancelFunc
 	watchChan        chan []string
 	restartWatchChan chan struct{}
 	watchErr         error
 	watchMut         sync.Mutex
 
+	operation    *folderOperation // the running or last one
+	operationMut sync.Mutex
+
+	pullBudget *pullBudget
+
 	puller puller
 }
 
@@ -72,8 +84,14 @@ type rescanRequest struct {
 	err     chan error
 }
 
+type maintenanceRequest struct {
+	enabled bool
+	done    chan struct{}
+}
+
 type puller interface {
-	pull() bool // true when successfull and should not be retried
+	pull() bool                   // true when successfull and should not be retried
+	nextRetry() (time.Time, bool) // when items that failed are due to be retried
 }
 
 func newFolder(model *model, fset *db.FileSet, ignores *ignore.Matcher, cfg config.FolderConfiguration, evLogger events.Logger, ioLimiter *byteSemaphore) folder {
@@ -97,9 +115,15 @@ func newFolder(model *model, fset *db.FileSet, ignores *ignore.Matcher, cfg conf
 
 		pullScheduled: make(chan struct{}, 1), // This needs to be 1-buffered so that we queue a pull if we're busy when it comes.
 
+		maintenanceReq: make(chan maintenanceRequest),
+
 		watchCancel:      func() {},
 		restartWatchChan: make(chan struct{}, 1),
 		watchMut:         sync.NewMutex(),
+
+		operationMut: sync.NewMutex(),
+
+		pullBudget: newPullBudget(model.db, cfg.ID, int64(cfg.PullBudgetMiB)<<20),
 	}
 }
 
@@ -137,6 +161,13 @@ func (f *folder) serve(ctx context.Context) {
 		}
 		// Pulling failed, try again later.
 		delay := pause + time.Since(startTime)
+		if next, ok := f.puller.nextRetry(); ok {
+			// No point in trying before any of the failed items are
+			// due for another go.
+			if until := time.Until(next); until > delay {
+				delay = until
+			}
+		}
 		l.Infof("Folder %v isn't making sync progress - retrying in %v.", f.Description(), delay)
 		pullFailTimer.Reset(delay)
 		if pause < 60*f.basePause() {
@@ -176,11 +207,31 @@ func (f *folder) serve(ctx context.Context) {
 			l.Debugln(f, "Scanning due to request")
 			req.err <- f.scanSubdirs(req.subdirs)
 
+		case <-f.ignoreExpiry:
+			l.Debugln(f, "Scanning due to expired ignore patterns")
+			f.ignoreExpiry = nil
+			f.scanSubdirs(nil)
+
 		case next := <-f.scanDelay:
 			l.Debugln(f, "Delaying scan")
 			f.scanTimer.Reset(next)
 
+		case req := <-f.maintenanceReq:
+			ended := f.setMaintenance(req.enabled)
+			close(req.done)
+			if ended {
+				// One scan for all that changed in the meantime, and the
+				// pull we put off.
+				f.scanTimerFired()
+				f.SchedulePull()
+			}
+
 		case fsEvents := <-f.watchChan:
+			if !f.InSyncWindow(time.Now()) {
+				// There will be a full scan when the window opens.
+				l.Debugln(f, "Not scanning due to watcher outside of sync window")
+				continue
+			}
 			l.Debugln(f, "Scan due to watcher")
 			f.scanSubdirs(fsEvents)
 
@@ -197,6 +248,44 @@ func (f *folder) Override() {}
 
 func (f *folder) Revert() {}
 
+func (f *folder) ResetBackoff([]string) {}
+
+// startOperation starts tracking an operation on the folder, unless
+// another one is running.
+func (f *folder) startOperation(name string, totalItems int, totalBytes int64) (*folderOperation, error) {
+	f.operationMut.Lock()
+	defer f.operationMut.Unlock()
+	if f.operation != nil && f.operation.running() {
+		return nil, errOperationRunning
+	}
+	f.operation = newFolderOperation(f.ID, name, totalItems, totalBytes, f.evLogger)
+	return f.operation, nil
+}
+
+// Operation returns the report of the running or last operation.
+func (f *folder) Operation() (OperationReport, error) {
+	f.operationMut.Lock()
+	op := f.operation
+	f.operationMut.Unlock()
+	if op == nil {
+		return OperationReport{}, errNoOperation
+	}
+	return op.Report(), nil
+}
+
+// CancelOperation cancels the running operation, which stops at the next
+// item.
+func (f *folder) CancelOperation() error {
+	f.operationMut.Lock()
+	op := f.operation
+	f.operationMut.Unlock()
+	if op == nil || !op.running() {
+		return errNoOperation
+	}
+	op.cancel()
+	return nil
+}
+
 func (f *folder) DelayScan(next time.Duration) {
 	f.Delay(next)
 }
@@ -207,6 +296,35 @@ func (f *folder) ignoresUpdated() {
 	}
 }
 
+// scheduleIgnoreExpiry arranges for a scan when the next of the ignore
+// patterns expires, so that what it ignored is synced again.
+func (f *folder) scheduleIgnoreExpiry() {
+	f.ignoreExpiry = nil
+	if next := f.ignores.NextExpiry(); !next.IsZero() {
+		f.ignoreExpiry = time.After(time.Until(next))
+	}
+}
+
+// syncIgnores reconciles .stignore with its synced copy if the folder
+// shares its ignore patterns, returning true if .stignore was replaced by
+// the patterns of another device.
+func (f *folder) syncIgnores() bool {
+	if !f.SyncIgnores {
+		return false
+	}
+	f.model.ignoresMut.Lock()
+	applied, err := syncIgnoreFiles(f.Filesystem())
+	f.model.ignoresMut.Unlock()
+	if err != nil {
+		l.Infof("Folder %v: syncing ignore patterns: %v", f.Description(), err)
+		return false
+	}
+	if applied {
+		l.Infof("Folder %v: applied the ignore patterns synced from another device", f.Description())
+	}
+	return applied
+}
+
 func (f *folder) SchedulePull() {
 	select {
 	case f.pullScheduled <- struct{}{}:
@@ -237,6 +355,52 @@ func (f *folder) Scan(subdirs []string) error {
 	}
 }
 
+// SetMaintenance starts or ends maintenance of the folder, such as for a
+// large reorganization, when the local changes are to be picked up all at
+// once at the end rather than one by one along the way.
+func (f *folder) SetMaintenance(enabled bool) error {
+	req := maintenanceRequest{
+		enabled: enabled,
+		done:    make(chan struct{}),
+	}
+
+	select {
+	case f.maintenanceReq <- req:
+		<-req.done
+		return nil
+	case <-f.ctx.Done():
+		return f.ctx.Err()
+	}
+}
+
+// ResumePull renews the pull budget of the folder, resuming a pull that
+// stopped at its checkpoint.
+func (f *folder) ResumePull() error {
+	if err := f.pullBudget.renew(); err != nil {
+		return err
+	}
+	l.Infof("Resuming pull of folder %v", f.Description())
+	f.SchedulePull()
+	return nil
+}
+
+// setMaintenance returns whether that ended maintenance.
+func (f *folder) setMaintenance(enabled bool) bool {
+	if enabled == f.maintenance {
+		return false
+	}
+	f.maintenance = enabled
+	if enabled {
+		l.Infof("Folder %v is in maintenance, not scanning or pulling", f.Description())
+		f.setState(FolderMaintenance)
+		return false
+	}
+
+	l.Infof("Folder %v is out of maintenance, rescanning", f.Description())
+	f.setState(FolderIdle)
+	return true
+}
+
 func (f *folder) Reschedule() {
 	if f.scanInterval == 0 {
 		return
@@ -279,6 +443,12 @@ func (f *folder) getHealthError() error {
 }
 
 func (f *folder) pull() bool {
+	if f.maintenance {
+		// There will be a pull when maintenance ends.
+		l.Debugln(f, "Not pulling in maintenance")
+		return true
+	}
+
 	select {
 	case <-f.initialScanFinished:
 	default:
@@ -286,6 +456,18 @@ func (f *folder) pull() bool {
 		return true
 	}
 
+	if !f.InSyncWindow(time.Now()) {
+		// The sync window scheduler will schedule a pull when it opens.
+		l.Debugln(f, "Not pulling outside of sync window")
+		return true
+	}
+
+	if f.pullBudget.isStopped() {
+		// Resuming the pull schedules one.
+		l.Debugln(f, "Not pulling at the pull budget checkpoint")
+		return true
+	}
+
 	// If there is nothing to do, don't even enter sync-waiting state.
 	abort := true
 	snap := f.fset.Snapshot()
@@ -301,13 +483,42 @@ func (f *folder) pull() bool {
 	f.setState(FolderSyncWaiting)
 	defer f.setState(FolderIdle)
 
-	f.ioLimiter.take(1)
+	// Waiting for a turn to pull before taking one for I/O, so as not to
+	// hold up scans while waiting.
+	f.model.folderPullLimiter.takeWithPriority(1, f.Priority)
+	defer f.model.folderPullLimiter.give(1)
+	f.ioLimiter.takeWithPriority(1, f.Priority)
 	defer f.ioLimiter.give(1)
 
 	return f.puller.pull()
 }
 
+// takeScanSnapshot snapshots the folder to be scanned, as configured.
+func (f *folder) takeScanSnapshot() (*snapshot.Snapshot, error) {
+	if f.FilesystemType != fs.FilesystemTypeBasic || f.OverlayPath != "" {
+		return nil, errors.New("only folders on the basic filesystem without an overlay can be snapshotted")
+	}
+	var method snapshot.Method
+	switch f.ScanSnapshot {
+	case config.ScanSnapshotZFS:
+		method = snapshot.ZFS
+	case config.ScanSnapshotBtrfs:
+		method = snapshot.Btrfs
+	case config.ScanSnapshotCommand:
+		method = snapshot.NewCommand(f.ScanSnapshotCommand, f.ScanSnapshotRelease)
+	default:
+		return nil, fmt.Errorf("unknown scan snapshot %v", f.ScanSnapshot)
+	}
+	return method.Take(f.ctx, f.ID, f.Filesystem().URI())
+}
+
 func (f *folder) scanSubdirs(subDirs []string) error {
+	if f.maintenance {
+		// There will be a full scan when maintenance ends.
+		l.Debugln(f, "Not scanning in maintenance")
+		return errFolderInMaintenance
+	}
+
 	if err := f.getHealthError(); err != nil {
 		// If there is a health error we set it as the folder error. We do not
 		// clear the folder error if there is no health error, as there might be
@@ -318,11 +529,13 @@ func (f *folder) scanSubdirs(subDirs []string) error {
 	}
 
 	oldHash := f.ignores.Hash()
+	f.syncIgnores()
 	if err := f.ignores.Load(".stignore"); err != nil && !fs.IsNotExist(err) {
 		err = errors.Wrap(err, "loading ignores")
 		f.setError(err)
 		return err
 	}
+	f.scheduleIgnoreExpiry()
 
 	// Check on the way out if the ignore patterns changed as part of scanning
 	// this folder. If they did we should schedule a pull of the folder so that
@@ -335,12 +548,20 @@ func (f *folder) scanSubdirs(subDirs []string) error {
 		}
 	}()
 
+	// Keep what the scan learned about the ignored files for the next
+	// start.
+	defer func() {
+		if err := f.ignores.SaveCache(); err != nil {
+			l.Debugln("Saving ignore cache of folder", f.Description(), err)
+		}
+	}()
+
 	// We've passed all health checks so now mark ourselves healthy and queued
 	// for scanning.
 	f.setError(nil)
 	f.setState(FolderScanWaiting)
 
-	f.ioLimiter.take(1)
+	f.ioLimiter.takeWithPriority(1, f.Priority)
 	defer f.ioLimiter.give(1)
 
 	for i := range subDirs {
@@ -370,23 +591,42 @@ func (f *folder) scanSubdirs(subDirs []string) error {
 	})
 
 	f.setState(FolderScanning)
+	scanStart := time.Now()
+	partial := len(subDirs) > 0
 
 	mtimefs := f.fset.MtimeFS()
+	scanfs := mtimefs
+	if f.ScanSnapshot != config.ScanSnapshotNone {
+		if scanSnap, err := f.takeScanSnapshot(); err != nil {
+			l.Warnf("Scanning folder %v without a snapshot: %v", f.Description(), err)
+		} else {
+			defer func() {
+				if err := scanSnap.Release(); err != nil {
+					l.Warnf("Releasing scan snapshot of folder %v: %v", f.Description(), err)
+				}
+			}()
+			scanfs = f.fset.MtimeFSFor(fs.NewFilesystem(fs.FilesystemTypeBasic, scanSnap.Path))
+		}
+	}
 	fchan := scanner.Walk(f.ctx, scanner.Config{
 		Folder:                f.ID,
 		Subs:                  subDirs,
 		Matcher:               f.ignores,
 		TempLifetime:          time.Duration(f.model.cfg.Options().KeepTemporariesH) * time.Hour,
+		KeepTemporaries:       f.pullBudget.isStopped(), // they are the checkpoint to resume from
 		CurrentFiler:          cFiler{snap},
-		Filesystem:            mtimefs,
+		Filesystem:            scanfs,
 		IgnorePerms:           f.IgnorePerms,
 		AutoNormalize:         f.AutoNormalize,
+		UnicodeNormalization:  f.UnicodeNormalization,
 		Hashers:               f.model.numHashers(f.ID),
 		ShortID:               f.shortID,
 		ProgressTickIntervalS: f.ScanProgressIntervalS,
 		LocalFlags:            f.localFlags,
 		ModTimeWindow:         f.ModTimeWindow(),
 		EventLogger:           f.evLogger,
+		ScanXattrs:            f.SyncXattrs,
+		XattrFilter:           f.XattrFilter,
 	})
 
 	batchFn := func(fs []protocol.FileInfo) error {
@@ -400,12 +640,13 @@ func (f *folder) scanSubdirs(subDirs []string) error {
 	// Resolve items which are identical with the global state.
 	if f.localFlags&protocol.FlagLocalReceiveOnly != 0 {
 		oldBatchFn := batchFn // can't reference batchFn directly (recursion)
+		modTimeWindow := f.remoteModTimeWindow()
 		batchFn = func(fs []protocol.FileInfo) error {
 			for i := range fs {
 				switch gf, ok := snap.GetGlobal(fs[i].Name); {
 				case !ok:
 					continue
-				case gf.IsEquivalentOptional(fs[i], f.ModTimeWindow(), false, false, protocol.FlagLocalReceiveOnly):
+				case gf.IsEquivalentOptional(fs[i], modTimeWindow, false, false, protocol.FlagLocalReceiveOnly):
 					// What we have locally is equivalent to the global file.
 					fs[i].Version = fs[i].Version.Merge(gf.Version)
 					fallthrough
@@ -432,6 +673,8 @@ func (f *folder) scanSubdirs(subDirs []string) error {
 	}()
 
 	f.clearScanErrors(subDirs)
+	hashedFiles := 0
+	var hashedBytes int64
 	for res := range fchan {
 		if res.Err != nil {
 			f.newScanError(res.Path, res.Err)
@@ -443,6 +686,10 @@ func (f *folder) scanSubdirs(subDirs []string) error {
 
 		batch.append(res.File)
 		changes++
+		if res.File.Type == protocol.FileInfoTypeFile {
+			hashedFiles++
+			hashedBytes += res.File.Size
+		}
 	}
 
 	if err := batch.flush(); err != nil {
@@ -496,7 +743,7 @@ func (f *folder) scanSubdirs(subDirs []string) error {
 				ignoredParent = ""
 			}
 
-			switch ignored := f.ignores.Match(file.Name).IsIgnored(); {
+			switch ignored := f.ignores.MatchStat(file.Name, ignoreStat(file)).IsIgnored(); {
 			case !file.IsIgnored() && ignored:
 				// File was not ignored at last pass but has been ignored.
 				if file.IsDirectory() {
@@ -585,12 +832,30 @@ func (f *folder) scanSubdirs(subDirs []string) error {
 		return err
 	}
 
-	f.ScanCompleted()
+	if err := f.ScanCompleted(stats.NewScanRecord(scanStart, partial, hashedFiles, hashedBytes)); err != nil {
+		l.Debugln(f, "recording scan statistics:", err)
+	}
 	f.setState(FolderIdle)
 	return nil
 }
 
 func (f *folder) scanTimerFired() {
+	if f.maintenance {
+		f.Reschedule()
+		return
+	}
+
+	select {
+	case <-f.initialScanFinished:
+		if !f.InSyncWindow(time.Now()) {
+			l.Debugln(f, "Not scanning outside of sync window")
+			f.Reschedule()
+			return
+		}
+	default:
+		// The initial scan happens regardless, to get the folder going.
+	}
+
 	err := f.scanSubdirs(nil)
 
 	select {
@@ -664,7 +929,7 @@ func (f *folder) monitorWatch(ctx context.Context) {
 	for {
 		select {
 		case <-failTimer.C:
-			eventChan, errChan, err = f.Filesystem().Watch(".", f.ignores, ctx, f.IgnorePerms)
+			eventChan, errChan, err = f.model.folderFilesystem(f.FolderConfiguration).Watch(".", f.ignores, ctx, f.IgnorePerms)
 			// We do this at most once per minute which is the
 			// default rescan time without watcher.
 			f.scanOnWatchErr()
@@ -775,6 +1040,18 @@ func (f *folder) basePause() time.Duration {
 	return time.Duration(f.PullerPauseS) * time.Second
 }
 
+// remoteModTimeWindow returns the window within which modification times
+// are considered equal when comparing our files to those of other devices.
+// Their modification times are only as precise as their filesystems, which
+// they announce.
+func (f *folder) remoteModTimeWindow() time.Duration {
+	window := f.ModTimeWindow()
+	if remote := f.model.remoteModTimeWindow(f.ID); remote > window {
+		window = remote
+	}
+	return window
+}
+
 func (f *folder) String() string {
 	return fmt.Sprintf("%s/%s@%p", f.Type, f.folderID, f)
 }
@@ -825,12 +1102,14 @@ func (f *folder) ForceRescan(file protocol.FileInfo) error {
 
 func (f *folder) updateLocalsFromScanning(fs []protocol.FileInfo) {
 	f.updateLocals(fs)
+	f.model.activity.record(f.ID, fs, false, time.Now())
 
 	f.emitDiskChangeEvents(fs, events.LocalChangeDetected)
 }
 
 func (f *folder) updateLocalsFromPulling(fs []protocol.FileInfo) {
 	f.updateLocals(fs)
+	f.model.activity.record(f.ID, fs, true, time.Now())
 
 	f.emitDiskChangeEvents(fs, events.RemoteChangeDetected)
 }
@@ -0,0 +1,38 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/ignore"
+	"github.com/syncthing/syncthing/lib/versioner"
+)
+
+func init() {
+	folderFactories[config.FolderTypeBackupTarget] = newBackupTargetFolder
+}
+
+// A backupTargetFolder receives the changes from the cluster like a
+// receiveOnlyFolder, and like it never sends its own, but local changes
+// are there to stay: there is no reverting them, and they're not reported
+// as something to act on. It's meant for keeping a copy of data that's
+// added to or changed elsewhere, possibly with versioning or
+// ignoreDelete, without the copy ever having a say in the cluster.
+type backupTargetFolder struct {
+	*receiveOnlyFolder
+}
+
+func newBackupTargetFolder(model *model, fset *db.FileSet, ignores *ignore.Matcher, cfg config.FolderConfiguration, ver versioner.Versioner, fs fs.Filesystem, evLogger events.Logger, ioLimiter *byteSemaphore) service {
+	ro := newReceiveOnlyFolder(model, fset, ignores, cfg, ver, fs, evLogger, ioLimiter).(*receiveOnlyFolder)
+	return &backupTargetFolder{ro}
+}
+
+// Revert does nothing, as local changes are kept.
+func (f *backupTargetFolder) Revert() {}
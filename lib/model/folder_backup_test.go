@@ -0,0 +1,61 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/config"
+)
+
+func TestBackupTargetKeepsLocalChanges(t *testing.T) {
+	w := createTmpWrapper(defaultCfg.Copy())
+	defer os.Remove(w.ConfigPath())
+	fcfg := testFolderConfigTmp()
+	fcfg.ID = "bt"
+	fcfg.Type = config.FolderTypeBackupTarget
+	waiter, _ := w.SetFolder(fcfg)
+	waiter.Wait()
+	m := setupModel(w)
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	name := filepath.Join(fcfg.Filesystem().URI(), "local")
+	must(t, ioutil.WriteFile(name, []byte("hello\n"), 0644))
+	must(t, m.ScanFolder("bt"))
+
+	size := receiveOnlyChangedSize(t, m, "bt")
+	if size.Files != 1 {
+		t.Fatalf("Expected the local file to be a local change: %+v", size)
+	}
+
+	// Reverting does nothing
+	m.Revert("bt")
+	if _, err := os.Stat(name); err != nil {
+		t.Error("Local file was removed:", err)
+	}
+	if size := receiveOnlyChangedSize(t, m, "bt"); size.Files != 1 {
+		t.Errorf("Expected the local change to stay: %+v", size)
+	}
+
+	// Nor are local changes reported in the summary
+	fss := NewFolderSummaryService(w, m, myID, m.evLogger).(*folderSummaryService)
+	summary, err := fss.Summary("bt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := summary["receiveOnlyTotalItems"]; ok {
+		t.Error("Unexpected local changes in summary")
+	}
+
+	// And the file isn't announced
+	if fi, ok := m.CurrentFolderFile("bt", "local"); !ok || !fi.IsInvalid() {
+		t.Error("Expected the local file to be invalid to others")
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"path/filepath"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// The number of names in the folder root announced to other devices.
+const rootSampleSize = 16
+
+// rootSample returns the first few names in the root of the folder, only
+// taking those for which visible returns true unless it's nil.
+func rootSample(fset *db.FileSet, visible func(name string) bool) []string {
+	snap := fset.Snapshot()
+	defer snap.Release()
+
+	var sample []string
+	snap.WithGlobalChildrenTruncated("", "", func(fi db.FileIntf) bool {
+		if fi.IsInvalid() || fi.IsDeleted() {
+			return true
+		}
+		if visible != nil && !visible(fi.FileName()) {
+			return true
+		}
+		sample = append(sample, filepath.ToSlash(fi.FileName()))
+		return len(sample) < rootSampleSize
+	})
+	return sample
+}
+
+// compareMarkerIDs compares our marker ID for a folder with the one
+// another device announced for it. The folders are mismatched when they
+// seem to be unrelated despite having the same folder ID: their markers
+// were created independently and there are no names in common in the
+// folder roots. A missing ID on either side is unknown rather than a
+// mismatch. Our ID is missing when the marker predates IDs, meaning we were
+// likely already sharing the folder, and a folder that is still empty is
+// about to be synced from the other side; in both cases we adopt their ID.
+func compareMarkerIDs(ourID string, fset *db.FileSet, theirs protocol.Folder) (mismatch, adopt bool) {
+	if theirs.MarkerID == "" || ourID == theirs.MarkerID {
+		return false, false
+	}
+	if ourID == "" {
+		return false, true
+	}
+
+	ourSample := rootSample(fset, nil)
+	if len(ourSample) == 0 {
+		return false, true
+	}
+	if len(theirs.RootSample) == 0 {
+		return false, false
+	}
+
+	names := make(map[string]struct{}, len(ourSample))
+	for _, name := range ourSample {
+		names[name] = struct{}{}
+	}
+	for _, name := range theirs.RootSample {
+		if _, ok := names[name]; ok {
+			return false, false
+		}
+	}
+	return true, false
+}
+
+// adoptMarkerID stores the marker ID of another device as ours for the
+// folder. Folders with a custom marker don't have an ID.
+func (m *model) adoptMarkerID(folder, id string) {
+	m.fmut.RLock()
+	cfg, ok := m.folderCfgs[folder]
+	m.fmut.RUnlock()
+	if !ok || cfg.MarkerName != config.DefaultMarkerName {
+		return
+	}
+	if err := cfg.SetMarkerID(id); err != nil {
+		l.Debugf("Adopting marker ID for folder %s: %v", cfg.Description(), err)
+		return
+	}
+	m.fmut.Lock()
+	if _, ok := m.folderCfgs[folder]; ok {
+		m.folderMarkerIDs[folder] = id
+	}
+	m.fmut.Unlock()
+}
+
+func (m *model) isMismatched(device protocol.DeviceID, folder string) bool {
+	m.pmut.RLock()
+	defer m.pmut.RUnlock()
+	for _, mismatched := range m.mismatchedFolders[device] {
+		if mismatched == folder {
+			return true
+		}
+	}
+	return false
+}
@@ -46,6 +46,10 @@ implemted in this file):
   the globally latest. As this is a user-initiated operation we do not cause
   conflict copies when reverting.
 
+- The files thrown away by a revert are archived by the versioner of the
+  folder, or by a trashcan versioner with the default settings if it has
+  none, so that the local changes can be recovered.
+
 - When pulling normally (i.e., not in the revert case) with local changes,
   normal conflict resolution will apply. Conflict copies will be created,
   but not propagated outwards (because receive only, right).
@@ -60,10 +64,27 @@ type receiveOnlyFolder struct {
 func newReceiveOnlyFolder(model *model, fset *db.FileSet, ignores *ignore.Matcher, cfg config.FolderConfiguration, ver versioner.Versioner, fs fs.Filesystem, evLogger events.Logger, ioLimiter *byteSemaphore) service {
 	sr := newSendReceiveFolder(model, fset, ignores, cfg, ver, fs, evLogger, ioLimiter).(*sendReceiveFolder)
 	sr.localFlags = protocol.FlagLocalReceiveOnly // gets propagated to the scanner, and set on locally changed files
+	if ver == nil {
+		var err error
+		sr.revertVersioner, err = versioner.New(cfg.ID, fs, config.VersioningConfiguration{Type: "trashcan"})
+		if err != nil {
+			l.Warnln("Creating revert versioner:", err)
+		}
+	}
 	return &receiveOnlyFolder{sr}
 }
 
 func (f *receiveOnlyFolder) Revert() {
+	snap := f.fset.Snapshot()
+	defer snap.Release()
+	changed := snap.ReceiveOnlyChangedSize()
+	op, err := f.startOperation("revert", int(changed.TotalItems()), changed.Bytes)
+	if err != nil {
+		l.Infof("Revert of folder %s: %v", f.Description(), err)
+		return
+	}
+	defer op.finish()
+
 	f.setState(FolderScanning)
 	defer f.setState(FolderIdle)
 
@@ -79,9 +100,10 @@ func (f *receiveOnlyFolder) Revert() {
 
 	batch := make([]protocol.FileInfo, 0, maxBatchSizeFiles)
 	batchSizeBytes := 0
-	snap := f.fset.Snapshot()
-	defer snap.Release()
 	snap.WithHave(protocol.LocalDeviceID, func(intf db.FileIntf) bool {
+		if op.cancelled() {
+			return false
+		}
 		fi := intf.(protocol.FileInfo)
 		if !fi.IsReceiveOnlyChanged() {
 			// We're only interested in files that have changed locally in
@@ -89,6 +111,13 @@ func (f *receiveOnlyFolder) Revert() {
 			return true
 		}
 
+		if !fi.IsDirectory() && !fi.IsSymlink() && !fi.IsDeleted() {
+			// To be archived when removed now or replaced by the pull.
+			f.revertedMut.Lock()
+			f.reverted[fi.Name] = struct{}{}
+			f.revertedMut.Unlock()
+		}
+
 		if len(fi.Version.Counters) == 1 && fi.Version.Counters[0].ID == f.shortID {
 			// We are the only device mentioned in the version vector so the
 			// file must originate here. A revert then means to delete it.
@@ -98,11 +127,16 @@ func (f *receiveOnlyFolder) Revert() {
 			handled, err := delQueue.handle(fi, snap)
 			if err != nil {
 				l.Infof("Revert: deleting %s: %v\n", fi.Name, err)
+				op.processed(fi.Name, "", fi.Size)
 				return true // continue
 			}
 			if !handled {
+				if !fi.IsDirectory() {
+					op.processed(fi.Name, "", fi.Size)
+				}
 				return true // continue
 			}
+			op.processed(fi.Name, operationActionDeleted, fi.Size)
 
 			fi = protocol.FileInfo{
 				Name:       fi.Name,
@@ -121,6 +155,7 @@ func (f *receiveOnlyFolder) Revert() {
 			// changes.
 			fi.Version = protocol.Vector{}
 			fi.LocalFlags &^= protocol.FlagLocalReceiveOnly
+			op.processed(fi.Name, operationActionReset, fi.Size)
 		}
 
 		batch = append(batch, fi)
@@ -139,13 +174,17 @@ func (f *receiveOnlyFolder) Revert() {
 	batch = batch[:0]
 	batchSizeBytes = 0
 
-	// Handle any queued directories
-	deleted, err := delQueue.flush(snap)
-	if err != nil {
-		l.Infoln("Revert:", err)
+	// Handle any queued directories, unless cancelled
+	var deleted []string
+	if !op.cancelled() {
+		deleted, err = delQueue.flush(snap)
+		if err != nil {
+			l.Infoln("Revert:", err)
+		}
 	}
 	now := time.Now()
 	for _, dir := range deleted {
+		op.processed(dir, operationActionDeleted, 0)
 		batch = append(batch, protocol.FileInfo{
 			Name:       dir,
 			Type:       protocol.FileInfoTypeDirectory,
@@ -91,6 +91,26 @@ func TestRecvOnlyRevertDeletes(t *testing.T) {
 		}
 	}
 
+	// The revert should be reported as done
+	report, err := m.FolderOperation("ro")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Operation != "revert" || report.Finished.IsZero() || report.Cancelled {
+		t.Errorf("Unexpected report %+v", report)
+	}
+	if report.Changes[operationActionDeleted] != 2 {
+		t.Errorf("Expected the unknown file and directory deleted, got %v", report.Changes)
+	}
+	if err := m.CancelFolderOperation("ro"); err != errNoOperation {
+		t.Errorf("Expected errNoOperation cancelling after the revert, got %v", err)
+	}
+
+	// The removed file should be in the trashcan, as there is no versioner
+	if _, err := ffs.Stat(".stversions/unknownDir/unknownFile"); err != nil {
+		t.Error("Reverted file wasn't archived:", err)
+	}
+
 	// We should now have one file and directory again.
 
 	size = globalSize(t, m, "ro")
@@ -329,6 +349,7 @@ func setupROFolder() (*model, *sendOnlyFolder) {
 	f := &sendOnlyFolder{
 		folder: folder{
 			stateTracker:        newStateTracker(fcfg.ID, m.evLogger),
+			model:               m,
 			fset:                m.folderFiles[fcfg.ID],
 			FolderConfiguration: fcfg,
 		},
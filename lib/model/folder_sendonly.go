@@ -7,6 +7,8 @@
 package model
 
 import (
+	"time"
+
 	"github.com/syncthing/syncthing/lib/config"
 	"github.com/syncthing/syncthing/lib/db"
 	"github.com/syncthing/syncthing/lib/events"
@@ -38,6 +40,11 @@ func (f *sendOnlyFolder) PullErrors() []FileError {
 	return nil
 }
 
+// nextRetry returns false, as there are no items that fail to pull.
+func (f *sendOnlyFolder) nextRetry() (time.Time, bool) {
+	return time.Time{}, false
+}
+
 // pull checks need for files that only differ by metadata (no changes on disk)
 func (f *sendOnlyFolder) pull() bool {
 	select {
@@ -49,6 +56,7 @@ func (f *sendOnlyFolder) pull() bool {
 
 	batch := make([]protocol.FileInfo, 0, maxBatchSizeFiles)
 	batchSizeBytes := 0
+	modTimeWindow := f.remoteModTimeWindow()
 
 	snap := f.fset.Snapshot()
 	defer snap.Release()
@@ -59,7 +67,7 @@ func (f *sendOnlyFolder) pull() bool {
 			batchSizeBytes = 0
 		}
 
-		if f.ignores.ShouldIgnore(intf.FileName()) {
+		if f.ignores.ShouldIgnoreStat(intf.FileName(), ignoreStat(intf)) {
 			file := intf.(protocol.FileInfo)
 			file.SetIgnored(f.shortID)
 			batch = append(batch, file)
@@ -77,7 +85,7 @@ func (f *sendOnlyFolder) pull() bool {
 		}
 
 		file := intf.(protocol.FileInfo)
-		if !file.IsEquivalentOptional(curFile, f.ModTimeWindow(), f.IgnorePerms, false, 0) {
+		if !file.IsEquivalentOptional(curFile, modTimeWindow, f.IgnorePerms, false, 0) {
 			return true
 		}
 
@@ -97,12 +105,23 @@ func (f *sendOnlyFolder) pull() bool {
 }
 
 func (f *sendOnlyFolder) Override() {
+	snap := f.fset.Snapshot()
+	defer snap.Release()
+	needed := snap.NeedSize()
+	op, err := f.startOperation("override", int(needed.TotalItems()), needed.Bytes)
+	if err != nil {
+		l.Infof("Override of folder %s: %v", f.Description(), err)
+		return
+	}
+	defer op.finish()
+
 	f.setState(FolderScanning)
 	batch := make([]protocol.FileInfo, 0, maxBatchSizeFiles)
 	batchSizeBytes := 0
-	snap := f.fset.Snapshot()
-	defer snap.Release()
 	snap.WithNeed(protocol.LocalDeviceID, func(fi db.FileIntf) bool {
+		if op.cancelled() {
+			return false
+		}
 		need := fi.(protocol.FileInfo)
 		if len(batch) == maxBatchSizeFiles || batchSizeBytes > maxBatchSizeBytes {
 			f.updateLocalsFromScanning(batch)
@@ -114,16 +133,19 @@ func (f *sendOnlyFolder) Override() {
 		// Don't override files that are in a bad state (ignored,
 		// unsupported, must rescan, ...).
 		if ok && have.IsInvalid() {
+			op.processed(need.Name, "", need.Size)
 			return true
 		}
 		if !ok || have.Name != need.Name {
 			// We are missing the file
+			op.processed(need.Name, operationActionDeleted, need.Size)
 			need.Deleted = true
 			need.Blocks = nil
 			need.Version = need.Version.Update(f.shortID)
 			need.Size = 0
 		} else {
 			// We have the file, replace with our version
+			op.processed(need.Name, operationActionOverridden, need.Size)
 			have.Version = have.Version.Merge(need.Version).Update(f.shortID)
 			need = have
 		}
@@ -55,22 +55,24 @@ type copyBlocksState struct {
 	*sharedPullerState
 	blocks []protocol.BlockInfo
 	have   int
+	origin string // the file to look for shifted blocks in
 }
 
 // Which filemode bits to preserve
 const retainBits = fs.ModeSetgid | fs.ModeSetuid | fs.ModeSticky
 
 var (
-	activity                  = newDeviceActivity()
-	errNoDevice               = errors.New("peers who had this file went away, or the file has changed while syncing. will retry later")
-	errDirHasToBeScanned      = errors.New("directory contains unexpected files, scheduling scan")
-	errDirHasIgnored          = errors.New("directory contains ignored files (see ignore documentation for (?d) prefix)")
-	errDirNotEmpty            = errors.New("directory is not empty; files within are probably ignored on connected devices only")
-	errNotAvailable           = errors.New("no connected device has the required version of this file")
-	errModified               = errors.New("file modified but not rescanned; will try again later")
-	errUnexpectedDirOnFileDel = errors.New("encountered directory when trying to remove file/symlink")
-	errIncompatibleSymlink    = errors.New("incompatible symlink entry; rescan with newer Syncthing on source")
-	contextRemovingOldItem    = "removing item to be replaced"
+	activity                    = newDeviceActivity()
+	errNoDevice                 = errors.New("peers who had this file went away, or the file has changed while syncing. will retry later")
+	errDirHasToBeScanned        = errors.New("directory contains unexpected files, scheduling scan")
+	errDirHasIgnored            = errors.New("directory contains ignored files (see ignore documentation for (?d) prefix)")
+	errDirNotEmpty              = errors.New("directory is not empty; files within are probably ignored on connected devices only")
+	errNotAvailable             = errors.New("no connected device has the required version of this file")
+	errModified                 = errors.New("file modified but not rescanned; will try again later")
+	errUnexpectedDirOnFileDel   = errors.New("encountered directory when trying to remove file/symlink")
+	errIncompatibleSymlink      = errors.New("incompatible symlink entry; rescan with newer Syncthing on source")
+	errCaseConflictTargetExists = errors.New("the name to rename to for the case conflict exists already")
+	contextRemovingOldItem      = "removing item to be replaced"
 )
 
 const (
@@ -96,6 +98,13 @@ type dbUpdateJob struct {
 	jobType int
 }
 
+// atomicBatch holds the changes of a puller iteration that are applied
+// together at its end.
+type atomicBatch struct {
+	items  []protocol.FileInfo  // directories, symlinks and metadata updates
+	pulled []*sharedPullerState // files waiting in their temp files
+}
+
 type sendReceiveFolder struct {
 	folder
 
@@ -107,6 +116,18 @@ type sendReceiveFolder struct {
 	pullErrors    map[string]string // errors for most recent/current iteration
 	oldPullErrors map[string]string // errors from previous iterations for log filtering only
 	pullErrorsMut sync.Mutex
+	failures      int                 // in the current iteration, counting items waiting for a retry only when applying atomically
+	caseConflicts map[string]struct{} // the pull errors that are case conflicts
+
+	backoff   *pullBackoff
+	caseCheck *caseChecker // for the current iteration, nil if the filesystem is case sensitive
+	atomic    *atomicBatch // for the current iteration, nil unless applying changes atomically
+
+	// In receive only mode without a versioner, the files thrown away by
+	// a revert are still archived, by the revert versioner.
+	revertVersioner versioner.Versioner
+	reverted        map[string]struct{}
+	revertedMut     sync.Mutex
 }
 
 func newSendReceiveFolder(model *model, fset *db.FileSet, ignores *ignore.Matcher, cfg config.FolderConfiguration, ver versioner.Versioner, fs fs.Filesystem, evLogger events.Logger, ioLimiter *byteSemaphore) service {
@@ -116,6 +137,9 @@ func newSendReceiveFolder(model *model, fset *db.FileSet, ignores *ignore.Matche
 		versioner:     ver,
 		queue:         newJobQueue(),
 		pullErrorsMut: sync.NewMutex(),
+		backoff:       newPullBackoff(),
+		reverted:      make(map[string]struct{}),
+		revertedMut:   sync.NewMutex(),
 	}
 	f.folder.puller = f
 	f.folder.Service = util.AsService(f.serve, f.String())
@@ -152,6 +176,7 @@ func (f *sendReceiveFolder) pull() bool {
 			f.ignoresUpdated()
 		}
 	}()
+	f.syncIgnores()
 	if err := f.ignores.Load(".stignore"); err != nil && !fs.IsNotExist(err) {
 		err = errors.Wrap(err, "loading ignores")
 		f.setError(err)
@@ -166,6 +191,9 @@ func (f *sendReceiveFolder) pull() bool {
 	defer func() {
 		close(scanChan)
 		f.setState(FolderIdle)
+		if err := f.pullBudget.save(); err != nil {
+			l.Warnf("Folder %v: storing pull budget: %v", f.Description(), err)
+		}
 	}()
 
 	changed := 0
@@ -185,6 +213,13 @@ func (f *sendReceiveFolder) pull() bool {
 
 		l.Debugln(f, "changed", changed, "on try", tries+1)
 
+		if f.pullBudget.isStopped() {
+			// What wasn't pulled stays needed, with the files that were
+			// started in their temp files, until the pull is resumed.
+			l.Infof("Folder %v: pull budget used up, stopping until the pull is resumed", f.Description())
+			break
+		}
+
 		if changed == 0 {
 			// No files were changed by the puller, so we are in
 			// sync (except for unrecoverable stuff like invalid
@@ -193,8 +228,21 @@ func (f *sendReceiveFolder) pull() bool {
 		}
 	}
 
+	// Items that still failed after all iterations are retried in a later
+	// pull, once their backoff has passed.
+	snap := f.fset.Snapshot()
+	f.backoff.settle(time.Now(), func(name string) (itemVersions, bool) {
+		global, ok := snap.GetGlobalTruncated(name)
+		if !ok {
+			return itemVersions{}, false
+		}
+		return f.itemVersions(snap, name, global.Version), true
+	})
+	snap.Release()
+
 	f.pullErrorsMut.Lock()
 	pullErrNum := len(f.pullErrors)
+	f.backoff.retain(f.pullErrors)
 	f.pullErrorsMut.Unlock()
 	if pullErrNum > 0 {
 		l.Infof("%v: Failed to sync %v items", f.Description(), pullErrNum)
@@ -204,7 +252,33 @@ func (f *sendReceiveFolder) pull() bool {
 		})
 	}
 
-	return changed == 0
+	// Patterns pulled from another device take effect with a scan right
+	// away, which pulls again if they changed.
+	if f.syncIgnores() {
+		f.scanTimer.Reset(0)
+	}
+
+	_, waiting := f.backoff.nextRetry()
+
+	return (changed == 0 || f.pullBudget.isStopped()) && !waiting
+}
+
+// nextRetry returns when the first of the items that failed is to be
+// retried.
+func (f *sendReceiveFolder) nextRetry() (time.Time, bool) {
+	return f.backoff.nextRetry()
+}
+
+func (f *sendReceiveFolder) itemVersions(snap *db.Snapshot, name string, global protocol.Vector) itemVersions {
+	cur, _ := snap.Get(protocol.LocalDeviceID, name)
+	return itemVersions{global: global, local: cur.Version}
+}
+
+// ResetBackoff makes the given items, or all of them, be retried right away
+// in a new pull instead of once their backoff has passed.
+func (f *sendReceiveFolder) ResetBackoff(files []string) {
+	f.backoff.reset(files)
+	f.SchedulePull()
 }
 
 // pullerIteration runs a single puller iteration for the given folder and
@@ -215,8 +289,20 @@ func (f *sendReceiveFolder) pullerIteration(scanChan chan<- string) int {
 	f.pullErrorsMut.Lock()
 	f.oldPullErrors = f.pullErrors
 	f.pullErrors = make(map[string]string)
+	f.caseConflicts = make(map[string]struct{})
+	f.failures = 0
 	f.pullErrorsMut.Unlock()
 
+	f.atomic = nil
+	if f.AtomicApply {
+		f.atomic = &atomicBatch{}
+	}
+
+	f.caseCheck = nil
+	if fs.IsCaseInsensitive(f.fs, f.MarkerName) {
+		f.caseCheck = newCaseChecker(f.fs)
+	}
+
 	snap := f.fset.Snapshot()
 	defer snap.Release()
 
@@ -276,7 +362,9 @@ func (f *sendReceiveFolder) pullerIteration(scanChan chan<- string) int {
 	close(finisherChan)
 	doneWg.Wait()
 
-	if err == nil {
+	if f.atomic != nil {
+		f.applyAtomic(fileDeletions, dirDeletions, snap, dbUpdateChan, scanChan)
+	} else if err == nil {
 		f.processDeletions(fileDeletions, dirDeletions, snap, dbUpdateChan, scanChan)
 	}
 
@@ -298,6 +386,11 @@ func (f *sendReceiveFolder) processNeeded(snap *db.Snapshot, dbUpdateChan chan<-
 	var dirDeletions []protocol.FileInfo
 	fileDeletions := map[string]protocol.FileInfo{}
 	buckets := map[string][]protocol.FileInfo{}
+	moves := &moveCandidates{}
+	var renameUpdates []renameCandidate
+	var renameNew []string
+	parents := make(map[string]struct{}) // of the queued files, when applying atomically
+	now := time.Now()
 
 	// Iterate the list of items that we need and sort them into piles.
 	// Regular files to pull goes into the file queue, everything else
@@ -319,13 +412,28 @@ func (f *sendReceiveFolder) processNeeded(snap *db.Snapshot, dbUpdateChan chan<-
 
 		file := intf.(protocol.FileInfo)
 
+		versions := func() itemVersions { return f.itemVersions(snap, file.Name, file.Version) }
+		if errStr, ok := f.backoff.waiting(file.Name, now, versions); ok {
+			// It failed before and isn't due for another try yet,
+			// but is still out of sync.
+			f.keepPullError(file.Name, errStr)
+			if f.atomic != nil {
+				// Holds up the whole batch until it's through.
+				f.pullErrorsMut.Lock()
+				f.failures++
+				f.pullErrorsMut.Unlock()
+			}
+			changed--
+			return true
+		}
+
 		switch {
-		case f.ignores.ShouldIgnore(file.Name):
+		case f.ignores.ShouldIgnoreStat(file.Name, ignoreStat(file)):
 			file.SetIgnored(f.shortID)
 			l.Debugln(f, "Handling ignored file", file)
 			dbUpdateChan <- dbUpdateJob{file, dbUpdateInvalidate}
 
-		case runtime.GOOS == "windows" && fs.WindowsInvalidFilename(file.Name):
+		case runtime.GOOS == "windows" && f.InvalidNamePolicy != config.InvalidNameEscape && fs.WindowsInvalidFilename(file.Name):
 			if file.IsDeleted() {
 				// Just pretend we deleted it, no reason to create an error
 				// about a deleted file that we can't have anyway.
@@ -334,18 +442,29 @@ func (f *sendReceiveFolder) processNeeded(snap *db.Snapshot, dbUpdateChan chan<-
 				dbUpdateChan <- dbUpdateJob{file, dbUpdateDeleteFile}
 			} else {
 				// We can't pull an invalid file.
-				f.newPullError(file.Name, fs.ErrInvalidFilename)
+				f.newPullError(file.Name, fs.WindowsFilenameError(file.Name))
 				// No reason to retry for this
 				changed--
 			}
 
+		case !file.IsDeleted() && f.checkCaseConflict(file.Name, snap, scanChan):
+			// Retrying is no use until something is renamed, unless we
+			// get to rename something ourselves.
+			if f.CaseConflictSuffix == "" {
+				changed--
+			}
+
 		case file.IsDeleted():
 			if file.IsDirectory() {
 				// Perform directory deletions at the end, as we may have
 				// files to delete inside them before we get to that point.
 				dirDeletions = append(dirDeletions, file)
 			} else if file.IsSymlink() {
-				f.deleteFile(file, snap, dbUpdateChan, scanChan)
+				if f.atomic != nil {
+					fileDeletions[file.Name] = file
+				} else {
+					f.deleteFile(file, snap, dbUpdateChan, scanChan)
+				}
 			} else {
 				df, ok := snap.Get(protocol.LocalDeviceID, file.Name)
 				// Local file can be already deleted, but with a lower version
@@ -357,6 +476,9 @@ func (f *sendReceiveFolder) processNeeded(snap *db.Snapshot, dbUpdateChan chan<-
 					// Put files into buckets per first hash
 					key := string(df.Blocks[0].Hash)
 					buckets[key] = append(buckets[key], df)
+					moves.add(df)
+				} else if f.atomic != nil {
+					fileDeletions[file.Name] = file
 				} else {
 					f.deleteFileWithCurrent(file, df, ok, dbUpdateChan, scanChan)
 				}
@@ -368,10 +490,27 @@ func (f *sendReceiveFolder) processNeeded(snap *db.Snapshot, dbUpdateChan chan<-
 				// We are supposed to copy the entire file, and then fetch nothing. We
 				// are only updating metadata, so we don't actually *need* to make the
 				// copy.
-				f.shortcutFile(file, curFile, dbUpdateChan)
+				if f.atomic != nil {
+					f.atomic.items = append(f.atomic.items, file)
+				} else {
+					f.shortcutFile(file, curFile, dbUpdateChan)
+				}
 			} else {
 				// Queue files for processing after directories and symlinks.
 				f.queue.Push(file.Name, file.Size, file.ModTime())
+				if f.atomic != nil {
+					for dir := filepath.Dir(file.Name); dir != "."; dir = filepath.Dir(dir) {
+						parents[dir] = struct{}{}
+					}
+				}
+				// Keep what's needed to find the renames that can't be
+				// done one at a time.
+				switch {
+				case !hasCurFile || curFile.IsDeleted():
+					renameNew = append(renameNew, file.Name)
+				case curFile.Type == protocol.FileInfoTypeFile && !curFile.IsInvalid() && len(curFile.Blocks) > 0 && len(file.Blocks) > 0:
+					renameUpdates = append(renameUpdates, renameCandidate{file.Name, string(curFile.Blocks[0].Hash), string(file.Blocks[0].Hash)})
+				}
 			}
 
 		case runtime.GOOS == "windows" && file.IsSymlink():
@@ -379,6 +518,10 @@ func (f *sendReceiveFolder) processNeeded(snap *db.Snapshot, dbUpdateChan chan<-
 			l.Debugln(f, "Invalidating symlink (unsupported)", file.Name)
 			dbUpdateChan <- dbUpdateJob{file, dbUpdateInvalidate}
 
+		case f.atomic != nil && (file.IsDirectory() || file.IsSymlink()):
+			l.Debugln(f, "Holding back", file.Name)
+			f.atomic.items = append(f.atomic.items, file)
+
 		case file.IsDirectory() && !file.IsSymlink():
 			l.Debugln(f, "Handling directory", file.Name)
 			if f.checkParent(file.Name, scanChan) {
@@ -405,23 +548,18 @@ func (f *sendReceiveFolder) processNeeded(snap *db.Snapshot, dbUpdateChan chan<-
 	default:
 	}
 
-	// Now do the file queue. Reorder it according to configuration.
-
-	switch f.Order {
-	case config.OrderRandom:
-		f.queue.Shuffle()
-	case config.OrderAlphabetic:
-	// The queue is already in alphabetic order.
-	case config.OrderSmallestFirst:
-		f.queue.SortSmallestFirst()
-	case config.OrderLargestFirst:
-		f.queue.SortLargestFirst()
-	case config.OrderOldestFirst:
-		f.queue.SortOldestFirst()
-	case config.OrderNewestFirst:
-		f.queue.SortNewestFirst()
+	if f.atomic != nil {
+		f.createParents(parents, snap, dbUpdateChan, scanChan)
 	}
 
+	// Now do the file queue. Reorder it according to configuration, with
+	// pinned files first.
+	f.queue.SortByOrder(f.Order)
+	f.queue.PinToFront(f.PinsPath)
+
+	// Swaps and case-only renames are done first, through temporary names.
+	renamed := f.planAndRename(renameUpdates, renameNew, fileDeletions, snap, dbUpdateChan, scanChan)
+
 	// Process the file queue.
 
 nextFile:
@@ -437,6 +575,11 @@ nextFile:
 			break
 		}
 
+		if renamed[fileName] {
+			f.queue.Done(fileName)
+			continue
+		}
+
 		fi, ok := snap.GetGlobal(fileName)
 		if !ok {
 			// File is no longer in the index. Mark it as done and drop it.
@@ -457,10 +600,15 @@ nextFile:
 		}
 
 		// Check our list of files to be removed for a match, in which case
-		// we can just do a rename instead.
+		// we can just do a rename instead. When applying atomically the
+		// file is copied from the candidate instead, to be put in place
+		// with everything else.
 		key := string(fi.Blocks[0].Hash)
 		for i, candidate := range buckets[key] {
-			if protocol.BlocksEqual(candidate.Blocks, fi.Blocks) {
+			if f.atomic != nil {
+				break
+			}
+			if _, pending := fileDeletions[candidate.Name]; pending && protocol.BlocksEqual(candidate.Blocks, fi.Blocks) {
 				// Remove the candidate from the bucket
 				lidx := len(buckets[key]) - 1
 				buckets[key][i] = buckets[key][lidx]
@@ -488,7 +636,16 @@ nextFile:
 		for _, dev := range devices {
 			if _, ok := f.model.Connection(dev); ok {
 				// Handle the file normally, by coping and pulling, etc.
-				f.handleFile(fi, snap, copyChan)
+				// If it looks like it was moved from a file that is to be
+				// deleted, that file is used as the original to copy from.
+				var origin string
+				if cur, ok := snap.Get(protocol.LocalDeviceID, fi.Name); !ok || cur.IsDeleted() {
+					if from, ok := moves.origin(fi, fileDeletions); ok {
+						l.Debugln(f, "looks moved", from.Name, "->", fi.Name)
+						origin = from.Name
+					}
+				}
+				f.handleFile(fi, origin, snap, copyChan)
 				continue nextFile
 			}
 		}
@@ -599,9 +756,15 @@ func (f *sendReceiveFolder) handleDir(file protocol.FileInfo, snap *db.Snapshot,
 		// not MkdirAll because the parent should already exist.
 		mkdir := func(path string) error {
 			err = f.fs.Mkdir(path, mode)
-			if err != nil || f.IgnorePerms || file.NoPermissions {
+			if err != nil {
 				return err
 			}
+			if err := f.maybeSetXattrs(path, file); err != nil {
+				return err
+			}
+			if f.IgnorePerms || file.NoPermissions {
+				return nil
+			}
 
 			// Copy the parent owner and group, if we are supposed to do that.
 			if err := f.maybeCopyOwner(path); err != nil {
@@ -635,6 +798,10 @@ func (f *sendReceiveFolder) handleDir(file protocol.FileInfo, snap *db.Snapshot,
 	// The directory already exists, so we just correct the mode bits. (We
 	// don't handle modification times on directories, because that sucks...)
 	// It's OK to change mode bits on stuff within non-writable directories.
+	if err := f.maybeSetXattrs(file.Name, file); err != nil {
+		f.newPullError(file.Name, err)
+		return
+	}
 	if !f.IgnorePerms && !file.NoPermissions {
 		if err := f.fs.Chmod(file.Name, mode|(fs.FileMode(info.Mode())&retainBits)); err != nil {
 			f.newPullError(file.Name, err)
@@ -857,8 +1024,8 @@ func (f *sendReceiveFolder) deleteFileWithCurrent(file, cur protocol.FileInfo, h
 		return
 	}
 
-	if f.versioner != nil && !cur.IsSymlink() {
-		err = f.inWritableDir(f.versioner.Archive, file.Name)
+	if ver := f.versionerFor(file.Name); ver != nil && !cur.IsSymlink() {
+		err = f.inWritableDir(ver.Archive, file.Name)
 	} else {
 		err = f.inWritableDir(f.fs.Remove, file.Name)
 	}
@@ -955,12 +1122,12 @@ func (f *sendReceiveFolder) renameFile(cur, source, target protocol.FileInfo, sn
 
 	tempName := fs.TempName(target.Name)
 
-	if f.versioner != nil {
+	if ver := f.versionerFor(source.Name); ver != nil {
 		err = f.CheckAvailableSpace(source.Size)
 		if err == nil {
 			err = osutil.Copy(f.fs, f.fs, source.Name, tempName)
 			if err == nil {
-				err = f.inWritableDir(f.versioner.Archive, source.Name)
+				err = f.inWritableDir(ver.Archive, source.Name)
 			}
 		}
 	} else {
@@ -1024,11 +1191,17 @@ func (f *sendReceiveFolder) renameFile(cur, source, target protocol.FileInfo, sn
 //                                                      +-----------------------+
 
 // handleFile queues the copies and pulls as necessary for a single new or
-// changed file.
-func (f *sendReceiveFolder) handleFile(file protocol.FileInfo, snap *db.Snapshot, copyChan chan<- copyBlocksState) {
+// changed file. The origin is the file it was moved from, if any, or else
+// the empty string.
+func (f *sendReceiveFolder) handleFile(file protocol.FileInfo, origin string, snap *db.Snapshot, copyChan chan<- copyBlocksState) {
 	curFile, hasCurFile := snap.Get(protocol.LocalDeviceID, file.Name)
 
 	have, _ := blockDiff(curFile.Blocks, file.Blocks)
+	if origin == "" {
+		origin = file.Name
+	} else if originFile, ok := snap.Get(protocol.LocalDeviceID, origin); ok {
+		have, _ = blockDiff(originFile.Blocks, file.Blocks)
+	}
 
 	tempName := fs.TempName(file.Name)
 
@@ -1076,6 +1249,10 @@ func (f *sendReceiveFolder) handleFile(file protocol.FileInfo, snap *db.Snapshot
 	// Shuffle the blocks
 	rand.Shuffle(blocks)
 
+	if f.BlockPullOrder == config.BlockPullOrderRarestFirst {
+		f.sortRarestFirst(file, blocks)
+	}
+
 	f.evLogger.Log(events.ItemStarted, map[string]string{
 		"folder": f.folderID,
 		"item":   file.Name,
@@ -1109,6 +1286,7 @@ func (f *sendReceiveFolder) handleFile(file protocol.FileInfo, snap *db.Snapshot
 		sharedPullerState: &s,
 		blocks:            blocks,
 		have:              len(have),
+		origin:            origin,
 	}
 	copyChan <- cs
 }
@@ -1175,6 +1353,11 @@ func (f *sendReceiveFolder) shortcutFile(file, curFile protocol.FileInfo, dbUpda
 
 	f.queue.Done(file.Name)
 
+	if err = f.maybeSetXattrs(file.Name, file); err != nil {
+		f.newPullError(file.Name, err)
+		return
+	}
+
 	if !f.IgnorePerms && !file.NoPermissions {
 		if err = f.fs.Chmod(file.Name, fs.FileMode(file.Permissions&0777)); err != nil {
 			f.newPullError(file.Name, err)
@@ -1219,7 +1402,7 @@ func (f *sendReceiveFolder) copierRoutine(in <-chan copyBlocksState, pullChan ch
 		folderFilesystems := make(map[string]fs.Filesystem)
 		var folders []string
 		for folder, cfg := range f.model.cfg.Folders() {
-			folderFilesystems[folder] = cfg.Filesystem()
+			folderFilesystems[folder] = f.model.folderFilesystem(cfg)
 			folders = append(folders, folder)
 		}
 
@@ -1240,7 +1423,7 @@ func (f *sendReceiveFolder) copierRoutine(in <-chan copyBlocksState, pullChan ch
 			}
 
 			if len(hashesToFind) > 0 {
-				file, err = f.fs.Open(state.file.Name)
+				file, err = f.fs.Open(state.origin)
 				if err == nil {
 					weakHashFinder, err = weakhash.NewFinder(f.ctx, file, state.file.BlockSize(), hashesToFind)
 					if err != nil {
@@ -1263,12 +1446,17 @@ func (f *sendReceiveFolder) copierRoutine(in <-chan copyBlocksState, pullChan ch
 			default:
 			}
 
-			if !f.DisableSparseFiles && state.reused == 0 && block.IsEmpty() {
-				// The block is a block of all zeroes, and we are not reusing
-				// a temp file, so there is no need to do anything with it.
-				// If we were reusing a temp file and had this block to copy,
-				// it would be because the block in the temp file was *not* a
-				// block of all zeroes, so then we should not skip it.
+			if !f.DisableSparseFiles && block.IsEmpty() {
+				// The block is a block of all zeroes, so there is no need to
+				// look for it. If we are not reusing a temp file, the file
+				// is a hole here already. If we were reusing a temp file and
+				// had this block to copy, it would be because the block in
+				// the temp file was *not* a block of all zeroes, so then we
+				// punch a hole in its place.
+				if err := state.punchHole(dstFd, block.Offset, int64(block.Size)); err != nil {
+					state.fail(errors.Wrap(err, "dst write"))
+					break
+				}
 
 				// Pretend we copied it.
 				state.copiedFromOrigin()
@@ -1283,7 +1471,7 @@ func (f *sendReceiveFolder) copierRoutine(in <-chan copyBlocksState, pullChan ch
 					return true
 				}
 
-				_, err = dstFd.WriteAt(buf, block.Offset)
+				err = state.writeBlock(dstFd, buf, block.Offset)
 				if err != nil {
 					state.fail(errors.Wrap(err, "dst write"))
 
@@ -1319,11 +1507,11 @@ func (f *sendReceiveFolder) copierRoutine(in <-chan copyBlocksState, pullChan ch
 						return false
 					}
 
-					_, err = dstFd.WriteAt(buf, block.Offset)
+					err = state.writeBlock(dstFd, buf, block.Offset)
 					if err != nil {
 						state.fail(errors.Wrap(err, "dst write"))
 					}
-					if path == state.file.Name {
+					if path == state.origin {
 						state.copiedFromOrigin()
 					}
 					return true
@@ -1448,6 +1636,11 @@ func (f *sendReceiveFolder) pullBlock(state pullBlockState, out chan<- *sharedPu
 
 		candidates = removeAvailability(candidates, selected)
 
+		if !f.pullBudget.take(int64(state.block.Size)) {
+			state.fail(errPullBudgetUsed)
+			break
+		}
+
 		// Fetch the block, while marking the selected device as in use so that
 		// leastBusy can select another device when someone else asks.
 		activity.using(selected)
@@ -1456,6 +1649,7 @@ func (f *sendReceiveFolder) pullBlock(state pullBlockState, out chan<- *sharedPu
 		activity.done(selected)
 		if lastError != nil {
 			l.Debugln("request:", f.folderID, state.file.Name, state.block.Offset, state.block.Size, "returned error:", lastError)
+			f.pullBudget.giveBack(int64(state.block.Size))
 			continue
 		}
 
@@ -1468,7 +1662,7 @@ func (f *sendReceiveFolder) pullBlock(state pullBlockState, out chan<- *sharedPu
 		}
 
 		// Save the block data we got from the cluster
-		_, err = fd.WriteAt(buf, state.block.Offset)
+		err = state.writeBlock(fd, buf, state.block.Offset)
 		if err != nil {
 			state.fail(errors.Wrap(err, "save"))
 		} else {
@@ -1480,6 +1674,12 @@ func (f *sendReceiveFolder) pullBlock(state pullBlockState, out chan<- *sharedPu
 }
 
 func (f *sendReceiveFolder) performFinish(file, curFile protocol.FileInfo, hasCurFile bool, tempName string, snap *db.Snapshot, dbUpdateChan chan<- dbUpdateJob, scanChan chan<- string) error {
+	// Before the permission bits, as they may not let us write the
+	// extended attributes.
+	if err := f.maybeSetXattrs(tempName, file); err != nil {
+		return err
+	}
+
 	// Set the correct permission bits on the new file
 	if !f.IgnorePerms && !file.NoPermissions {
 		if err := f.fs.Chmod(tempName, fs.FileMode(file.Permissions&0777)); err != nil {
@@ -1542,37 +1742,114 @@ func (f *sendReceiveFolder) finisherRoutine(snap *db.Snapshot, in <-chan *shared
 
 			f.queue.Done(state.file.Name)
 
-			if err == nil {
-				err = f.performFinish(state.file, state.curFile, state.hasCurFile, state.tempName, snap, dbUpdateChan, scanChan)
+			if err == nil && f.atomic != nil {
+				// Stays in its temp file until everything else is
+				// pulled as well.
+				f.atomic.pulled = append(f.atomic.pulled, state)
+				continue
 			}
 
-			if err != nil {
-				f.newPullError(state.file.Name, err)
-			} else {
-				minBlocksPerBlock := state.file.BlockSize() / protocol.MinBlockSize
-				blockStatsMut.Lock()
-				blockStats["total"] += (state.reused + state.copyTotal + state.pullTotal) * minBlocksPerBlock
-				blockStats["reused"] += state.reused * minBlocksPerBlock
-				blockStats["pulled"] += state.pullTotal * minBlocksPerBlock
-				// copyOriginShifted is counted towards copyOrigin due to progress bar reasons
-				// for reporting reasons we want to separate these.
-				blockStats["copyOrigin"] += (state.copyOrigin - state.copyOriginShifted) * minBlocksPerBlock
-				blockStats["copyOriginShifted"] += state.copyOriginShifted * minBlocksPerBlock
-				blockStats["copyElsewhere"] += (state.copyTotal - state.copyOrigin) * minBlocksPerBlock
-				blockStatsMut.Unlock()
+			f.finishPulled(state, err, snap, dbUpdateChan, scanChan)
+		}
+	}
+}
+
+// finishPulled puts a file that has been pulled into place, unless pulling
+// it failed.
+func (f *sendReceiveFolder) finishPulled(state *sharedPullerState, err error, snap *db.Snapshot, dbUpdateChan chan<- dbUpdateJob, scanChan chan<- string) {
+	if err == nil {
+		err = f.performFinish(state.file, state.curFile, state.hasCurFile, state.tempName, snap, dbUpdateChan, scanChan)
+	}
+
+	if err != nil {
+		f.newPullError(state.file.Name, err)
+	} else {
+		minBlocksPerBlock := state.file.BlockSize() / protocol.MinBlockSize
+		blockStatsMut.Lock()
+		blockStats["total"] += (state.reused + state.copyTotal + state.pullTotal) * minBlocksPerBlock
+		blockStats["reused"] += state.reused * minBlocksPerBlock
+		blockStats["pulled"] += state.pullTotal * minBlocksPerBlock
+		// copyOriginShifted is counted towards copyOrigin due to progress bar reasons
+		// for reporting reasons we want to separate these.
+		blockStats["copyOrigin"] += (state.copyOrigin - state.copyOriginShifted) * minBlocksPerBlock
+		blockStats["copyOriginShifted"] += state.copyOriginShifted * minBlocksPerBlock
+		blockStats["copyElsewhere"] += (state.copyTotal - state.copyOrigin) * minBlocksPerBlock
+		blockStatsMut.Unlock()
+	}
+
+	f.model.progressEmitter.Deregister(state)
+
+	f.evLogger.Log(events.ItemFinished, map[string]interface{}{
+		"folder": f.folderID,
+		"item":   state.file.Name,
+		"error":  events.Error(err),
+		"type":   "file",
+		"action": "update",
+	})
+}
+
+// createParents creates the new directories that the queued files are
+// pulled into, so that their temp files have a place to go. They are
+// taken out of the held back changes; the other directories wait for the
+// end of the iteration.
+func (f *sendReceiveFolder) createParents(parents map[string]struct{}, snap *db.Snapshot, dbUpdateChan chan<- dbUpdateJob, scanChan chan<- string) {
+	items := f.atomic.items[:0]
+	for _, file := range f.atomic.items {
+		if _, ok := parents[file.Name]; ok && file.IsDirectory() && !file.IsSymlink() {
+			if _, err := f.fs.Lstat(file.Name); fs.IsNotExist(err) {
+				if f.checkParent(file.Name, scanChan) {
+					f.handleDir(file, snap, dbUpdateChan, scanChan)
+				}
+				continue
 			}
+		}
+		items = append(items, file)
+	}
+	f.atomic.items = items
+}
+
+// applyAtomic applies the changes held back in an iteration one right
+// after the other, once all of them are there: directories, symlinks and
+// metadata updates first, then the pulled files and at last the
+// deletions. If anything else failed in the iteration, or is waiting for
+// a retry after failing before, nothing is applied. The pulled files are
+// left in their temp files instead, to be reused when it's tried again.
+func (f *sendReceiveFolder) applyAtomic(fileDeletions map[string]protocol.FileInfo, dirDeletions []protocol.FileInfo, snap *db.Snapshot, dbUpdateChan chan<- dbUpdateJob, scanChan chan<- string) {
+	f.pullErrorsMut.Lock()
+	failures := f.failures
+	f.pullErrorsMut.Unlock()
 
+	held := len(f.atomic.items) + len(f.atomic.pulled) + len(fileDeletions) + len(dirDeletions)
+	if failures > 0 || f.ctx.Err() != nil {
+		if failures > 0 && held > 0 {
+			l.Infof("Folder %v: not applying %d changes, as %d other items failed", f.Description(), held, failures)
+		}
+		for _, state := range f.atomic.pulled {
 			f.model.progressEmitter.Deregister(state)
+		}
+		return
+	}
 
-			f.evLogger.Log(events.ItemFinished, map[string]interface{}{
-				"folder": f.folderID,
-				"item":   state.file.Name,
-				"error":  events.Error(err),
-				"type":   "file",
-				"action": "update",
-			})
+	l.Debugf("%v applying %d changes", f, held)
+	for _, file := range f.atomic.items {
+		switch {
+		case file.IsDirectory() && !file.IsSymlink():
+			if f.checkParent(file.Name, scanChan) {
+				f.handleDir(file, snap, dbUpdateChan, scanChan)
+			}
+		case file.IsSymlink():
+			if f.checkParent(file.Name, scanChan) {
+				f.handleSymlink(file, snap, dbUpdateChan, scanChan)
+			}
+		default:
+			curFile, _ := snap.Get(protocol.LocalDeviceID, file.Name)
+			f.shortcutFile(file, curFile, dbUpdateChan)
 		}
 	}
+	for _, state := range f.atomic.pulled {
+		f.finishPulled(state, nil, snap, dbUpdateChan, scanChan)
+	}
+	f.processDeletions(fileDeletions, dirDeletions, snap, dbUpdateChan, scanChan)
 }
 
 // Moves the given filename to the front of the job queue
@@ -1632,6 +1909,8 @@ loop:
 				break loop
 			}
 
+			f.backoff.succeeded(job.file.Name)
+
 			switch job.jobType {
 			case dbUpdateHandleFile, dbUpdateShortcutFile:
 				changedDirs[filepath.Dir(job.file.Name)] = struct{}{}
@@ -1683,11 +1962,15 @@ func (f *sendReceiveFolder) pullScannerRoutine(scanChan <-chan string) {
 }
 
 func (f *sendReceiveFolder) inConflict(current, replacement protocol.Vector) bool {
+	return inConflict(current, replacement, f.shortID)
+}
+
+func inConflict(current, replacement protocol.Vector, shortID protocol.ShortID) bool {
 	if current.Concurrent(replacement) {
 		// Obvious case
 		return true
 	}
-	if replacement.Counter(f.shortID) > current.Counter(f.shortID) {
+	if replacement.Counter(shortID) > current.Counter(shortID) {
 		// The replacement file contains a higher version for ourselves than
 		// what we have. This isn't supposed to be possible, since it's only
 		// we who can increment that counter. We take it as a sign that
@@ -1698,6 +1981,22 @@ func (f *sendReceiveFolder) inConflict(current, replacement protocol.Vector) boo
 	return false
 }
 
+// sortRarestFirst orders the blocks so that those that the fewest other
+// devices have in their temporary files come first. When many devices pull
+// the same file, this makes them fetch different blocks from the source, so
+// they can then exchange the rest among themselves. The relative order of
+// equally rare blocks is retained.
+func (f *sendReceiveFolder) sortRarestFirst(file protocol.FileInfo, blocks []protocol.BlockInfo) {
+	counts := f.model.temporaryAvailability(f.folderID, file)
+	if len(counts) == 0 {
+		return
+	}
+	blockSize := int64(file.BlockSize())
+	sort.SliceStable(blocks, func(a, b int) bool {
+		return counts[int32(blocks[a].Offset/blockSize)] < counts[int32(blocks[b].Offset/blockSize)]
+	})
+}
+
 func removeAvailability(availabilities []Availability, availability Availability) []Availability {
 	for i := range availabilities {
 		if availabilities[i] == availability {
@@ -1755,6 +2054,14 @@ func (f *sendReceiveFolder) newPullError(path string, err error) {
 		// Error because the folder stopped - no point logging/tracking
 		return
 	}
+	if errors.Cause(err) == errPullBudgetUsed {
+		// Stopped at the checkpoint, not an error. It still keeps the
+		// staged files of an atomic apply from being applied.
+		f.pullErrorsMut.Lock()
+		f.failures++
+		f.pullErrorsMut.Unlock()
+		return
+	}
 
 	f.pullErrorsMut.Lock()
 	defer f.pullErrorsMut.Unlock()
@@ -1771,6 +2078,16 @@ func (f *sendReceiveFolder) newPullError(path string, err error) {
 	// for errors occurring specificly in the puller routine.
 	errStr := fmt.Sprintln("syncing:", err)
 	f.pullErrors[path] = errStr
+	f.failures++
+	switch errors.Cause(err).(type) {
+	case *fs.InvalidFilenameError:
+		// Invalid names are not retried until they change anyway.
+	case *fs.CaseConflictError:
+		// Neither are case conflicts, which are cheap to check again.
+		f.caseConflicts[path] = struct{}{}
+	default:
+		f.backoff.failed(path, err, errStr)
+	}
 
 	if oldErr, ok := f.oldPullErrors[path]; ok && oldErr == errStr {
 		l.Debugf("Repeat error on puller (folder %s, item %q): %v", f.Description(), path, err)
@@ -1781,12 +2098,26 @@ func (f *sendReceiveFolder) newPullError(path string, err error) {
 	l.Infof("Puller (folder %s, item %q): %v", f.Description(), path, err)
 }
 
+// keepPullError reports the last error of an item that isn't retried in
+// this iteration.
+func (f *sendReceiveFolder) keepPullError(path string, errStr string) {
+	f.pullErrorsMut.Lock()
+	if _, ok := f.pullErrors[path]; !ok {
+		f.pullErrors[path] = errStr
+	}
+	f.pullErrorsMut.Unlock()
+}
+
 func (f *sendReceiveFolder) Errors() []FileError {
 	scanErrors := f.folder.Errors()
 	f.pullErrorsMut.Lock()
 	errors := make([]FileError, 0, len(f.pullErrors)+len(f.scanErrors))
 	for path, err := range f.pullErrors {
-		errors = append(errors, FileError{path, err})
+		fe := FileError{Path: path, Err: err}
+		if _, ok := f.caseConflicts[path]; ok {
+			fe.Type = FileErrorCaseConflict
+		}
+		errors = append(errors, fe)
 	}
 	f.pullErrorsMut.Unlock()
 	errors = append(errors, scanErrors...)
@@ -1806,18 +2137,36 @@ func (f *sendReceiveFolder) deleteItemOnDisk(item protocol.FileInfo, snap *db.Sn
 		// to potential children.
 		return f.deleteDirOnDisk(item.Name, snap, scanChan)
 
-	case !item.IsSymlink() && f.versioner != nil:
+	case !item.IsSymlink():
 		// If we should use versioning, let the versioner archive the
 		// file before we replace it. Archiving a non-existent file is not
 		// an error.
 		// Symlinks aren't archived.
 
-		return f.inWritableDir(f.versioner.Archive, item.Name)
+		if ver := f.versionerFor(item.Name); ver != nil {
+			return f.inWritableDir(ver.Archive, item.Name)
+		}
 	}
 
 	return f.inWritableDir(f.fs.Remove, item.Name)
 }
 
+// versionerFor returns the versioner to archive the file with before
+// replacing or removing it, if any: the folder's, or else the revert
+// versioner for files thrown away by a revert.
+func (f *sendReceiveFolder) versionerFor(name string) versioner.Versioner {
+	if f.versioner != nil || f.revertVersioner == nil {
+		return f.versioner
+	}
+	f.revertedMut.Lock()
+	defer f.revertedMut.Unlock()
+	if _, ok := f.reverted[name]; !ok {
+		return nil
+	}
+	delete(f.reverted, name)
+	return f.revertVersioner
+}
+
 // deleteDirOnDisk attempts to delete a directory. It checks for files/dirs inside
 // the directory and removes them if possible or returns an error if it fails
 func (f *sendReceiveFolder) deleteDirOnDisk(dir string, snap *db.Snapshot, scanChan chan<- string) error {
@@ -1954,14 +2303,37 @@ func (f *sendReceiveFolder) maybeCopyOwner(path string) error {
 	return nil
 }
 
+// maybeSetXattrs sets the extended attributes of the file, if we are
+// supposed to and they were read on the same platform as ours.
+func (f *sendReceiveFolder) maybeSetXattrs(path string, file protocol.FileInfo) error {
+	if !f.SyncXattrs || file.XattrPlatform != runtime.GOOS {
+		// Not supposed to do anything.
+		return nil
+	}
+	xattrs := make([]fs.Xattr, len(file.Xattrs))
+	for i, xattr := range file.Xattrs {
+		xattrs[i] = fs.Xattr{Name: xattr.Name, Value: xattr.Value}
+	}
+	if err := f.fs.SetXattr(path, xattrs, f.XattrFilter); err != nil {
+		return errors.Wrap(err, "set extended attributes")
+	}
+	return nil
+}
+
 func (f *sendReceiveFolder) inWritableDir(fn func(string) error, path string) error {
 	return inWritableDir(fn, f.fs, path, f.IgnorePerms)
 }
 
 // A []FileError is sent as part of an event and will be JSON serialized.
+// The kinds of FileError that are told apart from the general one.
+const (
+	FileErrorCaseConflict = "caseConflict" // see fs.CaseConflictError
+)
+
 type FileError struct {
 	Path string `json:"path"`
 	Err  string `json:"error"`
+	Type string `json:"type,omitempty"`
 }
 
 type fileErrorList []FileError
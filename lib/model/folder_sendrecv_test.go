@@ -109,6 +109,7 @@ func setupSendReceiveFolder(files ...protocol.FileInfo) (*model, *sendReceiveFol
 		queue:         newJobQueue(),
 		pullErrors:    make(map[string]string),
 		pullErrorsMut: sync.NewMutex(),
+		backoff:       newPullBackoff(),
 	}
 	f.fs = fs.NewMtimeFS(f.Filesystem(), db.NewNamespacedKV(model.db, "mtime"))
 
@@ -149,7 +150,7 @@ func TestHandleFile(t *testing.T) {
 
 	copyChan := make(chan copyBlocksState, 1)
 
-	f.handleFile(requiredFile, f.fset.Snapshot(), copyChan)
+	f.handleFile(requiredFile, "", f.fset.Snapshot(), copyChan)
 
 	// Receive the results
 	toCopy := <-copyChan
@@ -195,7 +196,7 @@ func TestHandleFileWithTemp(t *testing.T) {
 
 	copyChan := make(chan copyBlocksState, 1)
 
-	f.handleFile(requiredFile, f.fset.Snapshot(), copyChan)
+	f.handleFile(requiredFile, "", f.fset.Snapshot(), copyChan)
 
 	// Receive the results
 	toCopy := <-copyChan
@@ -250,7 +251,7 @@ func TestCopierFinder(t *testing.T) {
 	go f.copierRoutine(copyChan, pullChan, finisherChan)
 	defer close(copyChan)
 
-	f.handleFile(requiredFile, f.fset.Snapshot(), copyChan)
+	f.handleFile(requiredFile, "", f.fset.Snapshot(), copyChan)
 
 	timeout := time.After(10 * time.Second)
 	pulls := make([]pullBlockState, 4)
@@ -382,7 +383,7 @@ func TestWeakHash(t *testing.T) {
 
 	// Test 1 - no weak hashing, file gets fully repulled (`expectBlocks` pulls).
 	fo.WeakHashThresholdPct = 101
-	fo.handleFile(desiredFile, fo.fset.Snapshot(), copyChan)
+	fo.handleFile(desiredFile, "", fo.fset.Snapshot(), copyChan)
 
 	var pulls []pullBlockState
 	timeout := time.After(10 * time.Second)
@@ -411,7 +412,7 @@ func TestWeakHash(t *testing.T) {
 
 	// Test 2 - using weak hash, expectPulls blocks pulled.
 	fo.WeakHashThresholdPct = -1
-	fo.handleFile(desiredFile, fo.fset.Snapshot(), copyChan)
+	fo.handleFile(desiredFile, "", fo.fset.Snapshot(), copyChan)
 
 	pulls = pulls[:0]
 	for len(pulls) < expectPulls {
@@ -430,6 +431,32 @@ func TestWeakHash(t *testing.T) {
 	if finish.copyOriginShifted != expectShifted {
 		t.Errorf("did not copy %d shifted", expectShifted)
 	}
+	if err := ffs.Remove(tempFile); err != nil {
+		t.Fatal(err)
+	}
+
+	// Test 3 - the same, for a file moved to another name, with the old
+	// one as the origin.
+	movedFile := desiredFile
+	movedFile.Name = "moved"
+	fo.handleFile(movedFile, "weakhash", fo.fset.Snapshot(), copyChan)
+
+	pulls = pulls[:0]
+	for len(pulls) < expectPulls {
+		select {
+		case pull := <-pullChan:
+			pulls = append(pulls, pull)
+		case <-time.After(10 * time.Second):
+			t.Errorf("timed out, got %d pulls expected %d", len(pulls), expectPulls)
+		}
+	}
+
+	finish = <-finisherChan
+	cleanupSharedPullerState(finish)
+
+	if finish.copyOriginShifted != expectShifted {
+		t.Errorf("did not copy %d shifted from the origin", expectShifted)
+	}
 }
 
 // Test that updating a file removes its old blocks from the blockmap
@@ -504,7 +531,7 @@ func TestDeregisterOnFailInCopy(t *testing.T) {
 		close(finisherChan)
 	}()
 
-	f.handleFile(file, snap, copyChan)
+	f.handleFile(file, "", snap, copyChan)
 
 	// Receive a block at puller, to indicate that at least a single copier
 	// loop has been performed.
@@ -610,7 +637,7 @@ func TestDeregisterOnFailInPull(t *testing.T) {
 		close(finisherChan)
 	}()
 
-	f.handleFile(file, snap, copyChan)
+	f.handleFile(file, "", snap, copyChan)
 
 	// Receive at finisher, we should error out as puller has nowhere to pull
 	// from.
@@ -866,7 +893,7 @@ func TestCopyOwner(t *testing.T) {
 		close(finisherChan)
 	}()
 
-	f.handleFile(file, snap, copierChan)
+	f.handleFile(file, "", snap, copierChan)
 	<-dbUpdateChan
 
 	info, err = f.fs.Lstat("foo/bar/baz")
@@ -1035,3 +1062,32 @@ func startCopier(f *sendReceiveFolder, pullChan chan<- pullBlockState, finisherC
 	}()
 	return copyChan, wg
 }
+
+func TestSortRarestFirst(t *testing.T) {
+	m, f := setupSendReceiveFolder()
+	defer cleanupSRFolder(f, m)
+
+	file := protocol.FileInfo{
+		Name:    "file",
+		Version: protocol.Vector{}.Update(device1.Short()),
+	}
+	for i := 0; i < 4; i++ {
+		file.Blocks = append(file.Blocks, protocol.BlockInfo{Offset: int64(i * protocol.MinBlockSize), Size: protocol.MinBlockSize})
+	}
+
+	m.pmut.Lock()
+	m.deviceDownloads[device1] = newDeviceDownloadState()
+	m.pmut.Unlock()
+	m.deviceDownloads[device1].Update(f.folderID, []protocol.FileDownloadProgressUpdate{
+		{UpdateType: protocol.UpdateTypeAppend, Name: file.Name, Version: file.Version, BlockIndexes: []int32{0, 2}},
+	})
+
+	blocks := append([]protocol.BlockInfo(nil), file.Blocks...)
+	f.sortRarestFirst(file, blocks)
+
+	for i, offset := range []int{1, 3, 0, 2} {
+		if exp := int64(offset * protocol.MinBlockSize); blocks[i].Offset != exp {
+			t.Errorf("block %d has offset %d, expected %d", i, blocks[i].Offset, exp)
+		}
+	}
+}
@@ -9,12 +9,14 @@ package model
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"strings"
 	"time"
 
 	"github.com/thejerf/suture"
 
 	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/db"
 	"github.com/syncthing/syncthing/lib/events"
 	"github.com/syncthing/syncthing/lib/protocol"
 	"github.com/syncthing/syncthing/lib/sync"
@@ -26,11 +28,14 @@ const minSummaryInterval = time.Minute
 type FolderSummaryService interface {
 	suture.Service
 	Summary(folder string) (map[string]interface{}, error)
+	SubtreeSummary(folder, prefix string) (map[string]interface{}, error)
+	AggregateSummary() map[string]interface{}
 	OnEventRequest()
 }
 
-// The folderSummaryService adds summary information events (FolderSummary and
-// FolderCompletion) into the event stream at certain intervals.
+// The folderSummaryService adds summary information events (FolderSummary,
+// CompletionSummary, FolderCompletion and AggregateSummary) into the event
+// stream at certain intervals.
 type folderSummaryService struct {
 	*suture.Supervisor
 
@@ -47,6 +52,20 @@ type folderSummaryService struct {
 	// For keeping track of when the last event request on the API was
 	lastEventReq    time.Time
 	lastEventReqMut sync.Mutex
+
+	// The last emitted summary per folder and completion per folder and
+	// device, to detect duplicates, and the last totals per folder to make
+	// up the aggregate summary from. Only accessed from calculateSummaries.
+	lastSummaries   map[string]map[string]interface{}
+	lastCompletions map[string]map[string]interface{}
+	lastTotals      map[string]folderTotals
+}
+
+// folderTotals is what a folder adds to the aggregate summary.
+type folderTotals struct {
+	global, local, need db.Counts
+	errors              int
+	state               string
 }
 
 func NewFolderSummaryService(cfg config.Wrapper, m Model, id protocol.DeviceID, evLogger events.Logger) FolderSummaryService {
@@ -62,6 +81,9 @@ func NewFolderSummaryService(cfg config.Wrapper, m Model, id protocol.DeviceID,
 		folders:         make(map[string]struct{}),
 		foldersMut:      sync.NewMutex(),
 		lastEventReqMut: sync.NewMutex(),
+		lastSummaries:   make(map[string]map[string]interface{}),
+		lastCompletions: make(map[string]map[string]interface{}),
+		lastTotals:      make(map[string]folderTotals),
 	}
 
 	service.Add(util.AsService(service.listenForUpdates, fmt.Sprintf("%s/listenForUpdates", service)))
@@ -75,17 +97,24 @@ func (c *folderSummaryService) String() string {
 }
 
 func (c *folderSummaryService) Summary(folder string) (map[string]interface{}, error) {
+	res, _, err := c.summary(folder)
+	return res, err
+}
+
+// summary returns the summary of the folder, and its totals for the
+// aggregate summary.
+func (c *folderSummaryService) summary(folder string) (map[string]interface{}, folderTotals, error) {
 	var res = make(map[string]interface{})
 
 	snap, err := c.model.DBSnapshot(folder)
 	if err != nil {
-		return nil, err
+		return nil, folderTotals{}, err
 	}
 
 	errors, err := c.model.FolderErrors(folder)
 	if err != nil && err != ErrFolderPaused && err != errFolderNotRunning {
 		// Stats from the db can still be obtained if the folder is just paused/being started
-		return nil, err
+		return nil, folderTotals{}, err
 	}
 	res["errors"] = len(errors)
 	res["pullErrors"] = len(errors) // deprecated
@@ -98,19 +127,14 @@ func (c *folderSummaryService) Summary(folder string) (map[string]interface{}, e
 	local := snap.LocalSize()
 	res["localFiles"], res["localDirectories"], res["localSymlinks"], res["localDeleted"], res["localBytes"], res["localTotalItems"] = local.Files, local.Directories, local.Symlinks, local.Deleted, local.Bytes, local.TotalItems()
 
-	need := snap.NeedSize()
-	need.Bytes -= c.model.FolderProgressBytesCompleted(folder)
-	// This may happen if we are in progress of pulling files that were
-	// deleted globally after the pull started.
-	if need.Bytes < 0 {
-		need.Bytes = 0
-	}
+	need := c.needSize(folder, snap)
 	res["needFiles"], res["needDirectories"], res["needSymlinks"], res["needDeletes"], res["needBytes"], res["needTotalItems"] = need.Files, need.Directories, need.Symlinks, need.Deleted, need.Bytes, need.TotalItems()
 
 	fcfg, ok := c.cfg.Folder(folder)
 
 	if ok && fcfg.IgnoreDelete {
 		res["needDeletes"] = 0
+		need.Deleted = 0
 	}
 
 	if ok && fcfg.Type == config.FolderTypeReceiveOnly {
@@ -127,7 +151,8 @@ func (c *folderSummaryService) Summary(folder string) (map[string]interface{}, e
 
 	res["inSyncFiles"], res["inSyncBytes"] = global.Files-need.Files, global.Bytes-need.Bytes
 
-	res["state"], res["stateChanged"], err = c.model.State(folder)
+	state, stateChanged, err := c.model.State(folder)
+	res["state"], res["stateChanged"] = state, stateChanged
 	if err != nil {
 		res["error"] = err.Error()
 	}
@@ -147,14 +172,126 @@ func (c *folderSummaryService) Summary(folder string) (map[string]interface{}, e
 		}
 	}
 
+	res["lastScanDuration"] = 0.0
+	if history, err := c.model.FolderScanHistory(folder); err == nil && len(history) > 0 {
+		res["lastScanDuration"] = history[len(history)-1].Duration
+	}
+
 	err = c.model.WatchError(folder)
 	if err != nil {
 		res["watchError"] = err.Error()
 	}
 
+	return res, folderTotals{global: global, local: local, need: need, errors: len(errors), state: state}, nil
+}
+
+// folderTotals returns what the folder adds to the aggregate summary.
+func (c *folderSummaryService) folderTotals(fcfg config.FolderConfiguration) (folderTotals, bool) {
+	snap, err := c.model.DBSnapshot(fcfg.ID)
+	if err != nil {
+		return folderTotals{}, false
+	}
+	defer snap.Release()
+
+	t := folderTotals{
+		global: snap.GlobalSize(),
+		local:  snap.LocalSize(),
+		need:   c.needSize(fcfg.ID, snap),
+	}
+	if fcfg.IgnoreDelete {
+		t.need.Deleted = 0
+	}
+	if ferrs, err := c.model.FolderErrors(fcfg.ID); err == nil {
+		t.errors = len(ferrs)
+	}
+	t.state, _, _ = c.model.State(fcfg.ID)
+	return t, true
+}
+
+// AggregateSummary returns the combined sizes of all folders, along with the
+// number of folders in each state and the total number of errors.
+func (c *folderSummaryService) AggregateSummary() map[string]interface{} {
+	return c.aggregateSummary(c.folderTotals)
+}
+
+// aggregateSummary combines the totals of the folders as returned by the
+// given function.
+func (c *folderSummaryService) aggregateSummary(totals func(config.FolderConfiguration) (folderTotals, bool)) map[string]interface{} {
+	var global, local, need db.Counts
+	states := make(map[string]int)
+	errors := 0
+
+	folders := c.cfg.FolderList()
+	for _, fcfg := range folders {
+		if fcfg.Paused {
+			states["paused"]++
+			continue
+		}
+
+		t, ok := totals(fcfg)
+		if !ok {
+			continue
+		}
+		global = global.Add(t.global)
+		local = local.Add(t.local)
+		need = need.Add(t.need)
+		errors += t.errors
+		if t.state != "" {
+			states[t.state]++
+		}
+	}
+
+	res := make(map[string]interface{})
+	res["folders"] = len(folders)
+	res["folderStates"] = states
+	res["errors"] = errors
+	res["globalFiles"], res["globalDirectories"], res["globalSymlinks"], res["globalDeleted"], res["globalBytes"], res["globalTotalItems"] = global.Files, global.Directories, global.Symlinks, global.Deleted, global.Bytes, global.TotalItems()
+	res["localFiles"], res["localDirectories"], res["localSymlinks"], res["localDeleted"], res["localBytes"], res["localTotalItems"] = local.Files, local.Directories, local.Symlinks, local.Deleted, local.Bytes, local.TotalItems()
+	res["needFiles"], res["needDirectories"], res["needSymlinks"], res["needDeletes"], res["needBytes"], res["needTotalItems"] = need.Files, need.Directories, need.Symlinks, need.Deleted, need.Bytes, need.TotalItems()
+	res["inSyncFiles"], res["inSyncBytes"] = global.Files-need.Files, global.Bytes-need.Bytes
+	return res
+}
+
+// SubtreeSummary returns the global, local and needed counts of the item at
+// the prefix in the folder and the items below it, as they're in the
+// database.
+func (c *folderSummaryService) SubtreeSummary(folder, prefix string) (map[string]interface{}, error) {
+	snap, err := c.model.DBSnapshot(folder)
+	if err != nil {
+		return nil, err
+	}
+	defer snap.Release()
+
+	prefix = strings.Trim(prefix, "/")
+	global := snap.GlobalSizePrefixed(prefix)
+	local := snap.LocalSizePrefixed(prefix)
+	need := snap.NeedSizePrefixed(prefix)
+	if fcfg, ok := c.cfg.Folder(folder); ok && fcfg.IgnoreDelete {
+		need.Deleted = 0
+	}
+
+	res := make(map[string]interface{})
+	res["folder"] = folder
+	res["prefix"] = prefix
+	res["globalFiles"], res["globalDirectories"], res["globalSymlinks"], res["globalDeleted"], res["globalBytes"], res["globalTotalItems"] = global.Files, global.Directories, global.Symlinks, global.Deleted, global.Bytes, global.TotalItems()
+	res["localFiles"], res["localDirectories"], res["localSymlinks"], res["localDeleted"], res["localBytes"], res["localTotalItems"] = local.Files, local.Directories, local.Symlinks, local.Deleted, local.Bytes, local.TotalItems()
+	res["needFiles"], res["needDirectories"], res["needSymlinks"], res["needDeletes"], res["needBytes"], res["needTotalItems"] = need.Files, need.Directories, need.Symlinks, need.Deleted, need.Bytes, need.TotalItems()
 	return res, nil
 }
 
+// needSize returns what we need of the folder, not counting the data that is
+// already downloaded by the puller.
+func (c *folderSummaryService) needSize(folder string, snap *db.Snapshot) db.Counts {
+	need := snap.NeedSize()
+	need.Bytes -= c.model.FolderProgressBytesCompleted(folder)
+	// This may happen if we are in progress of pulling files that were
+	// deleted globally after the pull started.
+	if need.Bytes < 0 {
+		need.Bytes = 0
+	}
+	return need
+}
+
 func (c *folderSummaryService) OnEventRequest() {
 	c.lastEventReqMut.Lock()
 	c.lastEventReq = time.Now()
@@ -205,7 +342,7 @@ func (c *folderSummaryService) processUpdate(ev events.Event) {
 		return
 
 	case events.DownloadProgress:
-		data := ev.Data.(map[string]map[string]*pullerProgress)
+		data := ev.Data.(map[string]map[string]*PullerProgress)
 		c.foldersMut.Lock()
 		for folder := range data {
 			c.folders[folder] = struct{}{}
@@ -263,9 +400,13 @@ func (c *folderSummaryService) calculateSummaries(ctx context.Context) {
 		select {
 		case <-pump.C:
 			t0 := time.Now()
-			for _, folder := range c.foldersToHandle() {
+			folders := c.foldersToHandle()
+			for _, folder := range folders {
 				c.sendSummary(folder)
 			}
+			if len(folders) > 0 {
+				c.sendAggregateSummary()
+			}
 
 			// We don't want to spend all our time calculating summaries. Lets
 			// set an arbitrary limit at not spending more than about 30% of
@@ -275,6 +416,7 @@ func (c *folderSummaryService) calculateSummaries(ctx context.Context) {
 
 		case folder := <-c.immediate:
 			c.sendSummary(folder)
+			c.sendAggregateSummary()
 
 		case <-ctx.Done():
 			return
@@ -310,15 +452,23 @@ func (c *folderSummaryService) foldersToHandle() []string {
 func (c *folderSummaryService) sendSummary(folder string) {
 	// The folder summary contains how many bytes, files etc
 	// are in the folder and how in sync we are.
-	data, err := c.Summary(folder)
+	data, totals, err := c.summary(folder)
 	if err != nil {
 		return
 	}
-	c.evLogger.Log(events.FolderSummary, map[string]interface{}{
-		"folder":  folder,
-		"summary": data,
-	})
+	c.lastTotals[folder] = totals
+	suppress := c.cfg.Options().SuppressDuplicateSummaries
+	if !suppress || !reflect.DeepEqual(data, c.lastSummaries[folder]) {
+		c.evLogger.Log(events.FolderSummary, map[string]interface{}{
+			"folder":  folder,
+			"summary": data,
+		})
+		c.lastSummaries[folder] = data
+	}
 
+	legacy := c.cfg.Options().LegacyCompletionEvents
+	completions := make(map[string]map[string]interface{})
+	changed := false
 	for _, devCfg := range c.cfg.Folders()[folder].Devices {
 		if devCfg.DeviceID.Equals(c.id) {
 			// We already know about ourselves.
@@ -334,6 +484,42 @@ func (c *folderSummaryService) sendSummary(folder string) {
 		comp := c.model.Completion(devCfg.DeviceID, folder).Map()
 		comp["folder"] = folder
 		comp["device"] = devCfg.DeviceID.String()
-		c.evLogger.Log(events.FolderCompletion, comp)
+		completions[devCfg.DeviceID.String()] = comp
+		key := folder + "/" + devCfg.DeviceID.String()
+		if suppress && reflect.DeepEqual(comp, c.lastCompletions[key]) {
+			continue
+		}
+		changed = true
+		if legacy {
+			c.evLogger.Log(events.FolderCompletion, comp)
+		}
+		c.lastCompletions[key] = comp
+	}
+
+	// One event for all the devices, so that there aren't as many events
+	// as devices when there are many.
+	if len(completions) > 0 && (changed || !suppress) {
+		c.evLogger.Log(events.CompletionSummary, map[string]interface{}{
+			"folder":      folder,
+			"completions": completions,
+		})
 	}
 }
+
+// sendAggregateSummary sends the summary event covering all folders. It's
+// made up of the totals kept from the last summary of each folder, so that
+// only folders not summarized before are read from the database.
+func (c *folderSummaryService) sendAggregateSummary() {
+	summary := c.aggregateSummary(func(fcfg config.FolderConfiguration) (folderTotals, bool) {
+		t, ok := c.lastTotals[fcfg.ID]
+		if !ok {
+			if t, ok = c.folderTotals(fcfg); ok {
+				c.lastTotals[fcfg.ID] = t
+			}
+		}
+		return t, ok
+	})
+	c.evLogger.Log(events.AggregateSummary, map[string]interface{}{
+		"summary": summary,
+	})
+}
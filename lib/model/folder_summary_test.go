@@ -0,0 +1,82 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/events"
+)
+
+func TestSummarySuppressDuplicates(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	opts := w.Options()
+	opts.SuppressDuplicateSummaries = true
+	w.SetOptions(opts)
+	m := setupModel(w)
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	fss := NewFolderSummaryService(w, m, myID, m.evLogger).(*folderSummaryService)
+	sub := m.evLogger.Subscribe(events.FolderSummary)
+	defer sub.Unsubscribe()
+
+	fss.sendSummary(fcfg.ID)
+	if _, err := sub.Poll(time.Second); err != nil {
+		t.Fatal("Expected a first summary:", err)
+	}
+
+	fss.sendSummary(fcfg.ID)
+	if ev, err := sub.Poll(100 * time.Millisecond); err != events.ErrTimeout {
+		t.Fatal("Expected no summary when nothing changed, got", ev, err)
+	}
+
+	opts.SuppressDuplicateSummaries = false
+	w.SetOptions(opts)
+	fss.sendSummary(fcfg.ID)
+	if _, err := sub.Poll(time.Second); err != nil {
+		t.Fatal("Expected a summary with suppression disabled:", err)
+	}
+}
+
+func TestCompletionSummary(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	opts := w.Options()
+	opts.LegacyCompletionEvents = false
+	w.SetOptions(opts)
+	m := setupModel(w)
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	addFakeConn(m, device1)
+
+	fss := NewFolderSummaryService(w, m, myID, m.evLogger).(*folderSummaryService)
+	sub := m.evLogger.Subscribe(events.CompletionSummary | events.FolderCompletion)
+	defer sub.Unsubscribe()
+
+	fss.sendSummary(fcfg.ID)
+	for {
+		ev, err := sub.Poll(time.Second)
+		if err != nil {
+			t.Fatal("Expected a completion summary:", err)
+		}
+		if ev.Type == events.FolderCompletion {
+			t.Fatal("Unexpected legacy completion event")
+		}
+		if ev.Type != events.CompletionSummary {
+			continue
+		}
+		data := ev.Data.(map[string]interface{})
+		if data["folder"] != fcfg.ID {
+			t.Fatalf("Wrong folder %v", data["folder"])
+		}
+		comps := data["completions"].(map[string]map[string]interface{})
+		if _, ok := comps[device1.String()]; !ok || len(comps) != 1 {
+			t.Fatalf("Expected the completion of %v only, got %v", device1, comps)
+		}
+		break
+	}
+}
@@ -23,6 +23,7 @@ const (
 	FolderSyncPreparing
 	FolderSyncing
 	FolderError
+	FolderMaintenance
 )
 
 func (s folderState) String() string {
@@ -41,6 +42,8 @@ func (s folderState) String() string {
 		return "syncing"
 	case FolderError:
 		return "error"
+	case FolderMaintenance:
+		return "maintenance"
 	default:
 		return "unknown"
 	}
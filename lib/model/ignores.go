@@ -0,0 +1,442 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/ignore"
+	"github.com/syncthing/syncthing/lib/osutil"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+var (
+	ErrIgnoresChanged  = errors.New("the ignore patterns changed since they were read")
+	ErrIgnoresConflict = errors.New("patterns to add contradict existing ones")
+)
+
+// IgnoreEditResult is the outcome of an edit of the ignore patterns of a
+// folder.
+type IgnoreEditResult struct {
+	Version   string   `json:"version"` // of the patterns after the edit, or the current ones if it failed
+	Added     []string `json:"added"`
+	Removed   []string `json:"removed"`
+	Present   []string `json:"present"`   // to add, but already there
+	Missing   []string `json:"missing"`   // to remove, but not there
+	Conflicts []string `json:"conflicts"` // to add, but there negated or the other way around
+}
+
+// EditIgnores adds and removes ignore patterns of the folder, as lines of
+// its .stignore, leaving the rest of it as it is. Nothing is changed if the
+// version isn't empty and not that of the current patterns, or if a
+// pattern to add contradicts an existing one, like "!foo" for "foo". The
+// folder is rescanned once if anything changed.
+func (m *model) EditIgnores(folder, version string, add, remove []string) (IgnoreEditResult, error) {
+	cfg, err := m.ignoresFolderConfig(folder)
+	if err != nil {
+		return IgnoreEditResult{}, err
+	}
+
+	m.ignoresMut.Lock()
+	res, err := editIgnores(cfg.Filesystem(), version, add, remove)
+	changed := len(res.Added) > 0 || len(res.Removed) > 0
+	if err == nil && changed && cfg.SyncIgnores {
+		err = shareIgnores(cfg.Filesystem())
+	}
+	m.ignoresMut.Unlock()
+	if err != nil || !changed {
+		return res, err
+	}
+
+	return res, m.scanForIgnores(folder, nil)
+}
+
+// UnignoreResult is the outcome of removing ignore patterns of a folder,
+// with what was rescanned for it.
+type UnignoreResult struct {
+	IgnoreEditResult
+	Rescanned []string `json:"rescanned"` // the subdirectories, unless the whole folder was
+	FullScan  bool     `json:"fullScan"`
+}
+
+// Unignore removes ignore patterns of the folder like EditIgnores, but
+// then only rescans the parts of the folder they applied to, which can be
+// told for rooted patterns like "/photos/2019". If any of the removed
+// patterns can match anywhere, the whole folder is rescanned.
+func (m *model) Unignore(folder, version string, patterns []string) (UnignoreResult, error) {
+	cfg, err := m.ignoresFolderConfig(folder)
+	if err != nil {
+		return UnignoreResult{}, err
+	}
+
+	m.ignoresMut.Lock()
+	edit, err := editIgnores(cfg.Filesystem(), version, nil, patterns)
+	if err == nil && len(edit.Removed) > 0 && cfg.SyncIgnores {
+		err = shareIgnores(cfg.Filesystem())
+	}
+	m.ignoresMut.Unlock()
+	res := UnignoreResult{IgnoreEditResult: edit}
+	if err != nil || len(edit.Removed) == 0 {
+		return res, err
+	}
+
+	res.Rescanned, res.FullScan = unignoredSubdirs(edit.Removed)
+	return res, m.scanForIgnores(folder, res.Rescanned)
+}
+
+// unignoredSubdirs returns the subdirectories that hold everything the
+// removed patterns applied to, or true if that's the whole folder.
+func unignoredSubdirs(removed []string) ([]string, bool) {
+	var subdirs []string
+	for _, line := range removed {
+		if strings.HasPrefix(line, "//") {
+			// Comments apply to nothing
+			continue
+		}
+		root, ok := ignore.PatternRoot(line)
+		if !ok {
+			return nil, true
+		}
+		subdirs = append(subdirs, osutil.NativeFilename(root))
+	}
+	if len(subdirs) == 0 {
+		// Nothing to scan, but scanning nothing means everything.
+		return nil, true
+	}
+	return subdirs, false
+}
+
+// ignoreStat returns what the ignore predicates are evaluated against for
+// the file, or nil for a deleted one, which is matched by name only.
+func ignoreStat(file db.FileIntf) *ignore.Stat {
+	if file.IsDeleted() {
+		return nil
+	}
+	return &ignore.Stat{
+		Size:    file.FileSize(),
+		ModTime: file.ModTime(),
+		IsDir:   file.IsDirectory(),
+	}
+}
+
+// deviceIgnores returns the matcher of the ignore patterns the folder has
+// just for the device, or nil if there are none.
+func deviceIgnores(cfg config.FolderConfiguration, devCfg config.FolderDeviceConfiguration) *ignore.Matcher {
+	if len(devCfg.IgnorePatterns) == 0 {
+		return nil
+	}
+	matcher := ignore.New(cfg.Filesystem(), ignore.WithCache(false))
+	if err := matcher.Parse(strings.NewReader(strings.Join(devCfg.IgnorePatterns, "\n")), ""); err != nil {
+		l.Warnf("Not using the ignore patterns of folder %s for device %v: %v", cfg.Description(), devCfg.DeviceID, err)
+		return nil
+	}
+	return matcher
+}
+
+// deviceIgnoresChanged returns whether the ignore patterns of the folder
+// for the device changed since the index was last sent to it, remembering
+// the current ones.
+func (m *model) deviceIgnoresChanged(folder string, device protocol.DeviceID, matcher *ignore.Matcher) bool {
+	hash := ""
+	if matcher != nil {
+		hash = matcher.Hash()
+	}
+	return m.deviceStateChanged("deviceIgnores/"+folder+"/"+device.String(), hash)
+}
+
+// deviceDeniedPathsChanged returns whether the paths of the folder denied
+// to the device changed since the index was last sent to it, remembering
+// the current ones.
+func (m *model) deviceDeniedPathsChanged(folder string, devCfg config.FolderDeviceConfiguration) bool {
+	return m.deviceStateChanged("deviceDeniedPaths/"+folder+"/"+devCfg.DeviceID.String(), strings.Join(devCfg.DeniedPaths, "\n"))
+}
+
+func (m *model) deviceStateChanged(key, value string) bool {
+	miscDB := db.NewMiscDataNamespace(m.db)
+	prev, _, err := miscDB.String(key)
+	if err != nil || prev == value {
+		return false
+	}
+	if err := miscDB.PutString(key, value); err != nil {
+		l.Warnln("Storing device state:", err)
+	}
+	return true
+}
+
+// shareIgnores copies .stignore to the synced file, which unlike
+// .stignore is kept even when there are no patterns, so that removing all
+// of them reaches the other devices too.
+func shareIgnores(filesystem fs.Filesystem) error {
+	lines, err := ignore.ReadIgnores(filesystem, ".stignore")
+	if err != nil {
+		return err
+	}
+	fd, err := osutil.CreateAtomicFilesystem(filesystem, ignore.SyncedIgnoresFile)
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		fmt.Fprintln(fd, line)
+	}
+	return fd.Close()
+}
+
+// syncIgnoreFiles reconciles .stignore with the synced copy of the
+// patterns: a missing copy is created, and otherwise the newer of the two
+// replaces the other. It returns true if .stignore was replaced.
+func syncIgnoreFiles(filesystem fs.Filesystem) (bool, error) {
+	syncedInfo, err := filesystem.Lstat(ignore.SyncedIgnoresFile)
+	if fs.IsNotExist(err) {
+		return false, shareIgnores(filesystem)
+	} else if err != nil {
+		return false, err
+	}
+
+	local, err := ignore.ReadIgnores(filesystem, ".stignore")
+	if err != nil {
+		return false, err
+	}
+	synced, err := ignore.ReadIgnores(filesystem, ignore.SyncedIgnoresFile)
+	if err != nil {
+		return false, err
+	}
+	if ignore.Version(local) == ignore.Version(synced) {
+		return false, nil
+	}
+
+	if localInfo, err := filesystem.Lstat(".stignore"); err == nil && localInfo.ModTime().After(syncedInfo.ModTime()) {
+		return false, shareIgnores(filesystem)
+	}
+	return true, ignore.WriteIgnores(filesystem, ".stignore", synced)
+}
+
+func editIgnores(filesystem fs.Filesystem, version string, add, remove []string) (IgnoreEditResult, error) {
+	lines, err := ignore.ReadIgnores(filesystem, ".stignore")
+	if err != nil {
+		return IgnoreEditResult{}, err
+	}
+	res := IgnoreEditResult{Version: ignore.Version(lines)}
+	if version != "" && version != res.Version {
+		return res, ErrIgnoresChanged
+	}
+
+	toRemove := make(map[string]bool, len(remove))
+	for _, pattern := range remove {
+		toRemove[strings.TrimSpace(pattern)] = true
+	}
+	kept := make([]string, 0, len(lines)+len(add))
+	removed := make(map[string]bool, len(remove))
+	for _, line := range lines {
+		if line == "" || !toRemove[line] {
+			kept = append(kept, line)
+			continue
+		}
+		if !removed[line] {
+			res.Removed = append(res.Removed, line)
+			removed[line] = true
+		}
+	}
+	for pattern := range toRemove {
+		if pattern != "" && !removed[pattern] {
+			res.Missing = append(res.Missing, pattern)
+		}
+	}
+	sort.Strings(res.Missing)
+
+	present := make(map[string]bool, len(kept))
+	for _, line := range kept {
+		present[line] = true
+	}
+	var added []string
+	for _, pattern := range add {
+		pattern = strings.TrimSpace(pattern)
+		switch {
+		case pattern == "":
+		case present[pattern]:
+			res.Present = append(res.Present, pattern)
+		case present[negatedPattern(pattern)]:
+			res.Conflicts = append(res.Conflicts, pattern)
+		default:
+			added = append(added, pattern)
+			present[pattern] = true
+		}
+	}
+	if len(res.Conflicts) > 0 {
+		res.Removed = nil
+		return res, ErrIgnoresConflict
+	}
+	if len(added) == 0 && len(res.Removed) == 0 {
+		return res, nil
+	}
+	kept = append(kept, added...)
+
+	// Don't write what can't be loaded
+	if err := ignore.New(filesystem, ignore.WithCache(false)).Parse(strings.NewReader(strings.Join(kept, "\n")), ".stignore"); err != nil {
+		return IgnoreEditResult{Version: res.Version}, err
+	}
+	if err := ignore.WriteIgnores(filesystem, ".stignore", kept); err != nil {
+		l.Warnln("Saving .stignore:", err)
+		return IgnoreEditResult{Version: res.Version}, err
+	}
+	res.Added = added
+	res.Version = ignore.Version(kept)
+	return res, nil
+}
+
+// ExplainIgnores returns for each of the paths whether it's ignored in the
+// folder, and by which pattern. That's by the given patterns, as lines of a
+// .stignore, or by the current ones if nil.
+func (m *model) ExplainIgnores(folder string, patterns, paths []string) ([]ignore.Explanation, error) {
+	cfg, ok := m.cfg.Folder(folder)
+	if !ok {
+		return nil, errFolderMissing
+	}
+
+	matcher := ignore.New(cfg.Filesystem(), ignore.WithCache(false), ignore.WithGitignore(cfg.HonorGitignore))
+	var err error
+	if patterns == nil {
+		err = matcher.Load(".stignore")
+		if fs.IsNotExist(err) {
+			err = nil
+		}
+	} else {
+		err = matcher.Parse(strings.NewReader(strings.Join(patterns, "\n")), ".stignore")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]ignore.Explanation, len(paths))
+	for i, path := range paths {
+		res[i] = matcher.Explain(osutil.NativeFilename(strings.Trim(path, "/")))
+		res[i].Path = path
+	}
+	return res, nil
+}
+
+// IgnoredUsage is how much of a folder on disk its ignore patterns keep
+// from being synced.
+type IgnoredUsage struct {
+	Files       int                   `json:"files"`
+	Directories int                   `json:"directories"`
+	Bytes       int64                 `json:"bytes"`
+	Patterns    []IgnoredPatternUsage `json:"patterns"` // the most bytes first
+}
+
+// IgnoredPatternUsage is how much is ignored by one pattern. What is in an
+// ignored directory counts for the pattern ignoring the directory.
+type IgnoredPatternUsage struct {
+	Pattern     string `json:"pattern"`
+	Reason      string `json:"reason"`
+	File        string `json:"file,omitempty"` // the file and line number of the pattern
+	Line        int    `json:"line,omitempty"`
+	Files       int    `json:"files"`
+	Directories int    `json:"directories"`
+	Bytes       int64  `json:"bytes"`
+}
+
+func (u *IgnoredPatternUsage) add(info fs.FileInfo) {
+	if info.IsDir() {
+		u.Directories++
+	} else {
+		u.Files++
+		if info.IsRegular() {
+			u.Bytes += info.Size()
+		}
+	}
+}
+
+// IgnoredUsage walks the folder on disk to tell how many files and bytes
+// are ignored in it, by each of the patterns.
+func (m *model) IgnoredUsage(folder string) (IgnoredUsage, error) {
+	cfg, ok := m.cfg.Folder(folder)
+	if !ok {
+		return IgnoredUsage{}, errFolderMissing
+	}
+	if err := cfg.CheckPath(); err != nil {
+		return IgnoredUsage{}, err
+	}
+
+	filesystem := cfg.Filesystem()
+	matcher := ignore.New(filesystem, ignore.WithCache(false), ignore.WithGitignore(cfg.HonorGitignore))
+	if err := matcher.Load(".stignore"); err != nil && !fs.IsNotExist(err) {
+		return IgnoredUsage{}, err
+	}
+	skipIgnoredDirs := matcher.SkipIgnoredDirs()
+
+	byPattern := make(map[string]*IgnoredPatternUsage)
+	err := filesystem.Walk(".", func(path string, info fs.FileInfo, err error) error {
+		if err != nil || path == "." {
+			return nil
+		}
+		if fs.IsInternal(path) || fs.IsTemporary(path) {
+			// Ours, not kept from being synced
+			if info.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		exp := matcher.ExplainStat(path, ignore.StatOf(info))
+		if !exp.Ignored {
+			return nil
+		}
+		key := fmt.Sprintf("%s\x00%s\x00%d\x00%s", exp.File, exp.Pattern, exp.Line, exp.Reason)
+		usage, ok := byPattern[key]
+		if !ok {
+			usage = &IgnoredPatternUsage{Pattern: exp.Pattern, Reason: exp.Reason, File: exp.File, Line: exp.Line}
+			byPattern[key] = usage
+		}
+		usage.add(info)
+		if !info.IsDir() || !skipIgnoredDirs {
+			return nil
+		}
+
+		// Nothing in the directory can be unignored, so all of it is the
+		// pattern's.
+		_ = filesystem.Walk(path, func(sub string, info fs.FileInfo, err error) error {
+			if err == nil && sub != path {
+				usage.add(info)
+			}
+			return nil
+		})
+		return fs.SkipDir
+	})
+	if err != nil {
+		return IgnoredUsage{}, err
+	}
+
+	res := IgnoredUsage{Patterns: make([]IgnoredPatternUsage, 0, len(byPattern))}
+	for _, usage := range byPattern {
+		res.Files += usage.Files
+		res.Directories += usage.Directories
+		res.Bytes += usage.Bytes
+		res.Patterns = append(res.Patterns, *usage)
+	}
+	sort.Slice(res.Patterns, func(a, b int) bool {
+		if res.Patterns[a].Bytes != res.Patterns[b].Bytes {
+			return res.Patterns[a].Bytes > res.Patterns[b].Bytes
+		}
+		return res.Patterns[a].Pattern < res.Patterns[b].Pattern
+	})
+	return res, nil
+}
+
+// negatedPattern returns the pattern with its meaning reversed, by adding
+// or removing the "!" prefix.
+func negatedPattern(pattern string) string {
+	if strings.HasPrefix(pattern, "!") {
+		return pattern[1:]
+	}
+	return "!" + pattern
+}
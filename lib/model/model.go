@@ -11,11 +11,13 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"path/filepath"
 	"reflect"
 	"regexp"
 	"runtime"
+	"sort"
 	"strings"
 	stdsync "sync"
 	"time"
@@ -50,6 +52,7 @@ type service interface {
 	Revert()
 	DelayScan(d time.Duration)
 	SchedulePull()                                    // something relevant changed, we should try a pull
+	ResetBackoff(files []string)                      // retry failed items now, or all of them if none are given
 	Jobs(page, perpage int) ([]string, []string, int) // In progress, Queued, skipped
 	Scan(subs []string) error
 	Serve()
@@ -59,6 +62,11 @@ type service interface {
 	WatchError() error
 	ForceRescan(file protocol.FileInfo) error
 	GetStatistics() (stats.FolderStatistics, error)
+	GetScanHistory() ([]stats.ScanRecord, error)
+	SetMaintenance(enabled bool) error
+	ResumePull() error
+	Operation() (OperationReport, error)
+	CancelOperation() error
 
 	getState() (folderState, time.Time, error)
 }
@@ -78,34 +86,57 @@ type Model interface {
 	ScanFolder(folder string) error
 	ScanFolders() map[string]error
 	ScanFolderSubdirs(folder string, subs []string) error
+	SetFolderMaintenance(folder string, enabled bool) error
+	ResumeFolderPull(folder string) error
 	State(folder string) (string, time.Time, error)
 	FolderErrors(folder string) ([]FileError, error)
 	WatchError(folder string) error
 	Override(folder string)
 	Revert(folder string)
+	FolderOperation(folder string) (OperationReport, error)
+	CancelFolderOperation(folder string) error
 	BringToFront(folder, file string)
+	ResetPullBackoff(folder string, files []string) error
 	GetIgnores(folder string) ([]string, []string, error)
 	SetIgnores(folder string, content []string) error
+	EditIgnores(folder, version string, add, remove []string) (IgnoreEditResult, error)
+	Unignore(folder, version string, patterns []string) (UnignoreResult, error)
+	IgnoredUsage(folder string) (IgnoredUsage, error)
+	ExplainIgnores(folder string, patterns, paths []string) ([]ignore.Explanation, error)
 
 	GetFolderVersions(folder string) (map[string][]versioner.FileVersion, error)
 	RestoreFolderVersions(folder string, versions map[string]time.Time) (map[string]string, error)
+	OpenFolderVersion(folder, file string, version time.Time) (fs.File, error)
+	RestoreFolderVersionTo(folder, file string, version time.Time, target string) error
+	CleanFolderVersions(folder string) (versioner.CleanupResult, error)
 
 	DBSnapshot(folder string) (*db.Snapshot, error)
 	NeedFolderFiles(folder string, page, perpage int) ([]db.FileInfoTruncated, []db.FileInfoTruncated, []db.FileInfoTruncated)
+	NeedReasons(folder string, files []string) map[string]NeedReason
+	RemoteNeedReason(folder string, device protocol.DeviceID) NeedReason
 	FolderProgressBytesCompleted(folder string) int64
+	FolderProgress(folder string) map[string]*PullerProgress
 
 	CurrentFolderFile(folder string, file string) (protocol.FileInfo, bool)
 	CurrentGlobalFile(folder string, file string) (protocol.FileInfo, bool)
 	Availability(folder string, file protocol.FileInfo, block protocol.BlockInfo) []Availability
+	FileContent(ctx context.Context, folder string, file protocol.FileInfo) (io.Reader, error)
+	RestoreDeletedFiles(ctx context.Context, folder string, files []string) (map[string]string, error)
+	NormalizeFolder(folder string, dryRun bool) ([]NormalizationFix, error)
+	PullPlan(folder string) (PullPlan, error)
+	FolderActivity(folder, prefix string, levels int) ([]DirectoryActivity, error)
 
 	Completion(device protocol.DeviceID, folder string) FolderCompletion
 	ConnectionStats() map[string]interface{}
 	DeviceStatistics() (map[string]stats.DeviceStatistics, error)
 	FolderStatistics() (map[string]stats.FolderStatistics, error)
+	FolderScanHistory(folder string) ([]stats.ScanRecord, error)
 	UsageReportingStats(version int, preview bool) map[string]interface{}
 
 	StartDeadlockDetector(timeout time.Duration)
 	GlobalDirectoryTree(folder, prefix string, levels int, dirsonly bool) map[string]interface{}
+	GlobalDirectoryList(folder, prefix string, dirsonly bool, sortBy DirectorySort, reverse bool, offset, limit int) (DirectoryPage, error)
+	GlobalDirectoryListContinue(token string, limit int) (DirectoryPage, error)
 }
 
 type model struct {
@@ -123,6 +154,8 @@ type model struct {
 	// constant or concurrency safe fields
 	finder            *db.BlockFinder
 	progressEmitter   *ProgressEmitter
+	browseCursors     *browseCursors
+	activity          *activityTracker
 	shortID           protocol.ShortID
 	cacheIgnoredFiles bool
 	// globalRequestLimiter limits the amount of data in concurrent incoming
@@ -131,6 +164,10 @@ type model struct {
 	// folderIOLimiter limits the number of concurrent I/O heavy operations,
 	// such as scans and pulls.
 	folderIOLimiter *byteSemaphore
+	// folderPullLimiter limits the number of folders pulling at once.
+	folderPullLimiter *byteSemaphore
+	// ignoresMut serializes the changes of .stignore files made by us.
+	ignoresMut sync.Mutex
 
 	// fields protected by fmut
 	fmut               sync.RWMutex
@@ -142,6 +179,7 @@ type model struct {
 	folderRunnerTokens map[string][]suture.ServiceToken                       // folder -> tokens for puller or scanner
 	folderRestartMuts  syncMutexMap                                           // folder -> restart mutex
 	folderVersioners   map[string]versioner.Versioner                         // folder -> versioner (may be nil)
+	folderMarkerIDs    map[string]string                                      // folder -> marker ID (empty if unknown)
 
 	// fields protected by pmut
 	pmut                sync.RWMutex
@@ -150,7 +188,11 @@ type model struct {
 	closed              map[protocol.DeviceID]chan struct{}
 	helloMessages       map[protocol.DeviceID]protocol.HelloResult
 	deviceDownloads     map[protocol.DeviceID]*deviceDownloadState
+	deviceRates         map[protocol.DeviceID]*deviceTransferRates
 	remotePausedFolders map[protocol.DeviceID][]string // deviceID -> folders
+	mismatchedFolders   map[protocol.DeviceID][]string // deviceID -> folders with unrelated contents
+	clockSkews          map[protocol.DeviceID]time.Duration
+	remoteMtimeWindows  map[protocol.DeviceID]map[string]time.Duration // deviceID -> folder -> announced mtime precision
 
 	foldersRunning int32 // for testing only
 }
@@ -162,14 +204,16 @@ var (
 )
 
 var (
-	errDeviceUnknown     = errors.New("unknown device")
-	errDevicePaused      = errors.New("device is paused")
-	errDeviceIgnored     = errors.New("device is ignored")
-	ErrFolderPaused      = errors.New("folder is paused")
-	errFolderNotRunning  = errors.New("folder is not running")
-	errFolderMissing     = errors.New("no such folder")
-	errNetworkNotAllowed = errors.New("network not allowed")
-	errNoVersioner       = errors.New("folder has no versioner")
+	errDeviceUnknown       = errors.New("unknown device")
+	errDevicePaused        = errors.New("device is paused")
+	errDeviceIgnored       = errors.New("device is ignored")
+	ErrFolderPaused        = errors.New("folder is paused")
+	errFolderNotRunning    = errors.New("folder is not running")
+	errFolderMissing       = errors.New("no such folder")
+	errNetworkNotAllowed   = errors.New("network not allowed")
+	errNoVersioner         = errors.New("folder has no versioner")
+	errNoVersionCleanup    = errors.New("versioner does not clean up versions")
+	errFolderInMaintenance = errors.New("folder is in maintenance")
 	// errors about why a connection is closed
 	errIgnoredFolderRemoved = errors.New("folder no longer ignored")
 	errReplacingConnection  = errors.New("replacing connection")
@@ -200,10 +244,14 @@ func NewModel(cfg config.Wrapper, id protocol.DeviceID, clientName, clientVersio
 		// constant or concurrency safe fields
 		finder:               db.NewBlockFinder(ldb),
 		progressEmitter:      NewProgressEmitter(cfg, evLogger),
+		browseCursors:        newBrowseCursors(),
+		activity:             newActivityTracker(),
 		shortID:              id.Short(),
 		cacheIgnoredFiles:    cfg.Options().CacheIgnoredFiles,
 		globalRequestLimiter: newByteSemaphore(1024 * cfg.Options().MaxConcurrentIncomingRequestKiB()),
 		folderIOLimiter:      newByteSemaphore(cfg.Options().MaxFolderConcurrency()),
+		folderPullLimiter:    newByteSemaphore(cfg.Options().MaxSyncingFolders()),
+		ignoresMut:           sync.NewMutex(),
 
 		// fields protected by fmut
 		fmut:               sync.NewRWMutex(),
@@ -214,6 +262,7 @@ func NewModel(cfg config.Wrapper, id protocol.DeviceID, clientName, clientVersio
 		folderRunners:      make(map[string]service),
 		folderRunnerTokens: make(map[string][]suture.ServiceToken),
 		folderVersioners:   make(map[string]versioner.Versioner),
+		folderMarkerIDs:    make(map[string]string),
 
 		// fields protected by pmut
 		pmut:                sync.NewRWMutex(),
@@ -222,16 +271,41 @@ func NewModel(cfg config.Wrapper, id protocol.DeviceID, clientName, clientVersio
 		closed:              make(map[protocol.DeviceID]chan struct{}),
 		helloMessages:       make(map[protocol.DeviceID]protocol.HelloResult),
 		deviceDownloads:     make(map[protocol.DeviceID]*deviceDownloadState),
+		deviceRates:         make(map[protocol.DeviceID]*deviceTransferRates),
 		remotePausedFolders: make(map[protocol.DeviceID][]string),
+		mismatchedFolders:   make(map[protocol.DeviceID][]string),
+		clockSkews:          make(map[protocol.DeviceID]time.Duration),
+		remoteMtimeWindows:  make(map[protocol.DeviceID]map[string]time.Duration),
 	}
 	for devID := range cfg.Devices() {
 		m.deviceStatRefs[devID] = stats.NewDeviceStatisticsReference(m.db, devID.String())
 	}
 	m.Add(m.progressEmitter)
+	m.Add(newSyncWindowScheduler(m))
+	ldb.SetRepairHandler(m.folderRepaired)
 
 	return m
 }
 
+// folderRepaired rebuilds the index of a folder once the database found it
+// corrupt and moved it out of the way.
+func (m *model) folderRepaired(r db.FolderRepair) {
+	m.evLogger.Log(events.DatabaseRepaired, map[string]interface{}{
+		"folder":          r.Folder,
+		"error":           r.Error.Error(),
+		"quarantinedKeys": r.QuarantinedKeys,
+	})
+
+	cfg, ok := m.cfg.Folder(r.Folder)
+	if !ok {
+		return
+	}
+	// Without their index IDs the other devices send us their full
+	// indexes on reconnecting, and our own comes back from scanning.
+	m.closeConns(cfg.DeviceIDs(), fmt.Errorf("rebuilding index of folder %v", cfg.Description()))
+	m.DelayScan(r.Folder, 0)
+}
+
 func (m *model) Serve() {
 	m.onServe()
 	m.Supervisor.Serve()
@@ -264,6 +338,7 @@ func (m *model) Stop() {
 	}
 	w := m.closeConns(ids, errStopped)
 	w.Wait()
+	m.browseCursors.releaseAll()
 }
 
 // StartDeadlockDetector starts a deadlock detector on the models locks which
@@ -332,6 +407,7 @@ func (m *model) startFolderLocked(cfg config.FolderConfiguration) {
 			l.Warnln("Failed to create folder marker:", err)
 		}
 	}
+	m.folderMarkerIDs[folder] = cfg.MarkerID()
 
 	ffs := fset.MtimeFS()
 
@@ -341,9 +417,9 @@ func (m *model) startFolderLocked(cfg config.FolderConfiguration) {
 	_ = ffs.Hide(".stignore")
 
 	var ver versioner.Versioner
-	if cfg.Versioning.Type != "" {
+	if cfg.Versioning.Enabled() {
 		var err error
-		ver, err = versioner.New(ffs, cfg.Versioning)
+		ver, err = versioner.New(folder, ffs, cfg.Versioning)
 		if err != nil {
 			panic(fmt.Errorf("creating versioner: %v", err))
 		}
@@ -354,6 +430,14 @@ func (m *model) startFolderLocked(cfg config.FolderConfiguration) {
 			token := m.Add(service)
 			m.folderRunnerTokens[folder] = append(m.folderRunnerTokens[folder], token)
 		}
+		ver = versioner.NewIndexed(ver, ffs, db.NewVersionIndex(m.db, folder), func(name string) protocol.ShortID {
+			snap := fset.Snapshot()
+			defer snap.Release()
+			if cur, ok := snap.Get(protocol.LocalDeviceID, name); ok {
+				return cur.ModifiedBy
+			}
+			return 0
+		})
 	}
 	m.folderVersioners[folder] = ver
 
@@ -368,9 +452,23 @@ func (m *model) startFolderLocked(cfg config.FolderConfiguration) {
 	token := m.Add(p)
 	m.folderRunnerTokens[folder] = append(m.folderRunnerTokens[folder], token)
 
+	if cfg.MountPath != "" {
+		token := m.Add(newFolderMount(m, fset, ffs, cfg))
+		m.folderRunnerTokens[folder] = append(m.folderRunnerTokens[folder], token)
+	}
+
 	l.Infof("Ready to synchronize %s (%s)", cfg.Description(), cfg.Type)
 }
 
+// folderFilesystem returns the filesystem of the folder, with names that
+// are invalid on this OS escaped if the folder is configured so.
+func (m *model) folderFilesystem(cfg config.FolderConfiguration) fs.Filesystem {
+	if cfg.InvalidNamePolicy != config.InvalidNameEscape {
+		return cfg.Filesystem()
+	}
+	return fs.NewEscapeFS(cfg.Filesystem(), db.NewEscapedNamesNamespace(m.db, cfg.ID))
+}
+
 func (m *model) warnAboutOverwritingProtectedFiles(cfg config.FolderConfiguration, ignores *ignore.Matcher) {
 	if cfg.Type == config.FolderTypeSendOnly {
 		return
@@ -415,7 +513,7 @@ func (m *model) addFolder(cfg config.FolderConfiguration) {
 
 	// Creating the fileset can take a long time (metadata calculation) so
 	// we do it outside of the lock.
-	fset := db.NewFileSet(cfg.ID, cfg.Filesystem(), m.db)
+	fset := db.NewFileSet(cfg.ID, m.folderFilesystem(cfg), m.db)
 
 	m.fmut.Lock()
 	defer m.fmut.Unlock()
@@ -426,7 +524,7 @@ func (m *model) addFolderLocked(cfg config.FolderConfiguration, fset *db.FileSet
 	m.folderCfgs[cfg.ID] = cfg
 	m.folderFiles[cfg.ID] = fset
 
-	ignores := ignore.New(cfg.Filesystem(), ignore.WithCache(m.cacheIgnoredFiles))
+	ignores := ignore.New(cfg.Filesystem(), ignore.WithCache(m.cacheIgnoredFiles), ignore.WithCacheStore(db.NewIgnoreCache(m.db, cfg.ID)), ignore.WithGitignore(cfg.HonorGitignore))
 	if err := ignores.Load(".stignore"); err != nil && !fs.IsNotExist(err) {
 		l.Warnln("Loading ignores:", err)
 	}
@@ -456,6 +554,7 @@ func (m *model) removeFolder(cfg config.FolderConfiguration) {
 
 	// Remove it from the database
 	db.DropFolder(m.db, cfg.ID)
+	m.activity.forget(cfg.ID)
 }
 
 func (m *model) stopFolder(cfg config.FolderConfiguration, err error) {
@@ -483,6 +582,7 @@ func (m *model) removeFolderLocked(cfg config.FolderConfiguration) {
 	delete(m.folderRunners, cfg.ID)
 	delete(m.folderRunnerTokens, cfg.ID)
 	delete(m.folderVersioners, cfg.ID)
+	delete(m.folderMarkerIDs, cfg.ID)
 }
 
 func (m *model) restartFolder(from, to config.FolderConfiguration) {
@@ -522,7 +622,7 @@ func (m *model) restartFolder(from, to config.FolderConfiguration) {
 	if !to.Paused {
 		// Creating the fileset can take a long time (metadata calculation)
 		// so we do it outside of the lock.
-		fset = db.NewFileSet(to.ID, to.Filesystem(), m.db)
+		fset = db.NewFileSet(to.ID, m.folderFilesystem(to), m.db)
 	}
 
 	m.stopFolder(from, fmt.Errorf("%v folder %v", errMsg, to.Description()))
@@ -541,7 +641,7 @@ func (m *model) restartFolder(from, to config.FolderConfiguration) {
 func (m *model) newFolder(cfg config.FolderConfiguration) {
 	// Creating the fileset can take a long time (metadata calculation) so
 	// we do it outside of the lock.
-	fset := db.NewFileSet(cfg.ID, cfg.Filesystem(), m.db)
+	fset := db.NewFileSet(cfg.ID, m.folderFilesystem(cfg), m.db)
 
 	// Close connections to affected devices
 	m.closeConns(cfg.DeviceIDs(), fmt.Errorf("started folder %v", cfg.Description()))
@@ -656,6 +756,8 @@ type ConnectionInfo struct {
 	ClientVersion string
 	Type          string
 	Crypto        string
+	ClockSkew     time.Duration
+	ClockSkewed   bool
 }
 
 func (info ConnectionInfo) MarshalJSON() ([]byte, error) {
@@ -669,6 +771,8 @@ func (info ConnectionInfo) MarshalJSON() ([]byte, error) {
 		"clientVersion": info.ClientVersion,
 		"type":          info.Type,
 		"crypto":        info.Crypto,
+		"clockSkew":     info.ClockSkew.Seconds(),
+		"clockSkewed":   info.ClockSkewed,
 	})
 }
 
@@ -695,6 +799,8 @@ func (m *model) ConnectionStats() map[string]interface{} {
 			ci.Crypto = conn.Crypto()
 			ci.Connected = ok
 			ci.Statistics = conn.Statistics()
+			ci.ClockSkew = m.clockSkews[device]
+			ci.ClockSkewed = isClockSkewed(ci.ClockSkew)
 			if addr := conn.RemoteAddr(); addr != nil {
 				ci.Address = addr.String()
 			}
@@ -747,22 +853,40 @@ func (m *model) FolderStatistics() (map[string]stats.FolderStatistics, error) {
 	return res, nil
 }
 
+// FolderScanHistory returns the records of the latest scans of the folder,
+// oldest first.
+func (m *model) FolderScanHistory(folder string) ([]stats.ScanRecord, error) {
+	m.fmut.RLock()
+	runner, ok := m.folderRunners[folder]
+	m.fmut.RUnlock()
+	if !ok {
+		return nil, errFolderMissing
+	}
+	return runner.GetScanHistory()
+}
+
 type FolderCompletion struct {
-	CompletionPct float64
-	NeedBytes     int64
-	NeedItems     int64
-	GlobalBytes   int64
-	NeedDeletes   int64
+	CompletionPct   float64
+	NeedBytes       int64
+	NeedItems       int64
+	GlobalBytes     int64
+	NeedDeletes     int64
+	InProgressBytes int64         // already downloaded into temporary files
+	Rate            float64       // bytes per second, averaged
+	ETA             time.Duration // zero if unknown
 }
 
 // Map returns the members as a map, e.g. used in api to serialize as Json.
 func (comp FolderCompletion) Map() map[string]interface{} {
 	return map[string]interface{}{
-		"completion":  comp.CompletionPct,
-		"needBytes":   comp.NeedBytes,
-		"needItems":   comp.NeedItems,
-		"globalBytes": comp.GlobalBytes,
-		"needDeletes": comp.NeedDeletes,
+		"completion":      comp.CompletionPct,
+		"needBytes":       comp.NeedBytes,
+		"needItems":       comp.NeedItems,
+		"globalBytes":     comp.GlobalBytes,
+		"needDeletes":     comp.NeedDeletes,
+		"inProgressBytes": comp.InProgressBytes,
+		"rate":            comp.Rate,
+		"eta":             int64(comp.ETA / time.Second),
 	}
 }
 
@@ -788,10 +912,11 @@ func (m *model) Completion(device protocol.DeviceID, folder string) FolderComple
 	}
 
 	m.pmut.RLock()
-	counts := m.deviceDownloads[device].GetBlockCounts(folder)
+	downloads := m.deviceDownloads[device]
+	rates := m.deviceRates[device]
 	m.pmut.RUnlock()
 
-	var need, items, fileNeed, downloaded, deletes int64
+	var need, items, fileNeed, downloaded, inProgress, deletes int64
 	snap.WithNeedTruncated(device, func(f db.FileIntf) bool {
 		ft := f.(db.FileInfoTruncated)
 
@@ -801,8 +926,11 @@ func (m *model) Completion(device protocol.DeviceID, folder string) FolderComple
 			return true
 		}
 
-		// This might might be more than it really is, because some blocks can be of a smaller size.
-		downloaded = int64(counts[ft.Name]) * int64(ft.BlockSize())
+		// The device tells us about the blocks it has already pulled into
+		// its temporary file, so large files show progress before they
+		// are complete.
+		downloaded = downloads.BytesDownloaded(folder, ft.Name, ft.Version, ft.FileSize(), ft.BlockSize())
+		inProgress += downloaded
 
 		fileNeed = ft.FileSize() - downloaded
 		if fileNeed < 0 {
@@ -826,14 +954,25 @@ func (m *model) Completion(device protocol.DeviceID, folder string) FolderComple
 		completionPct = 95 // chosen by fair dice roll
 	}
 
-	l.Debugf("%v Completion(%s, %q): %f (%d / %d = %f)", m, device, folder, completionPct, need, tot, needRatio)
+	// The rate is how fast the need shrinks, so it reflects what the
+	// device actually pulls, from us or anyone else.
+	rate := rates.Update(folder, need, time.Now())
+	var eta time.Duration
+	if rate > 0 && need > 0 {
+		eta = time.Duration(float64(need) / rate * float64(time.Second))
+	}
+
+	l.Debugf("%v Completion(%s, %q): %f (%d / %d = %f), %.0f B/s", m, device, folder, completionPct, need, tot, needRatio, rate)
 
 	return FolderCompletion{
-		CompletionPct: completionPct,
-		NeedBytes:     need,
-		NeedItems:     items,
-		GlobalBytes:   tot,
-		NeedDeletes:   deletes,
+		CompletionPct:   completionPct,
+		NeedBytes:       need,
+		NeedItems:       items,
+		GlobalBytes:     tot,
+		NeedDeletes:     deletes,
+		InProgressBytes: inProgress,
+		Rate:            rate,
+		ETA:             eta,
 	}
 }
 
@@ -852,6 +991,12 @@ func (m *model) FolderProgressBytesCompleted(folder string) int64 {
 	return m.progressEmitter.BytesCompleted(folder)
 }
 
+// FolderProgress returns the progress of the files currently being pulled in
+// the given folder. It's empty if progress updates are disabled.
+func (m *model) FolderProgress(folder string) map[string]*PullerProgress {
+	return m.progressEmitter.Progress(folder)
+}
+
 // NeedFolderFiles returns paginated list of currently needed files in
 // progress, queued, and to be queued on next puller iteration.
 func (m *model) NeedFolderFiles(folder string, page, perpage int) ([]db.FileInfoTruncated, []db.FileInfoTruncated, []db.FileInfoTruncated) {
@@ -942,7 +1087,8 @@ func (m *model) handleIndex(deviceID protocol.DeviceID, folder string, fs []prot
 
 	l.Debugf("%v (in): %s / %q: %d files", op, deviceID, folder, len(fs))
 
-	if cfg, ok := m.cfg.Folder(folder); !ok || !cfg.SharedWith(deviceID) {
+	cfg, ok := m.cfg.Folder(folder)
+	if !ok || !cfg.SharedWith(deviceID) {
 		l.Infof("%v for unexpected folder ID %q sent from device %q; ensure that the folder exists and that this device is selected under \"Share With\" in the folder configuration.", op, folder, deviceID)
 		return errors.Wrap(errFolderMissing, folder)
 	} else if cfg.Paused {
@@ -960,10 +1106,21 @@ func (m *model) handleIndex(deviceID protocol.DeviceID, folder string, fs []prot
 		return errors.Wrap(errFolderMissing, folder)
 	}
 
+	if m.isMismatched(deviceID, folder) {
+		// The other side may not know that we refuse this folder, so the
+		// index is dropped without closing the connection.
+		l.Debugf("%v for folder %q with unrelated contents from device %q, ignoring", op, folder, deviceID)
+		return nil
+	}
+
 	if running {
 		defer runner.SchedulePull()
 	}
 
+	// Names arrive in the normalization form native to this OS; the folder
+	// may use another.
+	fs = normalizeIndexNames(cfg.UnicodeNormalization.Form(), fs)
+
 	m.pmut.RLock()
 	downloads := m.deviceDownloads[deviceID]
 	m.pmut.RUnlock()
@@ -1016,7 +1173,9 @@ func (m *model) ClusterConfig(deviceID protocol.DeviceID, cm protocol.ClusterCon
 	}
 
 	m.fmut.RLock()
-	var paused []string
+	var paused, mismatched []string
+	adoptedMarkerIDs := make(map[string]string)
+	modTimeWindows := make(map[string]time.Duration)
 	for _, folder := range cm.Folders {
 		cfg, ok := m.cfg.Folder(folder.ID)
 		if !ok || !cfg.SharedWith(deviceID) {
@@ -1048,10 +1207,31 @@ func (m *model) ClusterConfig(deviceID protocol.DeviceID, cm protocol.ClusterCon
 			continue
 		}
 
-		if !folder.DisableTempIndexes {
+		mismatch, adopt := compareMarkerIDs(m.folderMarkerIDs[folder.ID], fs, folder)
+		if adopt {
+			adoptedMarkerIDs[folder.ID] = folder.MarkerID
+		}
+		if !cfg.IgnoreMarkerMismatch && mismatch {
+			// Neither send nor accept index data, so that the unrelated
+			// contents aren't merged.
+			mismatched = append(mismatched, folder.ID)
+			m.evLogger.Log(events.FolderMismatch, map[string]string{
+				"folder":      folder.ID,
+				"folderLabel": folder.Label,
+				"device":      deviceID.String(),
+			})
+			l.Warnf("Folder %s on device %v seems to have contents unrelated to ours; not syncing it. Enable \"ignore marker mismatch\" in the folder configuration if the folders are indeed the same.", folder.Description(), deviceID)
+			continue
+		}
+
+		if !folder.DisableTempIndexes && cfg.AdvertisesTempIndexes() {
 			tempIndexFolders = append(tempIndexFolders, folder.ID)
 		}
 
+		if folder.ModTimePrecisionMs > 0 {
+			modTimeWindows[folder.ID] = time.Duration(folder.ModTimePrecisionMs) * time.Millisecond
+		}
+
 		myIndexID := fs.IndexID(protocol.LocalDeviceID)
 		mySequence := fs.Sequence(protocol.LocalDeviceID)
 		var startSequence int64
@@ -1122,10 +1302,28 @@ func (m *model) ClusterConfig(deviceID protocol.DeviceID, cm protocol.ClusterCon
 			}
 		}
 
+		devCfg, _ := cfg.Device(deviceID)
+		devIgnores := deviceIgnores(cfg, devCfg)
+		// What the device ignores is announced as invalid, so it needs all
+		// of the index again when that changed.
+		if m.deviceIgnoresChanged(folder.ID, deviceID, devIgnores) && startSequence != 0 {
+			l.Infof("Device %v folder %s has new ignore patterns, sending the full index", deviceID, folder.Description())
+			startSequence = 0
+		}
+		// Files that are denied now but were sent before must be dropped by
+		// the device, which only a full index does.
+		if m.deviceDeniedPathsChanged(folder.ID, devCfg) && startSequence != 0 {
+			l.Infof("Device %v folder %s has new denied paths, sending the full index", deviceID, folder.Description())
+			startSequence = 0
+		}
 		is := &indexSender{
 			conn:         conn,
 			connClosed:   closed,
 			folder:       folder.ID,
+			deviceID:     deviceID,
+			cfg:          m.cfg,
+			deniedPaths:  devCfg.DeniedPaths,
+			ignores:      devIgnores,
 			fset:         fs,
 			prevSequence: startSequence,
 			evLogger:     m.evLogger,
@@ -1138,8 +1336,14 @@ func (m *model) ClusterConfig(deviceID protocol.DeviceID, cm protocol.ClusterCon
 	}
 	m.fmut.RUnlock()
 
+	for folder, id := range adoptedMarkerIDs {
+		m.adoptMarkerID(folder, id)
+	}
+
 	m.pmut.Lock()
 	m.remotePausedFolders[deviceID] = paused
+	m.mismatchedFolders[deviceID] = mismatched
+	m.remoteMtimeWindows[deviceID] = modTimeWindows
 	m.pmut.Unlock()
 
 	// This breaks if we send multiple CM messages during the same connection.
@@ -1380,7 +1584,11 @@ func (m *model) Closed(conn protocol.Connection, err error) {
 	delete(m.connRequestLimiters, device)
 	delete(m.helloMessages, device)
 	delete(m.deviceDownloads, device)
+	delete(m.deviceRates, device)
 	delete(m.remotePausedFolders, device)
+	delete(m.mismatchedFolders, device)
+	delete(m.clockSkews, device)
+	delete(m.remoteMtimeWindows, device)
 	closed := m.closed[device]
 	delete(m.closed, device)
 	m.pmut.Unlock()
@@ -1486,11 +1694,26 @@ func (m *model) Request(deviceID protocol.DeviceID, folder, name string, size in
 		return nil, protocol.ErrGeneric
 	}
 
+	if devCfg, ok := m.cfg.Device(deviceID); ok && devCfg.DonorMode {
+		if syncing := m.syncingFolders(); syncing > devCfg.DonorModeMaxSyncing {
+			// Not having the data right now is the closest there is to
+			// busy, and makes the other device quietly try elsewhere.
+			l.Debugf("Request from %s for file %s in folder %q refused, %d folders syncing", deviceID, name, folder, syncing)
+			return nil, protocol.ErrNoSuchFile
+		}
+	}
+
 	// Make sure the path is valid and in canonical form
 	if name, err = fs.Canonicalize(name); err != nil {
 		l.Debugf("Request from %s in folder %q for invalid filename %s", deviceID, folder, name)
 		return nil, protocol.ErrGeneric
 	}
+	name = folderCfg.UnicodeNormalization.Form().String(name)
+
+	if devCfg, _ := folderCfg.Device(deviceID); devCfg.DeniesPath(name) {
+		l.Debugf("Request from %s in folder %q for denied file %s", deviceID, folder, name)
+		return nil, protocol.ErrNoSuchFile
+	}
 
 	if deviceID != protocol.LocalDeviceID {
 		l.Debugf("%v REQ(in): %s: %q / %q o=%d s=%d t=%v", m, deviceID, folder, name, offset, size, fromTemporary)
@@ -1506,7 +1729,7 @@ func (m *model) Request(deviceID protocol.DeviceID, folder, name string, size in
 		return nil, protocol.ErrInvalid
 	}
 
-	folderFs := folderCfg.Filesystem()
+	folderFs := m.folderFilesystem(folderCfg)
 
 	if err := osutil.TraversesSymlink(folderFs, filepath.Dir(name)); err != nil {
 		l.Debugf("%v REQ(in) traversal check: %s - %s: %q / %q o=%d s=%d", m, err, deviceID, folder, name, offset, size)
@@ -1530,19 +1753,14 @@ func (m *model) Request(deviceID protocol.DeviceID, folder, name string, size in
 		}
 	}()
 
-	// Only check temp files if the flag is set, and if we are set to advertise
-	// the temp indexes.
-	if fromTemporary && !folderCfg.DisableTempIndexes {
-		tempFn := fs.TempName(name)
-
-		if info, err := folderFs.Lstat(tempFn); err != nil || !info.IsRegular() {
-			// Reject reads for anything that doesn't exist or is something
-			// other than a regular file.
-			l.Debugf("%v REQ(in) failed stating temp file (%v): %s: %q / %q o=%d s=%d", m, err, deviceID, folder, name, offset, size)
-			return nil, protocol.ErrNoSuchFile
-		}
-		err := readOffsetIntoBuf(folderFs, tempFn, offset, res.data)
-		if err == nil && scanner.Validate(res.data, hash, weakHash) {
+	// Only check temp files if we are set to advertise the temp indexes.
+	// They are checked first if the flag is set, otherwise as a fallback
+	// when the file itself doesn't have the block (yet), as long as there
+	// is a hash to make sure it's the right block.
+	useTemp := folderCfg.AdvertisesTempIndexes()
+	tempFallback := useTemp && !fromTemporary && len(hash) > 0
+	if fromTemporary && useTemp {
+		if m.readTemporary(folderFs, name, offset, res.data, hash, weakHash) {
 			return res, nil
 		}
 		// Fall through to reading from a non-temp file, just incase the temp
@@ -1550,6 +1768,9 @@ func (m *model) Request(deviceID protocol.DeviceID, folder, name string, size in
 	}
 
 	if info, err := folderFs.Lstat(name); err != nil || !info.IsRegular() {
+		if tempFallback && m.readTemporary(folderFs, name, offset, res.data, hash, weakHash) {
+			return res, nil
+		}
 		// Reject reads for anything that doesn't exist or is something
 		// other than a regular file.
 		l.Debugf("%v REQ(in) failed stating file (%v): %s: %q / %q o=%d s=%d", m, err, deviceID, folder, name, offset, size)
@@ -1557,6 +1778,9 @@ func (m *model) Request(deviceID protocol.DeviceID, folder, name string, size in
 	}
 
 	if err := readOffsetIntoBuf(folderFs, name, offset, res.data); fs.IsNotExist(err) {
+		if tempFallback && m.readTemporary(folderFs, name, offset, res.data, hash, weakHash) {
+			return res, nil
+		}
 		l.Debugf("%v REQ(in) file doesn't exist: %s: %q / %q o=%d s=%d", m, deviceID, folder, name, offset, size)
 		return nil, protocol.ErrNoSuchFile
 	} else if err != nil {
@@ -1565,6 +1789,11 @@ func (m *model) Request(deviceID protocol.DeviceID, folder, name string, size in
 	}
 
 	if !scanner.Validate(res.data, hash, weakHash) {
+		// The file may be the old version of one being downloaded, in
+		// which case the block can be there in the temp file.
+		if tempFallback && m.readTemporary(folderFs, name, offset, res.data, hash, weakHash) {
+			return res, nil
+		}
 		m.recheckFile(deviceID, folderFs, folder, name, size, offset, hash)
 		l.Debugf("%v REQ(in) failed validating data (%v): %s: %q / %q o=%d s=%d", m, err, deviceID, folder, name, offset, size)
 		return nil, protocol.ErrNoSuchFile
@@ -1573,6 +1802,20 @@ func (m *model) Request(deviceID protocol.DeviceID, folder, name string, size in
 	return res, nil
 }
 
+// readTemporary reads the block from the temp file of the given file into
+// buf, returning whether it was there with the expected contents.
+func (m *model) readTemporary(folderFs fs.Filesystem, name string, offset int64, buf, hash []byte, weakHash uint32) bool {
+	tempFn := fs.TempName(name)
+	if info, err := folderFs.Lstat(tempFn); err != nil || !info.IsRegular() {
+		// Ignore anything that doesn't exist or is something other than a
+		// regular file.
+		l.Debugf("%v REQ(in) failed stating temp file (%v): %q o=%d s=%d", m, err, name, offset, len(buf))
+		return false
+	}
+	err := readOffsetIntoBuf(folderFs, tempFn, offset, buf)
+	return err == nil && scanner.Validate(buf, hash, weakHash)
+}
+
 // newLimitedRequestResponse takes size bytes from the limiters in order,
 // skipping nil limiters, then returns a requestResponse of the given size.
 // When the requestResponse is closed the limiters are given back the bytes,
@@ -1708,36 +1951,72 @@ func (m *model) GetIgnores(folder string) ([]string, []string, error) {
 }
 
 func (m *model) SetIgnores(folder string, content []string) error {
+	cfg, err := m.ignoresFolderConfig(folder)
+	if err != nil {
+		return err
+	}
+
+	m.ignoresMut.Lock()
+	err = ignore.WriteIgnores(cfg.Filesystem(), ".stignore", content)
+	if err == nil && cfg.SyncIgnores {
+		err = shareIgnores(cfg.Filesystem())
+	}
+	m.ignoresMut.Unlock()
+	if err != nil {
+		l.Warnln("Saving .stignore:", err)
+		return err
+	}
+
+	return m.scanForIgnores(folder, nil)
+}
+
+// ignoresFolderConfig returns the config of the folder to write the ignore
+// patterns of, creating its root if necessary.
+func (m *model) ignoresFolderConfig(folder string) (config.FolderConfiguration, error) {
 	cfg, ok := m.cfg.Folders()[folder]
 	if !ok {
-		return fmt.Errorf("folder %s does not exist", cfg.Description())
+		return cfg, fmt.Errorf("folder %s does not exist", cfg.Description())
 	}
 
 	err := cfg.CheckPath()
 	if err == config.ErrPathMissing {
 		if err = cfg.CreateRoot(); err != nil {
-			return errors.Wrap(err, "failed to create folder root")
+			return cfg, errors.Wrap(err, "failed to create folder root")
 		}
 		err = cfg.CheckPath()
 	}
 	if err != nil && err != config.ErrMarkerMissing {
-		return err
-	}
-
-	if err := ignore.WriteIgnores(cfg.Filesystem(), ".stignore", content); err != nil {
-		l.Warnln("Saving .stignore:", err)
-		return err
+		return cfg, err
 	}
+	return cfg, nil
+}
 
+// scanForIgnores rescans the subdirectories of the folder, or all of it if
+// there are none, after its ignore patterns changed.
+func (m *model) scanForIgnores(folder string, subdirs []string) error {
 	m.fmut.RLock()
 	runner, ok := m.folderRunners[folder]
 	m.fmut.RUnlock()
 	if ok {
-		return runner.Scan(nil)
+		return runner.Scan(subdirs)
 	}
 	return nil
 }
 
+// remoteModTimeWindow returns the coarsest modification time precision
+// announced for the folder by the connected devices.
+func (m *model) remoteModTimeWindow(folder string) time.Duration {
+	m.pmut.RLock()
+	defer m.pmut.RUnlock()
+	var window time.Duration
+	for _, windows := range m.remoteMtimeWindows {
+		if w := windows[folder]; w > window {
+			window = w
+		}
+	}
+	return window
+}
+
 // OnHello is called when an device connects to us.
 // This allows us to extract some information from the Hello message
 // and add it to a list of known devices ahead of any checks.
@@ -1781,6 +2060,7 @@ func (m *model) GetHello(id protocol.DeviceID) protocol.HelloIntf {
 		DeviceName:    name,
 		ClientName:    m.clientName,
 		ClientVersion: m.clientVersion,
+		Timestamp:     time.Now().UnixNano(),
 	}
 }
 
@@ -1788,6 +2068,9 @@ func (m *model) GetHello(id protocol.DeviceID) protocol.HelloIntf {
 // be sent to the connected peer, thereafter index updates whenever the local
 // folder changes.
 func (m *model) AddConnection(conn connections.Connection, hello protocol.HelloResult) {
+	// Measure before anything else, to not count our own delays as skew.
+	skew := clockSkew(hello, time.Now())
+
 	deviceID := conn.ID()
 	device, ok := m.cfg.Device(deviceID)
 	if !ok {
@@ -1813,6 +2096,7 @@ func (m *model) AddConnection(conn connections.Connection, hello protocol.HelloR
 	m.conn[deviceID] = conn
 	m.closed[deviceID] = make(chan struct{})
 	m.deviceDownloads[deviceID] = newDeviceDownloadState()
+	m.deviceRates[deviceID] = newDeviceTransferRates()
 	// 0: default, <0: no limiting
 	switch {
 	case device.MaxRequestKiB > 0:
@@ -1822,6 +2106,7 @@ func (m *model) AddConnection(conn connections.Connection, hello protocol.HelloR
 	}
 
 	m.helloMessages[deviceID] = hello
+	m.clockSkews[deviceID] = skew
 
 	event := map[string]string{
 		"id":            deviceID.String(),
@@ -1838,6 +2123,18 @@ func (m *model) AddConnection(conn connections.Connection, hello protocol.HelloR
 
 	m.evLogger.Log(events.DeviceConnected, event)
 
+	if isClockSkewed(skew) {
+		m.evLogger.Log(events.ClockSkewDetected, map[string]interface{}{
+			"device": deviceID.String(),
+			"skew":   skew.Seconds(),
+		})
+		direction := "ahead of"
+		if skew < 0 {
+			skew, direction = -skew, "behind"
+		}
+		l.Warnf("The clock of device %s is about %v %s ours. Conflicts may be resolved in favour of the wrong changes until the clocks are corrected.", deviceID, skew.Round(time.Second), direction)
+	}
+
 	l.Infof(`Device %s client is "%s %s" named "%s" at %s`, deviceID, hello.ClientName, hello.ClientVersion, hello.DeviceName, conn)
 
 	conn.Start()
@@ -1861,7 +2158,7 @@ func (m *model) DownloadProgress(device protocol.DeviceID, folder string, update
 	cfg, ok := m.folderCfgs[folder]
 	m.fmut.RUnlock()
 
-	if !ok || cfg.DisableTempIndexes || !cfg.SharedWith(device) {
+	if !ok || !cfg.UsesTempIndexes() || !cfg.SharedWith(device) {
 		return nil
 	}
 
@@ -1894,6 +2191,10 @@ type indexSender struct {
 	conn         protocol.Connection
 	folder       string
 	dev          string
+	deviceID     protocol.DeviceID
+	cfg          config.Wrapper
+	deniedPaths  []string        // as of the last index sent
+	ignores      *ignore.Matcher // of the folder for the device, if any
 	fset         *db.FileSet
 	prevSequence int64
 	evLogger     events.Logger
@@ -1964,6 +2265,17 @@ func (s *indexSender) Complete() bool { return true }
 // sendIndexTo sends file infos with a sequence number higher than prevSequence and
 // returns the highest sent sequence number.
 func (s *indexSender) sendIndexTo(ctx context.Context) error {
+	// The configuration is read anew for every index, and the device gets
+	// all of it again when the denied paths changed so that it drops
+	// what it may no longer read.
+	folderCfg, _ := s.cfg.Folder(s.folder)
+	devCfg, _ := folderCfg.Device(s.deviceID)
+	if strings.Join(devCfg.DeniedPaths, "\n") != strings.Join(s.deniedPaths, "\n") {
+		l.Debugf("%v: Denied paths changed, sending the full index", s)
+		s.prevSequence = 0
+		s.deniedPaths = devCfg.DeniedPaths
+	}
+
 	initial := s.prevSequence == 0
 	batch := newFileInfoBatch(nil)
 	batch.flushFn = func(fs []protocol.FileInfo) error {
@@ -1995,8 +2307,14 @@ func (s *indexSender) sendIndexTo(ctx context.Context) error {
 
 		f = fi.(protocol.FileInfo)
 
-		// Mark the file as invalid if any of the local bad stuff flags are set.
-		f.RawInvalid = f.IsInvalid()
+		// Files the device may not read are never announced to it.
+		if devCfg.DeniesPath(f.Name) {
+			return true
+		}
+
+		// Mark the file as invalid if any of the local bad stuff flags are
+		// set, or if the device ignores it.
+		f.RawInvalid = f.IsInvalid() || s.ignores != nil && s.ignores.Match(f.Name).IsIgnored()
 		// If the file is marked LocalReceive (i.e., changed locally on a
 		// receive only folder) we do not want it to ever become the
 		// globally best version, invalid or not.
@@ -2087,6 +2405,37 @@ func (m *model) ScanFolderSubdirs(folder string, subs []string) error {
 	return runner.Scan(subs)
 }
 
+// SetFolderMaintenance starts or ends maintenance of the folder, during
+// which local changes aren't scanned and nothing is pulled. Ending it
+// rescans the whole folder.
+func (m *model) SetFolderMaintenance(folder string, enabled bool) error {
+	m.fmut.RLock()
+	err := m.checkFolderRunningLocked(folder)
+	runner := m.folderRunners[folder]
+	m.fmut.RUnlock()
+
+	if err != nil {
+		return err
+	}
+
+	return runner.SetMaintenance(enabled)
+}
+
+// ResumeFolderPull renews the pull budget of the folder, resuming a pull
+// that stopped at its checkpoint.
+func (m *model) ResumeFolderPull(folder string) error {
+	m.fmut.RLock()
+	err := m.checkFolderRunningLocked(folder)
+	runner := m.folderRunners[folder]
+	m.fmut.RUnlock()
+
+	if err != nil {
+		return err
+	}
+
+	return runner.ResumePull()
+}
+
 func (m *model) DelayScan(folder string, next time.Duration) {
 	m.fmut.RLock()
 	runner, ok := m.folderRunners[folder]
@@ -2145,14 +2494,25 @@ func (m *model) generateClusterConfig(device protocol.DeviceID) protocol.Cluster
 			ReadOnly:           folderCfg.Type == config.FolderTypeSendOnly,
 			IgnorePermissions:  folderCfg.IgnorePerms,
 			IgnoreDelete:       folderCfg.IgnoreDelete,
-			DisableTempIndexes: folderCfg.DisableTempIndexes,
+			DisableTempIndexes: !folderCfg.UsesTempIndexes(),
 			Paused:             folderCfg.Paused,
+			MarkerID:           m.folderMarkerIDs[folderCfg.ID],
+			ModTimePrecisionMs: int32(folderCfg.ModTimeWindow() / time.Millisecond),
 		}
 
 		var fs *db.FileSet
 		if !folderCfg.Paused {
 			fs = m.folderFiles[folderCfg.ID]
 		}
+		if fs != nil {
+			// The names the device may not see, or ignores, aren't part of
+			// the sample sent to it.
+			devCfg, _ := folderCfg.Device(device)
+			devIgnores := deviceIgnores(folderCfg, devCfg)
+			protocolFolder.RootSample = rootSample(fs, func(name string) bool {
+				return !devCfg.DeniesPath(name) && (devIgnores == nil || !devIgnores.Match(name).IsIgnored())
+			})
+		}
 
 		for _, device := range folderCfg.Devices {
 			deviceCfg, _ := m.cfg.Device(device.DeviceID)
@@ -2199,6 +2559,20 @@ func (m *model) State(folder string) (string, time.Time, error) {
 	return state.String(), changed, err
 }
 
+// syncingFolders returns the number of folders that are currently pulling
+// changes.
+func (m *model) syncingFolders() int {
+	m.fmut.RLock()
+	defer m.fmut.RUnlock()
+	n := 0
+	for _, runner := range m.folderRunners {
+		if state, _, _ := runner.getState(); state == FolderSyncPreparing || state == FolderSyncing {
+			n++
+		}
+	}
+	return n
+}
+
 func (m *model) FolderErrors(folder string) ([]FileError, error) {
 	m.fmut.RLock()
 	err := m.checkFolderRunningLocked(folder)
@@ -2251,6 +2625,30 @@ func (m *model) Revert(folder string) {
 	runner.Revert()
 }
 
+// FolderOperation returns the report of the running or last revert or
+// override of the folder.
+func (m *model) FolderOperation(folder string) (OperationReport, error) {
+	m.fmut.RLock()
+	runner, ok := m.folderRunners[folder]
+	m.fmut.RUnlock()
+	if !ok {
+		return OperationReport{}, errFolderMissing
+	}
+	return runner.Operation()
+}
+
+// CancelFolderOperation cancels the running revert or override of the
+// folder, keeping what it already changed.
+func (m *model) CancelFolderOperation(folder string) error {
+	m.fmut.RLock()
+	runner, ok := m.folderRunners[folder]
+	m.fmut.RUnlock()
+	if !ok {
+		return errFolderMissing
+	}
+	return runner.CancelOperation()
+}
+
 func (m *model) GlobalDirectoryTree(folder, prefix string, levels int, dirsonly bool) map[string]interface{} {
 	m.fmut.RLock()
 	files, ok := m.folderFiles[folder]
@@ -2317,6 +2715,71 @@ func (m *model) GlobalDirectoryTree(folder, prefix string, levels int, dirsonly
 	return output
 }
 
+// GlobalDirectoryList returns the first page of a listing of the items
+// directly within the directory prefix. Listings in ascending name order are
+// read lazily from the database, other orders require reading the whole
+// directory.
+func (m *model) GlobalDirectoryList(folder, prefix string, dirsonly bool, sortBy DirectorySort, reverse bool, offset, limit int) (DirectoryPage, error) {
+	m.fmut.RLock()
+	files, ok := m.folderFiles[folder]
+	m.fmut.RUnlock()
+	if !ok {
+		return DirectoryPage{}, errFolderMissing
+	}
+
+	var less func(a, b DirectoryEntry) bool
+	switch sortBy {
+	case DirectorySortName, "":
+		less = func(a, b DirectoryEntry) bool { return a.Name < b.Name }
+	case DirectorySortSize:
+		less = func(a, b DirectoryEntry) bool { return a.Size < b.Size }
+	case DirectorySortModified:
+		less = func(a, b DirectoryEntry) bool { return a.ModTime.Before(b.ModTime) }
+	default:
+		return DirectoryPage{}, fmt.Errorf("unknown sort order %q", sortBy)
+	}
+
+	cursor := &browseCursor{
+		prefix:   osutil.NativeFilename(prefix),
+		dirsonly: dirsonly,
+	}
+	snap := files.Snapshot()
+	cursor.sequence = snapshotSequence(snap)
+
+	if (sortBy == DirectorySortName || sortBy == "") && !reverse {
+		// Keep the snapshot for reading the following pages.
+		cursor.snap = snap
+		if offset > 0 {
+			skipped, _ := readDirectory(snap, cursor.prefix, "", dirsonly, offset-1, 1)
+			if len(skipped) == 0 {
+				snap.Release()
+				return DirectoryPage{Entries: []DirectoryEntry{}, Sequence: cursor.sequence}, nil
+			}
+			cursor.after = filepath.Join(cursor.prefix, skipped[0].Name)
+		}
+	} else {
+		entries, _ := readDirectory(snap, cursor.prefix, "", dirsonly, 0, 0)
+		snap.Release()
+		sort.SliceStable(entries, func(a, b int) bool {
+			if reverse {
+				return less(entries[b], entries[a])
+			}
+			return less(entries[a], entries[b])
+		})
+		if offset < len(entries) {
+			cursor.remaining = entries[offset:]
+		}
+	}
+
+	return m.browseCursors.nextPage(cursor, limit), nil
+}
+
+// GlobalDirectoryListContinue returns the next page of the listing the
+// token was given for.
+func (m *model) GlobalDirectoryListContinue(token string, limit int) (DirectoryPage, error) {
+	return m.browseCursors.continuePage(token, limit)
+}
+
 func (m *model) GetFolderVersions(folder string) (map[string][]versioner.FileVersion, error) {
 	m.fmut.RLock()
 	ver, ok := m.folderVersioners[folder]
@@ -2363,6 +2826,79 @@ func (m *model) RestoreFolderVersions(folder string, versions map[string]time.Ti
 	return restoreErrors, nil
 }
 
+// folderVersionAccessor returns the versioner of the folder, if it gives
+// access to the archived versions.
+func (m *model) folderVersionAccessor(folder string) (versioner.VersionAccessor, error) {
+	m.fmut.RLock()
+	ver, ok := m.folderVersioners[folder]
+	m.fmut.RUnlock()
+	if !ok {
+		return nil, errFolderMissing
+	}
+	if ver == nil {
+		return nil, errNoVersioner
+	}
+	va, ok := ver.(versioner.VersionAccessor)
+	if !ok {
+		return nil, versioner.ErrRestorationNotSupported
+	}
+	return va, nil
+}
+
+// OpenFolderVersion opens the archived version of the file from the given
+// time.
+func (m *model) OpenFolderVersion(folder, file string, version time.Time) (fs.File, error) {
+	va, err := m.folderVersionAccessor(folder)
+	if err != nil {
+		return nil, err
+	}
+	return va.OpenVersion(file, version)
+}
+
+// RestoreFolderVersionTo restores a copy of the archived version of the
+// file from the given time to the target path, which must not exist yet.
+func (m *model) RestoreFolderVersionTo(folder, file string, version time.Time, target string) error {
+	fcfg, ok := m.cfg.Folder(folder)
+	if !ok {
+		return errFolderMissing
+	}
+	va, err := m.folderVersionAccessor(folder)
+	if err != nil {
+		return err
+	}
+	if err := va.RestoreTo(file, version, target); err != nil {
+		return err
+	}
+
+	if !fcfg.FSWatcherEnabled {
+		go func() { _ = m.ScanFolderSubdirs(folder, []string{target}) }()
+	}
+	return nil
+}
+
+// CleanFolderVersions prunes the archived versions of the folder now,
+// rather than waiting for the versioner's next cleanup.
+func (m *model) CleanFolderVersions(folder string) (versioner.CleanupResult, error) {
+	m.fmut.RLock()
+	ver, ok := m.folderVersioners[folder]
+	m.fmut.RUnlock()
+	if !ok {
+		return versioner.CleanupResult{}, errFolderMissing
+	}
+	if ver == nil {
+		return versioner.CleanupResult{}, errNoVersioner
+	}
+	cleaner, ok := ver.(versioner.Cleaner)
+	if !ok {
+		return versioner.CleanupResult{}, errNoVersionCleanup
+	}
+	res, err := cleaner.Clean()
+	if err == versioner.ErrCleanupNotSupported {
+		return res, errNoVersionCleanup
+	}
+	return res, err
+}
+
 func (m *model) Availability(folder string, file protocol.FileInfo, block protocol.BlockInfo) []Availability {
 	// The slightly unusual locking sequence here is because we need to hold
 	// pmut for the duration (as the value returned from foldersFiles can
@@ -2396,6 +2932,10 @@ next:
 		}
 	}
 
+	if !cfg.UsesTempIndexes() {
+		return availabilities
+	}
+
 	for _, device := range cfg.Devices {
 		if m.deviceDownloads[device.DeviceID].Has(folder, file.Name, file.Version, int32(block.Offset/int64(file.BlockSize()))) {
 			availabilities = append(availabilities, Availability{ID: device.DeviceID, FromTemporary: true})
@@ -2405,6 +2945,28 @@ next:
 	return availabilities
 }
 
+// temporaryAvailability returns, per block index, the number of devices that
+// have that block of the file in a temporary file. Blocks that no one has
+// started downloading are not included.
+func (m *model) temporaryAvailability(folder string, file protocol.FileInfo) map[int32]int {
+	m.fmut.RLock()
+	cfg, ok := m.folderCfgs[folder]
+	m.fmut.RUnlock()
+	if !ok || !cfg.UsesTempIndexes() {
+		return nil
+	}
+
+	counts := make(map[int32]int)
+	m.pmut.RLock()
+	for _, device := range cfg.Devices {
+		for _, index := range m.deviceDownloads[device.DeviceID].BlockIndexes(folder, file.Name, file.Version) {
+			counts[index]++
+		}
+	}
+	m.pmut.RUnlock()
+	return counts
+}
+
 // BringToFront bumps the given files priority in the job queue.
 func (m *model) BringToFront(folder, file string) {
 	m.fmut.RLock()
@@ -2416,6 +2978,19 @@ func (m *model) BringToFront(folder, file string) {
 	}
 }
 
+// ResetPullBackoff makes the failed items, or all those in the folder if
+// none are given, be retried right away.
+func (m *model) ResetPullBackoff(folder string, files []string) error {
+	m.fmut.RLock()
+	runner, ok := m.folderRunners[folder]
+	m.fmut.RUnlock()
+	if !ok {
+		return errFolderMissing
+	}
+	runner.ResetBackoff(files)
+	return nil
+}
+
 func (m *model) ResetFolder(folder string) {
 	l.Infof("Cleaning data for folder %q", folder)
 	db.DropFolder(m.db, folder)
@@ -2521,6 +3096,7 @@ func (m *model) CommitConfiguration(from, to config.Configuration) bool {
 
 	m.globalRequestLimiter.setCapacity(1024 * to.Options.MaxConcurrentIncomingRequestKiB())
 	m.folderIOLimiter.setCapacity(to.Options.MaxFolderConcurrency())
+	m.folderPullLimiter.setCapacity(to.Options.MaxSyncingFolders())
 
 	// Some options don't require restart as those components handle it fine
 	// by themselves. Compare the options structs containing only the
@@ -16,6 +16,7 @@ import (
 	"math/rand"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"runtime/pprof"
 	"strconv"
@@ -36,6 +37,7 @@ import (
 	srand "github.com/syncthing/syncthing/lib/rand"
 	"github.com/syncthing/syncthing/lib/testutils"
 	"github.com/syncthing/syncthing/lib/versioner"
+	"golang.org/x/text/unicode/norm"
 )
 
 var testDataExpected = map[string]protocol.FileInfo{
@@ -461,6 +463,155 @@ func TestClusterConfig(t *testing.T) {
 	}
 }
 
+func TestClockSkew(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	m := setupModel(w)
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	sub := m.evLogger.Subscribe(events.ClockSkewDetected)
+	defer sub.Unsubscribe()
+
+	conn := &fakeConnection{id: device1, model: m}
+	m.AddConnection(conn, protocol.HelloResult{Timestamp: time.Now().Add(5 * time.Second).UnixNano()})
+	if ev, err := sub.Poll(100 * time.Millisecond); err == nil {
+		t.Fatal("Unexpected clock skew event", ev)
+	}
+	ci := m.ConnectionStats()["connections"].(map[string]ConnectionInfo)[device1.String()]
+	if ci.ClockSkewed || ci.ClockSkew < 4*time.Second || ci.ClockSkew > 5*time.Second {
+		t.Errorf("Unexpected clock skew %v, skewed %v", ci.ClockSkew, ci.ClockSkewed)
+	}
+	m.Closed(conn, protocol.ErrTimeout)
+
+	m.AddConnection(conn, protocol.HelloResult{Timestamp: time.Now().Add(-time.Hour).UnixNano()})
+	if _, err := sub.Poll(time.Second); err != nil {
+		t.Fatal("Expected a clock skew event")
+	}
+	ci = m.ConnectionStats()["connections"].(map[string]ConnectionInfo)[device1.String()]
+	if !ci.ClockSkewed || ci.ClockSkew > -time.Hour+time.Second {
+		t.Errorf("Unexpected clock skew %v, skewed %v", ci.ClockSkew, ci.ClockSkewed)
+	}
+	m.Closed(conn, protocol.ErrTimeout)
+
+	// Without a timestamp there is nothing to compare to.
+	m.AddConnection(conn, protocol.HelloResult{})
+	ci = m.ConnectionStats()["connections"].(map[string]ConnectionInfo)[device1.String()]
+	if ci.ClockSkewed || ci.ClockSkew != 0 {
+		t.Errorf("Unexpected clock skew %v, skewed %v", ci.ClockSkew, ci.ClockSkewed)
+	}
+}
+
+func TestClusterConfigFolderMismatch(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	m := setupModel(w)
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	must(t, ioutil.WriteFile(filepath.Join(fcfg.Filesystem().URI(), "ours"), []byte("data"), 0644))
+	must(t, m.ScanFolder("default"))
+	m.AddConnection(&fakeConnection{id: device1, model: m}, protocol.HelloResult{})
+
+	sub := m.evLogger.Subscribe(events.FolderMismatch)
+	defer sub.Unsubscribe()
+
+	cc := func(sample ...string) protocol.ClusterConfig {
+		return protocol.ClusterConfig{Folders: []protocol.Folder{{
+			ID:         "default",
+			MarkerID:   "theirs",
+			RootSample: sample,
+			Devices:    []protocol.Device{{ID: myID}, {ID: device1}},
+		}}}
+	}
+	theirFile := protocol.FileInfo{Name: "theirs", Type: protocol.FileInfoTypeDirectory, Version: protocol.Vector{}.Update(device1.Short())}
+
+	// Nothing in common, the folder is refused.
+	m.ClusterConfig(device1, cc("theirs"))
+	if _, err := sub.Poll(time.Second); err != nil {
+		t.Fatal("Expected a folder mismatch event")
+	}
+	must(t, m.Index(device1, "default", []protocol.FileInfo{theirFile}))
+	if _, ok := m.CurrentGlobalFile("default", "theirs"); ok {
+		t.Error("Index for a mismatched folder was accepted")
+	}
+
+	// A common name marks the folders as the same, e.g. seeded from a copy.
+	m.ClusterConfig(device1, cc("ours", "theirs"))
+	if ev, err := sub.Poll(100 * time.Millisecond); err == nil {
+		t.Fatal("Unexpected folder mismatch event", ev)
+	}
+	must(t, m.Index(device1, "default", []protocol.FileInfo{theirFile}))
+	if _, ok := m.CurrentGlobalFile("default", "theirs"); !ok {
+		t.Error("Index for a matching folder was not accepted")
+	}
+}
+
+func TestClusterConfigEmptyFolderAdoptsMarkerID(t *testing.T) {
+	m, _, fcfg := setupModelWithConnection()
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	m.ClusterConfig(device1, protocol.ClusterConfig{Folders: []protocol.Folder{{
+		ID:         "default",
+		MarkerID:   "theirs",
+		RootSample: []string{"theirs"},
+		Devices:    []protocol.Device{{ID: myID}, {ID: device1}},
+	}}})
+
+	if id := fcfg.MarkerID(); id != "theirs" {
+		t.Errorf("Empty folder has marker ID %q, expected it to adopt theirs", id)
+	}
+	if m.isMismatched(device1, "default") {
+		t.Error("Empty folder should not be mismatched")
+	}
+}
+
+func TestClusterConfigLegacyMarkerAdoptsMarkerID(t *testing.T) {
+	m, _, fcfg := setupModelWithConnection()
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	must(t, ioutil.WriteFile(filepath.Join(fcfg.Filesystem().URI(), "ours"), []byte("data"), 0644))
+	must(t, m.ScanFolder("default"))
+
+	// A marker predating IDs, as after an upgrade.
+	must(t, fcfg.Filesystem().Remove(filepath.Join(config.DefaultMarkerName, "id")))
+	m.fmut.Lock()
+	m.folderMarkerIDs["default"] = ""
+	m.fmut.Unlock()
+
+	m.ClusterConfig(device1, protocol.ClusterConfig{Folders: []protocol.Folder{{
+		ID:         "default",
+		MarkerID:   "theirs",
+		RootSample: []string{"theirs"},
+		Devices:    []protocol.Device{{ID: myID}, {ID: device1}},
+	}}})
+
+	if m.isMismatched(device1, "default") {
+		t.Error("Folder with a marker predating IDs should not be mismatched")
+	}
+	if id := fcfg.MarkerID(); id != "theirs" {
+		t.Errorf("Folder has marker ID %q, expected it to adopt theirs", id)
+	}
+}
+
+func TestClusterConfigMissingMarkerID(t *testing.T) {
+	m, _, fcfg := setupModelWithConnection()
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	must(t, ioutil.WriteFile(filepath.Join(fcfg.Filesystem().URI(), "ours"), []byte("data"), 0644))
+	must(t, m.ScanFolder("default"))
+	ourID := fcfg.MarkerID()
+
+	m.ClusterConfig(device1, protocol.ClusterConfig{Folders: []protocol.Folder{{
+		ID:         "default",
+		RootSample: []string{"theirs"},
+		Devices:    []protocol.Device{{ID: myID}, {ID: device1}},
+	}}})
+
+	if m.isMismatched(device1, "default") {
+		t.Error("Folder without a remote marker ID should not be mismatched")
+	}
+	if id := fcfg.MarkerID(); id != ourID {
+		t.Errorf("Marker ID changed from %q to %q", ourID, id)
+	}
+}
+
 func TestIntroducer(t *testing.T) {
 	var introducedByAnyone protocol.DeviceID
 
@@ -1932,6 +2083,115 @@ func TestGlobalDirectoryTree(t *testing.T) {
 	}
 }
 
+func TestGlobalDirectoryList(t *testing.T) {
+	db := db.NewLowlevel(backend.OpenMemory())
+	m := newModel(defaultCfgWrapper, myID, "syncthing", "dev", db, nil)
+	m.ServeBackground()
+	m.removeFolder(defaultFolderConfig)
+	m.addFolder(defaultFolderConfig)
+	defer cleanupModel(m)
+
+	b := func(typ protocol.FileInfoType, size, mod int64, path ...string) protocol.FileInfo {
+		return protocol.FileInfo{
+			Name:      filepath.Join(path...),
+			Type:      typ,
+			ModifiedS: mod,
+			Size:      size,
+			Version:   protocol.Vector{}.Update(device1.Short()),
+		}
+	}
+	m.Index(device1, "default", []protocol.FileInfo{
+		b(protocol.FileInfoTypeDirectory, 0, 3, "a"),
+		b(protocol.FileInfoTypeFile, 30, 1, "a", "file"),
+		b(protocol.FileInfoTypeFile, 20, 2, "b"),
+		b(protocol.FileInfoTypeSymlink, 0, 4, "c"),
+		b(protocol.FileInfoTypeFile, 10, 5, "d"),
+	})
+
+	names := func(entries []DirectoryEntry) []string {
+		var res []string
+		for _, e := range entries {
+			res = append(res, e.Name)
+		}
+		return res
+	}
+
+	cases := []struct {
+		prefix   string
+		dirsonly bool
+		sortBy   DirectorySort
+		reverse  bool
+		offset   int
+		limit    int
+		exp      []string
+		more     bool
+	}{
+		{"", false, "", false, 0, 0, []string{"a", "b", "c", "d"}, false},
+		{"", false, DirectorySortName, false, 1, 2, []string{"b", "c"}, true},
+		{"", false, DirectorySortName, false, 2, 2, []string{"c", "d"}, false},
+		{"", false, DirectorySortName, true, 0, 3, []string{"d", "c", "b"}, true},
+		{"", false, DirectorySortSize, false, 0, 0, []string{"d", "b", "a", "c"}, false},
+		{"", false, DirectorySortModified, true, 1, 2, []string{"c", "a"}, true},
+		{"", true, "", false, 0, 0, []string{"a"}, false},
+		{"", false, "", false, 10, 2, nil, false},
+		{"a", false, "", false, 0, 0, []string{"file"}, false},
+	}
+	for _, tc := range cases {
+		page, err := m.GlobalDirectoryList("default", tc.prefix, tc.dirsonly, tc.sortBy, tc.reverse, tc.offset, tc.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := names(page.Entries); !reflect.DeepEqual(got, tc.exp) || page.More != tc.more || (page.Token != "") != tc.more {
+			t.Errorf("%+v: got %v (more %v, token %q)", tc, got, page.More, page.Token)
+		}
+	}
+
+	page, _ := m.GlobalDirectoryList("default", "", false, "", false, 0, 0)
+	for i, typ := range []string{"directory", "file", "symlink", "file"} {
+		if page.Entries[i].Type != typ {
+			t.Errorf("Entry %v has type %v, expected %v", page.Entries[i].Name, page.Entries[i].Type, typ)
+		}
+	}
+
+	if _, err := m.GlobalDirectoryList("nonexistent", "", false, "", false, 0, 0); err == nil {
+		t.Error("Expected an error for a nonexistent folder")
+	}
+	if _, err := m.GlobalDirectoryList("default", "", false, "color", false, 0, 0); err == nil {
+		t.Error("Expected an error for an unknown sort order")
+	}
+
+	// Following pages are read from the same state as the first one, even
+	// when the folder changes in between.
+	for _, sortBy := range []DirectorySort{DirectorySortName, DirectorySortSize} {
+		all, _ := m.GlobalDirectoryList("default", "", false, sortBy, false, 0, 0)
+		page, err := m.GlobalDirectoryList("default", "", false, sortBy, false, 0, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := names(page.Entries)
+		m.IndexUpdate(device1, "default", []protocol.FileInfo{
+			b(protocol.FileInfoTypeFile, 0, 0, "0"+string(sortBy)),
+			b(protocol.FileInfoTypeFile, 0, 0, "e"+string(sortBy)),
+		})
+		first := page.Sequence
+		for page.More {
+			if page, err = m.GlobalDirectoryListContinue(page.Token, 2); err != nil {
+				t.Fatal(err)
+			}
+			if page.Sequence != first {
+				t.Errorf("Sequence changed from %v to %v between pages", first, page.Sequence)
+			}
+			got = append(got, names(page.Entries)...)
+		}
+		if exp := names(all.Entries); !reflect.DeepEqual(got, exp) {
+			t.Errorf("Sorted by %v: got %v, expected %v", sortBy, got, exp)
+		}
+		if _, err := m.GlobalDirectoryListContinue(page.Token, 1); err == nil {
+			t.Error("Expected an error for a used up token")
+		}
+	}
+}
+
 func TestGlobalDirectorySelfFixing(t *testing.T) {
 	db := db.NewLowlevel(backend.OpenMemory())
 	m := newModel(defaultCfgWrapper, myID, "syncthing", "dev", db, nil)
@@ -3294,6 +3554,47 @@ func TestRequestLimit(t *testing.T) {
 	}
 }
 
+// syncingRunner is a folder runner that is always busy pulling.
+type syncingRunner struct {
+	service
+}
+
+func (syncingRunner) getState() (folderState, time.Time, error) {
+	return FolderSyncing, time.Now(), nil
+}
+
+func TestRequestDonorMode(t *testing.T) {
+	wrapper := createTmpWrapper(defaultCfg.Copy())
+	dev, _ := wrapper.Device(device1)
+	dev.DonorMode = true
+	wrapper.SetDevice(dev)
+	m := setupModel(wrapper)
+	defer cleanupModel(m)
+
+	if _, err := m.Request(device1, "default", "foo", 6, 0, nil, 0, false); err != nil {
+		t.Fatal("Request while idle failed:", err)
+	}
+
+	m.fmut.Lock()
+	m.folderRunners["busy"] = syncingRunner{}
+	m.fmut.Unlock()
+	defer func() {
+		m.fmut.Lock()
+		delete(m.folderRunners, "busy")
+		m.fmut.Unlock()
+	}()
+
+	if _, err := m.Request(device1, "default", "foo", 6, 0, nil, 0, false); err != protocol.ErrNoSuchFile {
+		t.Fatal("Expected request while syncing to be refused, got", err)
+	}
+
+	dev.DonorModeMaxSyncing = 1
+	wrapper.SetDevice(dev)
+	if _, err := m.Request(device1, "default", "foo", 6, 0, nil, 0, false); err != nil {
+		t.Fatal("Request below threshold failed:", err)
+	}
+}
+
 func TestSanitizePath(t *testing.T) {
 	cases := [][2]string{
 		{"", ""},
@@ -3408,6 +3709,191 @@ func TestModTimeWindow(t *testing.T) {
 	}
 }
 
+func TestClusterConfigRootSampleFiltered(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	fcfg.Devices = []config.FolderDeviceConfiguration{
+		{DeviceID: myID},
+		{DeviceID: device1, DeniedPaths: []string{"secret"}},
+		{DeviceID: device2, IgnorePatterns: []string{"hidden"}},
+	}
+	w.SetFolder(fcfg)
+	m := setupModel(w)
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	for _, name := range []string{"hidden", "secret", "shared"} {
+		must(t, ioutil.WriteFile(filepath.Join(fcfg.Filesystem().URI(), name), []byte("data"), 0644))
+	}
+	must(t, m.ScanFolder("default"))
+
+	for dev, exp := range map[protocol.DeviceID][]string{
+		device1: {"hidden", "shared"},
+		device2: {"secret", "shared"},
+	} {
+		cm := m.generateClusterConfig(dev)
+		if len(cm.Folders) != 1 {
+			t.Fatalf("Expected one folder for %v, got %+v", dev, cm.Folders)
+		}
+		if sample := cm.Folders[0].RootSample; !reflect.DeepEqual(sample, exp) {
+			t.Errorf("Got root sample %v for %v, expected %v", sample, dev, exp)
+		}
+	}
+}
+
+func TestModTimePrecisionExchange(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	fcfg.RawModTimeWindowS = 1
+	w.SetFolder(fcfg)
+	m := setupModel(w)
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	cm := m.generateClusterConfig(device1)
+	if len(cm.Folders) != 1 || cm.Folders[0].ModTimePrecisionMs != 1000 {
+		t.Fatalf("Expected our precision to be announced, got %+v", cm.Folders)
+	}
+
+	conn := &fakeConnection{id: device1, model: m}
+	m.AddConnection(conn, protocol.HelloResult{})
+	m.ClusterConfig(device1, protocol.ClusterConfig{Folders: []protocol.Folder{{
+		ID:                 "default",
+		ModTimePrecisionMs: 2000,
+		Devices:            []protocol.Device{{ID: myID}, {ID: device1}},
+	}}})
+	if window := m.remoteModTimeWindow("default"); window != 2*time.Second {
+		t.Errorf("Got remote window %v, expected 2s", window)
+	}
+
+	m.Closed(conn, protocol.ErrTimeout)
+	if window := m.remoteModTimeWindow("default"); window != 0 {
+		t.Errorf("Got remote window %v after disconnecting, expected 0", window)
+	}
+}
+
+func TestFileContent(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	fcfg.Type = config.FolderTypeSendOnly
+	w.SetFolder(fcfg)
+	m := setupModel(w)
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	read := func(file protocol.FileInfo) ([]byte, error) {
+		t.Helper()
+		r, err := m.FileContent(context.Background(), "default", file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ioutil.ReadAll(r)
+	}
+
+	// Our own file is read from disk.
+	local := []byte("local data")
+	must(t, ioutil.WriteFile(filepath.Join(fcfg.Filesystem().URI(), "local"), local, 0644))
+	must(t, m.ScanFolder("default"))
+	lf, ok := m.CurrentFolderFile("default", "local")
+	if !ok {
+		t.Fatal("Local file missing")
+	}
+	if bs, err := read(lf); err != nil || !bytes.Equal(bs, local) {
+		t.Errorf("Got %q, %v for local file", bs, err)
+	}
+
+	// A file we don't have is requested from a device that has it.
+	remote := []byte("remote data")
+	fc := &fakeConnection{id: device1, model: m}
+	fc.addFile("remote", 0644, protocol.FileInfoTypeFile, remote)
+	m.AddConnection(fc, protocol.HelloResult{})
+	must(t, m.Index(device1, "default", fc.files))
+	gf, ok := m.CurrentGlobalFile("default", "remote")
+	if !ok {
+		t.Fatal("Remote file missing")
+	}
+	if bs, err := read(gf); err != nil || !bytes.Equal(bs, remote) {
+		t.Errorf("Got %q, %v for remote file", bs, err)
+	}
+
+	// Corrupt data is refused.
+	fc.mut.Lock()
+	fc.fileData["remote"] = []byte("corrupted!!")
+	fc.mut.Unlock()
+	if _, err := read(gf); err == nil {
+		t.Error("Expected an error for corrupt data")
+	}
+
+	if _, err := m.FileContent(context.Background(), "default", protocol.FileInfo{Name: "dir", Type: protocol.FileInfoTypeDirectory}); err != errNotAFile {
+		t.Errorf("Expected errNotAFile for a directory, got %v", err)
+	}
+}
+
+func TestFolderScanHistory(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	m := setupModel(w)
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	must(t, ioutil.WriteFile(filepath.Join(fcfg.Filesystem().URI(), "file"), []byte("some data"), 0644))
+	must(t, m.ScanFolderSubdirs("default", []string{"file"}))
+
+	history, err := m.FolderScanHistory("default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The initial scan and ours, and maybe more that happened meanwhile
+	found := false
+	for _, rec := range history {
+		if rec.Partial && rec.HashedFiles == 1 && rec.HashedBytes == 9 {
+			found = true
+		}
+	}
+	if len(history) < 2 || !found {
+		t.Errorf("Our scan is missing from the history %+v", history)
+	}
+
+	if _, err := m.FolderScanHistory("nonexistent"); err != errFolderMissing {
+		t.Errorf("Expected errFolderMissing, got %v", err)
+	}
+}
+
+func TestRestoreDeletedFiles(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	// Send only, so that the file isn't pulled the usual way.
+	fcfg.Type = config.FolderTypeSendOnly
+	w.SetFolder(fcfg)
+	m := setupModel(w)
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+	ffs := fcfg.Filesystem()
+
+	data := []byte("still on the other device")
+	fc := &fakeConnection{id: device1, model: m}
+	fc.addFile("dir/file", 0644, protocol.FileInfoTypeFile, data)
+	m.AddConnection(fc, protocol.HelloResult{})
+	must(t, m.Index(device1, "default", fc.files))
+
+	ferr, err := m.RestoreDeletedFiles(context.Background(), "default", []string{"dir/file", "missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ferr) != 1 || ferr["missing"] != errNoDeviceHasIt.Error() {
+		t.Errorf("Unexpected errors %v", ferr)
+	}
+	if bs, err := ioutil.ReadFile(filepath.Join(ffs.URI(), "dir", "file")); err != nil || !bytes.Equal(bs, data) {
+		t.Errorf("Got %q, %v for the restored file", bs, err)
+	}
+	if _, err := ffs.Lstat(fs.TempName("dir/file")); !fs.IsNotExist(err) {
+		t.Error("Temp file left behind:", err)
+	}
+
+	// An existing file isn't overwritten.
+	ferr, err = m.RestoreDeletedFiles(context.Background(), "default", []string{"dir/file"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ferr["dir/file"] != errFileExists.Error() {
+		t.Errorf("Expected errFileExists, got %v", ferr)
+	}
+
+	if _, err := m.RestoreDeletedFiles(context.Background(), "nonexistent", nil); err != errFolderMissing {
+		t.Errorf("Expected errFolderMissing, got %v", err)
+	}
+}
+
 func TestDevicePause(t *testing.T) {
 	m, _, fcfg := setupModelWithConnection()
 	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
@@ -3479,3 +3965,322 @@ func TestNewLimitedRequestResponse(t *testing.T) {
 		t.Error("Bytes weren't returned in a timely fashion")
 	}
 }
+
+func TestNormalizeIndexNames(t *testing.T) {
+	nfc, nfd := "\xC3\x84", "\x41\xCC\x88" // 'Ä'
+	older := protocol.FileInfo{Name: nfc, Version: protocol.Vector{}.Update(device1.Short())}
+	newer := protocol.FileInfo{Name: nfd, Version: older.Version.Update(device2.Short())}
+	other := protocol.FileInfo{Name: "other"}
+
+	files := normalizeIndexNames(norm.NFC, []protocol.FileInfo{other, older, newer})
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 files, got %v", files)
+	}
+	if files[0].Name != "other" {
+		t.Errorf("Unexpected first file %v", files[0])
+	}
+	if files[1].Name != nfc || !files[1].Version.Equal(newer.Version) {
+		t.Errorf("Expected the newer duplicate with NFC name, got %v", files[1])
+	}
+}
+
+func TestNormalizeFolder(t *testing.T) {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		t.Skip("Filesystem normalizes names on its own")
+	}
+
+	w, fcfg := tmpDefaultWrapper()
+	fcfg.UnicodeNormalization = config.NormalizationNFD
+	fcfg.AutoNormalize = false
+	w.SetFolder(fcfg)
+	m := setupModel(w)
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	dirNFC, dirNFD := "\xC3\x85", "\x41\xCC\x8A"   // 'Å'
+	fileNFC, fileNFD := "\xC3\x84", "\x41\xCC\x88" // 'Ä'
+	dir := fcfg.Filesystem().URI()
+	must(t, os.Mkdir(filepath.Join(dir, dirNFC), 0755))
+	must(t, ioutil.WriteFile(filepath.Join(dir, dirNFC, fileNFC), []byte("data"), 0644))
+	must(t, ioutil.WriteFile(filepath.Join(dir, "plain"), []byte("data"), 0644))
+
+	expected := []NormalizationFix{
+		{Name: dirNFC, Normalized: dirNFD, Action: normalizationRename},
+		{Name: filepath.Join(dirNFC, fileNFC), Normalized: filepath.Join(dirNFD, fileNFD), Action: normalizationRename},
+	}
+
+	fixes, err := m.NormalizeFolder("default", true)
+	must(t, err)
+	if !reflect.DeepEqual(fixes, expected) {
+		t.Errorf("Dry run gave %v, expected %v", fixes, expected)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, dirNFC, fileNFC)); err != nil {
+		t.Fatal("Dry run changed files:", err)
+	}
+
+	fixes, err = m.NormalizeFolder("default", false)
+	must(t, err)
+	if !reflect.DeepEqual(fixes, expected) {
+		t.Errorf("Got %v, expected %v", fixes, expected)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, dirNFD, fileNFD)); err != nil {
+		t.Fatal("File wasn't renamed:", err)
+	}
+	if _, ok := m.CurrentFolderFile("default", filepath.Join(dirNFD, fileNFD)); !ok {
+		t.Error("Renamed file wasn't scanned")
+	}
+
+	if fixes, err := m.NormalizeFolder("default", false); err != nil || len(fixes) != 0 {
+		t.Errorf("Expected nothing left to fix, got %v, %v", fixes, err)
+	}
+}
+
+func TestMaxSyncingFolders(t *testing.T) {
+	w := createTmpWrapper(defaultCfg.Copy())
+	defer os.Remove(w.ConfigPath())
+	opts := w.Options()
+	opts.RawMaxSyncingFolders = 1
+	w.SetOptions(opts)
+	m := setupModel(w)
+	defer cleanupModel(m)
+
+	if max := m.folderPullLimiter.max; max != 1 {
+		t.Errorf("Got a limit of %d syncing folders, expected 1", max)
+	}
+
+	opts.RawMaxSyncingFolders = 3
+	waiter, err := w.SetOptions(opts)
+	must(t, err)
+	waiter.Wait()
+	if max := m.folderPullLimiter.max; max != 3 {
+		t.Errorf("Got a limit of %d syncing folders after changing it, expected 3", max)
+	}
+}
+
+func TestFolderMaintenance(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	m := setupModel(w)
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	must(t, m.SetFolderMaintenance("default", true))
+	if state, _, _ := m.State("default"); state != "maintenance" {
+		t.Errorf("Got state %q in maintenance", state)
+	}
+
+	// Local changes aren't picked up
+	must(t, ioutil.WriteFile(filepath.Join(fcfg.Filesystem().URI(), "file"), []byte("data"), 0644))
+	if err := m.ScanFolder("default"); err != errFolderInMaintenance {
+		t.Errorf("Scanning in maintenance: got %v, expected %v", err, errFolderInMaintenance)
+	}
+	if _, ok := m.CurrentFolderFile("default", "file"); ok {
+		t.Error("File was scanned in maintenance")
+	}
+
+	// Until maintenance ends
+	must(t, m.SetFolderMaintenance("default", false))
+	// Scanning waits for the rescan after maintenance to finish
+	must(t, m.ScanFolder("default"))
+	if state, _, _ := m.State("default"); state != "idle" {
+		t.Errorf("Got state %q after maintenance", state)
+	}
+	if _, ok := m.CurrentFolderFile("default", "file"); !ok {
+		t.Error("File wasn't scanned after maintenance")
+	}
+}
+
+func TestEditIgnores(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	m := setupModel(w)
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	must(t, m.SetIgnores(fcfg.ID, []string{"// managed by hand", "foo", "!bar"}))
+	lines, _, err := m.GetIgnores(fcfg.ID)
+	must(t, err)
+	version := ignore.Version(lines)
+
+	res, err := m.EditIgnores(fcfg.ID, version, []string{"baz", "foo"}, []string{"foo", "qux"})
+	must(t, err)
+	if !reflect.DeepEqual(res.Added, []string{"baz", "foo"}) || !reflect.DeepEqual(res.Removed, []string{"foo"}) || !reflect.DeepEqual(res.Missing, []string{"qux"}) {
+		t.Errorf("Unexpected result %+v", res)
+	}
+	expected := []string{"// managed by hand", "!bar", "baz", "foo"}
+	lines, _, err = m.GetIgnores(fcfg.ID)
+	must(t, err)
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Incorrect ignores: %v != %v", lines, expected)
+	}
+	if res.Version != ignore.Version(expected) {
+		t.Error("Unexpected version after the edit")
+	}
+
+	// The version we had is outdated now.
+	if _, err := m.EditIgnores(fcfg.ID, version, []string{"quux"}, nil); err != ErrIgnoresChanged {
+		t.Errorf("Expected %v, got %v", ErrIgnoresChanged, err)
+	}
+
+	// Contradicting patterns change nothing.
+	res, err = m.EditIgnores(fcfg.ID, "", []string{"new", "bar"}, []string{"baz"})
+	if err != ErrIgnoresConflict {
+		t.Errorf("Expected %v, got %v", ErrIgnoresConflict, err)
+	}
+	if !reflect.DeepEqual(res.Conflicts, []string{"bar"}) {
+		t.Errorf("Unexpected conflicts %v", res.Conflicts)
+	}
+	lines, _, err = m.GetIgnores(fcfg.ID)
+	must(t, err)
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Incorrect ignores: %v != %v", lines, expected)
+	}
+}
+
+func TestUnignore(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	m := setupModel(w)
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	dir := fcfg.Filesystem().URI()
+	must(t, os.MkdirAll(filepath.Join(dir, "sub"), 0755))
+	must(t, ioutil.WriteFile(filepath.Join(dir, "sub", "file"), []byte("data"), 0644))
+	must(t, m.SetIgnores(fcfg.ID, []string{"/sub", "other", "// a comment"}))
+	if _, ok := m.CurrentFolderFile(fcfg.ID, filepath.Join("sub", "file")); ok {
+		t.Fatal("Ignored file was scanned")
+	}
+
+	res, err := m.Unignore(fcfg.ID, "", []string{"/sub", "// a comment", "missing"})
+	must(t, err)
+	if res.FullScan || !reflect.DeepEqual(res.Rescanned, []string{"sub"}) || !reflect.DeepEqual(res.Missing, []string{"missing"}) {
+		t.Errorf("Unexpected result %+v", res)
+	}
+	if _, ok := m.CurrentFolderFile(fcfg.ID, filepath.Join("sub", "file")); !ok {
+		t.Error("Unignored file wasn't scanned")
+	}
+
+	// Patterns that match anywhere need a full scan
+	res, err = m.Unignore(fcfg.ID, "", []string{"other"})
+	must(t, err)
+	if !res.FullScan || res.Rescanned != nil {
+		t.Errorf("Unexpected result %+v", res)
+	}
+}
+
+func TestIgnoredUsage(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	m := setupModel(w)
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	dir := fcfg.Filesystem().URI()
+	files := map[string]int{
+		filepath.Join("big", "a"):       100,
+		filepath.Join("big", "b"):       50,
+		"one.log":                       5,
+		filepath.Join("sub", "two.log"): 7,
+		"keep.txt":                      10,
+	}
+	for name, size := range files {
+		must(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755))
+		must(t, ioutil.WriteFile(filepath.Join(dir, name), make([]byte, size), 0644))
+	}
+	must(t, m.SetIgnores(fcfg.ID, []string{"/big", "*.log"}))
+
+	usage, err := m.IgnoredUsage(fcfg.ID)
+	must(t, err)
+	if usage.Files != 4 || usage.Directories != 1 || usage.Bytes != 162 {
+		t.Errorf("Unexpected totals %+v", usage)
+	}
+	if len(usage.Patterns) != 2 {
+		t.Fatalf("Expected two patterns, got %+v", usage.Patterns)
+	}
+	if p := usage.Patterns[0]; p.Pattern != "/big" || p.Line != 1 || p.Files != 2 || p.Directories != 1 || p.Bytes != 150 {
+		t.Errorf("Unexpected usage of the first pattern %+v", p)
+	}
+	if p := usage.Patterns[1]; p.Pattern != "*.log" || p.Line != 2 || p.Files != 2 || p.Directories != 0 || p.Bytes != 12 {
+		t.Errorf("Unexpected usage of the second pattern %+v", p)
+	}
+}
+
+func TestExplainIgnores(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	m := setupModel(w)
+	defer cleanupModelAndRemoveDir(m, fcfg.Filesystem().URI())
+
+	must(t, m.SetIgnores(fcfg.ID, []string{"!keep", "*.tmp"}))
+
+	res, err := m.ExplainIgnores(fcfg.ID, nil, []string{"dir/a.tmp", "keep", "other"})
+	must(t, err)
+	if len(res) != 3 || !res[0].Ignored || res[0].Pattern != "*.tmp" || res[0].Line != 2 || res[0].Path != "dir/a.tmp" || res[1].Ignored || res[1].Pattern != "!keep" || res[2].Reason != ignore.ReasonNone {
+		t.Errorf("Unexpected explanations %+v", res)
+	}
+
+	// Patterns not saved yet
+	res, err = m.ExplainIgnores(fcfg.ID, []string{"other"}, []string{"dir/a.tmp", "other"})
+	must(t, err)
+	if len(res) != 2 || res[0].Ignored || !res[1].Ignored || res[1].Line != 1 {
+		t.Errorf("Unexpected explanations %+v", res)
+	}
+}
+
+func TestSyncIgnoreFiles(t *testing.T) {
+	tmpDir := createTmpDir()
+	defer os.RemoveAll(tmpDir)
+	ffs := fs.NewFilesystem(fs.FilesystemTypeBasic, tmpDir)
+	must(t, ignore.WriteIgnores(ffs, ".stignore", []string{"foo"}))
+
+	// The synced copy is created from .stignore.
+	applied, err := syncIgnoreFiles(ffs)
+	must(t, err)
+	if applied {
+		t.Error("Unexpected change of .stignore")
+	}
+	synced, err := ignore.ReadIgnores(ffs, ignore.SyncedIgnoresFile)
+	must(t, err)
+	if !reflect.DeepEqual(synced, []string{"foo"}) {
+		t.Errorf("Incorrect synced ignores %v", synced)
+	}
+
+	// Patterns from another device, with nothing left.
+	fd, err := ffs.Create(ignore.SyncedIgnoresFile)
+	must(t, err)
+	must(t, fd.Close())
+	future := time.Now().Add(time.Hour)
+	must(t, ffs.Chtimes(ignore.SyncedIgnoresFile, future, future))
+	applied, err = syncIgnoreFiles(ffs)
+	must(t, err)
+	if !applied {
+		t.Error("Expected the synced patterns to be applied")
+	}
+	if _, err := ffs.Lstat(".stignore"); !fs.IsNotExist(err) {
+		t.Error("Expected .stignore to be removed, got", err)
+	}
+
+	// A newer .stignore is copied.
+	must(t, ignore.WriteIgnores(ffs, ".stignore", []string{"bar"}))
+	must(t, ffs.Chtimes(".stignore", future.Add(time.Hour), future.Add(time.Hour)))
+	applied, err = syncIgnoreFiles(ffs)
+	must(t, err)
+	if applied {
+		t.Error("Unexpected change of .stignore")
+	}
+	synced, err = ignore.ReadIgnores(ffs, ignore.SyncedIgnoresFile)
+	must(t, err)
+	if !reflect.DeepEqual(synced, []string{"bar"}) {
+		t.Errorf("Incorrect synced ignores %v", synced)
+	}
+}
+
+func TestBrowseCursorsEvictLeastRecent(t *testing.T) {
+	b := newBrowseCursors()
+	b.max = 2
+	defer b.releaseAll()
+
+	first := b.add(&browseCursor{})
+	second := b.add(&browseCursor{})
+	third := b.add(&browseCursor{})
+
+	if _, ok := b.take(first); ok {
+		t.Error("the least recently used listing should have been evicted")
+	}
+	for _, token := range []string{second, third} {
+		if _, ok := b.take(token); !ok {
+			t.Error("a recent listing should be kept")
+		}
+	}
+}
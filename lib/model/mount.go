@@ -0,0 +1,256 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/fuse"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/scanner"
+	"github.com/syncthing/syncthing/lib/sync"
+	"github.com/syncthing/syncthing/lib/util"
+	"github.com/thejerf/suture"
+)
+
+// The amount of blocks read from other devices that's kept in memory for
+// the mounts of all folders, for them not to be requested again by reads
+// close to each other.
+const mountCacheBytes = 64 << 20
+
+var mountCache = newBlockCache(mountCacheBytes)
+
+// folderMount mounts the global versions of the files of a folder read
+// only at its mount path. What the folder has is read from disk, the rest
+// from the connected devices that have it, as it's read. With everything
+// ignored, that gives the files of a huge folder on demand, without
+// keeping them locally.
+type folderMount struct {
+	suture.Service
+	model *model
+	fset  *db.FileSet
+	ffs   fs.Filesystem
+	cfg   config.FolderConfiguration
+}
+
+func newFolderMount(m *model, fset *db.FileSet, ffs fs.Filesystem, cfg config.FolderConfiguration) *folderMount {
+	fm := &folderMount{
+		model: m,
+		fset:  fset,
+		ffs:   ffs,
+		cfg:   cfg,
+	}
+	fm.Service = util.AsService(fm.serve, fm.String())
+	return fm
+}
+
+func (fm *folderMount) serve(ctx context.Context) {
+	path, err := fs.ExpandTilde(fm.cfg.MountPath)
+	if err == nil {
+		l.Infof("Mounting %s at %s", fm.cfg.Description(), path)
+		err = fuse.Serve(ctx, path, &globalTree{ctx: ctx, fm: fm})
+	}
+	if err != nil && ctx.Err() == nil {
+		// Not retried until the folder is restarted.
+		l.Warnf("Failed to mount %s at %s: %v", fm.cfg.Description(), fm.cfg.MountPath, err)
+		<-ctx.Done()
+	}
+}
+
+func (fm *folderMount) String() string {
+	return fmt.Sprintf("folderMount/%s@%p", fm.cfg.ID, fm)
+}
+
+// globalTree is the fuse.Tree of the global versions of the files of a
+// folder.
+type globalTree struct {
+	ctx context.Context
+	fm  *folderMount
+}
+
+func (t *globalTree) Stat(name string) (fuse.Entry, error) {
+	if name == "." {
+		return fuse.Entry{Name: name, Dir: true, Permissions: 0755}, nil
+	}
+	snap := t.fm.fset.Snapshot()
+	defer snap.Release()
+	f, ok := snap.GetGlobalTruncated(name)
+	if !ok || f.IsDeleted() || f.IsInvalid() {
+		return fuse.Entry{}, os.ErrNotExist
+	}
+	return mountEntry(f), nil
+}
+
+func (t *globalTree) List(dir string) ([]fuse.Entry, error) {
+	prefix := dir
+	if prefix == "." {
+		prefix = ""
+	}
+	snap := t.fm.fset.Snapshot()
+	defer snap.Release()
+	var entries []fuse.Entry
+	snap.WithGlobalChildrenTruncated(prefix, "", func(fi db.FileIntf) bool {
+		if !fi.IsDeleted() && !fi.IsInvalid() {
+			entries = append(entries, mountEntry(fi))
+		}
+		return true
+	})
+	return entries, nil
+}
+
+func (t *globalTree) ReadLink(name string) (string, error) {
+	snap := t.fm.fset.Snapshot()
+	defer snap.Release()
+	f, ok := snap.GetGlobalTruncated(name)
+	if !ok || f.IsDeleted() || f.IsInvalid() || !f.IsSymlink() {
+		return "", os.ErrNotExist
+	}
+	return f.SymlinkTarget, nil
+}
+
+func (t *globalTree) ReadAt(name string, p []byte, off int64) (int, error) {
+	snap := t.fm.fset.Snapshot()
+	defer snap.Release()
+	f, ok := snap.GetGlobal(name)
+	if !ok || f.IsDeleted() || f.IsInvalid() || f.Type != protocol.FileInfoTypeFile {
+		return 0, os.ErrNotExist
+	}
+
+	if cur, ok := snap.Get(protocol.LocalDeviceID, name); ok && !cur.IsDeleted() && !cur.IsInvalid() && cur.Version.Equal(f.Version) {
+		fd, err := t.fm.ffs.Open(name)
+		if err != nil {
+			return 0, err
+		}
+		defer fd.Close()
+		return fd.ReadAt(p, off)
+	}
+
+	n := 0
+	for _, block := range f.Blocks {
+		pos := off + int64(n)
+		if n == len(p) {
+			break
+		}
+		if pos >= block.Offset+int64(block.Size) {
+			continue
+		}
+		data, err := t.block(snap, f, block)
+		if err != nil {
+			return n, err
+		}
+		n += copy(p[n:], data[pos-block.Offset:])
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// block returns the data of the block, from the cache or otherwise from
+// the first connected device that has it.
+func (t *globalTree) block(snap *db.Snapshot, f protocol.FileInfo, block protocol.BlockInfo) ([]byte, error) {
+	if data, ok := mountCache.get(block.Hash); ok {
+		return data, nil
+	}
+	for _, dev := range snap.Availability(f.Name) {
+		if _, ok := t.fm.model.Connection(dev); !ok {
+			continue
+		}
+		data, err := t.fm.model.requestGlobal(t.ctx, dev, t.fm.cfg.ID, f.Name, block.Offset, int(block.Size), block.Hash, block.WeakHash, false)
+		if err != nil {
+			l.Debugf("%v mount read of %s from %s: %v", t.fm, f.Name, dev, err)
+			continue
+		}
+		if !scanner.Validate(data, block.Hash, block.WeakHash) {
+			l.Debugf("%v mount read of %s from %s: hash mismatch", t.fm, f.Name, dev)
+			continue
+		}
+		mountCache.add(block.Hash, data)
+		return data, nil
+	}
+	return nil, errNotAvailable
+}
+
+func mountEntry(f db.FileIntf) fuse.Entry {
+	entry := fuse.Entry{
+		Name:        filepath.Base(f.FileName()),
+		Dir:         f.IsDirectory(),
+		Symlink:     f.IsSymlink(),
+		Size:        f.FileSize(),
+		ModTime:     f.ModTime(),
+		Permissions: f.FilePermissions() & 0777,
+	}
+	if !f.HasPermissionBits() {
+		entry.Permissions = 0644
+		if entry.Dir {
+			entry.Permissions = 0755
+		}
+	}
+	if entry.Dir {
+		entry.Size = 0
+	}
+	return entry
+}
+
+// blockCache keeps the most recently used blocks, by hash, up to a total
+// size.
+type blockCache struct {
+	max    int
+	mut    sync.Mutex
+	size   int
+	order  *list.List // of *cachedBlock, most recently used first
+	blocks map[string]*list.Element
+}
+
+type cachedBlock struct {
+	hash string
+	data []byte
+}
+
+func newBlockCache(max int) *blockCache {
+	return &blockCache{
+		max:    max,
+		mut:    sync.NewMutex(),
+		order:  list.New(),
+		blocks: make(map[string]*list.Element),
+	}
+}
+
+func (c *blockCache) get(hash []byte) ([]byte, bool) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	el, ok := c.blocks[string(hash)]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*cachedBlock).data, true
+}
+
+func (c *blockCache) add(hash, data []byte) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if _, ok := c.blocks[string(hash)]; ok || len(data) > c.max {
+		return
+	}
+	c.blocks[string(hash)] = c.order.PushFront(&cachedBlock{hash: string(hash), data: data})
+	c.size += len(data)
+	for c.size > c.max {
+		el := c.order.Back()
+		b := c.order.Remove(el).(*cachedBlock)
+		delete(c.blocks, b.hash)
+		c.size -= len(b.data)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"path/filepath"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// moveCandidates are the local files that are to be deleted while pulling,
+// which new files may have been moved or renamed from. Files that were
+// moved unchanged are handled by a plain rename, so these are for the
+// ones that were also changed on the way, or are shifted in a way that
+// leaves no blocks in common.
+type moveCandidates struct {
+	files  []protocol.FileInfo
+	blocks map[string][]int // block hash -> indexes into files, built on first use
+}
+
+func (c *moveCandidates) add(file protocol.FileInfo) {
+	c.files = append(c.files, file)
+	c.blocks = nil
+}
+
+func (c *moveCandidates) index() {
+	c.blocks = make(map[string][]int)
+	for i, file := range c.files {
+		for _, block := range file.Blocks {
+			key := string(block.Hash)
+			if idxs := c.blocks[key]; len(idxs) > 0 && idxs[len(idxs)-1] == i {
+				// Repeated block within the same file
+				continue
+			}
+			c.blocks[key] = append(c.blocks[key], i)
+		}
+	}
+}
+
+// origin returns the file that the given one was most likely moved from,
+// among those still pending deletion: the one with the most blocks in
+// common, or failing that one with the same name in another directory.
+func (c *moveCandidates) origin(file protocol.FileInfo, pending map[string]protocol.FileInfo) (protocol.FileInfo, bool) {
+	if len(c.files) == 0 {
+		return protocol.FileInfo{}, false
+	}
+	if c.blocks == nil {
+		c.index()
+	}
+
+	counts := make(map[int]int)
+	seen := make(map[string]struct{}, len(file.Blocks))
+	for _, block := range file.Blocks {
+		key := string(block.Hash)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		for _, i := range c.blocks[key] {
+			counts[i]++
+		}
+	}
+
+	best, bestCount := -1, 0
+	for i, count := range counts {
+		if _, ok := pending[c.files[i].Name]; !ok {
+			continue
+		}
+		if count > bestCount || count == bestCount && i < best {
+			best, bestCount = i, count
+		}
+	}
+	if best >= 0 {
+		return c.files[best], true
+	}
+
+	base := filepath.Base(file.Name)
+	for _, cand := range c.files {
+		if _, ok := pending[cand.Name]; ok && filepath.Base(cand.Name) == base {
+			return cand, true
+		}
+	}
+	return protocol.FileInfo{}, false
+}
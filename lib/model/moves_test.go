@@ -0,0 +1,61 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestMoveCandidates(t *testing.T) {
+	file := func(name string, hashes ...string) protocol.FileInfo {
+		f := protocol.FileInfo{Name: filepath.FromSlash(name)}
+		for _, h := range hashes {
+			f.Blocks = append(f.Blocks, protocol.BlockInfo{Hash: []byte(h)})
+		}
+		return f
+	}
+
+	var c moveCandidates
+	pending := make(map[string]protocol.FileInfo)
+	for _, f := range []protocol.FileInfo{
+		file("one", "a", "b", "c"),
+		file("two", "a", "x", "x", "x"),
+		file("dir/three", "y"),
+	} {
+		c.add(f)
+		pending[f.Name] = f
+	}
+
+	cases := []struct {
+		file   protocol.FileInfo
+		origin string
+	}{
+		{file("new", "a", "b", "d"), "one"},
+		{file("new", "x", "a"), "two"},
+		{file("other/three", "z"), filepath.FromSlash("dir/three")},
+		{file("new", "z"), ""},
+	}
+	for _, tc := range cases {
+		origin, ok := c.origin(tc.file, pending)
+		if tc.origin == "" {
+			if ok {
+				t.Errorf("Got origin %v for %v, expected none", origin.Name, tc.file.Name)
+			}
+		} else if origin.Name != tc.origin {
+			t.Errorf("Got origin %v for %v, expected %v", origin.Name, tc.file.Name, tc.origin)
+		}
+	}
+
+	// Once deleted, or renamed to something else, it's no longer an origin
+	delete(pending, "one")
+	if origin, _ := c.origin(file("new", "a", "b", "d"), pending); origin.Name != "two" {
+		t.Errorf("Got origin %v, expected two", origin.Name)
+	}
+}
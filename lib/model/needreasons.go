@@ -0,0 +1,152 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"time"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// The reasons an item can be out of sync, as best as can be told.
+const (
+	NeedPending           = "pending"           // nothing in the way, it's waiting its turn
+	NeedFailed            = "failed"            // pulling it failed, see the error
+	NeedNotAvailable      = "notAvailable"      // no connected device has it
+	NeedDevicePaused      = "devicePaused"      // only paused devices have it
+	NeedIgnoredRemotely   = "ignoredRemotely"   // the devices that have it ignore it
+	NeedIgnoreDelete      = "ignoreDelete"      // it was deleted and the folder ignores deletes
+	NeedFolderPaused      = "folderPaused"      // the folder is paused, here or on the remote device
+	NeedOutsideSyncWindow = "outsideSyncWindow" // the folder doesn't sync at this time
+	NeedNotConnected      = "notConnected"      // the remote device isn't connected
+)
+
+// A NeedReason is why an item is out of sync.
+type NeedReason struct {
+	Reason string `json:"reason"`
+	Error  string `json:"error,omitempty"`
+}
+
+// NeedReasons returns why each of the given files that the folder needs is
+// not in sync yet.
+func (m *model) NeedReasons(folder string, files []string) map[string]NeedReason {
+	cfg, ok := m.cfg.Folder(folder)
+	if !ok {
+		return nil
+	}
+
+	reasons := make(map[string]NeedReason, len(files))
+	common := ""
+	switch {
+	case cfg.Paused:
+		common = NeedFolderPaused
+	case !cfg.InSyncWindow(time.Now()):
+		common = NeedOutsideSyncWindow
+	}
+
+	m.fmut.RLock()
+	rf, rfOk := m.folderFiles[folder]
+	runner, runnerOk := m.folderRunners[folder]
+	m.fmut.RUnlock()
+
+	pullErrors := make(map[string]string)
+	if runnerOk {
+		for _, fe := range runner.Errors() {
+			pullErrors[fe.Path] = fe.Err
+		}
+	}
+
+	if !rfOk || common != "" {
+		if common == "" {
+			common = NeedPending
+		}
+		for _, name := range files {
+			reasons[name] = NeedReason{Reason: common}
+		}
+		return reasons
+	}
+
+	snap := rf.Snapshot()
+	defer snap.Release()
+
+	m.pmut.RLock()
+	defer m.pmut.RUnlock()
+
+	for _, name := range files {
+		if err, ok := pullErrors[name]; ok {
+			reasons[name] = NeedReason{Reason: NeedFailed, Error: err}
+			continue
+		}
+
+		global, ok := snap.GetGlobalTruncated(name)
+		switch {
+		case !ok:
+			reasons[name] = NeedReason{Reason: NeedPending}
+		case global.IsInvalid():
+			reasons[name] = NeedReason{Reason: NeedIgnoredRemotely}
+		case global.IsDeleted() && cfg.IgnoreDelete:
+			reasons[name] = NeedReason{Reason: NeedIgnoreDelete}
+		case global.IsDeleted() || global.IsDirectory() || global.IsSymlink():
+			// Nothing to get from anyone
+			reasons[name] = NeedReason{Reason: NeedPending}
+		default:
+			reasons[name] = NeedReason{Reason: m.availabilityReasonPLocked(folder, snap.Availability(name))}
+		}
+	}
+
+	return reasons
+}
+
+// availabilityReasonPLocked returns whether there is a device to pull a
+// file from, given the devices that have it. Must be called with pmut held.
+func (m *model) availabilityReasonPLocked(folder string, devices []protocol.DeviceID) string {
+	paused := false
+	for _, dev := range devices {
+		if cfg, ok := m.cfg.Device(dev); ok && cfg.Paused || m.remoteFolderPausedPLocked(dev, folder) {
+			paused = true
+			continue
+		}
+		if _, ok := m.conn[dev]; ok {
+			return NeedPending
+		}
+	}
+	if paused {
+		return NeedDevicePaused
+	}
+	return NeedNotAvailable
+}
+
+func (m *model) remoteFolderPausedPLocked(device protocol.DeviceID, folder string) bool {
+	for _, paused := range m.remotePausedFolders[device] {
+		if paused == folder {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoteNeedReason returns why the items the remote device needs in the
+// folder are not in sync yet. It's the same for all of them, as far as we
+// can tell from here.
+func (m *model) RemoteNeedReason(folder string, device protocol.DeviceID) NeedReason {
+	if cfg, ok := m.cfg.Folder(folder); ok && cfg.Paused {
+		return NeedReason{Reason: NeedFolderPaused}
+	}
+	if cfg, ok := m.cfg.Device(device); ok && cfg.Paused {
+		return NeedReason{Reason: NeedDevicePaused}
+	}
+
+	m.pmut.RLock()
+	defer m.pmut.RUnlock()
+	if _, ok := m.conn[device]; !ok {
+		return NeedReason{Reason: NeedNotConnected}
+	}
+	if m.remoteFolderPausedPLocked(device, folder) {
+		return NeedReason{Reason: NeedFolderPaused}
+	}
+	return NeedReason{Reason: NeedPending}
+}
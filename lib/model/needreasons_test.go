@@ -0,0 +1,67 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"os"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestNeedReasons(t *testing.T) {
+	w := createTmpWrapper(defaultCfg.Copy())
+	defer os.Remove(w.ConfigPath())
+	fcfg, _ := w.Folder("default")
+	fcfg.IgnoreDelete = true
+	waiter, _ := w.SetFolder(fcfg)
+	waiter.Wait()
+	m := setupModel(w)
+	defer cleanupModel(m)
+
+	version := protocol.Vector{}.Update(device1.Short())
+	files := []protocol.FileInfo{
+		{Name: "remote", Version: version, Blocks: []protocol.BlockInfo{{Size: 100, Hash: []byte("some hash bytes")}}, Size: 100},
+		{Name: "ignored", Version: version, RawInvalid: true},
+		{Name: "deleted", Version: version, Deleted: true},
+	}
+	m.fmut.RLock()
+	fset := m.folderFiles["default"]
+	m.fmut.RUnlock()
+	fset.Update(device1, files)
+
+	expected := map[string]string{
+		"remote":  NeedNotAvailable,
+		"ignored": NeedIgnoredRemotely,
+		"deleted": NeedIgnoreDelete,
+		"unknown": NeedPending,
+	}
+	check := func() {
+		t.Helper()
+		reasons := m.NeedReasons("default", []string{"remote", "ignored", "deleted", "unknown"})
+		for name, reason := range expected {
+			if reasons[name].Reason != reason {
+				t.Errorf("Got reason %q for %v, expected %q", reasons[name].Reason, name, reason)
+			}
+		}
+	}
+	check()
+	if reason := m.RemoteNeedReason("default", device1); reason.Reason != NeedNotConnected {
+		t.Errorf("Got remote reason %q, expected %q", reason.Reason, NeedNotConnected)
+	}
+
+	// With the device that has it paused
+	dev, _ := w.Device(device1)
+	dev.Paused = true
+	waiter, _ = w.SetDevice(dev)
+	waiter.Wait()
+	expected["remote"] = NeedDevicePaused
+	check()
+	if reason := m.RemoteNeedReason("default", device1); reason.Reason != NeedDevicePaused {
+		t.Errorf("Got remote reason %q, expected %q", reason.Reason, NeedDevicePaused)
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"path/filepath"
+	"unicode/utf8"
+
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/scanner"
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizationFix describes a file name that isn't in the normalization
+// form of its folder, and what was (or on a dry run, would be) done about
+// it: "rename" it to the normalized name, "merge" it into an identical file
+// already having that name, or nothing as it's a "conflict" with a
+// different item having that name.
+type NormalizationFix struct {
+	Name       string `json:"name"`
+	Normalized string `json:"normalized"`
+	Action     string `json:"action"`
+	Error      string `json:"error,omitempty"`
+}
+
+const (
+	normalizationRename   = "rename"
+	normalizationMerge    = "merge"
+	normalizationConflict = "conflict"
+)
+
+// normalizeIndexNames brings the names of files received from a remote
+// device into the given normalization form. Files whose names end up the
+// same are copies on the remote side that only differ in their
+// normalization; of those, only the newest version, or the one winning a
+// conflict, is kept.
+func normalizeIndexNames(form norm.Form, files []protocol.FileInfo) []protocol.FileInfo {
+	var seen map[string]int
+	out := files[:0]
+	for _, f := range files {
+		normName := form.String(f.Name)
+		if normName == f.Name && seen == nil {
+			out = append(out, f)
+			continue
+		}
+		if seen == nil {
+			// There is something to normalize, so from here on we have to
+			// keep track of the names we passed.
+			seen = make(map[string]int, len(files))
+			for i, prev := range out {
+				seen[prev.Name] = i
+			}
+		}
+		f.Name = normName
+		if i, ok := seen[normName]; ok {
+			l.Debugf("Normalization-variant duplicates of %q in index", normName)
+			if prev := out[i]; f.Version.GreaterEqual(prev.Version) || f.Version.Concurrent(prev.Version) && f.WinsConflict(prev) {
+				out[i] = f
+			}
+			continue
+		}
+		seen[normName] = len(out)
+		out = append(out, f)
+	}
+	return out
+}
+
+// NormalizeFolder fixes the names of the files in a folder that aren't in
+// the normalization form of the folder, for example because they predate
+// the policy or weren't scanned due to their names. The affected paths are
+// rescanned afterwards. With dryRun nothing is changed and the fixes that
+// would be made are returned.
+func (m *model) NormalizeFolder(folder string, dryRun bool) ([]NormalizationFix, error) {
+	m.fmut.RLock()
+	cfg, ok := m.folderCfgs[folder]
+	ignores := m.folderIgnores[folder]
+	err := m.checkFolderRunningLocked(folder)
+	m.fmut.RUnlock()
+	if !ok {
+		return nil, errFolderMissing
+	}
+	if err != nil && !dryRun {
+		return nil, err
+	}
+
+	form := cfg.UnicodeNormalization.Form()
+	filesystem := m.folderFilesystem(cfg)
+
+	// Collect the names first, as renaming directories while walking them
+	// doesn't work. They come in order, directories before their contents.
+	var names []string
+	err = filesystem.Walk(".", func(path string, info fs.FileInfo, err error) error {
+		skip := error(nil)
+		if info != nil && info.IsDir() {
+			skip = fs.SkipDir
+		}
+		switch {
+		case err != nil:
+			return skip
+		case path == ".":
+			return nil
+		case !utf8.ValidString(path), fs.IsInternal(path), ignores.Match(path).IsIgnored():
+			return skip
+		case fs.IsTemporary(path):
+			return nil
+		}
+		names = append(names, path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Know where renamed directories went when handling the items within.
+	renamed := make(map[string]string)
+	var fixes []NormalizationFix
+	var subs []string
+	for _, name := range names {
+		dir, base := filepath.Split(name)
+		dir = filepath.Clean(dir)
+		path, normDir := name, dir
+		if newDir, ok := renamed[dir]; ok {
+			normDir = newDir
+			if !dryRun {
+				path = filepath.Join(newDir, base)
+			}
+		}
+		normBase := form.String(base)
+		if normBase == base {
+			if normDir != dir {
+				renamed[name] = filepath.Join(normDir, base)
+			}
+			continue
+		}
+
+		fix := NormalizationFix{
+			Name:       name,
+			Normalized: filepath.Join(normDir, normBase),
+			Action:     normalizationRename,
+		}
+		newPath, merged, err := scanner.NormalizeOnDisk(filesystem, path, filepath.Join(filepath.Dir(path), normBase), dryRun)
+		switch {
+		case scanner.IsUTF8Conflict(err):
+			fix.Action = normalizationConflict
+		case err != nil:
+			fix.Error = err.Error()
+		case merged:
+			fix.Action = normalizationMerge
+		default:
+			if !dryRun {
+				fix.Normalized = newPath
+			}
+			renamed[name] = fix.Normalized
+		}
+		if err == nil && !dryRun {
+			subs = append(subs, name, newPath)
+		}
+		fixes = append(fixes, fix)
+	}
+
+	if len(subs) > 0 {
+		if err := m.ScanFolderSubdirs(folder, subs); err != nil {
+			return fixes, err
+		}
+	}
+	return fixes, nil
+}
@@ -0,0 +1,161 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+const (
+	operationProgressInterval = time.Second
+	maxOperationReportFiles   = 1000
+)
+
+var (
+	errOperationRunning = errors.New("another operation is running on the folder")
+	errNoOperation      = errors.New("no operation has run on the folder")
+)
+
+// The actions of the operations, as listed in their reports
+const (
+	operationActionDeleted    = "deleted"    // removed by a revert, or by an override as we don't have it
+	operationActionReset      = "reset"      // to be replaced by the global version by a revert
+	operationActionOverridden = "overridden" // our version made the global one by an override
+)
+
+// An OperationReport describes an operation on all of a folder, like a
+// revert or an override, while it runs and when it's done.
+type OperationReport struct {
+	Folder     string            `json:"folder"`
+	Operation  string            `json:"operation"`
+	Started    time.Time         `json:"started"`
+	Finished   time.Time         `json:"finished"` // zero while running
+	Cancelled  bool              `json:"cancelled"`
+	TotalItems int               `json:"totalItems"` // to process, as known when starting
+	TotalBytes int64             `json:"totalBytes"`
+	Items      int               `json:"items"` // processed so far
+	Bytes      int64             `json:"bytes"`
+	Changes    map[string]int    `json:"changes"` // the number of items by action
+	Files      []OperationChange `json:"files"`   // the first items changed
+	Truncated  bool              `json:"truncated"`
+}
+
+// An OperationChange is an item changed by an operation.
+type OperationChange struct {
+	Name   string `json:"name"`
+	Action string `json:"action"`
+}
+
+func (r OperationReport) copy() OperationReport {
+	cp := r
+	cp.Changes = make(map[string]int, len(r.Changes))
+	for action, n := range r.Changes {
+		cp.Changes[action] = n
+	}
+	cp.Files = append([]OperationChange(nil), r.Files...)
+	return cp
+}
+
+// A folderOperation tracks an operation on a folder, emitting
+// FolderOperationProgress events while it runs and a
+// FolderOperationFinished event when done. It can be cancelled, which the
+// operation checks for between items.
+type folderOperation struct {
+	ctx       context.Context
+	cancel    context.CancelFunc
+	evLogger  events.Logger
+	mut       sync.Mutex
+	report    OperationReport
+	lastEvent time.Time
+}
+
+func newFolderOperation(folder, operation string, totalItems int, totalBytes int64, evLogger events.Logger) *folderOperation {
+	ctx, cancel := context.WithCancel(context.Background())
+	now := time.Now()
+	return &folderOperation{
+		ctx:      ctx,
+		cancel:   cancel,
+		evLogger: evLogger,
+		mut:      sync.NewMutex(),
+		report: OperationReport{
+			Folder:     folder,
+			Operation:  operation,
+			Started:    now,
+			TotalItems: totalItems,
+			TotalBytes: totalBytes,
+			Changes:    make(map[string]int),
+		},
+		lastEvent: now,
+	}
+}
+
+// processed counts an item as processed, changed by the action if not
+// empty.
+func (o *folderOperation) processed(name, action string, size int64) {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+
+	o.report.Items++
+	o.report.Bytes += size
+	if action != "" {
+		o.report.Changes[action]++
+		if len(o.report.Files) < maxOperationReportFiles {
+			o.report.Files = append(o.report.Files, OperationChange{Name: name, Action: action})
+		} else {
+			o.report.Truncated = true
+		}
+	}
+
+	if now := time.Now(); now.Sub(o.lastEvent) >= operationProgressInterval {
+		o.lastEvent = now
+		o.evLogger.Log(events.FolderOperationProgress, o.progressLocked())
+	}
+}
+
+func (o *folderOperation) progressLocked() map[string]interface{} {
+	return map[string]interface{}{
+		"folder":     o.report.Folder,
+		"operation":  o.report.Operation,
+		"items":      o.report.Items,
+		"bytes":      o.report.Bytes,
+		"totalItems": o.report.TotalItems,
+		"totalBytes": o.report.TotalBytes,
+	}
+}
+
+func (o *folderOperation) cancelled() bool {
+	return o.ctx.Err() != nil
+}
+
+func (o *folderOperation) finish() {
+	o.mut.Lock()
+	o.report.Finished = time.Now()
+	o.report.Cancelled = o.cancelled()
+	report := o.report.copy()
+	o.mut.Unlock()
+	o.cancel()
+
+	l.Infof("%s of folder %s done, %d items processed, changes: %v", report.Operation, report.Folder, report.Items, report.Changes)
+	o.evLogger.Log(events.FolderOperationFinished, report)
+}
+
+func (o *folderOperation) running() bool {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+	return o.report.Finished.IsZero()
+}
+
+func (o *folderOperation) Report() OperationReport {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+	return o.report.copy()
+}
@@ -104,12 +104,12 @@ func (t *ProgressEmitter) serve(ctx context.Context) {
 }
 
 func (t *ProgressEmitter) sendDownloadProgressEventLocked() {
-	output := make(map[string]map[string]*pullerProgress)
+	output := make(map[string]map[string]*PullerProgress)
 	for folder, pullers := range t.registry {
 		if len(pullers) == 0 {
 			continue
 		}
-		output[folder] = make(map[string]*pullerProgress)
+		output[folder] = make(map[string]*PullerProgress)
 		for name, puller := range pullers {
 			output[folder][name] = puller.Progress()
 		}
@@ -120,6 +120,11 @@ func (t *ProgressEmitter) sendDownloadProgressEventLocked() {
 
 func (t *ProgressEmitter) sendDownloadProgressMessagesLocked(ctx context.Context) {
 	for id, conn := range t.connections {
+		if devCfg, ok := t.cfg.Device(id); ok && devCfg.DonorMode {
+			// Requests from donor devices are refused while we're pulling,
+			// so don't tell them about the blocks we've got so far.
+			continue
+		}
 		for _, folder := range t.foldersByConns[id] {
 			pullers, ok := t.registry[folder]
 			if !ok {
@@ -270,6 +275,19 @@ func (t *ProgressEmitter) BytesCompleted(folder string) (bytes int64) {
 	return
 }
 
+// Progress returns the progress of each file currently being pulled in the
+// given folder.
+func (t *ProgressEmitter) Progress(folder string) map[string]*PullerProgress {
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	progress := make(map[string]*PullerProgress, len(t.registry[folder]))
+	for name, s := range t.registry[folder] {
+		progress[name] = s.Progress()
+	}
+	return progress
+}
+
 func (t *ProgressEmitter) String() string {
 	return fmt.Sprintf("ProgressEmitter@%p", t)
 }
@@ -39,7 +39,7 @@ func expectEvent(w events.Subscription, t *testing.T, size int) {
 	if event.Type != events.DownloadProgress {
 		t.Fatal("Unexpected event:", event, "at", caller(1))
 	}
-	data := event.Data.(map[string]map[string]*pullerProgress)
+	data := event.Data.(map[string]map[string]*PullerProgress)
 	if len(data) != size {
 		t.Fatal("Unexpected event data size:", data, "at", caller(1))
 	}
@@ -108,6 +108,37 @@ func TestProgressEmitter(t *testing.T) {
 
 }
 
+func TestProgressEmitterProgress(t *testing.T) {
+	c := createTmpWrapper(config.Configuration{})
+	defer os.Remove(c.ConfigPath())
+	c.SetOptions(config.OptionsConfiguration{
+		ProgressUpdateIntervalS: 60,
+	})
+	p := NewProgressEmitter(c, events.NoopLogger)
+
+	s := sharedPullerState{
+		folder:     "folder",
+		file:       protocol.FileInfo{Name: "file"},
+		copyTotal:  3,
+		copyNeeded: 3,
+		updated:    time.Now(),
+		mut:        sync.NewRWMutex(),
+	}
+	p.Register(&s)
+	s.copyDone(protocol.BlockInfo{})
+
+	progress := p.Progress("folder")
+	if len(progress) != 1 || progress["file"] == nil {
+		t.Fatalf("Expected progress for one file, got %v", progress)
+	}
+	if prog := progress["file"]; prog.Total != 3 || prog.CopiedFromElsewhere != 1 {
+		t.Errorf("Unexpected progress %+v", prog)
+	}
+	if progress := p.Progress("other"); len(progress) != 0 {
+		t.Errorf("Expected no progress for other folder, got %v", progress)
+	}
+}
+
 func TestSendDownloadProgressMessages(t *testing.T) {
 	c := createTmpWrapper(config.Configuration{})
 	defer os.Remove(c.ConfigPath())
@@ -459,6 +490,46 @@ func TestSendDownloadProgressMessages(t *testing.T) {
 	}
 }
 
+func TestSendDownloadProgressDonorMode(t *testing.T) {
+	c := createTmpWrapper(config.Configuration{})
+	defer os.Remove(c.ConfigPath())
+	c.SetOptions(config.OptionsConfiguration{
+		ProgressUpdateIntervalS: 60,
+		TempIndexMinBlocks:      10,
+	})
+	dev := config.NewDeviceConfiguration(device1, "device1")
+	dev.DonorMode = true
+	waiter, _ := c.SetDevice(dev)
+	waiter.Wait()
+
+	fc := &fakeConnection{id: device1}
+
+	evLogger := events.NewLogger()
+	go evLogger.Serve()
+	defer evLogger.Stop()
+
+	p := NewProgressEmitter(c, evLogger)
+	p.temporaryIndexSubscribe(fc, []string{"folder"})
+	p.registry["folder"] = map[string]*sharedPullerState{
+		"1": {
+			folder: "folder",
+			file: protocol.FileInfo{
+				Name:    "state1",
+				Version: (protocol.Vector{}).Update(0),
+				Blocks:  make([]protocol.BlockInfo, 11),
+			},
+			available:        []int32{1},
+			mut:              sync.NewRWMutex(),
+			availableUpdated: time.Now(),
+		},
+	}
+
+	sendMsgs(p)
+	if len(fc.downloadProgressMessages) != 0 {
+		t.Error("Expected no download progress to a device in donor mode, got", fc.downloadProgressMessages)
+	}
+}
+
 func sendMsgs(p *ProgressEmitter) {
 	p.mut.Lock()
 	defer p.mut.Unlock()
@@ -0,0 +1,188 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+// Items that failed to sync are not retried until their backoff has passed.
+// Errors that are likely to go away by themselves, such as no device being
+// connected that has the file, are retried sooner than those that are
+// likely to need the user to do something, such as permission denied.
+const (
+	transientBackoffMin  = time.Minute
+	transientBackoffMax  = time.Hour
+	persistentBackoffMin = 10 * time.Minute
+	persistentBackoffMax = 6 * time.Hour
+)
+
+// itemVersions are the global and local versions of an item, either of
+// which changing makes it worth another try.
+type itemVersions struct {
+	global, local protocol.Vector
+}
+
+func (v itemVersions) equal(o itemVersions) bool {
+	return v.global.Equal(o.global) && v.local.Equal(o.local)
+}
+
+type backoffEntry struct {
+	versions   itemVersions // of the item that failed
+	failures   int
+	persistent bool
+	next       time.Time // don't retry before
+	err        string    // the last error, to keep reporting it meanwhile
+	pending    bool      // failed in the current pull
+}
+
+// pullBackoff keeps track of the items that failed to sync and when to
+// retry them. An item is retried right away when it changes, remotely or
+// locally, as that may well have taken care of the problem.
+type pullBackoff struct {
+	entries map[string]*backoffEntry
+	mut     sync.Mutex
+}
+
+func newPullBackoff() *pullBackoff {
+	return &pullBackoff{
+		entries: make(map[string]*backoffEntry),
+		mut:     sync.NewMutex(),
+	}
+}
+
+// isPersistentPullError returns whether the error is one that retrying is
+// unlikely to fix.
+func isPersistentPullError(err error) bool {
+	cause := errors.Cause(err)
+	return os.IsPermission(cause) || cause == errIncompatibleSymlink
+}
+
+func backoffDelay(failures int, persistent bool) time.Duration {
+	delay, max := transientBackoffMin, transientBackoffMax
+	if persistent {
+		delay, max = persistentBackoffMin, persistentBackoffMax
+	}
+	for i := 1; i < failures && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
+// failed records that the item failed in the current pull. The backoff
+// starts when the pull is settled, so that it's still retried in the
+// iterations of the same pull.
+func (b *pullBackoff) failed(name string, err error, errStr string) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	e, ok := b.entries[name]
+	if !ok {
+		e = &backoffEntry{}
+		b.entries[name] = e
+	}
+	e.pending = true
+	e.persistent = isPersistentPullError(err)
+	e.err = errStr
+}
+
+// settle starts or extends the backoff of the items that failed in the
+// pull, given the versions of each that were attempted.
+func (b *pullBackoff) settle(now time.Time, versions func(name string) (itemVersions, bool)) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	for name, e := range b.entries {
+		if !e.pending {
+			continue
+		}
+		e.pending = false
+		v, ok := versions(name)
+		if !ok {
+			delete(b.entries, name)
+			continue
+		}
+		if !v.equal(e.versions) {
+			e.versions = v
+			e.failures = 0
+		}
+		e.failures++
+		e.next = now.Add(backoffDelay(e.failures, e.persistent))
+	}
+}
+
+// waiting returns the last error of the item if it is to wait before being
+// retried. Items that changed since they failed don't wait. The current
+// versions are only looked up for items that failed.
+func (b *pullBackoff) waiting(name string, now time.Time, versions func() itemVersions) (string, bool) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	e, ok := b.entries[name]
+	if !ok {
+		return "", false
+	}
+	if !versions().equal(e.versions) {
+		delete(b.entries, name)
+		return "", false
+	}
+	if !now.Before(e.next) {
+		return "", false
+	}
+	return e.err, true
+}
+
+// nextRetry returns when the first item that is waiting is to be retried.
+func (b *pullBackoff) nextRetry() (time.Time, bool) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	var next time.Time
+	for _, e := range b.entries {
+		if next.IsZero() || e.next.Before(next) {
+			next = e.next
+		}
+	}
+	return next, !next.IsZero()
+}
+
+func (b *pullBackoff) succeeded(name string) {
+	b.mut.Lock()
+	delete(b.entries, name)
+	b.mut.Unlock()
+}
+
+// retain forgets the items that aren't among the given ones, e.g. because
+// they are no longer needed.
+func (b *pullBackoff) retain(keep map[string]string) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	for name := range b.entries {
+		if _, ok := keep[name]; !ok {
+			delete(b.entries, name)
+		}
+	}
+}
+
+// reset makes the given items, or all of them if none are given, be
+// retried right away.
+func (b *pullBackoff) reset(names []string) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	if len(names) == 0 {
+		b.entries = make(map[string]*backoffEntry)
+		return
+	}
+	for _, name := range names {
+		delete(b.entries, name)
+	}
+}
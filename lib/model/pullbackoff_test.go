@@ -0,0 +1,109 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	cases := []struct {
+		failures   int
+		persistent bool
+		delay      time.Duration
+	}{
+		{1, false, time.Minute},
+		{2, false, 2 * time.Minute},
+		{4, false, 8 * time.Minute},
+		{10, false, time.Hour},
+		{1, true, 10 * time.Minute},
+		{3, true, 40 * time.Minute},
+		{100, true, 6 * time.Hour},
+	}
+	for _, tc := range cases {
+		if d := backoffDelay(tc.failures, tc.persistent); d != tc.delay {
+			t.Errorf("%d failures, persistent %v: got %v, expected %v", tc.failures, tc.persistent, d, tc.delay)
+		}
+	}
+}
+
+func TestPersistentPullError(t *testing.T) {
+	perm := &os.PathError{Op: "open", Path: "foo", Err: os.ErrPermission}
+	if !isPersistentPullError(errors.Wrap(perm, "opening")) {
+		t.Error("permission denied should be persistent")
+	}
+	if isPersistentPullError(errNotAvailable) {
+		t.Error("not available should be transient")
+	}
+}
+
+func TestPullBackoff(t *testing.T) {
+	b := newPullBackoff()
+	now := time.Now()
+	v1 := protocol.Vector{}.Update(device1.Short())
+	name := "foo"
+	current := itemVersions{global: v1}
+	versions := func(string) (itemVersions, bool) { return current, true }
+	waiting := func(at time.Time) (string, bool) {
+		return b.waiting(name, at, func() itemVersions { return current })
+	}
+
+	if _, ok := waiting(now); ok {
+		t.Fatal("unexpected wait before failing")
+	}
+
+	b.failed(name, errNotAvailable, "not available")
+	b.settle(now, versions)
+	if errStr, ok := waiting(now.Add(30 * time.Second)); !ok || errStr != "not available" {
+		t.Errorf("expected to wait with the last error, got %v %q", ok, errStr)
+	}
+	if _, ok := waiting(now.Add(time.Minute)); ok {
+		t.Error("expected a retry after a minute")
+	}
+	if next, ok := b.nextRetry(); !ok || !next.Equal(now.Add(time.Minute)) {
+		t.Errorf("unexpected next retry %v", next)
+	}
+
+	// Failing again doubles the wait
+	b.failed(name, errNotAvailable, "not available")
+	b.settle(now, versions)
+	if _, ok := waiting(now.Add(90 * time.Second)); !ok {
+		t.Error("expected to wait after the second failure")
+	}
+
+	// A new version, or a local change, is tried right away
+	current.global = v1.Update(device2.Short())
+	if _, ok := waiting(now); ok {
+		t.Error("unexpected wait for a new version")
+	}
+	b.failed(name, errNotAvailable, "not available")
+	b.settle(now, versions)
+	current.local = v1
+	if _, ok := waiting(now); ok {
+		t.Error("unexpected wait after a local change")
+	}
+
+	b.failed(name, errNotAvailable, "not available")
+	b.settle(now, versions)
+	b.reset(nil)
+	if _, ok := waiting(now); ok {
+		t.Error("unexpected wait after reset")
+	}
+
+	b.failed(name, errNotAvailable, "not available")
+	b.settle(now, versions)
+	b.succeeded(name)
+	if _, ok := b.nextRetry(); ok {
+		t.Error("unexpected retry after success")
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+var errPullBudgetUsed = errors.New("pull budget used up")
+
+// A pullBudget limits how much a folder pulls from other devices, e.g. for
+// the data window of a metered connection. When the next block would go
+// over it, pulling stops at a checkpoint: the blocks already requested are
+// written, and the partly pulled files stay in their temp files to go on
+// from when the budget is renewed. The budget is kept in the database, so
+// that a restart doesn't renew it.
+type pullBudget struct {
+	limit  int64 // bytes, zero for no limit
+	miscDB *db.NamespacedKV
+	key    string
+
+	mut     sync.Mutex
+	used    int64
+	stopped bool // at the checkpoint, until renewed
+}
+
+func newPullBudget(ldb *db.Lowlevel, folder string, limit int64) *pullBudget {
+	b := &pullBudget{
+		limit:  limit,
+		miscDB: db.NewMiscDataNamespace(ldb),
+		key:    "pullBudget/" + folder,
+		mut:    sync.NewMutex(),
+	}
+	if limit > 0 {
+		b.used, _, _ = b.miscDB.Int64(b.key + "/used")
+		b.stopped, _, _ = b.miscDB.Bool(b.key + "/stopped")
+	}
+	return b
+}
+
+// take returns whether the bytes may be pulled, counting them as used if
+// so. Otherwise pulling stops at the checkpoint.
+func (b *pullBudget) take(bytes int64) bool {
+	if b.limit <= 0 {
+		return true
+	}
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	if b.stopped || b.used+bytes > b.limit {
+		b.stopped = true
+		return false
+	}
+	b.used += bytes
+	return true
+}
+
+// giveBack returns taken bytes that weren't pulled after all.
+func (b *pullBudget) giveBack(bytes int64) {
+	if b.limit <= 0 {
+		return
+	}
+	b.mut.Lock()
+	b.used -= bytes
+	b.mut.Unlock()
+}
+
+// isStopped returns whether pulling stopped at the checkpoint.
+func (b *pullBudget) isStopped() bool {
+	if b.limit <= 0 {
+		return false
+	}
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	return b.stopped
+}
+
+// renew resets the budget to its full limit.
+func (b *pullBudget) renew() error {
+	b.mut.Lock()
+	b.used = 0
+	b.stopped = false
+	b.mut.Unlock()
+	return b.save()
+}
+
+// save stores what is used of the budget.
+func (b *pullBudget) save() error {
+	if b.limit <= 0 {
+		return nil
+	}
+	b.mut.Lock()
+	used, stopped := b.used, b.stopped
+	b.mut.Unlock()
+	if err := b.miscDB.PutInt64(b.key+"/used", used); err != nil {
+		return err
+	}
+	return b.miscDB.PutBool(b.key+"/stopped", stopped)
+}
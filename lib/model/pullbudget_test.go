@@ -0,0 +1,54 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/db/backend"
+)
+
+func TestPullBudget(t *testing.T) {
+	ldb := db.NewLowlevel(backend.OpenMemory())
+
+	unlimited := newPullBudget(ldb, "unlimited", 0)
+	if !unlimited.take(1<<40) || unlimited.isStopped() {
+		t.Error("Expected no limit without a budget")
+	}
+
+	b := newPullBudget(ldb, "default", 100)
+	if !b.take(60) {
+		t.Fatal("Expected to pull within the budget")
+	}
+	b.giveBack(60)
+	if !b.take(60) || !b.take(40) {
+		t.Fatal("Expected to pull the whole budget")
+	}
+	if b.isStopped() {
+		t.Error("Unexpected stop before going over the budget")
+	}
+	if b.take(1) || !b.isStopped() {
+		t.Fatal("Expected to stop when going over the budget")
+	}
+	if err := b.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The checkpoint is kept over restarts, until renewed.
+	b = newPullBudget(ldb, "default", 100)
+	if !b.isStopped() || b.take(1) {
+		t.Error("Expected to be stopped after a restart")
+	}
+	if err := b.renew(); err != nil {
+		t.Fatal(err)
+	}
+	b = newPullBudget(ldb, "default", 100)
+	if b.isStopped() || !b.take(100) {
+		t.Error("Expected the whole budget after renewing")
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"errors"
+	"runtime"
+	"sort"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/ignore"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+var errSendOnlyPull = errors.New("send only folders don't pull")
+
+// PullPlan is what the puller would do to bring the folder up to date
+// with what's currently known about the other devices.
+type PullPlan struct {
+	// Items to create or change: directories and symlinks first, then files
+	// in the order they would be pulled.
+	Update []PlannedItem `json:"update"`
+	// Files that would be renamed instead of deleted and pulled again.
+	Rename []PlannedRename `json:"rename"`
+	// Items to delete, directories last.
+	Delete []PlannedItem `json:"delete"`
+	// Items changed both here and on another device. The local version of
+	// a file is moved to a conflict copy, or kept over a deletion.
+	Conflicts []string `json:"conflicts"`
+	// Items that can't be pulled at the moment.
+	Errors []FileError `json:"errors"`
+}
+
+// PlannedItem is an item the puller would change.
+type PlannedItem struct {
+	Name string `json:"name"`
+	Type string `json:"type"` // "file", "dir" or "symlink"
+	Size int64  `json:"size"`
+	// Bytes that would be copied from the current version of a file, and
+	// downloaded. Blocks are also copied from other local files when
+	// possible, so the latter is an upper bound.
+	CopyBytes int64 `json:"copyBytes"`
+	PullBytes int64 `json:"pullBytes"`
+	// Set when only the metadata of a file changed.
+	MetadataOnly bool `json:"metadataOnly,omitempty"`
+}
+
+// PlannedRename is a file that would be renamed.
+type PlannedRename struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// PullPlan works out what pulling the folder would do right now, the same
+// way the puller sorts out what's needed, without doing any of it. Paused
+// folders are planned from the index they had when they were paused.
+func (m *model) PullPlan(folder string) (PullPlan, error) {
+	m.fmut.RLock()
+	cfg, ok := m.folderCfgs[folder]
+	fset := m.folderFiles[folder]
+	ignores := m.folderIgnores[folder]
+	m.fmut.RUnlock()
+	if !ok {
+		if cfg, ok = m.cfg.Folder(folder); !ok {
+			return PullPlan{}, errFolderMissing
+		}
+		fset = db.NewFileSet(cfg.ID, m.folderFilesystem(cfg), m.db)
+		ignores = ignore.New(cfg.Filesystem(), ignore.WithGitignore(cfg.HonorGitignore))
+		if err := ignores.Load(".stignore"); err != nil && !fs.IsNotExist(err) {
+			return PullPlan{}, err
+		}
+	}
+	if cfg.Type == config.FolderTypeSendOnly {
+		return PullPlan{}, errSendOnlyPull
+	}
+
+	snap := fset.Snapshot()
+	defer snap.Release()
+
+	plan := PullPlan{
+		Update:    []PlannedItem{},
+		Rename:    []PlannedRename{},
+		Delete:    []PlannedItem{},
+		Conflicts: []string{},
+		Errors:    []FileError{},
+	}
+	queue := newJobQueue()
+	queued := make(map[string]protocol.FileInfo)
+	var dirDeletions []protocol.FileInfo
+	fileDeletions := map[string]protocol.FileInfo{}
+	buckets := map[string][]protocol.FileInfo{}
+
+	snap.WithNeed(protocol.LocalDeviceID, func(intf db.FileIntf) bool {
+		if cfg.IgnoreDelete && intf.IsDeleted() {
+			return true
+		}
+
+		file := intf.(protocol.FileInfo)
+		cur, hasCur := snap.Get(protocol.LocalDeviceID, file.Name)
+
+		switch {
+		case ignores.ShouldIgnoreStat(file.Name, ignoreStat(file)):
+			// Only marked as ignored in the database.
+
+		case runtime.GOOS == "windows" && cfg.InvalidNamePolicy != config.InvalidNameEscape && fs.WindowsInvalidFilename(file.Name):
+			if !file.IsDeleted() {
+				plan.Errors = append(plan.Errors, FileError{Path: file.Name, Err: fs.WindowsFilenameError(file.Name).Error()})
+			}
+
+		case file.IsDeleted():
+			switch {
+			case !hasCur || cur.IsDeleted():
+				// There is nothing to delete.
+			case file.IsDirectory():
+				dirDeletions = append(dirDeletions, file)
+			case !file.IsSymlink() && !cur.IsSymlink() && !cur.IsDirectory() && !cur.IsInvalid() && len(cur.Blocks) > 0:
+				// Might turn out to be a rename.
+				fileDeletions[file.Name] = file
+				key := string(cur.Blocks[0].Hash)
+				buckets[key] = append(buckets[key], cur)
+			default:
+				m.planDeletion(&plan, file, cur)
+			}
+
+		case file.Type == protocol.FileInfoTypeFile:
+			if _, need := blockDiff(cur.Blocks, file.Blocks); hasCur && len(need) == 0 {
+				item := plannedItem(file, cur, hasCur)
+				item.MetadataOnly = true
+				plan.Update = append(plan.Update, item)
+			} else {
+				queue.Push(file.Name, file.Size, file.ModTime())
+				queued[file.Name] = file
+			}
+
+		case runtime.GOOS == "windows" && file.IsSymlink():
+			// Only marked as unsupported in the database.
+
+		default:
+			m.planUpdate(&plan, file, cur, hasCur)
+		}
+
+		return true
+	})
+
+	queue.SortByOrder(cfg.Order)
+	queue.PinToFront(cfg.PinsPath)
+
+nextFile:
+	for {
+		name, ok := queue.Pop()
+		if !ok {
+			break
+		}
+		file := queued[name]
+
+		key := string(file.Blocks[0].Hash)
+		for i, candidate := range buckets[key] {
+			if protocol.BlocksEqual(candidate.Blocks, file.Blocks) {
+				lidx := len(buckets[key]) - 1
+				buckets[key][i] = buckets[key][lidx]
+				buckets[key] = buckets[key][:lidx]
+				delete(fileDeletions, candidate.Name)
+				plan.Rename = append(plan.Rename, PlannedRename{From: candidate.Name, To: file.Name})
+				continue nextFile
+			}
+		}
+
+		for _, dev := range snap.Availability(name) {
+			if _, ok := m.Connection(dev); ok {
+				cur, hasCur := snap.Get(protocol.LocalDeviceID, name)
+				m.planUpdate(&plan, file, cur, hasCur)
+				continue nextFile
+			}
+		}
+		plan.Errors = append(plan.Errors, FileError{Path: name, Err: errNotAvailable.Error()})
+	}
+
+	names := make([]string, 0, len(fileDeletions))
+	for name := range fileDeletions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		cur, _ := snap.Get(protocol.LocalDeviceID, name)
+		m.planDeletion(&plan, fileDeletions[name], cur)
+	}
+	for i := len(dirDeletions) - 1; i >= 0; i-- {
+		plan.Delete = append(plan.Delete, plannedItem(dirDeletions[i], protocol.FileInfo{}, false))
+	}
+
+	return plan, nil
+}
+
+func (m *model) planUpdate(plan *PullPlan, file, cur protocol.FileInfo, hasCur bool) {
+	plan.Update = append(plan.Update, plannedItem(file, cur, hasCur))
+	// Only replacing a file is checked for conflicts.
+	if hasCur && !cur.IsDeleted() && !cur.IsDirectory() && !cur.IsSymlink() && inConflict(cur.Version, file.Version, m.shortID) {
+		plan.Conflicts = append(plan.Conflicts, file.Name)
+	}
+}
+
+func (m *model) planDeletion(plan *PullPlan, file, cur protocol.FileInfo) {
+	switch {
+	case cur.IsDirectory():
+		plan.Errors = append(plan.Errors, FileError{Path: file.Name, Err: errUnexpectedDirOnFileDel.Error()})
+	case inConflict(cur.Version, file.Version, m.shortID):
+		plan.Conflicts = append(plan.Conflicts, file.Name)
+	default:
+		plan.Delete = append(plan.Delete, plannedItem(file, cur, true))
+	}
+}
+
+func plannedItem(file, cur protocol.FileInfo, hasCur bool) PlannedItem {
+	item := PlannedItem{
+		Name: file.Name,
+		Type: "file",
+		Size: file.Size,
+	}
+	switch {
+	case file.IsDirectory():
+		item.Type = "dir"
+	case file.IsSymlink():
+		item.Type = "symlink"
+	}
+	if file.IsDeleted() {
+		item.Size = cur.Size
+		return item
+	}
+	if !hasCur {
+		cur = protocol.FileInfo{}
+	}
+	have, need := blockDiff(cur.Blocks, file.Blocks)
+	for _, b := range have {
+		item.CopyBytes += int64(b.Size)
+	}
+	for _, b := range need {
+		item.PullBytes += int64(b.Size)
+	}
+	return item
+}
@@ -0,0 +1,88 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestPullPlan(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	dir := fcfg.Filesystem().URI()
+	for name, data := range map[string]string{"old": "moved", "conflict": "local", "deleteme": "x"} {
+		must(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644))
+	}
+	m, fc := setupModelWithConnectionFromWrapper(w)
+	defer cleanupModelAndRemoveDir(m, dir)
+
+	// The plan is made from the database while the folder is paused, so
+	// nothing gets pulled in the meantime.
+	fcfg.Paused = true
+	fcfg.Order = config.OrderAlphabetic
+	waiter, err := w.SetFolder(fcfg)
+	must(t, err)
+	waiter.Wait()
+	fc = addFakeConn(m, device1)
+
+	fset := db.NewFileSet(fcfg.ID, fcfg.Filesystem(), m.db)
+	deleted := func(name string) protocol.FileInfo {
+		snap := fset.Snapshot()
+		defer snap.Release()
+		f, ok := snap.Get(protocol.LocalDeviceID, name)
+		if !ok {
+			t.Fatal("missing local file", name)
+		}
+		f.Deleted = true
+		f.Blocks = nil
+		f.Version = f.Version.Update(device1.Short())
+		return f
+	}
+	fc.addFile("new", 0644, protocol.FileInfoTypeFile, []byte("hello"))
+	fc.addFile("renamed", 0644, protocol.FileInfoTypeFile, []byte("moved"))
+	fc.addFile("conflict", 0644, protocol.FileInfoTypeFile, []byte("remote"))
+	fc.addFile("dir", 0755, protocol.FileInfoTypeDirectory, nil)
+	for i := range fc.files {
+		if fc.files[i].Name == "conflict" {
+			// Newer, so it wins the conflict.
+			fc.files[i].ModifiedS += 10
+		}
+	}
+	fset.Update(device1, append(fc.files, deleted("old"), deleted("deleteme")))
+
+	plan, err := m.PullPlan("default")
+	must(t, err)
+
+	expected := PullPlan{
+		Update: []PlannedItem{
+			{Name: "dir", Type: "dir"},
+			{Name: "conflict", Type: "file", Size: 6, PullBytes: 6},
+			{Name: "new", Type: "file", Size: 5, PullBytes: 5},
+		},
+		Rename:    []PlannedRename{{From: "old", To: "renamed"}},
+		Delete:    []PlannedItem{{Name: "deleteme", Type: "file", Size: 1}},
+		Conflicts: []string{"conflict"},
+		Errors:    []FileError{},
+	}
+	if !reflect.DeepEqual(plan, expected) {
+		t.Errorf("Got plan\n%+v\nexpected\n%+v", plan, expected)
+	}
+
+	// Without the device having the files, they can't be pulled.
+	m.Closed(fc, protocol.ErrClosed)
+	plan, err = m.PullPlan("default")
+	must(t, err)
+	if len(plan.Errors) != 2 {
+		t.Errorf("Expected the two files to be unavailable, got %v", plan.Errors)
+	}
+}
@@ -7,9 +7,11 @@
 package model
 
 import (
+	"path/filepath"
 	"sort"
 	"time"
 
+	"github.com/syncthing/syncthing/lib/config"
 	"github.com/syncthing/syncthing/lib/rand"
 	"github.com/syncthing/syncthing/lib/sync"
 )
@@ -71,6 +73,17 @@ func (q *jobQueue) BringToFront(filename string) {
 	}
 }
 
+// PinToFront moves the queued files that are pinned to the front, keeping
+// the order among them and among the rest.
+func (q *jobQueue) PinToFront(pinned func(string) bool) {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+
+	sort.SliceStable(q.queued, func(a, b int) bool {
+		return pinned(q.queued[a].name) && !pinned(q.queued[b].name)
+	})
+}
+
 func (q *jobQueue) Done(file string) {
 	q.mut.Lock()
 	defer q.mut.Unlock()
@@ -180,6 +193,67 @@ func (q *jobQueue) SortNewestFirst() {
 	sort.Sort(sort.Reverse(oldestFirst(q.queued)))
 }
 
+// SortByDirectory keeps the files of each directory together, with the
+// directories in alphabetic order, so that one directory is complete
+// before the next is started.
+func (q *jobQueue) SortByDirectory() {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+
+	sortByDirectory(q.queued, func(a, b string) bool { return a < b })
+}
+
+// SortSmallestDirectoryFirst is like SortByDirectory, but starts with the
+// directories with the least data left to pull, so that as many as
+// possible are complete early on.
+func (q *jobQueue) SortSmallestDirectoryFirst() {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+
+	sizes := make(map[string]int64)
+	for _, e := range q.queued {
+		sizes[filepath.Dir(e.name)] += e.size
+	}
+	sortByDirectory(q.queued, func(a, b string) bool {
+		if sizes[a] != sizes[b] {
+			return sizes[a] < sizes[b]
+		}
+		return a < b
+	})
+}
+
+// sortByDirectory sorts the entries by their directory, in the order the
+// less function tells for directories, and by name within each.
+func sortByDirectory(entries []jobQueueEntry, less func(a, b string) bool) {
+	dirs := make([]string, len(entries))
+	for i, e := range entries {
+		dirs[i] = filepath.Dir(e.name)
+	}
+	sort.Sort(byDirectory{entries, dirs, less})
+}
+
+// SortByOrder reorders the queue according to the folder's pull order.
+func (q *jobQueue) SortByOrder(order config.PullOrder) {
+	switch order {
+	case config.OrderRandom:
+		q.Shuffle()
+	case config.OrderAlphabetic:
+	// The queue is already in alphabetic order.
+	case config.OrderSmallestFirst:
+		q.SortSmallestFirst()
+	case config.OrderLargestFirst:
+		q.SortLargestFirst()
+	case config.OrderOldestFirst:
+		q.SortOldestFirst()
+	case config.OrderNewestFirst:
+		q.SortNewestFirst()
+	case config.OrderDirectoryFirst:
+		q.SortByDirectory()
+	case config.OrderSmallestDirectoryFirst:
+		q.SortSmallestDirectoryFirst()
+	}
+}
+
 // The usual sort.Interface boilerplate
 
 type smallestFirst []jobQueueEntry
@@ -188,6 +262,24 @@ func (q smallestFirst) Len() int           { return len(q) }
 func (q smallestFirst) Less(a, b int) bool { return q[a].size < q[b].size }
 func (q smallestFirst) Swap(a, b int)      { q[a], q[b] = q[b], q[a] }
 
+type byDirectory struct {
+	entries []jobQueueEntry
+	dirs    []string // of the entries
+	less    func(a, b string) bool
+}
+
+func (q byDirectory) Len() int { return len(q.entries) }
+func (q byDirectory) Less(a, b int) bool {
+	if q.dirs[a] != q.dirs[b] {
+		return q.less(q.dirs[a], q.dirs[b])
+	}
+	return q.entries[a].name < q.entries[b].name
+}
+func (q byDirectory) Swap(a, b int) {
+	q.entries[a], q.entries[b] = q.entries[b], q.entries[a]
+	q.dirs[a], q.dirs[b] = q.dirs[b], q.dirs[a]
+}
+
 type oldestFirst []jobQueueEntry
 
 func (q oldestFirst) Len() int           { return len(q) }
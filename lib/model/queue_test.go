@@ -8,6 +8,7 @@ package model
 
 import (
 	"fmt"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -161,6 +162,21 @@ func TestBringToFront(t *testing.T) {
 	}
 }
 
+func TestPinToFront(t *testing.T) {
+	q := newJobQueue()
+	q.Push("f1", 0, time.Time{})
+	q.Push("d1", 0, time.Time{})
+	q.Push("f2", 0, time.Time{})
+	q.Push("d2", 0, time.Time{})
+
+	q.PinToFront(func(name string) bool { return name[0] == 'd' })
+
+	_, queued, _ := q.Jobs(1, 100)
+	if diff, equal := messagediff.PrettyDiff([]string{"d1", "d2", "f1", "f2"}, queued); !equal {
+		t.Errorf("Order does not match. Diff:\n%s", diff)
+	}
+}
+
 func TestShuffle(t *testing.T) {
 	q := newJobQueue()
 	q.Push("f1", 0, time.Time{})
@@ -250,6 +266,31 @@ func TestSortByAge(t *testing.T) {
 	}
 }
 
+func TestSortByDirectory(t *testing.T) {
+	q := newJobQueue()
+	q.Push(filepath.Join("a", "f1"), 10, time.Time{})
+	q.Push(filepath.Join("a", "b", "f2"), 10, time.Time{})
+	q.Push(filepath.Join("a b", "f3"), 10, time.Time{})
+	q.Push(filepath.Join("a", "f4"), 30, time.Time{})
+	q.Push("f5", 50, time.Time{})
+
+	q.SortByDirectory()
+
+	_, actual, _ := q.Jobs(1, 100)
+	expected := []string{"f5", filepath.Join("a", "f1"), filepath.Join("a", "f4"), filepath.Join("a b", "f3"), filepath.Join("a", "b", "f2")}
+	if diff, equal := messagediff.PrettyDiff(expected, actual); !equal {
+		t.Errorf("SortByDirectory() diff:\n%s", diff)
+	}
+
+	q.SortSmallestDirectoryFirst()
+
+	_, actual, _ = q.Jobs(1, 100)
+	expected = []string{filepath.Join("a b", "f3"), filepath.Join("a", "b", "f2"), filepath.Join("a", "f1"), filepath.Join("a", "f4"), "f5"}
+	if diff, equal := messagediff.PrettyDiff(expected, actual); !equal {
+		t.Errorf("SortSmallestDirectoryFirst() diff:\n%s", diff)
+	}
+}
+
 func BenchmarkJobQueueBump(b *testing.B) {
 	files := genFiles(b.N)
 
@@ -0,0 +1,239 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"path/filepath"
+	"sort"
+
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/events"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/osutil"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// A renameCandidate is a needed file that we have an older version of, by
+// the first block hashes of that and of the needed version, to find those
+// whose needed content is what another one has now.
+type renameCandidate struct {
+	name       string
+	curHash    string
+	neededHash string
+}
+
+// renameCycles returns the cycles of files, like a swap of two, where the
+// needed content of each file is the current content of the next one, and
+// that of the last the current content of the first. Files can't be
+// renamed into place one at a time for those, as each name is taken until
+// the next one is renamed. The equal function tells whether the needed
+// content of the first file is the current content of the second.
+func renameCycles(cands []renameCandidate, equal func(target, source string) bool) [][]string {
+	byCurHash := make(map[string][]string)
+	for _, c := range cands {
+		byCurHash[c.curHash] = append(byCurHash[c.curHash], c.name)
+	}
+
+	// The file whose current content each file needs, each used once
+	next := make(map[string]string)
+	used := make(map[string]bool)
+	for _, c := range cands {
+		for _, source := range byCurHash[c.neededHash] {
+			if source != c.name && !used[source] && equal(c.name, source) {
+				next[c.name] = source
+				used[source] = true
+				break
+			}
+		}
+	}
+
+	names := make([]string, 0, len(next))
+	for name := range next {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var cycles [][]string
+	seen := make(map[string]bool)
+	for _, start := range names {
+		if seen[start] {
+			continue
+		}
+		var cycle []string
+		for name, ok := start, true; ok && !seen[name]; name, ok = next[name] {
+			seen[name] = true
+			cycle = append(cycle, name)
+		}
+		if len(cycle) > 1 && next[cycle[len(cycle)-1]] == start {
+			cycles = append(cycles, cycle)
+		}
+	}
+	return cycles
+}
+
+// caseOnlyRenames returns the files to be deleted that the new files are a
+// rename of, differing only in case, by the name of the new file. Those
+// can't be renamed directly on case insensitive filesystems, where the new
+// name is taken by the old one. Only renames within a directory are
+// considered, as the directory of the new name might not exist yet. The
+// equal function tells whether the content of the new file is that of the
+// one to be deleted.
+func caseOnlyRenames(newNames []string, deletions map[string]protocol.FileInfo, equal func(target, source string) bool) map[string]string {
+	if len(newNames) == 0 || len(deletions) == 0 {
+		return nil
+	}
+	byLower := make(map[string]string, len(deletions))
+	for name := range deletions {
+		byLower[fs.UnicodeLowercase(name)] = name
+	}
+	renames := make(map[string]string)
+	for _, name := range newNames {
+		source, ok := byLower[fs.UnicodeLowercase(name)]
+		if ok && source != name && filepath.Dir(source) == filepath.Dir(name) && equal(name, source) {
+			renames[name] = source
+			delete(byLower, fs.UnicodeLowercase(name))
+		}
+	}
+	return renames
+}
+
+// A renameMove is the rename of our current version of a file into the
+// place of a needed one.
+type renameMove struct {
+	source protocol.FileInfo
+	target protocol.FileInfo
+}
+
+// renameViaTemp does the moves as one, by first renaming each source to
+// the temporary name of its target, and then those into place. Nothing is
+// changed if any of the sources changed since they were scanned, or can't
+// be renamed.
+func (f *sendReceiveFolder) renameViaTemp(moves []renameMove, snap *db.Snapshot, dbUpdateChan chan<- dbUpdateJob, scanChan chan<- string) error {
+	// Used in the defer closure below, updated by the function body. Take
+	// care not to declare another err.
+	var err error
+
+	for _, move := range moves {
+		f.evLogger.Log(events.ItemStarted, map[string]string{
+			"folder": f.folderID,
+			"item":   move.target.Name,
+			"type":   "file",
+			"action": "update",
+		})
+	}
+	defer func() {
+		for _, move := range moves {
+			f.evLogger.Log(events.ItemFinished, map[string]interface{}{
+				"folder": f.folderID,
+				"item":   move.target.Name,
+				"error":  events.Error(err),
+				"type":   "file",
+				"action": "update",
+			})
+		}
+	}()
+
+	for _, move := range moves {
+		l.Debugln(f, "planned rename", move.source.Name, "->", move.target.Name)
+		if err = f.checkToBeDeleted(move.source, scanChan); err != nil {
+			return err
+		}
+	}
+
+	for i, move := range moves {
+		err = osutil.RenameOrCopy(f.fs, f.fs, move.source.Name, fs.TempName(move.target.Name))
+		if err == nil {
+			continue
+		}
+		// Put back what was moved already
+		for _, done := range moves[:i] {
+			if rerr := osutil.RenameOrCopy(f.fs, f.fs, fs.TempName(done.target.Name), done.source.Name); rerr != nil {
+				l.Infof("Folder %v: failed to undo the rename of %q: %v", f.Description(), done.source.Name, rerr)
+				scanChan <- done.source.Name
+			}
+		}
+		return err
+	}
+
+	for _, move := range moves {
+		curTarget, hasCurTarget := snap.Get(protocol.LocalDeviceID, move.target.Name)
+		if ferr := f.performFinish(move.target, curTarget, hasCurTarget, fs.TempName(move.target.Name), snap, dbUpdateChan, scanChan); ferr != nil {
+			// The content is in the temporary file, to be reused when
+			// the file is pulled again.
+			f.newPullError(move.target.Name, ferr)
+			err = ferr
+			continue
+		}
+
+		blockStatsMut.Lock()
+		minBlocksPerBlock := move.target.BlockSize() / protocol.MinBlockSize
+		blockStats["total"] += len(move.target.Blocks) * minBlocksPerBlock
+		blockStats["renamed"] += len(move.target.Blocks) * minBlocksPerBlock
+		blockStatsMut.Unlock()
+	}
+	return err
+}
+
+// planAndRename finds the case-only renames and the cycles of renames
+// among the queued files, and does them. It returns the files that were
+// taken care of that way, which are not to be pulled.
+func (f *sendReceiveFolder) planAndRename(updates []renameCandidate, newNames []string, fileDeletions map[string]protocol.FileInfo, snap *db.Snapshot, dbUpdateChan chan<- dbUpdateJob, scanChan chan<- string) map[string]bool {
+	equal := func(target, source string) bool {
+		global, ok := snap.GetGlobal(target)
+		if !ok || global.IsDeleted() || global.IsInvalid() || global.Type != protocol.FileInfoTypeFile {
+			return false
+		}
+		cur, ok := snap.Get(protocol.LocalDeviceID, source)
+		if !ok || cur.IsDeleted() || cur.IsInvalid() || cur.Type != protocol.FileInfoTypeFile {
+			return false
+		}
+		return protocol.BlocksEqual(cur.Blocks, global.Blocks)
+	}
+	moveOf := func(target, source string) renameMove {
+		global, _ := snap.GetGlobal(target)
+		cur, _ := snap.Get(protocol.LocalDeviceID, source)
+		return renameMove{source: cur, target: global}
+	}
+
+	handled := make(map[string]bool)
+
+	for target, source := range caseOnlyRenames(newNames, fileDeletions, equal) {
+		if err := f.renameViaTemp([]renameMove{moveOf(target, source)}, snap, dbUpdateChan, scanChan); err != nil {
+			l.Debugln(f, "case-only rename", source, "->", target, err)
+			continue
+		}
+		dbUpdateChan <- dbUpdateJob{fileDeletions[source], dbUpdateDeleteFile}
+		delete(fileDeletions, source)
+		if f.caseCheck != nil {
+			f.caseCheck.renamed(source, target)
+		}
+		handled[target] = true
+	}
+
+	if f.atomic != nil {
+		// The files of a cycle are staged as copies of each other and
+		// applied together with the rest. The case-only renames can't
+		// wait, as the new name can't be staged next to the old one.
+		return handled
+	}
+
+	for _, cycle := range renameCycles(updates, equal) {
+		moves := make([]renameMove, len(cycle))
+		for i, target := range cycle {
+			moves[i] = moveOf(target, cycle[(i+1)%len(cycle)])
+		}
+		if err := f.renameViaTemp(moves, snap, dbUpdateChan, scanChan); err != nil {
+			l.Debugln(f, "renaming cycle", cycle, err)
+			continue
+		}
+		for _, target := range cycle {
+			handled[target] = true
+		}
+	}
+
+	return handled
+}
@@ -0,0 +1,65 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestRenameCycles(t *testing.T) {
+	// Each file has the content of its name now, and needs the content
+	// given by neededHash.
+	cands := []renameCandidate{
+		{"a", "a", "b"},
+		{"b", "b", "a"},
+		{"c", "c", "d"},
+		{"d", "d", "e"},
+		{"e", "e", "c"},
+		// Needs the content of a file that isn't renamed along
+		{"f", "f", "g"},
+		// Needs the content of the first file, but that's taken
+		{"h", "h", "a"},
+	}
+	equal := func(target, source string) bool {
+		for _, c := range cands {
+			if c.name == target {
+				return c.neededHash == source
+			}
+		}
+		return false
+	}
+
+	cycles := renameCycles(cands, equal)
+	expected := [][]string{{"a", "b"}, {"c", "d", "e"}}
+	if !reflect.DeepEqual(cycles, expected) {
+		t.Errorf("Unexpected cycles %v, expected %v", cycles, expected)
+	}
+
+	// Nothing is a cycle if the contents differ beyond the first block
+	never := func(target, source string) bool { return false }
+	if cycles := renameCycles(cands, never); len(cycles) != 0 {
+		t.Errorf("Unexpected cycles %v", cycles)
+	}
+}
+
+func TestCaseOnlyRenames(t *testing.T) {
+	deletions := map[string]protocol.FileInfo{
+		"dir/File.txt": {Name: "dir/File.txt"},
+		"dir/Other":    {Name: "dir/Other"},
+		"Dir/moved":    {Name: "Dir/moved"},
+	}
+	equal := func(target, source string) bool { return source != "dir/Other" }
+
+	renames := caseOnlyRenames([]string{"dir/file.txt", "dir/other", "dir/moved", "dir/new"}, deletions, equal)
+	expected := map[string]string{"dir/file.txt": "dir/File.txt"}
+	if !reflect.DeepEqual(renames, expected) {
+		t.Errorf("Unexpected renames %v, expected %v", renames, expected)
+	}
+}
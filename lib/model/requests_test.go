@@ -9,6 +9,7 @@ package model
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"errors"
 	"io/ioutil"
 	"os"
@@ -1171,3 +1172,264 @@ func TestRequestLastFileProgress(t *testing.T) {
 		t.Fatal("Timed out before file was requested")
 	}
 }
+
+func TestRequestDeniedPath(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	for i := range fcfg.Devices {
+		if fcfg.Devices[i].DeviceID == device1 {
+			fcfg.Devices[i].DeniedPaths = []string{"secret"}
+		}
+	}
+	w.SetFolder(fcfg)
+	tfs := fcfg.Filesystem()
+	m, fc := setupModelWithConnectionFromWrapper(w)
+	defer cleanupModelAndRemoveDir(m, tfs.URI())
+
+	done := make(chan struct{})
+	fc.mut.Lock()
+	fc.indexFn = func(_ context.Context, folder string, fs []protocol.FileInfo) {
+		for _, f := range fs {
+			if strings.HasPrefix(f.Name, "secret") {
+				t.Error("Denied file sent in index:", f.Name)
+			}
+			if f.Name == "public" {
+				close(done)
+			}
+		}
+	}
+	fc.mut.Unlock()
+
+	must(t, tfs.MkdirAll("secret", 0755))
+	for _, name := range []string{filepath.Join("secret", "file"), "public"} {
+		must(t, ioutil.WriteFile(filepath.Join(tfs.URI(), name), []byte("contents"), 0644))
+	}
+	must(t, m.ScanFolder("default"))
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for index")
+	}
+
+	if _, err := m.Request(device1, "default", filepath.Join("secret", "file"), 8, 0, nil, 0, false); err != protocol.ErrNoSuchFile {
+		t.Error("Expected denied request to fail with no such file, got", err)
+	}
+	if _, err := m.Request(device1, "default", "public", 8, 0, nil, 0, false); err != nil {
+		t.Error("Request for allowed file failed:", err)
+	}
+}
+
+func TestDeviceIgnorePatterns(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	for i := range fcfg.Devices {
+		if fcfg.Devices[i].DeviceID == device1 {
+			fcfg.Devices[i].IgnorePatterns = []string{"*.iso"}
+		}
+	}
+	w.SetFolder(fcfg)
+	tfs := fcfg.Filesystem()
+	m, fc := setupModelWithConnectionFromWrapper(w)
+	defer cleanupModelAndRemoveDir(m, tfs.URI())
+
+	done := make(chan struct{})
+	fc.mut.Lock()
+	fc.indexFn = func(_ context.Context, folder string, fs []protocol.FileInfo) {
+		for _, f := range fs {
+			switch f.Name {
+			case "image.iso":
+				if !f.IsInvalid() {
+					t.Error("Ignored file not sent as invalid")
+				}
+				close(done)
+			case "text":
+				if f.IsInvalid() {
+					t.Error("File not ignored sent as invalid")
+				}
+			}
+		}
+	}
+	fc.mut.Unlock()
+
+	for _, name := range []string{"text", "image.iso"} {
+		must(t, ioutil.WriteFile(filepath.Join(tfs.URI(), name), []byte("contents"), 0644))
+	}
+	must(t, m.ScanFolder("default"))
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for index")
+	}
+
+	// Locally the file isn't ignored
+	if f, ok := m.CurrentFolderFile("default", "image.iso"); !ok || f.IsInvalid() {
+		t.Error("Expected the file to be valid locally")
+	}
+}
+
+func TestRequestFromTempFile(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	tfs := fcfg.Filesystem()
+	m, _ := setupModelWithConnectionFromWrapper(w)
+	defer cleanupModelAndRemoveDir(m, tfs.URI())
+
+	contents := []byte("new contents")
+	hash := sha256.Sum256(contents)
+	request := func() ([]byte, error) {
+		res, err := m.Request(device1, "default", "file", int32(len(contents)), 0, hash[:], 0, false)
+		if err != nil {
+			return nil, err
+		}
+		return res.Data(), nil
+	}
+
+	// Only the temp file exists, while it's being downloaded
+	must(t, ioutil.WriteFile(filepath.Join(tfs.URI(), fs.TempName("file")), contents, 0644))
+	if data, err := request(); err != nil || !bytes.Equal(data, contents) {
+		t.Errorf("Got %q, %v from temp file, expected %q", data, err, contents)
+	}
+
+	// The file exists, but in an older version
+	must(t, ioutil.WriteFile(filepath.Join(tfs.URI(), "file"), []byte("old contents"), 0644))
+	if data, err := request(); err != nil || !bytes.Equal(data, contents) {
+		t.Errorf("Got %q, %v from temp file next to old one, expected %q", data, err, contents)
+	}
+
+	// Neither has the block
+	must(t, tfs.Remove(fs.TempName("file")))
+	if _, err := request(); err != protocol.ErrNoSuchFile {
+		t.Error("Expected no such file, got", err)
+	}
+}
+
+func TestRequestAtomicApply(t *testing.T) {
+	w, fcfg := tmpDefaultWrapper()
+	fcfg.AtomicApply = true
+	waiter, _ := w.SetFolder(fcfg)
+	waiter.Wait()
+	tfs := fcfg.Filesystem()
+	m, fc := setupModelWithConnectionFromWrapper(w)
+	defer cleanupModelAndRemoveDir(m, tfs.URI())
+
+	// Pulling "b" fails, so neither "a" nor the directory "d" may be
+	// applied
+	fc.mut.Lock()
+	fc.requestFn = func(_ context.Context, _, name string, _ int64, _ int, _ []byte, _ bool) ([]byte, error) {
+		if name == "b" {
+			return nil, errors.New("unavailable")
+		}
+		return fc.fileData[name], nil
+	}
+	fc.mut.Unlock()
+
+	sub := m.evLogger.Subscribe(events.FolderErrors)
+	defer sub.Unsubscribe()
+
+	fc.addFile("a", 0644, protocol.FileInfoTypeFile, []byte("aaa"))
+	fc.addFile("b", 0644, protocol.FileInfoTypeFile, []byte("bbb"))
+	fc.addFile("d", 0755, protocol.FileInfoTypeDirectory, nil)
+	fc.sendIndexUpdate()
+
+	select {
+	case <-sub.C():
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out waiting for the pull to fail")
+	}
+	if _, err := tfs.Lstat("a"); !fs.IsNotExist(err) {
+		t.Error("Expected a to not be applied, got", err)
+	}
+	if _, err := tfs.Lstat(fs.TempName("a")); err != nil {
+		t.Error("Expected a to be kept in its temp file, got", err)
+	}
+	if _, err := tfs.Lstat("d"); !fs.IsNotExist(err) {
+		t.Error("Expected d to not be applied, got", err)
+	}
+
+	// Once everything is there, everything is applied
+	fc.mut.Lock()
+	fc.requestFn = nil
+	fc.mut.Unlock()
+	must(t, m.ResetPullBackoff("default", nil))
+
+	timeout := time.Now().Add(10 * time.Second)
+	for _, name := range []string{"a", "b", "d"} {
+		for {
+			if _, err := tfs.Lstat(name); err == nil {
+				break
+			}
+			if time.Now().After(timeout) {
+				t.Fatal("Timed out waiting for", name)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+func TestRequestCaseConflict(t *testing.T) {
+	m, fc, tfs := setupCaseConflict(t, "/TestRequestCaseConflict", "")
+	defer cleanupModel(m)
+
+	sub := m.evLogger.Subscribe(events.FolderErrors)
+	defer sub.Unsubscribe()
+
+	fc.addFile("foo", 0644, protocol.FileInfoTypeFile, []byte("remote"))
+	fc.sendIndexUpdate()
+
+	select {
+	case ev := <-sub.C():
+		errs := ev.Data.(map[string]interface{})["errors"].([]FileError)
+		if len(errs) != 1 || errs[0].Path != "foo" || errs[0].Type != FileErrorCaseConflict {
+			t.Fatal("Expected a case conflict for foo, got", errs)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out waiting for the case conflict")
+	}
+	// The fake filesystem doesn't keep the data, so tell the files apart
+	// by size.
+	if fi, err := tfs.Lstat("Foo"); err != nil || fi.Size() != int64(len("local")) {
+		t.Fatal("Expected Foo to be left alone, got", fi, err)
+	}
+}
+
+func TestRequestCaseConflictSuffix(t *testing.T) {
+	m, fc, tfs := setupCaseConflict(t, "/TestRequestCaseConflictSuffix", ".case")
+	defer cleanupModel(m)
+
+	fc.addFile("foo", 0644, protocol.FileInfoTypeFile, []byte("remote"))
+	fc.sendIndexUpdate()
+
+	timeout := time.Now().Add(10 * time.Second)
+	for {
+		if fi, err := tfs.Lstat("foo"); err == nil && fi.Size() == int64(len("remote")) {
+			break
+		}
+		if time.Now().After(timeout) {
+			t.Fatal("Timed out waiting for foo")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if fi, err := tfs.Lstat("Foo.case"); err != nil || fi.Size() != int64(len("local")) {
+		t.Error("Expected Foo to be renamed to Foo.case, got", fi, err)
+	}
+}
+
+// setupCaseConflict returns a model with a case insensitive folder that
+// has a local file "Foo".
+func setupCaseConflict(t *testing.T, path, suffix string) (*model, *fakeConnection, fs.Filesystem) {
+	t.Helper()
+	w, fcfg := tmpDefaultWrapper()
+	fcfg.FilesystemType = fs.FilesystemTypeFake
+	fcfg.Path = path + "?insens=true"
+	fcfg.CaseConflictSuffix = suffix
+	waiter, _ := w.SetFolder(fcfg)
+	waiter.Wait()
+	fcfg, _ = w.Folder("default")
+	tfs := fcfg.Filesystem()
+	fd, err := tfs.Create("Foo")
+	must(t, err)
+	_, err = fd.Write([]byte("local"))
+	must(t, err)
+	fd.Close()
+	m, fc := setupModelWithConnectionFromWrapper(w)
+	return m, fc, tfs
+}
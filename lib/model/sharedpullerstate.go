@@ -49,8 +49,8 @@ type sharedPullerState struct {
 	mut               sync.RWMutex    // Protects the above
 }
 
-// A momentary state representing the progress of the puller
-type pullerProgress struct {
+// PullerProgress is a momentary state representing the progress of the puller
+type PullerProgress struct {
 	Total                   int   `json:"total"`
 	Reused                  int   `json:"reused"`
 	CopiedFromOrigin        int   `json:"copiedFromOrigin"`
@@ -77,6 +77,14 @@ func (w *lockedWriterAt) WriteAt(p []byte, off int64) (n int, err error) {
 	return w.fd.WriteAt(p, off)
 }
 
+// PunchHole makes the range read as zeroes, deallocating it where the file
+// can be sparse. Like WriteAt it only needs a read-lock.
+func (w *lockedWriterAt) PunchHole(offset, size int64) error {
+	w.mut.RLock()
+	defer w.mut.RUnlock()
+	return fs.PunchHole(w.fd, offset, size)
+}
+
 // SyncClose ensures that no more writes are happening before going ahead and
 // syncing and closing the fd, thus needs to acquire a write-lock.
 func (w *lockedWriterAt) SyncClose() error {
@@ -114,6 +122,33 @@ func (s *sharedPullerState) tempFile() (io.WriterAt, error) {
 	return s.writer, nil
 }
 
+// writeBlock writes the data of a block to the temp file at the offset.
+// Data of all zeroes is left as a hole instead, when the file is sparse.
+func (s *sharedPullerState) writeBlock(fd io.WriterAt, data []byte, offset int64) error {
+	if s.sparse && fs.IsZeroes(data) {
+		return s.punchHole(fd, offset, int64(len(data)))
+	}
+	_, err := fd.WriteAt(data, offset)
+	return err
+}
+
+// punchHole makes the range of the temp file read as zeroes, leaving it
+// a hole where possible.
+func (s *sharedPullerState) punchHole(fd io.WriterAt, offset, size int64) error {
+	if s.sparse && s.reused == 0 {
+		// A new temp file was truncated to its size, so it is a hole
+		// wherever nothing has been written.
+		return nil
+	}
+	if p, ok := fd.(interface {
+		PunchHole(offset, size int64) error
+	}); ok {
+		return p.PunchHole(offset, size)
+	}
+	_, err := fd.WriteAt(make([]byte, size), offset)
+	return err
+}
+
 // tempFileInWritableDir should only be called from tempFile.
 func (s *sharedPullerState) tempFileInWritableDir(_ string) error {
 	// The permissions to use for the temporary file should be those of the
@@ -300,12 +335,12 @@ func (s *sharedPullerState) finalClose() (bool, error) {
 }
 
 // Progress returns the momentarily progress for the puller
-func (s *sharedPullerState) Progress() *pullerProgress {
+func (s *sharedPullerState) Progress() *PullerProgress {
 	s.mut.RLock()
 	defer s.mut.RUnlock()
 	total := s.reused + s.copyTotal + s.pullTotal
 	done := total - s.copyNeeded - s.pullNeeded
-	return &pullerProgress{
+	return &PullerProgress{
 		Total:               total,
 		Reused:              s.reused,
 		CopiedFromOrigin:    s.copyOrigin,
@@ -0,0 +1,84 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/thejerf/suture"
+
+	"github.com/syncthing/syncthing/lib/util"
+)
+
+// syncWindowScheduler keeps track of the folders' sync windows. The folders
+// don't scan or pull outside them on their own, so when a window opens the
+// scheduler has them catch up on what they missed with a full scan and a
+// pull.
+type syncWindowScheduler struct {
+	suture.Service
+	model *model
+	open  map[string]bool // folder -> whether its window was open at the last check
+}
+
+func newSyncWindowScheduler(m *model) *syncWindowScheduler {
+	s := &syncWindowScheduler{
+		model: m,
+		open:  make(map[string]bool),
+	}
+	s.Service = util.AsService(s.serve, "syncWindowScheduler")
+	return s
+}
+
+func (s *syncWindowScheduler) serve(ctx context.Context) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	s.check(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, folder := range s.check(now) {
+				s.model.fmut.RLock()
+				runner, ok := s.model.folderRunners[folder]
+				s.model.fmut.RUnlock()
+				if !ok {
+					continue
+				}
+				runner.SchedulePull()
+				runner.DelayScan(0)
+			}
+		}
+	}
+}
+
+// check returns the folders whose sync window has opened since the last
+// check.
+func (s *syncWindowScheduler) check(now time.Time) []string {
+	var opened []string
+	folders := s.model.cfg.Folders()
+	for id, cfg := range folders {
+		if len(cfg.SyncWindows) == 0 {
+			delete(s.open, id)
+			continue
+		}
+		open := cfg.InSyncWindow(now)
+		if was, ok := s.open[id]; ok && open && !was {
+			l.Infof("Sync window of folder %s opened", cfg.Description())
+			opened = append(opened, id)
+		}
+		s.open[id] = open
+	}
+	for id := range s.open {
+		if _, ok := folders[id]; !ok {
+			delete(s.open, id)
+		}
+	}
+	return opened
+}
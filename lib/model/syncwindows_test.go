@@ -0,0 +1,41 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSyncWindowScheduler(t *testing.T) {
+	w := createTmpWrapper(defaultCfg.Copy())
+	defer os.Remove(w.ConfigPath())
+	fcfg, _ := w.Folder("default")
+	fcfg.SyncWindows = []string{"22:00-06:00"}
+	waiter, err := w.SetFolder(fcfg)
+	must(t, err)
+	waiter.Wait()
+
+	s := newSyncWindowScheduler(&model{cfg: w})
+	day := time.Date(2019, 6, 7, 0, 0, 0, 0, time.Local)
+
+	// The first check only finds out where we are
+	if opened := s.check(day.Add(23 * time.Hour)); len(opened) != 0 {
+		t.Error("Expected nothing to open on the first check, got", opened)
+	}
+	if opened := s.check(day.Add(30 * time.Hour)); len(opened) != 0 {
+		t.Error("Expected nothing to open, got", opened)
+	}
+	if opened := s.check(day.Add(46*time.Hour + time.Minute)); !reflect.DeepEqual(opened, []string{fcfg.ID}) {
+		t.Error("Expected the folder to open, got", opened)
+	}
+	if opened := s.check(day.Add(47 * time.Hour)); len(opened) != 0 {
+		t.Error("Expected nothing to open again, got", opened)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"math"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/sync"
+)
+
+const (
+	// Samples closer together than this are not used to calculate a new
+	// rate, as they mostly add noise.
+	minRateSampleInterval = time.Second
+	// The time constant for the exponential moving average of the rate.
+	rateAveragingWindow = 30 * time.Second
+)
+
+// folderTransferRate tracks how fast the need of a device in a folder is
+// shrinking, based on successive completion calculations.
+type folderTransferRate struct {
+	when time.Time
+	need int64
+	rate float64 // bytes per second
+}
+
+// update records the current need and returns the averaged rate.
+func (r *folderTransferRate) update(need int64, now time.Time) float64 {
+	if r.when.IsZero() {
+		r.when, r.need = now, need
+		return 0
+	}
+	dt := now.Sub(r.when)
+	if dt < minRateSampleInterval {
+		return r.rate
+	}
+
+	// An increasing need means that new data appeared, which says nothing
+	// about how fast the device is pulling.
+	var done int64
+	if need < r.need {
+		done = r.need - need
+	}
+	current := float64(done) / dt.Seconds()
+
+	alpha := 1 - math.Exp(-dt.Seconds()/rateAveragingWindow.Seconds())
+	r.rate += alpha * (current - r.rate)
+	r.when, r.need = now, need
+	return r.rate
+}
+
+// deviceTransferRates holds the transfer rates of all folders of a specific
+// device.
+type deviceTransferRates struct {
+	mut     sync.Mutex
+	folders map[string]*folderTransferRate
+}
+
+func newDeviceTransferRates() *deviceTransferRates {
+	return &deviceTransferRates{
+		mut:     sync.NewMutex(),
+		folders: make(map[string]*folderTransferRate),
+	}
+}
+
+// Update records the current need of the device in the folder and returns
+// the averaged rate in bytes per second.
+func (t *deviceTransferRates) Update(folder string, need int64, now time.Time) float64 {
+	if t == nil {
+		return 0
+	}
+	t.mut.Lock()
+	defer t.mut.Unlock()
+
+	r, ok := t.folders[folder]
+	if !ok {
+		r = &folderTransferRate{}
+		t.folders[folder] = r
+	}
+	return r.update(need, now)
+}
@@ -0,0 +1,53 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeviceTransferRates(t *testing.T) {
+	r := newDeviceTransferRates()
+	now := time.Now()
+
+	if rate := r.Update("folder", 1000000, now); rate != 0 {
+		t.Errorf("first sample gave rate %v, expected 0", rate)
+	}
+
+	// A steady 1000 B/s converges on 1000 B/s.
+	var rate float64
+	need := int64(1000000)
+	for i := 0; i < 300; i++ {
+		now = now.Add(time.Second)
+		need -= 1000
+		rate = r.Update("folder", need, now)
+	}
+	if rate < 990 || rate > 1000 {
+		t.Errorf("steady rate is %v, expected about 1000", rate)
+	}
+
+	// Samples in quick succession don't change the rate.
+	if res := r.Update("folder", need-100000, now.Add(time.Millisecond)); res != rate {
+		t.Errorf("rate changed to %v on a quick sample", res)
+	}
+
+	// More need is not negative progress.
+	now = now.Add(time.Minute)
+	if res := r.Update("folder", need+100000, now); res < 0 || res >= rate {
+		t.Errorf("rate %v after need increased, expected less than %v but not negative", res, rate)
+	}
+
+	if rate := r.Update("other", 1000, now); rate != 0 {
+		t.Errorf("other folder has rate %v, expected 0", rate)
+	}
+
+	var nilRates *deviceTransferRates
+	if rate := nilRates.Update("folder", 1000, now); rate != 0 {
+		t.Errorf("nil rates gave rate %v, expected 0", rate)
+	}
+}
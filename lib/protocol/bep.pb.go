@@ -213,6 +213,8 @@ type Hello struct {
 	DeviceName    string `protobuf:"bytes,1,opt,name=device_name,json=deviceName,proto3" json:"device_name,omitempty"`
 	ClientName    string `protobuf:"bytes,2,opt,name=client_name,json=clientName,proto3" json:"client_name,omitempty"`
 	ClientVersion string `protobuf:"bytes,3,opt,name=client_version,json=clientVersion,proto3" json:"client_version,omitempty"`
+	// The time the message was sent, in nanoseconds since the Unix epoch.
+	Timestamp int64 `protobuf:"varint,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
 }
 
 func (m *Hello) Reset()         { *m = Hello{} }
@@ -324,13 +326,21 @@ func (m *ClusterConfig) XXX_DiscardUnknown() {
 var xxx_messageInfo_ClusterConfig proto.InternalMessageInfo
 
 type Folder struct {
-	ID                 string   `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Label              string   `protobuf:"bytes,2,opt,name=label,proto3" json:"label,omitempty"`
-	ReadOnly           bool     `protobuf:"varint,3,opt,name=read_only,json=readOnly,proto3" json:"read_only,omitempty"`
-	IgnorePermissions  bool     `protobuf:"varint,4,opt,name=ignore_permissions,json=ignorePermissions,proto3" json:"ignore_permissions,omitempty"`
-	IgnoreDelete       bool     `protobuf:"varint,5,opt,name=ignore_delete,json=ignoreDelete,proto3" json:"ignore_delete,omitempty"`
-	DisableTempIndexes bool     `protobuf:"varint,6,opt,name=disable_temp_indexes,json=disableTempIndexes,proto3" json:"disable_temp_indexes,omitempty"`
-	Paused             bool     `protobuf:"varint,7,opt,name=paused,proto3" json:"paused,omitempty"`
+	ID                 string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Label              string `protobuf:"bytes,2,opt,name=label,proto3" json:"label,omitempty"`
+	ReadOnly           bool   `protobuf:"varint,3,opt,name=read_only,json=readOnly,proto3" json:"read_only,omitempty"`
+	IgnorePermissions  bool   `protobuf:"varint,4,opt,name=ignore_permissions,json=ignorePermissions,proto3" json:"ignore_permissions,omitempty"`
+	IgnoreDelete       bool   `protobuf:"varint,5,opt,name=ignore_delete,json=ignoreDelete,proto3" json:"ignore_delete,omitempty"`
+	DisableTempIndexes bool   `protobuf:"varint,6,opt,name=disable_temp_indexes,json=disableTempIndexes,proto3" json:"disable_temp_indexes,omitempty"`
+	Paused             bool   `protobuf:"varint,7,opt,name=paused,proto3" json:"paused,omitempty"`
+	// The random identifier stored in the folder marker, and a sample of
+	// the names in the folder root. Used to detect unrelated folders that
+	// happen to share an ID.
+	MarkerID   string   `protobuf:"bytes,8,opt,name=marker_id,json=markerId,proto3" json:"marker_id,omitempty"`
+	RootSample []string `protobuf:"bytes,9,rep,name=root_sample,json=rootSample,proto3" json:"root_sample,omitempty"`
+	// The precision of modification times in the folder on the device,
+	// when coarser than the nanoseconds of the protocol.
+	ModTimePrecisionMs int32    `protobuf:"varint,10,opt,name=mod_time_precision_ms,json=modTimePrecisionMs,proto3" json:"mod_time_precision_ms,omitempty"`
 	Devices            []Device `protobuf:"bytes,16,rep,name=devices,proto3" json:"devices"`
 }
 
@@ -415,6 +425,9 @@ var xxx_messageInfo_Device proto.InternalMessageInfo
 type Index struct {
 	Folder string     `protobuf:"bytes,1,opt,name=folder,proto3" json:"folder,omitempty"`
 	Files  []FileInfo `protobuf:"bytes,2,rep,name=files,proto3" json:"files"`
+	// A SHA-256 over the metadata of the files, to detect corruption on
+	// the way. Empty from devices that don't compute it.
+	Checksum []byte `protobuf:"bytes,3,opt,name=checksum,proto3" json:"checksum,omitempty"`
 }
 
 func (m *Index) Reset()         { *m = Index{} }
@@ -453,6 +466,9 @@ var xxx_messageInfo_Index proto.InternalMessageInfo
 type IndexUpdate struct {
 	Folder string     `protobuf:"bytes,1,opt,name=folder,proto3" json:"folder,omitempty"`
 	Files  []FileInfo `protobuf:"bytes,2,rep,name=files,proto3" json:"files"`
+	// A SHA-256 over the metadata of the files, to detect corruption on
+	// the way. Empty from devices that don't compute it.
+	Checksum []byte `protobuf:"bytes,3,opt,name=checksum,proto3" json:"checksum,omitempty"`
 }
 
 func (m *IndexUpdate) Reset()         { *m = IndexUpdate{} }
@@ -489,15 +505,19 @@ func (m *IndexUpdate) XXX_DiscardUnknown() {
 var xxx_messageInfo_IndexUpdate proto.InternalMessageInfo
 
 type FileInfo struct {
-	Name          string       `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Size          int64        `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`
-	ModifiedS     int64        `protobuf:"varint,5,opt,name=modified_s,json=modifiedS,proto3" json:"modified_s,omitempty"`
-	ModifiedBy    ShortID      `protobuf:"varint,12,opt,name=modified_by,json=modifiedBy,proto3,customtype=ShortID" json:"modified_by"`
-	Version       Vector       `protobuf:"bytes,9,opt,name=version,proto3" json:"version"`
-	Sequence      int64        `protobuf:"varint,10,opt,name=sequence,proto3" json:"sequence,omitempty"`
-	Blocks        []BlockInfo  `protobuf:"bytes,16,rep,name=blocks,proto3" json:"blocks"`
-	SymlinkTarget string       `protobuf:"bytes,17,opt,name=symlink_target,json=symlinkTarget,proto3" json:"symlink_target,omitempty"`
-	BlocksHash    []byte       `protobuf:"bytes,18,opt,name=blocks_hash,json=blocksHash,proto3" json:"blocks_hash,omitempty"`
+	Name          string      `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Size          int64       `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`
+	ModifiedS     int64       `protobuf:"varint,5,opt,name=modified_s,json=modifiedS,proto3" json:"modified_s,omitempty"`
+	ModifiedBy    ShortID     `protobuf:"varint,12,opt,name=modified_by,json=modifiedBy,proto3,customtype=ShortID" json:"modified_by"`
+	Version       Vector      `protobuf:"bytes,9,opt,name=version,proto3" json:"version"`
+	Sequence      int64       `protobuf:"varint,10,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	Blocks        []BlockInfo `protobuf:"bytes,16,rep,name=blocks,proto3" json:"blocks"`
+	SymlinkTarget string      `protobuf:"bytes,17,opt,name=symlink_target,json=symlinkTarget,proto3" json:"symlink_target,omitempty"`
+	BlocksHash    []byte      `protobuf:"bytes,18,opt,name=blocks_hash,json=blocksHash,proto3" json:"blocks_hash,omitempty"`
+	// Extended attributes, which include ACLs on Linux, as read on the
+	// platform (GOOS) named. The platform is empty when they were not read.
+	Xattrs        []Xattr      `protobuf:"bytes,19,rep,name=xattrs,proto3" json:"xattrs"`
+	XattrPlatform string       `protobuf:"bytes,20,opt,name=xattr_platform,json=xattrPlatform,proto3" json:"xattr_platform,omitempty"`
 	Type          FileInfoType `protobuf:"varint,2,opt,name=type,proto3,enum=protocol.FileInfoType" json:"type,omitempty"`
 	Permissions   uint32       `protobuf:"varint,4,opt,name=permissions,proto3" json:"permissions,omitempty"`
 	ModifiedNs    int32        `protobuf:"varint,11,opt,name=modified_ns,json=modifiedNs,proto3" json:"modified_ns,omitempty"`
@@ -583,6 +603,44 @@ func (m *BlockInfo) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_BlockInfo proto.InternalMessageInfo
 
+type Xattr struct {
+	Name  string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Value []byte `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+}
+
+func (m *Xattr) Reset()         { *m = Xattr{} }
+func (m *Xattr) String() string { return proto.CompactTextString(m) }
+func (*Xattr) ProtoMessage()    {}
+func (*Xattr) Descriptor() ([]byte, []int) {
+	return fileDescriptor_e3f59eb60afbbc6e, []int{9}
+}
+func (m *Xattr) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *Xattr) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_Xattr.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *Xattr) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Xattr.Merge(m, src)
+}
+func (m *Xattr) XXX_Size() int {
+	return m.ProtoSize()
+}
+func (m *Xattr) XXX_DiscardUnknown() {
+	xxx_messageInfo_Xattr.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Xattr proto.InternalMessageInfo
+
 type Vector struct {
 	Counters []Counter `protobuf:"bytes,1,rep,name=counters,proto3" json:"counters"`
 }
@@ -591,7 +649,7 @@ func (m *Vector) Reset()         { *m = Vector{} }
 func (m *Vector) String() string { return proto.CompactTextString(m) }
 func (*Vector) ProtoMessage()    {}
 func (*Vector) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e3f59eb60afbbc6e, []int{9}
+	return fileDescriptor_e3f59eb60afbbc6e, []int{10}
 }
 func (m *Vector) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -629,7 +687,7 @@ func (m *Counter) Reset()         { *m = Counter{} }
 func (m *Counter) String() string { return proto.CompactTextString(m) }
 func (*Counter) ProtoMessage()    {}
 func (*Counter) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e3f59eb60afbbc6e, []int{10}
+	return fileDescriptor_e3f59eb60afbbc6e, []int{11}
 }
 func (m *Counter) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -673,7 +731,7 @@ func (m *Request) Reset()         { *m = Request{} }
 func (m *Request) String() string { return proto.CompactTextString(m) }
 func (*Request) ProtoMessage()    {}
 func (*Request) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e3f59eb60afbbc6e, []int{11}
+	return fileDescriptor_e3f59eb60afbbc6e, []int{12}
 }
 func (m *Request) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -712,7 +770,7 @@ func (m *Response) Reset()         { *m = Response{} }
 func (m *Response) String() string { return proto.CompactTextString(m) }
 func (*Response) ProtoMessage()    {}
 func (*Response) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e3f59eb60afbbc6e, []int{12}
+	return fileDescriptor_e3f59eb60afbbc6e, []int{13}
 }
 func (m *Response) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -750,7 +808,7 @@ func (m *DownloadProgress) Reset()         { *m = DownloadProgress{} }
 func (m *DownloadProgress) String() string { return proto.CompactTextString(m) }
 func (*DownloadProgress) ProtoMessage()    {}
 func (*DownloadProgress) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e3f59eb60afbbc6e, []int{13}
+	return fileDescriptor_e3f59eb60afbbc6e, []int{14}
 }
 func (m *DownloadProgress) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -790,7 +848,7 @@ func (m *FileDownloadProgressUpdate) Reset()         { *m = FileDownloadProgress
 func (m *FileDownloadProgressUpdate) String() string { return proto.CompactTextString(m) }
 func (*FileDownloadProgressUpdate) ProtoMessage()    {}
 func (*FileDownloadProgressUpdate) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e3f59eb60afbbc6e, []int{14}
+	return fileDescriptor_e3f59eb60afbbc6e, []int{15}
 }
 func (m *FileDownloadProgressUpdate) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -826,7 +884,7 @@ func (m *Ping) Reset()         { *m = Ping{} }
 func (m *Ping) String() string { return proto.CompactTextString(m) }
 func (*Ping) ProtoMessage()    {}
 func (*Ping) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e3f59eb60afbbc6e, []int{15}
+	return fileDescriptor_e3f59eb60afbbc6e, []int{16}
 }
 func (m *Ping) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -863,7 +921,7 @@ func (m *Close) Reset()         { *m = Close{} }
 func (m *Close) String() string { return proto.CompactTextString(m) }
 func (*Close) ProtoMessage()    {}
 func (*Close) Descriptor() ([]byte, []int) {
-	return fileDescriptor_e3f59eb60afbbc6e, []int{16}
+	return fileDescriptor_e3f59eb60afbbc6e, []int{17}
 }
 func (m *Close) XXX_Unmarshal(b []byte) error {
 	return m.Unmarshal(b)
@@ -908,6 +966,7 @@ func init() {
 	proto.RegisterType((*IndexUpdate)(nil), "protocol.IndexUpdate")
 	proto.RegisterType((*FileInfo)(nil), "protocol.FileInfo")
 	proto.RegisterType((*BlockInfo)(nil), "protocol.BlockInfo")
+	proto.RegisterType((*Xattr)(nil), "protocol.Xattr")
 	proto.RegisterType((*Vector)(nil), "protocol.Vector")
 	proto.RegisterType((*Counter)(nil), "protocol.Counter")
 	proto.RegisterType((*Request)(nil), "protocol.Request")
@@ -921,121 +980,131 @@ func init() {
 func init() { proto.RegisterFile("bep.proto", fileDescriptor_e3f59eb60afbbc6e) }
 
 var fileDescriptor_e3f59eb60afbbc6e = []byte{
-	// 1816 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xac, 0x56, 0x4f, 0x6f, 0xdb, 0xc8,
-	0x15, 0x17, 0x25, 0xea, 0xdf, 0x93, 0xec, 0xa5, 0x27, 0x89, 0xcb, 0x32, 0x59, 0x89, 0x51, 0x92,
-	0x8d, 0xd6, 0xd8, 0x26, 0xe9, 0xee, 0xb6, 0x45, 0x8b, 0xb6, 0x80, 0xfe, 0xd0, 0x8e, 0x50, 0x47,
-	0x72, 0x47, 0x72, 0xb6, 0xd9, 0x43, 0x09, 0x5a, 0x1c, 0xc9, 0x44, 0x28, 0x8e, 0x4a, 0x52, 0x76,
-	0xb4, 0x1f, 0x41, 0xa7, 0x1e, 0x7b, 0x11, 0xb0, 0x40, 0x4f, 0xfd, 0x26, 0x39, 0xa6, 0x3d, 0x14,
-	0x45, 0x0f, 0x46, 0xd7, 0xb9, 0xec, 0xb1, 0x9f, 0xa0, 0x2d, 0x66, 0x86, 0x94, 0x28, 0x7b, 0xb3,
-	0xc8, 0xa1, 0x27, 0xce, 0xbc, 0xf7, 0x9b, 0x37, 0x33, 0xbf, 0xf7, 0xde, 0x6f, 0x08, 0xc5, 0x13,
-	0x32, 0x7d, 0x34, 0xf5, 0x69, 0x48, 0x51, 0x81, 0x7f, 0x86, 0xd4, 0xd5, 0xee, 0xf9, 0x64, 0x4a,
-	0x83, 0xc7, 0x7c, 0x7e, 0x32, 0x1b, 0x3d, 0x1e, 0xd3, 0x31, 0xe5, 0x13, 0x3e, 0x12, 0xf0, 0xda,
-	0x14, 0xb2, 0x4f, 0x89, 0xeb, 0x52, 0x54, 0x85, 0x92, 0x4d, 0xce, 0x9c, 0x21, 0x31, 0x3d, 0x6b,
-	0x42, 0x54, 0x49, 0x97, 0xea, 0x45, 0x0c, 0xc2, 0xd4, 0xb5, 0x26, 0x84, 0x01, 0x86, 0xae, 0x43,
-	0xbc, 0x50, 0x00, 0xd2, 0x02, 0x20, 0x4c, 0x1c, 0xf0, 0x00, 0xb6, 0x23, 0xc0, 0x19, 0xf1, 0x03,
-	0x87, 0x7a, 0x6a, 0x86, 0x63, 0xb6, 0x84, 0xf5, 0xb9, 0x30, 0xd6, 0x02, 0xc8, 0x3d, 0x25, 0x96,
-	0x4d, 0x7c, 0xf4, 0x31, 0xc8, 0xe1, 0x7c, 0x2a, 0xf6, 0xda, 0xfe, 0xf4, 0xd6, 0xa3, 0xf8, 0xe4,
-	0x8f, 0x9e, 0x91, 0x20, 0xb0, 0xc6, 0x64, 0x30, 0x9f, 0x12, 0xcc, 0x21, 0xe8, 0xd7, 0x50, 0x1a,
-	0xd2, 0xc9, 0xd4, 0x27, 0x01, 0x0f, 0x9c, 0xe6, 0x2b, 0xee, 0x5c, 0x5b, 0xd1, 0x5a, 0x63, 0x70,
-	0x72, 0x41, 0xad, 0x01, 0x5b, 0x2d, 0x77, 0x16, 0x84, 0xc4, 0x6f, 0x51, 0x6f, 0xe4, 0x8c, 0xd1,
-	0x13, 0xc8, 0x8f, 0xa8, 0x6b, 0x13, 0x3f, 0x50, 0x25, 0x3d, 0x53, 0x2f, 0x7d, 0xaa, 0xac, 0x83,
-	0xed, 0x73, 0x47, 0x53, 0x7e, 0x7d, 0x51, 0x4d, 0xe1, 0x18, 0x56, 0xfb, 0x73, 0x1a, 0x72, 0xc2,
-	0x83, 0x76, 0x21, 0xed, 0xd8, 0x82, 0xa2, 0x66, 0xee, 0xf2, 0xa2, 0x9a, 0xee, 0xb4, 0x71, 0xda,
-	0xb1, 0xd1, 0x4d, 0xc8, 0xba, 0xd6, 0x09, 0x71, 0x23, 0x72, 0xc4, 0x04, 0xdd, 0x86, 0xa2, 0x4f,
-	0x2c, 0xdb, 0xa4, 0x9e, 0x3b, 0xe7, 0x94, 0x14, 0x70, 0x81, 0x19, 0x7a, 0x9e, 0x3b, 0x47, 0x3f,
-	0x02, 0xe4, 0x8c, 0x3d, 0xea, 0x13, 0x73, 0x4a, 0xfc, 0x89, 0xc3, 0x4f, 0x1b, 0xa8, 0x32, 0x47,
-	0xed, 0x08, 0xcf, 0xd1, 0xda, 0x81, 0xee, 0xc1, 0x56, 0x04, 0xb7, 0x89, 0x4b, 0x42, 0xa2, 0x66,
-	0x39, 0xb2, 0x2c, 0x8c, 0x6d, 0x6e, 0x43, 0x4f, 0xe0, 0xa6, 0xed, 0x04, 0xd6, 0x89, 0x4b, 0xcc,
-	0x90, 0x4c, 0xa6, 0xa6, 0xe3, 0xd9, 0xe4, 0x15, 0x09, 0xd4, 0x1c, 0xc7, 0xa2, 0xc8, 0x37, 0x20,
-	0x93, 0x69, 0x47, 0x78, 0xd0, 0x2e, 0xe4, 0xa6, 0xd6, 0x2c, 0x20, 0xb6, 0x9a, 0xe7, 0x98, 0x68,
-	0xc6, 0x58, 0x12, 0x15, 0x10, 0xa8, 0xca, 0x55, 0x96, 0xda, 0xdc, 0x11, 0xb3, 0x14, 0xc1, 0x6a,
-	0xff, 0x4e, 0x43, 0x4e, 0x78, 0xd0, 0x47, 0x2b, 0x96, 0xca, 0xcd, 0x5d, 0x86, 0xfa, 0xe7, 0x45,
-	0xb5, 0x20, 0x7c, 0x9d, 0x76, 0x82, 0x35, 0x04, 0x72, 0xa2, 0xa2, 0xf8, 0x18, 0xdd, 0x81, 0xa2,
-	0x65, 0xdb, 0x2c, 0x7b, 0x24, 0x50, 0x33, 0x7a, 0xa6, 0x5e, 0xc4, 0x6b, 0x03, 0xfa, 0xd9, 0x66,
-	0x35, 0xc8, 0x57, 0xeb, 0xe7, 0x5d, 0x65, 0xc0, 0x52, 0x31, 0x24, 0x7e, 0x54, 0xc1, 0x59, 0xbe,
-	0x5f, 0x81, 0x19, 0x78, 0xfd, 0xde, 0x85, 0xf2, 0xc4, 0x7a, 0x65, 0x06, 0xe4, 0x0f, 0x33, 0xe2,
-	0x0d, 0x09, 0xa7, 0x2b, 0x83, 0x4b, 0x13, 0xeb, 0x55, 0x3f, 0x32, 0xa1, 0x0a, 0x80, 0xe3, 0x85,
-	0x3e, 0xb5, 0x67, 0x43, 0xe2, 0x47, 0x5c, 0x25, 0x2c, 0xe8, 0x27, 0x50, 0xe0, 0x64, 0x9b, 0x8e,
-	0xad, 0x16, 0x74, 0xa9, 0x2e, 0x37, 0xb5, 0xe8, 0xe2, 0x79, 0x4e, 0x35, 0xbf, 0x77, 0x3c, 0xc4,
-	0x79, 0x8e, 0xed, 0xd8, 0xe8, 0x97, 0xa0, 0x05, 0x2f, 0x1d, 0x96, 0x28, 0x11, 0x29, 0x74, 0xa8,
-	0x67, 0xfa, 0x64, 0x42, 0xcf, 0x2c, 0x37, 0x50, 0x8b, 0x7c, 0x1b, 0x95, 0x21, 0x3a, 0x09, 0x00,
-	0x8e, 0xfc, 0xb5, 0x1e, 0x64, 0x79, 0x44, 0x96, 0x45, 0x51, 0xac, 0x51, 0xf7, 0x46, 0x33, 0xf4,
-	0x08, 0xb2, 0x23, 0xc7, 0x25, 0x81, 0x9a, 0xe6, 0x39, 0x44, 0x89, 0x4a, 0x77, 0x5c, 0xd2, 0xf1,
-	0x46, 0x34, 0xca, 0xa2, 0x80, 0xd5, 0x8e, 0xa1, 0xc4, 0x03, 0x1e, 0x4f, 0x6d, 0x2b, 0x24, 0xff,
-	0xb7, 0xb0, 0xff, 0x95, 0xa1, 0x10, 0x7b, 0x56, 0x49, 0x97, 0x12, 0x49, 0x47, 0x20, 0x07, 0xce,
-	0x57, 0x84, 0xf7, 0x48, 0x06, 0xf3, 0x31, 0xfa, 0x10, 0x60, 0x42, 0x6d, 0x67, 0xe4, 0x10, 0xdb,
-	0x0c, 0x78, 0xca, 0x32, 0xb8, 0x18, 0x5b, 0xfa, 0xe8, 0x09, 0x94, 0x56, 0xee, 0x93, 0xb9, 0x5a,
-	0xe6, 0x9c, 0x7f, 0x10, 0x73, 0xde, 0x3f, 0xa5, 0x7e, 0xd8, 0x69, 0xe3, 0x55, 0x88, 0xe6, 0x9c,
-	0x95, 0x74, 0x2c, 0x4f, 0x8c, 0xd8, 0x8d, 0x92, 0x7e, 0x4e, 0x86, 0x21, 0x5d, 0x35, 0x7e, 0x04,
-	0x43, 0x1a, 0x14, 0x56, 0x35, 0x01, 0xfc, 0x00, 0xab, 0x39, 0xfa, 0x31, 0xe4, 0x4e, 0x5c, 0x3a,
-	0x7c, 0x19, 0xf7, 0xc7, 0x8d, 0x75, 0xb0, 0x26, 0xb3, 0x27, 0x58, 0x88, 0x80, 0x4c, 0x26, 0x83,
-	0xf9, 0xc4, 0x75, 0xbc, 0x97, 0x66, 0x68, 0xf9, 0x63, 0x12, 0xaa, 0x3b, 0x42, 0x26, 0x23, 0xeb,
-	0x80, 0x1b, 0x99, 0xdc, 0x8a, 0x05, 0xe6, 0xa9, 0x15, 0x9c, 0xaa, 0x88, 0xb5, 0x11, 0x06, 0x61,
-	0x7a, 0x6a, 0x05, 0xa7, 0x68, 0x2f, 0x52, 0x4f, 0xa1, 0x85, 0xbb, 0xd7, 0xd9, 0x4f, 0xc8, 0xa7,
-	0x0e, 0xa5, 0xab, 0xf2, 0xb2, 0x85, 0x93, 0x26, 0xb6, 0xdd, 0x8a, 0x48, 0x2f, 0x50, 0x4b, 0xba,
-	0x54, 0xcf, 0xae, 0x79, 0xeb, 0x06, 0xe8, 0x31, 0x88, 0xcd, 0x4d, 0x9e, 0xa2, 0x2d, 0xe6, 0x6f,
-	0x2a, 0x97, 0x17, 0xd5, 0x32, 0xb6, 0xce, 0xf9, 0x55, 0xfb, 0xce, 0x57, 0x04, 0x17, 0x4f, 0xe2,
-	0x21, 0xdb, 0xd3, 0xa5, 0x43, 0xcb, 0x35, 0x47, 0xae, 0x35, 0x0e, 0xd4, 0x6f, 0xf3, 0x7c, 0x53,
-	0xe0, 0xb6, 0x7d, 0x66, 0x42, 0x2a, 0x53, 0x17, 0xa6, 0x58, 0x76, 0x24, 0x4d, 0xf1, 0x14, 0xd5,
-	0x21, 0xef, 0x78, 0x67, 0x96, 0xeb, 0x44, 0x82, 0xd4, 0xdc, 0xbe, 0xbc, 0xa8, 0x02, 0xb6, 0xce,
-	0x3b, 0xc2, 0x8a, 0x63, 0x37, 0x63, 0xd3, 0xa3, 0x1b, 0xda, 0x59, 0xe0, 0xa1, 0xb6, 0x3c, 0x9a,
-	0xd0, 0xcd, 0x5f, 0xc8, 0x7f, 0xfa, 0xba, 0x9a, 0xaa, 0x79, 0x50, 0x5c, 0x65, 0x85, 0x55, 0x1b,
-	0x67, 0x36, 0xc3, 0x99, 0xe5, 0x63, 0x56, 0xea, 0x74, 0x34, 0x0a, 0x48, 0xc8, 0xeb, 0x32, 0x83,
-	0xa3, 0xd9, 0xaa, 0x32, 0xd3, 0x9c, 0x16, 0x51, 0x99, 0xb7, 0xa1, 0x78, 0x4e, 0xac, 0x97, 0x22,
-	0x3d, 0x82, 0xd1, 0x02, 0x33, 0xb0, 0xe4, 0x44, 0xfb, 0xfd, 0x0a, 0x72, 0xa2, 0xa4, 0xd0, 0x67,
-	0x50, 0x18, 0xd2, 0x99, 0x17, 0xae, 0xdf, 0x9b, 0x9d, 0xa4, 0x5c, 0x71, 0x4f, 0x54, 0x27, 0x2b,
-	0x60, 0x6d, 0x1f, 0xf2, 0x91, 0x0b, 0x3d, 0x58, 0x69, 0xa9, 0xdc, 0xbc, 0x75, 0xa5, 0xbc, 0x37,
-	0x1f, 0xa0, 0x33, 0xcb, 0x9d, 0x89, 0x83, 0xca, 0x58, 0x4c, 0x6a, 0x7f, 0x95, 0x20, 0x8f, 0x59,
-	0xc5, 0x06, 0x61, 0xe2, 0xe9, 0xca, 0x6e, 0x3c, 0x5d, 0xeb, 0x26, 0x4f, 0x6f, 0x34, 0x79, 0xdc,
-	0xa7, 0x99, 0x44, 0x9f, 0xae, 0x59, 0x92, 0xbf, 0x93, 0xa5, 0x6c, 0x82, 0xa5, 0x98, 0xe5, 0x5c,
-	0x82, 0xe5, 0x07, 0xb0, 0x3d, 0xf2, 0xe9, 0x84, 0x3f, 0x4e, 0xd4, 0xb7, 0xfc, 0x79, 0xa4, 0xa4,
-	0x5b, 0xcc, 0x3a, 0x88, 0x8d, 0x9b, 0x04, 0x17, 0x36, 0x09, 0xae, 0x99, 0x50, 0xc0, 0x24, 0x98,
-	0x52, 0x2f, 0x20, 0xef, 0xbc, 0x13, 0x02, 0xd9, 0xb6, 0x42, 0x8b, 0xdf, 0xa8, 0x8c, 0xf9, 0x18,
-	0x3d, 0x04, 0x79, 0x48, 0x6d, 0x71, 0x9f, 0xed, 0x64, 0xbb, 0x1a, 0xbe, 0x4f, 0xfd, 0x16, 0xb5,
-	0x09, 0xe6, 0x80, 0xda, 0x14, 0x94, 0x36, 0x3d, 0xf7, 0x5c, 0x6a, 0xd9, 0x47, 0x3e, 0x1d, 0xb3,
-	0x17, 0xe4, 0x9d, 0x4a, 0xd8, 0x86, 0xfc, 0x8c, 0x6b, 0x65, 0xac, 0x85, 0xf7, 0x37, 0xbb, 0xf1,
-	0x6a, 0x20, 0x21, 0xac, 0xb1, 0xce, 0x44, 0x4b, 0x6b, 0x7f, 0x97, 0x40, 0x7b, 0x37, 0x1a, 0x75,
-	0xa0, 0x24, 0x90, 0x66, 0xe2, 0xa7, 0xa9, 0xfe, 0x3e, 0x1b, 0x71, 0x21, 0x80, 0xd9, 0x6a, 0xfc,
-	0x9d, 0x2f, 0x6e, 0x42, 0x17, 0x33, 0xef, 0xa7, 0x8b, 0x0f, 0x61, 0x4b, 0x28, 0x42, 0xfc, 0x7f,
-	0x21, 0xeb, 0x99, 0x7a, 0xb6, 0x99, 0x56, 0x52, 0xb8, 0x7c, 0x22, 0xda, 0x8c, 0xdb, 0x6b, 0x39,
-	0x90, 0x8f, 0x1c, 0x6f, 0x5c, 0xab, 0x42, 0xb6, 0xe5, 0x52, 0x9e, 0xb0, 0x9c, 0x4f, 0xac, 0x80,
-	0x7a, 0x31, 0x8f, 0x62, 0xb6, 0xf7, 0xb7, 0x34, 0x94, 0x12, 0xff, 0x7e, 0xe8, 0x09, 0x6c, 0xb7,
-	0x0e, 0x8f, 0xfb, 0x03, 0x03, 0x9b, 0xad, 0x5e, 0x77, 0xbf, 0x73, 0xa0, 0xa4, 0xb4, 0x3b, 0x8b,
-	0xa5, 0xae, 0x4e, 0xd6, 0xa0, 0xcd, 0xdf, 0xba, 0x2a, 0x64, 0x3b, 0xdd, 0xb6, 0xf1, 0x3b, 0x45,
-	0xd2, 0x6e, 0x2e, 0x96, 0xba, 0x92, 0x00, 0x8a, 0x37, 0xf2, 0x13, 0x28, 0x73, 0x80, 0x79, 0x7c,
-	0xd4, 0x6e, 0x0c, 0x0c, 0x25, 0xad, 0x69, 0x8b, 0xa5, 0xbe, 0x7b, 0x15, 0x17, 0x71, 0x7e, 0x0f,
-	0xf2, 0xd8, 0xf8, 0xed, 0xb1, 0xd1, 0x1f, 0x28, 0x19, 0x6d, 0x77, 0xb1, 0xd4, 0x51, 0x02, 0x18,
-	0xb7, 0xd4, 0x03, 0x28, 0x60, 0xa3, 0x7f, 0xd4, 0xeb, 0xf6, 0x0d, 0x45, 0xd6, 0x7e, 0xb0, 0x58,
-	0xea, 0x37, 0x36, 0x50, 0x51, 0x95, 0xfe, 0x14, 0x76, 0xda, 0xbd, 0x2f, 0xba, 0x87, 0xbd, 0x46,
-	0xdb, 0x3c, 0xc2, 0xbd, 0x03, 0x6c, 0xf4, 0xfb, 0x4a, 0x56, 0xab, 0x2e, 0x96, 0xfa, 0xed, 0x04,
-	0xfe, 0x5a, 0xd1, 0x7d, 0x08, 0xf2, 0x51, 0xa7, 0x7b, 0xa0, 0xe4, 0xb4, 0x1b, 0x8b, 0xa5, 0xfe,
-	0x41, 0x02, 0xca, 0x48, 0x65, 0x37, 0x6e, 0x1d, 0xf6, 0xfa, 0x86, 0x92, 0xbf, 0x76, 0x63, 0x4e,
-	0xf6, 0xde, 0xef, 0x01, 0x5d, 0xff, 0x3b, 0x46, 0xf7, 0x41, 0xee, 0xf6, 0xba, 0x86, 0x92, 0x12,
-	0xf7, 0xbf, 0x8e, 0xe8, 0x52, 0x8f, 0xa0, 0x1a, 0x64, 0x0e, 0xbf, 0xfc, 0x5c, 0x91, 0xb4, 0x1f,
-	0x2e, 0x96, 0xfa, 0xad, 0xeb, 0xa0, 0xc3, 0x2f, 0x3f, 0xdf, 0xa3, 0x50, 0x4a, 0x06, 0xae, 0x41,
-	0xe1, 0x99, 0x31, 0x68, 0xb4, 0x1b, 0x83, 0x86, 0x92, 0x12, 0x47, 0x8a, 0xdd, 0xcf, 0x48, 0x68,
-	0xf1, 0x26, 0xbc, 0x03, 0xd9, 0xae, 0xf1, 0xdc, 0xc0, 0x8a, 0xa4, 0xed, 0x2c, 0x96, 0xfa, 0x56,
-	0x0c, 0xe8, 0x92, 0x33, 0xe2, 0xa3, 0x0a, 0xe4, 0x1a, 0x87, 0x5f, 0x34, 0x5e, 0xf4, 0x95, 0xb4,
-	0x86, 0x16, 0x4b, 0x7d, 0x3b, 0x76, 0x37, 0xdc, 0x73, 0x6b, 0x1e, 0xec, 0xfd, 0x47, 0x82, 0x72,
-	0xf2, 0x8d, 0x43, 0x15, 0x90, 0xf7, 0x3b, 0x87, 0x46, 0xbc, 0x5d, 0xd2, 0xc7, 0xc6, 0xa8, 0x0e,
-	0xc5, 0x76, 0x07, 0x1b, 0xad, 0x41, 0x0f, 0xbf, 0x88, 0xef, 0x92, 0x04, 0xb5, 0x1d, 0x9f, 0x17,
-	0xf8, 0x1c, 0xfd, 0x1c, 0xca, 0xfd, 0x17, 0xcf, 0x0e, 0x3b, 0xdd, 0xdf, 0x98, 0x3c, 0x62, 0x5a,
-	0x7b, 0xb8, 0x58, 0xea, 0x77, 0x37, 0xc0, 0x64, 0xea, 0x93, 0xa1, 0x15, 0x12, 0xbb, 0x2f, 0xde,
-	0x6b, 0xe6, 0x2c, 0x48, 0xa8, 0x05, 0x3b, 0xf1, 0xd2, 0xf5, 0x66, 0x19, 0xed, 0x93, 0xc5, 0x52,
-	0xff, 0xe8, 0x7b, 0xd7, 0xaf, 0x76, 0x2f, 0x48, 0xe8, 0x3e, 0xe4, 0xa3, 0x20, 0x71, 0x25, 0x25,
-	0x97, 0x46, 0x0b, 0xf6, 0xfe, 0x22, 0x41, 0x71, 0x25, 0x57, 0x8c, 0xf0, 0x6e, 0xcf, 0x34, 0x30,
-	0xee, 0xe1, 0x98, 0x81, 0x95, 0xb3, 0x4b, 0xf9, 0x10, 0xdd, 0x85, 0xfc, 0x81, 0xd1, 0x35, 0x70,
-	0xa7, 0x15, 0x37, 0xc6, 0x0a, 0x72, 0x40, 0x3c, 0xe2, 0x3b, 0x43, 0xf4, 0x31, 0x94, 0xbb, 0x3d,
-	0xb3, 0x7f, 0xdc, 0x7a, 0x1a, 0x5f, 0x9d, 0xef, 0x9f, 0x08, 0xd5, 0x9f, 0x0d, 0x4f, 0x39, 0x9f,
-	0x7b, 0xac, 0x87, 0x9e, 0x37, 0x0e, 0x3b, 0x6d, 0x01, 0xcd, 0x68, 0xea, 0x62, 0xa9, 0xdf, 0x5c,
-	0x41, 0xa3, 0x47, 0x9a, 0x61, 0xf7, 0x6c, 0xa8, 0x7c, 0xbf, 0x30, 0x21, 0x1d, 0x72, 0x8d, 0xa3,
-	0x23, 0xa3, 0xdb, 0x8e, 0x4f, 0xbf, 0xf6, 0x35, 0xa6, 0x53, 0xe2, 0xd9, 0x0c, 0xb1, 0xdf, 0xc3,
-	0x07, 0xc6, 0x20, 0x3e, 0xfc, 0x1a, 0xb1, 0x4f, 0xd9, 0xcf, 0x52, 0xb3, 0xfe, 0xfa, 0x9b, 0x4a,
-	0xea, 0xcd, 0x37, 0x95, 0xd4, 0xeb, 0xcb, 0x8a, 0xf4, 0xe6, 0xb2, 0x22, 0xfd, 0xeb, 0xb2, 0x92,
-	0xfa, 0xf6, 0xb2, 0x22, 0xfd, 0xf1, 0x6d, 0x25, 0xf5, 0xf5, 0xdb, 0x8a, 0xf4, 0xe6, 0x6d, 0x25,
-	0xf5, 0x8f, 0xb7, 0x95, 0xd4, 0x49, 0x8e, 0x8b, 0xda, 0x67, 0xff, 0x0b, 0x00, 0x00, 0xff, 0xff,
-	0x39, 0xd2, 0xc3, 0x6e, 0x32, 0x0f, 0x00, 0x00,
+	// 1971 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x57, 0xcf, 0x6f, 0xdb, 0xc8,
+	0xf5, 0x17, 0xf5, 0x93, 0x7a, 0x92, 0x1d, 0x79, 0xe2, 0xf8, 0xcb, 0xaf, 0x36, 0x2b, 0x31, 0x4a,
+	0xb2, 0x71, 0x8c, 0xdd, 0xfc, 0xd8, 0xdd, 0xb6, 0x68, 0xd1, 0x16, 0xd0, 0x0f, 0xda, 0x11, 0x6a,
+	0x4b, 0xea, 0x48, 0xc9, 0x6e, 0xf6, 0x50, 0x82, 0x16, 0x47, 0x36, 0x61, 0x92, 0xa3, 0x25, 0x29,
+	0x3b, 0xde, 0x3f, 0x41, 0x87, 0xa2, 0xc7, 0x5e, 0x04, 0x2c, 0x7a, 0xeb, 0x7f, 0x92, 0x63, 0x5a,
+	0xa0, 0x45, 0xd1, 0x83, 0xd1, 0x75, 0x2e, 0x7b, 0xec, 0x5f, 0x50, 0x14, 0x33, 0x43, 0x52, 0x94,
+	0x9d, 0x2c, 0xf6, 0xd4, 0x93, 0x66, 0xde, 0xfb, 0xcc, 0x3c, 0xbe, 0xf7, 0x79, 0x3f, 0x46, 0x50,
+	0x3c, 0x24, 0xd3, 0x47, 0x53, 0x8f, 0x06, 0x14, 0xc9, 0xfc, 0x67, 0x4c, 0xed, 0xea, 0x5d, 0x8f,
+	0x4c, 0xa9, 0xff, 0x98, 0xef, 0x0f, 0x67, 0x93, 0xc7, 0x47, 0xf4, 0x88, 0xf2, 0x0d, 0x5f, 0x09,
+	0x78, 0xe3, 0xf7, 0x12, 0xe4, 0x9e, 0x11, 0xdb, 0xa6, 0xa8, 0x0e, 0x25, 0x93, 0x9c, 0x5a, 0x63,
+	0xa2, 0xbb, 0x86, 0x43, 0x14, 0x49, 0x95, 0xb6, 0x8b, 0x18, 0x84, 0xa8, 0x67, 0x38, 0x84, 0x01,
+	0xc6, 0xb6, 0x45, 0xdc, 0x40, 0x00, 0xd2, 0x02, 0x20, 0x44, 0x1c, 0x70, 0x1f, 0xd6, 0x43, 0xc0,
+	0x29, 0xf1, 0x7c, 0x8b, 0xba, 0x4a, 0x86, 0x63, 0xd6, 0x84, 0xf4, 0x85, 0x10, 0xa2, 0xdb, 0x50,
+	0x0c, 0x2c, 0x87, 0xf8, 0x81, 0xe1, 0x4c, 0x95, 0xac, 0x2a, 0x6d, 0x67, 0xf0, 0x52, 0xd0, 0xf0,
+	0x21, 0xff, 0x8c, 0x18, 0x26, 0xf1, 0xd0, 0x43, 0xc8, 0x06, 0xe7, 0x53, 0xf1, 0x25, 0xeb, 0x9f,
+	0xde, 0x7a, 0x14, 0x39, 0xf6, 0xe8, 0x80, 0xf8, 0xbe, 0x71, 0x44, 0x46, 0xe7, 0x53, 0x82, 0x39,
+	0x04, 0xfd, 0x1a, 0x4a, 0x63, 0xea, 0x4c, 0x3d, 0xe2, 0x73, 0xb3, 0x69, 0x7e, 0xe2, 0xf6, 0xb5,
+	0x13, 0xed, 0x25, 0x06, 0x27, 0x0f, 0x34, 0x9a, 0xb0, 0xd6, 0xb6, 0x67, 0x7e, 0x40, 0xbc, 0x36,
+	0x75, 0x27, 0xd6, 0x11, 0x7a, 0x02, 0x85, 0x09, 0xb5, 0x4d, 0xe2, 0xf9, 0x8a, 0xa4, 0x66, 0xb6,
+	0x4b, 0x9f, 0x56, 0x96, 0x97, 0xed, 0x72, 0x45, 0x2b, 0xfb, 0xfa, 0xa2, 0x9e, 0xc2, 0x11, 0xac,
+	0xf1, 0xa7, 0x0c, 0xe4, 0x85, 0x06, 0x6d, 0x41, 0xda, 0x32, 0x45, 0x00, 0x5b, 0xf9, 0xcb, 0x8b,
+	0x7a, 0xba, 0xdb, 0xc1, 0x69, 0xcb, 0x44, 0x9b, 0x90, 0xb3, 0x8d, 0x43, 0x62, 0x87, 0xa1, 0x13,
+	0x1b, 0xf4, 0x01, 0x14, 0x3d, 0x62, 0x98, 0x3a, 0x75, 0xed, 0x73, 0x1e, 0x30, 0x19, 0xcb, 0x4c,
+	0xd0, 0x77, 0xed, 0x73, 0xf4, 0x09, 0x20, 0xeb, 0xc8, 0xa5, 0x1e, 0xd1, 0xa7, 0xc4, 0x73, 0x2c,
+	0xfe, 0xb5, 0x3e, 0x0f, 0x9a, 0x8c, 0x37, 0x84, 0x66, 0xb0, 0x54, 0xa0, 0xbb, 0xb0, 0x16, 0xc2,
+	0x4d, 0x62, 0x93, 0x80, 0x28, 0x39, 0x8e, 0x2c, 0x0b, 0x61, 0x87, 0xcb, 0xd0, 0x13, 0xd8, 0x34,
+	0x2d, 0xdf, 0x38, 0xb4, 0x89, 0x1e, 0x10, 0x67, 0xaa, 0x5b, 0xae, 0x49, 0x5e, 0x11, 0x5f, 0xc9,
+	0x73, 0x2c, 0x0a, 0x75, 0x23, 0xe2, 0x4c, 0xbb, 0x42, 0x83, 0xb6, 0x20, 0x3f, 0x35, 0x66, 0x3e,
+	0x31, 0x95, 0x02, 0xc7, 0x84, 0x3b, 0xf4, 0x10, 0x8a, 0x8e, 0xe1, 0x9d, 0x10, 0x4f, 0xb7, 0x4c,
+	0x45, 0xe6, 0xfe, 0x96, 0x2f, 0x2f, 0xea, 0xf2, 0x01, 0x17, 0x76, 0x3b, 0x58, 0x16, 0xea, 0xae,
+	0xc9, 0x92, 0xc7, 0xa3, 0x34, 0xd0, 0x7d, 0xc3, 0x99, 0xda, 0x44, 0x29, 0xaa, 0x19, 0x96, 0x3c,
+	0x4c, 0x34, 0xe4, 0x12, 0xf4, 0x14, 0x6e, 0x39, 0xd4, 0xd4, 0x59, 0x22, 0xe8, 0x53, 0x8f, 0x8c,
+	0x2d, 0xe6, 0x91, 0xee, 0xf8, 0x0a, 0xa8, 0xd2, 0x76, 0x0e, 0x23, 0x87, 0x9a, 0x23, 0xcb, 0x21,
+	0x83, 0x48, 0x75, 0xe0, 0x33, 0x92, 0x44, 0x7a, 0xfa, 0x4a, 0xe5, 0x2a, 0x49, 0x1d, 0xae, 0x88,
+	0x48, 0x0a, 0x61, 0x8d, 0x7f, 0xa7, 0x21, 0x2f, 0x34, 0xe8, 0xa3, 0x98, 0xa4, 0x72, 0x6b, 0x8b,
+	0xa1, 0xfe, 0x79, 0x51, 0x97, 0x85, 0xae, 0xdb, 0x49, 0x90, 0x86, 0x20, 0x9b, 0x48, 0x77, 0xbe,
+	0x66, 0x19, 0x6c, 0x98, 0x26, 0x4b, 0x1e, 0xe2, 0x2b, 0x19, 0xee, 0xca, 0x52, 0x80, 0x7e, 0xb6,
+	0x9a, 0x8c, 0xd9, 0xab, 0xe9, 0xfb, 0xbe, 0x2c, 0x64, 0x99, 0x30, 0x26, 0x5e, 0x58, 0x5e, 0x39,
+	0x6e, 0x4f, 0x66, 0x02, 0x5e, 0x5c, 0x77, 0xa0, 0xec, 0x18, 0xaf, 0x74, 0x9f, 0x7c, 0x3d, 0x23,
+	0xee, 0x98, 0x70, 0xb6, 0x32, 0xb8, 0xe4, 0x18, 0xaf, 0x86, 0xa1, 0x08, 0xd5, 0x00, 0x2c, 0x37,
+	0xf0, 0xa8, 0x39, 0x1b, 0x13, 0x2f, 0xa4, 0x2a, 0x21, 0x41, 0x3f, 0x01, 0x99, 0x73, 0x1d, 0xb1,
+	0x95, 0x6d, 0x55, 0x43, 0xc7, 0x0b, 0x9c, 0x69, 0xee, 0x77, 0xb4, 0xc4, 0x05, 0x8e, 0xed, 0x9a,
+	0xe8, 0x97, 0x50, 0xf5, 0x4f, 0xac, 0xa9, 0x1e, 0xdd, 0x14, 0x30, 0x62, 0x3c, 0xe2, 0xd0, 0x53,
+	0xc3, 0xf6, 0x95, 0x22, 0x37, 0xa3, 0x30, 0x44, 0x37, 0x01, 0xc0, 0xa1, 0xbe, 0x71, 0x02, 0x39,
+	0x7e, 0x23, 0x4b, 0x22, 0x51, 0x2b, 0x61, 0x6b, 0x09, 0x77, 0xe8, 0x11, 0xe4, 0x26, 0x96, 0x4d,
+	0x7c, 0x25, 0xcd, 0x39, 0x44, 0x89, 0x42, 0xb3, 0x6c, 0xd2, 0x75, 0x27, 0x34, 0x64, 0x51, 0xc0,
+	0x50, 0x15, 0xe4, 0xf1, 0x31, 0x19, 0x9f, 0xf8, 0x33, 0x87, 0x97, 0x4b, 0x19, 0xc7, 0xfb, 0xc6,
+	0xd7, 0x50, 0xe2, 0xc6, 0x9e, 0x4f, 0x4d, 0x23, 0x20, 0xff, 0x13, 0x93, 0x7f, 0xcb, 0x81, 0x1c,
+	0x9d, 0x8a, 0x93, 0x45, 0x4a, 0x24, 0x0b, 0x82, 0xac, 0x6f, 0x7d, 0x43, 0xf8, 0xc1, 0x0c, 0xe6,
+	0x6b, 0xf4, 0x21, 0x80, 0x43, 0x4d, 0x6b, 0x62, 0x11, 0x53, 0xf7, 0x39, 0xd5, 0x19, 0x5c, 0x8c,
+	0x24, 0x43, 0xf4, 0x04, 0x4a, 0xb1, 0xfa, 0xf0, 0x5c, 0x29, 0x73, 0xae, 0x6e, 0x44, 0x5c, 0x0d,
+	0x8f, 0xa9, 0x17, 0x74, 0x3b, 0x38, 0xbe, 0xa2, 0x75, 0xce, 0x4a, 0x21, 0xea, 0xb9, 0x8c, 0x90,
+	0x95, 0x52, 0x78, 0x41, 0xc6, 0x01, 0x8d, 0xfb, 0x55, 0x08, 0x63, 0x3e, 0xc5, 0xb9, 0x04, 0xfc,
+	0x03, 0xe2, 0x3d, 0x7a, 0x0a, 0xf9, 0x43, 0x9b, 0x8e, 0x4f, 0xa2, 0xba, 0xba, 0xb9, 0xbc, 0xac,
+	0xc5, 0xe4, 0x89, 0x08, 0x85, 0x40, 0xd6, 0xfb, 0xfd, 0x73, 0xc7, 0xb6, 0xdc, 0x13, 0x3d, 0x30,
+	0xbc, 0x23, 0x12, 0x28, 0x1b, 0xa2, 0xf7, 0x87, 0xd2, 0x11, 0x17, 0xb2, 0x36, 0x20, 0x0e, 0xe8,
+	0xc7, 0x86, 0x7f, 0xac, 0x20, 0x1e, 0x4c, 0x10, 0xa2, 0x67, 0x86, 0x7f, 0x8c, 0x3e, 0x81, 0xfc,
+	0x2b, 0x23, 0x08, 0x3c, 0x5f, 0xb9, 0xc9, 0x4d, 0xdf, 0x58, 0x9a, 0xfe, 0x92, 0xc9, 0x23, 0xb3,
+	0x02, 0xc4, 0xcc, 0xf2, 0x95, 0x3e, 0xb5, 0x8d, 0x60, 0x42, 0x3d, 0x47, 0xd9, 0x14, 0x66, 0xb9,
+	0x74, 0x10, 0x0a, 0xd1, 0x4e, 0x38, 0x4a, 0xc4, 0x60, 0xd8, 0xba, 0xce, 0x77, 0x62, 0x96, 0xa8,
+	0x50, 0xba, 0xda, 0x6b, 0xd7, 0x70, 0x52, 0xc4, 0x9c, 0x88, 0xe9, 0x71, 0x7d, 0xa5, 0xc4, 0x1b,
+	0x54, 0xcc, 0x46, 0xcf, 0x47, 0x8f, 0x41, 0xb8, 0xa4, 0x73, 0xe2, 0xd7, 0x98, 0xbe, 0x55, 0xb9,
+	0xbc, 0xa8, 0x97, 0xb1, 0x71, 0xc6, 0x03, 0x38, 0xb4, 0xbe, 0x21, 0xb8, 0x78, 0x18, 0x2d, 0x99,
+	0x4d, 0x9b, 0x8e, 0x0d, 0x5b, 0x9f, 0xd8, 0xc6, 0x91, 0xaf, 0x7c, 0x5f, 0xe0, 0x46, 0x81, 0xcb,
+	0x76, 0x99, 0x08, 0x29, 0xac, 0xd7, 0xb1, 0xf6, 0x6d, 0x86, 0x7d, 0x3a, 0xda, 0xa2, 0x6d, 0x28,
+	0x58, 0xee, 0xa9, 0x61, 0x5b, 0x61, 0x77, 0x6e, 0xad, 0x5f, 0x5e, 0xd4, 0x01, 0x1b, 0x67, 0x5d,
+	0x21, 0xc5, 0x91, 0x9a, 0x05, 0xcb, 0xa5, 0x2b, 0x83, 0x44, 0xe6, 0x57, 0xad, 0xb9, 0x34, 0x31,
+	0x44, 0x7e, 0x91, 0xfd, 0xe3, 0xb7, 0xf5, 0x54, 0xc3, 0x85, 0x62, 0xcc, 0x35, 0xcb, 0x61, 0xce,
+	0x97, 0x48, 0x7e, 0xbe, 0x66, 0xc5, 0x45, 0x27, 0x13, 0x9f, 0x04, 0x3c, 0xdb, 0x33, 0x38, 0xdc,
+	0xc5, 0xf9, 0x9e, 0xe6, 0x61, 0xe1, 0x6b, 0xd6, 0xd9, 0xce, 0x88, 0x71, 0x22, 0x48, 0x17, 0x11,
+	0x95, 0x99, 0x80, 0x51, 0x1e, 0xda, 0x7b, 0x0a, 0x39, 0x4e, 0xf0, 0x3b, 0x6b, 0x68, 0x13, 0x72,
+	0xa7, 0x86, 0x3d, 0x13, 0x97, 0x96, 0xb1, 0xd8, 0x34, 0x7e, 0x05, 0x79, 0x91, 0xdb, 0xe8, 0x33,
+	0x90, 0xc7, 0x74, 0xe6, 0x06, 0xcb, 0x79, 0xbd, 0x91, 0xec, 0xb7, 0x5c, 0x13, 0x66, 0x4e, 0x0c,
+	0x6c, 0xec, 0x42, 0x21, 0x54, 0xa1, 0xfb, 0xf1, 0x30, 0xc8, 0xb6, 0x6e, 0x5d, 0xa9, 0xb3, 0xd5,
+	0x01, 0xbe, 0xfc, 0x8c, 0x6c, 0xf4, 0x19, 0x7f, 0x91, 0xa0, 0x80, 0x59, 0xe9, 0xf8, 0x41, 0x62,
+	0xf4, 0xe7, 0x56, 0x46, 0xff, 0xb2, 0x13, 0xa5, 0x57, 0x3a, 0x51, 0xe4, 0x6c, 0x26, 0xe1, 0xec,
+	0x32, 0xb0, 0xd9, 0x77, 0x06, 0x36, 0x97, 0x08, 0x6c, 0x44, 0x4c, 0x3e, 0x41, 0xcc, 0x7d, 0x58,
+	0x9f, 0x78, 0xd4, 0xe1, 0xc3, 0x9d, 0x7a, 0x86, 0x77, 0x1e, 0x8e, 0x82, 0x35, 0x26, 0x1d, 0x45,
+	0xc2, 0x55, 0x4e, 0xe4, 0x55, 0x4e, 0x1a, 0x3a, 0xc8, 0x98, 0xf8, 0x53, 0xea, 0xfa, 0xe4, 0xbd,
+	0x3e, 0x21, 0xc8, 0x9a, 0x46, 0x60, 0x84, 0x9c, 0xf0, 0x35, 0x7a, 0x00, 0xd9, 0x31, 0x35, 0x85,
+	0x3f, 0xeb, 0xc9, 0xbe, 0xa1, 0x79, 0x1e, 0xf5, 0xda, 0xd4, 0x24, 0x98, 0x03, 0x1a, 0x53, 0xa8,
+	0x74, 0xe8, 0x99, 0x6b, 0x53, 0xc3, 0x1c, 0x78, 0xf4, 0x88, 0x8d, 0xc0, 0xf7, 0xb6, 0xeb, 0x0e,
+	0x14, 0x66, 0xbc, 0xa1, 0x47, 0x0d, 0xfb, 0xde, 0x6a, 0x01, 0x5f, 0xbd, 0x48, 0x74, 0xff, 0xa8,
+	0xe1, 0x85, 0x47, 0x1b, 0x7f, 0x97, 0xa0, 0xfa, 0x7e, 0x34, 0xea, 0x42, 0x49, 0x20, 0xf5, 0xc4,
+	0xa3, 0x73, 0xfb, 0xc7, 0x18, 0xe2, 0xbd, 0x03, 0x66, 0xf1, 0xfa, 0x9d, 0x4f, 0x86, 0x44, 0x83,
+	0xce, 0xfc, 0xb8, 0x06, 0xfd, 0x00, 0xd6, 0x44, 0x13, 0x89, 0xde, 0x67, 0x59, 0x35, 0xb3, 0x9d,
+	0x6b, 0xa5, 0x2b, 0x29, 0x5c, 0x3e, 0x14, 0x95, 0xc9, 0xe5, 0x8d, 0x3c, 0x64, 0x07, 0x96, 0x7b,
+	0xd4, 0xa8, 0x43, 0xae, 0x6d, 0x53, 0x4e, 0x58, 0xde, 0x23, 0x86, 0x4f, 0xdd, 0x28, 0x8e, 0x62,
+	0xb7, 0xf3, 0xd7, 0x34, 0x94, 0x12, 0x6f, 0x67, 0xf4, 0x04, 0xd6, 0xdb, 0xfb, 0xcf, 0x87, 0x23,
+	0x0d, 0xeb, 0xed, 0x7e, 0x6f, 0xb7, 0xbb, 0x57, 0x49, 0x55, 0x6f, 0xcf, 0x17, 0xaa, 0xe2, 0x2c,
+	0x41, 0xab, 0xcf, 0xe2, 0x3a, 0xe4, 0xba, 0xbd, 0x8e, 0xf6, 0x65, 0x45, 0xaa, 0x6e, 0xce, 0x17,
+	0x6a, 0x25, 0x01, 0x14, 0x43, 0xfe, 0x63, 0x28, 0x73, 0x80, 0xfe, 0x7c, 0xd0, 0x69, 0x8e, 0xb4,
+	0x4a, 0xba, 0x5a, 0x9d, 0x2f, 0xd4, 0xad, 0xab, 0xb8, 0x30, 0xe6, 0x77, 0xa1, 0x80, 0xb5, 0xdf,
+	0x3e, 0xd7, 0x86, 0xa3, 0x4a, 0xa6, 0xba, 0x35, 0x5f, 0xa8, 0x28, 0x01, 0x8c, 0x4a, 0xea, 0x3e,
+	0xc8, 0x58, 0x1b, 0x0e, 0xfa, 0xbd, 0xa1, 0x56, 0xc9, 0x56, 0xff, 0x6f, 0xbe, 0x50, 0x6f, 0xae,
+	0xa0, 0xc2, 0x2c, 0xfd, 0x29, 0x6c, 0x74, 0xfa, 0x5f, 0xf4, 0xf6, 0xfb, 0xcd, 0x8e, 0x3e, 0xc0,
+	0xfd, 0x3d, 0xac, 0x0d, 0x87, 0x95, 0x5c, 0xb5, 0x3e, 0x5f, 0xa8, 0x1f, 0x24, 0xf0, 0xd7, 0x92,
+	0xee, 0x43, 0xc8, 0x0e, 0xba, 0xbd, 0xbd, 0x4a, 0xbe, 0x7a, 0x73, 0xbe, 0x50, 0x6f, 0x24, 0xa0,
+	0x2c, 0xa8, 0xcc, 0xe3, 0xf6, 0x7e, 0x7f, 0xa8, 0x55, 0x0a, 0xd7, 0x3c, 0xe6, 0xc1, 0xde, 0xf9,
+	0x1d, 0xa0, 0xeb, 0xff, 0x2e, 0xd0, 0x3d, 0xc8, 0xf6, 0xfa, 0x3d, 0xad, 0x92, 0x12, 0xfe, 0x5f,
+	0x47, 0xf4, 0xa8, 0x4b, 0x50, 0x03, 0x32, 0xfb, 0x5f, 0x7d, 0x5e, 0x91, 0xaa, 0xff, 0x3f, 0x5f,
+	0xa8, 0xb7, 0xae, 0x83, 0xf6, 0xbf, 0xfa, 0x7c, 0x87, 0x42, 0x29, 0x79, 0x71, 0x03, 0xe4, 0x03,
+	0x6d, 0xd4, 0xec, 0x34, 0x47, 0xcd, 0x4a, 0x4a, 0x7c, 0x52, 0xa4, 0x3e, 0x20, 0x81, 0xc1, 0x8b,
+	0xf0, 0x36, 0xe4, 0x7a, 0xda, 0x0b, 0x0d, 0x57, 0xa4, 0xea, 0xc6, 0x7c, 0xa1, 0xae, 0x45, 0x80,
+	0x1e, 0x39, 0x25, 0x1e, 0xaa, 0x41, 0xbe, 0xb9, 0xff, 0x45, 0xf3, 0xe5, 0xb0, 0x92, 0xae, 0xa2,
+	0xf9, 0x42, 0x5d, 0x8f, 0xd4, 0x4d, 0xfb, 0xcc, 0x38, 0xf7, 0x77, 0xfe, 0x23, 0x41, 0x39, 0x39,
+	0x16, 0x51, 0x0d, 0xb2, 0xbb, 0xdd, 0x7d, 0x2d, 0x32, 0x97, 0xd4, 0xb1, 0x35, 0xda, 0x86, 0x62,
+	0xa7, 0x8b, 0xb5, 0xf6, 0xa8, 0x8f, 0x5f, 0x46, 0xbe, 0x24, 0x41, 0x1d, 0xcb, 0xe3, 0x09, 0x7e,
+	0x8e, 0x7e, 0x0e, 0xe5, 0xe1, 0xcb, 0x83, 0xfd, 0x6e, 0xef, 0x37, 0x3a, 0xbf, 0x31, 0x5d, 0x7d,
+	0x30, 0x5f, 0xa8, 0x77, 0x56, 0xc0, 0x84, 0x3d, 0xfe, 0x8d, 0x80, 0x98, 0x43, 0xf1, 0x70, 0x60,
+	0x4a, 0x59, 0x42, 0x6d, 0xd8, 0x88, 0x8e, 0x2e, 0x8d, 0x65, 0xaa, 0x1f, 0xcf, 0x17, 0xea, 0x47,
+	0x3f, 0x78, 0x3e, 0xb6, 0x2e, 0x4b, 0xe8, 0x1e, 0x14, 0xc2, 0x4b, 0xa2, 0x4c, 0x4a, 0x1e, 0x0d,
+	0x0f, 0xec, 0xfc, 0x59, 0x82, 0x62, 0xdc, 0xae, 0x58, 0xc0, 0x7b, 0x7d, 0x5d, 0xc3, 0xb8, 0x8f,
+	0xa3, 0x08, 0xc4, 0xca, 0x1e, 0xe5, 0x4b, 0x74, 0x07, 0x0a, 0x7b, 0x5a, 0x4f, 0xc3, 0xdd, 0x76,
+	0x54, 0x18, 0x31, 0x64, 0x8f, 0xb8, 0xc4, 0xb3, 0xc6, 0xe8, 0x21, 0x94, 0x7b, 0x7d, 0x7d, 0xf8,
+	0xbc, 0xfd, 0x2c, 0x72, 0x9d, 0xdb, 0x4f, 0x5c, 0x35, 0x9c, 0x8d, 0x8f, 0x79, 0x3c, 0x77, 0x58,
+	0x0d, 0xbd, 0x68, 0xee, 0x77, 0x3b, 0x02, 0x9a, 0xa9, 0x2a, 0xf3, 0x85, 0xba, 0x19, 0x43, 0xc3,
+	0xb9, 0xce, 0xb0, 0x3b, 0x26, 0xd4, 0x7e, 0xb8, 0x31, 0x21, 0x15, 0xf2, 0xcd, 0xc1, 0x40, 0xeb,
+	0x75, 0xa2, 0xaf, 0x5f, 0xea, 0x9a, 0xd3, 0x29, 0x71, 0x4d, 0x86, 0xd8, 0xed, 0xe3, 0x3d, 0x6d,
+	0x54, 0x91, 0xae, 0x22, 0x76, 0x29, 0x7b, 0xb5, 0xb5, 0xb6, 0x5f, 0x7f, 0x57, 0x4b, 0xbd, 0xf9,
+	0xae, 0x96, 0x7a, 0x7d, 0x59, 0x93, 0xde, 0x5c, 0xd6, 0xa4, 0x7f, 0x5d, 0xd6, 0x52, 0xdf, 0x5f,
+	0xd6, 0xa4, 0x3f, 0xbc, 0xad, 0xa5, 0xbe, 0x7d, 0x5b, 0x93, 0xde, 0xbc, 0xad, 0xa5, 0xfe, 0xf1,
+	0xb6, 0x96, 0x3a, 0xcc, 0xf3, 0xa6, 0xf6, 0xd9, 0x7f, 0x07, 0x00, 0x3d, 0xfa, 0xf7, 0x64, 0x91,
+	0x10, 0x00, 0x00,
 }
 
 func (m *Hello) Marshal() (dAtA []byte, err error) {
@@ -1058,6 +1127,11 @@ func (m *Hello) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.Timestamp != 0 {
+		i = encodeVarintBep(dAtA, i, uint64(m.Timestamp))
+		i--
+		dAtA[i] = 0x20
+	}
 	if len(m.ClientVersion) > 0 {
 		i -= len(m.ClientVersion)
 		copy(dAtA[i:], m.ClientVersion)
@@ -1188,6 +1262,27 @@ func (m *Folder) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 			dAtA[i] = 0x82
 		}
 	}
+	if m.ModTimePrecisionMs != 0 {
+		i = encodeVarintBep(dAtA, i, uint64(m.ModTimePrecisionMs))
+		i--
+		dAtA[i] = 0x50
+	}
+	if len(m.RootSample) > 0 {
+		for iNdEx := len(m.RootSample) - 1; iNdEx >= 0; iNdEx-- {
+			i -= len(m.RootSample[iNdEx])
+			copy(dAtA[i:], m.RootSample[iNdEx])
+			i = encodeVarintBep(dAtA, i, uint64(len(m.RootSample[iNdEx])))
+			i--
+			dAtA[i] = 0x4a
+		}
+	}
+	if len(m.MarkerID) > 0 {
+		i -= len(m.MarkerID)
+		copy(dAtA[i:], m.MarkerID)
+		i = encodeVarintBep(dAtA, i, uint64(len(m.MarkerID)))
+		i--
+		dAtA[i] = 0x42
+	}
 	if m.Paused {
 		i--
 		if m.Paused {
@@ -1366,6 +1461,13 @@ func (m *Index) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if len(m.Checksum) > 0 {
+		i -= len(m.Checksum)
+		copy(dAtA[i:], m.Checksum)
+		i = encodeVarintBep(dAtA, i, uint64(len(m.Checksum)))
+		i--
+		dAtA[i] = 0x1a
+	}
 	if len(m.Files) > 0 {
 		for iNdEx := len(m.Files) - 1; iNdEx >= 0; iNdEx-- {
 			{
@@ -1410,6 +1512,13 @@ func (m *IndexUpdate) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if len(m.Checksum) > 0 {
+		i -= len(m.Checksum)
+		copy(dAtA[i:], m.Checksum)
+		i = encodeVarintBep(dAtA, i, uint64(len(m.Checksum)))
+		i--
+		dAtA[i] = 0x1a
+	}
 	if len(m.Files) > 0 {
 		for iNdEx := len(m.Files) - 1; iNdEx >= 0; iNdEx-- {
 			{
@@ -1461,6 +1570,31 @@ func (m *FileInfo) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		i--
 		dAtA[i] = 0xc0
 	}
+	if len(m.XattrPlatform) > 0 {
+		i -= len(m.XattrPlatform)
+		copy(dAtA[i:], m.XattrPlatform)
+		i = encodeVarintBep(dAtA, i, uint64(len(m.XattrPlatform)))
+		i--
+		dAtA[i] = 0x1
+		i--
+		dAtA[i] = 0xa2
+	}
+	if len(m.Xattrs) > 0 {
+		for iNdEx := len(m.Xattrs) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Xattrs[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintBep(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x1
+			i--
+			dAtA[i] = 0x9a
+		}
+	}
 	if len(m.BlocksHash) > 0 {
 		i -= len(m.BlocksHash)
 		copy(dAtA[i:], m.BlocksHash)
@@ -1630,6 +1764,43 @@ func (m *BlockInfo) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
+func (m *Xattr) Marshal() (dAtA []byte, err error) {
+	size := m.ProtoSize()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *Xattr) MarshalTo(dAtA []byte) (int, error) {
+	size := m.ProtoSize()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *Xattr) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Value) > 0 {
+		i -= len(m.Value)
+		copy(dAtA[i:], m.Value)
+		i = encodeVarintBep(dAtA, i, uint64(len(m.Value)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if len(m.Name) > 0 {
+		i -= len(m.Name)
+		copy(dAtA[i:], m.Name)
+		i = encodeVarintBep(dAtA, i, uint64(len(m.Name)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
 func (m *Vector) Marshal() (dAtA []byte, err error) {
 	size := m.ProtoSize()
 	dAtA = make([]byte, size)
@@ -1992,6 +2163,9 @@ func (m *Hello) ProtoSize() (n int) {
 	if l > 0 {
 		n += 1 + l + sovBep(uint64(l))
 	}
+	if m.Timestamp != 0 {
+		n += 1 + sovBep(uint64(m.Timestamp))
+	}
 	return n
 }
 
@@ -2054,6 +2228,19 @@ func (m *Folder) ProtoSize() (n int) {
 	if m.Paused {
 		n += 2
 	}
+	l = len(m.MarkerID)
+	if l > 0 {
+		n += 1 + l + sovBep(uint64(l))
+	}
+	if len(m.RootSample) > 0 {
+		for _, s := range m.RootSample {
+			l = len(s)
+			n += 1 + l + sovBep(uint64(l))
+		}
+	}
+	if m.ModTimePrecisionMs != 0 {
+		n += 1 + sovBep(uint64(m.ModTimePrecisionMs))
+	}
 	if len(m.Devices) > 0 {
 		for _, e := range m.Devices {
 			l = e.ProtoSize()
@@ -2119,6 +2306,10 @@ func (m *Index) ProtoSize() (n int) {
 			n += 1 + l + sovBep(uint64(l))
 		}
 	}
+	l = len(m.Checksum)
+	if l > 0 {
+		n += 1 + l + sovBep(uint64(l))
+	}
 	return n
 }
 
@@ -2138,6 +2329,10 @@ func (m *IndexUpdate) ProtoSize() (n int) {
 			n += 1 + l + sovBep(uint64(l))
 		}
 	}
+	l = len(m.Checksum)
+	if l > 0 {
+		n += 1 + l + sovBep(uint64(l))
+	}
 	return n
 }
 
@@ -2200,6 +2395,16 @@ func (m *FileInfo) ProtoSize() (n int) {
 	if l > 0 {
 		n += 2 + l + sovBep(uint64(l))
 	}
+	if len(m.Xattrs) > 0 {
+		for _, e := range m.Xattrs {
+			l = e.ProtoSize()
+			n += 2 + l + sovBep(uint64(l))
+		}
+	}
+	l = len(m.XattrPlatform)
+	if l > 0 {
+		n += 2 + l + sovBep(uint64(l))
+	}
 	if m.LocalFlags != 0 {
 		n += 2 + sovBep(uint64(m.LocalFlags))
 	}
@@ -2228,6 +2433,23 @@ func (m *BlockInfo) ProtoSize() (n int) {
 	return n
 }
 
+func (m *Xattr) ProtoSize() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Name)
+	if l > 0 {
+		n += 1 + l + sovBep(uint64(l))
+	}
+	l = len(m.Value)
+	if l > 0 {
+		n += 1 + l + sovBep(uint64(l))
+	}
+	return n
+}
+
 func (m *Vector) ProtoSize() (n int) {
 	if m == nil {
 		return 0
@@ -2508,6 +2730,25 @@ func (m *Hello) Unmarshal(dAtA []byte) error {
 			}
 			m.ClientVersion = string(dAtA[iNdEx:postIndex])
 			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Timestamp", wireType)
+			}
+			m.Timestamp = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Timestamp |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipBep(dAtA[iNdEx:])
@@ -2903,6 +3144,89 @@ func (m *Folder) Unmarshal(dAtA []byte) error {
 				}
 			}
 			m.Paused = bool(v != 0)
+		case 8:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field MarkerID", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.MarkerID = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 9:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field RootSample", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.RootSample = append(m.RootSample, string(dAtA[iNdEx:postIndex]))
+			iNdEx = postIndex
+		case 10:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ModTimePrecisionMs", wireType)
+			}
+			m.ModTimePrecisionMs = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ModTimePrecisionMs |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		case 16:
 			if wireType != 2 {
 				return fmt.Errorf("proto: wrong wireType = %d for field Devices", wireType)
@@ -3335,6 +3659,40 @@ func (m *Index) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Checksum", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Checksum = append(m.Checksum[:0], dAtA[iNdEx:postIndex]...)
+			if m.Checksum == nil {
+				m.Checksum = []byte{}
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipBep(dAtA[iNdEx:])
@@ -3454,6 +3812,40 @@ func (m *IndexUpdate) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Checksum", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Checksum = append(m.Checksum[:0], dAtA[iNdEx:postIndex]...)
+			if m.Checksum == nil {
+				m.Checksum = []byte{}
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipBep(dAtA[iNdEx:])
@@ -3884,6 +4276,72 @@ func (m *FileInfo) Unmarshal(dAtA []byte) error {
 				m.BlocksHash = []byte{}
 			}
 			iNdEx = postIndex
+		case 19:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Xattrs", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Xattrs = append(m.Xattrs, Xattr{})
+			if err := m.Xattrs[len(m.Xattrs)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 20:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field XattrPlatform", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.XattrPlatform = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
 		case 1000:
 			if wireType != 0 {
 				return fmt.Errorf("proto: wrong wireType = %d for field LocalFlags", wireType)
@@ -4071,6 +4529,125 @@ func (m *BlockInfo) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
+func (m *Xattr) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowBep
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Xattr: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Xattr: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Name = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Value", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowBep
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthBep
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthBep
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Value = append(m.Value[:0], dAtA[iNdEx:postIndex]...)
+			if m.Value == nil {
+				m.Value = []byte{}
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipBep(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthBep
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
 func (m *Vector) Unmarshal(dAtA []byte) error {
 	l := len(dAtA)
 	iNdEx := 0
@@ -187,6 +187,7 @@ func (f FileInfo) IsEquivalentOptional(other FileInfo, modTimeWindow time.Durati
 //  - deleted flag
 //  - invalid flag
 //  - permissions, unless they are ignored
+//  - extended attributes, when read on the same platform
 // A file is not "equivalent", if it has different
 //  - modification time (difference bigger than modTimeWindow)
 //  - size
@@ -214,6 +215,10 @@ func (f FileInfo) isEquivalent(other FileInfo, modTimeWindow time.Duration, igno
 		return false
 	}
 
+	if f.XattrPlatform != "" && f.XattrPlatform == other.XattrPlatform && !XattrsEqual(f.Xattrs, other.Xattrs) {
+		return false
+	}
+
 	switch f.Type {
 	case FileInfoTypeFile:
 		return f.Size == other.Size && ModTimeEqual(f.ModTime(), other.ModTime(), modTimeWindow) && (ignoreBlocks || BlocksEqual(f.Blocks, other.Blocks))
@@ -249,6 +254,20 @@ func PermsEqual(a, b uint32) bool {
 	}
 }
 
+// XattrsEqual returns whether two slices of extended attributes are
+// exactly the same, in the same order.
+func XattrsEqual(a, b []Xattr) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || !bytes.Equal(a[i].Value, b[i].Value) {
+			return false
+		}
+	}
+	return true
+}
+
 // BlocksEqual returns whether two slices of blocks are exactly the same hash
 // and index pair wise.
 func BlocksEqual(a, b []BlockInfo) bool {
@@ -337,3 +356,68 @@ func BlocksHash(bs []BlockInfo) []byte {
 	}
 	return h.Sum(nil)
 }
+
+// IndexChecksum returns the checksum of index messages with the files,
+// over the fields of them that are sent. Fields not known to the
+// protocol version are not covered.
+func IndexChecksum(folder string, fs []FileInfo) []byte {
+	h := sha256.New()
+	var buf [8]byte
+	putUint64 := func(v uint64) {
+		binary.BigEndian.PutUint64(buf[:], v)
+		_, _ = h.Write(buf[:])
+	}
+	putBytes := func(bs []byte) {
+		putUint64(uint64(len(bs)))
+		_, _ = h.Write(bs)
+	}
+	putBool := func(v bool) {
+		if v {
+			putUint64(1)
+		} else {
+			putUint64(0)
+		}
+	}
+
+	putBytes([]byte(folder))
+	putUint64(uint64(len(fs)))
+	for _, f := range fs {
+		putBytes([]byte(f.Name))
+		putUint64(uint64(f.Type))
+		putUint64(uint64(f.Size))
+		putUint64(uint64(f.Permissions))
+		putUint64(uint64(f.ModifiedS))
+		putUint64(uint64(f.ModifiedNs))
+		putUint64(uint64(f.ModifiedBy))
+		putBool(f.Deleted)
+		putBool(f.RawInvalid)
+		putBool(f.NoPermissions)
+		putUint64(uint64(f.Sequence))
+		putUint64(uint64(f.RawBlockSize))
+		putBytes([]byte(f.SymlinkTarget))
+		putBytes(f.BlocksHash)
+		putUint64(uint64(len(f.Version.Counters)))
+		for _, c := range f.Version.Counters {
+			putUint64(uint64(c.ID))
+			putUint64(c.Value)
+		}
+		putUint64(uint64(len(f.Blocks)))
+		for _, b := range f.Blocks {
+			putUint64(uint64(b.Offset))
+			putUint64(uint64(b.Size))
+			putUint64(uint64(b.WeakHash))
+			putBytes(b.Hash)
+		}
+		// Only when there are any, so that the checksum of the files
+		// without stays what it was before they were added.
+		if len(f.Xattrs) > 0 || f.XattrPlatform != "" {
+			putBytes([]byte(f.XattrPlatform))
+			putUint64(uint64(len(f.Xattrs)))
+			for _, x := range f.Xattrs {
+				putBytes([]byte(x.Name))
+				putBytes(x.Value)
+			}
+		}
+	}
+	return h.Sum(nil)
+}
@@ -23,6 +23,7 @@ type HelloResult struct {
 	DeviceName    string
 	ClientName    string
 	ClientVersion string
+	Timestamp     int64
 }
 
 var (
@@ -3,6 +3,7 @@
 package protocol
 
 import (
+	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/binary"
@@ -107,6 +108,7 @@ var (
 	errDeletedHasBlocks   = errors.New("deleted file with non-empty block list")
 	errDirectoryHasBlocks = errors.New("directory with non-empty block list")
 	errFileHasNoBlocks    = errors.New("file with empty block list")
+	errIndexChecksum      = errors.New("index checksum mismatch")
 )
 
 type Model interface {
@@ -257,8 +259,9 @@ func (c *rawConnection) Index(ctx context.Context, folder string, idx []FileInfo
 	}
 	c.idxMut.Lock()
 	c.send(ctx, &Index{
-		Folder: folder,
-		Files:  idx,
+		Folder:   folder,
+		Files:    idx,
+		Checksum: IndexChecksum(folder, idx),
 	}, nil)
 	c.idxMut.Unlock()
 	return nil
@@ -273,8 +276,9 @@ func (c *rawConnection) IndexUpdate(ctx context.Context, folder string, idx []Fi
 	}
 	c.idxMut.Lock()
 	c.send(ctx, &IndexUpdate{
-		Folder: folder,
-		Files:  idx,
+		Folder:   folder,
+		Files:    idx,
+		Checksum: IndexChecksum(folder, idx),
 	}, nil)
 	c.idxMut.Unlock()
 	return nil
@@ -398,6 +402,9 @@ func (c *rawConnection) dispatcherLoop() (err error) {
 			if state != stateReady {
 				return fmt.Errorf("protocol error: index message in state %d", state)
 			}
+			if err := checkIndexChecksum(msg.Folder, msg.Files, msg.Checksum); err != nil {
+				return errors.Wrap(err, "protocol error: index")
+			}
 			if err := checkIndexConsistency(msg.Files); err != nil {
 				return errors.Wrap(err, "protocol error: index")
 			}
@@ -411,6 +418,9 @@ func (c *rawConnection) dispatcherLoop() (err error) {
 			if state != stateReady {
 				return fmt.Errorf("protocol error: index update message in state %d", state)
 			}
+			if err := checkIndexChecksum(msg.Folder, msg.Files, msg.Checksum); err != nil {
+				return errors.Wrap(err, "protocol error: index update")
+			}
 			if err := checkIndexConsistency(msg.Files); err != nil {
 				return errors.Wrap(err, "protocol error: index update")
 			}
@@ -561,6 +571,20 @@ func (c *rawConnection) handleIndexUpdate(im IndexUpdate) error {
 	return c.receiver.IndexUpdate(c.id, im.Folder, im.Files)
 }
 
+// checkIndexChecksum verifies the checksum of an index message, if the
+// other device sent one. A mismatch means the message was corrupted on the
+// way, so rather than taking in wrong metadata we disconnect, to get the
+// index again when reconnecting.
+func checkIndexChecksum(folder string, fs []FileInfo, checksum []byte) error {
+	if len(checksum) == 0 {
+		return nil
+	}
+	if !bytes.Equal(IndexChecksum(folder, fs), checksum) {
+		return errIndexChecksum
+	}
+	return nil
+}
+
 // checkIndexConsistency verifies a number of invariants on FileInfos received in
 // index messages.
 func checkIndexConsistency(fs []FileInfo) error {
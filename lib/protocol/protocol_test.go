@@ -273,6 +273,15 @@ func TestMarshalIndexMessage(t *testing.T) {
 			if len(f.Version.Counters) == 0 {
 				m1.Files[i].Version.Counters = nil
 			}
+			if len(f.Xattrs) == 0 {
+				m1.Files[i].Xattrs = nil
+			} else {
+				for j := range f.Xattrs {
+					if len(f.Xattrs[j].Value) == 0 {
+						f.Xattrs[j].Value = nil
+					}
+				}
+			}
 		}
 
 		return testMarshal(t, "index", &m1, &Index{})
@@ -563,6 +572,64 @@ func TestCheckConsistency(t *testing.T) {
 	}
 }
 
+func TestIndexChecksum(t *testing.T) {
+	files := []FileInfo{
+		{
+			Name:      "foo",
+			Type:      FileInfoTypeFile,
+			Size:      1234,
+			ModifiedS: 1234567890,
+			Version:   Vector{}.Update(42),
+			Sequence:  1,
+			Blocks:    []BlockInfo{{Size: 1234, Offset: 0, Hash: []byte{1, 2, 3, 4}}},
+			Xattrs:    []Xattr{{Name: "user.foo", Value: []byte("bar")}},
+		},
+		{
+			Name:     "bar",
+			Type:     FileInfoTypeDirectory,
+			Sequence: 2,
+		},
+	}
+
+	// The checksum holds after a trip over the wire
+	msg := Index{Folder: "default", Files: files, Checksum: IndexChecksum("default", files)}
+	bs, err := msg.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rcvd Index
+	if err := rcvd.Unmarshal(bs); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkIndexChecksum(rcvd.Folder, rcvd.Files, rcvd.Checksum); err != nil {
+		t.Error("Unexpected error for unchanged index:", err)
+	}
+
+	// But not when anything changed
+	rcvd.Files[0].Blocks[0].Hash[0]++
+	if err := checkIndexChecksum(rcvd.Folder, rcvd.Files, rcvd.Checksum); err != errIndexChecksum {
+		t.Error("Expected checksum mismatch for a changed block, got", err)
+	}
+	rcvd.Files[0].Blocks[0].Hash[0]--
+	rcvd.Files[1].Deleted = true
+	if err := checkIndexChecksum(rcvd.Folder, rcvd.Files, rcvd.Checksum); err != errIndexChecksum {
+		t.Error("Expected checksum mismatch for a changed flag, got", err)
+	}
+	rcvd.Files[1].Deleted = false
+	rcvd.Files[0].Xattrs[0].Value[0]++
+	if err := checkIndexChecksum(rcvd.Folder, rcvd.Files, rcvd.Checksum); err != errIndexChecksum {
+		t.Error("Expected checksum mismatch for a changed xattr, got", err)
+	}
+	if err := checkIndexChecksum("other", files, msg.Checksum); err != errIndexChecksum {
+		t.Error("Expected checksum mismatch for another folder, got", err)
+	}
+
+	// Devices that don't send a checksum
+	if err := checkIndexChecksum(rcvd.Folder, rcvd.Files, nil); err != nil {
+		t.Error("Unexpected error without checksum:", err)
+	}
+}
+
 func TestBlockSize(t *testing.T) {
 	cases := []struct {
 		fileSize  int64
@@ -741,6 +808,26 @@ func TestIsEquivalent(t *testing.T) {
 			eq:       true,
 		},
 
+		// Difference in extended attributes read on the same platform is
+		// not OK
+		{
+			a:  FileInfo{XattrPlatform: "linux", Xattrs: []Xattr{{Name: "user.a", Value: []byte("a")}}},
+			b:  FileInfo{XattrPlatform: "linux", Xattrs: []Xattr{{Name: "user.a", Value: []byte("b")}}},
+			eq: false,
+		},
+
+		// ... but they can't be compared otherwise
+		{
+			a:  FileInfo{XattrPlatform: "linux", Xattrs: []Xattr{{Name: "user.a", Value: []byte("a")}}},
+			b:  FileInfo{XattrPlatform: "darwin"},
+			eq: true,
+		},
+		{
+			a:  FileInfo{XattrPlatform: "linux", Xattrs: []Xattr{{Name: "user.a", Value: []byte("a")}}},
+			b:  FileInfo{},
+			eq: true,
+		},
+
 		// These attributes are not checked at all
 		{
 			a:  FileInfo{NoPermissions: false},
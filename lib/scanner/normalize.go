@@ -0,0 +1,123 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package scanner
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// NormalizeOnDisk renames the item at path to normPath, the same name in
+// another normalization form, and returns the name it ended up at. When a
+// regular file with identical contents already exists at normPath the two
+// are merged by removing the item at path, and merged is true. Any other
+// item in the way is a conflict. With dryRun the outcome is determined
+// without changing anything.
+func NormalizeOnDisk(filesystem fs.Filesystem, path, normPath string, dryRun bool) (newPath string, merged bool, err error) {
+	info, err := filesystem.Lstat(path)
+	if err != nil {
+		return "", false, err
+	}
+	normInfo, err := filesystem.Lstat(normPath)
+	if fs.IsNotExist(err) {
+		// Nothing exists with the normalized filename. Good.
+		if dryRun {
+			return normPath, false, nil
+		}
+		if err = filesystem.Rename(path, normPath); err != nil {
+			return "", false, err
+		}
+		l.Infof(`Normalized UTF8 encoding of file name "%s".`, path)
+		return normPath, false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	if filesystem.SameFile(info, normInfo) {
+		// With some filesystems (ZFS), if there is an un-normalized path and you ask whether the normalized
+		// version exists, it responds with true. Therefore we need to check fs.SameFile as well.
+		// In this case, a call to Rename won't do anything, so we have to rename via a temp file.
+
+		// We don't want to use the standard syncthing prefix here, as that will result in the file being ignored
+		// and eventually deleted by Syncthing if the rename back fails.
+
+		if dryRun {
+			return normPath, false, nil
+		}
+		tempPath := fs.TempNameWithPrefix(normPath, "")
+		if err = filesystem.Rename(path, tempPath); err != nil {
+			return "", false, err
+		}
+		if err = filesystem.Rename(tempPath, normPath); err != nil {
+			// I don't ever expect this to happen, but if it does, we should probably tell our caller that the normalized
+			// path is the temp path: that way at least the user's data still gets synced.
+			l.Warnf(`Error renaming "%s" to "%s" while normalizating UTF8 encoding: %v. You will want to rename this file back manually`, tempPath, normPath, err)
+			return tempPath, false, nil
+		}
+		return normPath, false, nil
+	}
+	// There is something already in the way at the normalized file name.
+	// If it's a copy of the same file the two are duplicates that only
+	// look different, and the un-normalized one can go.
+	if !info.IsRegular() || !normInfo.IsRegular() || info.Size() != normInfo.Size() {
+		return "", false, errUTF8Conflict
+	}
+	if same, err := sameContents(filesystem, path, normPath); err != nil {
+		return "", false, err
+	} else if !same {
+		return "", false, errUTF8Conflict
+	}
+	if dryRun {
+		return normPath, true, nil
+	}
+	if err := filesystem.Remove(path); err != nil {
+		return "", false, err
+	}
+	l.Infof(`Merged file "%s" into identical "%s" with normalized UTF8 encoding.`, path, normPath)
+	return normPath, true, nil
+}
+
+// IsUTF8Conflict returns true if the error is the result of NormalizeOnDisk
+// finding a different item at the normalized name.
+func IsUTF8Conflict(err error) bool {
+	return err == errUTF8Conflict
+}
+
+func sameContents(filesystem fs.Filesystem, a, b string) (bool, error) {
+	fa, err := filesystem.Open(a)
+	if err != nil {
+		return false, err
+	}
+	defer fa.Close()
+	fb, err := filesystem.Open(b)
+	if err != nil {
+		return false, err
+	}
+	defer fb.Close()
+
+	bufA := make([]byte, protocol.MinBlockSize)
+	bufB := make([]byte, protocol.MinBlockSize)
+	for {
+		nA, errA := io.ReadFull(fa, bufA)
+		nB, errB := io.ReadFull(fb, bufB)
+		if !bytes.Equal(bufA[:nA], bufB[:nB]) {
+			return false, nil
+		}
+		if errA == io.EOF || errA == io.ErrUnexpectedEOF {
+			return errB == io.EOF || errB == io.ErrUnexpectedEOF, nil
+		}
+		if errA != nil {
+			return false, errA
+		}
+		if errB != nil {
+			return false, errB
+		}
+	}
+}
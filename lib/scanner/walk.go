@@ -12,18 +12,19 @@ import (
 	"fmt"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"sync/atomic"
 	"time"
 	"unicode/utf8"
 
 	metrics "github.com/rcrowley/go-metrics"
+	"github.com/syncthing/syncthing/lib/config"
 	"github.com/syncthing/syncthing/lib/events"
 	"github.com/syncthing/syncthing/lib/fs"
 	"github.com/syncthing/syncthing/lib/ignore"
 	"github.com/syncthing/syncthing/lib/osutil"
 	"github.com/syncthing/syncthing/lib/protocol"
-	"golang.org/x/text/unicode/norm"
 )
 
 type Config struct {
@@ -35,6 +36,9 @@ type Config struct {
 	Matcher *ignore.Matcher
 	// Number of hours to keep temporary files for
 	TempLifetime time.Duration
+	// If KeepTemporaries is true, temporary files are kept regardless of
+	// their age, as they are yet to be pulled into.
+	KeepTemporaries bool
 	// If CurrentFiler is not nil, it is queried for the current file before rescanning.
 	CurrentFiler CurrentFiler
 	// The Filesystem provides an abstraction on top of the actual filesystem.
@@ -46,6 +50,8 @@ type Config struct {
 	// When AutoNormalize is set, file names that are in UTF8 but incorrect
 	// normalization form will be corrected.
 	AutoNormalize bool
+	// The normalization form file names are expected in
+	UnicodeNormalization config.UnicodeNormalization
 	// Number of routines to use for hashing
 	Hashers int
 	// Our vector clock id
@@ -59,6 +65,11 @@ type Config struct {
 	ModTimeWindow time.Duration
 	// Event logger to which the scan progress events are sent
 	EventLogger events.Logger
+	// If ScanXattrs is true, the extended attributes of files and
+	// directories that the XattrFilter permits are read. Otherwise they
+	// are kept as they were.
+	ScanXattrs  bool
+	XattrFilter fs.XattrFilter
 }
 
 type CurrentFiler interface {
@@ -229,7 +240,7 @@ func (w *walker) walkAndHashFiles(ctx context.Context, toHashChan chan<- protoco
 
 		if fs.IsTemporary(path) {
 			l.Debugln("temporary:", path, "err:", err)
-			if err == nil && info.IsRegular() && info.ModTime().Add(w.TempLifetime).Before(now) {
+			if err == nil && !w.KeepTemporaries && info.IsRegular() && info.ModTime().Add(w.TempLifetime).Before(now) {
 				w.Filesystem.Remove(path)
 				l.Debugln("removing temporary:", path, info.ModTime())
 			}
@@ -241,7 +252,11 @@ func (w *walker) walkAndHashFiles(ctx context.Context, toHashChan chan<- protoco
 			return skip
 		}
 
-		if w.Matcher.Match(path).IsIgnored() {
+		var st *ignore.Stat
+		if err == nil {
+			st = ignore.StatOf(info)
+		}
+		if w.Matcher.MatchStat(path, st).IsIgnored() {
 			l.Debugln("ignored (patterns):", path)
 			// Only descend if matcher says so and the current file is not a symlink.
 			if err != nil || w.Matcher.SkipIgnoredDirs() || info.IsSymlink() {
@@ -303,11 +318,14 @@ func (w *walker) handleItem(ctx context.Context, path string, toHashChan chan<-
 	}
 
 	oldPath := path
-	path, err = w.normalizePath(path, info)
+	path, err = w.normalizePath(path)
 	if err != nil {
 		w.handleError(ctx, "normalizing path", oldPath, err, finishedChan)
 		return skip
 	}
+	if path == "" {
+		return skip
+	}
 
 	switch {
 	case info.IsSymlink():
@@ -353,9 +371,10 @@ func (w *walker) walkRegular(ctx context.Context, relPath string, info fs.FileIn
 	f = w.updateFileInfo(f, curFile)
 	f.NoPermissions = w.IgnorePerms
 	f.RawBlockSize = int32(blockSize)
+	f, xattrsRead := w.updateXattrs(f, curFile)
 
 	if hasCurFile {
-		if curFile.IsEquivalentOptional(f, w.ModTimeWindow, w.IgnorePerms, true, w.LocalFlags) {
+		if !xattrsRead && curFile.IsEquivalentOptional(f, w.ModTimeWindow, w.IgnorePerms, true, w.LocalFlags) {
 			return nil
 		}
 		if curFile.ShouldConflict() {
@@ -386,9 +405,10 @@ func (w *walker) walkDir(ctx context.Context, relPath string, info fs.FileInfo,
 	f, _ := CreateFileInfo(info, relPath, nil)
 	f = w.updateFileInfo(f, curFile)
 	f.NoPermissions = w.IgnorePerms
+	f, xattrsRead := w.updateXattrs(f, curFile)
 
 	if hasCurFile {
-		if curFile.IsEquivalentOptional(f, w.ModTimeWindow, w.IgnorePerms, true, w.LocalFlags) {
+		if !xattrsRead && curFile.IsEquivalentOptional(f, w.ModTimeWindow, w.IgnorePerms, true, w.LocalFlags) {
 			return nil
 		}
 		if curFile.ShouldConflict() {
@@ -457,17 +477,10 @@ func (w *walker) walkSymlink(ctx context.Context, relPath string, info fs.FileIn
 }
 
 // normalizePath returns the normalized relative path (possibly after fixing
-// it on disk), or skip is true.
-func (w *walker) normalizePath(path string, info fs.FileInfo) (normPath string, err error) {
-	if runtime.GOOS == "darwin" {
-		// Mac OS X file names should always be NFD normalized.
-		normPath = norm.NFD.String(path)
-	} else {
-		// Every other OS in the known universe uses NFC or just plain
-		// doesn't bother to define an encoding. In our case *we* do care,
-		// so we enforce NFC regardless.
-		normPath = norm.NFC.String(path)
-	}
+// it on disk), or an empty path if the item was merged into an identical
+// one already at the normalized path.
+func (w *walker) normalizePath(path string) (normPath string, err error) {
+	normPath = w.UnicodeNormalization.Form().String(path)
 
 	if path == normPath {
 		// The file name is already normalized: nothing to do
@@ -481,38 +494,15 @@ func (w *walker) normalizePath(path string, info fs.FileInfo) (normPath string,
 	}
 
 	// We will attempt to normalize it.
-	normInfo, err := w.Filesystem.Lstat(normPath)
-	if fs.IsNotExist(err) {
-		// Nothing exists with the normalized filename. Good.
-		if err = w.Filesystem.Rename(path, normPath); err != nil {
-			return "", err
-		}
-		l.Infof(`Normalized UTF8 encoding of file name "%s".`, path)
-		return normPath, nil
+	normPath, merged, err := NormalizeOnDisk(w.Filesystem, path, normPath, false)
+	if err != nil {
+		return "", err
 	}
-	if w.Filesystem.SameFile(info, normInfo) {
-		// With some filesystems (ZFS), if there is an un-normalized path and you ask whether the normalized
-		// version exists, it responds with true. Therefore we need to check fs.SameFile as well.
-		// In this case, a call to Rename won't do anything, so we have to rename via a temp file.
-
-		// We don't want to use the standard syncthing prefix here, as that will result in the file being ignored
-		// and eventually deleted by Syncthing if the rename back fails.
-
-		tempPath := fs.TempNameWithPrefix(normPath, "")
-		if err = w.Filesystem.Rename(path, tempPath); err != nil {
-			return "", err
-		}
-		if err = w.Filesystem.Rename(tempPath, normPath); err != nil {
-			// I don't ever expect this to happen, but if it does, we should probably tell our caller that the normalized
-			// path is the temp path: that way at least the user's data still gets synced.
-			l.Warnf(`Error renaming "%s" to "%s" while normalizating UTF8 encoding: %v. You will want to rename this file back manually`, tempPath, normPath, err)
-			return tempPath, nil
-		}
-		return normPath, nil
+	if merged {
+		// The item at the normalized path is walked on its own.
+		return "", nil
 	}
-	// There is something already in the way at the normalized
-	// file name.
-	return "", errUTF8Conflict
+	return normPath, nil
 }
 
 // updateFileInfo updates walker specific members of protocol.FileInfo that do not depend on type
@@ -528,6 +518,48 @@ func (w *walker) updateFileInfo(file, curFile protocol.FileInfo) protocol.FileIn
 	return file
 }
 
+// updateXattrs sets the extended attributes of the file, as read when
+// they are scanned and as they were otherwise. Attributes the filter
+// doesn't permit are kept as they were too, as are those of another
+// platform when there are none here, as they can't have been applied. It
+// also returns whether attributes were read where there were none, or
+// those of another platform, which isEquivalent doesn't tell.
+func (w *walker) updateXattrs(file, curFile protocol.FileInfo) (protocol.FileInfo, bool) {
+	file.Xattrs, file.XattrPlatform = curFile.Xattrs, curFile.XattrPlatform
+	if !w.ScanXattrs {
+		return file, false
+	}
+
+	read, err := w.Filesystem.GetXattr(file.Name, w.XattrFilter)
+	if err != nil {
+		l.Debugf("Reading extended attributes of %v: %v", file.Name, err)
+		return file, false
+	}
+	xattrs := make([]protocol.Xattr, len(read))
+	for i, xattr := range read {
+		xattrs[i] = protocol.Xattr{Name: xattr.Name, Value: xattr.Value}
+	}
+
+	if curFile.XattrPlatform != runtime.GOOS {
+		if len(xattrs) == 0 {
+			return file, false
+		}
+		file.Xattrs, file.XattrPlatform = xattrs, runtime.GOOS
+		return file, true
+	}
+
+	for _, xattr := range curFile.Xattrs {
+		if w.XattrFilter != nil && !w.XattrFilter.Permit(xattr.Name) {
+			xattrs = append(xattrs, xattr)
+		}
+	}
+	sort.Slice(xattrs, func(a, b int) bool {
+		return xattrs[a].Name < xattrs[b].Name
+	})
+	file.Xattrs = xattrs
+	return file, false
+}
+
 func (w *walker) handleError(ctx context.Context, context, path string, err error, finishedChan chan<- ScanResult) {
 	// Ignore missing items, as deletions are not handled by the scanner.
 	if fs.IsNotExist(err) {
@@ -18,6 +18,7 @@ import (
 	"runtime"
 	rdebug "runtime/debug"
 	"sort"
+	"strings"
 	"sync"
 	"testing"
 
@@ -247,6 +248,46 @@ func TestNormalization(t *testing.T) {
 	}
 }
 
+func TestNormalizationMerge(t *testing.T) {
+	if runtime.GOOS == "darwin" {
+		t.Skip("Normalization test not possible on darwin")
+		return
+	}
+
+	os.RemoveAll("testdata/normalization-merge")
+	defer os.RemoveAll("testdata/normalization-merge")
+
+	files := map[string]string{
+		"1-\xC3\x84":     "same",      // NFC 'Ä'
+		"1-\x41\xCC\x88": "same",      // NFD 'Ä' -- identical to the above, merged
+		"2-\xC3\x85":     "different", // NFC 'Å'
+		"2-\x41\xCC\x8A": "other",     // NFD 'Å' -- conflicts with the above, ignored
+	}
+	if err := testFs.MkdirAll("normalization-merge", 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		fd, err := testFs.OpenFile(filepath.Join("normalization-merge", name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fd.Write([]byte(content))
+		fd.Close()
+	}
+
+	tmp := walkDir(testFs, "normalization-merge", nil, nil, 0)
+	if len(tmp) != 3 {
+		t.Fatalf("Expected 3 items, got %d: %v", len(tmp), tmp)
+	}
+
+	if _, err := testFs.Lstat(filepath.Join("normalization-merge", "1-\x41\xCC\x88")); !fs.IsNotExist(err) {
+		t.Error("Identical NFD duplicate should have been removed, got", err)
+	}
+	if _, err := testFs.Lstat(filepath.Join("normalization-merge", "2-\x41\xCC\x8A")); err != nil {
+		t.Error("Conflicting NFD duplicate should have been kept, got", err)
+	}
+}
+
 func TestIssue1507(t *testing.T) {
 	w := &walker{}
 	w.Matcher = ignore.New(w.Filesystem)
@@ -482,6 +523,86 @@ func TestWalkReceiveOnly(t *testing.T) {
 	}
 }
 
+// xattrFS gives the file of a singleFileFS extended attributes.
+type xattrFS struct {
+	singleFileFS
+	xattrs []fs.Xattr
+}
+
+func (s *xattrFS) GetXattr(name string, filter fs.XattrFilter) ([]fs.Xattr, error) {
+	var xattrs []fs.Xattr
+	for _, xattr := range s.xattrs {
+		if filter == nil || filter.Permit(xattr.Name) {
+			xattrs = append(xattrs, xattr)
+		}
+	}
+	return xattrs, nil
+}
+
+type prefixXattrFilter string
+
+func (p prefixXattrFilter) Permit(name string) bool {
+	return strings.HasPrefix(name, string(p))
+}
+
+func TestWalkXattrs(t *testing.T) {
+	xfs := &xattrFS{singleFileFS: singleFileFS{name: "testfile.dat", filesize: 1024}}
+	sf := fs.NewWalkFilesystem(xfs)
+	current := make(fakeCurrentFiler)
+
+	walk := func() []protocol.FileInfo {
+		t.Helper()
+		cfg := testConfig()
+		cfg.Filesystem = sf
+		cfg.CurrentFiler = current
+		cfg.ScanXattrs = true
+		cfg.XattrFilter = prefixXattrFilter("user.")
+		var files []protocol.FileInfo
+		for res := range Walk(context.TODO(), cfg) {
+			if res.Err != nil {
+				t.Fatal(res.Err)
+			}
+			files = append(files, res.File)
+		}
+		return files
+	}
+
+	// Nothing read where there is nothing, so that what came from
+	// another platform is kept.
+	other := protocol.FileInfo{Name: "testfile.dat", Size: 1024, XattrPlatform: "other", Xattrs: []protocol.Xattr{{Name: "user.other"}}}
+	current[other.Name] = other
+	files := walk()
+	if len(files) != 1 || files[0].XattrPlatform != "other" {
+		t.Fatalf("Expected the attributes of the other platform to be kept, got %v", files)
+	}
+
+	// Attributes read replace those, and the ones not permitted are
+	// kept from before.
+	xfs.xattrs = []fs.Xattr{{Name: "user.b", Value: []byte("b")}, {Name: "security.a", Value: []byte("a")}}
+	cur := files[0]
+	cur.Xattrs = nil
+	current[cur.Name] = cur
+	if files := walk(); len(files) != 1 || files[0].XattrPlatform != runtime.GOOS || len(files[0].Xattrs) != 1 {
+		t.Fatalf("Expected the attributes to be read, got %v", files)
+	}
+	cur.XattrPlatform = runtime.GOOS
+	cur.Xattrs = []protocol.Xattr{{Name: "trusted.c", Value: []byte("c")}, {Name: "user.b", Value: []byte("b")}}
+	current[cur.Name] = cur
+	if files := walk(); len(files) != 0 {
+		t.Fatalf("Expected nothing to have changed, got %v", files)
+	}
+
+	xfs.xattrs[0].Value = []byte("changed")
+	files = walk()
+	if len(files) != 1 {
+		t.Fatal("Expected the changed attribute to be picked up")
+	}
+	expected := []protocol.Xattr{{Name: "trusted.c", Value: []byte("c")}, {Name: "user.b", Value: []byte("changed")}}
+	if !protocol.XattrsEqual(files[0].Xattrs, expected) {
+		t.Errorf("Unexpected attributes %v, expected %v", files[0].Xattrs, expected)
+	}
+}
+
 func walkDir(fs fs.Filesystem, dir string, cfiler CurrentFiler, matcher *ignore.Matcher, localFlags uint32) []protocol.FileInfo {
 	cfg := testConfig()
 	cfg.Filesystem = fs
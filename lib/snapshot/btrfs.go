@@ -0,0 +1,55 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package snapshot
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+)
+
+// BtrfsSnapshotDir is where in a subvolume the snapshots of it are taken.
+// The folder skips it as an internal file when it is the subvolume.
+const BtrfsSnapshotDir = ".stsnapshots"
+
+// Btrfs takes a read only snapshot of the subvolume the folder is on, in
+// the BtrfsSnapshotDir of the subvolume.
+var Btrfs Method = btrfs{}
+
+type btrfs struct{}
+
+func (btrfs) Take(ctx context.Context, folderID, path string) (*Snapshot, error) {
+	path, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, err
+	}
+	root, err := btrfsSubvolume(path)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Join(root, BtrfsSnapshotDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	snapshot := filepath.Join(dir, snapshotName(folderID))
+	if _, err := run(ctx, "btrfs", "subvolume", "snapshot", "-r", root, snapshot); err != nil {
+		return nil, err
+	}
+	return &Snapshot{
+		Path: filepath.Join(snapshot, rel),
+		release: func() error {
+			// Not the scan context, which may be cancelled already.
+			_, err := run(context.Background(), "btrfs", "subvolume", "delete", snapshot)
+			return err
+		},
+	}, nil
+}
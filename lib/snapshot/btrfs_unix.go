@@ -0,0 +1,37 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build !windows
+
+package snapshot
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// The root directory of a Btrfs subvolume always has this inode number.
+const btrfsSubvolumeIno = 256
+
+// btrfsSubvolume returns the root of the subvolume the path is in.
+func btrfsSubvolume(path string) (string, error) {
+	for {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+		if stat, ok := info.Sys().(*syscall.Stat_t); ok && stat.Ino == btrfsSubvolumeIno {
+			return path, nil
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", fmt.Errorf("no Btrfs subvolume for %s", path)
+		}
+		path = parent
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+)
+
+// command snapshots with whatever the take command does, for other
+// filesystems and volume managers. The command is given the folder by the
+// %FOLDER_ID% and %FOLDER_PATH% parameters and prints the path of the
+// folder in the snapshot as the last line of its output. The release
+// command, if any, gets the same parameters and %SNAPSHOT_PATH%.
+type command struct {
+	take    string
+	release string
+}
+
+// NewCommand returns a Method running the given commands.
+func NewCommand(take, release string) Method {
+	return command{take: take, release: release}
+}
+
+func (c command) Take(ctx context.Context, folderID, path string) (*Snapshot, error) {
+	params := map[string]string{
+		"%FOLDER_ID%":   folderID,
+		"%FOLDER_PATH%": path,
+	}
+	out, err := runCommand(ctx, c.take, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshotPath string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			snapshotPath = line
+		}
+	}
+	if !filepath.IsAbs(snapshotPath) {
+		return nil, fmt.Errorf("snapshot command printed no absolute path: %q", snapshotPath)
+	}
+
+	snap := &Snapshot{Path: snapshotPath}
+	if c.release != "" {
+		params["%SNAPSHOT_PATH%"] = snapshotPath
+		snap.release = func() error {
+			// Not the scan context, which may be cancelled already.
+			_, err := runCommand(context.Background(), c.release, params)
+			return err
+		}
+	}
+	return snap, nil
+}
+
+func runCommand(ctx context.Context, cmd string, params map[string]string) ([]byte, error) {
+	if cmd == "" {
+		return nil, errors.New("snapshot command is empty")
+	}
+	words, err := shellquote.Split(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot command is invalid: %v", err)
+	}
+	for i, word := range words {
+		for key, val := range params {
+			word = strings.Replace(word, key, val, -1)
+		}
+		words[i] = word
+	}
+	return run(ctx, words[0], words[1:]...)
+}
@@ -0,0 +1,67 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package snapshot
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the commands need a shell")
+	}
+
+	dir, err := ioutil.TempDir("", "syncthing-snapshot-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	folder := filepath.Join(dir, "folder")
+	if err := os.Mkdir(folder, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(folder, "file"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	method := NewCommand(
+		`sh -c 'echo taking %FOLDER_ID%; cp -R "$0" "$0.snap" && echo "$0.snap"' %FOLDER_PATH%`,
+		`rm -r %SNAPSHOT_PATH%`,
+	)
+	snap, err := method.Take(context.Background(), "default", folder)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Path != folder+".snap" {
+		t.Errorf("Unexpected snapshot path %q", snap.Path)
+	}
+	if data, err := ioutil.ReadFile(filepath.Join(snap.Path, "file")); err != nil || string(data) != "data" {
+		t.Errorf("Unexpected file in the snapshot: %q, %v", data, err)
+	}
+
+	if err := snap.Release(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(snap.Path); !os.IsNotExist(err) {
+		t.Errorf("Expected the snapshot to be removed, got %v", err)
+	}
+	if err := snap.Release(); err != nil {
+		t.Error("Unexpected error releasing again:", err)
+	}
+
+	if _, err := NewCommand("false", "").Take(context.Background(), "default", folder); err == nil {
+		t.Error("Expected a failing command to fail")
+	}
+	if _, err := NewCommand("echo relative", "").Take(context.Background(), "default", folder); err == nil {
+		t.Error("Expected a relative path to fail")
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// Package snapshot takes read only snapshots of folders, to be scanned
+// while the folders themselves keep changing.
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"time"
+)
+
+// A Snapshot is a read only copy of a folder at the time it was taken.
+type Snapshot struct {
+	Path    string // where the folder is in the snapshot
+	release func() error
+}
+
+// Release removes the snapshot. It is safe to call more than once.
+func (s *Snapshot) Release() error {
+	if s.release == nil {
+		return nil
+	}
+	release := s.release
+	s.release = nil
+	return release()
+}
+
+// A Method takes snapshots of folders.
+type Method interface {
+	Take(ctx context.Context, folderID, path string) (*Snapshot, error)
+}
+
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// snapshotName is a name for a new snapshot of the folder, that is valid
+// for both ZFS and Btrfs.
+func snapshotName(folderID string) string {
+	return fmt.Sprintf("syncthing-%s-%s", unsafeNameChars.ReplaceAllString(folderID, "_"), time.Now().UTC().Format("20060102-150405.000"))
+}
+
+// run runs the command, returning its standard output. The error contains
+// what the command said on standard error.
+func run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	l.Debugln("running", name, args)
+	out, err := exec.CommandContext(ctx, name, args...).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return nil, fmt.Errorf("%s: %v: %s", name, err, bytes.TrimSpace(exitErr.Stderr))
+		}
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return out, nil
+}
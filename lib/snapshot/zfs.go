@@ -0,0 +1,54 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// ZFS snapshots the dataset the folder is on, finding the folder in the
+// .zfs/snapshot directory of the dataset.
+var ZFS Method = zfs{}
+
+type zfs struct{}
+
+func (zfs) Take(ctx context.Context, folderID, path string) (*Snapshot, error) {
+	path, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, err
+	}
+	out, err := run(ctx, "zfs", "list", "-H", "-o", "name,mountpoint", path)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Split(strings.TrimSpace(string(out)), "\t")
+	if len(fields) != 2 || !filepath.IsAbs(fields[1]) {
+		return nil, fmt.Errorf("no mounted ZFS dataset for %s", path)
+	}
+	dataset, mountpoint := fields[0], fields[1]
+	rel, err := filepath.Rel(mountpoint, path)
+	if err != nil {
+		return nil, err
+	}
+
+	name := snapshotName(folderID)
+	snapshot := dataset + "@" + name
+	if _, err := run(ctx, "zfs", "snapshot", snapshot); err != nil {
+		return nil, err
+	}
+	return &Snapshot{
+		Path: filepath.Join(mountpoint, ".zfs", "snapshot", name, rel),
+		release: func() error {
+			// Not the scan context, which may be cancelled already.
+			_, err := run(context.Background(), "zfs", "destroy", snapshot)
+			return err
+		},
+	}, nil
+}
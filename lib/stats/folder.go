@@ -7,6 +7,7 @@
 package stats
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/syncthing/syncthing/lib/db"
@@ -17,6 +18,38 @@ type FolderStatistics struct {
 	LastScan time.Time `json:"lastScan"`
 }
 
+// A ScanRecord describes a completed scan of a folder: how long it took
+// and how many files it hashed, new or changed since the previous scan.
+type ScanRecord struct {
+	Started        time.Time `json:"started"`
+	Duration       float64   `json:"duration"` // seconds
+	Partial        bool      `json:"partial"`  // only some subdirectories were scanned
+	HashedFiles    int       `json:"hashedFiles"`
+	HashedBytes    int64     `json:"hashedBytes"`
+	FilesPerSecond float64   `json:"filesPerSecond"`
+	HashedMBps     float64   `json:"hashedMBps"`
+}
+
+// NewScanRecord returns the record of a scan started at the given time,
+// completing now.
+func NewScanRecord(started time.Time, partial bool, files int, bytes int64) ScanRecord {
+	rec := ScanRecord{
+		Started:     started,
+		Duration:    time.Since(started).Seconds(),
+		Partial:     partial,
+		HashedFiles: files,
+		HashedBytes: bytes,
+	}
+	if rec.Duration > 0 {
+		rec.FilesPerSecond = float64(files) / rec.Duration
+		rec.HashedMBps = float64(bytes) / (1 << 20) / rec.Duration
+	}
+	return rec
+}
+
+// The number of scans kept in the history
+const scanHistoryLength = 50
+
 type FolderStatisticsReference struct {
 	ns     *db.NamespacedKV
 	folder string
@@ -73,8 +106,36 @@ func (s *FolderStatisticsReference) ReceivedFile(file string, deleted bool) erro
 	return nil
 }
 
-func (s *FolderStatisticsReference) ScanCompleted() error {
-	return s.ns.PutTime("lastScan", time.Now())
+func (s *FolderStatisticsReference) ScanCompleted(rec ScanRecord) error {
+	if err := s.ns.PutTime("lastScan", time.Now()); err != nil {
+		return err
+	}
+	history, err := s.GetScanHistory()
+	if err != nil {
+		return err
+	}
+	history = append(history, rec)
+	if len(history) > scanHistoryLength {
+		history = history[len(history)-scanHistoryLength:]
+	}
+	bs, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+	return s.ns.PutBytes("scanHistory", bs)
+}
+
+// GetScanHistory returns the records of the latest scans, oldest first.
+func (s *FolderStatisticsReference) GetScanHistory() ([]ScanRecord, error) {
+	bs, ok, err := s.ns.Bytes("scanHistory")
+	if err != nil || !ok {
+		return nil, err
+	}
+	var history []ScanRecord
+	if err := json.Unmarshal(bs, &history); err != nil {
+		return nil, err
+	}
+	return history, nil
 }
 
 func (s *FolderStatisticsReference) GetLastScanTime() (time.Time, error) {
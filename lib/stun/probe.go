@@ -0,0 +1,146 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package stun
+
+import (
+	"bytes"
+	"net"
+	"time"
+
+	"github.com/ccding/go-stun/stun"
+
+	"github.com/syncthing/syncthing/lib/rand"
+)
+
+const (
+	// Enough servers to tell whether they all see the same mapping.
+	probeServers   = 2
+	hairpinTimeout = 2 * time.Second
+)
+
+// ProbeResult is what a NAT probe found out about the way to the
+// internet, to explain why direct connections do or don't work.
+type ProbeResult struct {
+	When            time.Time `json:"when"`
+	NATType         string    `json:"natType"`
+	Punchable       bool      `json:"punchable"`
+	ExternalAddress string    `json:"externalAddress,omitempty"`
+	// Whether packets to our own external address come back to us, which
+	// other devices behind the same NAT need to reach us that way.
+	Hairpinning bool `json:"hairpinning"`
+	// Whether all servers saw the same external address, as they should
+	// with anything but a symmetric NAT. Unknown unless at least two
+	// servers answered.
+	ConsistentMapping *bool         `json:"consistentMapping"`
+	Servers           []ServerProbe `json:"servers"`
+	Error             string        `json:"error,omitempty"`
+}
+
+// ServerProbe is what one STUN server answered.
+type ServerProbe struct {
+	Server          string `json:"server"`
+	NATType         string `json:"natType,omitempty"`
+	ExternalAddress string `json:"externalAddress,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+// Probe classifies the NAT with the help of the given STUN servers, from a
+// UDP socket of its own. Servers are tried in order until enough of them
+// answered. It takes a while, as every test waits for its answers.
+func Probe(servers []string) ProbeResult {
+	res := ProbeResult{
+		When:    time.Now().Truncate(time.Second),
+		NATType: NATUnknown.String(),
+	}
+
+	conn, err := net.ListenUDP("udp", nil)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	defer conn.Close()
+	client := stun.NewClientWithConnection(conn)
+	client.SetSoftwareName("") // Explicitly unset this, seems to freak some servers out.
+
+	var extAddr *Host
+	answered := 0
+	for _, server := range servers {
+		if answered == probeServers {
+			break
+		}
+		sp := ServerProbe{Server: server}
+		udpAddr, err := net.ResolveUDPAddr("udp", server)
+		if err != nil {
+			sp.Error = err.Error()
+			res.Servers = append(res.Servers, sp)
+			continue
+		}
+		client.SetServerAddr(udpAddr.String())
+		natType, addr, err := client.Discover()
+		sp.NATType = natType.String()
+		switch {
+		case err != nil:
+			sp.Error = err.Error()
+		case addr == nil || natType == NATError || natType == NATUnknown || natType == NATBlocked:
+			// The server is most likely borked
+		default:
+			sp.ExternalAddress = addr.TransportAddr()
+			if answered == 0 {
+				extAddr = addr
+				res.NATType = natType.String()
+				res.Punchable = punchable(natType)
+				res.ExternalAddress = addr.TransportAddr()
+			} else {
+				consistent := res.ConsistentMapping == nil || *res.ConsistentMapping
+				consistent = consistent && addr.TransportAddr() == extAddr.TransportAddr()
+				res.ConsistentMapping = &consistent
+			}
+			answered++
+		}
+		res.Servers = append(res.Servers, sp)
+	}
+
+	if extAddr == nil {
+		res.Error = "no STUN server could tell the NAT type"
+		return res
+	}
+	res.Hairpinning = hairpins(conn, extAddr)
+	return res
+}
+
+// hairpins returns whether a packet sent to our external address comes
+// back to us.
+func hairpins(conn net.PacketConn, extAddr *Host) bool {
+	to, err := net.ResolveUDPAddr("udp", extAddr.TransportAddr())
+	if err != nil {
+		return false
+	}
+	token := []byte("syncthing-hairpin-" + rand.String(16))
+	if _, err := conn.WriteTo(token, to); err != nil {
+		l.Debugln("Hairpinning probe:", err)
+		return false
+	}
+
+	buf := make([]byte, 1500)
+	deadline := time.Now().Add(hairpinTimeout)
+	_ = conn.SetReadDeadline(deadline)
+	for time.Now().Before(deadline) {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			return false
+		}
+		// Anything else is a late STUN answer
+		if bytes.Equal(buf[:n], token) {
+			return true
+		}
+	}
+	return false
+}
+
+func punchable(natType NATType) bool {
+	return natType == NATNone || natType == NATPortRestricted || natType == NATRestricted || natType == NATFull || natType == NATSymmetricUDPFirewall
+}
@@ -140,6 +140,21 @@ func (a *App) startup() error {
 	defaultSub := events.NewBufferedSubscription(a.evLogger.Subscribe(api.DefaultEventMask), api.EventSubBufferSize)
 	diskSub := events.NewBufferedSubscription(a.evLogger.Subscribe(api.DiskEventMask), api.EventSubBufferSize)
 
+	// The persisted event log lets API clients catch up on what happened
+	// while they, or we, were not running. Events from when it was enabled
+	// before aren't kept around once it's disabled.
+	var persistentSub events.BufferedSubscription
+	if opts := a.cfg.Options(); !opts.PersistentEventsEnabled {
+		if err := db.DropEventLog(a.ll); err != nil {
+			l.Warnln("Removing persistent event log:", err)
+		}
+	} else if eventLog, err := db.NewEventLog(a.ll, a.evLogger.Subscribe(persistentEventMask(opts.PersistentEventTypes)), opts.PersistentEventsMax, time.Duration(opts.PersistentEventsMaxAgeH)*time.Hour); err != nil {
+		l.Warnln("Opening persistent event log:", err)
+	} else {
+		a.mainService.Add(eventLog)
+		persistentSub = eventLog
+	}
+
 	// Attempt to increase the limit on number of open files to the maximum
 	// allowed, in case we have many peers. We don't really care enough to
 	// report the error if there is one.
@@ -282,16 +297,20 @@ func (a *App) startup() error {
 		}
 	}
 
-	if a.cfg.Options().LocalAnnEnabled {
+	if opts := a.cfg.Options(); opts.LocalAnnEnabled {
+		localOpts := discover.LocalOptions{
+			Interfaces: opts.LocalAnnInterfaces,
+			Unicast:    opts.LocalAnnUnicastHosts,
+		}
 		// v4 broadcasts
-		bcd, err := discover.NewLocal(a.myID, fmt.Sprintf(":%d", a.cfg.Options().LocalAnnPort), connectionsService, a.evLogger)
+		bcd, err := discover.NewLocal(a.myID, fmt.Sprintf(":%d", opts.LocalAnnPort), connectionsService, localOpts, a.evLogger)
 		if err != nil {
 			l.Warnln("IPv4 local discovery:", err)
 		} else {
 			cachedDiscovery.Add(bcd, 0, 0)
 		}
 		// v6 multicasts
-		mcd, err := discover.NewLocal(a.myID, a.cfg.Options().LocalAnnMCAddr, connectionsService, a.evLogger)
+		mcd, err := discover.NewLocal(a.myID, opts.LocalAnnMCAddr, connectionsService, localOpts, a.evLogger)
 		if err != nil {
 			l.Warnln("IPv6 local discovery:", err)
 		} else {
@@ -323,7 +342,7 @@ func (a *App) startup() error {
 
 	// GUI
 
-	if err := a.setupGUI(m, defaultSub, diskSub, cachedDiscovery, connectionsService, usageReportingSvc, errors, systemLog); err != nil {
+	if err := a.setupGUI(m, defaultSub, diskSub, persistentSub, cachedDiscovery, connectionsService, usageReportingSvc, errors, systemLog); err != nil {
 		l.Warnln("Failed starting API:", err)
 		return err
 	}
@@ -408,7 +427,7 @@ func (a *App) stopWithErr(stopReason ExitStatus, err error) ExitStatus {
 	return a.exitStatus
 }
 
-func (a *App) setupGUI(m model.Model, defaultSub, diskSub events.BufferedSubscription, discoverer discover.CachingMux, connectionsService connections.Service, urService *ur.Service, errors, systemLog logger.Recorder) error {
+func (a *App) setupGUI(m model.Model, defaultSub, diskSub, persistentSub events.BufferedSubscription, discoverer discover.CachingMux, connectionsService connections.Service, urService *ur.Service, errors, systemLog logger.Recorder) error {
 	guiCfg := a.cfg.GUI()
 
 	if !guiCfg.Enabled {
@@ -425,7 +444,7 @@ func (a *App) setupGUI(m model.Model, defaultSub, diskSub events.BufferedSubscri
 	summaryService := model.NewFolderSummaryService(a.cfg, m, a.myID, a.evLogger)
 	a.mainService.Add(summaryService)
 
-	apiSvc := api.New(a.myID, a.cfg, a.opts.AssetDir, tlsDefaultCommonName, m, defaultSub, diskSub, a.evLogger, discoverer, connectionsService, urService, summaryService, errors, systemLog, cpu, &controller{a}, a.opts.NoUpgrade)
+	apiSvc := api.New(a.myID, a.cfg, a.opts.AssetDir, tlsDefaultCommonName, m, defaultSub, diskSub, persistentSub, a.evLogger, discoverer, connectionsService, urService, summaryService, errors, systemLog, cpu, &controller{a}, a.opts.NoUpgrade)
 	a.mainService.Add(apiSvc)
 
 	if err := apiSvc.WaitForStart(); err != nil {
@@ -449,6 +468,21 @@ func checkShortIDs(cfg config.Wrapper) error {
 	return nil
 }
 
+// persistentEventMask returns the mask of the given event types, ignoring
+// those that don't exist.
+func persistentEventMask(types []string) events.EventType {
+	var mask events.EventType
+	for _, name := range types {
+		t := events.UnmarshalEventType(name)
+		if t == 0 {
+			l.Warnf("Not persisting unknown event type %q", name)
+			continue
+		}
+		mask |= t
+	}
+	return mask
+}
+
 // Implements api.Controller
 type controller struct{ *App }
 
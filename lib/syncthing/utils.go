@@ -12,6 +12,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"time"
 
 	"github.com/pkg/errors"
 
@@ -123,6 +124,6 @@ func copyFile(src, dst string) error {
 	return nil
 }
 
-func OpenDBBackend(path string, tuning config.Tuning) (backend.Backend, error) {
-	return backend.Open(path, backend.Tuning(tuning))
+func OpenDBBackend(path string, tuning config.Tuning, durability config.DatabaseDurability, syncInterval time.Duration) (backend.Backend, error) {
+	return backend.Open(path, backend.Tuning(tuning), backend.Durability(durability), syncInterval)
 }
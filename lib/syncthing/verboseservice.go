@@ -97,10 +97,26 @@ func (s *verboseService) formatEvent(ev events.Event) string {
 		data := ev.Data.(map[string]string)
 		return fmt.Sprintf("Rejected connection from device %v at %v", data["device"], data["address"])
 
+	case events.ConnectionRejected:
+		data := ev.Data.(map[string]string)
+		return fmt.Sprintf("Rejected %s connection from %v claiming to be %v: %s", data["type"], data["address"], data["device"], data["reason"])
+
 	case events.FolderRejected:
 		data := ev.Data.(map[string]string)
 		return fmt.Sprintf("Rejected unshared folder %q from device %v", data["folder"], data["device"])
 
+	case events.FolderMismatch:
+		data := ev.Data.(map[string]string)
+		return fmt.Sprintf("Refused to sync folder %q with device %v, which seems to have unrelated contents", data["folder"], data["device"])
+
+	case events.ClockSkewDetected:
+		data := ev.Data.(map[string]interface{})
+		return fmt.Sprintf("Clock of device %v differs from ours by %vs", data["device"], data["skew"])
+
+	case events.DatabaseRepaired:
+		data := ev.Data.(map[string]interface{})
+		return fmt.Sprintf("Rebuilding the corrupt index of folder %q (%v); quarantined %v entries", data["folder"], data["error"], data["quarantinedKeys"])
+
 	case events.ItemStarted:
 		data := ev.Data.(map[string]string)
 		return fmt.Sprintf("Started syncing %q / %q (%v %v)", data["folder"], data["item"], data["action"], data["type"])
@@ -122,6 +138,11 @@ func (s *verboseService) formatEvent(ev events.Event) string {
 		data := ev.Data.(map[string]interface{})
 		return fmt.Sprintf("Completion for folder %q on device %v is %v%%", data["folder"], data["device"], data["completion"])
 
+	case events.CompletionSummary:
+		data := ev.Data.(map[string]interface{})
+		comps := data["completions"].(map[string]map[string]interface{})
+		return fmt.Sprintf("Completion for folder %q on %d devices", data["folder"], len(comps))
+
 	case events.FolderSummary:
 		data := ev.Data.(map[string]interface{})
 		sum := make(map[string]interface{})
@@ -133,6 +154,10 @@ func (s *verboseService) formatEvent(ev events.Event) string {
 		}
 		return fmt.Sprintf("Summary for folder %q is %v", data["folder"], sum)
 
+	case events.AggregateSummary:
+		data := ev.Data.(map[string]interface{})
+		return fmt.Sprintf("Summary for all folders is %v", data["summary"])
+
 	case events.FolderScanProgress:
 		data := ev.Data.(map[string]interface{})
 		folder := data["folder"].(string)
@@ -90,41 +90,19 @@ func SecureDefault() *tls.Config {
 	}
 }
 
-// NewCertificate generates and returns a new TLS certificate.
+// NewCertificate generates and returns a new TLS certificate, saved to the
+// given files.
 func NewCertificate(certFile, keyFile, commonName string, lifetimeDays int) (tls.Certificate, error) {
-	priv, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	certBlock, keyBlock, err := generateCertificate(commonName, lifetimeDays)
 	if err != nil {
-		return tls.Certificate{}, errors.Wrap(err, "generate key")
-	}
-
-	notBefore := time.Now().Truncate(24 * time.Hour)
-	notAfter := notBefore.Add(time.Duration(lifetimeDays*24) * time.Hour)
-
-	// NOTE: update checkExpiry() appropriately if you add or change attributes
-	// in here, especially DNSNames or IPAddresses.
-	template := x509.Certificate{
-		SerialNumber: new(big.Int).SetInt64(rand.Int63()),
-		Subject: pkix.Name{
-			CommonName: commonName,
-		},
-		NotBefore:             notBefore,
-		NotAfter:              notAfter,
-		SignatureAlgorithm:    x509.ECDSAWithSHA256,
-		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
-		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
-		BasicConstraintsValid: true,
-	}
-
-	derBytes, err := x509.CreateCertificate(rand.Reader, &template, &template, publicKey(priv), priv)
-	if err != nil {
-		return tls.Certificate{}, errors.Wrap(err, "create cert")
+		return tls.Certificate{}, err
 	}
 
 	certOut, err := os.Create(certFile)
 	if err != nil {
 		return tls.Certificate{}, errors.Wrap(err, "save cert")
 	}
-	err = pem.Encode(certOut, &pem.Block{Type: "CERTIFICATE", Bytes: derBytes})
+	err = pem.Encode(certOut, certBlock)
 	if err != nil {
 		return tls.Certificate{}, errors.Wrap(err, "save cert")
 	}
@@ -138,12 +116,7 @@ func NewCertificate(certFile, keyFile, commonName string, lifetimeDays int) (tls
 		return tls.Certificate{}, errors.Wrap(err, "save key")
 	}
 
-	block, err := pemBlockForKey(priv)
-	if err != nil {
-		return tls.Certificate{}, errors.Wrap(err, "save key")
-	}
-
-	err = pem.Encode(keyOut, block)
+	err = pem.Encode(keyOut, keyBlock)
 	if err != nil {
 		return tls.Certificate{}, errors.Wrap(err, "save key")
 	}
@@ -155,6 +128,53 @@ func NewCertificate(certFile, keyFile, commonName string, lifetimeDays int) (tls
 	return tls.LoadX509KeyPair(certFile, keyFile)
 }
 
+// GenerateCertificate generates and returns a new TLS certificate, without
+// saving it anywhere.
+func GenerateCertificate(commonName string, lifetimeDays int) (tls.Certificate, error) {
+	certBlock, keyBlock, err := generateCertificate(commonName, lifetimeDays)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.X509KeyPair(pem.EncodeToMemory(certBlock), pem.EncodeToMemory(keyBlock))
+}
+
+func generateCertificate(commonName string, lifetimeDays int) (*pem.Block, *pem.Block, error) {
+	priv, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "generate key")
+	}
+
+	notBefore := time.Now().Truncate(24 * time.Hour)
+	notAfter := notBefore.Add(time.Duration(lifetimeDays*24) * time.Hour)
+
+	// NOTE: update checkExpiry() appropriately if you add or change attributes
+	// in here, especially DNSNames or IPAddresses.
+	template := x509.Certificate{
+		SerialNumber: new(big.Int).SetInt64(rand.Int63()),
+		Subject: pkix.Name{
+			CommonName: commonName,
+		},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		SignatureAlgorithm:    x509.ECDSAWithSHA256,
+		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+	}
+
+	derBytes, err := x509.CreateCertificate(rand.Reader, &template, &template, publicKey(priv), priv)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "create cert")
+	}
+
+	keyBlock, err := pemBlockForKey(priv)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "save key")
+	}
+
+	return &pem.Block{Type: "CERTIFICATE", Bytes: derBytes}, keyBlock, nil
+}
+
 type DowngradingListener struct {
 	net.Listener
 	TLSConfig *tls.Config
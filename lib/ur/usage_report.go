@@ -123,16 +123,19 @@ func (s *Service) reportData(urVersion int, preview bool) map[string]interface{}
 
 	var rescanIntvs []int
 	folderUses := map[string]int{
-		"sendonly":            0,
-		"sendreceive":         0,
-		"receiveonly":         0,
-		"ignorePerms":         0,
-		"ignoreDelete":        0,
-		"autoNormalize":       0,
-		"simpleVersioning":    0,
-		"externalVersioning":  0,
-		"staggeredVersioning": 0,
-		"trashcanVersioning":  0,
+		"sendonly":              0,
+		"sendreceive":           0,
+		"receiveonly":           0,
+		"backuptarget":          0,
+		"ignorePerms":           0,
+		"ignoreDelete":          0,
+		"autoNormalize":         0,
+		"simpleVersioning":      0,
+		"externalVersioning":    0,
+		"staggeredVersioning":   0,
+		"trashcanVersioning":    0,
+		"systemtrashVersioning": 0,
+		"dedupVersioning":       0,
 	}
 	for _, cfg := range s.cfg.Folders() {
 		rescanIntvs = append(rescanIntvs, cfg.RescanIntervalS)
@@ -144,6 +147,8 @@ func (s *Service) reportData(urVersion int, preview bool) map[string]interface{}
 			folderUses["sendreceive"]++
 		case config.FolderTypeReceiveOnly:
 			folderUses["receiveonly"]++
+		case config.FolderTypeBackupTarget:
+			folderUses["backuptarget"]++
 		}
 		if cfg.IgnorePerms {
 			folderUses["ignorePerms"]++
@@ -0,0 +1,582 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package versioner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thejerf/suture"
+
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/osutil"
+	"github.com/syncthing/syncthing/lib/protocol"
+	"github.com/syncthing/syncthing/lib/sync"
+	"github.com/syncthing/syncthing/lib/util"
+)
+
+func init() {
+	// Register the constructor for this type of versioner with the name "dedup"
+	Register("dedup", newDedup)
+}
+
+// The dedup versioner keeps the blocks of the versions rather than whole
+// files, each block once however many versions have it, for large files
+// that change a little at a time. The blocks are those of the index, with
+// the same sizes and SHA-256 hashes, and are stored by hash in the blocks
+// directory of the archive. Each version is a manifest in the files
+// directory, named like the versions of the simple versioner and listing
+// the blocks to put together again. Cleaning up removes the versions over
+// the number to keep of each file, as archiving does, and then the blocks
+// that no version uses any more.
+type dedup struct {
+	suture.Service
+	keep          int
+	cleanInterval time.Duration
+	schedule      *schedule
+	folderFs      fs.Filesystem
+	versionsFs    fs.Filesystem
+	mut           sync.Mutex
+}
+
+const (
+	dedupBlocksDir = "blocks"
+	dedupFilesDir  = "files"
+)
+
+type dedupManifest struct {
+	Size        int64       `json:"size"`
+	ModTime     time.Time   `json:"modTime"`
+	Permissions fs.FileMode `json:"permissions"`
+	BlockSize   int         `json:"blockSize"`
+	Blocks      []string    `json:"blocks"` // hex encoded hashes
+}
+
+func newDedup(folderFs fs.Filesystem, params map[string]string) Versioner {
+	keep, err := strconv.Atoi(params["keep"])
+	if err != nil {
+		keep = 5 // A reasonable default
+	}
+	cleanInterval, err := strconv.ParseInt(params["cleanInterval"], 10, 0)
+	if err != nil {
+		cleanInterval = 3600 // Default: clean once per hour
+	}
+
+	// Backwards compatibility
+	params["fsPath"] = params["versionsPath"]
+
+	v := &dedup{
+		keep:          keep,
+		cleanInterval: time.Duration(cleanInterval) * time.Second,
+		schedule:      parseCleanupSchedule(params),
+		folderFs:      folderFs,
+		versionsFs:    fsFromParams(folderFs, params),
+		mut:           sync.NewMutex(),
+	}
+	v.Service = util.AsService(v.serve, v.String())
+
+	l.Debugf("instantiated %#v", v)
+	return v
+}
+
+func (v *dedup) String() string {
+	return fmt.Sprintf("dedup@%p", v)
+}
+
+func (v *dedup) serve(ctx context.Context) {
+	timer := time.NewTimer(cleanupDelay(v.schedule, v.cleanInterval, time.Now()))
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			if res, err := v.Clean(); err != nil {
+				l.Warnln("Versioner: cleaning up blocks:", err)
+			} else if res.Files > 0 {
+				l.Infof("Versioner: cleaned up %d files (%d bytes) in %v", res.Files, res.Bytes, v.versionsFs)
+			}
+			timer.Reset(cleanupDelay(v.schedule, v.cleanInterval, time.Now()))
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func blockPath(hash string) string {
+	return filepath.Join(dedupBlocksDir, hash[:2], hash)
+}
+
+func manifestPath(filePath, tag string) string {
+	return filepath.Join(dedupFilesDir, TagFilename(filePath, tag))
+}
+
+// Archive moves the named file away to a version archive. If this function
+// returns nil, the named file does not exist any more (has been archived).
+func (v *dedup) Archive(filePath string) error {
+	filePath = osutil.NativeFilename(filePath)
+	info, err := v.folderFs.Lstat(filePath)
+	if fs.IsNotExist(err) {
+		l.Debugln("not archiving nonexistent file", filePath)
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if info.IsSymlink() {
+		panic("bug: attempting to version a symlink")
+	}
+
+	v.mut.Lock()
+	defer v.mut.Unlock()
+
+	// The blocks and then the manifest must be on disk before the file
+	// is removed, with the directories they were put in.
+	dirs := make(map[string]struct{})
+	manifest, err := v.storeBlocks(filePath, info, dirs)
+	if err != nil {
+		return err
+	}
+	syncDedupDirs(v.versionsFs, dirs)
+	bs, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	name := manifestPath(filePath, now.Format(TimeFormat))
+	l.Debugln("archiving", filePath, "as", name)
+	if err := v.versionsFs.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	if err := writeDedupFile(v.versionsFs, name, bs); err != nil {
+		return err
+	}
+	_ = v.versionsFs.Chtimes(name, now, info.ModTime())
+	dirs = make(map[string]struct{})
+	addDedupDirs(dirs, name)
+	syncDedupDirs(v.versionsFs, dirs)
+
+	if err := v.folderFs.Remove(filePath); err != nil {
+		return err
+	}
+
+	v.expire(findAllVersions(v.versionsFs, filepath.Join(dedupFilesDir, filePath)))
+	return nil
+}
+
+// storeBlocks stores the blocks of the file that aren't in the archive yet,
+// or are there but damaged, and returns the manifest listing them all. The
+// directories it put blocks in are added to dirs.
+func (v *dedup) storeBlocks(filePath string, info fs.FileInfo, dirs map[string]struct{}) (dedupManifest, error) {
+	manifest := dedupManifest{
+		Size:        info.Size(),
+		ModTime:     info.ModTime(),
+		Permissions: info.Mode() & fs.ModePerm,
+		BlockSize:   protocol.BlockSize(info.Size()),
+	}
+
+	fd, err := v.folderFs.Open(filePath)
+	if err != nil {
+		return manifest, err
+	}
+	defer fd.Close()
+
+	buf := make([]byte, manifest.BlockSize)
+	for {
+		n, err := io.ReadFull(fd, buf)
+		if err == io.EOF {
+			break
+		} else if err != nil && err != io.ErrUnexpectedEOF {
+			return manifest, err
+		}
+
+		sum := sha256.Sum256(buf[:n])
+		hash := hex.EncodeToString(sum[:])
+		manifest.Blocks = append(manifest.Blocks, hash)
+
+		name := blockPath(hash)
+		if have, err := hashDedupFile(v.versionsFs, name); err == nil && have == hash {
+			// Already have it
+			continue
+		} else if err == nil {
+			l.Infof("Versioner: replacing damaged block %s while archiving %s", hash, filePath)
+		}
+		if err := v.versionsFs.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			return manifest, err
+		}
+		if err := writeDedupFile(v.versionsFs, name, buf[:n]); err != nil {
+			return manifest, err
+		}
+		addDedupDirs(dirs, name)
+
+		if n < len(buf) {
+			break
+		}
+	}
+	return manifest, nil
+}
+
+// writeDedupFile writes the data to the named file through a temporary
+// file, so that there is never a partial one by the name. The data is
+// synced before the rename; the directory is up to the caller.
+func writeDedupFile(filesystem fs.Filesystem, name string, data []byte) error {
+	tmp := fs.TempName(name)
+	fd, err := filesystem.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := fd.Write(data); err != nil {
+		fd.Close()
+		filesystem.Remove(tmp)
+		return err
+	}
+	if err := fd.Sync(); err != nil {
+		fd.Close()
+		filesystem.Remove(tmp)
+		return err
+	}
+	if err := fd.Close(); err != nil {
+		filesystem.Remove(tmp)
+		return err
+	}
+	return filesystem.Rename(tmp, name)
+}
+
+// hashDedupFile returns the hex encoded SHA-256 hash of the named file.
+func hashDedupFile(filesystem fs.Filesystem, name string) (string, error) {
+	fd, err := filesystem.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer fd.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, fd); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// addDedupDirs adds the directory of the named file, and those above it,
+// to the set.
+func addDedupDirs(dirs map[string]struct{}, name string) {
+	for dir := filepath.Dir(name); ; dir = filepath.Dir(dir) {
+		dirs[dir] = struct{}{}
+		if dir == "." {
+			return
+		}
+	}
+}
+
+// syncDedupDirs fsyncs the directories, so that the files renamed into
+// them and the directories created in them stay there. Failing that isn't
+// an error, as not all platforms can.
+func syncDedupDirs(filesystem fs.Filesystem, dirs map[string]struct{}) {
+	for dir := range dirs {
+		fd, err := filesystem.Open(dir)
+		if err != nil {
+			l.Debugf("fsync %q failed: %v", dir, err)
+			continue
+		}
+		if err := fd.Sync(); err != nil {
+			l.Debugf("fsync %q failed: %v", dir, err)
+		}
+		fd.Close()
+	}
+}
+
+func (v *dedup) readManifest(name string) (dedupManifest, error) {
+	var manifest dedupManifest
+	fd, err := v.versionsFs.Open(name)
+	if err != nil {
+		return manifest, err
+	}
+	defer fd.Close()
+	bs, err := ioutil.ReadAll(fd)
+	if err != nil {
+		return manifest, err
+	}
+	err = json.Unmarshal(bs, &manifest)
+	return manifest, err
+}
+
+// expire removes all but the newest versions to keep of a file, given the
+// names of their manifests, oldest first. The blocks are left for the next
+// cleanup.
+func (v *dedup) expire(versions []string) int {
+	removed := 0
+	if len(versions) <= v.keep {
+		return removed
+	}
+	for _, name := range versions[:len(versions)-v.keep] {
+		l.Debugln("cleaning out", name)
+		if err := v.versionsFs.Remove(name); err != nil {
+			l.Warnln("removing old version:", err)
+			continue
+		}
+		removed++
+	}
+	return removed
+}
+
+func (v *dedup) GetVersions() (map[string][]FileVersion, error) {
+	files := make(map[string][]FileVersion)
+	if _, err := v.versionsFs.Lstat(dedupFilesDir); fs.IsNotExist(err) {
+		return files, nil
+	}
+
+	err := v.versionsFs.Walk(dedupFilesDir, func(path string, f fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !f.IsRegular() || fs.IsTemporary(path) {
+			return nil
+		}
+		name, tag := UntagFilename(path)
+		if name == "" {
+			return nil
+		}
+		versionTime, err := time.ParseInLocation(TimeFormat, tag, time.Local)
+		if err != nil {
+			return nil
+		}
+		manifest, err := v.readManifest(path)
+		if err != nil {
+			l.Debugln("reading manifest:", err)
+			return nil
+		}
+		name = osutil.NormalizedFilename(strings.TrimPrefix(name, dedupFilesDir+string(fs.PathSeparator)))
+		files[name] = append(files[name], FileVersion{
+			VersionTime: versionTime,
+			ModTime:     manifest.ModTime.Truncate(time.Second),
+			Size:        manifest.Size,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func (v *dedup) findManifest(filePath string, versionTime time.Time) (string, dedupManifest, error) {
+	tag := versionTime.In(time.Local).Truncate(time.Second).Format(TimeFormat)
+	name := manifestPath(osutil.NativeFilename(filePath), tag)
+	manifest, err := v.readManifest(name)
+	if fs.IsNotExist(err) {
+		return "", manifest, errNotFound
+	}
+	return name, manifest, err
+}
+
+func (v *dedup) Restore(filePath string, versionTime time.Time) error {
+	filePath = osutil.NativeFilename(filePath)
+	name, manifest, err := v.findManifest(filePath, versionTime)
+	if err != nil {
+		return err
+	}
+
+	// Archive whatever is in the way first, as the other versioners do.
+	// That may expire the version to restore, but not its blocks.
+	if info, err := v.folderFs.Lstat(filePath); err == nil {
+		switch {
+		case info.IsDir():
+			return errDirectory
+		case info.IsSymlink():
+			if err := v.folderFs.Remove(filePath); err != nil {
+				return err
+			}
+		default:
+			if err := v.Archive(filePath); err != nil {
+				return err
+			}
+		}
+	} else if !fs.IsNotExist(err) {
+		return err
+	}
+
+	v.mut.Lock()
+	defer v.mut.Unlock()
+	if err := v.restore(manifest, filePath); err != nil {
+		return err
+	}
+	// The version is back in the folder, not in the archive
+	if err := v.versionsFs.Remove(name); err != nil && !fs.IsNotExist(err) {
+		l.Debugln("removing restored version:", err)
+	}
+	return nil
+}
+
+// RestoreTo puts the version of the file together again at the target,
+// which must not exist yet.
+func (v *dedup) RestoreTo(filePath string, versionTime time.Time, target string) error {
+	target, err := fs.Canonicalize(osutil.NativeFilename(target))
+	if err != nil {
+		return err
+	}
+
+	v.mut.Lock()
+	defer v.mut.Unlock()
+
+	_, manifest, err := v.findManifest(filePath, versionTime)
+	if err != nil {
+		return err
+	}
+	return v.restore(manifest, target)
+}
+
+func (v *dedup) restore(manifest dedupManifest, target string) error {
+	if _, err := v.folderFs.Lstat(target); err == nil {
+		return errFileAlreadyExists
+	} else if !fs.IsNotExist(err) {
+		return err
+	}
+
+	_ = v.folderFs.MkdirAll(filepath.Dir(target), 0755)
+	tmp := fs.TempName(target)
+	if err := v.assemble(manifest, tmp); err != nil {
+		v.folderFs.Remove(tmp)
+		return err
+	}
+	_ = v.folderFs.Chtimes(tmp, manifest.ModTime, manifest.ModTime)
+	return v.folderFs.Rename(tmp, target)
+}
+
+func (v *dedup) assemble(manifest dedupManifest, name string) error {
+	perm := manifest.Permissions
+	if perm == 0 {
+		perm = 0644
+	}
+	out, err := v.folderFs.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	var written int64
+	h := sha256.New()
+	for _, hash := range manifest.Blocks {
+		fd, err := v.versionsFs.Open(blockPath(hash))
+		if err != nil {
+			out.Close()
+			return err
+		}
+		h.Reset()
+		n, err := io.Copy(io.MultiWriter(out, h), fd)
+		fd.Close()
+		if err != nil {
+			out.Close()
+			return err
+		}
+		if hex.EncodeToString(h.Sum(nil)) != hash {
+			out.Close()
+			return fmt.Errorf("block %s is damaged", hash)
+		}
+		written += n
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if written != manifest.Size {
+		return fmt.Errorf("restored %d bytes, expected %d", written, manifest.Size)
+	}
+	return nil
+}
+
+// Clean removes the versions over the number to keep of each file, and
+// then the blocks that no version uses any more. Only the size of the
+// blocks counts as pruned bytes, as that's what takes the space.
+func (v *dedup) Clean() (CleanupResult, error) {
+	v.mut.Lock()
+	defer v.mut.Unlock()
+
+	var res CleanupResult
+	if _, err := v.versionsFs.Lstat(dedupBlocksDir); fs.IsNotExist(err) {
+		return res, nil
+	}
+
+	versionsPerFile := make(map[string][]string)
+	if _, err := v.versionsFs.Lstat(dedupFilesDir); err == nil {
+		err := v.versionsFs.Walk(dedupFilesDir, func(path string, f fs.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !f.IsRegular() {
+				return nil
+			}
+			if fs.IsTemporary(path) {
+				// Left over from an interrupted archiving
+				return v.versionsFs.Remove(path)
+			}
+			if name, _ := UntagFilename(path); name != "" {
+				versionsPerFile[name] = append(versionsPerFile[name], path)
+			}
+			return nil
+		})
+		if err != nil {
+			return res, err
+		}
+	}
+
+	used := make(map[string]struct{})
+	dirTracker := make(emptyDirTracker)
+	for _, versions := range versionsPerFile {
+		sort.Strings(versions)
+		res.Files += v.expire(versions)
+		if len(versions) > v.keep {
+			versions = versions[len(versions)-v.keep:]
+		}
+		for _, name := range versions {
+			manifest, err := v.readManifest(name)
+			if err != nil {
+				// Not knowing which blocks it uses, we can't remove any
+				return res, fmt.Errorf("reading %s: %v", name, err)
+			}
+			for _, hash := range manifest.Blocks {
+				used[hash] = struct{}{}
+			}
+		}
+	}
+
+	err := v.versionsFs.Walk(dedupBlocksDir, func(path string, f fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if f.IsDir() {
+			dirTracker.addDir(path)
+			return nil
+		}
+		if _, ok := used[filepath.Base(path)]; ok {
+			dirTracker.addFile(path)
+			return nil
+		}
+		l.Debugln("removing unused block", path)
+		if err := v.versionsFs.Remove(path); err != nil {
+			l.Warnln("removing unused block:", err)
+			dirTracker.addFile(path)
+			return nil
+		}
+		res.Bytes += f.Size()
+		return nil
+	})
+	if err != nil {
+		return res, err
+	}
+	dirTracker.deleteEmptyDirs(v.versionsFs)
+
+	return res, nil
+}
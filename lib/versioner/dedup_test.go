@@ -0,0 +1,145 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package versioner
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestDedupArchiveRestoreClean(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dedup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	folderFs := fs.NewFilesystem(fs.FilesystemTypeBasic, dir)
+	v := newDedup(folderFs, map[string]string{"keep": "2"}).(*dedup)
+
+	countBlocks := func() int {
+		n := 0
+		filepath.Walk(filepath.Join(dir, ".stversions", dedupBlocksDir), func(_ string, info os.FileInfo, err error) error {
+			if err == nil && info.Mode().IsRegular() {
+				n++
+			}
+			return nil
+		})
+		return n
+	}
+
+	// Two and a half blocks, and then the same with the last half changed
+	old := bytes.Repeat([]byte("a"), 5*protocol.MinBlockSize/2)
+	changed := append(append([]byte{}, old[:2*protocol.MinBlockSize]...), bytes.Repeat([]byte("b"), protocol.MinBlockSize/2)...)
+	name := filepath.Join("dir", "file")
+	must(t, folderFs.MkdirAll("dir", 0755))
+
+	must(t, ioutil.WriteFile(filepath.Join(dir, name), old, 0644))
+	must(t, v.Archive(name))
+	if _, err := folderFs.Lstat(name); !fs.IsNotExist(err) {
+		t.Fatal("archived file is still there")
+	}
+	// The two full blocks are the same
+	if n := countBlocks(); n != 2 {
+		t.Errorf("got %d blocks, expected 2", n)
+	}
+
+	// Pretend the first version is from a while ago, not to be replaced by
+	// the next one within the same second
+	oldTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	versions := findAllVersions(v.versionsFs, filepath.Join(dedupFilesDir, name))
+	must(t, v.versionsFs.Rename(versions[0], manifestPath(name, oldTime.Format(TimeFormat))))
+
+	must(t, ioutil.WriteFile(filepath.Join(dir, name), changed, 0644))
+	must(t, v.Archive(name))
+	if n := countBlocks(); n != 3 {
+		t.Errorf("got %d blocks, expected 3", n)
+	}
+
+	all, err := v.GetVersions()
+	must(t, err)
+	if len(all[name]) != 2 {
+		t.Fatalf("got versions %v, expected two of %s", all, name)
+	}
+
+	must(t, v.Restore(name, oldTime))
+	if bs, err := ioutil.ReadFile(filepath.Join(dir, name)); err != nil || !bytes.Equal(bs, old) {
+		t.Errorf("restored file differs, %v", err)
+	}
+
+	// Restoring took the old version out of the archive, leaving the
+	// block only it had until cleaning up
+	res, err := v.Clean()
+	must(t, err)
+	if res.Files != 0 || res.Bytes != protocol.MinBlockSize/2 {
+		t.Errorf("cleanup pruned %+v, expected the half block of the old version", res)
+	}
+	if n := countBlocks(); n != 2 {
+		t.Errorf("got %d blocks after cleanup, expected 2", n)
+	}
+}
+
+func TestDedupDamagedBlock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dedup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	folderFs := fs.NewFilesystem(fs.FilesystemTypeBasic, dir)
+	v := newDedup(folderFs, map[string]string{"keep": "2"}).(*dedup)
+
+	data := append(bytes.Repeat([]byte("a"), protocol.MinBlockSize), bytes.Repeat([]byte("b"), protocol.MinBlockSize)...)
+	must(t, ioutil.WriteFile(filepath.Join(dir, "file"), data, 0644))
+	must(t, v.Archive("file"))
+
+	sum := sha256.Sum256(data[:protocol.MinBlockSize])
+	block := filepath.Join(dir, ".stversions", blockPath(hex.EncodeToString(sum[:])))
+	must(t, ioutil.WriteFile(block, []byte("damaged"), 0644))
+
+	latest := func() time.Time {
+		all, err := v.GetVersions()
+		must(t, err)
+		var latest time.Time
+		for _, version := range all["file"] {
+			if version.VersionTime.After(latest) {
+				latest = version.VersionTime
+			}
+		}
+		return latest
+	}
+
+	// The damaged block isn't restored
+	if err := v.RestoreTo("file", latest(), "restored"); err == nil {
+		t.Error("restoring with a damaged block succeeded")
+	}
+	if _, err := folderFs.Lstat("restored"); !fs.IsNotExist(err) {
+		t.Error("restored file exists, got", err)
+	}
+
+	// Archiving the block again replaces it
+	must(t, ioutil.WriteFile(filepath.Join(dir, "file"), data, 0644))
+	must(t, v.Archive("file"))
+	must(t, v.RestoreTo("file", latest(), "restored"))
+	if bs, err := ioutil.ReadFile(filepath.Join(dir, "restored")); err != nil || !bytes.Equal(bs, data) {
+		t.Errorf("restored file differs, %v", err)
+	}
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -7,9 +7,13 @@
 package versioner
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
 	"strings"
 	"time"
@@ -21,14 +25,53 @@ import (
 
 func init() {
 	// Register the constructor for this type of versioner with the name "external"
-	factories["external"] = newExternal
+	Register("external", newExternal)
 }
 
+// The external versioner runs a command for each file to archive, with the
+// file given by the templated parameters of the command line. The command
+// also gets the details as JSON on standard input and in the environment,
+// see externalRequest. With the "json" protocol the command also lists
+// and restores the versions it keeps, answering the "versions" action with
+// a JSON object like the one of GetVersions on standard output.
 type external struct {
 	command    string
+	folderID   string
+	protocol   string // "json" for commands that list and restore versions
 	filesystem fs.Filesystem
 }
 
+const (
+	externalArchive  = "archive"
+	externalVersions = "versions"
+	externalRestore  = "restore"
+)
+
+// externalRequest is what the command gets on standard input.
+type externalRequest struct {
+	Action           string                `json:"action"`
+	FolderID         string                `json:"folderID"`
+	FolderFilesystem string                `json:"folderFilesystem"`
+	FolderPath       string                `json:"folderPath"`
+	FilePath         string                `json:"filePath,omitempty"`
+	File             *externalFileInfo     `json:"file,omitempty"`        // the file to archive
+	Conflict         *externalConflictInfo `json:"conflict,omitempty"`    // when that is a conflict copy
+	VersionTime      *time.Time            `json:"versionTime,omitempty"` // the version to restore
+}
+
+type externalFileInfo struct {
+	Size        int64       `json:"size"`
+	ModTime     time.Time   `json:"modTime"`
+	Permissions fs.FileMode `json:"permissions"`
+}
+
+// externalConflictInfo describes a conflict copy, by its name.
+type externalConflictInfo struct {
+	Original string    `json:"original"`
+	Time     time.Time `json:"time"`
+	Device   string    `json:"device"` // the short ID of the device that made the other change
+}
+
 func newExternal(filesystem fs.Filesystem, params map[string]string) Versioner {
 	command := params["command"]
 
@@ -38,6 +81,8 @@ func newExternal(filesystem fs.Filesystem, params map[string]string) Versioner {
 
 	s := external{
 		command:    command,
+		folderID:   params["folderID"],
+		protocol:   params["protocol"],
 		filesystem: filesystem,
 	}
 
@@ -61,19 +106,83 @@ func (v external) Archive(filePath string) error {
 
 	l.Debugln("archiving", filePath)
 
+	req := v.request(externalArchive, filePath)
+	req.File = &externalFileInfo{
+		Size:        info.Size(),
+		ModTime:     info.ModTime(),
+		Permissions: info.Mode() & fs.ModePerm,
+	}
+	req.Conflict = parseConflictName(filePath)
+	if _, err := v.run(req); err != nil {
+		return err
+	}
+
+	// return error if the file was not removed
+	if _, err = v.filesystem.Lstat(filePath); fs.IsNotExist(err) {
+		return nil
+	}
+	return errors.New("Versioner: file was not removed by external script")
+}
+
+func (v external) GetVersions() (map[string][]FileVersion, error) {
+	if v.protocol != "json" {
+		return nil, ErrRestorationNotSupported
+	}
+	out, err := v.run(v.request(externalVersions, ""))
+	if err != nil {
+		return nil, err
+	}
+	var versions map[string][]FileVersion
+	if err := json.Unmarshal(out, &versions); err != nil {
+		return nil, fmt.Errorf("Versioner: invalid list of versions from external script: %v", err)
+	}
+	return versions, nil
+}
+
+func (v external) Restore(filePath string, versionTime time.Time) error {
+	if v.protocol != "json" {
+		return ErrRestorationNotSupported
+	}
+	req := v.request(externalRestore, filePath)
+	req.VersionTime = &versionTime
+	if _, err := v.run(req); err != nil {
+		return err
+	}
+
+	// return error if the file was not restored
+	if _, err := v.filesystem.Lstat(filePath); err != nil {
+		return errors.New("Versioner: file was not restored by external script")
+	}
+	return nil
+}
+
+func (v external) request(action, filePath string) externalRequest {
+	return externalRequest{
+		Action:           action,
+		FolderID:         v.folderID,
+		FolderFilesystem: v.filesystem.Type().String(),
+		FolderPath:       v.filesystem.URI(),
+		FilePath:         filePath,
+	}
+}
+
+// run runs the command for the request, returning its standard output.
+func (v external) run(req externalRequest) ([]byte, error) {
 	if v.command == "" {
-		return errors.New("Versioner: command is empty, please enter a valid command")
+		return nil, errors.New("Versioner: command is empty, please enter a valid command")
 	}
 
 	words, err := shellquote.Split(v.command)
 	if err != nil {
-		return errors.New("Versioner: command is invalid: " + err.Error())
+		return nil, errors.New("Versioner: command is invalid: " + err.Error())
 	}
 
 	context := map[string]string{
-		"%FOLDER_FILESYSTEM%": v.filesystem.Type().String(),
-		"%FOLDER_PATH%":       v.filesystem.URI(),
-		"%FILE_PATH%":         filePath,
+		"%FOLDER_ID%":         req.FolderID,
+		"%FOLDER_FILESYSTEM%": req.FolderFilesystem,
+		"%FOLDER_PATH%":       req.FolderPath,
+		"%FILE_PATH%":         req.FilePath,
+		"%ACTION%":            req.Action,
 	}
 
 	for i, word := range words {
@@ -84,6 +193,11 @@ func (v external) Archive(filePath string) error {
 		words[i] = word
 	}
 
+	input, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
 	cmd := exec.Command(words[0], words[1:]...)
 	env := os.Environ()
 	// filter STGUIAUTH and STGUIAPIKEY from environment variables
@@ -93,24 +207,53 @@ func (v external) Archive(filePath string) error {
 			filteredEnv = append(filteredEnv, x)
 		}
 	}
+	filteredEnv = append(filteredEnv,
+		"STVERSIONER_ACTION="+req.Action,
+		"STFOLDER_ID="+req.FolderID,
+		"STFOLDER_FILESYSTEM="+req.FolderFilesystem,
+		"STFOLDER_PATH="+req.FolderPath,
+		"STFILE_PATH="+req.FilePath,
+	)
 	cmd.Env = filteredEnv
-	combinedOutput, err := cmd.CombinedOutput()
-	l.Debugln("external command output:", string(combinedOutput))
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	l.Debugln("external command output:", stdout.String(), stderr.String())
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return stdout.Bytes(), nil
+}
 
-	// return error if the file was not removed
-	if _, err = v.filesystem.Lstat(filePath); fs.IsNotExist(err) {
+// parseConflictName returns what the name of a conflict copy says about
+// the conflict, or nil for other files.
+func parseConflictName(name string) *externalConflictInfo {
+	const marker = ".sync-conflict-"
+	dir, base := filepath.Split(name)
+	i := strings.Index(base, marker)
+	if i < 0 {
 		return nil
 	}
-	return errors.New("Versioner: file was not removed by external script")
-}
-
-func (v external) GetVersions() (map[string][]FileVersion, error) {
-	return nil, ErrRestorationNotSupported
-}
-
-func (v external) Restore(filePath string, versionTime time.Time) error {
-	return ErrRestorationNotSupported
+	// The rest is like "20060102-150405-DEVICE.ext"
+	rest := base[i+len(marker):]
+	const stamp = "20060102-150405"
+	if len(rest) < len(stamp)+1 || rest[len(stamp)] != '-' {
+		return nil
+	}
+	t, err := time.ParseInLocation(stamp, rest[:len(stamp)], time.Local)
+	if err != nil {
+		return nil
+	}
+	device := rest[len(stamp)+1:]
+	ext := ""
+	if j := strings.IndexByte(device, '.'); j >= 0 {
+		device, ext = device[:j], device[j:]
+	}
+	return &externalConflictInfo{
+		Original: dir + base[:i] + ext,
+		Time:     t,
+		Device:   device,
+	}
 }
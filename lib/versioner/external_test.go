@@ -7,11 +7,13 @@
 package versioner
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/syncthing/syncthing/lib/fs"
 )
@@ -77,6 +79,70 @@ func TestExternal(t *testing.T) {
 	}
 }
 
+func TestExternalJSON(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell")
+	}
+
+	file := filepath.Join("testdata", "folder path", "file.sync-conflict-20191016-102030-ABCDEFG.txt")
+	prepForRemoval(t, file)
+	defer os.RemoveAll("testdata")
+
+	// The command keeps the request and removes the file named in the
+	// environment, or lists the versions.
+	e := external{
+		filesystem: fs.NewFilesystem(fs.FilesystemTypeBasic, "."),
+		folderID:   "default",
+		protocol:   "json",
+		command:    `sh -c 'if [ "$STVERSIONER_ACTION" = versions ]; then echo "{\"file\": [{\"size\": 6}]}"; else cat > testdata/request.json; rm -f "$STFILE_PATH"; fi'`,
+	}
+	if err := e.Archive(file); err != nil {
+		t.Fatal(err)
+	}
+
+	bs, err := ioutil.ReadFile("testdata/request.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var req externalRequest
+	if err := json.Unmarshal(bs, &req); err != nil {
+		t.Fatal(err)
+	}
+	if req.Action != "archive" || req.FolderID != "default" || req.FilePath != file {
+		t.Errorf("unexpected request %s", bs)
+	}
+	if req.File == nil || req.File.Size != 6 {
+		t.Errorf("expected the file size in request %s", bs)
+	}
+	if req.Conflict == nil || req.Conflict.Original != filepath.Join("testdata", "folder path", "file.txt") || req.Conflict.Device != "ABCDEFG" {
+		t.Errorf("expected the conflict in request %s", bs)
+	}
+
+	versions, err := e.GetVersions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions["file"]) != 1 || versions["file"][0].Size != 6 {
+		t.Errorf("unexpected versions %v", versions)
+	}
+}
+
+func TestParseConflictName(t *testing.T) {
+	c := parseConflictName("dir/file.sync-conflict-20191016-102030-ABCDEFG.tar.gz")
+	if c == nil {
+		t.Fatal("expected a conflict")
+	}
+	if c.Original != "dir/file.tar.gz" || c.Device != "ABCDEFG" || !c.Time.Equal(time.Date(2019, 10, 16, 10, 20, 30, 0, time.Local)) {
+		t.Errorf("unexpected conflict %+v", c)
+	}
+
+	for _, name := range []string{"dir/file.txt", "file.sync-conflict-2019.txt", "file.sync-conflict-20191016-102030.txt"} {
+		if c := parseConflictName(name); c != nil {
+			t.Errorf("%q is not a conflict copy, got %+v", name, c)
+		}
+	}
+}
+
 func prepForRemoval(t *testing.T, file string) {
 	if err := os.RemoveAll("testdata"); err != nil {
 		t.Fatal(err)
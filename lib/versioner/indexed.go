@@ -0,0 +1,174 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package versioner
+
+import (
+	"errors"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/osutil"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+// ErrCleanupNotSupported is returned by Clean of an indexed versioner when
+// the versioner it wraps doesn't clean up.
+var ErrCleanupNotSupported = errors.New("versioner does not clean up versions")
+
+// A SourceFunc returns the device that last modified the named file.
+type SourceFunc func(name string) protocol.ShortID
+
+// The indexed versioner records the metadata of the versions archived by
+// another versioner in the database, so that it's known where a version
+// came from regardless of how the other versioner names it. The versions
+// it lists are still those the other versioner has, as it may prune them
+// on its own; records of versions that are gone are dropped then.
+type indexed struct {
+	Versioner
+	filesystem fs.Filesystem
+	index      *db.VersionIndex
+	source     SourceFunc
+}
+
+// NewIndexed returns a versioner that archives with the given one, keeping
+// the metadata of the versions in the index. The source func may be nil.
+func NewIndexed(ver Versioner, filesystem fs.Filesystem, index *db.VersionIndex, source SourceFunc) Versioner {
+	return &indexed{
+		Versioner:  ver,
+		filesystem: filesystem,
+		index:      index,
+		source:     source,
+	}
+}
+
+func (v *indexed) Archive(filePath string) error {
+	info, err := v.filesystem.Lstat(filePath)
+	if err != nil || info.IsSymlink() {
+		// Nothing to keep track of, let the versioner deal with it
+		return v.Versioner.Archive(filePath)
+	}
+
+	rec := db.ArchivedVersion{
+		VersionTime: time.Now().Truncate(time.Second),
+		ModTime:     info.ModTime(),
+		Size:        info.Size(),
+	}
+	if v.source != nil {
+		rec.Device = v.source(filePath)
+	}
+	if err := v.Versioner.Archive(filePath); err != nil {
+		return err
+	}
+	rec.ArchivedAt = time.Now()
+	if err := v.index.Add(osutil.NormalizedFilename(filePath), rec); err != nil {
+		l.Debugln("recording archived version of", filePath, err)
+	}
+	return nil
+}
+
+// GetVersions returns the versions the versioner has, with the device they
+// came from where it's recorded.
+func (v *indexed) GetVersions() (map[string][]FileVersion, error) {
+	versions, err := v.Versioner.GetVersions()
+	if err != nil {
+		return nil, err
+	}
+	recorded, err := v.index.All()
+	if err != nil {
+		l.Debugln("reading version index:", err)
+		return versions, nil
+	}
+
+	for name, recs := range recorded {
+		fileVersions := versions[name]
+		var kept []db.ArchivedVersion
+		for _, rec := range recs {
+			found := false
+			for i := range fileVersions {
+				if rec.Matches(fileVersions[i].VersionTime) {
+					if rec.Device != 0 {
+						fileVersions[i].Device = rec.Device.String()
+					}
+					found = true
+				}
+			}
+			if found {
+				kept = append(kept, rec)
+			}
+		}
+		if len(kept) != len(recs) {
+			if err := v.index.Set(name, kept); err != nil {
+				l.Debugln("updating version index:", err)
+			}
+		}
+	}
+	return versions, nil
+}
+
+func (v *indexed) Restore(filePath string, versionTime time.Time) error {
+	if err := v.Versioner.Restore(filePath, versionTime); err != nil {
+		return err
+	}
+	v.forget(filePath, versionTime)
+	return nil
+}
+
+func (v *indexed) OpenVersion(filePath string, versionTime time.Time) (fs.File, error) {
+	va, ok := v.Versioner.(VersionAccessor)
+	if !ok {
+		return nil, ErrRestorationNotSupported
+	}
+	return va.OpenVersion(filePath, versionTime)
+}
+
+func (v *indexed) RestoreTo(filePath string, versionTime time.Time, target string) error {
+	va, ok := v.Versioner.(VersionAccessor)
+	if !ok {
+		return ErrRestorationNotSupported
+	}
+	return va.RestoreTo(filePath, versionTime, target)
+}
+
+func (v *indexed) Clean() (CleanupResult, error) {
+	cleaner, ok := v.Versioner.(Cleaner)
+	if !ok {
+		return CleanupResult{}, ErrCleanupNotSupported
+	}
+	res, err := cleaner.Clean()
+	if err == nil {
+		// Drops the records of the pruned versions
+		_, _ = v.GetVersions()
+	}
+	return res, err
+}
+
+// forget drops the record of the version, when the versioner doesn't have
+// it any more.
+func (v *indexed) forget(filePath string, versionTime time.Time) {
+	name := osutil.NormalizedFilename(filePath)
+	recs, err := v.index.Versions(name)
+	if err != nil {
+		l.Debugln("reading version index:", err)
+		return
+	}
+	kept := recs[:0]
+	for _, rec := range recs {
+		if !rec.Matches(versionTime) {
+			kept = append(kept, rec)
+		}
+	}
+	if len(kept) != len(recs) {
+		if err := v.index.Set(name, kept); err != nil {
+			l.Debugln("updating version index:", err)
+		}
+	}
+}
+
+func (v *indexed) String() string {
+	return "versioner/indexed"
+}
@@ -0,0 +1,71 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package versioner
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/db"
+	"github.com/syncthing/syncthing/lib/db/backend"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/protocol"
+)
+
+func TestIndexedVersioner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ffs := fs.NewFilesystem(fs.FilesystemTypeBasic, dir)
+
+	ldb := db.NewLowlevel(backend.OpenMemory())
+	defer ldb.Close()
+	index := db.NewVersionIndex(ldb, "default")
+	source := func(name string) protocol.ShortID { return protocol.ShortID(42) }
+	v := NewIndexed(newSimple(ffs, map[string]string{"keep": "5"}), ffs, index, source)
+
+	writeFile(t, ffs, "a", "version one")
+	must(t, v.Archive("a"))
+
+	recs, err := index.Versions("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 1 || recs[0].Size != int64(len("version one")) || recs[0].Device != 42 {
+		t.Fatalf("Unexpected records %+v", recs)
+	}
+
+	versions, err := v.GetVersions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions["a"]) != 1 || versions["a"][0].Device != protocol.ShortID(42).String() {
+		t.Fatalf("Unexpected versions %+v", versions)
+	}
+
+	must(t, v.Restore("a", versions["a"][0].VersionTime))
+	if recs, err := index.Versions("a"); err != nil {
+		t.Fatal(err)
+	} else if len(recs) != 0 {
+		t.Errorf("Expected the restored version to be forgotten, got %+v", recs)
+	}
+
+	// Records of versions removed behind our back are dropped when listing.
+	must(t, v.Archive("a"))
+	must(t, ffs.RemoveAll(".stversions"))
+	if _, err := v.GetVersions(); err != nil {
+		t.Fatal(err)
+	}
+	if recs, err := index.Versions("a"); err != nil {
+		t.Fatal(err)
+	} else if len(recs) != 0 {
+		t.Errorf("Expected stale records to be dropped, got %+v", recs)
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package versioner
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/thejerf/suture"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/ignore"
+)
+
+// The overriding versioner routes each file to the versioner of the first
+// override whose pattern matches it, or else to that of the folder. Where
+// there is no versioning, files are just removed. It runs the versioners
+// that are services as its own.
+type overriding struct {
+	*suture.Supervisor
+	filesystem fs.Filesystem
+	vers       []Versioner
+	def        int // index in vers, -1 for no versioning
+	overrides  []override
+}
+
+type override struct {
+	matcher *ignore.Matcher
+	ver     int // index in vers, -1 for no versioning
+}
+
+func newOverriding(folderID string, filesystem fs.Filesystem, cfg config.VersioningConfiguration) (Versioner, error) {
+	v := &overriding{
+		Supervisor: suture.New("versioner/overriding", suture.Spec{
+			PassThroughPanics: true,
+		}),
+		filesystem: filesystem,
+	}
+
+	var err error
+	if v.def, err = v.newVersioner(folderID, config.VersioningConfiguration{Type: cfg.Type, Params: cfg.Params}); err != nil {
+		return nil, err
+	}
+	for _, o := range cfg.Overrides {
+		matcher := ignore.New(filesystem, ignore.WithCache(false))
+		if err := matcher.Parse(strings.NewReader(o.Pattern), ""); err != nil {
+			return nil, fmt.Errorf("versioning override %q: %v", o.Pattern, err)
+		}
+		ver, err := v.newVersioner(folderID, o.Config())
+		if err != nil {
+			return nil, fmt.Errorf("versioning override %q: %v", o.Pattern, err)
+		}
+		v.overrides = append(v.overrides, override{matcher, ver})
+	}
+
+	l.Debugf("instantiated overriding versioner with %d overrides", len(v.overrides))
+	return v, nil
+}
+
+// newVersioner adds the versioner for the configuration, returning its
+// index.
+func (v *overriding) newVersioner(folderID string, cfg config.VersioningConfiguration) (int, error) {
+	if cfg.Type == "" {
+		return -1, nil
+	}
+	ver, err := New(folderID, v.filesystem, cfg)
+	if err != nil {
+		return -1, err
+	}
+	if service, ok := ver.(suture.Service); ok {
+		v.Add(service)
+	}
+	v.vers = append(v.vers, ver)
+	return len(v.vers) - 1, nil
+}
+
+// index returns the index of the versioner for the file, -1 for none.
+func (v *overriding) index(filePath string) int {
+	for _, o := range v.overrides {
+		if o.matcher.Match(filePath).IsIgnored() {
+			return o.ver
+		}
+	}
+	return v.def
+}
+
+// versionerFor returns the versioner for the file, nil for none.
+func (v *overriding) versionerFor(filePath string) Versioner {
+	if i := v.index(filePath); i >= 0 {
+		return v.vers[i]
+	}
+	return nil
+}
+
+func (v *overriding) Archive(filePath string) error {
+	if ver := v.versionerFor(filePath); ver != nil {
+		return ver.Archive(filePath)
+	}
+	if err := v.filesystem.Remove(filePath); err != nil && !fs.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// GetVersions lists the versions of each file kept by the versioner the
+// file is routed to, as versioners may share an archive.
+func (v *overriding) GetVersions() (map[string][]FileVersion, error) {
+	res := make(map[string][]FileVersion)
+	supported := false
+	for i, ver := range v.vers {
+		versions, err := ver.GetVersions()
+		if err == ErrRestorationNotSupported {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		supported = true
+		for file, fileVersions := range versions {
+			if v.index(file) == i {
+				res[file] = fileVersions
+			}
+		}
+	}
+	if !supported {
+		return nil, ErrRestorationNotSupported
+	}
+	return res, nil
+}
+
+func (v *overriding) Restore(filePath string, versionTime time.Time) error {
+	ver := v.versionerFor(filePath)
+	if ver == nil {
+		return ErrRestorationNotSupported
+	}
+	return ver.Restore(filePath, versionTime)
+}
+
+func (v *overriding) OpenVersion(filePath string, versionTime time.Time) (fs.File, error) {
+	va, ok := v.versionerFor(filePath).(VersionAccessor)
+	if !ok {
+		return nil, ErrRestorationNotSupported
+	}
+	return va.OpenVersion(filePath, versionTime)
+}
+
+func (v *overriding) RestoreTo(filePath string, versionTime time.Time, target string) error {
+	va, ok := v.versionerFor(filePath).(VersionAccessor)
+	if !ok {
+		return ErrRestorationNotSupported
+	}
+	return va.RestoreTo(filePath, versionTime, target)
+}
+
+func (v *overriding) Clean() (CleanupResult, error) {
+	var res CleanupResult
+	for _, ver := range v.vers {
+		cleaner, ok := ver.(Cleaner)
+		if !ok {
+			continue
+		}
+		cres, err := cleaner.Clean()
+		res.add(cres)
+		if err != nil {
+			return res, err
+		}
+	}
+	return res, nil
+}
+
+func (v *overriding) String() string {
+	return "versioner/overriding"
+}
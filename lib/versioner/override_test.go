@@ -0,0 +1,69 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package versioner
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/fs"
+)
+
+func TestOverridingVersioner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ffs := fs.NewFilesystem(fs.FilesystemTypeBasic, dir)
+	cfg := config.VersioningConfiguration{
+		Type:   "trashcan",
+		Params: map[string]string{},
+		Overrides: []config.VersioningOverride{
+			{Pattern: "cache"},
+			{Pattern: "documents", Type: "simple", Params: map[string]string{"keep": "2"}},
+		},
+	}
+	v, err := New("default", ffs, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := []string{filepath.Join("cache", "a"), filepath.Join("documents", "b"), "c"}
+	for _, file := range files {
+		must(t, ffs.MkdirAll(filepath.Dir(file), 0755))
+		fd, err := ffs.Create(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fd.Close()
+		if err := v.Archive(file); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ffs.Lstat(file); !fs.IsNotExist(err) {
+			t.Errorf("%v wasn't archived: %v", file, err)
+		}
+	}
+
+	versions, err := v.GetVersions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Nothing of the cache, as it isn't versioned, and each of the others
+	// once even as the archive is shared.
+	if len(versions) != 2 || len(versions[files[1]]) != 1 || len(versions[files[2]]) != 1 {
+		t.Errorf("Unexpected versions %v", versions)
+	}
+
+	cfg.Overrides = append(cfg.Overrides, config.VersioningOverride{Pattern: "other", Type: "nonexistent"})
+	if _, err := New("default", ffs, cfg); err == nil {
+		t.Error("Expected an error for an override of an unknown type")
+	}
+}
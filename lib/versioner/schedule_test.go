@@ -0,0 +1,52 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package versioner
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	// A Wednesday
+	now := time.Date(2019, 10, 16, 10, 20, 30, 0, time.UTC)
+	cases := []struct {
+		expr string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2019, 10, 16, 10, 21, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2019, 10, 16, 10, 30, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2019, 10, 17, 3, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2019, 10, 17, 0, 0, 0, 0, time.UTC)},
+		{"30 2 * * 0", time.Date(2019, 10, 20, 2, 30, 0, 0, time.UTC)},
+		{"30 2 * * 7", time.Date(2019, 10, 20, 2, 30, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2019, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1-5,20 2 *", time.Date(2020, 2, 1, 12, 0, 0, 0, time.UTC)},
+		// Either day will do when both are given
+		{"0 0 31 * 5", time.Date(2019, 10, 18, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tc := range cases {
+		s, err := parseSchedule(tc.expr)
+		if err != nil {
+			t.Errorf("%q: %v", tc.expr, err)
+			continue
+		}
+		if next := s.next(now); !next.Equal(tc.next) {
+			t.Errorf("%q: next is %v, expected %v", tc.expr, next, tc.next)
+		}
+	}
+}
+
+func TestParseScheduleInvalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "5-1 * * * *", "*/0 * * * *", "a * * * *"} {
+		if _, err := parseSchedule(expr); err == nil {
+			t.Errorf("%q should be invalid", expr)
+		}
+	}
+}
@@ -7,6 +7,7 @@
 package versioner
 
 import (
+	"sort"
 	"strconv"
 	"time"
 
@@ -15,11 +16,12 @@ import (
 
 func init() {
 	// Register the constructor for this type of versioner with the name "simple"
-	factories["simple"] = newSimple
+	Register("simple", newSimple)
 }
 
 type simple struct {
 	keep       int
+	maxSize    int64
 	folderFs   fs.Filesystem
 	versionsFs fs.Filesystem
 }
@@ -32,6 +34,7 @@ func newSimple(folderFs fs.Filesystem, params map[string]string) Versioner {
 
 	s := simple{
 		keep:       keep,
+		maxSize:    parseMaxSize(params),
 		folderFs:   folderFs,
 		versionsFs: fsFromParams(folderFs, params),
 	}
@@ -48,19 +51,66 @@ func (v simple) Archive(filePath string) error {
 		return err
 	}
 
-	// Versions are sorted by timestamp in the file name, oldest first.
-	versions := findAllVersions(v.versionsFs, filePath)
-	if len(versions) > v.keep {
-		for _, toRemove := range versions[:len(versions)-v.keep] {
-			l.Debugln("cleaning out", toRemove)
+	v.expire(findAllVersions(v.versionsFs, filePath))
+	enforceMaxSize(v.versionsFs, v.maxSize)
+
+	return nil
+}
+
+// expire removes all but the newest versions to keep of a file, given its
+// versions sorted by the timestamp in the file name, oldest first.
+func (v simple) expire(versions []string) CleanupResult {
+	var res CleanupResult
+	if len(versions) <= v.keep {
+		return res
+	}
+	for _, toRemove := range versions[:len(versions)-v.keep] {
+		l.Debugln("cleaning out", toRemove)
+		info, err := v.versionsFs.Lstat(toRemove)
+		if err == nil {
 			err = v.versionsFs.Remove(toRemove)
-			if err != nil {
-				l.Warnln("removing old version:", err)
-			}
 		}
+		if err != nil {
+			l.Warnln("removing old version:", err)
+			continue
+		}
+		res.removed(info.Size())
 	}
+	return res
+}
 
-	return nil
+// Clean removes the versions over the number to keep of each file and the
+// maximum size, as archiving does for the file archived. Versions archived
+// with a different configuration are pruned this way.
+func (v simple) Clean() (CleanupResult, error) {
+	var res CleanupResult
+	if _, err := v.versionsFs.Lstat("."); fs.IsNotExist(err) {
+		return res, nil
+	}
+
+	versionsPerFile := make(map[string][]string)
+	err := v.versionsFs.Walk(".", func(path string, f fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !f.IsRegular() {
+			return nil
+		}
+		if name, _ := UntagFilename(path); name != "" {
+			versionsPerFile[name] = append(versionsPerFile[name], path)
+		}
+		return nil
+	})
+	if err != nil {
+		return res, err
+	}
+
+	for _, versions := range versionsPerFile {
+		sort.Strings(versions)
+		res.add(v.expire(versions))
+	}
+	res.add(enforceMaxSize(v.versionsFs, v.maxSize))
+	return res, nil
 }
 
 func (v simple) GetVersions() (map[string][]FileVersion, error) {
@@ -70,3 +120,11 @@ func (v simple) GetVersions() (map[string][]FileVersion, error) {
 func (v simple) Restore(filepath string, versionTime time.Time) error {
 	return restoreFile(v.versionsFs, v.folderFs, filepath, versionTime, TagFilename)
 }
+
+func (v simple) OpenVersion(filePath string, versionTime time.Time) (fs.File, error) {
+	return openVersion(v.versionsFs, filePath, versionTime)
+}
+
+func (v simple) RestoreTo(filePath string, versionTime time.Time, target string) error {
+	return restoreVersionTo(v.versionsFs, v.folderFs, filePath, versionTime, target)
+}
@@ -9,6 +9,7 @@ package versioner
 import (
 	"io/ioutil"
 	"math"
+	"os"
 	"path/filepath"
 	"testing"
 	"time"
@@ -87,3 +88,107 @@ func TestSimpleVersioningVersionCount(t *testing.T) {
 		time.Sleep(time.Second)
 	}
 }
+
+func TestSimpleOpenAndRestoreVersionTo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fs := fs.NewFilesystem(fs.FilesystemTypeBasic, dir)
+	v := newSimple(fs, map[string]string{"keep": "2"})
+	va := v.(VersionAccessor)
+
+	path := filepath.Join("dir", "test.txt")
+	if err := fs.MkdirAll("dir", 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := fs.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("first version"))
+	f.Close()
+	if err := v.Archive(path); err != nil {
+		t.Fatal(err)
+	}
+
+	versions, err := v.GetVersions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions[path]) != 1 {
+		t.Fatalf("got versions %v", versions)
+	}
+	versionTime := versions[path][0].VersionTime
+
+	fd, err := va.OpenVersion(path, versionTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bs, err := ioutil.ReadAll(fd)
+	fd.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bs) != "first version" {
+		t.Errorf("got %q", bs)
+	}
+	if _, err := va.OpenVersion(path, versionTime.Add(-time.Hour)); err != errNotFound {
+		t.Errorf("opening a version that doesn't exist: got %v, expected %v", err, errNotFound)
+	}
+
+	target := filepath.Join("other", "restored.txt")
+	if err := va.RestoreTo(path, versionTime, target); err != nil {
+		t.Fatal(err)
+	}
+	if bs, err := ioutil.ReadFile(filepath.Join(dir, target)); err != nil || string(bs) != "first version" {
+		t.Errorf("restored %q, %v", bs, err)
+	}
+	if versions, _ := v.GetVersions(); len(versions[path]) != 1 {
+		t.Errorf("version gone after restoring a copy: %v", versions)
+	}
+
+	if err := va.RestoreTo(path, versionTime, target); err != errFileAlreadyExists {
+		t.Errorf("restoring on top of a file: got %v, expected %v", err, errFileAlreadyExists)
+	}
+	if err := va.RestoreTo(path, versionTime, filepath.Join("..", "outside.txt")); err == nil {
+		t.Error("restoring outside of the folder should fail")
+	}
+}
+
+func TestEnforceMaxSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	versionsFs := fs.NewFilesystem(fs.FilesystemTypeBasic, dir)
+	now := time.Now()
+	// Oldest first, whatever the names
+	versions := []string{
+		TagFilename("b.txt", now.Add(-3*time.Hour).Format(TimeFormat)),
+		TagFilename("a.txt", now.Add(-2*time.Hour).Format(TimeFormat)),
+		TagFilename("b.txt", now.Add(-time.Hour).Format(TimeFormat)),
+		TagFilename("a.txt", now.Format(TimeFormat)),
+	}
+	for _, name := range versions {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), make([]byte, 100), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if size := parseMaxSize(map[string]string{"maxSize": "0.25 kB"}); size != 250 {
+		t.Fatalf("got max size %d, expected 250", size)
+	}
+	enforceMaxSize(versionsFs, 250)
+
+	for i, name := range versions {
+		_, err := versionsFs.Lstat(name)
+		if kept := err == nil; kept != (i >= 2) {
+			t.Errorf("version %d (%s): kept=%v", i, name, kept)
+		}
+	}
+}
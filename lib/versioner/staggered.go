@@ -22,7 +22,7 @@ import (
 
 func init() {
 	// Register the constructor for this type of versioner with the name "staggered"
-	factories["staggered"] = newStaggered
+	Register("staggered", newStaggered)
 }
 
 type interval struct {
@@ -33,6 +33,8 @@ type interval struct {
 type staggered struct {
 	suture.Service
 	cleanInterval int64
+	schedule      *schedule
+	maxSize       int64
 	folderFs      fs.Filesystem
 	versionsFs    fs.Filesystem
 	interval      [4]interval
@@ -57,6 +59,8 @@ func newStaggered(folderFs fs.Filesystem, params map[string]string) Versioner {
 
 	s := &staggered{
 		cleanInterval: cleanInterval,
+		schedule:      parseCleanupSchedule(params),
+		maxSize:       parseMaxSize(params),
 		folderFs:      folderFs,
 		versionsFs:    versionsFs,
 		interval: [4]interval{
@@ -74,32 +78,50 @@ func newStaggered(folderFs fs.Filesystem, params map[string]string) Versioner {
 }
 
 func (v *staggered) serve(ctx context.Context) {
-	v.clean()
+	// Without a schedule we clean up at startup and then every interval
+	if v.schedule == nil {
+		v.cleanAndLog()
+	}
 	if v.testCleanDone != nil {
 		close(v.testCleanDone)
 	}
 
-	tck := time.NewTicker(time.Duration(v.cleanInterval) * time.Second)
-	defer tck.Stop()
+	interval := time.Duration(v.cleanInterval) * time.Second
+	timer := time.NewTimer(cleanupDelay(v.schedule, interval, time.Now()))
+	defer timer.Stop()
 	for {
 		select {
-		case <-tck.C:
-			v.clean()
+		case <-timer.C:
+			v.cleanAndLog()
+			timer.Reset(cleanupDelay(v.schedule, interval, time.Now()))
 		case <-ctx.Done():
 			return
 		}
 	}
 }
 
-func (v *staggered) clean() {
+func (v *staggered) cleanAndLog() {
+	res, err := v.Clean()
+	if err != nil {
+		l.Warnln("Versioner: error scanning versions dir", err)
+		return
+	}
+	if res.Files > 0 {
+		l.Infof("Versioner: cleaned up %d versions (%d bytes) in %v", res.Files, res.Bytes, v.versionsFs)
+	}
+}
+
+// Clean expires the versions by the intervals and the maximum size.
+func (v *staggered) Clean() (CleanupResult, error) {
 	l.Debugln("Versioner clean: Waiting for lock on", v.versionsFs)
 	v.mutex.Lock()
 	defer v.mutex.Unlock()
 	l.Debugln("Versioner clean: Cleaning", v.versionsFs)
 
+	var res CleanupResult
 	if _, err := v.versionsFs.Stat("."); fs.IsNotExist(err) {
 		// There is no need to clean a nonexistent dir.
-		return
+		return res, nil
 	}
 
 	versionsPerFile := make(map[string][]string)
@@ -129,23 +151,26 @@ func (v *staggered) clean() {
 	}
 
 	if err := v.versionsFs.Walk(".", walkFn); err != nil {
-		l.Warnln("Versioner: error scanning versions dir", err)
-		return
+		return res, err
 	}
 
 	for _, versionList := range versionsPerFile {
-		v.expire(versionList)
+		res.add(v.expire(versionList))
 	}
+	res.add(enforceMaxSize(v.versionsFs, v.maxSize))
 
 	dirTracker.deleteEmptyDirs(v.versionsFs)
 
 	l.Debugln("Cleaner: Finished cleaning", v.versionsFs)
+	return res, nil
 }
 
-func (v *staggered) expire(versions []string) {
+func (v *staggered) expire(versions []string) CleanupResult {
 	l.Debugln("Versioner: Expiring versions", versions)
+	var res CleanupResult
 	for _, file := range v.toRemove(versions, time.Now()) {
-		if fi, err := v.versionsFs.Lstat(file); err != nil {
+		fi, err := v.versionsFs.Lstat(file)
+		if err != nil {
 			l.Warnln("versioner:", err)
 			continue
 		} else if fi.IsDir() {
@@ -155,8 +180,11 @@ func (v *staggered) expire(versions []string) {
 
 		if err := v.versionsFs.Remove(file); err != nil {
 			l.Warnf("Versioner: can't remove %q: %v", file, err)
+			continue
 		}
+		res.removed(fi.Size())
 	}
+	return res
 }
 
 func (v *staggered) toRemove(versions []string, now time.Time) []string {
@@ -221,6 +249,7 @@ func (v *staggered) Archive(filePath string) error {
 	}
 
 	v.expire(findAllVersions(v.versionsFs, filePath))
+	enforceMaxSize(v.versionsFs, v.maxSize)
 
 	return nil
 }
@@ -233,6 +262,14 @@ func (v *staggered) Restore(filepath string, versionTime time.Time) error {
 	return restoreFile(v.versionsFs, v.folderFs, filepath, versionTime, TagFilename)
 }
 
+func (v *staggered) OpenVersion(filePath string, versionTime time.Time) (fs.File, error) {
+	return openVersion(v.versionsFs, filePath, versionTime)
+}
+
+func (v *staggered) RestoreTo(filePath string, versionTime time.Time, target string) error {
+	return restoreVersionTo(v.versionsFs, v.folderFs, filePath, versionTime, target)
+}
+
 func (v *staggered) String() string {
 	return fmt.Sprintf("Staggered/@%p", v)
 }
@@ -0,0 +1,59 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package versioner
+
+import (
+	"time"
+
+	"github.com/syncthing/syncthing/lib/fs"
+)
+
+func init() {
+	// Register the constructor for this type of versioner with the name "systemtrash"
+	Register("systemtrash", newSystemTrash)
+}
+
+// systemTrash moves files to the trash of the operating system, so they
+// are restored from there with the usual tools rather than by us.
+type systemTrash struct {
+	folderFs fs.Filesystem
+}
+
+func newSystemTrash(folderFs fs.Filesystem, params map[string]string) Versioner {
+	s := systemTrash{
+		folderFs: folderFs,
+	}
+
+	l.Debugf("instantiated %#v", s)
+	return s
+}
+
+// Archive moves the named file away to the system trash. If this function
+// returns nil, the named file does not exist any more (has been archived).
+func (v systemTrash) Archive(filePath string) error {
+	info, err := v.folderFs.Lstat(filePath)
+	if fs.IsNotExist(err) {
+		l.Debugln("not archiving nonexistent file", filePath)
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if info.IsSymlink() {
+		panic("bug: attempting to version a symlink")
+	}
+
+	l.Debugln("moving to system trash", filePath)
+	return fs.MoveToTrash(v.folderFs, filePath)
+}
+
+func (v systemTrash) GetVersions() (map[string][]FileVersion, error) {
+	return nil, ErrRestorationNotSupported
+}
+
+func (v systemTrash) Restore(filePath string, versionTime time.Time) error {
+	return ErrRestorationNotSupported
+}
@@ -20,7 +20,7 @@ import (
 
 func init() {
 	// Register the constructor for this type of versioner
-	factories["trashcan"] = newTrashcan
+	Register("trashcan", newTrashcan)
 }
 
 type trashcan struct {
@@ -28,6 +28,7 @@ type trashcan struct {
 	folderFs     fs.Filesystem
 	versionsFs   fs.Filesystem
 	cleanoutDays int
+	schedule     *schedule
 }
 
 func newTrashcan(folderFs fs.Filesystem, params map[string]string) Versioner {
@@ -38,6 +39,7 @@ func newTrashcan(folderFs fs.Filesystem, params map[string]string) Versioner {
 		folderFs:     folderFs,
 		versionsFs:   fsFromParams(folderFs, params),
 		cleanoutDays: cleanoutDays,
+		schedule:     parseCleanupSchedule(params),
 	}
 	s.Service = util.AsService(s.serve, s.String())
 
@@ -57,8 +59,9 @@ func (t *trashcan) serve(ctx context.Context) {
 	l.Debugln(t, "starting")
 	defer l.Debugln(t, "stopping")
 
-	// Do the first cleanup one minute after startup.
-	timer := time.NewTimer(time.Minute)
+	// Do the first cleanup one minute after startup, unless there's a
+	// schedule for it.
+	timer := time.NewTimer(cleanupDelay(t.schedule, time.Minute, time.Now()))
 	defer timer.Stop()
 
 	for {
@@ -67,25 +70,35 @@ func (t *trashcan) serve(ctx context.Context) {
 			return
 
 		case <-timer.C:
-			if t.cleanoutDays > 0 {
-				if err := t.cleanoutArchive(); err != nil {
-					l.Infoln("Cleaning trashcan:", err)
-				}
+			if res, err := t.Clean(); err != nil {
+				l.Infoln("Cleaning trashcan:", err)
+			} else if res.Files > 0 {
+				l.Infof("Cleaned up %d files (%d bytes) from trashcan %v", res.Files, res.Bytes, t.versionsFs)
 			}
 
 			// Cleanups once a day should be enough.
-			timer.Reset(24 * time.Hour)
+			timer.Reset(cleanupDelay(t.schedule, 24*time.Hour, time.Now()))
 		}
 	}
 }
 
+// Clean removes the files that have been in the trash can for longer than
+// the configured number of days, if any.
+func (t *trashcan) Clean() (CleanupResult, error) {
+	if t.cleanoutDays <= 0 {
+		return CleanupResult{}, nil
+	}
+	return t.cleanoutArchive()
+}
+
 func (t *trashcan) String() string {
 	return fmt.Sprintf("trashcan@%p", t)
 }
 
-func (t *trashcan) cleanoutArchive() error {
+func (t *trashcan) cleanoutArchive() (CleanupResult, error) {
+	var res CleanupResult
 	if _, err := t.versionsFs.Lstat("."); fs.IsNotExist(err) {
-		return nil
+		return res, nil
 	}
 
 	cutoff := time.Now().Add(time.Duration(-24*t.cleanoutDays) * time.Hour)
@@ -103,7 +116,9 @@ func (t *trashcan) cleanoutArchive() error {
 
 		if info.ModTime().Before(cutoff) {
 			// The file is too old; remove it.
-			err = t.versionsFs.Remove(path)
+			if err = t.versionsFs.Remove(path); err == nil {
+				res.removed(info.Size())
+			}
 		} else {
 			// Keep this file, and remember it so we don't unnecessarily try
 			// to remove this directory.
@@ -113,12 +128,12 @@ func (t *trashcan) cleanoutArchive() error {
 	}
 
 	if err := t.versionsFs.Walk(".", walkFn); err != nil {
-		return err
+		return res, err
 	}
 
 	dirTracker.deleteEmptyDirs(t.versionsFs)
 
-	return nil
+	return res, nil
 }
 
 func (t *trashcan) GetVersions() (map[string][]FileVersion, error) {
@@ -151,3 +166,11 @@ func (t *trashcan) Restore(filepath string, versionTime time.Time) error {
 
 	return t.versionsFs.Rename(taggedName, filepath)
 }
+
+func (t *trashcan) OpenVersion(filePath string, versionTime time.Time) (fs.File, error) {
+	return openVersion(t.versionsFs, filePath, versionTime)
+}
+
+func (t *trashcan) RestoreTo(filePath string, versionTime time.Time, target string) error {
+	return restoreVersionTo(t.versionsFs, t.folderFs, filePath, versionTime, target)
+}
@@ -54,9 +54,13 @@ func TestTrashcanCleanout(t *testing.T) {
 	}
 
 	versioner := newTrashcan(fs.NewFilesystem(fs.FilesystemTypeBasic, "testdata"), map[string]string{"cleanoutDays": "7"}).(*trashcan)
-	if err := versioner.cleanoutArchive(); err != nil {
+	res, err := versioner.cleanoutArchive()
+	if err != nil {
 		t.Fatal(err)
 	}
+	if res.Files != 5 || res.Bytes != 5*4 {
+		t.Errorf("cleanup removed %d files, %d bytes; expected 5 files, 20 bytes", res.Files, res.Bytes)
+	}
 
 	for _, tc := range testcases {
 		_, err := os.Lstat(tc.file)
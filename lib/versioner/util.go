@@ -15,6 +15,7 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"github.com/syncthing/syncthing/lib/config"
 	"github.com/syncthing/syncthing/lib/fs"
 	"github.com/syncthing/syncthing/lib/osutil"
 	"github.com/syncthing/syncthing/lib/util"
@@ -251,6 +252,55 @@ func restoreFile(src, dst fs.Filesystem, filePath string, versionTime time.Time,
 	return err
 }
 
+// findVersion returns the name in the archive of the version of the file
+// from the given time. Versions are named with a tag, or not in the case of
+// the latest one in a trashcan, when the version time is its mtime.
+func findVersion(src fs.Filesystem, filePath string, versionTime time.Time) (string, fs.FileInfo, error) {
+	filePath = osutil.NativeFilename(filePath)
+	tag := versionTime.In(time.Local).Truncate(time.Second).Format(TimeFormat)
+	taggedFilePath := TagFilename(filePath, tag)
+	if info, err := src.Lstat(taggedFilePath); err == nil && info.IsRegular() {
+		return taggedFilePath, info, nil
+	}
+	if info, err := src.Lstat(filePath); err == nil && info.IsRegular() && info.ModTime().Truncate(time.Second).Equal(versionTime) {
+		return filePath, info, nil
+	}
+	return "", nil, errNotFound
+}
+
+func openVersion(src fs.Filesystem, filePath string, versionTime time.Time) (fs.File, error) {
+	name, _, err := findVersion(src, filePath, versionTime)
+	if err != nil {
+		return nil, err
+	}
+	return src.Open(name)
+}
+
+// restoreVersionTo copies the version of the file to the target, which
+// must not exist yet.
+func restoreVersionTo(src, dst fs.Filesystem, filePath string, versionTime time.Time, target string) error {
+	target, err := fs.Canonicalize(osutil.NativeFilename(target))
+	if err != nil {
+		return err
+	}
+	name, info, err := findVersion(src, filePath, versionTime)
+	if err != nil {
+		return err
+	}
+	if _, err := dst.Lstat(target); err == nil {
+		return errFileAlreadyExists
+	} else if !fs.IsNotExist(err) {
+		return err
+	}
+
+	_ = dst.MkdirAll(filepath.Dir(target), 0755)
+	if err := osutil.Copy(src, dst, name, target); err != nil {
+		return err
+	}
+	_ = dst.Chtimes(target, info.ModTime(), info.ModTime())
+	return nil
+}
+
 func fsFromParams(folderFs fs.Filesystem, params map[string]string) (versionsFs fs.Filesystem) {
 	if params["fsType"] == "" && params["fsPath"] == "" {
 		versionsFs = fs.NewFilesystem(folderFs.Type(), filepath.Join(folderFs.URI(), ".stversions"))
@@ -272,6 +322,82 @@ func fsFromParams(folderFs fs.Filesystem, params map[string]string) (versionsFs
 	return
 }
 
+// parseMaxSize returns the total size in bytes that the versions of a
+// folder may take, given by the "maxSize" param as in "10 GB". Zero means
+// no limit.
+func parseMaxSize(params map[string]string) int64 {
+	size, err := config.ParseSize(params["maxSize"])
+	if err != nil || size.Percentage() {
+		if params["maxSize"] != "" {
+			l.Warnf("Versioner: ignoring invalid maximum size %q", params["maxSize"])
+		}
+		return 0
+	}
+	return int64(size.BaseValue())
+}
+
+type sizedVersion struct {
+	path        string
+	versionTime time.Time
+	size        int64
+}
+
+// enforceMaxSize removes versions, oldest first, until all of them together
+// take at most maxSize bytes, and returns what it removed.
+func enforceMaxSize(versionsFs fs.Filesystem, maxSize int64) CleanupResult {
+	var res CleanupResult
+	if maxSize <= 0 {
+		return res
+	}
+
+	var versions []sizedVersion
+	var total int64
+	err := versionsFs.Walk(".", func(path string, f fs.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !f.IsRegular() {
+			return nil
+		}
+		versionTime := f.ModTime()
+		if tag := extractTag(path); tag != "" {
+			if t, err := time.ParseInLocation(TimeFormat, tag, time.Local); err == nil {
+				versionTime = t
+			}
+		}
+		versions = append(versions, sizedVersion{path, versionTime, f.Size()})
+		total += f.Size()
+		return nil
+	})
+	if err != nil {
+		l.Warnln("Versioner: error scanning versions dir", err)
+		return res
+	}
+	if total <= maxSize {
+		return res
+	}
+
+	sort.Slice(versions, func(a, b int) bool {
+		if versions[a].versionTime.Equal(versions[b].versionTime) {
+			return versions[a].path < versions[b].path
+		}
+		return versions[a].versionTime.Before(versions[b].versionTime)
+	})
+	for _, v := range versions {
+		if total <= maxSize {
+			break
+		}
+		l.Debugln("Versioner: over maximum size -> delete", v.path)
+		if err := versionsFs.Remove(v.path); err != nil {
+			l.Warnf("Versioner: can't remove %q: %v", v.path, err)
+			continue
+		}
+		total -= v.size
+		res.removed(v.size)
+	}
+	return res
+}
+
 func findAllVersions(fs fs.Filesystem, filePath string) []string {
 	inFolderPath := filepath.Dir(filePath)
 	file := filepath.Base(filePath)
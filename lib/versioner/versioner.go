@@ -10,10 +10,12 @@ package versioner
 
 import (
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/syncthing/syncthing/lib/config"
 	"github.com/syncthing/syncthing/lib/fs"
+	"github.com/syncthing/syncthing/lib/sync"
 )
 
 type Versioner interface {
@@ -22,15 +24,85 @@ type Versioner interface {
 	Restore(filePath string, versionTime time.Time) error
 }
 
+// A VersionAccessor is a versioner that also gives access to the contents
+// of the archived versions, and restores them to other places than where
+// they were archived from. Not all versioners keep versions where they can
+// do that.
+type VersionAccessor interface {
+	// OpenVersion opens the version of the file from the given time.
+	OpenVersion(filePath string, versionTime time.Time) (fs.File, error)
+	// RestoreTo restores a copy of the version of the file from the given
+	// time to the target path in the folder, keeping the version archived.
+	RestoreTo(filePath string, versionTime time.Time, target string) error
+}
+
+// A Cleaner is a versioner that prunes the versions it keeps, the ones that
+// are too old or too many by its configuration, on its own schedule and on
+// request.
+type Cleaner interface {
+	// Clean prunes the versions now.
+	Clean() (CleanupResult, error)
+}
+
+// CleanupResult is what a cleanup pruned.
+type CleanupResult struct {
+	Files int   `json:"files"`
+	Bytes int64 `json:"bytes"`
+}
+
+func (r *CleanupResult) removed(size int64) {
+	r.Files++
+	r.Bytes += size
+}
+
+func (r *CleanupResult) add(other CleanupResult) {
+	r.Files += other.Files
+	r.Bytes += other.Bytes
+}
+
 type FileVersion struct {
 	VersionTime time.Time `json:"versionTime"`
 	ModTime     time.Time `json:"modTime"`
 	Size        int64     `json:"size"`
+	Device      string    `json:"device,omitempty"` // the short ID of the device it came from, if known
 }
 
-type factory func(filesystem fs.Filesystem, params map[string]string) Versioner
+// A Factory returns a versioner for the folder on the given filesystem,
+// configured by the params of its versioning configuration.
+type Factory func(filesystem fs.Filesystem, params map[string]string) Versioner
 
-var factories = make(map[string]factory)
+var (
+	factories    = make(map[string]Factory)
+	factoriesMut = sync.NewRWMutex()
+)
+
+// Register makes a type of versioner available under the given name, to
+// be chosen by the type in a folder's versioning configuration. It's meant
+// to be called from an init function, for builds that bring their own
+// versioner. It panics if the name is empty or already taken.
+func Register(name string, factory Factory) {
+	if name == "" || factory == nil {
+		panic("versioner: registering without a name or factory")
+	}
+	factoriesMut.Lock()
+	defer factoriesMut.Unlock()
+	if _, ok := factories[name]; ok {
+		panic(fmt.Sprintf("versioner: %q registered twice", name))
+	}
+	factories[name] = factory
+}
+
+// Types returns the names of the registered types of versioner, sorted.
+func Types() []string {
+	factoriesMut.RLock()
+	defer factoriesMut.RUnlock()
+	types := make([]string, 0, len(factories))
+	for name := range factories {
+		types = append(types, name)
+	}
+	sort.Strings(types)
+	return types
+}
 
 var ErrRestorationNotSupported = fmt.Errorf("version restoration not supported with the current versioner")
 
@@ -39,11 +111,23 @@ const (
 	timeGlob   = "[0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9]-[0-9][0-9][0-9][0-9][0-9][0-9]" // glob pattern matching TimeFormat
 )
 
-func New(fs fs.Filesystem, cfg config.VersioningConfiguration) (Versioner, error) {
+// New returns the versioner for the folder with the given ID. Its params are
+// those of the configuration, plus the folder ID as "folderID", for the
+// versioners that pass it on. With overrides, it's one routing each file to
+// the versioner configured for it.
+func New(folderID string, fs fs.Filesystem, cfg config.VersioningConfiguration) (Versioner, error) {
+	if len(cfg.Overrides) > 0 {
+		return newOverriding(folderID, fs, cfg)
+	}
+
+	factoriesMut.RLock()
 	fac, ok := factories[cfg.Type]
+	factoriesMut.RUnlock()
 	if !ok {
 		return nil, fmt.Errorf("requested versioning type %q does not exist", cfg.Type)
 	}
 
-	return fac(fs, cfg.Params), nil
+	params := cfg.Copy().Params
+	params["folderID"] = folderID
+	return fac(fs, params), nil
 }
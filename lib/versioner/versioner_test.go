@@ -0,0 +1,55 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+package versioner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/syncthing/syncthing/lib/config"
+	"github.com/syncthing/syncthing/lib/fs"
+)
+
+type customVersioner struct {
+	params map[string]string
+}
+
+func (customVersioner) Archive(string) error                           { return nil }
+func (customVersioner) GetVersions() (map[string][]FileVersion, error) { return nil, nil }
+func (customVersioner) Restore(string, time.Time) error                { return nil }
+
+func TestRegister(t *testing.T) {
+	Register("custom", func(_ fs.Filesystem, params map[string]string) Versioner {
+		return customVersioner{params}
+	})
+
+	ffs := fs.NewFilesystem(fs.FilesystemTypeFake, "/TestRegister")
+	v, err := New("default", ffs, config.VersioningConfiguration{Type: "custom", Params: map[string]string{"bucket": "old"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cv, ok := v.(customVersioner); !ok || cv.params["bucket"] != "old" || cv.params["folderID"] != "default" {
+		t.Errorf("Expected the custom versioner with its params, got %#v", v)
+	}
+
+	found := false
+	for _, name := range Types() {
+		if name == "custom" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Custom versioner missing from", Types())
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected registering a name twice to panic")
+		}
+	}()
+	Register("simple", newSimple)
+}
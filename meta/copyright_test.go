@@ -31,6 +31,7 @@ var copyrightRegexps = []string{
 	`package auto`,
 	`automatically generated by genxdr`,
 	`generated by protoc`,
+	`generated by genopenapi`,
 }
 
 var copyrightRe = regexp.MustCompile(strings.Join(copyrightRegexps, "|"))
@@ -0,0 +1,233 @@
+// Copyright (C) 2026 The Syncthing Authors.
+//
+// This Source Code Form is subject to the terms of the Mozilla Public
+// License, v. 2.0. If a copy of the MPL was not distributed with this file,
+// You can obtain one at https://mozilla.org/MPL/2.0/.
+
+// +build ignore
+
+// Generates the list of REST operations the OpenAPI document is built
+// from, by reading the handler registrations in the API package source in
+// the given directory. The parameters of each endpoint come from the
+// comment on its registration line, in the form "device folder [page]
+// [sub...] <body>", and its description from the doc comment of the
+// handler method.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+var tpl = template.Must(template.New("openapi").Parse(`// Code generated by genopenapi.go - DO NOT EDIT.
+
+package api
+
+var restOperations = []restOperation{
+{{range .}}	{
+		Method: {{printf "%q" .Method}},
+		Path:   {{printf "%q" .Path}},
+{{if .Summary}}		Summary: {{printf "%q" .Summary}},
+{{end}}{{if .Description}}		Description: {{printf "%q" .Description}},
+{{end}}{{if .Params}}		Params: []restParam{
+{{range .Params}}			{Name: {{printf "%q" .Name}}{{if .Required}}, Required: true{{end}}{{if .Multi}}, Multi: true{{end}}},
+{{end}}		},
+{{end}}{{if .Body}}		Body: true,
+{{end}}{{if .Deprecated}}		Deprecated: true,
+{{end}}	},
+{{end}}}
+`))
+
+// The muxes holding the documented endpoints, and their methods.
+var muxMethods = map[string]string{
+	"getRestMux":  "get",
+	"postRestMux": "post",
+}
+
+type operation struct {
+	Method      string
+	Path        string
+	Summary     string
+	Description string
+	Params      []param
+	Body        bool
+	Deprecated  bool
+}
+
+type param struct {
+	Name     string
+	Required bool
+	Multi    bool
+}
+
+func main() {
+	out := flag.String("o", "", "Output file (default stdout)")
+	flag.Parse()
+
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, flag.Arg(0), func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go") && !strings.HasSuffix(info.Name(), "_gen.go")
+	}, parser.ParseComments)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var files []*ast.File
+	for _, pkg := range pkgs {
+		var names []string
+		for name := range pkg.Files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			files = append(files, pkg.Files[name])
+		}
+	}
+
+	docs := handlerDocs(files)
+	var ops []operation
+	for _, f := range files {
+		ops = append(ops, operations(fset, f, docs)...)
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, ops); err != nil {
+		log.Fatal(err)
+	}
+	bs, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *out == "" {
+		os.Stdout.Write(bs)
+		return
+	}
+	if err := ioutil.WriteFile(*out, bs, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// handlerDocs returns the doc comments of methods, by name.
+func handlerDocs(files []*ast.File) map[string]string {
+	docs := make(map[string]string)
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil || fn.Doc == nil {
+				continue
+			}
+			docs[fn.Name.Name] = fn.Doc.Text()
+		}
+	}
+	return docs
+}
+
+func operations(fset *token.FileSet, f *ast.File, docs map[string]string) []operation {
+	// Registrations are documented by the comment on the same line.
+	lineComments := make(map[int]string)
+	for _, cg := range f.Comments {
+		lineComments[fset.Position(cg.Pos()).Line] = strings.TrimSpace(cg.Text())
+	}
+
+	var ops []operation
+	ast.Inspect(f, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) != 2 {
+			return true
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok || sel.Sel.Name != "HandleFunc" {
+			return true
+		}
+		mux, ok := sel.X.(*ast.Ident)
+		if !ok {
+			return true
+		}
+		method, ok := muxMethods[mux.Name]
+		if !ok {
+			return true
+		}
+		lit, ok := call.Args[0].(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return true
+		}
+
+		op := operation{
+			Method: method,
+			Path:   strings.Trim(lit.Value, `"`),
+		}
+		parseParams(&op, lineComments[fset.Position(call.End()).Line])
+		op.Summary, op.Description = describe(handlerName(call.Args[1]), docs)
+		ops = append(ops, op)
+		return true
+	})
+	return ops
+}
+
+// parseParams fills in the operation from a registration comment such as
+// "folder [sub...] [delay]".
+func parseParams(op *operation, comment string) {
+	for _, word := range strings.Fields(comment) {
+		switch {
+		case word == "-":
+		case word == "(deprecated)":
+			op.Deprecated = true
+		case word == "<body>":
+			op.Body = true
+		default:
+			p := param{Required: true}
+			if strings.HasPrefix(word, "[") && strings.HasSuffix(word, "]") {
+				p.Required = false
+				word = word[1 : len(word)-1]
+			}
+			if strings.HasSuffix(word, "...") {
+				p.Multi = true
+				word = strings.TrimSuffix(word, "...")
+			}
+			p.Name = word
+			op.Params = append(op.Params, p)
+		}
+	}
+}
+
+// handlerName returns the name of the method handling a request, from
+// either "s.handler" or "s.makeHandler(...)".
+func handlerName(expr ast.Expr) string {
+	if call, ok := expr.(*ast.CallExpr); ok {
+		expr = call.Fun
+	}
+	if sel, ok := expr.(*ast.SelectorExpr); ok {
+		return sel.Sel.Name
+	}
+	return ""
+}
+
+// describe turns the doc comment of a handler, like "getFoo returns the
+// foo. More details.", into a summary "Returns the foo." and the rest as
+// description.
+func describe(handler string, docs map[string]string) (summary, description string) {
+	doc := strings.Join(strings.Fields(docs[handler]), " ")
+	if doc == "" {
+		return "", ""
+	}
+	if rest := strings.TrimPrefix(doc, handler+" "); rest != doc {
+		r := []rune(rest)
+		r[0] = unicode.ToUpper(r[0])
+		doc = string(r)
+	}
+	if i := strings.Index(doc, ". "); i >= 0 {
+		return doc[:i+1], doc[i+2:]
+	}
+	return doc, ""
+}